@@ -0,0 +1,143 @@
+import { DataSourceSettings } from '@grafana/data';
+import { ConfigSubSection, Stack } from '@grafana/experimental';
+import { Field, Icon, Input, Label, Switch, Tooltip } from '@grafana/ui';
+
+import { SQLOptions, SQLQueryGuardrails } from '../../types';
+
+import { NumberInput } from './NumberInput';
+
+interface Props {
+  onOptionsChange: Function;
+  options: DataSourceSettings<SQLOptions>;
+  // Describes how the database enforces the statement timeout
+  statementTimeoutDescription?: string;
+}
+
+export const QueryGuardrails = (props: Props) => {
+  const { onOptionsChange, options, statementTimeoutDescription } = props;
+  const jsonData = options.jsonData;
+
+  const updateJsonData = (values: SQLQueryGuardrails) => {
+    return onOptionsChange({
+      ...options,
+      jsonData: {
+        ...jsonData,
+        ...values,
+      },
+    });
+  };
+
+  const labelWidth = 40;
+
+  return (
+    <ConfigSubSection
+      title="Query guardrails"
+      description="Protect the database from expensive dashboard queries. Queries breaking a guardrail are rejected."
+    >
+      <Field
+        label={
+          <Label>
+            <Stack gap={0.5}>
+              <span>Max time range</span>
+              <Tooltip
+                content={
+                  <span>The longest time range a query can use, for example 7d. If empty, it isn&apos;t limited.</span>
+                }
+              >
+                <Icon name="info-circle" size="sm" />
+              </Tooltip>
+            </Stack>
+          </Label>
+        }
+      >
+        <Input
+          value={jsonData.maxTimeRange ?? ''}
+          placeholder="7d"
+          onChange={(e) => updateJsonData({ maxTimeRange: e.currentTarget.value.trim() || undefined })}
+          width={labelWidth}
+        />
+      </Field>
+
+      <Field
+        label={
+          <Label>
+            <Stack gap={0.5}>
+              <span>Require time filter</span>
+              <Tooltip
+                content={
+                  <span>
+                    Reject queries that don&apos;t use the <code>$__timeFilter</code> macro, or{' '}
+                    <code>$__unixEpochFilter</code> and <code>$__unixEpochNanoFilter</code>. Macros in comments and
+                    strings are ignored.
+                  </span>
+                }
+              >
+                <Icon name="info-circle" size="sm" />
+              </Tooltip>
+            </Stack>
+          </Label>
+        }
+      >
+        <Switch
+          value={jsonData.requireTimeFilter ?? false}
+          onChange={(e) => updateJsonData({ requireTimeFilter: e.currentTarget.checked })}
+        />
+      </Field>
+
+      <Field
+        label={
+          <Label>
+            <Stack gap={0.5}>
+              <span>Statement timeout</span>
+              <Tooltip
+                content={
+                  <span>
+                    The maximum execution time of a query, in seconds. If set to 0, queries aren&apos;t limited.
+                    {statementTimeoutDescription && ` ${statementTimeoutDescription}`}
+                  </span>
+                }
+              >
+                <Icon name="info-circle" size="sm" />
+              </Tooltip>
+            </Stack>
+          </Label>
+        }
+      >
+        <NumberInput
+          value={jsonData.statementTimeout ?? 0}
+          defaultValue={0}
+          onChange={(value) => updateJsonData({ statementTimeout: value })}
+          width={labelWidth}
+        />
+      </Field>
+
+      <Field
+        label={
+          <Label>
+            <Stack gap={0.5}>
+              <span>Max query cost</span>
+              <Tooltip
+                content={
+                  <span>
+                    Reject queries whose cost, as estimated by the query planner, is higher than this value. Queries
+                    the database can&apos;t estimate the cost for are rejected too. If set to 0, the cost isn&apos;t
+                    checked.
+                  </span>
+                }
+              >
+                <Icon name="info-circle" size="sm" />
+              </Tooltip>
+            </Stack>
+          </Label>
+        }
+      >
+        <NumberInput
+          value={jsonData.maxQueryCost ?? 0}
+          defaultValue={0}
+          onChange={(value) => updateJsonData({ maxQueryCost: value })}
+          width={labelWidth}
+        />
+      </Field>
+    </ConfigSubSection>
+  );
+};
@@ -34,7 +34,14 @@ export interface SQLConnectionLimits {
   connMaxLifetime: number;
 }
 
-export interface SQLOptions extends SQLConnectionLimits, DataSourceJsonData {
+export interface SQLQueryGuardrails {
+  maxTimeRange?: string;
+  requireTimeFilter?: boolean;
+  statementTimeout?: number;
+  maxQueryCost?: number;
+}
+
+export interface SQLOptions extends SQLConnectionLimits, SQLQueryGuardrails, DataSourceJsonData {
   tlsAuth: boolean;
   tlsAuthWithCACert: boolean;
   timezone: string;
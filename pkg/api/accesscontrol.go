@@ -29,6 +29,8 @@ var (
 	ScopeProvisionersDatasources   = ac.Scope("provisioners", "datasources")
 	ScopeProvisionersNotifications = ac.Scope("provisioners", "notifications")
 	ScopeProvisionersAlertRules    = ac.Scope("provisioners", "alerting")
+	ScopeProvisionersTeamSync      = ac.Scope("provisioners", "teamsync")
+	ScopeProvisionersReports       = ac.Scope("provisioners", "reports")
 )
 
 // declareFixedRoles declares to the AccessControl service fixed roles and their
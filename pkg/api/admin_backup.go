@@ -0,0 +1,92 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/services/backup"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// swagger:route POST /admin/backup admin adminCreateBackup
+//
+// Create a backup.
+//
+// Streams an archive of the database, the files of unified storage and the plugins, encrypted with the passphrase.
+// The database is read from a consistent snapshot while Grafana keeps running.
+// Restore it with `grafana cli admin restore` while Grafana is stopped.
+// Only available to Grafana Server Admins, when `backup_api_enabled` is set in the `[security]` section of the configuration.
+//
+// Produces:
+// - application/octet-stream
+//
+// Responses:
+// 200: adminCreateBackupResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) AdminCreateBackup(c *contextmodel.ReqContext) response.Response {
+	form := dtos.AdminCreateBackupForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if form.Passphrase == "" {
+		return response.Err(backup.ErrEmptyPassphrase.Errorf("empty passphrase"))
+	}
+
+	return backupResponse{backup: hs.backupService, passphrase: form.Passphrase}
+}
+
+// backupResponse streams a backup, which may not fit in memory
+type backupResponse struct {
+	backup     *backup.Service
+	passphrase string
+}
+
+func (r backupResponse) Status() int {
+	return http.StatusOK
+}
+
+func (r backupResponse) Body() []byte {
+	return nil
+}
+
+func (r backupResponse) WriteTo(ctx *contextmodel.ReqContext) {
+	header := ctx.Resp.Header()
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set("Content-Disposition", fmt.Sprintf(`attachment;filename="grafana-backup-%s.tar.gz.age"`, time.Now().UTC().Format("20060102-150405")))
+	header.Set("Cache-Control", "no-store")
+
+	manifest, err := r.backup.Backup(ctx.Req.Context(), ctx.Resp, r.passphrase)
+	if err != nil {
+		if !ctx.Resp.Written() {
+			// the database couldn't be dumped, nothing was sent yet
+			header.Del("Content-Disposition")
+			response.ErrOrFallback(http.StatusInternalServerError, "Failed to create backup", err).WriteTo(ctx)
+			return
+		}
+		// the status is sent already, a failure can only truncate the archive
+		ctx.Logger.Error("Failed to write backup", "err", err)
+		return
+	}
+	ctx.Logger.Info("Created backup", "tables", len(manifest.Tables), "directories", manifest.Directories)
+}
+
+// swagger:parameters adminCreateBackup
+type AdminCreateBackupParams struct {
+	// in:body
+	// required:true
+	Body dtos.AdminCreateBackupForm `json:"body"`
+}
+
+// swagger:response adminCreateBackupResponse
+type AdminCreateBackupResponse struct {
+	// The backup, a gzipped tar archive encrypted with age
+	// in: body
+	Body []byte `json:"body"`
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+func TestAPI_AdminCreateBackup(t *testing.T) {
+	server := SetupAPITestServer(t, func(hs *HTTPServer) {
+		hs.Cfg = setting.NewCfg()
+		hs.Cfg.BackupAPIEnabled = true
+	})
+
+	t.Run("should require a passphrase", func(t *testing.T) {
+		admin := userWithPermissions(1, nil)
+		admin.IsGrafanaAdmin = true
+		req := server.NewPostRequest("/api/admin/backup", strings.NewReader(`{"passphrase":""}`))
+		req.Header.Set("Content-Type", "application/json")
+		res, err := server.Send(webtest.RequestWithSignedInUser(req, admin))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should require a server admin", func(t *testing.T) {
+		req := server.NewPostRequest("/api/admin/backup", strings.NewReader(`{"passphrase":"secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		res, err := server.Send(webtest.RequestWithSignedInUser(req, userWithPermissions(1, nil)))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("should not be available unless enabled", func(t *testing.T) {
+		server := SetupAPITestServer(t, func(hs *HTTPServer) {
+			hs.Cfg = setting.NewCfg()
+		})
+		admin := userWithPermissions(1, nil)
+		admin.IsGrafanaAdmin = true
+		req := server.NewPostRequest("/api/admin/backup", strings.NewReader(`{"passphrase":"secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		res, err := server.Send(webtest.RequestWithSignedInUser(req, admin))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
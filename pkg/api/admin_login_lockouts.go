@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// swagger:route GET /admin/login-lockouts admin adminGetLoginLockouts
+//
+// List the active login lockouts.
+//
+// Usernames, IP addresses and subnets are locked out after too many failed login attempts.
+// If you are running Grafana Enterprise and have Fine-grained access control enabled, you need to have a permission with action `users:read` and scope `global.users:*`.
+//
+// Responses:
+// 200: adminGetLoginLockoutsResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) AdminGetLoginLockouts(c *contextmodel.ReqContext) response.Response {
+	lockouts, err := hs.loginAttemptService.GetLockouts(c.Req.Context())
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get login lockouts", err)
+	}
+
+	return response.JSON(http.StatusOK, lockouts)
+}
+
+// swagger:route DELETE /admin/login-lockouts/{lockout_id} admin adminDeleteLoginLockout
+//
+// Lift a login lockout.
+//
+// The failed login attempts of the locked out username, IP address or subnet are reset.
+// If you are running Grafana Enterprise and have Fine-grained access control enabled, you need to have a permission with action `users:write` and scope `global.users:*`.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) AdminDeleteLoginLockout(c *contextmodel.ReqContext) response.Response {
+	lockoutID, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+
+	if err := hs.loginAttemptService.DeleteLockout(c.Req.Context(), lockoutID); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to delete login lockout", err)
+	}
+
+	return response.Success("Login lockout deleted")
+}
+
+// swagger:parameters adminDeleteLoginLockout
+type AdminDeleteLoginLockoutParams struct {
+	// in:path
+	// required:true
+	LockoutID int64 `json:"lockout_id"`
+}
+
+// swagger:response adminGetLoginLockoutsResponse
+type AdminGetLoginLockoutsResponse struct {
+	// in:body
+	Body []*loginattempt.Lockout `json:"body"`
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
+	"github.com/grafana/grafana/pkg/services/loginattempt/loginattempttest"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+func TestAPI_AdminGetLoginLockouts(t *testing.T) {
+	lockouts := []*loginattempt.Lockout{
+		{ID: 1, Type: loginattempt.LockoutTypeIP, Value: "192.168.1.10", Count: 1, LockedUntil: time.Unix(1700000300, 0).UTC(), Created: time.Unix(1700000000, 0).UTC()},
+	}
+
+	t.Run("should list lockouts", func(t *testing.T) {
+		server := SetupAPITestServer(t, func(hs *HTTPServer) {
+			hs.Cfg = setting.NewCfg()
+			hs.loginAttemptService = loginattempttest.FakeLoginAttemptService{ExpectedLockouts: lockouts}
+		})
+
+		req := webtest.RequestWithSignedInUser(server.NewGetRequest("/api/admin/login-lockouts"), userWithPermissions(1, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionUsersRead, Scope: accesscontrol.ScopeGlobalUsersAll},
+		}))
+		res, err := server.Send(req)
+		require.NoError(t, err)
+
+		var result []*loginattempt.Lockout
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, lockouts, result)
+	})
+
+	t.Run("should require permission", func(t *testing.T) {
+		server := SetupAPITestServer(t, func(hs *HTTPServer) {
+			hs.Cfg = setting.NewCfg()
+			hs.loginAttemptService = loginattempttest.FakeLoginAttemptService{ExpectedLockouts: lockouts}
+		})
+
+		res, err := server.Send(webtest.RequestWithSignedInUser(server.NewGetRequest("/api/admin/login-lockouts"), userWithPermissions(1, nil)))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+}
+
+func TestAPI_AdminDeleteLoginLockout(t *testing.T) {
+	permissions := []accesscontrol.Permission{{Action: accesscontrol.ActionUsersWrite, Scope: accesscontrol.ScopeGlobalUsersAll}}
+
+	tests := []struct {
+		desc         string
+		url          string
+		expectedErr  error
+		permissions  []accesscontrol.Permission
+		expectedCode int
+	}{
+		{
+			desc:         "should delete lockout",
+			url:          "/api/admin/login-lockouts/1",
+			permissions:  permissions,
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "should return not found for unknown lockout",
+			url:          "/api/admin/login-lockouts/1",
+			expectedErr:  loginattempt.ErrLockoutNotFound.Errorf("not found"),
+			permissions:  permissions,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			desc:         "should return bad request for invalid id",
+			url:          "/api/admin/login-lockouts/abc",
+			permissions:  permissions,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "should require permission",
+			url:          "/api/admin/login-lockouts/1",
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			mock := &loginattempttest.MockLoginAttemptService{ExpectedErr: tt.expectedErr}
+			server := SetupAPITestServer(t, func(hs *HTTPServer) {
+				hs.Cfg = setting.NewCfg()
+				hs.loginAttemptService = mock
+			})
+
+			res, err := server.Send(webtest.RequestWithSignedInUser(server.NewRequest(http.MethodDelete, tt.url, nil), userWithPermissions(1, tt.permissions)))
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+
+			assert.Equal(t, tt.expectedCode, res.StatusCode)
+			assert.Equal(t, tt.expectedCode != http.StatusForbidden && tt.expectedCode != http.StatusBadRequest, mock.DeleteLockoutCalled)
+		})
+	}
+}
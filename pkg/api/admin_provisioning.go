@@ -85,3 +85,50 @@ func (hs *HTTPServer) AdminProvisioningReloadAlerting(c *contextmodel.ReqContext
 	}
 	return response.Success("Alerting config reloaded")
 }
+
+// swagger:route POST /admin/provisioning/teamsync/reload admin_provisioning adminProvisioningReloadTeamSync
+//
+// Reload team sync provisioning configurations.
+//
+// Reloads the provisioning config files for teams and their external groups again. It won’t return until the new provisioned entities are already stored in the database.
+// If you have Fine-grained access control enabled, you need to have a permission with action `provisioning:reload` and scope `provisioners:teamsync`.
+//
+// Security:
+// - basic:
+//
+// Responses:
+// 200: okResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) AdminProvisioningReloadTeamSync(c *contextmodel.ReqContext) response.Response {
+	err := hs.ProvisioningService.ProvisionTeamSync(c.Req.Context())
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to reload team sync config", err)
+	}
+	return response.Success("Team sync config reloaded")
+}
+
+// swagger:route POST /admin/provisioning/reports/reload admin_provisioning adminProvisioningReloadReports
+//
+// Reload report provisioning configurations.
+//
+// Reloads the provisioning config files for reports again. It won’t return until the new provisioned entities are already stored in the database.
+// If you have Fine-grained access control enabled, you need to have a permission with action `provisioning:reload` and scope `provisioners:reports`.
+//
+// Security:
+// - basic:
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) AdminProvisioningReloadReports(c *contextmodel.ReqContext) response.Response {
+	err := hs.ProvisioningService.ProvisionReports(c.Req.Context())
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to reload reports config", err)
+	}
+	return response.Success("Reports config reloaded")
+}
@@ -136,6 +136,26 @@ func TestAPI_AdminProvisioningReload_AccessControl(t *testing.T) {
 			expectedCode: http.StatusForbidden,
 			url:          "/api/admin/provisioning/alerting/reload",
 		},
+		{
+			desc:         "should work for reports with specific scope",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"message":"Reports config reloaded"}`,
+			permissions: []accesscontrol.Permission{
+				{
+					Action: ActionProvisioningReload,
+					Scope:  ScopeProvisionersReports,
+				},
+			},
+			url: "/api/admin/provisioning/reports/reload",
+			checkCall: func(mock provisioning.ProvisioningServiceMock) {
+				assert.Len(t, mock.Calls.ProvisionReports, 1)
+			},
+		},
+		{
+			desc:         "should fail for reports with no permission",
+			expectedCode: http.StatusForbidden,
+			url:          "/api/admin/provisioning/reports/reload",
+		},
 	}
 
 	for _, tt := range tests {
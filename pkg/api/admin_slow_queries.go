@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/slowquerylog"
+)
+
+// swagger:route GET /admin/slow-queries/panels admin adminGetSlowQueryPanels
+//
+// List the dashboard panels that spent the most time in slow queries.
+//
+// Only the data source queries taking longer than the `[slow_query_log] threshold` setting are counted.
+// If you are running Grafana Enterprise and have Fine-grained access control enabled, you need to have a permission with action `server.stats:read`.
+//
+// Responses:
+// 200: adminGetSlowQueryPanelsResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) AdminGetSlowQueryPanels(c *contextmodel.ReqContext) response.Response {
+	query, err := slowQueryTopQuery(c)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "since is invalid", err)
+	}
+
+	panels, err := hs.slowQueryLog.GetTopPanels(c.Req.Context(), query)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get slow query panels", err)
+	}
+
+	return response.JSON(http.StatusOK, panels)
+}
+
+// swagger:route GET /admin/slow-queries/datasources admin adminGetSlowQueryDatasources
+//
+// List the data sources that spent the most time in slow queries.
+//
+// Only the data source queries taking longer than the `[slow_query_log] threshold` setting are counted.
+// If you are running Grafana Enterprise and have Fine-grained access control enabled, you need to have a permission with action `server.stats:read`.
+//
+// Responses:
+// 200: adminGetSlowQueryDatasourcesResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) AdminGetSlowQueryDatasources(c *contextmodel.ReqContext) response.Response {
+	query, err := slowQueryTopQuery(c)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "since is invalid", err)
+	}
+
+	datasources, err := hs.slowQueryLog.GetTopDatasources(c.Req.Context(), query)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get slow query data sources", err)
+	}
+
+	return response.JSON(http.StatusOK, datasources)
+}
+
+func slowQueryTopQuery(c *contextmodel.ReqContext) (slowquerylog.GetTopQuery, error) {
+	query := slowquerylog.GetTopQuery{
+		OrgID: c.QueryInt64("orgId"),
+		Limit: c.QueryInt("limit"),
+	}
+	if since := c.Query("since"); since != "" {
+		d, err := gtime.ParseDuration(since)
+		if err != nil {
+			return query, err
+		}
+		query.Since = time.Now().Add(-d)
+	}
+	return query, nil
+}
+
+// swagger:parameters adminGetSlowQueryPanels adminGetSlowQueryDatasources
+type AdminGetSlowQueriesParams struct {
+	// Only count the slow queries of the last duration, for example 1h or 7d.
+	// in:query
+	// required:false
+	// default:24h
+	Since string `json:"since"`
+	// in:query
+	// required:false
+	// default:10
+	Limit int `json:"limit"`
+	// Only count the slow queries of an organization.
+	// in:query
+	// required:false
+	OrgID int64 `json:"orgId"`
+}
+
+// swagger:response adminGetSlowQueryPanelsResponse
+type AdminGetSlowQueryPanelsResponse struct {
+	// in:body
+	Body []*slowquerylog.PanelLoad `json:"body"`
+}
+
+// swagger:response adminGetSlowQueryDatasourcesResponse
+type AdminGetSlowQueryDatasourcesResponse struct {
+	// in:body
+	Body []*slowquerylog.DatasourceLoad `json:"body"`
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/slowquerylog"
+	"github.com/grafana/grafana/pkg/services/slowquerylog/slowquerylogtest"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+func TestAPI_AdminGetSlowQueries(t *testing.T) {
+	panels := []*slowquerylog.PanelLoad{
+		{OrgID: 1, DashboardUID: "dash", PanelID: 2, Count: 3, TotalDurationMs: 30000, AvgDurationMs: 10000, MaxDurationMs: 15000, Users: 2, LastSeen: time.Unix(1700000000, 0).UTC()},
+	}
+	datasources := []*slowquerylog.DatasourceLoad{
+		{OrgID: 1, DatasourceUID: "prom", DatasourceType: "prometheus", Count: 3, TotalDurationMs: 30000, AvgDurationMs: 10000, MaxDurationMs: 15000, Dashboards: 1, LastSeen: time.Unix(1700000000, 0).UTC()},
+	}
+	permissions := []accesscontrol.Permission{{Action: accesscontrol.ActionServerStatsRead}}
+
+	setup := func(t *testing.T) *webtest.Server {
+		return SetupAPITestServer(t, func(hs *HTTPServer) {
+			hs.Cfg = setting.NewCfg()
+			hs.slowQueryLog = &slowquerylogtest.FakeService{ExpectedPanels: panels, ExpectedDatasources: datasources}
+		})
+	}
+
+	t.Run("should list the top panels", func(t *testing.T) {
+		server := setup(t)
+		res, err := server.Send(webtest.RequestWithSignedInUser(server.NewGetRequest("/api/admin/slow-queries/panels?since=7d&limit=5"), userWithPermissions(1, permissions)))
+		require.NoError(t, err)
+
+		var result []*slowquerylog.PanelLoad
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, panels, result)
+	})
+
+	t.Run("should list the top data sources", func(t *testing.T) {
+		server := setup(t)
+		res, err := server.Send(webtest.RequestWithSignedInUser(server.NewGetRequest("/api/admin/slow-queries/datasources"), userWithPermissions(1, permissions)))
+		require.NoError(t, err)
+
+		var result []*slowquerylog.DatasourceLoad
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, datasources, result)
+	})
+
+	t.Run("should reject an invalid duration", func(t *testing.T) {
+		server := setup(t)
+		res, err := server.Send(webtest.RequestWithSignedInUser(server.NewGetRequest("/api/admin/slow-queries/panels?since=yesterday"), userWithPermissions(1, permissions)))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should require permission", func(t *testing.T) {
+		server := setup(t)
+		res, err := server.Send(webtest.RequestWithSignedInUser(server.NewGetRequest("/api/admin/slow-queries/panels"), userWithPermissions(1, nil)))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/dashboardsnapshots"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
 	"github.com/grafana/grafana/pkg/util/errhttp"
 	"github.com/grafana/grafana/pkg/web"
@@ -92,6 +93,53 @@ func (hs *HTTPServer) CreateDashboardSnapshot(c *contextmodel.ReqContext) {
 	}, cmd, hs.dashboardsnapshotsService)
 }
 
+// swagger:route POST /snapshots/capture snapshots captureDashboardSnapshot
+//
+// Create a snapshot of a dashboard with the data of its panels queried by the server.
+//
+// The queries of every panel run on behalf of the signed in user, for the given time range and template variable values.
+//
+// Responses:
+// 200: createDashboardSnapshotResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) CaptureDashboardSnapshot(c *contextmodel.ReqContext) response.Response {
+	if !hs.Cfg.SnapshotEnabled {
+		return response.Error(http.StatusForbidden, "Dashboard Snapshots are disabled", nil)
+	}
+
+	cmd := dashboardsnapshots.CaptureDashboardSnapshotCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd.OrgID = c.SignedInUser.GetOrgID()
+
+	evaluator := ac.EvalPermission(dashboards.ActionDashboardsWrite, dashboards.ScopeDashboardsProvider.GetResourceScopeUID(cmd.DashboardUID))
+	if canSave, err := hs.AccessControl.Evaluate(c.Req.Context(), c.SignedInUser, evaluator); err != nil || !canSave {
+		return response.Error(http.StatusForbidden, "forbidden", err)
+	}
+
+	result, err := hs.dashboardSnapshotCapture.CaptureDashboardSnapshot(c.Req.Context(), c.SignedInUser, &cmd)
+	if err != nil {
+		if errors.Is(err, dashboards.ErrDashboardNotFound) {
+			return response.Error(http.StatusNotFound, "Dashboard not found", err)
+		}
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to capture snapshot", err)
+	}
+
+	metrics.MApiDashboardSnapshotCreate.Inc()
+
+	return response.JSON(http.StatusOK, dashboardsnapshot.DashboardCreateResponse{
+		Key:       result.Key,
+		DeleteKey: result.DeleteKey,
+		URL:       setting.ToAbsUrl("dashboard/snapshot/" + result.Key),
+		DeleteURL: setting.ToAbsUrl("api/snapshots-delete/" + result.DeleteKey),
+	})
+}
+
 // GET /api/snapshots/:key
 // swagger:route GET /snapshots/{key} snapshots getDashboardSnapshot
 //
@@ -314,6 +362,13 @@ type CreateSnapshotParams struct {
 	Body dashboardsnapshots.CreateDashboardSnapshotCommand `json:"body"`
 }
 
+// swagger:parameters captureDashboardSnapshot
+type CaptureSnapshotParams struct {
+	// in:body
+	// required:true
+	Body dashboardsnapshots.CaptureDashboardSnapshotCommand `json:"body"`
+}
+
 // swagger:parameters searchDashboardSnapshots
 type GetSnapshotsParams struct {
 	// Search Query
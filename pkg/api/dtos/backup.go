@@ -0,0 +1,6 @@
+package dtos
+
+type AdminCreateBackupForm struct {
+	// Passphrase the backup is encrypted with, it's required to restore it
+	Passphrase string `json:"passphrase"`
+}
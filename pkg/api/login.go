@@ -228,7 +228,16 @@ func (hs *HTTPServer) LoginAPIPing(c *contextmodel.ReqContext) response.Response
 }
 
 func (hs *HTTPServer) LoginPost(c *contextmodel.ReqContext) response.Response {
-	identity, err := hs.authnService.Login(c.Req.Context(), authn.ClientForm, &authn.Request{HTTPRequest: c.Req})
+	return hs.loginWithClient(c, authn.ClientForm)
+}
+
+// LoginMFAPost completes the second factor challenge issued by LoginPost
+func (hs *HTTPServer) LoginMFAPost(c *contextmodel.ReqContext) response.Response {
+	return hs.loginWithClient(c, authn.ClientMFA)
+}
+
+func (hs *HTTPServer) loginWithClient(c *contextmodel.ReqContext, client string) response.Response {
+	identity, err := hs.authnService.Login(c.Req.Context(), client, &authn.Request{HTTPRequest: c.Req})
 	if err != nil {
 		tokenErr := &auth.CreateTokenErr{}
 		if errors.As(err, &tokenErr) {
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/panelexport"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// swagger:route GET /dashboards/uid/{uid}/panels/{panelId}/export dashboards exportPanelData
+//
+// Export the data of a panel as a CSV, XLSX or Parquet file.
+//
+// The queries and expressions of the panel run on behalf of the signed in user, for the given time range and
+// template variable values. The frames of the queries are joined by time in CSV and Parquet, and written to a sheet
+// each in XLSX. No content is returned when the queries return no data.
+//
+// Produces:
+// - text/csv
+// - application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// - application/vnd.apache.parquet
+//
+// Responses:
+// 200: exportPanelDataResponse
+// 204: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) ExportPanelData(c *contextmodel.ReqContext) response.Response {
+	panelID, err := strconv.ParseInt(web.Params(c.Req)[":panelId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "panelId is invalid", err)
+	}
+
+	query := c.Req.URL.Query()
+	q := &panelexport.ExportQuery{
+		OrgID:        c.SignedInUser.GetOrgID(),
+		DashboardUID: web.Params(c.Req)[":uid"],
+		PanelID:      panelID,
+		From:         query.Get("from"),
+		To:           query.Get("to"),
+		Timezone:     query.Get("timezone"),
+		Format:       panelexport.Format(query.Get("format")),
+		Variables:    map[string][]string{},
+	}
+	if s := query.Get("frameIndex"); s != "" {
+		frameIndex, err := strconv.Atoi(s)
+		if err != nil {
+			return response.Error(http.StatusBadRequest, "frameIndex is invalid", err)
+		}
+		q.FrameIndex = &frameIndex
+	}
+	// the variables are passed like in the dashboard URLs
+	for key, values := range query {
+		if name, ok := strings.CutPrefix(key, "var-"); ok {
+			q.Variables[name] = values
+		}
+	}
+
+	export, err := hs.panelExport.Export(c.Req.Context(), c.SignedInUser, q)
+	if err != nil {
+		if errors.Is(err, dashboards.ErrDashboardNotFound) {
+			return response.Error(http.StatusNotFound, "Dashboard not found", err)
+		}
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to export panel data", err)
+	}
+	if export.Empty() {
+		return response.Empty(http.StatusNoContent)
+	}
+
+	return panelExportResponse{export: export}
+}
+
+// panelExportResponse streams the file of an export, which may not fit in memory once encoded
+type panelExportResponse struct {
+	export *panelexport.Export
+}
+
+func (r panelExportResponse) Status() int {
+	return http.StatusOK
+}
+
+func (r panelExportResponse) Body() []byte {
+	return nil
+}
+
+func (r panelExportResponse) WriteTo(ctx *contextmodel.ReqContext) {
+	header := ctx.Resp.Header()
+	header.Set("Content-Type", r.export.ContentType())
+	header.Set("Content-Disposition", fmt.Sprintf(`attachment;filename="%s"`, r.export.Filename))
+	header.Set("Cache-Control", "no-store")
+	ctx.Resp.WriteHeader(http.StatusOK)
+
+	// the status is sent already, a failure can only truncate the file
+	if err := r.export.Write(ctx.Resp); err != nil {
+		ctx.Logger.Error("Failed to write panel export", "filename", r.export.Filename, "err", err)
+	}
+}
+
+// swagger:parameters exportPanelData
+type ExportPanelDataParams struct {
+	// in:path
+	// required:true
+	UID string `json:"uid"`
+	// in:path
+	// required:true
+	PanelID int64 `json:"panelId"`
+	// csv, xlsx or parquet
+	// in:query
+	// default:csv
+	Format string `json:"format"`
+	// Start of the time range, absolute in epoch milliseconds or relative like now-6h. Defaults to the time range of
+	// the dashboard.
+	// in:query
+	From string `json:"from"`
+	// End of the time range, absolute in epoch milliseconds or relative like now.
+	// in:query
+	To string `json:"to"`
+	// IANA time zone the relative time range is resolved in and the times are written in. Defaults to the time
+	// zone of the dashboard, or UTC.
+	// in:query
+	Timezone string `json:"timezone"`
+	// Index of the single frame to export, all the frames are exported by default.
+	// in:query
+	FrameIndex int `json:"frameIndex"`
+}
+
+// swagger:response exportPanelDataResponse
+type ExportPanelDataResponse struct {
+	// in: body
+	Body []byte `json:"body"`
+}
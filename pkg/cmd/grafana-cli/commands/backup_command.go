@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli/v2"
+
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/server"
+	"github.com/grafana/grafana/pkg/services/backup"
+)
+
+// backupCommand writes an encrypted backup of the instance to the file given as argument
+func backupCommand(c utils.CommandLine, runner server.Runner) error {
+	path := c.Args().First()
+	if path == "" {
+		return errors.New("the backup file is required, like grafana cli admin backup grafana.backup")
+	}
+	passphrase, err := backupPassphrase(c)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create the backup file: %w", err)
+	}
+
+	svc := backup.ProvideService(runner.Cfg, runner.SQLStore, runner.SecretsService)
+	manifest, err := svc.Backup(context.Background(), f, passphrase)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+
+	var rows int64
+	for _, t := range manifest.Tables {
+		rows += t.Rows
+	}
+	logger.Infof("\n")
+	logger.Infof("Backed up %d rows of %d tables, %d data keys and the files of %v to %s %s\n", rows, len(manifest.Tables),
+		manifest.DataKeys, manifest.Directories, path, color.GreenString("✔"))
+	logger.Infof("Keep the passphrase, the backup can't be restored without it.\n")
+	return nil
+}
+
+// restoreCommand replaces the database and the files of the instance with a backup, Grafana must be stopped while
+// it runs
+func restoreCommand(c utils.CommandLine, runner server.Runner) error {
+	path := c.Args().First()
+	if path == "" {
+		return errors.New("the backup file is required, like grafana cli admin restore grafana.backup")
+	}
+	passphrase, err := backupPassphrase(c)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("failed to open the backup file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	svc := backup.ProvideService(runner.Cfg, runner.SQLStore, runner.SecretsService)
+	manifest, err := svc.Restore(context.Background(), f, passphrase, backup.RestoreOptions{
+		SkipFiles: c.Bool("skip-files"),
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("\n")
+	logger.Infof("Restored the backup of Grafana %s taken at %s %s\n", manifest.GrafanaVersion,
+		manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"), color.GreenString("✔"))
+	return nil
+}
+
+func backupPassphrase(c utils.CommandLine) (string, error) {
+	if !c.Bool("passphrase-from-stdin") {
+		if passphrase := c.String("passphrase"); passphrase != "" {
+			return passphrase, nil
+		}
+		return "", errors.New("the passphrase is required, use --passphrase, --passphrase-from-stdin or the GF_BACKUP_PASSPHRASE environment variable")
+	}
+
+	logger.Infof("Passphrase: ")
+	scanner := bufio.NewScanner(os.Stdin)
+	if ok := scanner.Scan(); !ok {
+		if err := scanner.Err(); err != nil {
+			return "", fmt.Errorf("can't read passphrase from stdin: %w", err)
+		}
+		return "", fmt.Errorf("can't read passphrase from stdin")
+	}
+	if scanner.Text() == "" {
+		return "", errors.New("the passphrase is empty")
+	}
+	return scanner.Text(), nil
+}
+
+func backupFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "passphrase",
+			Usage:   "Passphrase the backup is encrypted with",
+			EnvVars: []string{"GF_BACKUP_PASSPHRASE"},
+		},
+		&cli.BoolFlag{
+			Name:  "passphrase-from-stdin",
+			Usage: "Read the passphrase from stdin",
+			Value: false,
+		},
+	}
+}
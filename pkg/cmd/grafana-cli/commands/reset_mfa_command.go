@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fatih/color"
+
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/server"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+var (
+	ErrMissingLogin      = errors.New("reset-mfa requires the login or the email of a user")
+	ErrUserCannotBeFound = errors.New("user cannot be found")
+)
+
+func resetMFACommand(c utils.CommandLine, runner server.Runner) error {
+	loginOrEmail := c.Args().First()
+	if loginOrEmail == "" {
+		return ErrMissingLogin
+	}
+
+	if err := resetMFA(loginOrEmail, runner.UserService, runner.MFAService); err != nil {
+		return err
+	}
+
+	logger.Infof("\n")
+	logger.Infof("Multi-factor authentication of %s reset successfully %s", loginOrEmail, color.GreenString("✔"))
+	return nil
+}
+
+func resetMFA(loginOrEmail string, userSvc user.Service, mfaSvc mfa.Service) error {
+	usr, err := userSvc.GetByLogin(context.Background(), &user.GetUserByLoginQuery{LoginOrEmail: loginOrEmail})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return ErrUserCannotBeFound
+		}
+		return fmt.Errorf("could not read user from database. Error: %v", err)
+	}
+
+	if err := mfaSvc.Reset(context.Background(), usr.ID); err != nil {
+		return fmt.Errorf("failed to reset multi-factor authentication: %w", err)
+	}
+
+	return nil
+}
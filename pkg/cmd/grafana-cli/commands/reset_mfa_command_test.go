@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/mfa/mfatest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+func TestResetMFA(t *testing.T) {
+	tests := map[string]struct {
+		UserErr   error
+		MFAErr    error
+		ExpectErr string
+	}{
+		"basic success": {},
+		"user cannot be found": {
+			UserErr:   user.ErrUserNotFound,
+			ExpectErr: ErrUserCannotBeFound.Error(),
+		},
+		"reset fails": {
+			MFAErr:    errors.New("db error"),
+			ExpectErr: "failed to reset multi-factor authentication: db error",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			userSvc := &usertest.FakeUserService{ExpectedUser: &user.User{ID: 2}, ExpectedError: test.UserErr}
+			mfaSvc := &mfatest.FakeService{ExpectedErr: test.MFAErr}
+			err := resetMFA("user@example.org", userSvc, mfaSvc)
+			if test.ExpectErr != "" {
+				require.EqualError(t, err, test.ExpectErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -39,7 +39,7 @@ func ServerCommand(version, commit, enterpriseCommit, buildBranch, buildstamp st
 				BuildStamp:       buildstamp,
 			}, context)
 		},
-		Subcommands: []*cli.Command{TargetCommand(version, commit, buildBranch, buildstamp)},
+		Subcommands: []*cli.Command{TargetCommand(version, commit, buildBranch, buildstamp), MigrationsCommand()},
 	}
 }
 
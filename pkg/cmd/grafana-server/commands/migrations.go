@@ -0,0 +1,210 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/server"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// MigrationsCommand plans, verifies and rolls back the database migrations of Grafana without starting it
+func MigrationsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrations",
+		Usage: "plan, verify and roll back the database migrations",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "plan",
+				Usage: "print the SQL of the pending migrations without executing them",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "dialect",
+						Usage: "print the SQL for this database (mysql, postgres or sqlite3), defaults to the configured database",
+					},
+				}, commonFlags...),
+				Action: runMigrationsCommand(planMigrations),
+			},
+			{
+				Name:   "verify",
+				Usage:  "fail if migrations were modified after they were executed",
+				Flags:  commonFlags,
+				Action: runMigrationsCommand(verifyMigrations),
+			},
+			{
+				Name:  "rollback",
+				Usage: "roll back the migrations executed since a time, Grafana must be stopped",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "since",
+						Usage:    "roll back the migrations executed since this time (RFC 3339), or for this duration (e.g. 2h)",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "print the SQL rolling back the migrations without executing it",
+					},
+				}, commonFlags...),
+				Action: runMigrationsCommand(rollBackMigrations),
+			},
+		},
+	}
+}
+
+func runMigrationsCommand(command func(c *cli.Context, mg *migrator.Migrator) error) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		defer func() {
+			if err := log.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to close log: %s\n", err)
+			}
+		}()
+
+		configOptions := strings.Split(ConfigOverrides, " ")
+		cfg, err := setting.NewCfgFromArgs(setting.CommandLineArgs{
+			Config:   ConfigFile,
+			HomePath: HomePath,
+			// tailing arguments have precedence over the options string
+			Args: append(configOptions, c.Args().Slice()...),
+		})
+		if err != nil {
+			return err
+		}
+
+		runner, err := server.InitializeForMigrations(cfg)
+		if err != nil {
+			return err
+		}
+
+		// the store doesn't run the migrations, unlike the one of the server
+		tracer := tracing.NewNoopTracerService()
+		store, err := sqlstore.NewSQLStoreWithoutSideEffects(cfg, runner.Features, bus.ProvideBus(tracer), tracer)
+		if err != nil {
+			return fmt.Errorf("failed to connect to the database: %w", err)
+		}
+		engine := store.GetEngine()
+		defer func() { _ = engine.Close() }()
+
+		mg := migrator.NewMigrator(engine, cfg)
+		runner.Migrations.AddMigration(mg)
+		return command(c, mg)
+	}
+}
+
+func planMigrations(c *cli.Context, mg *migrator.Migrator) error {
+	dialect := mg.Dialect
+	if name := c.String("dialect"); name != "" {
+		if name != migrator.MySQL && name != migrator.Postgres && name != migrator.SQLite {
+			return fmt.Errorf("unsupported dialect %q, use mysql, postgres or sqlite3", name)
+		}
+		dialect = migrator.NewDialect(name)
+	}
+
+	plan, err := mg.Plan(dialect)
+	if err != nil {
+		return err
+	}
+	if err := printModifiedMigrations(mg); err != nil {
+		return err
+	}
+	if len(plan) == 0 {
+		fmt.Println("-- The database is up to date")
+		return nil
+	}
+
+	fmt.Printf("-- %d pending migrations for %s\n", len(plan), dialect.DriverName())
+	for _, m := range plan {
+		fmt.Printf("\n-- %s\n", m.ID)
+		if m.Condition != "" {
+			fmt.Printf("-- skipped unless: %s\n", m.Condition)
+		}
+		if m.Code {
+			fmt.Printf("-- code migration: %s\n", m.SQL)
+			continue
+		}
+		fmt.Printf("%s;\n", strings.TrimSuffix(strings.TrimSpace(m.SQL), ";"))
+	}
+	return nil
+}
+
+func verifyMigrations(_ *cli.Context, mg *migrator.Migrator) error {
+	if _, err := mg.GetMigrationLog(); err != nil {
+		return err
+	}
+	modified, err := mg.ModifiedMigrations()
+	if err != nil {
+		return err
+	}
+	if len(modified) > 0 {
+		return fmt.Errorf("migrations were modified after they were executed: %s", strings.Join(modified, ", "))
+	}
+
+	fmt.Println("The executed migrations weren't modified")
+	return nil
+}
+
+func rollBackMigrations(c *cli.Context, mg *migrator.Migrator) error {
+	since, err := parseSince(c.String("since"), time.Now())
+	if err != nil {
+		return err
+	}
+
+	plan, err := mg.RollbackPlan(since)
+	if err != nil {
+		return err
+	}
+	if len(plan) == 0 {
+		fmt.Printf("No migrations were executed since %s\n", since.Format(time.RFC3339))
+		return nil
+	}
+
+	if c.Bool("dry-run") {
+		fmt.Printf("-- %d migrations to roll back\n", len(plan))
+		for _, m := range plan {
+			if m.Skipped {
+				fmt.Printf("\n-- %s (skipped by its condition at %s, only removed from the migration log)\n", m.ID, m.Executed.Format(time.RFC3339))
+				continue
+			}
+			fmt.Printf("\n-- %s (executed at %s)\n%s;\n", m.ID, m.Executed.Format(time.RFC3339), m.SQL)
+		}
+		return nil
+	}
+
+	if err := mg.RollBack(since); err != nil {
+		return err
+	}
+	fmt.Printf("Rolled back %d migrations, start the previous version of Grafana\n", len(plan))
+	return nil
+}
+
+func printModifiedMigrations(mg *migrator.Migrator) error {
+	modified, err := mg.ModifiedMigrations()
+	if err != nil {
+		return err
+	}
+	for _, id := range modified {
+		fmt.Printf("-- WARNING: migration %q was modified after it was executed\n", id)
+	}
+	return nil
+}
+
+// parseSince parses a time in the RFC 3339 format, or a duration before now
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if since, err := time.Parse(time.RFC3339, value); err == nil {
+		return since, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return time.Time{}, errors.New("invalid --since, use a time in the RFC 3339 format (e.g. 2024-06-01T10:00:00Z) or a duration (e.g. 2h)")
+	}
+	return now.Add(-d), nil
+}
@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	since, err := parseSince("2024-06-01T10:00:00Z", now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC), since)
+
+	since, err = parseSince("90m", now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 6, 1, 10, 30, 0, 0, time.UTC), since)
+
+	_, err = parseSince("-1h", now)
+	require.Error(t, err)
+
+	_, err = parseSince("yesterday", now)
+	require.Error(t, err)
+}
@@ -441,7 +441,7 @@ func ReadLoggingConfig(modes []string, logsPath string, cfg *ini.File) error {
 		return nil
 	}
 
-	defaultLevelName, _ := getLogLevelFromConfig("log", "info", cfg)
+	defaultLevelName, defaultLevel := getLogLevelFromConfig("log", "info", cfg)
 	defaultFilters := getFilters(util.SplitString(cfg.Section("log").Key("filters").String()))
 
 	configLoggers := make([]logWithFilters, 0, len(modes))
@@ -508,6 +508,16 @@ func ReadLoggingConfig(modes []string, logsPath string, cfg *ini.File) error {
 
 		configLoggers = append(configLoggers, handler)
 	}
+
+	// keep the recent lines in memory for the support bundles, with the levels and filters of the [log] section
+	if size := cfg.Section("log").Key("recent_lines").MustInt(0); size > 0 {
+		r := newRecentLogger(size)
+		configLoggers = append(configLoggers, logWithFilters{val: r, filters: defaultFilters, maxLevel: defaultLevel})
+		recent.Store(r)
+	} else {
+		recent.Store(nil)
+	}
+
 	if len(configLoggers) > 0 {
 		root.initialize(configLoggers)
 	}
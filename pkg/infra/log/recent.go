@@ -0,0 +1,95 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	gokitlog "github.com/go-kit/log"
+)
+
+// maxRecentLineLength bounds the memory taken by lines with large values, like request bodies
+const maxRecentLineLength = 4096
+
+var recent atomic.Pointer[recentLogger]
+
+// recentLogger keeps the last lines logged by each logger in memory, so that they can be included in support bundles
+// even when the logs of the instance are not at hand.
+type recentLogger struct {
+	size    int
+	mu      sync.Mutex
+	buffers map[string]*lineRing
+}
+
+type lineRing struct {
+	lines []string
+	next  int
+}
+
+func newRecentLogger(size int) *recentLogger {
+	return &recentLogger{
+		size:    size,
+		buffers: map[string]*lineRing{},
+	}
+}
+
+func (r *recentLogger) Log(keyvals ...any) error {
+	name := ""
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keyvals[i] == "logger" {
+			name = fmt.Sprint(keyvals[i+1])
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := gokitlog.NewLogfmtLogger(&buf).Log(keyvals...); err != nil {
+		return err
+	}
+	line := string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	if len(line) > maxRecentLineLength {
+		line = line[:maxRecentLineLength] + "..."
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ring, ok := r.buffers[name]
+	if !ok {
+		ring = &lineRing{}
+		r.buffers[name] = ring
+	}
+	if len(ring.lines) < r.size {
+		ring.lines = append(ring.lines, line)
+		return nil
+	}
+	ring.lines[ring.next] = line
+	ring.next = (ring.next + 1) % r.size
+	return nil
+}
+
+func (r *recentLogger) lines() map[string][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lines := make(map[string][]string, len(r.buffers))
+	for name, ring := range r.buffers {
+		ordered := make([]string, 0, len(ring.lines))
+		ordered = append(ordered, ring.lines[ring.next:]...)
+		ordered = append(ordered, ring.lines[:ring.next]...)
+		lines[name] = ordered
+	}
+	return lines
+}
+
+// RecentLines returns the last lines logged by each logger, oldest first, keyed by the name of the logger.
+// The lines logged without a logger name are keyed by an empty string. It returns nil when the recent lines are
+// not kept, see the recent_lines setting of the [log] section.
+func RecentLines() map[string][]string {
+	r := recent.Load()
+	if r == nil {
+		return nil
+	}
+	return r.lines()
+}
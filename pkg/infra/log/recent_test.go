@@ -0,0 +1,50 @@
+package log
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log/level"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+)
+
+func TestRecentLogger(t *testing.T) {
+	t.Run("should keep the last lines of each logger", func(t *testing.T) {
+		r := newRecentLogger(2)
+		for _, msg := range []string{"one", "two", "three"} {
+			require.NoError(t, r.Log("logger", "sqlstore", level.Key(), level.InfoValue(), "msg", msg))
+		}
+		require.NoError(t, r.Log("msg", "unnamed"))
+		require.NoError(t, r.Log("logger", "live", "msg", strings.Repeat("x", 2*maxRecentLineLength)))
+
+		lines := r.lines()
+		require.Equal(t, []string{"logger=sqlstore level=info msg=two", "logger=sqlstore level=info msg=three"}, lines["sqlstore"])
+		require.Equal(t, []string{"msg=unnamed"}, lines[""])
+		require.Len(t, lines["live"][0], maxRecentLineLength+3)
+	})
+
+	t.Run("should be disabled by default", func(t *testing.T) {
+		require.NoError(t, ReadLoggingConfig(nil, "", ini.Empty()))
+		require.Nil(t, RecentLines())
+	})
+
+	t.Run("should follow the level of the log section", func(t *testing.T) {
+		t.Cleanup(func() { recent.Store(nil) })
+
+		cfg := ini.Empty()
+		_, err := cfg.Section("log").NewKey("level", "warn")
+		require.NoError(t, err)
+		_, err = cfg.Section("log").NewKey("recent_lines", "10")
+		require.NoError(t, err)
+		require.NoError(t, ReadLoggingConfig(nil, "", cfg))
+
+		logger := New("recent-test")
+		logger.Info("ignored")
+		logger.Warn("kept")
+
+		lines := RecentLines()["recent-test"]
+		require.Len(t, lines, 1)
+		require.Contains(t, lines[0], "msg=kept")
+	})
+}
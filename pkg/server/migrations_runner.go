@@ -0,0 +1,26 @@
+package server
+
+import (
+	"github.com/grafana/grafana/pkg/registry"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// MigrationsRunner is a simplified version of Runner that is used in the grafana
+// server migrations command. It has the dependencies required to read the database
+// migrations, without connecting to the database or running them.
+type MigrationsRunner struct {
+	Cfg        *setting.Cfg
+	Features   featuremgmt.FeatureToggles
+	Migrations registry.DatabaseMigrator
+}
+
+func NewMigrationsRunner(cfg *setting.Cfg, features featuremgmt.FeatureToggles,
+	migrations registry.DatabaseMigrator,
+) MigrationsRunner {
+	return MigrationsRunner{
+		Cfg:        cfg,
+		Features:   features,
+		Migrations: migrations,
+	}
+}
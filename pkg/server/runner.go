@@ -2,8 +2,10 @@ package server
 
 import (
 	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/registry"
 	"github.com/grafana/grafana/pkg/services/encryption"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/mfa"
 	"github.com/grafana/grafana/pkg/services/secrets"
 	"github.com/grafana/grafana/pkg/services/secrets/manager"
 	"github.com/grafana/grafana/pkg/services/user"
@@ -19,12 +21,14 @@ type Runner struct {
 	SecretsService    *manager.SecretsService
 	SecretsMigrator   secrets.Migrator
 	UserService       user.Service
+	MFAService        mfa.Service
+	Migrations        registry.DatabaseMigrator
 }
 
 func NewRunner(cfg *setting.Cfg, sqlStore db.DB, settingsProvider setting.Provider,
 	encryptionService encryption.Internal, features featuremgmt.FeatureToggles,
 	secretsService *manager.SecretsService, secretsMigrator secrets.Migrator,
-	userService user.Service,
+	userService user.Service, mfaService mfa.Service, migrations registry.DatabaseMigrator,
 ) Runner {
 	return Runner{
 		Cfg:               cfg,
@@ -35,5 +39,7 @@ func NewRunner(cfg *setting.Cfg, sqlStore db.DB, settingsProvider setting.Provid
 		SecretsMigrator:   secretsMigrator,
 		Features:          features,
 		UserService:       userService,
+		MFAService:        mfaService,
+		Migrations:        migrations,
 	}
 }
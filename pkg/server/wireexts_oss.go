@@ -137,6 +137,17 @@ var wireExtsBaseCLISet = wire.NewSet(
 	licensing.ProvideService, wire.Bind(new(licensing.Licensing), new(*licensing.OSSLicensingService)),
 )
 
+// The wireExtsMigrationsSet is the set of dependencies for the OSS migrations
+// command, which reads the database migrations without starting Grafana.
+var wireExtsMigrationsSet = wire.NewSet(
+	NewMigrationsRunner,
+
+	featuremgmt.ProvideManagerService,
+	featuremgmt.ProvideToggles,
+	migrations.ProvideOSSMigrations,
+	wire.Bind(new(registry.DatabaseMigrator), new(*migrations.OSSMigrations)),
+)
+
 // wireModuleServerSet is a wire set for the ModuleServer.
 var wireExtsModuleServerSet = wire.NewSet(
 	NewModule,
@@ -27,6 +27,7 @@ const (
 	ClientRender      = "auth.client.render"
 	ClientSession     = "auth.client.session"
 	ClientForm        = "auth.client.form"
+	ClientMFA         = "auth.client.mfa"
 	ClientProxy       = "auth.client.proxy"
 	ClientSAML        = "auth.client.saml"
 )
@@ -49,7 +50,7 @@ type ClientParams struct {
 	EnableUser bool
 	// FetchSyncedUser ensure that all required information is added to the identity
 	FetchSyncedUser bool
-	// SyncTeams will sync the groups from identity to teams in grafana, enterprise only feature
+	// SyncTeams will sync the groups from identity to teams in grafana
 	SyncTeams bool
 	// SyncOrgRoles will sync the roles from the identity to orgs in grafana
 	SyncOrgRoles bool
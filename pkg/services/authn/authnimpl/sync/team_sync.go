@@ -0,0 +1,93 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/authlib/claims"
+
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/teamsync"
+)
+
+// teamSyncCacheTTL is how long the teams of a user are not synced again when its groups don't change.
+// Clients such as JWT and the auth proxy authenticate every request, logins are always synced.
+const teamSyncCacheTTL = 5 * time.Minute
+
+func ProvideTeamSync(teamSyncService teamsync.Service, tracer tracing.Tracer) *TeamSync {
+	return &TeamSync{
+		teamSyncService: teamSyncService,
+		log:             log.New("team.sync"),
+		tracer:          tracer,
+		cache:           localcache.New(teamSyncCacheTTL, 2*teamSyncCacheTTL),
+	}
+}
+
+type TeamSync struct {
+	teamSyncService teamsync.Service
+	log             log.Logger
+	tracer          tracing.Tracer
+	cache           *localcache.CacheService
+}
+
+// SyncTeamsHook adds users to the teams mapped to their external groups and removes them from the teams of the groups they left
+func (s *TeamSync) SyncTeamsHook(ctx context.Context, id *authn.Identity, r *authn.Request) error {
+	ctx, span := s.tracer.Start(ctx, "team.sync.SyncTeamsHook")
+	defer span.End()
+
+	// clients don't request a team sync when the external user info has SkipTeamSync set
+	if !id.ClientParams.SyncTeams {
+		return nil
+	}
+
+	ctxLogger := s.log.FromContext(ctx).New("id", id.ID, "login", id.Login)
+
+	if !id.IsIdentityType(claims.TypeUser) {
+		ctxLogger.Warn("Failed to sync teams, invalid namespace for identity", "type", id.GetIdentityType())
+		return nil
+	}
+
+	userID, err := id.GetInternalID()
+	if err != nil {
+		ctxLogger.Warn("Failed to sync teams, invalid ID for identity", "type", id.GetIdentityType(), "err", err)
+		return nil
+	}
+
+	cacheKey := teamSyncCacheKey(userID, id.Groups)
+	isLogin := r != nil && r.GetMeta(authn.MetaKeyIsLogin) != ""
+	if _, ok := s.cache.Get(cacheKey); ok && !isLogin {
+		return nil
+	}
+
+	ctxLogger.Debug("Syncing teams", "groups", id.Groups)
+	result, err := s.teamSyncService.SyncUserTeams(ctx, &teamsync.SyncUserTeamsCommand{UserID: userID, Groups: id.Groups})
+	if err != nil {
+		// a failed sync doesn't prevent the user from signing in with its current teams
+		ctxLogger.Error("Failed to sync teams", "error", err)
+		return nil
+	}
+
+	if len(result.Added) > 0 || len(result.Removed) > 0 {
+		ctxLogger.Info("Synced teams", "added", len(result.Added), "removed", len(result.Removed))
+	}
+
+	s.cache.SetDefault(cacheKey, true)
+	return nil
+}
+
+func teamSyncCacheKey(userID int64, groups []string) string {
+	sorted := make([]string, len(groups))
+	copy(sorted, groups)
+	sort.Strings(sorted)
+
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return "team-sync-" + strconv.FormatInt(userID, 10) + "-" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,84 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/teamsync"
+	"github.com/grafana/grafana/pkg/services/teamsync/teamsynctest"
+)
+
+func TestTeamSync_SyncTeamsHook(t *testing.T) {
+	userIdentity := func(groups ...string) *authn.Identity {
+		return &authn.Identity{
+			ID:           "1",
+			Type:         claims.TypeUser,
+			Login:        "test",
+			Groups:       groups,
+			ClientParams: authn.ClientParams{SyncTeams: true},
+		}
+	}
+	loginRequest := func() *authn.Request {
+		r := &authn.Request{}
+		r.SetMeta(authn.MetaKeyIsLogin, "true")
+		return r
+	}
+
+	t.Run("should skip sync when not requested by the client", func(t *testing.T) {
+		fake := &teamsynctest.FakeService{ExpectedSyncResult: &teamsync.SyncResult{}}
+		s := ProvideTeamSync(fake, tracing.InitializeTracerForTest())
+
+		id := userIdentity("engineering")
+		id.ClientParams.SyncTeams = false
+		require.NoError(t, s.SyncTeamsHook(context.Background(), id, loginRequest()))
+		assert.Empty(t, fake.SyncUserTeamsCalls)
+	})
+
+	t.Run("should skip sync when identity is not a user", func(t *testing.T) {
+		fake := &teamsynctest.FakeService{ExpectedSyncResult: &teamsync.SyncResult{}}
+		s := ProvideTeamSync(fake, tracing.InitializeTracerForTest())
+
+		id := userIdentity("engineering")
+		id.Type = claims.TypeServiceAccount
+		require.NoError(t, s.SyncTeamsHook(context.Background(), id, loginRequest()))
+		assert.Empty(t, fake.SyncUserTeamsCalls)
+	})
+
+	t.Run("should sync teams on login", func(t *testing.T) {
+		fake := &teamsynctest.FakeService{ExpectedSyncResult: &teamsync.SyncResult{}}
+		s := ProvideTeamSync(fake, tracing.InitializeTracerForTest())
+
+		require.NoError(t, s.SyncTeamsHook(context.Background(), userIdentity("engineering"), loginRequest()))
+		require.NoError(t, s.SyncTeamsHook(context.Background(), userIdentity("engineering"), loginRequest()))
+		require.Len(t, fake.SyncUserTeamsCalls, 2)
+		assert.Equal(t, &teamsync.SyncUserTeamsCommand{UserID: 1, Groups: []string{"engineering"}}, fake.SyncUserTeamsCalls[0])
+	})
+
+	t.Run("should only sync again on requests when groups change", func(t *testing.T) {
+		fake := &teamsynctest.FakeService{ExpectedSyncResult: &teamsync.SyncResult{}}
+		s := ProvideTeamSync(fake, tracing.InitializeTracerForTest())
+
+		require.NoError(t, s.SyncTeamsHook(context.Background(), userIdentity("a", "b"), &authn.Request{}))
+		require.NoError(t, s.SyncTeamsHook(context.Background(), userIdentity("b", "a"), &authn.Request{}))
+		assert.Len(t, fake.SyncUserTeamsCalls, 1)
+
+		require.NoError(t, s.SyncTeamsHook(context.Background(), userIdentity("a"), &authn.Request{}))
+		assert.Len(t, fake.SyncUserTeamsCalls, 2)
+	})
+
+	t.Run("should not fail authentication when sync fails", func(t *testing.T) {
+		fake := &teamsynctest.FakeService{ExpectedErr: errors.New("boom")}
+		s := ProvideTeamSync(fake, tracing.InitializeTracerForTest())
+
+		require.NoError(t, s.SyncTeamsHook(context.Background(), userIdentity("engineering"), &authn.Request{}))
+		require.NoError(t, s.SyncTeamsHook(context.Background(), userIdentity("engineering"), &authn.Request{}))
+		assert.Len(t, fake.SyncUserTeamsCalls, 2)
+	})
+}
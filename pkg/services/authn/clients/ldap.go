@@ -120,7 +120,7 @@ func (c *LDAP) identityFromLDAPInfo(orgID int64, info *login.ExternalUserInfo) *
 		Groups:          info.Groups,
 		ClientParams: authn.ClientParams{
 			SyncUser:        true,
-			SyncTeams:       true,
+			SyncTeams:       !info.SkipTeamSync,
 			EnableUser:      true,
 			FetchSyncedUser: true,
 			SyncPermissions: true,
@@ -74,6 +74,42 @@ func TestLDAP_AuthenticateProxy(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc:     "should not sync teams when ldap service skips team sync",
+			username: "test",
+			expectedLDAPInfo: &login.ExternalUserInfo{
+				AuthModule:   login.LDAPAuthModule,
+				AuthId:       "123",
+				Email:        "test@test.com",
+				Login:        "test",
+				Name:         "test test",
+				Groups:       []string{"1", "2"},
+				OrgRoles:     map[int64]org.RoleType{1: org.RoleViewer},
+				SkipTeamSync: true,
+			},
+			expectedIdentity: &authn.Identity{
+				OrgID:           1,
+				OrgRoles:        map[int64]org.RoleType{1: org.RoleViewer},
+				Login:           "test",
+				Name:            "test test",
+				Email:           "test@test.com",
+				AuthenticatedBy: login.LDAPAuthModule,
+				AuthID:          "123",
+				Groups:          []string{"1", "2"},
+				ClientParams: authn.ClientParams{
+					SyncUser:        true,
+					SyncTeams:       false,
+					EnableUser:      true,
+					FetchSyncedUser: true,
+					SyncOrgRoles:    true,
+					SyncPermissions: true,
+					LookUpParams: login.UserLookupParams{
+						Email: strPtr("test@test.com"),
+						Login: strPtr("test"),
+					},
+				},
+			},
+		},
 		{
 			desc:            "should return error when user is not found",
 			username:        "test",
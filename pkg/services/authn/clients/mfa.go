@@ -0,0 +1,104 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+var (
+	errBadMFAForm    = errutil.BadRequest("mfa-auth.invalid", errutil.WithPublicMessage("bad multi-factor authentication data"))
+	errMFAAuthFailed = errutil.Unauthorized("mfa-auth.failed", errutil.WithPublicMessage("Invalid verification code"))
+)
+
+var _ authn.Client = new(MFA)
+
+func ProvideMFA(cfg *setting.Cfg, mfaService mfa.Service, loginAttempts loginattempt.Service, userService user.Service) *MFA {
+	return &MFA{cfg, mfaService, loginAttempts, userService}
+}
+
+// MFA completes the challenge issued by the password client to users with a second factor
+type MFA struct {
+	cfg           *setting.Cfg
+	mfaService    mfa.Service
+	loginAttempts loginattempt.Service
+	userService   user.Service
+}
+
+type mfaForm struct {
+	Challenge string `json:"challenge" binding:"Required"`
+	Code      string `json:"code" binding:"Required"`
+}
+
+func (c *MFA) Name() string {
+	return authn.ClientMFA
+}
+
+func (c *MFA) Authenticate(ctx context.Context, r *authn.Request) (*authn.Identity, error) {
+	form := mfaForm{}
+	if err := web.Bind(r.HTTPRequest, &form); err != nil {
+		return nil, errBadMFAForm.Errorf("failed to parse request: %w", err)
+	}
+
+	userID, err := c.mfaService.VerifyChallenge(ctx, form.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	usr, err := c.userService.GetByID(ctx, &user.GetUserByIDQuery{ID: userID})
+	if err != nil {
+		return nil, errMFAAuthFailed.Errorf("failed to get user %d: %w", userID, err)
+	}
+
+	r.SetMeta(authn.MetaKeyUsername, usr.Login)
+	r.SetMeta(authn.MetaKeyAuthModule, "grafana")
+
+	// codes share the brute force protection of passwords
+	ok, err := c.loginAttempts.Validate(ctx, usr.Login, remoteAddr(c.cfg, r))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errMFAAuthFailed.Errorf("too many consecutive incorrect login attempts for user - login for user temporarily blocked")
+	}
+
+	status, err := c.mfaService.GetStatus(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// users required to use a second factor confirm their enrollment while logging in
+	if status.Enabled {
+		err = c.mfaService.Verify(ctx, userID, form.Code)
+	} else {
+		err = c.mfaService.ConfirmEnrollment(ctx, userID, form.Code)
+	}
+	if err != nil {
+		if errors.Is(err, mfa.ErrInvalidCode) {
+			_ = c.loginAttempts.Add(ctx, usr.Login, remoteAddr(c.cfg, r))
+		}
+		return nil, errMFAAuthFailed.Errorf("failed to verify second factor: %w", err)
+	}
+
+	return &authn.Identity{
+		ID:              strconv.FormatInt(userID, 10),
+		Type:            claims.TypeUser,
+		OrgID:           r.OrgID,
+		ClientParams:    authn.ClientParams{FetchSyncedUser: true, SyncPermissions: true},
+		AuthenticatedBy: login.PasswordAuthModule,
+	}, nil
+}
+
+func (c *MFA) IsEnabled() bool {
+	return c.mfaService.IsEnabled()
+}
@@ -0,0 +1,100 @@
+package clients
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/loginattempt/loginattempttest"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/mfa/mfatest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestMFA_Authenticate(t *testing.T) {
+	type testCase struct {
+		desc             string
+		body             string
+		blockLogin       bool
+		challengeErr     error
+		verifyErr        error
+		expectedErr      error
+		expectedAdd      bool
+		expectedIdentity *authn.Identity
+	}
+
+	tests := []testCase{
+		{
+			desc: "should authenticate user with a valid challenge and code",
+			body: `{"challenge": "token", "code": "123456"}`,
+			expectedIdentity: &authn.Identity{
+				ID:              "1",
+				Type:            claims.TypeUser,
+				OrgID:           1,
+				ClientParams:    authn.ClientParams{FetchSyncedUser: true, SyncPermissions: true},
+				AuthenticatedBy: login.PasswordAuthModule,
+			},
+		},
+		{
+			desc:        "should fail for missing code",
+			body:        `{"challenge": "token"}`,
+			expectedErr: errBadMFAForm,
+		},
+		{
+			desc:         "should fail for invalid challenge",
+			body:         `{"challenge": "token", "code": "123456"}`,
+			challengeErr: mfa.ErrInvalidChallenge.Errorf("expired"),
+			expectedErr:  mfa.ErrInvalidChallenge,
+		},
+		{
+			desc:        "should fail when login is blocked by too many attempts",
+			body:        `{"challenge": "token", "code": "123456"}`,
+			blockLogin:  true,
+			expectedErr: errMFAAuthFailed,
+		},
+		{
+			desc:        "should record a failed attempt for invalid code",
+			body:        `{"challenge": "token", "code": "123456"}`,
+			verifyErr:   mfa.ErrInvalidCode.Errorf("invalid code"),
+			expectedErr: errMFAAuthFailed,
+			expectedAdd: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			loginAttempts := &loginattempttest.MockLoginAttemptService{ExpectedValid: !tt.blockLogin}
+			mfaService := &mfatest.FakeService{
+				ExpectedUserID:    1,
+				ExpectedStatus:    &mfa.Status{Enabled: true},
+				ExpectedErr:       tt.challengeErr,
+				ExpectedVerifyErr: tt.verifyErr,
+			}
+			userService := &usertest.FakeUserService{ExpectedUser: &user.User{ID: 1, Login: "test"}}
+
+			c := ProvideMFA(setting.NewCfg(), mfaService, loginAttempts, userService)
+			identity, err := c.Authenticate(context.Background(), &authn.Request{OrgID: 1, HTTPRequest: &http.Request{
+				Header: map[string][]string{"Content-Type": {"application/json"}},
+				Body:   io.NopCloser(strings.NewReader(tt.body)),
+			}})
+
+			assert.Equal(t, tt.expectedAdd, loginAttempts.AddCalled)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, identity)
+				return
+			}
+			assert.NoError(t, err)
+			assert.EqualValues(t, *tt.expectedIdentity, *identity)
+		})
+	}
+}
@@ -7,23 +7,28 @@ import (
 	"github.com/grafana/grafana/pkg/apimachinery/errutil"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/authn"
+	"github.com/grafana/grafana/pkg/services/login"
 	"github.com/grafana/grafana/pkg/services/loginattempt"
-	"github.com/grafana/grafana/pkg/web"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 var (
 	errInvalidPassword    = errutil.Unauthorized("password-auth.invalid", errutil.WithPublicMessage("Invalid password or username"))
 	errPasswordAuthFailed = errutil.Unauthorized("password-auth.failed", errutil.WithPublicMessage("Invalid username or password"))
+	errPasswordOnlyAuth   = errutil.Unauthorized("password-auth.mfa-required", errutil.WithPublicMessage("Multi-factor authentication is required, use the login form or a service account token"))
 )
 
 var _ authn.PasswordClient = new(Password)
 
-func ProvidePassword(loginAttempts loginattempt.Service, clients ...authn.PasswordClient) *Password {
-	return &Password{loginAttempts, clients, log.New("authn.password")}
+func ProvidePassword(cfg *setting.Cfg, loginAttempts loginattempt.Service, mfaService mfa.Service, clients ...authn.PasswordClient) *Password {
+	return &Password{cfg, loginAttempts, mfaService, clients, log.New("authn.password")}
 }
 
 type Password struct {
+	cfg           *setting.Cfg
 	loginAttempts loginattempt.Service
+	mfaService    mfa.Service
 	clients       []authn.PasswordClient
 	log           log.Logger
 }
@@ -31,7 +36,7 @@ type Password struct {
 func (c *Password) AuthenticatePassword(ctx context.Context, r *authn.Request, username, password string) (*authn.Identity, error) {
 	r.SetMeta(authn.MetaKeyUsername, username)
 
-	ok, err := c.loginAttempts.Validate(ctx, username)
+	ok, err := c.loginAttempts.Validate(ctx, username, remoteAddr(c.cfg, r))
 	if err != nil {
 		return nil, err
 	}
@@ -53,12 +58,52 @@ func (c *Password) AuthenticatePassword(ctx context.Context, r *authn.Request, u
 			continue
 		}
 
+		if err := c.challenge(ctx, r, identity); err != nil {
+			return nil, err
+		}
+
 		return identity, nil
 	}
 
 	if errors.Is(clientErrs, errInvalidPassword) {
-		_ = c.loginAttempts.Add(ctx, username, web.RemoteAddr(r.HTTPRequest))
+		_ = c.loginAttempts.Add(ctx, username, remoteAddr(c.cfg, r))
 	}
 
 	return nil, errPasswordAuthFailed.Errorf("failed to authenticate identity: %w", clientErrs)
 }
+
+// challenge fails the authentication of Grafana users who need a second factor.
+// Logins get a challenge to complete with a TOTP code before the session is created,
+// other requests can't use a password alone.
+func (c *Password) challenge(ctx context.Context, r *authn.Request, identity *authn.Identity) error {
+	if identity.AuthenticatedBy != login.PasswordAuthModule {
+		return nil
+	}
+
+	userID, err := identity.GetInternalID()
+	if err != nil {
+		return err
+	}
+
+	challenge, err := c.mfaService.Challenge(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if challenge == nil {
+		return nil
+	}
+
+	if r.GetMeta(authn.MetaKeyIsLogin) != "true" {
+		return errPasswordOnlyAuth.Errorf("user %d needs a second factor to authenticate", userID)
+	}
+
+	return mfa.ChallengeRequiredError(userID, challenge)
+}
+
+// remoteAddr returns the address a login comes from, used by the brute force login protection
+func remoteAddr(cfg *setting.Cfg, r *authn.Request) string {
+	if r.HTTPRequest == nil {
+		return ""
+	}
+	return loginattempt.RemoteAddr(r.HTTPRequest, cfg.BruteForceLoginProtection.TrustedProxies)
+}
@@ -2,6 +2,8 @@ package clients
 
 import (
 	"context"
+	"net"
+	"net/http"
 	"testing"
 
 	"github.com/grafana/authlib/claims"
@@ -9,7 +11,11 @@ import (
 
 	"github.com/grafana/grafana/pkg/services/authn"
 	"github.com/grafana/grafana/pkg/services/authn/authntest"
+	"github.com/grafana/grafana/pkg/services/login"
 	"github.com/grafana/grafana/pkg/services/loginattempt/loginattempttest"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/mfa/mfatest"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 func TestPassword_AuthenticatePassword(t *testing.T) {
@@ -19,6 +25,7 @@ func TestPassword_AuthenticatePassword(t *testing.T) {
 		password         string
 		req              *authn.Request
 		blockLogin       bool
+		challenge        *mfa.Challenge
 		clients          []authn.PasswordClient
 		expectedErr      error
 		expectedIdentity *authn.Identity
@@ -64,11 +71,38 @@ func TestPassword_AuthenticatePassword(t *testing.T) {
 			clients:     []authn.PasswordClient{authntest.FakePasswordClient{ExpectedErr: errIdentityNotFound}, authntest.FakePasswordClient{ExpectedErr: errIdentityNotFound}},
 			expectedErr: errPasswordAuthFailed,
 		},
+		{
+			desc:             "should not challenge users authenticated by other clients",
+			username:         "test",
+			password:         "test",
+			req:              loginRequest(),
+			challenge:        &mfa.Challenge{Token: "token"},
+			clients:          []authn.PasswordClient{authntest.FakePasswordClient{ExpectedIdentity: &authn.Identity{ID: "1", Type: claims.TypeUser, AuthenticatedBy: login.LDAPAuthModule}}},
+			expectedIdentity: &authn.Identity{ID: "1", Type: claims.TypeUser, AuthenticatedBy: login.LDAPAuthModule},
+		},
+		{
+			desc:        "should challenge login of user with a second factor",
+			username:    "test",
+			password:    "test",
+			req:         loginRequest(),
+			challenge:   &mfa.Challenge{Token: "token"},
+			clients:     []authn.PasswordClient{authntest.FakePasswordClient{ExpectedIdentity: &authn.Identity{ID: "1", Type: claims.TypeUser, AuthenticatedBy: login.PasswordAuthModule}}},
+			expectedErr: mfa.ErrChallengeRequired,
+		},
+		{
+			desc:        "should fail basic auth of user with a second factor",
+			username:    "test",
+			password:    "test",
+			req:         &authn.Request{},
+			challenge:   &mfa.Challenge{Token: "token"},
+			clients:     []authn.PasswordClient{authntest.FakePasswordClient{ExpectedIdentity: &authn.Identity{ID: "1", Type: claims.TypeUser, AuthenticatedBy: login.PasswordAuthModule}}},
+			expectedErr: errPasswordOnlyAuth,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.desc, func(t *testing.T) {
-			c := ProvidePassword(loginattempttest.FakeLoginAttemptService{ExpectedValid: !tt.blockLogin}, tt.clients...)
+			c := ProvidePassword(setting.NewCfg(), loginattempttest.FakeLoginAttemptService{ExpectedValid: !tt.blockLogin}, &mfatest.FakeService{ExpectedChallenge: tt.challenge}, tt.clients...)
 
 			identity, err := c.AuthenticatePassword(context.Background(), tt.req, tt.username, tt.password)
 			if tt.expectedErr != nil {
@@ -81,3 +115,60 @@ func TestPassword_AuthenticatePassword(t *testing.T) {
 		})
 	}
 }
+
+func TestPassword_LoginAttempts(t *testing.T) {
+	t.Run("should validate and record login attempts with the remote address", func(t *testing.T) {
+		loginAttempts := &loginattempttest.MockLoginAttemptService{ExpectedValid: true}
+		c := ProvidePassword(setting.NewCfg(), loginAttempts, &mfatest.FakeService{}, authntest.FakePasswordClient{ExpectedErr: errInvalidPassword})
+
+		req := &authn.Request{HTTPRequest: &http.Request{Header: http.Header{}, RemoteAddr: "192.168.1.10:5000"}}
+		_, err := c.AuthenticatePassword(context.Background(), req, "test", "test")
+		assert.ErrorIs(t, err, errPasswordAuthFailed)
+		assert.True(t, loginAttempts.ValidateCalled)
+		assert.Equal(t, "192.168.1.10", loginAttempts.ValidatedIPAddress)
+		assert.True(t, loginAttempts.AddCalled)
+	})
+
+	t.Run("should ignore forwarded address of untrusted client", func(t *testing.T) {
+		loginAttempts := &loginattempttest.MockLoginAttemptService{ExpectedValid: true}
+		c := ProvidePassword(setting.NewCfg(), loginAttempts, &mfatest.FakeService{}, authntest.FakePasswordClient{ExpectedErr: errInvalidPassword})
+
+		req := &authn.Request{HTTPRequest: &http.Request{Header: http.Header{}, RemoteAddr: "192.168.1.10:5000"}}
+		req.HTTPRequest.Header.Set("X-Forwarded-For", "203.0.113.7")
+		req.HTTPRequest.Header.Set("X-Real-IP", "203.0.113.8")
+		_, err := c.AuthenticatePassword(context.Background(), req, "test", "test")
+		assert.ErrorIs(t, err, errPasswordAuthFailed)
+		assert.Equal(t, "192.168.1.10", loginAttempts.ValidatedIPAddress)
+	})
+
+	t.Run("should use forwarded address of trusted proxy", func(t *testing.T) {
+		loginAttempts := &loginattempttest.MockLoginAttemptService{ExpectedValid: true}
+		cfg := setting.NewCfg()
+		_, proxies, _ := net.ParseCIDR("192.168.1.0/24")
+		cfg.BruteForceLoginProtection.TrustedProxies = []*net.IPNet{proxies}
+		c := ProvidePassword(cfg, loginAttempts, &mfatest.FakeService{}, authntest.FakePasswordClient{ExpectedErr: errInvalidPassword})
+
+		req := &authn.Request{HTTPRequest: &http.Request{Header: http.Header{}, RemoteAddr: "192.168.1.10:5000"}}
+		req.HTTPRequest.Header.Set("X-Forwarded-For", "203.0.113.7")
+		_, err := c.AuthenticatePassword(context.Background(), req, "test", "test")
+		assert.ErrorIs(t, err, errPasswordAuthFailed)
+		assert.Equal(t, "203.0.113.7", loginAttempts.ValidatedIPAddress)
+	})
+
+	t.Run("should block login from a locked out address", func(t *testing.T) {
+		loginAttempts := &loginattempttest.MockLoginAttemptService{ExpectedValid: false}
+		c := ProvidePassword(setting.NewCfg(), loginAttempts, &mfatest.FakeService{}, authntest.FakePasswordClient{ExpectedIdentity: &authn.Identity{ID: "1", Type: claims.TypeUser}})
+
+		req := &authn.Request{HTTPRequest: &http.Request{Header: http.Header{}, RemoteAddr: "192.168.1.10:5000"}}
+		identity, err := c.AuthenticatePassword(context.Background(), req, "test", "test")
+		assert.ErrorIs(t, err, errPasswordAuthFailed)
+		assert.Nil(t, identity)
+		assert.False(t, loginAttempts.AddCalled)
+	})
+}
+
+func loginRequest() *authn.Request {
+	r := &authn.Request{}
+	r.SetMeta(authn.MetaKeyIsLogin, "true")
+	return r
+}
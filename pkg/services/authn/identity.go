@@ -316,5 +316,6 @@ func (i *Identity) ExternalUserInfo() login.ExternalUserInfo {
 		OrgRoles:       i.OrgRoles,
 		IsGrafanaAdmin: i.IsGrafanaAdmin,
 		IsDisabled:     i.IsDisabled,
+		SkipTeamSync:   !i.ClientParams.SyncTeams,
 	}
 }
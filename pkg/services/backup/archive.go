@@ -0,0 +1,308 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+type archiveWriter struct {
+	enc io.WriteCloser
+	gz  *gzip.Writer
+	tw  *tar.Writer
+}
+
+// newArchiveWriter writes a gzipped tar encrypted with a passphrase, a scrypt recipient of age
+func newArchiveWriter(w io.Writer, passphrase string) (*archiveWriter, error) {
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := age.Encrypt(w, recipient)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(enc)
+	return &archiveWriter{enc: enc, gz: gz, tw: tar.NewWriter(gz)}, nil
+}
+
+func (a *archiveWriter) writeJSON(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := a.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err = io.Copy(a.tw, bytes.NewReader(data))
+	return err
+}
+
+func (a *archiveWriter) writeFile(name string, filePath string) error {
+	f, err := os.Open(filepath.Clean(filePath))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(a.tw, f)
+	return err
+}
+
+// writeDir adds the directories and regular files under root, the symbolic links are skipped
+func (a *archiveWriter) writeDir(name string, root string) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		entry := path.Join(name, filepath.ToSlash(rel))
+
+		switch {
+		case d.IsDir():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			hdr, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			hdr.Name = entry + "/"
+			return a.tw.WriteHeader(hdr)
+		case d.Type().IsRegular():
+			return a.writeFile(entry, p)
+		default:
+			return nil
+		}
+	})
+}
+
+func (a *archiveWriter) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	if err := a.gz.Close(); err != nil {
+		return err
+	}
+	return a.enc.Close()
+}
+
+func openArchive(r io.Reader, passphrase string) (*tar.Reader, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase.Errorf("empty passphrase")
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := age.Decrypt(r, identity)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, ErrInvalidPassphrase.Errorf("failed to decrypt the backup: %w", err)
+		}
+		return nil, ErrInvalidArchive.Errorf("failed to decrypt the backup: %w", err)
+	}
+	gz, err := gzip.NewReader(dec)
+	if err != nil {
+		return nil, ErrInvalidArchive.Errorf("failed to decompress the backup: %w", err)
+	}
+	return tar.NewReader(gz), nil
+}
+
+// directory is a directory of files of the instance included in the backups
+type directory struct {
+	name string
+	path string
+	// replace removes the files that aren't in the backup on restore, for the directories only Grafana writes to
+	replace bool
+}
+
+// directories returns the directories of files of the instance
+func (s *Service) directories() []directory {
+	apiserverCfg := s.cfg.SectionWithEnvOverrides("grafana-apiserver")
+	dirs := []directory{
+		{name: "unified-storage", path: apiserverCfg.Key("storage_path").MustString(filepath.Join(s.cfg.DataPath, "grafana-apiserver")), replace: true},
+	}
+	if u, err := url.Parse(apiserverCfg.Key("blob_url").MustString("")); err == nil && u.Scheme == "file" && u.Path != "" {
+		dirs = append(dirs, directory{name: "blobs", path: u.Path, replace: true})
+	}
+	dirs = append(dirs, directory{name: "plugins", path: s.cfg.PluginsPath})
+
+	configured := make([]directory, 0, len(dirs))
+	for _, dir := range dirs {
+		if dir.path != "" {
+			configured = append(configured, dir)
+		}
+	}
+	return configured
+}
+
+func exists(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+func isDirFile(name string) bool {
+	return strings.HasPrefix(name, filesDir)
+}
+
+// fileRestore extracts the files of the backup to the directories of the instance they were taken from. The files
+// are extracted to temporary directories next to them first, and only moved in place by commit once the whole
+// backup has been read.
+type fileRestore struct {
+	dirs map[string]directory
+	// staged are the temporary directories the files are extracted to, by directory name
+	staged  map[string]string
+	skipped map[string]bool
+	log     log.Logger
+}
+
+func newFileRestore(dirs []directory, logger log.Logger) *fileRestore {
+	r := &fileRestore{
+		dirs:    make(map[string]directory, len(dirs)),
+		staged:  map[string]string{},
+		skipped: map[string]bool{},
+		log:     logger,
+	}
+	for _, dir := range dirs {
+		r.dirs[dir.name] = dir
+	}
+	return r
+}
+
+func (r *fileRestore) extract(hdr *tar.Header, content io.Reader) error {
+	name, rel, _ := strings.Cut(strings.TrimPrefix(hdr.Name, filesDir), "/")
+	dir, ok := r.dirs[name]
+	if !ok {
+		if !r.skipped[name] {
+			r.log.Warn("Skipping the files of a directory that isn't configured", "directory", name)
+			r.skipped[name] = true
+		}
+		return nil
+	}
+
+	root, ok := r.staged[name]
+	if !ok {
+		// on the same file system than the directory, so that the files can be renamed in place
+		parent := filepath.Dir(filepath.Clean(dir.path))
+		if err := os.MkdirAll(parent, 0o750); err != nil {
+			return err
+		}
+		staged, err := os.MkdirTemp(parent, "."+filepath.Base(dir.path)+".restore-")
+		if err != nil {
+			return err
+		}
+		if err := os.Chmod(staged, 0o750); err != nil {
+			return err
+		}
+		r.staged[name] = staged
+		root = staged
+	}
+
+	target := filepath.Join(root, filepath.FromSlash(rel))
+	if target != root && !strings.HasPrefix(target, root+string(os.PathSeparator)) {
+		return ErrInvalidArchive.Errorf("the file %s is outside of its directory", hdr.Name)
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(target, 0o750)
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fs.FileMode(hdr.Mode).Perm())
+		if err != nil {
+			return err
+		}
+		// the size of the files is bounded by the headers the backup was written with
+		if _, err := io.CopyN(f, content, hdr.Size); err != nil {
+			_ = f.Close()
+			return err
+		}
+		return f.Close()
+	default:
+		return nil
+	}
+}
+
+// commit moves the extracted files to their directories. The directories that are replaced are swapped with the
+// extracted ones, the files of the other directories are added to the existing ones.
+func (r *fileRestore) commit() error {
+	for name, staged := range r.staged {
+		dir := r.dirs[name]
+		if dir.replace {
+			if err := os.RemoveAll(dir.path); err != nil {
+				return err
+			}
+			if err := os.Rename(staged, dir.path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := filepath.WalkDir(staged, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(staged, p)
+			if err != nil {
+				return err
+			}
+			target := filepath.Join(dir.path, rel)
+			if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+				return err
+			}
+			return os.Rename(p, target)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return r.cleanup()
+}
+
+// cleanup removes the temporary directories that are left
+func (r *fileRestore) cleanup() error {
+	var errs []error
+	for _, staged := range r.staged {
+		errs = append(errs, os.RemoveAll(staged))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,277 @@
+// Package backup writes and restores archives of a Grafana instance: the database, the files of unified storage and
+// the plugins. The archive is a gzipped tar encrypted with a passphrase. The data keys of envelope encryption are
+// stored decrypted in it and encrypted again on restore, so that a backup can be restored on an instance with
+// another secret_key or KMS provider.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/secrets/manager"
+	"github.com/grafana/grafana/pkg/services/sqlstore/dbcopy"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// FormatVersion is the version of the layout of the archives, archives of a newer version can't be restored
+const FormatVersion = 1
+
+const (
+	manifestFile = "manifest.json"
+	dataKeysFile = "secrets/data_keys.json"
+	databaseDir  = "database/"
+	filesDir     = "files/"
+)
+
+var (
+	ErrEmptyPassphrase   = errutil.BadRequest("backup.emptyPassphrase", errutil.WithPublicMessage("A passphrase is required to encrypt the backup"))
+	ErrInvalidPassphrase = errutil.BadRequest("backup.invalidPassphrase", errutil.WithPublicMessage("The passphrase doesn't decrypt the backup"))
+	ErrInvalidArchive    = errutil.BadRequest("backup.invalidArchive", errutil.WithPublicMessage("The file isn't a valid Grafana backup"))
+	ErrUnsupportedFormat = errutil.BadRequest("backup.unsupportedFormat")
+	ErrSchemaMismatch    = errutil.BadRequest("backup.schemaMismatch")
+	ErrMissingProvider   = errutil.Internal("backup.missingProvider")
+)
+
+// Manifest describes the content of a backup, it's the first file of the archive
+type Manifest struct {
+	FormatVersion  int       `json:"formatVersion"`
+	GrafanaVersion string    `json:"grafanaVersion"`
+	CreatedAt      time.Time `json:"createdAt"`
+	DatabaseType   string    `json:"databaseType"`
+	// Migrations are the ids of the migrations applied to the database, the database a backup is restored to must
+	// have the same ones
+	Migrations []string `json:"migrations"`
+	// Tables are in the order their rows are restored, the tables referenced by foreign keys first
+	Tables []Table `json:"tables"`
+	// DataKeys is the number of data keys of envelope encryption stored decrypted
+	DataKeys int `json:"dataKeys"`
+	// Directories are the names of the directories of files in the backup
+	Directories []string `json:"directories"`
+}
+
+type Table struct {
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+	Rows    int64    `json:"rows"`
+}
+
+type Column struct {
+	Name string `json:"name"`
+	// Binary values are encoded in base64
+	Binary bool `json:"binary,omitempty"`
+}
+
+type RestoreOptions struct {
+	// SkipFiles only restores the database
+	SkipFiles bool
+}
+
+// keyProviders gives access to the key encryption providers of envelope encryption
+type keyProviders interface {
+	GetProviders() map[secrets.ProviderID]secrets.Provider
+	CurrentProviderID() secrets.ProviderID
+}
+
+type Service struct {
+	cfg      *setting.Cfg
+	sqlStore db.DB
+	keys     keyProviders
+	log      log.Logger
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore db.DB, secretsService *manager.SecretsService) *Service {
+	return &Service{
+		cfg:      cfg,
+		sqlStore: sqlStore,
+		keys:     secretsService,
+		log:      log.New("backup"),
+	}
+}
+
+// Backup writes an archive of the instance encrypted with passphrase to w. The database is read from a consistent
+// snapshot, Grafana can keep running while it's taken.
+func (s *Service) Backup(ctx context.Context, w io.Writer, passphrase string) (*Manifest, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase.Errorf("empty passphrase")
+	}
+
+	tmp, err := os.MkdirTemp("", "grafana-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	manifest := &Manifest{
+		FormatVersion:  FormatVersion,
+		GrafanaVersion: s.cfg.BuildVersion,
+		CreatedAt:      time.Now().UTC(),
+		DatabaseType:   string(s.sqlStore.GetDBType()),
+	}
+
+	// the database is dumped to temporary files first, the size of the files of the archive is written before them
+	dataKeys, err := s.dumpDatabase(ctx, manifest, tmp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump the database: %w", err)
+	}
+	manifest.DataKeys = len(dataKeys)
+
+	var dirs []directory
+	for _, dir := range s.directories() {
+		if exists(dir.path) {
+			dirs = append(dirs, dir)
+			manifest.Directories = append(manifest.Directories, dir.name)
+		}
+	}
+
+	aw, err := newArchiveWriter(w, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if err := aw.writeJSON(manifestFile, manifest); err != nil {
+		return nil, err
+	}
+	if err := aw.writeJSON(dataKeysFile, dataKeys); err != nil {
+		return nil, err
+	}
+	for _, table := range manifest.Tables {
+		if err := aw.writeFile(databaseDir+table.Name+".jsonl", tableFile(tmp, table.Name)); err != nil {
+			return nil, err
+		}
+	}
+	for _, dir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := aw.writeDir(filesDir+dir.name, dir.path); err != nil {
+			return nil, fmt.Errorf("failed to archive the %s files: %w", dir.name, err)
+		}
+	}
+	if err := aw.Close(); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Created backup", "tables", len(manifest.Tables), "dataKeys", manifest.DataKeys, "directories", manifest.Directories)
+	return manifest, nil
+}
+
+// Restore replaces the database and the files of the instance with the content of a backup. The migrations of the
+// database must be the same than the ones of the backup, which is checked before anything is changed, and the
+// database and the files are only replaced once the whole backup has been read. Grafana must be stopped while it
+// runs.
+func (s *Service) Restore(ctx context.Context, r io.Reader, passphrase string, opts RestoreOptions) (*Manifest, error) {
+	ar, err := openArchive(r, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr, err := ar.Next()
+	if err != nil || hdr.Name != manifestFile {
+		return nil, ErrInvalidArchive.Errorf("the backup doesn't start with a manifest")
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(ar).Decode(&manifest); err != nil {
+		return nil, ErrInvalidArchive.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+		return nil, ErrUnsupportedFormat.Errorf("the backup has the format version %d, this Grafana version supports up to %d", manifest.FormatVersion, FormatVersion)
+	}
+
+	tables, err := s.checkSchema(&manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr, err = ar.Next()
+	if err != nil || hdr.Name != dataKeysFile {
+		return nil, ErrInvalidArchive.Errorf("the backup doesn't contain the data keys")
+	}
+	var dataKeys []dataKey
+	if err := json.NewDecoder(ar).Decode(&dataKeys); err != nil {
+		return nil, ErrInvalidArchive.Errorf("invalid data keys: %w", err)
+	}
+	encryptedKeys, err := s.encryptDataKeys(ctx, dataKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// nothing is changed until the whole backup has been read: the rows are written in a transaction and the files
+	// are extracted next to their directories
+	tx, err := dbcopy.Begin(s.sqlStore.GetEngine())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+
+	database := &databaseRestore{
+		tx:            tx,
+		tables:        tables,
+		restored:      map[string]bool{},
+		encryptedKeys: encryptedKeys,
+		keyProvider:   string(s.keys.CurrentProviderID()),
+		log:           s.log,
+	}
+	if err := database.clear(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to empty the database: %w", err)
+	}
+
+	files := newFileRestore(s.directories(), s.log)
+	defer func() {
+		if err := files.cleanup(); err != nil {
+			s.log.Warn("Failed to remove the extracted files of the backup", "error", err)
+		}
+	}()
+
+	for {
+		hdr, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidArchive.Errorf("failed to read the backup: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case isTableFile(hdr.Name):
+			if err := database.restoreTable(&manifest, hdr.Name, ar); err != nil {
+				return nil, err
+			}
+		case isDirFile(hdr.Name):
+			if opts.SkipFiles {
+				continue
+			}
+			if err := files.extract(hdr, ar); err != nil {
+				return nil, err
+			}
+		default:
+			s.log.Warn("Skipping unknown file of the backup", "name", hdr.Name)
+		}
+	}
+
+	if err := database.checkComplete(&manifest); err != nil {
+		return nil, err
+	}
+	if err := database.resetSequences(); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit the restored database: %w", err)
+	}
+
+	if err := files.commit(); err != nil {
+		return nil, fmt.Errorf("the database was restored, but the files couldn't be moved to their directories: %w", err)
+	}
+
+	s.log.Info("Restored backup", "createdAt", manifest.CreatedAt, "grafanaVersion", manifest.GrafanaVersion, "tables", len(manifest.Tables))
+	return &manifest, nil
+}
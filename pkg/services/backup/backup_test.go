@@ -0,0 +1,244 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/secrets/database"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+// fakeProvider encrypts by adding a prefix, the prefix stands for the secret_key of an instance
+type fakeProvider struct {
+	prefix string
+}
+
+func (p fakeProvider) Encrypt(_ context.Context, blob []byte) ([]byte, error) {
+	return append([]byte(p.prefix), blob...), nil
+}
+
+func (p fakeProvider) Decrypt(_ context.Context, blob []byte) ([]byte, error) {
+	if !bytes.HasPrefix(blob, []byte(p.prefix)) {
+		return nil, fmt.Errorf("not encrypted by %s", p.prefix)
+	}
+	return bytes.TrimPrefix(blob, []byte(p.prefix)), nil
+}
+
+type fakeKeyProviders struct {
+	current  secrets.ProviderID
+	provider secrets.Provider
+}
+
+func (f fakeKeyProviders) GetProviders() map[secrets.ProviderID]secrets.Provider {
+	return map[secrets.ProviderID]secrets.Provider{f.current: f.provider}
+}
+
+func (f fakeKeyProviders) CurrentProviderID() secrets.ProviderID {
+	return f.current
+}
+
+func TestIntegrationBackupRestore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	sqlStore, cfg := db.InitTestDBWithCfg(t)
+	cfg.DataPath = t.TempDir()
+	cfg.PluginsPath = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(cfg.PluginsPath, "my-panel"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.PluginsPath, "my-panel", "plugin.json"), []byte(`{"id":"my-panel"}`), 0o600))
+
+	kv := kvstore.ProvideService(sqlStore)
+	require.NoError(t, kv.Set(ctx, 1, "backup", "restored", "before"))
+
+	keys := database.ProvideSecretsStore(sqlStore)
+	require.NoError(t, keys.CreateDataKey(ctx, &secrets.DataKey{
+		Active:        true,
+		Id:            "key-1",
+		Label:         "2024-05-01/root@secretKey.v1",
+		Scope:         "root",
+		Provider:      "secretKey.v1",
+		EncryptedData: []byte("old:data-key"),
+		Created:       time.Now(),
+		Updated:       time.Now(),
+	}))
+
+	s := &Service{
+		cfg:      cfg,
+		sqlStore: sqlStore,
+		keys:     fakeKeyProviders{current: "secretKey.v1", provider: fakeProvider{prefix: "old:"}},
+		log:      log.NewNopLogger(),
+	}
+
+	var archive bytes.Buffer
+	manifest, err := s.Backup(ctx, &archive, "passphrase")
+	require.NoError(t, err)
+	require.Equal(t, FormatVersion, manifest.FormatVersion)
+	require.Equal(t, 1, manifest.DataKeys)
+	require.Equal(t, []string{"plugins"}, manifest.Directories)
+	require.NotEmpty(t, manifest.Migrations)
+	require.NotContains(t, archive.String(), "data-key")
+
+	// the backup is restored on an instance with another secret_key
+	require.NoError(t, kv.Set(ctx, 1, "backup", "restored", "after"))
+	require.NoError(t, kv.Set(ctx, 1, "backup", "removed", "after"))
+	require.NoError(t, os.RemoveAll(filepath.Join(cfg.PluginsPath, "my-panel")))
+	s.keys = fakeKeyProviders{current: "secretKey.v1", provider: fakeProvider{prefix: "new:"}}
+
+	t.Run("should fail with another passphrase", func(t *testing.T) {
+		_, err := s.Restore(ctx, bytes.NewReader(archive.Bytes()), "wrong", RestoreOptions{})
+		require.ErrorIs(t, err, ErrInvalidPassphrase)
+	})
+
+	t.Run("should fail when the archive isn't a backup", func(t *testing.T) {
+		_, err := s.Restore(ctx, strings.NewReader("not a backup"), "passphrase", RestoreOptions{})
+		require.ErrorIs(t, err, ErrInvalidArchive)
+	})
+
+	t.Run("should not change anything when the backup is incomplete", func(t *testing.T) {
+		incomplete := withoutEntry(t, archive.Bytes(), "passphrase", databaseDir+dataKeysTable+".jsonl")
+		_, err := s.Restore(ctx, bytes.NewReader(incomplete), "passphrase", RestoreOptions{})
+		require.ErrorIs(t, err, ErrInvalidArchive)
+
+		// the tables restored before the error are rolled back
+		value, _, err := kv.Get(ctx, 1, "backup", "restored")
+		require.NoError(t, err)
+		require.Equal(t, "after", value)
+		_, ok, err := kv.Get(ctx, 1, "backup", "removed")
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		// and the extracted files are removed
+		require.NoFileExists(t, filepath.Join(cfg.PluginsPath, "my-panel", "plugin.json"))
+		staged, err := filepath.Glob(filepath.Join(filepath.Dir(cfg.PluginsPath), ".*.restore-*"))
+		require.NoError(t, err)
+		require.Empty(t, staged)
+	})
+
+	t.Run("should restore the database, the data keys and the files", func(t *testing.T) {
+		restored, err := s.Restore(ctx, bytes.NewReader(archive.Bytes()), "passphrase", RestoreOptions{})
+		require.NoError(t, err)
+		require.Equal(t, manifest.CreatedAt.Unix(), restored.CreatedAt.Unix())
+
+		value, ok, err := kv.Get(ctx, 1, "backup", "restored")
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "before", value)
+		_, ok, err = kv.Get(ctx, 1, "backup", "removed")
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		key, err := keys.GetDataKey(ctx, "key-1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("new:data-key"), key.EncryptedData)
+
+		content, err := os.ReadFile(filepath.Join(cfg.PluginsPath, "my-panel", "plugin.json"))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":"my-panel"}`, string(content))
+	})
+
+	t.Run("should fail when the database has other migrations", func(t *testing.T) {
+		_, err := sqlStore.GetEngine().Table(migrationLogTable).Insert(&migrator.MigrationLog{
+			MigrationID: "a newer migration",
+			Success:     true,
+			Timestamp:   time.Now(),
+		})
+		require.NoError(t, err)
+
+		_, err = s.Restore(ctx, bytes.NewReader(archive.Bytes()), "passphrase", RestoreOptions{})
+		require.ErrorIs(t, err, ErrSchemaMismatch)
+
+		value, _, err := kv.Get(ctx, 1, "backup", "restored")
+		require.NoError(t, err)
+		require.Equal(t, "before", value)
+	})
+}
+
+// withoutEntry returns a copy of a backup without one of its files
+func withoutEntry(t *testing.T, archive []byte, passphrase string, name string) []byte {
+	t.Helper()
+
+	ar, err := openArchive(bytes.NewReader(archive), passphrase)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	aw, err := newArchiveWriter(&buf, passphrase)
+	require.NoError(t, err)
+
+	for {
+		hdr, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if hdr.Name == name {
+			continue
+		}
+		require.NoError(t, aw.tw.WriteHeader(hdr))
+		_, err = io.Copy(aw.tw, ar)
+		require.NoError(t, err)
+	}
+	require.NoError(t, aw.Close())
+	return buf.Bytes()
+}
+
+func TestDecodeValue(t *testing.T) {
+	v, err := decodeValue("AAEC", true)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 1, 2}, v)
+
+	v, err = decodeValue("AAEC", false)
+	require.NoError(t, err)
+	require.Equal(t, "AAEC", v)
+
+	_, err = decodeValue("not base64", true)
+	require.Error(t, err)
+}
+
+func TestCopySQLite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "source.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	_, err = db.ExecContext(ctx, "CREATE TABLE kv (k TEXT, v TEXT)")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "INSERT INTO kv VALUES ('a', 'before')")
+	require.NoError(t, err)
+
+	snapshot, err := copySQLite(ctx, db, dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = snapshot.Close() })
+
+	tx, err := snapshot.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+	var v string
+	require.NoError(t, tx.QueryRowContext(ctx, "SELECT v FROM kv WHERE k = 'a'").Scan(&v))
+	require.Equal(t, "before", v)
+
+	// the database can be written while the copy is read
+	_, err = db.ExecContext(ctx, "UPDATE kv SET v = 'after' WHERE k = 'a'")
+	require.NoError(t, err)
+	require.NoError(t, tx.QueryRowContext(ctx, "SELECT v FROM kv WHERE k = 'a'").Scan(&v))
+	require.Equal(t, "before", v)
+}
@@ -0,0 +1,467 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	// the driver of the copies of SQLite databases
+	_ "github.com/mattn/go-sqlite3"
+	"xorm.io/core"
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/kmsproviders"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/sqlstore/dbcopy"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+const (
+	migrationLogTable = "migration_log"
+	dataKeysTable     = "data_keys"
+	// sqliteCopyFile is the copy of a SQLite database the rows are dumped from
+	sqliteCopyFile = "grafana.db"
+)
+
+// dataKey is a data key of envelope encryption, decrypted
+type dataKey struct {
+	ID  string `json:"id"`
+	Key []byte `json:"key"`
+}
+
+// backupTables returns the tables of a database by name, the migration log is left out, the migrations are
+// compared rather than restored
+func backupTables(engine *xorm.Engine) (map[string]*core.Table, error) {
+	metas, err := engine.DBMetas()
+	if err != nil {
+		return nil, err
+	}
+	tables := make(map[string]*core.Table, len(metas))
+	for _, t := range metas {
+		if t.Name == "" || t.Name == migrationLogTable || strings.HasPrefix(t.Name, "sqlite_") {
+			continue
+		}
+		tables[t.Name] = t
+	}
+	return tables, nil
+}
+
+// dumpDatabase writes the rows of each table to a file of dir, from a snapshot of the database, and returns the
+// decrypted data keys
+func (s *Service) dumpDatabase(ctx context.Context, manifest *Manifest, dir string) ([]dataKey, error) {
+	engine := s.sqlStore.GetEngine()
+	dialect := s.sqlStore.GetDialect()
+
+	tables, err := backupTables(engine)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	order, err := dbcopy.DependencyOrder(ctx, engine, names)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotDB := engine.DB().DB
+	if dialect.DriverName() == migrator.SQLite {
+		// without WAL, a transaction reading SQLite for the whole dump would hold the shared lock and fail the
+		// writes of Grafana, the dump reads a copy instead
+		if snapshotDB, err = copySQLite(ctx, snapshotDB, dir); err != nil {
+			return nil, fmt.Errorf("failed to copy the database: %w", err)
+		}
+		defer func() { _ = snapshotDB.Close() }()
+	}
+
+	tx, err := dialect.BeginSnapshot(ctx, snapshotDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start a snapshot: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	manifest.Migrations, err = migrations(ctx, tx, dialect)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range order {
+		table, err := dumpTable(ctx, tx, dialect, tables[name], dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dump table %s: %w", name, err)
+		}
+		manifest.Tables = append(manifest.Tables, *table)
+	}
+
+	var stored []storedDataKey
+	if _, ok := tables[dataKeysTable]; ok {
+		if stored, err = readDataKeys(ctx, tx, dialect); err != nil {
+			return nil, err
+		}
+	}
+	// the keys may be decrypted by a remote KMS, the snapshot isn't needed anymore
+	_ = tx.Rollback()
+
+	return s.decryptDataKeys(ctx, stored)
+}
+
+// copySQLite copies a SQLite database to a file of dir with VACUUM INTO, which only locks the database while the
+// pages are copied, and opens the copy
+func copySQLite(ctx context.Context, db *sql.DB, dir string) (*sql.DB, error) {
+	path := filepath.Join(dir, sqliteCopyFile)
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return nil, err
+	}
+	return sql.Open("sqlite3", path)
+}
+
+func migrations(ctx context.Context, tx *sql.Tx, dialect migrator.Dialect) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s", dialect.Quote("migration_id"),
+		dialect.Quote(migrationLogTable), dialect.Quote("success"), dialect.BooleanStr(true)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the migration log: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, rows.Err()
+}
+
+// dumpTable writes the rows of a table as JSON arrays, one per line, with the binary values in base64
+func dumpTable(ctx context.Context, tx *sql.Tx, dialect migrator.Dialect, table *core.Table, dir string) (*Table, error) {
+	result := &Table{Name: table.Name}
+	names := table.ColumnsSeq()
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		result.Columns = append(result.Columns, Column{Name: name, Binary: table.GetColumn(name).SQLType.IsBlob()})
+		quoted[i] = dialect.Quote(name)
+	}
+
+	f, err := os.Create(tableFile(dir, table.Name))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	if len(names) == 0 {
+		return result, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoted, ", "), dialect.Quote(table.Name)))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	bw := bufio.NewWriter(f)
+	enc := json.NewEncoder(bw)
+	values := make([]any, len(names))
+	pointers := make([]any, len(names))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			// the drivers return text as bytes, which would be encoded in base64
+			if b, ok := v.([]byte); ok && !result.Columns[i].Binary {
+				values[i] = string(b)
+			}
+		}
+		if err := enc.Encode(values); err != nil {
+			return nil, err
+		}
+		result.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	return result, f.Close()
+}
+
+func tableFile(dir string, table string) string {
+	return filepath.Join(dir, table+".jsonl")
+}
+
+type storedDataKey struct {
+	id        string
+	provider  string
+	encrypted []byte
+}
+
+func readDataKeys(ctx context.Context, tx *sql.Tx, dialect migrator.Dialect) ([]storedDataKey, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT %s, %s, %s FROM %s", dialect.Quote("name"),
+		dialect.Quote("provider"), dialect.Quote("encrypted_data"), dialect.Quote(dataKeysTable)))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var keys []storedDataKey
+	for rows.Next() {
+		var k storedDataKey
+		if err := rows.Scan(&k.id, &k.provider, &k.encrypted); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// decryptDataKeys decrypts the data keys with the key encryption providers. The keys of a provider that isn't
+// configured anymore can't be decrypted by Grafana either, they stay encrypted in the rows of the table.
+func (s *Service) decryptDataKeys(ctx context.Context, stored []storedDataKey) ([]dataKey, error) {
+	providers := s.keys.GetProviders()
+	keys := make([]dataKey, 0, len(stored))
+	for _, k := range stored {
+		provider, ok := providers[kmsproviders.NormalizeProviderID(secrets.ProviderID(k.provider))]
+		if !ok {
+			s.log.Warn("Could not find the provider of a data key, it's kept encrypted", "id", k.id, "provider", k.provider)
+			continue
+		}
+		decrypted, err := provider.Decrypt(ctx, k.encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt the data key %s: %w", k.id, err)
+		}
+		keys = append(keys, dataKey{ID: k.id, Key: decrypted})
+	}
+	return keys, nil
+}
+
+// encryptDataKeys encrypts the data keys of a backup with the current key encryption provider, by id
+func (s *Service) encryptDataKeys(ctx context.Context, keys []dataKey) (map[string][]byte, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	current := s.keys.CurrentProviderID()
+	provider, ok := s.keys.GetProviders()[current]
+	if !ok {
+		return nil, ErrMissingProvider.Errorf("the encryption provider %s isn't configured, enable envelope encryption to restore the data keys", current)
+	}
+
+	encrypted := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		data, err := provider.Encrypt(ctx, k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt the data key %s: %w", k.ID, err)
+		}
+		encrypted[k.ID] = data
+	}
+	return encrypted, nil
+}
+
+// checkSchema fails when the database hasn't been migrated like the one of the backup, before anything is restored,
+// and returns the tables of the database by name
+func (s *Service) checkSchema(manifest *Manifest) (map[string]*core.Table, error) {
+	engine := s.sqlStore.GetEngine()
+	applied, err := migrator.NewMigrator(engine, s.cfg).GetMigrationLog()
+	if err != nil {
+		return nil, err
+	}
+
+	var missing, extra int
+	inBackup := make(map[string]struct{}, len(manifest.Migrations))
+	for _, id := range manifest.Migrations {
+		inBackup[id] = struct{}{}
+		if _, ok := applied[id]; !ok {
+			missing++
+		}
+	}
+	for id := range applied {
+		if _, ok := inBackup[id]; !ok {
+			extra++
+		}
+	}
+	if missing > 0 || extra > 0 {
+		return nil, ErrSchemaMismatch.Errorf("the backup was taken with Grafana %s, the database misses %d of its migrations and has %d it doesn't have: restore it with Grafana %s",
+			manifest.GrafanaVersion, missing, extra, manifest.GrafanaVersion)
+	}
+
+	tables, err := backupTables(engine)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range manifest.Tables {
+		dst, ok := tables[t.Name]
+		if !ok {
+			if t.Rows > 0 {
+				return nil, ErrSchemaMismatch.Errorf("the table %s of the backup doesn't exist in the database", t.Name)
+			}
+			continue
+		}
+		for _, col := range t.Columns {
+			if dst.GetColumn(col.Name) == nil {
+				return nil, ErrSchemaMismatch.Errorf("the column %s of table %s of the backup doesn't exist in the database", col.Name, t.Name)
+			}
+		}
+	}
+	return tables, nil
+}
+
+func isTableFile(name string) bool {
+	return strings.HasPrefix(name, databaseDir) && strings.HasSuffix(name, ".jsonl")
+}
+
+// databaseRestore replaces the rows of the tables of a database with the ones of a backup, in a transaction that is
+// committed once the whole backup has been read
+type databaseRestore struct {
+	tx     *dbcopy.Tx
+	tables map[string]*core.Table
+	// restored are the tables of the backup that have been read
+	restored map[string]bool
+	// encryptedKeys are the data keys of the backup encrypted by keyProvider, by id
+	encryptedKeys map[string][]byte
+	keyProvider   string
+	log           log.Logger
+}
+
+// clear deletes the rows of the tables of the backup, the tables referencing others first
+func (r *databaseRestore) clear(manifest *Manifest) error {
+	for i := len(manifest.Tables) - 1; i >= 0; i-- {
+		name := manifest.Tables[i].Name
+		if _, ok := r.tables[name]; !ok {
+			continue
+		}
+		if err := r.tx.Empty(name); err != nil {
+			return fmt.Errorf("table %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (r *databaseRestore) restoreTable(manifest *Manifest, name string, content io.Reader) error {
+	tableName := strings.TrimSuffix(strings.TrimPrefix(name, databaseDir), ".jsonl")
+	var table *Table
+	for i := range manifest.Tables {
+		if manifest.Tables[i].Name == tableName {
+			table = &manifest.Tables[i]
+		}
+	}
+	if table == nil {
+		return ErrInvalidArchive.Errorf("the table %s isn't in the manifest", tableName)
+	}
+	if r.restored[tableName] {
+		return ErrInvalidArchive.Errorf("the table %s is in the backup twice", tableName)
+	}
+	r.restored[tableName] = true
+	dst, ok := r.tables[tableName]
+	if !ok || len(table.Columns) == 0 {
+		// an empty table of a plugin that isn't installed
+		return nil
+	}
+
+	names := make([]string, len(table.Columns))
+	index := make(map[string]int, len(table.Columns))
+	for i, col := range table.Columns {
+		names[i] = col.Name
+		index[col.Name] = i
+	}
+	w, err := r.tx.NewTableWriter(dst, names)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(content)
+	dec.UseNumber()
+	for {
+		var row []any
+		if err := dec.Decode(&row); err == io.EOF {
+			break
+		} else if err != nil {
+			return ErrInvalidArchive.Errorf("invalid row of table %s: %w", tableName, err)
+		}
+		if len(row) != len(table.Columns) {
+			return ErrInvalidArchive.Errorf("a row of table %s has %d values for %d columns", tableName, len(row), len(table.Columns))
+		}
+		for i, v := range row {
+			if row[i], err = decodeValue(v, table.Columns[i].Binary); err != nil {
+				return ErrInvalidArchive.Errorf("invalid value of column %s of table %s: %w", table.Columns[i].Name, tableName, err)
+			}
+		}
+		if tableName == dataKeysTable {
+			r.replaceDataKey(row, index)
+		}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("failed to restore table %s: %w", tableName, err)
+		}
+	}
+
+	rows, err := w.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to restore table %s: %w", tableName, err)
+	}
+	if rows != table.Rows {
+		return ErrInvalidArchive.Errorf("the table %s has %d rows in the backup, %d were restored", tableName, table.Rows, rows)
+	}
+	r.log.Debug("Restored table", "table", tableName, "rows", rows)
+	return nil
+}
+
+// replaceDataKey replaces a data key encrypted by the instance the backup was taken from with the same key encrypted
+// by this instance
+func (r *databaseRestore) replaceDataKey(row []any, index map[string]int) {
+	id, _ := row[index["name"]].(string)
+	encrypted, ok := r.encryptedKeys[id]
+	if !ok {
+		return
+	}
+	row[index["encrypted_data"]] = encrypted
+	row[index["provider"]] = r.keyProvider
+}
+
+// checkComplete fails when tables of the manifest with rows are missing from the backup, like when it is truncated
+func (r *databaseRestore) checkComplete(manifest *Manifest) error {
+	for _, t := range manifest.Tables {
+		if t.Rows > 0 && !r.restored[t.Name] {
+			return ErrInvalidArchive.Errorf("the rows of table %s are missing from the backup", t.Name)
+		}
+	}
+	return nil
+}
+
+func (r *databaseRestore) resetSequences() error {
+	for name, table := range r.tables {
+		if err := r.tx.ResetSequence(table); err != nil {
+			return fmt.Errorf("failed to reset the sequence of table %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// decodeValue reverts the JSON encoding of a value of a row, the values are converted to the types of the columns
+// when inserted
+func decodeValue(v any, binary bool) (any, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case string:
+		if binary {
+			return base64.StdEncoding.DecodeString(v)
+		}
+	}
+	return v, nil
+}
@@ -21,6 +21,8 @@ import (
 	"github.com/grafana/grafana/pkg/services/dashboardsnapshots"
 	dashver "github.com/grafana/grafana/pkg/services/dashboardversion"
 	"github.com/grafana/grafana/pkg/services/ngalert/image"
+	"github.com/grafana/grafana/pkg/services/ngalert/state/historian"
+	"github.com/grafana/grafana/pkg/services/publicdashboards"
 	"github.com/grafana/grafana/pkg/services/queryhistory"
 	"github.com/grafana/grafana/pkg/services/shorturls"
 	tempuser "github.com/grafana/grafana/pkg/services/temp_user"
@@ -28,39 +30,44 @@ import (
 )
 
 type CleanUpService struct {
-	log                       log.Logger
-	tracer                    tracing.Tracer
-	store                     db.DB
-	Cfg                       *setting.Cfg
-	ServerLockService         *serverlock.ServerLockService
-	ShortURLService           shorturls.Service
-	QueryHistoryService       queryhistory.Service
-	dashboardVersionService   dashver.Service
-	dashboardSnapshotService  dashboardsnapshots.Service
-	deleteExpiredImageService *image.DeleteExpiredService
-	tempUserService           tempuser.Service
-	annotationCleaner         annotations.Cleaner
-	dashboardService          dashboards.DashboardService
+	log                         log.Logger
+	tracer                      tracing.Tracer
+	store                       db.DB
+	Cfg                         *setting.Cfg
+	ServerLockService           *serverlock.ServerLockService
+	ShortURLService             shorturls.Service
+	QueryHistoryService         queryhistory.Service
+	dashboardVersionService     dashver.Service
+	dashboardSnapshotService    dashboardsnapshots.Service
+	deleteExpiredImageService   *image.DeleteExpiredService
+	deleteExpiredHistoryService *historian.DeleteExpiredService
+	tempUserService             tempuser.Service
+	annotationCleaner           annotations.Cleaner
+	dashboardService            dashboards.DashboardService
+	publicDashboardService      publicdashboards.Service
 }
 
 func ProvideService(cfg *setting.Cfg, serverLockService *serverlock.ServerLockService,
 	shortURLService shorturls.Service, sqlstore db.DB, queryHistoryService queryhistory.Service,
 	dashboardVersionService dashver.Service, dashSnapSvc dashboardsnapshots.Service, deleteExpiredImageService *image.DeleteExpiredService,
-	tempUserService tempuser.Service, tracer tracing.Tracer, annotationCleaner annotations.Cleaner, dashboardService dashboards.DashboardService) *CleanUpService {
+	tempUserService tempuser.Service, tracer tracing.Tracer, annotationCleaner annotations.Cleaner, dashboardService dashboards.DashboardService,
+	deleteExpiredHistoryService *historian.DeleteExpiredService, publicDashboardService publicdashboards.Service) *CleanUpService {
 	s := &CleanUpService{
-		Cfg:                       cfg,
-		ServerLockService:         serverLockService,
-		ShortURLService:           shortURLService,
-		QueryHistoryService:       queryHistoryService,
-		store:                     sqlstore,
-		log:                       log.New("cleanup"),
-		dashboardVersionService:   dashboardVersionService,
-		dashboardSnapshotService:  dashSnapSvc,
-		deleteExpiredImageService: deleteExpiredImageService,
-		tempUserService:           tempUserService,
-		tracer:                    tracer,
-		annotationCleaner:         annotationCleaner,
-		dashboardService:          dashboardService,
+		Cfg:                         cfg,
+		ServerLockService:           serverLockService,
+		ShortURLService:             shortURLService,
+		QueryHistoryService:         queryHistoryService,
+		store:                       sqlstore,
+		log:                         log.New("cleanup"),
+		dashboardVersionService:     dashboardVersionService,
+		dashboardSnapshotService:    dashSnapSvc,
+		deleteExpiredImageService:   deleteExpiredImageService,
+		deleteExpiredHistoryService: deleteExpiredHistoryService,
+		tempUserService:             tempUserService,
+		tracer:                      tracer,
+		annotationCleaner:           annotationCleaner,
+		dashboardService:            dashboardService,
+		publicDashboardService:      publicDashboardService,
 	}
 	return s
 }
@@ -106,6 +113,15 @@ func (srv *CleanUpService) clean(ctx context.Context) {
 		{"delete stale query history", srv.deleteStaleQueryHistory},
 		{"expire old email verifications", srv.expireOldVerifications},
 		{"cleanup trash dashboards", srv.cleanUpTrashDashboards},
+		{"disable expired public dashboards", srv.disableExpiredPublicDashboards},
+	}
+
+	if historian.SQLBackendEnabled(srv.Cfg.UnifiedAlerting.StateHistory) {
+		cleanupJobs = append(cleanupJobs, cleanUpJob{"delete expired alert state history", srv.deleteExpiredStateHistory})
+	}
+
+	if srv.Cfg.PublicDashboardsViewsRetention > 0 {
+		cleanupJobs = append(cleanupJobs, cleanUpJob{"delete old public dashboard views", srv.deleteOldPublicDashboardViews})
 	}
 
 	if srv.Cfg.ShortLinkExpiration > 0 {
@@ -231,6 +247,37 @@ func (srv *CleanUpService) deleteExpiredImages(ctx context.Context) {
 	}
 }
 
+func (srv *CleanUpService) deleteExpiredStateHistory(ctx context.Context) {
+	logger := srv.log.FromContext(ctx)
+	if !srv.Cfg.UnifiedAlerting.IsEnabled() {
+		return
+	}
+	if rowsAffected, err := srv.deleteExpiredHistoryService.DeleteExpired(ctx); err != nil {
+		logger.Error("Failed to delete expired alert state history", "error", err.Error())
+	} else {
+		logger.Debug("Deleted expired alert state history", "rows affected", rowsAffected)
+	}
+}
+
+func (srv *CleanUpService) disableExpiredPublicDashboards(ctx context.Context) {
+	logger := srv.log.FromContext(ctx)
+	if rowsAffected, err := srv.publicDashboardService.DisableExpired(ctx); err != nil {
+		logger.Error("Failed to disable expired public dashboards", "error", err.Error())
+	} else {
+		logger.Debug("Disabled expired public dashboards", "rows affected", rowsAffected)
+	}
+}
+
+func (srv *CleanUpService) deleteOldPublicDashboardViews(ctx context.Context) {
+	logger := srv.log.FromContext(ctx)
+	olderThan := time.Now().Add(-srv.Cfg.PublicDashboardsViewsRetention)
+	if rowsAffected, err := srv.publicDashboardService.DeleteViewsBefore(ctx, olderThan); err != nil {
+		logger.Error("Failed to delete old public dashboard views", "error", err.Error())
+	} else {
+		logger.Debug("Deleted old public dashboard views", "rows affected", rowsAffected)
+	}
+}
+
 func (srv *CleanUpService) expireOldUserInvites(ctx context.Context) {
 	logger := srv.log.FromContext(ctx)
 	maxInviteLifetime := srv.Cfg.UserInviteMaxLifetime
@@ -0,0 +1,162 @@
+// Package panelquery builds the queries of dashboard panels the way the frontend does, so that the server can run them
+// through the query service.
+package panelquery
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/services/dashboards/templating"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/publicdashboards/service/intervalv2"
+)
+
+// DefaultMaxDataPoints replaces the width of the panel the frontend uses to compute the resolution of the queries.
+const DefaultMaxDataPoints = 1000
+
+var ErrNoDefaultDatasource = errutil.BadRequest(
+	"panelquery.no-default-datasource",
+	errutil.WithPublicMessage("A panel has no data source and the organization has no default data source"),
+)
+
+type Builder struct {
+	dataSourceService  datasources.DataSourceService
+	intervalCalculator intervalv2.Calculator
+}
+
+func NewBuilder(dataSourceService datasources.DataSourceService) *Builder {
+	return &Builder{
+		dataSourceService:  dataSourceService,
+		intervalCalculator: intervalv2.NewCalculator(),
+	}
+}
+
+// Request holds what the queries of the panels of a dashboard share
+type Request struct {
+	OrgID int64
+	From  time.Time
+	To    time.Time
+	Vars  templating.Variables
+	// resolved on the first panel without a data source
+	defaultDatasourceRef map[string]any
+}
+
+// Queries returns the queries of a panel with their data source, interval and variables resolved.
+// The hidden queries are only returned when an expression of the panel may reference them.
+func (b *Builder) Queries(ctx context.Context, req *Request, panel *simplejson.Json) ([]*simplejson.Json, error) {
+	interval := b.intervalCalculator.Calculate(
+		backend.TimeRange{From: req.From, To: req.To},
+		panelMinInterval(panel, req.Vars),
+		panel.Get("maxDataPoints").MustInt64(DefaultMaxDataPoints),
+	)
+
+	hasExpression := HasExpression(panel)
+
+	var queries []*simplejson.Json
+	for _, obj := range panel.Get("targets").MustArray() {
+		q := simplejson.NewFromAny(obj)
+
+		// hidden queries are only needed by the expressions that reference them, and removed from their response
+		if !hasExpression && q.Get("hide").MustBool() {
+			continue
+		}
+
+		if DatasourceUID(q) == "" {
+			ds := panel.Get("datasource").Interface()
+			if DatasourceUID(panel) == "" {
+				var err error
+				if ds, err = b.defaultDatasource(ctx, req); err != nil {
+					return nil, err
+				}
+			}
+			q.Set("datasource", ds)
+		}
+
+		templating.Interpolate(q, req.Vars)
+		q.Set("intervalMs", interval.Milliseconds())
+		q.Set("maxDataPoints", panel.Get("maxDataPoints").MustInt64(DefaultMaxDataPoints))
+		queries = append(queries, q)
+	}
+	return queries, nil
+}
+
+// defaultDatasource resolves the datasource of panels that don't reference any, like the frontend does.
+func (b *Builder) defaultDatasource(ctx context.Context, req *Request) (map[string]any, error) {
+	if req.defaultDatasourceRef != nil {
+		return req.defaultDatasourceRef, nil
+	}
+
+	all, err := b.dataSourceService.GetDataSources(ctx, &datasources.GetDataSourcesQuery{OrgID: req.OrgID})
+	if err != nil {
+		return nil, err
+	}
+	for _, ds := range all {
+		if ds.IsDefault {
+			req.defaultDatasourceRef = map[string]any{"type": ds.Type, "uid": ds.UID}
+			return req.defaultDatasourceRef, nil
+		}
+	}
+	return nil, ErrNoDefaultDatasource.Errorf("organization %d has no default data source", req.OrgID)
+}
+
+// HasExpression returns true when a query of the panel is a server-side expression
+func HasExpression(panel *simplejson.Json) bool {
+	for _, obj := range panel.Get("targets").MustArray() {
+		if expr.NodeTypeFromDatasourceUID(DatasourceUID(simplejson.NewFromAny(obj))) == expr.TypeCMDNode {
+			return true
+		}
+	}
+	return false
+}
+
+// FindPanel returns the panel of a dashboard with the given id, including the panels of collapsed rows
+func FindPanel(dashboard *simplejson.Json, id int64) (*simplejson.Json, bool) {
+	var find func(panels []any) (*simplejson.Json, bool)
+	find = func(panels []any) (*simplejson.Json, bool) {
+		for _, obj := range panels {
+			panel := simplejson.NewFromAny(obj)
+			if panel.Get("type").MustString() == "row" {
+				if nested, ok := find(panel.Get("panels").MustArray()); ok {
+					return nested, true
+				}
+				continue
+			}
+			if panelID, err := panel.Get("id").Int64(); err == nil && panelID == id {
+				return panel, true
+			}
+		}
+		return nil, false
+	}
+	return find(dashboard.Get("panels").MustArray())
+}
+
+// DatasourceUID returns the uid of the data source referenced by a panel or a query
+func DatasourceUID(j *simplejson.Json) string {
+	uid := j.Get("datasource").Get("uid").MustString()
+
+	// before 8.3 the datasource could be referenced by a string
+	if uid == "" {
+		uid = j.Get("datasource").MustString()
+	}
+
+	return uid
+}
+
+func panelMinInterval(panel *simplejson.Json, vars templating.Variables) time.Duration {
+	interval := templating.InterpolateString(panel.Get("interval").MustString(), vars)
+	if interval == "" {
+		return 0
+	}
+	// the frontend accepts a ">" prefix to mark the interval as a minimum
+	if d, err := gtime.ParseDuration(strings.TrimPrefix(interval, ">")); err == nil {
+		return d
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package panelquery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/services/dashboards/templating"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/fakes"
+)
+
+const dashboardJSON = `{
+	"panels": [
+		{
+			"id": 1,
+			"datasource": {"type": "prometheus", "uid": "prom"},
+			"interval": ">$interval",
+			"targets": [
+				{"refId": "A", "expr": "up{region=\"$region\"}"},
+				{"refId": "B", "expr": "hidden", "hide": true}
+			]
+		},
+		{
+			"id": 2,
+			"type": "row",
+			"panels": [
+				{
+					"id": 3,
+					"maxDataPoints": 100,
+					"targets": [
+						{"refId": "A", "expr": "errors", "hide": true},
+						{"refId": "B", "datasource": {"type": "__expr__", "uid": "__expr__"}, "type": "math", "expression": "$A * 2"}
+					]
+				}
+			]
+		}
+	]
+}`
+
+func TestQueries(t *testing.T) {
+	dashboard, err := simplejson.NewJson([]byte(dashboardJSON))
+	require.NoError(t, err)
+
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	req := &Request{
+		OrgID: 1,
+		From:  from,
+		To:    from.Add(24 * time.Hour),
+		Vars:  templating.Variables{"region": {"eu"}, "interval": {"5m"}},
+	}
+	builder := NewBuilder(&fakes.FakeDataSourceService{DataSources: []*datasources.DataSource{
+		{OrgID: 1, UID: "loki", Type: "loki", IsDefault: true},
+	}})
+
+	t.Run("should skip the hidden queries", func(t *testing.T) {
+		panel, ok := FindPanel(dashboard, 1)
+		require.True(t, ok)
+		require.False(t, HasExpression(panel))
+
+		queries, err := builder.Queries(context.Background(), req, panel)
+		require.NoError(t, err)
+		require.Len(t, queries, 1)
+		require.Equal(t, `up{region="eu"}`, queries[0].Get("expr").MustString())
+		require.Equal(t, "prom", DatasourceUID(queries[0]))
+		require.Equal(t, int64(DefaultMaxDataPoints), queries[0].Get("maxDataPoints").MustInt64())
+		require.Equal(t, (5 * time.Minute).Milliseconds(), queries[0].Get("intervalMs").MustInt64())
+	})
+
+	t.Run("should keep the hidden queries of expressions", func(t *testing.T) {
+		panel, ok := FindPanel(dashboard, 3)
+		require.True(t, ok)
+		require.True(t, HasExpression(panel))
+
+		queries, err := builder.Queries(context.Background(), req, panel)
+		require.NoError(t, err)
+		require.Len(t, queries, 2)
+		require.Equal(t, "loki", DatasourceUID(queries[0]))
+		require.Equal(t, "__expr__", DatasourceUID(queries[1]))
+		require.Equal(t, int64(100), queries[1].Get("maxDataPoints").MustInt64())
+	})
+
+	t.Run("should fail without a default data source", func(t *testing.T) {
+		panel, _ := FindPanel(dashboard, 3)
+		builder := NewBuilder(&fakes.FakeDataSourceService{})
+
+		_, err := builder.Queries(context.Background(), &Request{OrgID: 1, From: req.From, To: req.To}, panel)
+		require.ErrorIs(t, err, ErrNoDefaultDatasource)
+	})
+
+	t.Run("should not find rows or missing panels", func(t *testing.T) {
+		_, ok := FindPanel(dashboard, 2)
+		require.False(t, ok)
+		_, ok = FindPanel(dashboard, 4)
+		require.False(t, ok)
+	})
+}
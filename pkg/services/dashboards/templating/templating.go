@@ -0,0 +1,219 @@
+// Package templating resolves dashboard template variables on the server, for features that run panel queries
+// without the frontend, such as server-side snapshots and public dashboards.
+package templating
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+const allValue = "$__all"
+
+// Same expression the frontend template service uses: $var, [[var:format]] and ${var.fieldPath:format}.
+var variableRegex = regexp.MustCompile(`\$(\w+)|\[\[(\w+?)(?::(\w+))?\]\]|\${(\w+)(?:\.([^:^\}]+))?(?::([^\}]+))?}`)
+
+// Variables maps template variable names to their selected values.
+type Variables map[string][]string
+
+// Merge returns a copy of v where the values of the variables in overrides replace the ones in v.
+func (v Variables) Merge(overrides Variables) Variables {
+	merged := make(Variables, len(v)+len(overrides))
+	for name, values := range v {
+		merged[name] = values
+	}
+	for name, values := range overrides {
+		merged[name] = values
+	}
+	return merged
+}
+
+// CurrentValues returns the values currently selected for the template variables of the dashboard.
+// A variable set to "All" resolves to every option of the variable.
+func CurrentValues(dashboard *simplejson.Json) Variables {
+	vars := make(Variables)
+	for _, obj := range dashboard.GetPath("templating", "list").MustArray() {
+		variable := simplejson.NewFromAny(obj)
+		name := variable.Get("name").MustString()
+		if name == "" {
+			continue
+		}
+
+		vars[name] = SelectedValues(variable)
+	}
+	return vars
+}
+
+// SelectedValues returns the values currently selected for the template variable.
+func SelectedValues(variable *simplejson.Json) []string {
+	values := stringValues(variable.GetPath("current", "value").Interface())
+	if len(values) == 1 && values[0] == allValue {
+		return OptionValues(variable)
+	}
+	return values
+}
+
+// OptionValues returns the values of the options stored in the variable model, without the "All" option.
+func OptionValues(variable *simplejson.Json) []string {
+	var values []string
+	for _, obj := range variable.Get("options").MustArray() {
+		for _, value := range stringValues(simplejson.NewFromAny(obj).Get("value").Interface()) {
+			if value != allValue {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// SetCurrentValues updates the current selection of the dashboard template variables so that the dashboard
+// shows the values its queries were interpolated with. Variables of the dashboard missing from vars are left untouched.
+func SetCurrentValues(dashboard *simplejson.Json, vars Variables) {
+	for _, obj := range dashboard.GetPath("templating", "list").MustArray() {
+		variable := simplejson.NewFromAny(obj)
+		if values, ok := vars[variable.Get("name").MustString()]; ok {
+			SetSelectedValues(variable, values)
+		}
+	}
+}
+
+// SetSelectedValues updates the current selection of the template variable.
+func SetSelectedValues(variable *simplejson.Json, values []string) {
+	var value any = strings.Join(values, ",")
+	if variable.Get("multi").MustBool() {
+		multi := make([]any, len(values))
+		for i, v := range values {
+			multi[i] = v
+		}
+		value = multi
+	}
+	variable.Set("current", map[string]any{
+		"selected": true,
+		"text":     value,
+		"value":    value,
+	})
+}
+
+// Interpolate replaces the references to template variables in all the string values of the query model.
+// References to unknown variables, including the global ones such as $__interval, are left for the data source to handle.
+func Interpolate(query *simplejson.Json, vars Variables) {
+	m, err := query.Map()
+	if err != nil {
+		return
+	}
+	for key, value := range m {
+		// the refId is used to match the response to the query
+		if key == "refId" {
+			continue
+		}
+		m[key] = interpolateValue(value, vars)
+	}
+}
+
+// InterpolateString replaces the references to template variables in s.
+func InterpolateString(s string, vars Variables) string {
+	if len(vars) == 0 || !strings.ContainsAny(s, "$[") {
+		return s
+	}
+
+	return variableRegex.ReplaceAllStringFunc(s, func(match string) string {
+		groups := variableRegex.FindStringSubmatch(match)
+		name := groups[1] + groups[2] + groups[4]
+		format := groups[3] + groups[6]
+
+		values, ok := vars[name]
+		if !ok {
+			return match
+		}
+		return FormatValues(values, format)
+	})
+}
+
+func interpolateValue(value any, vars Variables) any {
+	switch v := value.(type) {
+	case string:
+		return InterpolateString(v, vars)
+	case map[string]any:
+		for key, item := range v {
+			v[key] = interpolateValue(item, vars)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = interpolateValue(item, vars)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// FormatValues formats the values of a variable with one of the formats supported by the frontend, e.g. ${var:csv}.
+// Unknown formats fall back to the default one, which uses the glob syntax for multiple values.
+func FormatValues(values []string, format string) string {
+	switch format {
+	case "raw", "csv", "text":
+		return strings.Join(values, ",")
+	case "pipe":
+		return strings.Join(values, "|")
+	case "regex":
+		escaped := make([]string, len(values))
+		for i, value := range values {
+			escaped[i] = regexp.QuoteMeta(value)
+		}
+		if len(escaped) == 1 {
+			return escaped[0]
+		}
+		return "(" + strings.Join(escaped, "|") + ")"
+	case "singlequote":
+		return quoteValues(values, "'", `\'`)
+	case "doublequote":
+		return quoteValues(values, `"`, `\"`)
+	case "sqlstring":
+		return quoteValues(values, "'", "''")
+	case "json":
+		var v any = values
+		if len(values) == 1 {
+			v = values[0]
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	default:
+		if len(values) == 1 {
+			return values[0]
+		}
+		return "{" + strings.Join(values, ",") + "}"
+	}
+}
+
+func quoteValues(values []string, quote string, escapedQuote string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = quote + strings.ReplaceAll(value, quote, escapedQuote) + quote
+	}
+	return strings.Join(quoted, ",")
+}
+
+func stringValues(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	case []string:
+		return v
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,93 @@
+package templating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+func TestInterpolateString(t *testing.T) {
+	vars := Variables{
+		"region": {"eu-west"},
+		"host":   {"a.example", "b'example"},
+	}
+
+	testCases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{desc: "dollar syntax", input: `up{region="$region"}`, expected: `up{region="eu-west"}`},
+		{desc: "braces syntax", input: `${region}_total`, expected: `eu-west_total`},
+		{desc: "brackets syntax", input: `[[region]]`, expected: `eu-west`},
+		{desc: "default format for multiple values", input: `$host`, expected: `{a.example,b'example}`},
+		{desc: "csv", input: `${host:csv}`, expected: `a.example,b'example`},
+		{desc: "pipe", input: `${host:pipe}`, expected: `a.example|b'example`},
+		{desc: "regex", input: `${host:regex}`, expected: `(a\.example|b'example)`},
+		{desc: "regex single value", input: `${region:regex}`, expected: `eu-west`},
+		{desc: "sqlstring", input: `host IN (${host:sqlstring})`, expected: `host IN ('a.example','b''example')`},
+		{desc: "singlequote", input: `${host:singlequote}`, expected: `'a.example','b\'example'`},
+		{desc: "doublequote", input: `${region:doublequote}`, expected: `"eu-west"`},
+		{desc: "json", input: `${host:json}`, expected: `["a.example","b'example"]`},
+		{desc: "brackets with format", input: `[[host:pipe]]`, expected: `a.example|b'example`},
+		{desc: "unknown variable", input: `rate(x[$__interval]) + $unknown`, expected: `rate(x[$__interval]) + $unknown`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.Equal(t, tc.expected, InterpolateString(tc.input, vars))
+		})
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	query, err := simplejson.NewJson([]byte(`{
+		"refId": "$region",
+		"expr": "up{region=\"$region\"}",
+		"datasource": {"uid": "${ds}"},
+		"filters": [{"value": "$region"}],
+		"limit": 10
+	}`))
+	require.NoError(t, err)
+
+	Interpolate(query, Variables{"region": {"eu"}, "ds": {"prom-eu"}})
+
+	require.Equal(t, "$region", query.Get("refId").MustString())
+	require.Equal(t, `up{region="eu"}`, query.Get("expr").MustString())
+	require.Equal(t, "prom-eu", query.GetPath("datasource", "uid").MustString())
+	require.Equal(t, "eu", query.Get("filters").GetIndex(0).Get("value").MustString())
+	require.Equal(t, 10, query.Get("limit").MustInt())
+}
+
+func TestCurrentValues(t *testing.T) {
+	dashboard, err := simplejson.NewJson([]byte(`{
+		"templating": {
+			"list": [
+				{"name": "region", "current": {"text": "eu", "value": "eu"}},
+				{"name": "host", "multi": true, "current": {"value": ["a", "b"]}},
+				{
+					"name": "env",
+					"current": {"value": ["$__all"]},
+					"options": [{"value": "$__all"}, {"value": "prod"}, {"value": "dev"}]
+				}
+			]
+		}
+	}`))
+	require.NoError(t, err)
+
+	vars := CurrentValues(dashboard)
+	require.Equal(t, Variables{
+		"region": {"eu"},
+		"host":   {"a", "b"},
+		"env":    {"prod", "dev"},
+	}, vars)
+
+	vars = vars.Merge(Variables{"region": {"us"}, "host": {"c"}})
+	SetCurrentValues(dashboard, vars)
+
+	list := dashboard.GetPath("templating", "list")
+	require.Equal(t, "us", list.GetIndex(0).GetPath("current", "value").MustString())
+	require.Equal(t, []string{"c"}, list.GetIndex(1).GetPath("current", "value").MustStringArray())
+}
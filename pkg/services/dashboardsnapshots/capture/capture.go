@@ -0,0 +1,266 @@
+// Package capture creates dashboard snapshots from panel data queried by the server, either on demand or
+// periodically for the dashboards configured in the [snapshot_capture.<name>] sections.
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	common "github.com/grafana/grafana/pkg/apimachinery/apis/common/v0alpha1"
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	dashboardsnapshot "github.com/grafana/grafana/pkg/apis/dashboardsnapshot/v0alpha1"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/dashboards/panelquery"
+	"github.com/grafana/grafana/pkg/services/dashboards/templating"
+	"github.com/grafana/grafana/pkg/services/dashboardsnapshots"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/query"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+type Service struct {
+	cfg              *setting.Cfg
+	log              log.Logger
+	dashboardService dashboards.DashboardService
+	snapshotService  dashboardsnapshots.Service
+	queryService     query.Service
+	queryBuilder     *panelquery.Builder
+	serverLock       *serverlock.ServerLockService
+}
+
+func ProvideService(
+	cfg *setting.Cfg,
+	dashboardService dashboards.DashboardService,
+	dataSourceService datasources.DataSourceService,
+	snapshotService dashboardsnapshots.Service,
+	queryService query.Service,
+	serverLock *serverlock.ServerLockService,
+) *Service {
+	return &Service{
+		cfg:              cfg,
+		log:              log.New("dashboardsnapshots.capture"),
+		dashboardService: dashboardService,
+		snapshotService:  snapshotService,
+		queryService:     queryService,
+		queryBuilder:     panelquery.NewBuilder(dataSourceService),
+		serverLock:       serverLock,
+	}
+}
+
+// CaptureDashboardSnapshot runs the queries of every panel of the dashboard on behalf of user and stores the resulting
+// data frames in a new snapshot, the same way the frontend does when the snapshot is created from the share modal.
+func (s *Service) CaptureDashboardSnapshot(ctx context.Context, user identity.Requester, cmd *dashboardsnapshots.CaptureDashboardSnapshotCommand) (*dashboardsnapshots.DashboardSnapshot, error) {
+	dashboard, err := s.dashboardService.GetDashboard(ctx, &dashboards.GetDashboardQuery{UID: cmd.DashboardUID, OrgID: cmd.OrgID})
+	if err != nil {
+		return nil, err
+	}
+
+	// work on a copy, the dashboard may be cached
+	raw, err := dashboard.Data.Encode()
+	if err != nil {
+		return nil, err
+	}
+	model, err := simplejson.NewJson(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to, err := captureTimeRange(model, cmd.From, cmd.To)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := templating.CurrentValues(model)
+	for name := range cmd.Variables {
+		if _, ok := vars[name]; !ok {
+			return nil, dashboardsnapshots.ErrCaptureBadRequest.Errorf("dashboard %s has no template variable named %q", cmd.DashboardUID, name)
+		}
+	}
+	vars = vars.Merge(cmd.Variables)
+
+	c := &capture{
+		Service:      s,
+		user:         user,
+		dashboardUID: cmd.DashboardUID,
+		request:      &panelquery.Request{OrgID: cmd.OrgID, From: from, To: to, Vars: vars},
+	}
+	if err := c.capturePanels(ctx, model.Get("panels").MustArray()); err != nil {
+		return nil, err
+	}
+
+	scrubDashboard(model, vars, from, to)
+	model.SetPath([]string{"snapshot", "originalUrl"}, fmt.Sprintf("/d/%s", dashboard.UID))
+	model.SetPath([]string{"snapshot", "timestamp"}, time.Now().UTC().Format(time.RFC3339))
+
+	return s.createSnapshot(ctx, user, cmd, dashboard, model)
+}
+
+func (s *Service) createSnapshot(ctx context.Context, user identity.Requester, cmd *dashboardsnapshots.CaptureDashboardSnapshotCommand, dashboard *dashboards.Dashboard, model *simplejson.Json) (*dashboardsnapshots.DashboardSnapshot, error) {
+	raw, err := model.Encode()
+	if err != nil {
+		return nil, err
+	}
+	snapshotDashboard := &common.Unstructured{}
+	if err := json.Unmarshal(raw, snapshotDashboard); err != nil {
+		return nil, err
+	}
+
+	createCmd := &dashboardsnapshots.CreateDashboardSnapshotCommand{
+		DashboardCreateCommand: dashboardsnapshot.DashboardCreateCommand{
+			Name:      cmd.Name,
+			Dashboard: snapshotDashboard,
+			Expires:   cmd.Expires,
+		},
+		OrgID: cmd.OrgID,
+	}
+	if createCmd.Name == "" {
+		createCmd.Name = dashboard.Title
+	}
+	// service identities don't map to a user, the snapshot is then not owned by anyone
+	createCmd.UserID, _ = identity.UserIdentifier(user.GetID())
+
+	if createCmd.Key, err = util.GetRandomString(32); err != nil {
+		return nil, err
+	}
+	if createCmd.DeleteKey, err = util.GetRandomString(32); err != nil {
+		return nil, err
+	}
+
+	return s.snapshotService.CreateDashboardSnapshot(ctx, createCmd)
+}
+
+type capture struct {
+	*Service
+	user         identity.Requester
+	dashboardUID string
+	request      *panelquery.Request
+}
+
+func (c *capture) capturePanels(ctx context.Context, panels []any) error {
+	for _, obj := range panels {
+		panel := simplejson.NewFromAny(obj)
+
+		// collapsed rows contain their panels, expanded rows have none
+		if panel.Get("type").MustString() == "row" {
+			if err := c.capturePanels(ctx, panel.Get("panels").MustArray()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		frames, err := c.capturePanel(ctx, panel)
+		if err != nil {
+			return err
+		}
+
+		panel.Set("snapshotData", frames)
+		panel.Set("targets", []any{})
+		panel.Set("links", []any{})
+		panel.Del("datasource")
+	}
+	return nil
+}
+
+// capturePanel returns the data frames of the panel, serialized the way the frontend reads them back from snapshotData.
+func (c *capture) capturePanel(ctx context.Context, panel *simplejson.Json) ([]any, error) {
+	queries, err := c.queryBuilder.Queries(ctx, c.request, panel)
+	if err != nil || len(queries) == 0 {
+		return []any{}, err
+	}
+
+	res, err := c.queryService.QueryData(ctx, c.user, false, dtos.MetricRequest{
+		From:    strconv.FormatInt(c.request.From.UnixMilli(), 10),
+		To:      strconv.FormatInt(c.request.To.UnixMilli(), 10),
+		Queries: queries,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query panel %d: %w", panel.Get("id").MustInt64(), err)
+	}
+
+	frames := []any{}
+	// follow the order of the queries rather than the one of the responses map
+	for _, q := range queries {
+		refID := q.Get("refId").MustString()
+		dr, ok := res.Responses[refID]
+		if !ok {
+			continue
+		}
+		if dr.Error != nil {
+			// same as in the frontend, a failing query leaves the panel without its data
+			c.log.Warn("Query of captured panel failed", "dashboard", c.dashboardUID, "panel", panel.Get("id").MustInt64(), "refId", refID, "error", dr.Error)
+			continue
+		}
+		for _, frame := range dr.Frames {
+			if frame.RefID == "" {
+				frame.RefID = refID
+			}
+			b, err := json.Marshal(frame)
+			if err != nil {
+				return nil, err
+			}
+			var v any
+			if err := json.Unmarshal(b, &v); err != nil {
+				return nil, err
+			}
+			frames = append(frames, v)
+		}
+	}
+	return frames, nil
+}
+
+// captureTimeRange resolves the requested time range, or the one saved in the dashboard, to absolute times.
+func captureTimeRange(dashboard *simplejson.Json, from string, to string) (time.Time, time.Time, error) {
+	if from == "" && to == "" {
+		from = dashboard.GetPath("time", "from").MustString("now-6h")
+		to = dashboard.GetPath("time", "to").MustString("now")
+	}
+
+	location, err := time.LoadLocation(dashboard.Get("timezone").MustString())
+	if err != nil {
+		location = time.UTC
+	}
+
+	timeRange := gtime.NewTimeRange(from, to)
+	fromTime, err := timeRange.ParseFrom(gtime.WithLocation(location))
+	if err != nil {
+		return time.Time{}, time.Time{}, dashboardsnapshots.ErrCaptureBadRequest.Errorf("invalid from: %w", err)
+	}
+	toTime, err := timeRange.ParseTo(gtime.WithLocation(location))
+	if err != nil {
+		return time.Time{}, time.Time{}, dashboardsnapshots.ErrCaptureBadRequest.Errorf("invalid to: %w", err)
+	}
+	if !fromTime.Before(toTime) {
+		return time.Time{}, time.Time{}, dashboardsnapshots.ErrCaptureBadRequest.Errorf("from must be before to")
+	}
+
+	return fromTime, toTime, nil
+}
+
+// scrubDashboard removes from the dashboard what a snapshot can't use, as the frontend does before saving a snapshot:
+// the variables keep only their current value and the annotations, which are not captured, are removed.
+func scrubDashboard(dashboard *simplejson.Json, vars templating.Variables, from time.Time, to time.Time) {
+	templating.SetCurrentValues(dashboard, vars)
+	for _, obj := range dashboard.GetPath("templating", "list").MustArray() {
+		variable := simplejson.NewFromAny(obj)
+		variable.Set("query", "")
+		variable.Set("options", []any{variable.Get("current").Interface()})
+		// never refresh
+		variable.Set("refresh", 0)
+	}
+
+	dashboard.SetPath([]string{"annotations", "list"}, []any{})
+	dashboard.Set("time", map[string]any{
+		"from": from.UTC().Format(time.RFC3339),
+		"to":   to.UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,194 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/dashboardsnapshots"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/query"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const dashboardJSON = `{
+	"uid": "status",
+	"title": "Status",
+	"time": {"from": "now-6h", "to": "now"},
+	"templating": {
+		"list": [
+			{"name": "region", "type": "query", "query": "label_values(region)", "current": {"text": "eu", "value": "eu"}, "options": [], "refresh": 1}
+		]
+	},
+	"annotations": {"list": [{"name": "Annotations & Alerts", "builtIn": 1}]},
+	"panels": [
+		{
+			"id": 1,
+			"type": "timeseries",
+			"datasource": {"type": "prometheus", "uid": "prom"},
+			"targets": [
+				{"refId": "A", "expr": "up{region=\"$region\"}"},
+				{"refId": "B", "expr": "hidden", "hide": true}
+			]
+		},
+		{
+			"id": 2,
+			"type": "row",
+			"collapsed": true,
+			"panels": [
+				{"id": 3, "type": "stat", "targets": [{"refId": "A", "expr": "count(up)"}]}
+			]
+		}
+	]
+}`
+
+func setupCapture(t *testing.T) (*Service, *query.FakeQueryService, *dashboardsnapshots.MockService) {
+	t.Helper()
+
+	dashboardData, err := simplejson.NewJson([]byte(dashboardJSON))
+	require.NoError(t, err)
+
+	dashboardService := &dashboards.FakeDashboardService{}
+	dashboardService.On("GetDashboard", mock.Anything, &dashboards.GetDashboardQuery{UID: "status", OrgID: 1}).
+		Return(&dashboards.Dashboard{UID: "status", OrgID: 1, Title: "Status", Data: dashboardData}, nil)
+	dashboardService.On("GetDashboard", mock.Anything, mock.Anything).Return(nil, dashboards.ErrDashboardNotFound)
+
+	dataSourceService := &fakes.FakeDataSourceService{DataSources: []*datasources.DataSource{
+		{OrgID: 1, UID: "prom", Type: "prometheus"},
+		{OrgID: 1, UID: "default", Type: "loki", IsDefault: true},
+	}}
+
+	queryService := &query.FakeQueryService{}
+	snapshotService := dashboardsnapshots.NewMockService(t)
+
+	s := ProvideService(setting.NewCfg(), dashboardService, dataSourceService, snapshotService, queryService, nil)
+	return s, queryService, snapshotService
+}
+
+func TestCaptureDashboardSnapshot(t *testing.T) {
+	signedInUser := &user.SignedInUser{UserID: 3, OrgID: 1}
+
+	t.Run("should store the data of every panel", func(t *testing.T) {
+		s, queryService, snapshotService := setupCapture(t)
+
+		var requests []dtos.MetricRequest
+		queryService.On("QueryData", mock.Anything, signedInUser, false, mock.Anything).
+			Run(func(args mock.Arguments) {
+				requests = append(requests, args.Get(3).(dtos.MetricRequest))
+			}).
+			Return(&backend.QueryDataResponse{Responses: backend.Responses{
+				"A": {Frames: data.Frames{data.NewFrame("up", data.NewField("value", nil, []float64{1, 2}))}},
+			}}, nil)
+
+		var created *dashboardsnapshots.CreateDashboardSnapshotCommand
+		snapshotService.On("CreateDashboardSnapshot", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				created = args.Get(1).(*dashboardsnapshots.CreateDashboardSnapshotCommand)
+			}).
+			Return(&dashboardsnapshots.DashboardSnapshot{Key: "key"}, nil)
+
+		result, err := s.CaptureDashboardSnapshot(context.Background(), signedInUser, &dashboardsnapshots.CaptureDashboardSnapshotCommand{
+			DashboardUID: "status",
+			From:         "now-1h",
+			To:           "now",
+			Variables:    map[string][]string{"region": {"us"}},
+			Expires:      60,
+			OrgID:        1,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "key", result.Key)
+
+		// the hidden query is not sent and the panel of the collapsed row uses the default data source
+		require.Len(t, requests, 2)
+		require.Len(t, requests[0].Queries, 1)
+		require.Equal(t, `up{region="us"}`, requests[0].Queries[0].Get("expr").MustString())
+		require.Equal(t, int64(1000), requests[0].Queries[0].Get("maxDataPoints").MustInt64())
+		require.NotEqual(t, int64(0), requests[0].Queries[0].Get("intervalMs").MustInt64())
+		require.Equal(t, "default", requests[1].Queries[0].GetPath("datasource", "uid").MustString())
+
+		require.Equal(t, "Status", created.Name)
+		require.Equal(t, int64(60), created.Expires)
+		require.Equal(t, int64(3), created.UserID)
+		require.Len(t, created.Key, 32)
+		require.Len(t, created.DeleteKey, 32)
+
+		raw, err := created.Dashboard.MarshalJSON()
+		require.NoError(t, err)
+		snapshot, err := simplejson.NewJson(raw)
+		require.NoError(t, err)
+
+		panel := snapshot.Get("panels").GetIndex(0)
+		require.Empty(t, panel.Get("targets").MustArray())
+		_, hasDatasource := panel.CheckGet("datasource")
+		require.False(t, hasDatasource)
+		frames := panel.Get("snapshotData").MustArray()
+		require.Len(t, frames, 1)
+		frame := simplejson.NewFromAny(frames[0])
+		require.Equal(t, "up", frame.GetPath("schema", "name").MustString())
+		require.Equal(t, "A", frame.GetPath("schema", "refId").MustString())
+
+		nested := snapshot.Get("panels").GetIndex(1).Get("panels").GetIndex(0)
+		require.Len(t, nested.Get("snapshotData").MustArray(), 1)
+
+		variable := snapshot.GetPath("templating", "list").GetIndex(0)
+		require.Equal(t, "us", variable.GetPath("current", "value").MustString())
+		require.Equal(t, "", variable.Get("query").MustString())
+		require.Equal(t, 0, variable.Get("refresh").MustInt())
+		require.Empty(t, snapshot.GetPath("annotations", "list").MustArray())
+		require.Equal(t, "/d/status", snapshot.GetPath("snapshot", "originalUrl").MustString())
+	})
+
+	t.Run("should reject unknown template variables", func(t *testing.T) {
+		s, _, _ := setupCapture(t)
+
+		_, err := s.CaptureDashboardSnapshot(context.Background(), signedInUser, &dashboardsnapshots.CaptureDashboardSnapshotCommand{
+			DashboardUID: "status",
+			Variables:    map[string][]string{"env": {"prod"}},
+			OrgID:        1,
+		})
+		require.ErrorIs(t, err, dashboardsnapshots.ErrCaptureBadRequest)
+	})
+
+	t.Run("should reject invalid time ranges", func(t *testing.T) {
+		s, _, _ := setupCapture(t)
+
+		_, err := s.CaptureDashboardSnapshot(context.Background(), signedInUser, &dashboardsnapshots.CaptureDashboardSnapshotCommand{
+			DashboardUID: "status",
+			From:         "now",
+			To:           "now-1h",
+			OrgID:        1,
+		})
+		require.ErrorIs(t, err, dashboardsnapshots.ErrCaptureBadRequest)
+	})
+
+	t.Run("should fail when the dashboard does not exist", func(t *testing.T) {
+		s, _, _ := setupCapture(t)
+
+		_, err := s.CaptureDashboardSnapshot(context.Background(), signedInUser, &dashboardsnapshots.CaptureDashboardSnapshotCommand{
+			DashboardUID: "missing",
+			OrgID:        1,
+		})
+		require.ErrorIs(t, err, dashboards.ErrDashboardNotFound)
+	})
+
+	t.Run("should not store a snapshot when a query cannot run", func(t *testing.T) {
+		s, queryService, _ := setupCapture(t)
+		queryService.On("QueryData", mock.Anything, mock.Anything, false, mock.Anything).Return(nil, errors.New("access denied"))
+
+		_, err := s.CaptureDashboardSnapshot(context.Background(), signedInUser, &dashboardsnapshots.CaptureDashboardSnapshotCommand{
+			DashboardUID: "status",
+			OrgID:        1,
+		})
+		require.ErrorContains(t, err, "access denied")
+	})
+}
@@ -0,0 +1,78 @@
+package capture
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/dashboardsnapshots"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// scheduleCheckInterval is how often the schedules are checked. The server lock spaces the captures of a schedule
+// by its interval across all the instances, so the captures may be late by this much.
+const scheduleCheckInterval = time.Minute
+
+// IsDisabled returns true when there is no dashboard to capture periodically.
+func (s *Service) IsDisabled() bool {
+	return !s.cfg.SnapshotEnabled || len(s.cfg.SnapshotCaptureSchedules) == 0
+}
+
+// Run captures the dashboards configured in the [snapshot_capture.<name>] sections, each at its own interval.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, schedule := range s.cfg.SnapshotCaptureSchedules {
+				s.runSchedule(ctx, schedule)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Service) runSchedule(ctx context.Context, schedule setting.SnapshotCaptureSchedule) {
+	err := s.serverLock.LockAndExecute(ctx, "capture dashboard snapshot "+schedule.Name, schedule.Interval, func(ctx context.Context) {
+		logger := s.log.FromContext(ctx).New("schedule", schedule.Name, "dashboard", schedule.DashboardUID)
+
+		snapshot, err := s.CaptureDashboardSnapshot(ctx, scheduleUser(schedule), &dashboardsnapshots.CaptureDashboardSnapshotCommand{
+			DashboardUID: schedule.DashboardUID,
+			From:         schedule.From,
+			To:           schedule.To,
+			Variables:    schedule.Variables,
+			Name:         schedule.SnapshotName,
+			Expires:      int64(schedule.Expires.Seconds()),
+			OrgID:        schedule.OrgID,
+		})
+		if err != nil {
+			logger.Error("Failed to capture dashboard snapshot", "error", err)
+			return
+		}
+		logger.Info("Captured dashboard snapshot", "key", snapshot.Key)
+	})
+	if err != nil {
+		s.log.Error("Failed to lock dashboard snapshot capture", "schedule", schedule.Name, "error", err)
+	}
+}
+
+// scheduleUser is the identity scheduled captures run with. Schedules are set up by the server administrator,
+// so it can query every data source of the organization but only read the captured dashboard.
+func scheduleUser(schedule setting.SnapshotCaptureSchedule) *user.SignedInUser {
+	return &user.SignedInUser{
+		OrgID: schedule.OrgID,
+		Login: "snapshot-capture",
+		Permissions: map[int64]map[string][]string{
+			schedule.OrgID: {
+				datasources.ActionQuery:         {datasources.ScopeAll},
+				datasources.ActionRead:          {datasources.ScopeAll},
+				dashboards.ActionDashboardsRead: {dashboards.ScopeDashboardsProvider.GetResourceScopeUID(schedule.DashboardUID)},
+			},
+		},
+	}
+}
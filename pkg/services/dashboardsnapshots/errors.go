@@ -5,3 +5,5 @@ import (
 )
 
 var ErrBaseNotFound = errutil.NotFound("dashboardsnapshots.not-found", errutil.WithPublicMessage("Snapshot not found"))
+
+var ErrCaptureBadRequest = errutil.BadRequest("dashboardsnapshots.capture.bad-request")
@@ -69,6 +69,37 @@ type CreateDashboardSnapshotCommand struct {
 	DashboardEncrypted []byte `json:"-"`
 }
 
+// CaptureDashboardSnapshotCommand creates a snapshot from data queried by the server rather than sent by the browser.
+// swagger:model
+type CaptureDashboardSnapshotCommand struct {
+	// UID of the dashboard to capture.
+	// required:true
+	DashboardUID string `json:"dashboardUid" binding:"Required"`
+
+	// Start of the captured time range, absolute or relative such as `now-6h`. Defaults to the time range saved in the dashboard.
+	// required:false
+	From string `json:"from"`
+
+	// End of the captured time range, absolute or relative such as `now`. Defaults to the time range saved in the dashboard.
+	// required:false
+	To string `json:"to"`
+
+	// Values of the template variables, overriding the ones saved in the dashboard.
+	// required:false
+	Variables map[string][]string `json:"variables"`
+
+	// Snapshot name. Defaults to the title of the dashboard.
+	// required:false
+	Name string `json:"name"`
+
+	// When the snapshot should expire in seconds. Default is never to expire.
+	// required:false
+	// default:0
+	Expires int64 `json:"expires"`
+
+	OrgID int64 `json:"-"`
+}
+
 type DeleteDashboardSnapshotCommand struct {
 	DeleteKey string `json:"-"`
 }
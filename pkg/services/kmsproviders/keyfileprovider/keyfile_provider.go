@@ -0,0 +1,192 @@
+// Package keyfileprovider encrypts the data keys of envelope encryption with AES-256-GCM keys read from a keyring
+// file, kept out of the Grafana configuration. The file has one key per line, with its id:
+//
+//	# <key id> <base64 of 32 random bytes>
+//	2024-01 W1ZsbSqQGuL+lD2nYvB0Bq0LxNqXLWz8KtJ5DQ5Y1aU=
+//	2024-07 x0hxz2Jj8KmOa7RYDBd1rSkh8mKpjMQ1bUQeVg7R9p8=
+//
+// The last key of the file encrypts, all of them decrypt. To rotate the key, append a new one, run
+// `grafana cli admin secrets-migration re-encrypt-data-keys`, then remove the old one. The file is read again when
+// it changes.
+package keyfileprovider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/secrets"
+)
+
+const (
+	// formatVersion is the first byte of the encrypted data keys
+	formatVersion = 1
+	keyLength     = 32
+	maxKeyIDLen   = 255
+)
+
+var ErrInvalidCiphertext = errors.New("the data key wasn't encrypted by a key file provider")
+
+type keyring struct {
+	keys map[string]cipher.AEAD
+	// current is the id of the key that encrypts
+	current string
+}
+
+type provider struct {
+	path string
+	log  log.Logger
+
+	mtx     sync.Mutex
+	keyring *keyring
+	modTime time.Time
+	size    int64
+}
+
+// New returns a provider reading the keys of the file at path, the file must contain at least one key
+func New(path string) (secrets.Provider, error) {
+	if path == "" {
+		return nil, errors.New("key_file is required")
+	}
+	p := &provider{path: path, log: log.New("kmsproviders.keyfile")}
+	if _, err := p.load(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *provider) Encrypt(_ context.Context, blob []byte) ([]byte, error) {
+	kr, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	aead := kr.keys[kr.current]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	// the id of the key is authenticated, a data key can't be passed off as encrypted by another key
+	header := append([]byte{formatVersion, byte(len(kr.current))}, kr.current...)
+	out := append(header, nonce...)
+	return aead.Seal(out, nonce, blob, []byte(kr.current)), nil
+}
+
+func (p *provider) Decrypt(_ context.Context, blob []byte) ([]byte, error) {
+	if len(blob) < 2 || blob[0] != formatVersion {
+		return nil, ErrInvalidCiphertext
+	}
+	idLen := int(blob[1])
+	if len(blob) < 2+idLen {
+		return nil, ErrInvalidCiphertext
+	}
+	id := string(blob[2 : 2+idLen])
+
+	kr, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	aead, ok := kr.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("the key %s isn't in the key file %s anymore", id, p.path)
+	}
+
+	rest := blob[2+idLen:]
+	if len(rest) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	return aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], []byte(id))
+}
+
+// load returns the keys of the file, read again when its modification time or size changed
+func (p *provider) load() (*keyring, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the key file: %w", err)
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.keyring != nil && info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return p.keyring, nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the key file: %w", err)
+	}
+	kr, err := parseKeyring(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key file %s: %w", p.path, err)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		p.log.Warn("The key file can be read by other users than the owner", "path", p.path, "mode", info.Mode().Perm())
+	}
+
+	if p.keyring != nil {
+		p.log.Info("Reloaded the key file", "path", p.path, "keys", len(kr.keys), "current", kr.current)
+	}
+	p.keyring, p.modTime, p.size = kr, info.ModTime(), info.Size()
+	return kr, nil
+}
+
+func parseKeyring(data []byte) (*keyring, error) {
+	kr := &keyring{keys: map[string]cipher.AEAD{}}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a key id and a base64 key", n)
+		}
+		id := fields[0]
+		if len(id) > maxKeyIDLen {
+			return nil, fmt.Errorf("line %d: the key id is longer than %d characters", n, maxKeyIDLen)
+		}
+		if _, ok := kr.keys[id]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key id %s", n, id)
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: the key isn't valid base64: %w", n, err)
+		}
+		if len(key) != keyLength {
+			return nil, fmt.Errorf("line %d: the key has %d bytes, expected %d", n, len(key), keyLength)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		kr.keys[id] = aead
+		kr.current = id
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(kr.keys) == 0 {
+		return nil, errors.New("the file doesn't contain any key")
+	}
+	return kr, nil
+}
@@ -0,0 +1,106 @@
+package keyfileprovider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T, id string) string {
+	t.Helper()
+	key := make([]byte, keyLength)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return id + " " + base64.StdEncoding.EncodeToString(key) + "\n"
+}
+
+func writeKeyFile(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "")), 0o600))
+	// the file is read again when its modification time changes, which may have a coarse resolution
+	modTime := time.Now().Add(time.Duration(len(lines)) * time.Second)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "grafana.keys")
+	first, second := newKey(t, "2024-01"), newKey(t, "2024-07")
+	writeKeyFile(t, path, "# keys of the data keys\n", first)
+
+	p, err := New(path)
+	require.NoError(t, err)
+
+	encrypted, err := p.Encrypt(ctx, []byte("data key"))
+	require.NoError(t, err)
+	require.NotContains(t, string(encrypted), "data key")
+	decrypted, err := p.Decrypt(ctx, encrypted)
+	require.NoError(t, err)
+	require.Equal(t, []byte("data key"), decrypted)
+
+	t.Run("should encrypt with the last key after a rotation", func(t *testing.T) {
+		writeKeyFile(t, path, first, second)
+
+		rotated, err := p.Encrypt(ctx, []byte("data key"))
+		require.NoError(t, err)
+		require.Equal(t, "2024-07", string(rotated[2:2+rotated[1]]))
+
+		// the data keys encrypted with the previous key can still be decrypted
+		decrypted, err := p.Decrypt(ctx, encrypted)
+		require.NoError(t, err)
+		require.Equal(t, []byte("data key"), decrypted)
+
+		writeKeyFile(t, path, second)
+		_, err = p.Decrypt(ctx, encrypted)
+		require.ErrorContains(t, err, "the key 2024-01 isn't in the key file")
+
+		decrypted, err = p.Decrypt(ctx, rotated)
+		require.NoError(t, err)
+		require.Equal(t, []byte("data key"), decrypted)
+	})
+
+	t.Run("should reject tampered data keys", func(t *testing.T) {
+		encrypted, err := p.Encrypt(ctx, []byte("data key"))
+		require.NoError(t, err)
+		encrypted[len(encrypted)-1] ^= 1
+		_, err = p.Decrypt(ctx, encrypted)
+		require.Error(t, err)
+
+		_, err = p.Decrypt(ctx, []byte("not encrypted"))
+		require.ErrorIs(t, err, ErrInvalidCiphertext)
+	})
+}
+
+func TestParseKeyring(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(make([]byte, keyLength))
+
+	tests := []struct {
+		name string
+		file string
+		err  string
+	}{
+		{name: "empty", file: "# no keys\n", err: "doesn't contain any key"},
+		{name: "missing id", file: key + "\n", err: "line 1: expected a key id and a base64 key"},
+		{name: "duplicate id", file: "a " + key + "\na " + key + "\n", err: "line 2: duplicate key id a"},
+		{name: "invalid base64", file: "a not-base64\n", err: "isn't valid base64"},
+		{name: "short key", file: "a " + base64.StdEncoding.EncodeToString([]byte("short")) + "\n", err: "the key has 5 bytes, expected 32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseKeyring([]byte(tt.file))
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+
+	kr, err := parseKeyring([]byte("a " + key + "\n\n# comment\nb " + key + "\n"))
+	require.NoError(t, err)
+	require.Len(t, kr.keys, 2)
+	require.Equal(t, "b", kr.current)
+}
@@ -12,6 +12,13 @@ const (
 	// which fallbacks to Grafana's secret key. See the
 	// defaultprovider package for further information.
 	Default = "secretKey.v1"
+
+	// KeyFile is the kind of the providers reading the key encryption keys from a file, see the keyfileprovider
+	// package
+	KeyFile = "keyfile"
+	// VaultTransit is the kind of the providers encrypting with the transit secrets engine of HashiCorp Vault, see
+	// the vaulttransitprovider package
+	VaultTransit = "vaulttransit"
 )
 
 type Service interface {
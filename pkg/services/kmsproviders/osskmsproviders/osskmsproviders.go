@@ -1,10 +1,16 @@
 package osskmsproviders
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/encryption"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/kmsproviders"
 	grafana "github.com/grafana/grafana/pkg/services/kmsproviders/defaultprovider"
+	"github.com/grafana/grafana/pkg/services/kmsproviders/keyfileprovider"
+	"github.com/grafana/grafana/pkg/services/kmsproviders/vaulttransitprovider"
 	"github.com/grafana/grafana/pkg/services/secrets"
 	"github.com/grafana/grafana/pkg/setting"
 )
@@ -13,6 +19,7 @@ type Service struct {
 	enc      encryption.Internal
 	cfg      *setting.Cfg
 	features featuremgmt.FeatureToggles
+	log      log.Logger
 }
 
 func ProvideService(enc encryption.Internal, cfg *setting.Cfg, features featuremgmt.FeatureToggles) Service {
@@ -20,11 +27,68 @@ func ProvideService(enc encryption.Internal, cfg *setting.Cfg, features featurem
 		enc:      enc,
 		cfg:      cfg,
 		features: features,
+		log:      log.New("kmsproviders"),
 	}
 }
 
+// Provide returns the default provider, and the key file and Vault transit providers configured in
+// [security.encryption.<kind>.<key name>] sections and listed in available_encryption_providers
 func (s Service) Provide() (map[secrets.ProviderID]secrets.Provider, error) {
-	return map[secrets.ProviderID]secrets.Provider{
+	providers := map[secrets.ProviderID]secrets.Provider{
 		kmsproviders.Default: grafana.New(s.cfg, s.enc),
-	}, nil
+	}
+
+	for _, id := range s.configuredProviders() {
+		kind, err := id.Kind()
+		if err != nil {
+			return nil, err
+		}
+
+		section := s.cfg.SectionWithEnvOverrides("security.encryption." + string(id))
+		var provider secrets.Provider
+		switch kind {
+		case kmsproviders.KeyFile:
+			provider, err = keyfileprovider.New(section.Key("key_file").String())
+		case kmsproviders.VaultTransit:
+			provider, err = vaulttransitprovider.New(vaulttransitprovider.Config{
+				URL:               section.Key("url").String(),
+				TransitEnginePath: section.Key("transit_engine_path").MustString("transit"),
+				KeyRing:           section.Key("key_ring").String(),
+				TokenFile:         section.Key("token_file").String(),
+				Namespace:         section.Key("namespace").String(),
+				CACert:            section.Key("ca_cert").String(),
+				TLSSkipVerify:     section.Key("tls_skip_verify").MustBool(false),
+				Timeout:           section.Key("timeout").MustDuration(0),
+			})
+		default:
+			// the providers of Grafana Enterprise
+			s.log.Debug("Skipping encryption provider of an unknown kind", "provider", id)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure encryption provider %s: %w", id, err)
+		}
+		providers[id] = provider
+	}
+
+	return providers, nil
+}
+
+// configuredProviders returns the providers listed in available_encryption_providers and the current one
+func (s Service) configuredProviders() []secrets.ProviderID {
+	security := s.cfg.SectionWithEnvOverrides("security")
+	names := strings.Fields(strings.ReplaceAll(security.Key("available_encryption_providers").String(), ",", " "))
+	names = append(names, security.Key("encryption_provider").String())
+
+	seen := map[secrets.ProviderID]bool{}
+	ids := make([]secrets.ProviderID, 0, len(names))
+	for _, name := range names {
+		id := kmsproviders.NormalizeProviderID(secrets.ProviderID(name))
+		if id == "" || id == kmsproviders.Default || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
 }
@@ -0,0 +1,75 @@
+package osskmsproviders
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/infra/usagestats"
+	encryptionprovider "github.com/grafana/grafana/pkg/services/encryption/provider"
+	encryptionservice "github.com/grafana/grafana/pkg/services/encryption/service"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/kmsproviders"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func setupService(t *testing.T, rawCfg string) Service {
+	t.Helper()
+	raw, err := ini.Load([]byte(rawCfg))
+	require.NoError(t, err)
+	cfg := &setting.Cfg{Raw: raw}
+
+	enc, err := encryptionservice.ProvideEncryptionService(tracing.InitializeTracerForTest(), encryptionprovider.Provider{}, &usagestats.UsageStatsMock{}, cfg)
+	require.NoError(t, err)
+	return ProvideService(enc, cfg, featuremgmt.WithFeatures())
+}
+
+func TestService_Provide(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "grafana.keys")
+	require.NoError(t, os.WriteFile(keyFile, []byte("2024-01 "+base64.StdEncoding.EncodeToString(make([]byte, 32))+"\n"), 0o600))
+	t.Setenv("VAULT_TOKEN", "s.token")
+
+	t.Run("should only provide the default provider without configuration", func(t *testing.T) {
+		providers, err := setupService(t, "[security]\nsecret_key = sdDkslslld\n").Provide()
+		require.NoError(t, err)
+		require.Len(t, providers, 1)
+		require.Contains(t, providers, secrets.ProviderID(kmsproviders.Default))
+	})
+
+	t.Run("should provide the key file and vault transit providers", func(t *testing.T) {
+		providers, err := setupService(t, `
+[security]
+secret_key = sdDkslslld
+encryption_provider = keyfile.v1
+available_encryption_providers = keyfile.v1 vaulttransit.v1 awskms.v1
+
+[security.encryption.keyfile.v1]
+key_file = `+keyFile+`
+
+[security.encryption.vaulttransit.v1]
+url = http://localhost:8200
+key_ring = grafana
+`).Provide()
+		require.NoError(t, err)
+		require.Len(t, providers, 3)
+		require.Contains(t, providers, secrets.ProviderID("keyfile.v1"))
+		require.Contains(t, providers, secrets.ProviderID("vaulttransit.v1"))
+	})
+
+	t.Run("should fail when a provider is misconfigured", func(t *testing.T) {
+		_, err := setupService(t, `
+[security]
+encryption_provider = keyfile.v1
+
+[security.encryption.keyfile.v1]
+key_file = `+filepath.Join(t.TempDir(), "missing")+`
+`).Provide()
+		require.ErrorContains(t, err, "failed to configure encryption provider keyfile.v1")
+	})
+}
@@ -0,0 +1,183 @@
+// Package vaulttransitprovider encrypts the data keys of envelope encryption with the transit secrets engine of
+// HashiCorp Vault, or of any server implementing its HTTP API. The key encryption key never leaves Vault, rotating
+// it in Vault and running `grafana cli admin secrets-migration re-encrypt-data-keys` encrypts the data keys with its
+// latest version.
+package vaulttransitprovider
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/secrets"
+)
+
+// tokenEnvVar is read when no token file is configured, like the Vault CLI does
+const tokenEnvVar = "VAULT_TOKEN"
+
+type Config struct {
+	// URL of the Vault server, like http://localhost:8200
+	URL string
+	// TransitEnginePath is the mount path of the transit secrets engine
+	TransitEnginePath string
+	// KeyRing is the name of the transit key
+	KeyRing string
+	// TokenFile contains the token, it's read at every request so that it can be renewed by an agent
+	TokenFile string
+	// Namespace of Vault Enterprise
+	Namespace     string
+	CACert        string
+	TLSSkipVerify bool
+	Timeout       time.Duration
+}
+
+type provider struct {
+	cfg     Config
+	baseURL *url.URL
+	client  *http.Client
+}
+
+func New(cfg Config) (secrets.Provider, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	baseURL, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if cfg.KeyRing == "" {
+		return nil, errors.New("key_ring is required")
+	}
+	if cfg.TransitEnginePath == "" {
+		cfg.TransitEnginePath = "transit"
+	}
+	if cfg.TokenFile == "" && os.Getenv(tokenEnvVar) == "" {
+		return nil, fmt.Errorf("token_file or the %s environment variable is required", tokenEnvVar)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// nolint:gosec
+		InsecureSkipVerify: cfg.TLSSkipVerify,
+	}
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(filepath.Clean(cfg.CACert))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_cert: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("ca_cert doesn't contain any PEM certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &provider{
+		cfg:     cfg,
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: cfg.Timeout, Transport: transport},
+	}, nil
+}
+
+func (p *provider) Encrypt(ctx context.Context, blob []byte) ([]byte, error) {
+	var res struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	if err := p.post(ctx, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(blob)}, &res); err != nil {
+		return nil, err
+	}
+	if res.Data.Ciphertext == "" {
+		return nil, errors.New("vault transit encrypt returned no ciphertext")
+	}
+	// the ciphertext is prefixed by the version of the key, like vault:v2:...
+	return []byte(res.Data.Ciphertext), nil
+}
+
+func (p *provider) Decrypt(ctx context.Context, blob []byte) ([]byte, error) {
+	var res struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := p.post(ctx, "decrypt", map[string]string{"ciphertext": string(blob)}, &res); err != nil {
+		return nil, err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(res.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("vault transit decrypt returned an invalid plaintext: %w", err)
+	}
+	return plaintext, nil
+}
+
+func (p *provider) post(ctx context.Context, operation string, body any, out any) error {
+	token, err := p.token()
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	endpoint := p.baseURL.JoinPath("v1", p.cfg.TransitEnginePath, operation, p.cfg.KeyRing)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", token)
+	if p.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.cfg.Namespace)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault transit %s failed: %w", operation, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		if err := json.Unmarshal(data, &vaultErr); err != nil || len(vaultErr.Errors) == 0 {
+			return fmt.Errorf("vault transit %s failed with status %d", operation, res.StatusCode)
+		}
+		return fmt.Errorf("vault transit %s failed with status %d: %s", operation, res.StatusCode, strings.Join(vaultErr.Errors, "; "))
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("vault transit %s returned an invalid response: %w", operation, err)
+	}
+	return nil
+}
+
+func (p *provider) token() (string, error) {
+	if p.cfg.TokenFile == "" {
+		return os.Getenv(tokenEnvVar), nil
+	}
+	data, err := os.ReadFile(filepath.Clean(p.cfg.TokenFile))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the vault token: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
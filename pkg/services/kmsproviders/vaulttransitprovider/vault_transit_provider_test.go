@@ -0,0 +1,126 @@
+package vaulttransitprovider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTransit is a stand-in for the transit secrets engine, the ciphertexts aren't encrypted but carry the version of
+// the key like the ones of Vault
+type fakeTransit struct {
+	mtx     sync.Mutex
+	token   string
+	version int
+}
+
+func (f *fakeTransit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	fail := func(status int, msg string) {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string][]string{"errors": {msg}})
+	}
+	if r.Header.Get("X-Vault-Token") != f.token || r.Header.Get("X-Vault-Namespace") != "grafana" {
+		fail(http.StatusForbidden, "permission denied")
+		return
+	}
+
+	var body map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	switch r.URL.Path {
+	case "/v1/transit/encrypt/grafana":
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{
+			"ciphertext": fmt.Sprintf("vault:v%d:%s", f.version, body["plaintext"]),
+		}})
+	case "/v1/transit/decrypt/grafana":
+		parts := strings.SplitN(body["ciphertext"], ":", 3)
+		if len(parts) != 3 || parts[0] != "vault" {
+			fail(http.StatusBadRequest, "invalid ciphertext: no prefix")
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"plaintext": parts[2]}})
+	default:
+		fail(http.StatusNotFound, "no handler for route")
+	}
+}
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	transit := &fakeTransit{token: "s.token", version: 1}
+	server := httptest.NewServer(transit)
+	t.Cleanup(server.Close)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("s.token\n"), 0o600))
+
+	p, err := New(Config{URL: server.URL, KeyRing: "grafana", TokenFile: tokenFile, Namespace: "grafana"})
+	require.NoError(t, err)
+
+	encrypted, err := p.Encrypt(ctx, []byte("data key"))
+	require.NoError(t, err)
+	require.Equal(t, "vault:v1:"+base64.StdEncoding.EncodeToString([]byte("data key")), string(encrypted))
+
+	decrypted, err := p.Decrypt(ctx, encrypted)
+	require.NoError(t, err)
+	require.Equal(t, []byte("data key"), decrypted)
+
+	t.Run("should encrypt with the latest version of the key", func(t *testing.T) {
+		transit.mtx.Lock()
+		transit.version = 2
+		transit.mtx.Unlock()
+
+		rotated, err := p.Encrypt(ctx, []byte("data key"))
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(string(rotated), "vault:v2:"))
+	})
+
+	t.Run("should read the token again when it's renewed", func(t *testing.T) {
+		transit.mtx.Lock()
+		transit.token = "s.renewed"
+		transit.mtx.Unlock()
+
+		_, err := p.Decrypt(ctx, encrypted)
+		require.ErrorContains(t, err, "vault transit decrypt failed with status 403: permission denied")
+
+		require.NoError(t, os.WriteFile(tokenFile, []byte("s.renewed"), 0o600))
+		_, err = p.Decrypt(ctx, encrypted)
+		require.NoError(t, err)
+	})
+
+	t.Run("should return the errors of vault", func(t *testing.T) {
+		_, err := p.Decrypt(ctx, []byte("not encrypted"))
+		require.ErrorContains(t, err, "invalid ciphertext: no prefix")
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv(tokenEnvVar, "")
+
+	_, err := New(Config{KeyRing: "grafana", TokenFile: "token"})
+	require.ErrorContains(t, err, "url is required")
+
+	_, err = New(Config{URL: "http://localhost:8200", TokenFile: "token"})
+	require.ErrorContains(t, err, "key_ring is required")
+
+	_, err = New(Config{URL: "http://localhost:8200", KeyRing: "grafana"})
+	require.ErrorContains(t, err, "token_file or the VAULT_TOKEN environment variable is required")
+
+	t.Setenv(tokenEnvVar, "s.token")
+	_, err = New(Config{URL: "http://localhost:8200", KeyRing: "grafana"})
+	require.NoError(t, err)
+}
@@ -330,7 +330,7 @@ func (s *Service) identityFromLDAPUser(user *login.ExternalUserInfo) *authn.Iden
 		Groups:          user.Groups,
 		ClientParams: authn.ClientParams{
 			SyncUser:     true,
-			SyncTeams:    true,
+			SyncTeams:    !user.SkipTeamSync,
 			EnableUser:   true,
 			SyncOrgRoles: !s.cfg.SkipOrgRoleSync,
 			AllowSignUp:  s.cfg.AllowSignUp,
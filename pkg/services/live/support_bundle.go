@@ -0,0 +1,56 @@
+package live
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/supportbundles"
+)
+
+func (g *GrafanaLive) supportBundleCollector() supportbundles.Collector {
+	return supportbundles.Collector{
+		UID:               "live",
+		DisplayName:       "Grafana Live",
+		Description:       "Connection, user and channel counts of Grafana Live on every node",
+		IncludedByDefault: false,
+		Default:           true,
+		Fn: func(ctx context.Context) (*supportbundles.SupportItem, error) {
+			hub := g.node.Hub()
+
+			bWriter := bytes.NewBuffer(nil)
+			bWriter.WriteString("# Grafana Live\n\n")
+			bWriter.WriteString(fmt.Sprintf("max_connections: %d  \n", g.Cfg.LiveMaxConnections))
+			haEngine := g.Cfg.LiveHAEngine
+			if haEngine == "" {
+				haEngine = "none"
+			}
+			bWriter.WriteString(fmt.Sprintf("ha_engine: %s  \n", haEngine))
+
+			bWriter.WriteString("\n## This node\n\n")
+			bWriter.WriteString(fmt.Sprintf("connections: %d  \nusers: %d  \nchannels: %d  \n",
+				hub.NumClients(), hub.NumUsers(), hub.NumChannels()))
+
+			info, err := g.node.Info()
+			if err != nil {
+				// the counts of the other nodes are optional, they are unavailable when the HA engine is down
+				bWriter.WriteString(fmt.Sprintf("\nFailed to get the information of the nodes: %s\n", err))
+			} else {
+				bWriter.WriteString("\n## Nodes\n\n")
+				bWriter.WriteString("| Name | UID | Version | Connections | Users | Channels | Uptime |\n")
+				bWriter.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+				for _, node := range info.Nodes {
+					bWriter.WriteString(fmt.Sprintf("| %s | %s | %s | %d | %d | %d | %s |\n",
+						node.Name, node.UID, node.Version, node.NumClients, node.NumUsers, node.NumChannels,
+						time.Duration(node.Uptime)*time.Second))
+				}
+			}
+
+			return &supportbundles.SupportItem{
+				Filename:  "live.md",
+				FileBytes: bWriter.Bytes(),
+			}, nil
+		},
+	}
+}
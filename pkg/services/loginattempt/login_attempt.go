@@ -2,21 +2,52 @@ package loginattempt
 
 import (
 	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
 )
 
+var ErrLockoutNotFound = errutil.NotFound("login-attempt.lockout-not-found", errutil.WithPublicMessage("Lockout not found"))
+
 type Service interface {
 	// Add adds a new login attempt record for provided username
 	Add(ctx context.Context, username, IPAddress string) error
-	// Validate checks if username has to many login attempts inside a window.
-	// Will return true if provided username do not have too many attempts.
-	Validate(ctx context.Context, username string) (bool, error)
-	// Reset resets all login attempts attached to username
+	// Validate checks if username, or the IP address and subnet the login comes from, has to many login attempts.
+	// Will return true if the login isn't locked out.
+	Validate(ctx context.Context, username, IPAddress string) (bool, error)
+	// Reset resets all login attempts and the lockout attached to username
 	Reset(ctx context.Context, username string) error
+	// GetLockouts returns the active lockouts
+	GetLockouts(ctx context.Context) ([]*Lockout, error)
+	// DeleteLockout lifts a lockout and resets the login attempts it was created for
+	DeleteLockout(ctx context.Context, id int64) error
 }
 
 type LoginAttempt struct {
 	Id        int64
 	Username  string
 	IpAddress string
+	Subnet    string
 	Created   int64
 }
+
+// LockoutType is what a lockout applies to
+type LockoutType string
+
+const (
+	LockoutTypeUsername LockoutType = "username"
+	LockoutTypeIP       LockoutType = "ip"
+	LockoutTypeSubnet   LockoutType = "subnet"
+)
+
+// Lockout blocks the logins of a username, or from an IP address or subnet, until it expires.
+// Lockouts get longer every time the same username, IP address or subnet is locked out again.
+type Lockout struct {
+	ID    int64       `json:"id"`
+	Type  LockoutType `json:"type"`
+	Value string      `json:"value"`
+	// Count is the number of consecutive lockouts
+	Count       int64     `json:"count"`
+	LockedUntil time.Time `json:"lockedUntil"`
+	Created     time.Time `json:"created"`
+}
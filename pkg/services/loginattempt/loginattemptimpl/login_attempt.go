@@ -2,12 +2,17 @@ package loginattemptimpl
 
 import (
 	"context"
+	"net"
 	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
@@ -16,20 +21,33 @@ const (
 	loginAttemptsWindow           = time.Minute * 5
 )
 
-func ProvideService(db db.DB, cfg *setting.Cfg, lock *serverlock.ServerLockService) *Service {
+func ProvideService(db db.DB, cfg *setting.Cfg, lock *serverlock.ServerLockService, reg prometheus.Registerer) *Service {
 	return &Service{
-		&xormStore{db: db, now: time.Now},
-		cfg,
-		lock,
-		log.New("login_attempt"),
+		store:   &xormStore{db: db, now: time.Now},
+		cfg:     cfg,
+		lock:    lock,
+		logger:  log.New("login_attempt"),
+		audit:   log.New("login_attempt.audit"),
+		metrics: newMetrics(reg),
+		now:     time.Now,
 	}
 }
 
 type Service struct {
-	store  store
-	cfg    *setting.Cfg
-	lock   *serverlock.ServerLockService
-	logger log.Logger
+	store   store
+	cfg     *setting.Cfg
+	lock    *serverlock.ServerLockService
+	logger  log.Logger
+	audit   log.Logger
+	metrics *metrics
+	now     func() time.Time
+}
+
+// lockoutKey is a username, IP address or subnet the failed login attempts are counted for
+type lockoutKey struct {
+	lockoutType loginattempt.LockoutType
+	value       string
+	maxAttempts int64
 }
 
 func (s *Service) Run(ctx context.Context) error {
@@ -54,37 +72,217 @@ func (s *Service) Add(ctx context.Context, username, IPAddress string) error {
 		return nil
 	}
 
-	_, err := s.store.CreateLoginAttempt(ctx, CreateLoginAttemptCommand{
+	cmd := CreateLoginAttemptCommand{
 		Username:  strings.ToLower(username),
 		IpAddress: IPAddress,
-	})
-	return err
+	}
+	if ip := parseIP(IPAddress); ip != nil {
+		cmd.IpAddress = ip.String()
+		cmd.Subnet = s.subnet(ip)
+	}
+
+	if _, err := s.store.CreateLoginAttempt(ctx, cmd); err != nil {
+		return err
+	}
+
+	s.metrics.failedAttempts.Inc()
+	return nil
 }
 
 func (s *Service) Reset(ctx context.Context, username string) error {
-	return s.store.DeleteLoginAttempts(ctx, DeleteLoginAttemptsCommand{strings.ToLower(username)})
+	username = strings.ToLower(username)
+	if err := s.store.DeleteLoginAttempts(ctx, DeleteLoginAttemptsCommand{Username: username}); err != nil {
+		return err
+	}
+
+	l, err := s.store.GetLockout(ctx, loginattempt.LockoutTypeUsername, username)
+	if err != nil || l == nil {
+		return err
+	}
+	return s.store.DeleteLockout(ctx, l.ID)
 }
 
-func (s *Service) Validate(ctx context.Context, username string) (bool, error) {
+func (s *Service) Validate(ctx context.Context, username, IPAddress string) (bool, error) {
 	if s.cfg.DisableBruteForceLoginProtection {
 		return true, nil
 	}
 
-	loginAttemptCountQuery := GetUserLoginAttemptCountQuery{
-		Username: strings.ToLower(username),
-		Since:    time.Now().Add(-loginAttemptsWindow),
+	for _, key := range s.lockoutKeys(username, IPAddress) {
+		ok, err := s.validateKey(ctx, key)
+		if err != nil || !ok {
+			return ok, err
+		}
 	}
 
-	count, err := s.store.GetUserLoginAttemptCount(ctx, loginAttemptCountQuery)
+	return true, nil
+}
+
+func (s *Service) GetLockouts(ctx context.Context) ([]*loginattempt.Lockout, error) {
+	lockouts, err := s.store.GetActiveLockouts(ctx, s.now())
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+
+	result := make([]*loginattempt.Lockout, 0, len(lockouts))
+	for _, l := range lockouts {
+		result = append(result, l.toDTO())
+	}
+	return result, nil
+}
+
+func (s *Service) DeleteLockout(ctx context.Context, id int64) error {
+	l, err := s.store.GetLockoutByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.DeleteLockout(ctx, id); err != nil {
+		return err
+	}
+
+	cmd := DeleteLoginAttemptsCommand{}
+	switch loginattempt.LockoutType(l.LockoutType) {
+	case loginattempt.LockoutTypeIP:
+		cmd.IpAddress = l.Value
+	case loginattempt.LockoutTypeSubnet:
+		cmd.Subnet = l.Value
+	default:
+		cmd.Username = l.Value
 	}
+	if err := s.store.DeleteLoginAttempts(ctx, cmd); err != nil {
+		return err
+	}
+
+	requester := ""
+	if r, err := identity.GetRequester(ctx); err == nil {
+		requester = r.GetLogin()
+	}
+	s.audit.Info("Lockout deleted", "type", l.LockoutType, "value", l.Value, "lockedUntil", time.Unix(l.LockedUntil, 0), "requester", requester)
+	return nil
+}
+
+// validateKey checks if key is locked out, and locks it out when it has too many failed login attempts
+func (s *Service) validateKey(ctx context.Context, key lockoutKey) (bool, error) {
+	now := s.now()
 
-	if count >= maxInvalidLoginAttempts {
+	l, err := s.store.GetLockout(ctx, key.lockoutType, key.value)
+	if err != nil {
+		return false, err
+	}
+	if l != nil && l.LockedUntil > now.Unix() {
+		s.metrics.blockedLogins.WithLabelValues(string(key.lockoutType)).Inc()
 		return false, nil
 	}
 
-	return true, nil
+	// attempts made before or during the previous lockout don't count toward the next one
+	since := now.Add(-loginAttemptsWindow)
+	if l != nil && l.LockedUntil > since.Unix() {
+		since = time.Unix(l.LockedUntil, 0)
+	}
+
+	count, err := s.countAttempts(ctx, key, since)
+	if err != nil {
+		return false, err
+	}
+	if count < key.maxAttempts {
+		return true, nil
+	}
+
+	if err := s.lockOut(ctx, key, l, count, now); err != nil {
+		return false, err
+	}
+	s.metrics.blockedLogins.WithLabelValues(string(key.lockoutType)).Inc()
+	return false, nil
+}
+
+func (s *Service) countAttempts(ctx context.Context, key lockoutKey, since time.Time) (int64, error) {
+	switch key.lockoutType {
+	case loginattempt.LockoutTypeIP:
+		return s.store.GetLoginAttemptCount(ctx, GetLoginAttemptCountQuery{IpAddress: key.value, Since: since})
+	case loginattempt.LockoutTypeSubnet:
+		return s.store.GetLoginAttemptCount(ctx, GetLoginAttemptCountQuery{Subnet: key.value, Since: since})
+	default:
+		return s.store.GetUserLoginAttemptCount(ctx, GetUserLoginAttemptCountQuery{Username: key.value, Since: since})
+	}
+}
+
+// lockOut creates the lockout of key, or extends its previous one.
+// Every lockout following shortly after the previous one lasts twice as long.
+func (s *Service) lockOut(ctx context.Context, key lockoutKey, previous *lockout, attempts int64, now time.Time) error {
+	settings := s.settings()
+
+	l := previous
+	if l == nil {
+		l = &lockout{LockoutType: string(key.lockoutType), Value: key.value}
+	}
+
+	if previous != nil && now.Sub(time.Unix(previous.LockedUntil, 0)) < settings.MaxLockoutDuration {
+		l.Count = previous.Count + 1
+	} else {
+		l.Count = 1
+	}
+
+	duration := settings.LockoutDuration
+	for i := int64(1); i < l.Count && duration < settings.MaxLockoutDuration; i++ {
+		duration *= 2
+	}
+	if duration > settings.MaxLockoutDuration {
+		duration = settings.MaxLockoutDuration
+	}
+	l.LockedUntil = now.Add(duration).Unix()
+
+	if err := s.store.SaveLockout(ctx, l); err != nil {
+		return err
+	}
+
+	s.metrics.lockouts.WithLabelValues(string(key.lockoutType)).Inc()
+	s.audit.Warn("Logins locked out after too many failed attempts", "type", key.lockoutType, "value", key.value, "attempts", attempts, "lockouts", l.Count, "lockedUntil", time.Unix(l.LockedUntil, 0))
+	return nil
+}
+
+func (s *Service) lockoutKeys(username, IPAddress string) []lockoutKey {
+	settings := s.settings()
+
+	keys := []lockoutKey{{loginattempt.LockoutTypeUsername, strings.ToLower(username), settings.MaxAttempts}}
+
+	ip := parseIP(IPAddress)
+	if ip == nil {
+		return keys
+	}
+	if settings.IPMaxAttempts > 0 {
+		keys = append(keys, lockoutKey{loginattempt.LockoutTypeIP, ip.String(), settings.IPMaxAttempts})
+	}
+	if settings.SubnetMaxAttempts > 0 {
+		keys = append(keys, lockoutKey{loginattempt.LockoutTypeSubnet, s.subnet(ip), settings.SubnetMaxAttempts})
+	}
+	return keys
+}
+
+// settings returns the brute force login protection settings, with defaults for the ones not set
+func (s *Service) settings() setting.BruteForceLoginProtectionSettings {
+	settings := s.cfg.BruteForceLoginProtection
+	if settings.MaxAttempts <= 0 {
+		settings.MaxAttempts = maxInvalidLoginAttempts
+	}
+	if settings.LockoutDuration <= 0 {
+		settings.LockoutDuration = loginAttemptsWindow
+	}
+	if settings.MaxLockoutDuration < settings.LockoutDuration {
+		settings.MaxLockoutDuration = settings.LockoutDuration
+	}
+	return settings
+}
+
+// subnet returns the subnet of ip, in CIDR notation
+func (s *Service) subnet(ip net.IP) string {
+	settings := s.settings()
+
+	if ip4 := ip.To4(); ip4 != nil {
+		mask := net.CIDRMask(settings.IPv4SubnetPrefix, 8*net.IPv4len)
+		return (&net.IPNet{IP: ip4.Mask(mask), Mask: mask}).String()
+	}
+	mask := net.CIDRMask(settings.IPv6SubnetPrefix, 8*net.IPv6len)
+	return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
 }
 
 func (s *Service) cleanup(ctx context.Context) {
@@ -97,9 +295,26 @@ func (s *Service) cleanup(ctx context.Context) {
 		} else {
 			s.logger.Debug("Deleted expired login attempts", "rows affected", deletedLogs)
 		}
+
+		// lockouts are kept for a while after they end so the next ones last longer
+		olderThan := time.Now().Add(-s.settings().MaxLockoutDuration)
+		if deletedLockouts, err := s.store.DeleteExpiredLockouts(ctx, olderThan); err != nil {
+			s.logger.Error("Problem deleting expired lockouts", "error", err.Error())
+		} else {
+			s.logger.Debug("Deleted expired lockouts", "rows affected", deletedLockouts)
+		}
 	})
 
 	if err != nil {
 		s.logger.Error("Failed to lock and execute cleanup of old login attempts", "error", err)
 	}
 }
+
+// parseIP parses the address of a login, as returned by loginattempt.RemoteAddr
+func parseIP(address string) net.IP {
+	address = strings.Trim(strings.TrimSpace(address), "[]")
+	if i := strings.IndexByte(address, '%'); i >= 0 {
+		address = address[:i]
+	}
+	return net.ParseIP(address)
+}
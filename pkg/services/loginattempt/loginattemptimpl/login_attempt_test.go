@@ -3,10 +3,14 @@ package loginattemptimpl
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/loginattempt"
 	"github.com/grafana/grafana/pkg/setting"
 )
@@ -65,15 +69,12 @@ func TestService_Validate(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			cfg := setting.NewCfg()
 			cfg.DisableBruteForceLoginProtection = tt.disabled
-			service := &Service{
-				store: fakeStore{
-					ExpectedCount: tt.loginAttempts,
-					ExpectedErr:   tt.expectedErr,
-				},
-				cfg: cfg,
-			}
+			service := setupTestService(cfg, &fakeStore{
+				ExpectedCount: tt.loginAttempts,
+				ExpectedErr:   tt.expectedErr,
+			})
 
-			ok, err := service.Validate(context.Background(), "test")
+			ok, err := service.Validate(context.Background(), "test", "192.168.1.10")
 			assert.Equal(t, tt.expected, ok)
 			assert.Equal(t, tt.expectedErr, err)
 		})
@@ -85,7 +86,7 @@ func TestLoginAttempts(t *testing.T) {
 	cfg := setting.NewCfg()
 	cfg.DisableBruteForceLoginProtection = false
 	db := db.InitTestDB(t)
-	service := ProvideService(db, cfg, nil)
+	service := ProvideService(db, cfg, nil, prometheus.NewRegistry())
 
 	// add multiple login attempts with different uppercases, they all should be counted as the same user
 	_ = service.Add(ctx, "admin", "[::1]")
@@ -100,9 +101,229 @@ func TestLoginAttempts(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, int64(6), count)
 
-	ok, err := service.Validate(ctx, "admin")
+	ok, err := service.Validate(ctx, "admin", "[::1]")
 	assert.False(t, ok)
 	assert.Nil(t, err)
+
+	// the lockout applies to the username only
+	ok, err = service.Validate(ctx, "editor", "[::1]")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	lockouts, err := service.GetLockouts(ctx)
+	require.NoError(t, err)
+	require.Len(t, lockouts, 1)
+	assert.Equal(t, loginattempt.LockoutTypeUsername, lockouts[0].Type)
+	assert.Equal(t, "admin", lockouts[0].Value)
+
+	// deleting the lockout resets the login attempts
+	require.NoError(t, service.DeleteLockout(ctx, lockouts[0].ID))
+	ok, err = service.Validate(ctx, "admin", "[::1]")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	err = service.DeleteLockout(ctx, lockouts[0].ID)
+	assert.ErrorIs(t, err, loginattempt.ErrLockoutNotFound)
+}
+
+func TestLoginAttempts_IPAddress(t *testing.T) {
+	ctx := context.Background()
+	cfg := setting.NewCfg()
+	cfg.BruteForceLoginProtection = setting.BruteForceLoginProtectionSettings{
+		MaxAttempts:       5,
+		IPMaxAttempts:     3,
+		SubnetMaxAttempts: 4,
+		IPv4SubnetPrefix:  24,
+		IPv6SubnetPrefix:  64,
+	}
+	db := db.InitTestDB(t)
+	service := ProvideService(db, cfg, nil, prometheus.NewRegistry())
+
+	// attempts for different usernames from the same IP address
+	for _, username := range []string{"alice", "bob", "carol"} {
+		require.NoError(t, service.Add(ctx, username, "192.168.1.10"))
+	}
+
+	ok, err := service.Validate(ctx, "dave", "192.168.1.10")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// other addresses of the subnet are below the subnet limit
+	ok, err = service.Validate(ctx, "dave", "192.168.1.11")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, service.Add(ctx, "dave", "192.168.1.11"))
+	ok, err = service.Validate(ctx, "dave", "192.168.1.12")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = service.Validate(ctx, "dave", "192.168.2.10")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	lockouts, err := service.GetLockouts(ctx)
+	require.NoError(t, err)
+	values := make([]string, 0, len(lockouts))
+	for _, l := range lockouts {
+		values = append(values, string(l.Type)+":"+l.Value)
+	}
+	assert.ElementsMatch(t, []string{"ip:192.168.1.10", "subnet:192.168.1.0/24"}, values)
+}
+
+func TestService_ValidateAddress(t *testing.T) {
+	cfg := setting.NewCfg()
+	cfg.BruteForceLoginProtection = setting.BruteForceLoginProtectionSettings{
+		IPMaxAttempts:     10,
+		SubnetMaxAttempts: 20,
+		IPv4SubnetPrefix:  24,
+		IPv6SubnetPrefix:  64,
+	}
+
+	t.Run("should lock out IP address with too many attempts", func(t *testing.T) {
+		store := &fakeStore{ExpectedIPCount: 10}
+		service := setupTestService(cfg, store)
+
+		ok, err := service.Validate(context.Background(), "test", "192.168.1.10")
+		require.NoError(t, err)
+		assert.False(t, ok)
+		require.NotNil(t, store.SavedLockout)
+		assert.Equal(t, string(loginattempt.LockoutTypeIP), store.SavedLockout.LockoutType)
+		assert.Equal(t, "192.168.1.10", store.SavedLockout.Value)
+	})
+
+	t.Run("should lock out subnet with too many attempts", func(t *testing.T) {
+		store := &fakeStore{ExpectedSubnetCount: 20}
+		service := setupTestService(cfg, store)
+
+		ok, err := service.Validate(context.Background(), "test", "[2001:db8::1]")
+		require.NoError(t, err)
+		assert.False(t, ok)
+		require.NotNil(t, store.SavedLockout)
+		assert.Equal(t, string(loginattempt.LockoutTypeSubnet), store.SavedLockout.LockoutType)
+		assert.Equal(t, "2001:db8::/64", store.SavedLockout.Value)
+	})
+
+	t.Run("should only count username attempts when address can't be parsed", func(t *testing.T) {
+		store := &fakeStore{ExpectedIPCount: 10, ExpectedSubnetCount: 20}
+		service := setupTestService(cfg, store)
+
+		ok, err := service.Validate(context.Background(), "test", "unknown")
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("should block logins during active lockout", func(t *testing.T) {
+		store := &fakeStore{ExpectedLockout: &lockout{
+			ID:          1,
+			LockoutType: string(loginattempt.LockoutTypeIP),
+			Value:       "192.168.1.10",
+			Count:       1,
+			LockedUntil: time.Now().Add(time.Minute).Unix(),
+		}}
+		service := setupTestService(cfg, store)
+
+		ok, err := service.Validate(context.Background(), "test", "192.168.1.10")
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Nil(t, store.SavedLockout)
+	})
+}
+
+func TestService_ProgressiveLockout(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name             string
+		previous         *lockout
+		expectedCount    int64
+		expectedDuration time.Duration
+	}{
+		{
+			name:             "should lock out for the lockout duration the first time",
+			expectedCount:    1,
+			expectedDuration: 5 * time.Minute,
+		},
+		{
+			name:             "should double the lockout duration after a recent lockout",
+			previous:         &lockout{ID: 1, Count: 1, LockedUntil: now.Add(-time.Hour).Unix()},
+			expectedCount:    2,
+			expectedDuration: 10 * time.Minute,
+		},
+		{
+			name:             "should keep doubling the lockout duration",
+			previous:         &lockout{ID: 1, Count: 3, LockedUntil: now.Add(-time.Hour).Unix()},
+			expectedCount:    4,
+			expectedDuration: 40 * time.Minute,
+		},
+		{
+			name:             "should cap the lockout duration",
+			previous:         &lockout{ID: 1, Count: 20, LockedUntil: now.Add(-time.Hour).Unix()},
+			expectedCount:    21,
+			expectedDuration: 24 * time.Hour,
+		},
+		{
+			name:             "should start over after an old lockout",
+			previous:         &lockout{ID: 1, Count: 3, LockedUntil: now.Add(-48 * time.Hour).Unix()},
+			expectedCount:    1,
+			expectedDuration: 5 * time.Minute,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := setting.NewCfg()
+			cfg.BruteForceLoginProtection = setting.BruteForceLoginProtectionSettings{
+				MaxAttempts:        5,
+				LockoutDuration:    5 * time.Minute,
+				MaxLockoutDuration: 24 * time.Hour,
+			}
+			if tt.previous != nil {
+				tt.previous.LockoutType = string(loginattempt.LockoutTypeUsername)
+				tt.previous.Value = "test"
+			}
+			store := &fakeStore{ExpectedCount: 5, ExpectedLockout: tt.previous}
+			service := setupTestService(cfg, store)
+			service.now = func() time.Time { return now }
+
+			ok, err := service.Validate(context.Background(), "test", "")
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			require.NotNil(t, store.SavedLockout)
+			assert.Equal(t, tt.expectedCount, store.SavedLockout.Count)
+			assert.Equal(t, now.Add(tt.expectedDuration).Unix(), store.SavedLockout.LockedUntil)
+		})
+	}
+}
+
+func TestService_DeleteLockout(t *testing.T) {
+	t.Run("should delete lockout and the login attempts of its IP address", func(t *testing.T) {
+		store := &fakeStore{ExpectedLockout: &lockout{ID: 2, LockoutType: string(loginattempt.LockoutTypeIP), Value: "192.168.1.10"}}
+		service := setupTestService(setting.NewCfg(), store)
+
+		require.NoError(t, service.DeleteLockout(context.Background(), 2))
+		assert.Equal(t, int64(2), store.DeletedLockoutID)
+		assert.Equal(t, DeleteLoginAttemptsCommand{IpAddress: "192.168.1.10"}, store.DeleteLoginAttemptsCmd)
+	})
+
+	t.Run("should return error for unknown lockout", func(t *testing.T) {
+		service := setupTestService(setting.NewCfg(), &fakeStore{})
+
+		err := service.DeleteLockout(context.Background(), 2)
+		assert.ErrorIs(t, err, loginattempt.ErrLockoutNotFound)
+	})
+}
+
+func setupTestService(cfg *setting.Cfg, store store) *Service {
+	return &Service{
+		store:   store,
+		cfg:     cfg,
+		logger:  log.NewNopLogger(),
+		audit:   log.NewNopLogger(),
+		metrics: newMetrics(nil),
+		now:     time.Now,
+	}
 }
 
 var _ store = new(fakeStore)
@@ -110,21 +331,71 @@ var _ store = new(fakeStore)
 type fakeStore struct {
 	ExpectedErr         error
 	ExpectedCount       int64
+	ExpectedIPCount     int64
+	ExpectedSubnetCount int64
 	ExpectedDeletedRows int64
+	ExpectedLockout     *lockout
+
+	SavedLockout           *lockout
+	DeletedLockoutID       int64
+	DeleteLoginAttemptsCmd DeleteLoginAttemptsCommand
 }
 
-func (f fakeStore) GetUserLoginAttemptCount(ctx context.Context, query GetUserLoginAttemptCountQuery) (int64, error) {
+func (f *fakeStore) GetUserLoginAttemptCount(ctx context.Context, query GetUserLoginAttemptCountQuery) (int64, error) {
 	return f.ExpectedCount, f.ExpectedErr
 }
 
-func (f fakeStore) CreateLoginAttempt(ctx context.Context, command CreateLoginAttemptCommand) (loginattempt.LoginAttempt, error) {
+func (f *fakeStore) GetLoginAttemptCount(ctx context.Context, query GetLoginAttemptCountQuery) (int64, error) {
+	if query.IpAddress != "" {
+		return f.ExpectedIPCount, f.ExpectedErr
+	}
+	return f.ExpectedSubnetCount, f.ExpectedErr
+}
+
+func (f *fakeStore) CreateLoginAttempt(ctx context.Context, command CreateLoginAttemptCommand) (loginattempt.LoginAttempt, error) {
 	return loginattempt.LoginAttempt{}, f.ExpectedErr
 }
 
-func (f fakeStore) DeleteOldLoginAttempts(ctx context.Context, command DeleteOldLoginAttemptsCommand) (int64, error) {
+func (f *fakeStore) DeleteOldLoginAttempts(ctx context.Context, command DeleteOldLoginAttemptsCommand) (int64, error) {
 	return f.ExpectedDeletedRows, f.ExpectedErr
 }
 
-func (f fakeStore) DeleteLoginAttempts(ctx context.Context, cmd DeleteLoginAttemptsCommand) error {
+func (f *fakeStore) DeleteLoginAttempts(ctx context.Context, cmd DeleteLoginAttemptsCommand) error {
+	f.DeleteLoginAttemptsCmd = cmd
+	return f.ExpectedErr
+}
+
+func (f *fakeStore) GetLockout(ctx context.Context, lockoutType loginattempt.LockoutType, value string) (*lockout, error) {
+	if f.ExpectedLockout != nil && f.ExpectedLockout.LockoutType == string(lockoutType) && f.ExpectedLockout.Value == value {
+		return f.ExpectedLockout, f.ExpectedErr
+	}
+	return nil, f.ExpectedErr
+}
+
+func (f *fakeStore) GetLockoutByID(ctx context.Context, id int64) (*lockout, error) {
+	if f.ExpectedLockout == nil || f.ExpectedLockout.ID != id {
+		return nil, loginattempt.ErrLockoutNotFound.Errorf("lockout %d not found", id)
+	}
+	return f.ExpectedLockout, f.ExpectedErr
+}
+
+func (f *fakeStore) GetActiveLockouts(ctx context.Context, after time.Time) ([]*lockout, error) {
+	if f.ExpectedLockout == nil {
+		return []*lockout{}, f.ExpectedErr
+	}
+	return []*lockout{f.ExpectedLockout}, f.ExpectedErr
+}
+
+func (f *fakeStore) SaveLockout(ctx context.Context, l *lockout) error {
+	f.SavedLockout = l
 	return f.ExpectedErr
 }
+
+func (f *fakeStore) DeleteLockout(ctx context.Context, id int64) error {
+	f.DeletedLockoutID = id
+	return f.ExpectedErr
+}
+
+func (f *fakeStore) DeleteExpiredLockouts(ctx context.Context, olderThan time.Time) (int64, error) {
+	return f.ExpectedDeletedRows, f.ExpectedErr
+}
@@ -0,0 +1,49 @@
+package loginattemptimpl
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsSubSystem = "login_attempt"
+	metricsNamespace = "grafana"
+)
+
+type metrics struct {
+	failedAttempts prometheus.Counter
+	blockedLogins  *prometheus.CounterVec
+	lockouts       *prometheus.CounterVec
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	m := &metrics{
+		failedAttempts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "failed_attempts_total",
+			Help:      "Number of failed login attempts",
+		}),
+		blockedLogins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "blocked_logins_total",
+			Help:      "Number of logins blocked by a lockout",
+		}, []string{"type"}),
+		lockouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "lockouts_total",
+			Help:      "Number of lockouts of a username, an IP address or a subnet",
+		}, []string{"type"}),
+	}
+
+	if reg != nil {
+		reg.MustRegister(
+			m.failedAttempts,
+			m.blockedLogins,
+			m.lockouts,
+		)
+	}
+
+	return m
+}
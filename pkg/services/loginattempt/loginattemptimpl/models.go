@@ -2,11 +2,14 @@ package loginattemptimpl
 
 import (
 	"time"
+
+	"github.com/grafana/grafana/pkg/services/loginattempt"
 )
 
 type CreateLoginAttemptCommand struct {
 	Username  string
 	IpAddress string
+	Subnet    string
 }
 
 type GetUserLoginAttemptCountQuery struct {
@@ -14,10 +17,45 @@ type GetUserLoginAttemptCountQuery struct {
 	Since    time.Time
 }
 
+// GetLoginAttemptCountQuery counts the login attempts from an IP address or a subnet
+type GetLoginAttemptCountQuery struct {
+	IpAddress string
+	Subnet    string
+	Since     time.Time
+}
+
 type DeleteOldLoginAttemptsCommand struct {
 	OlderThan time.Time
 }
 
+// DeleteLoginAttemptsCommand deletes the login attempts of a username, or from an IP address or a subnet
 type DeleteLoginAttemptsCommand struct {
-	Username string
+	Username  string
+	IpAddress string
+	Subnet    string
+}
+
+type lockout struct {
+	ID          int64  `xorm:"pk autoincr 'id'"`
+	LockoutType string `xorm:"lockout_type"`
+	Value       string `xorm:"value"`
+	Count       int64  `xorm:"lockout_count"`
+	LockedUntil int64  `xorm:"locked_until"`
+	Created     int64  `xorm:"created"`
+	Updated     int64  `xorm:"updated"`
+}
+
+func (lockout) TableName() string {
+	return "login_lockout"
+}
+
+func (l *lockout) toDTO() *loginattempt.Lockout {
+	return &loginattempt.Lockout{
+		ID:          l.ID,
+		Type:        loginattempt.LockoutType(l.LockoutType),
+		Value:       l.Value,
+		Count:       l.Count,
+		LockedUntil: time.Unix(l.LockedUntil, 0),
+		Created:     time.Unix(l.Created, 0),
+	}
 }
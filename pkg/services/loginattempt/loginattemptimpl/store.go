@@ -18,6 +18,18 @@ type store interface {
 	DeleteOldLoginAttempts(ctx context.Context, cmd DeleteOldLoginAttemptsCommand) (int64, error)
 	DeleteLoginAttempts(ctx context.Context, cmd DeleteLoginAttemptsCommand) error
 	GetUserLoginAttemptCount(ctx context.Context, query GetUserLoginAttemptCountQuery) (int64, error)
+	GetLoginAttemptCount(ctx context.Context, query GetLoginAttemptCountQuery) (int64, error)
+
+	// GetLockout returns the lockout of a username, IP address or subnet, or nil when there's none
+	GetLockout(ctx context.Context, lockoutType loginattempt.LockoutType, value string) (*lockout, error)
+	GetLockoutByID(ctx context.Context, id int64) (*lockout, error)
+	// GetActiveLockouts returns the lockouts ending after the given time
+	GetActiveLockouts(ctx context.Context, after time.Time) ([]*lockout, error)
+	// SaveLockout creates the lockout, or updates it when it has an ID
+	SaveLockout(ctx context.Context, l *lockout) error
+	DeleteLockout(ctx context.Context, id int64) error
+	// DeleteExpiredLockouts deletes the lockouts that ended before the given time
+	DeleteExpiredLockouts(ctx context.Context, olderThan time.Time) (int64, error)
 }
 
 func (xs *xormStore) CreateLoginAttempt(ctx context.Context, cmd CreateLoginAttemptCommand) (result loginattempt.LoginAttempt, err error) {
@@ -25,6 +37,7 @@ func (xs *xormStore) CreateLoginAttempt(ctx context.Context, cmd CreateLoginAtte
 		loginAttempt := loginattempt.LoginAttempt{
 			Username:  cmd.Username,
 			IpAddress: cmd.IpAddress,
+			Subnet:    cmd.Subnet,
 			Created:   xs.now().Unix(),
 		}
 
@@ -58,7 +71,15 @@ func (xs *xormStore) DeleteOldLoginAttempts(ctx context.Context, cmd DeleteOldLo
 
 func (xs *xormStore) DeleteLoginAttempts(ctx context.Context, cmd DeleteLoginAttemptsCommand) error {
 	return xs.db.WithDbSession(ctx, func(sess *db.Session) error {
-		_, err := sess.Exec("DELETE FROM login_attempt WHERE username = ?", cmd.Username)
+		var err error
+		switch {
+		case cmd.IpAddress != "":
+			_, err = sess.Exec("DELETE FROM login_attempt WHERE ip_address = ?", cmd.IpAddress)
+		case cmd.Subnet != "":
+			_, err = sess.Exec("DELETE FROM login_attempt WHERE subnet = ?", cmd.Subnet)
+		default:
+			_, err = sess.Exec("DELETE FROM login_attempt WHERE username = ?", cmd.Username)
+		}
 		return err
 	})
 }
@@ -82,3 +103,93 @@ func (xs *xormStore) GetUserLoginAttemptCount(ctx context.Context, query GetUser
 
 	return total, err
 }
+
+func (xs *xormStore) GetLoginAttemptCount(ctx context.Context, query GetLoginAttemptCountQuery) (int64, error) {
+	var total int64
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		q := sess.Where("created >= ?", query.Since.Unix())
+		if query.IpAddress != "" {
+			q = q.And("ip_address = ?", query.IpAddress)
+		} else {
+			q = q.And("subnet = ?", query.Subnet)
+		}
+
+		var err error
+		total, err = q.Count(new(loginattempt.LoginAttempt))
+		return err
+	})
+	return total, err
+}
+
+func (xs *xormStore) GetLockout(ctx context.Context, lockoutType loginattempt.LockoutType, value string) (*lockout, error) {
+	var l lockout
+	var has bool
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		has, err = sess.Where("lockout_type = ? AND value = ?", string(lockoutType), value).Get(&l)
+		return err
+	})
+	if err != nil || !has {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (xs *xormStore) GetLockoutByID(ctx context.Context, id int64) (*lockout, error) {
+	var l lockout
+	var has bool
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var err error
+		has, err = sess.ID(id).Get(&l)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, loginattempt.ErrLockoutNotFound.Errorf("lockout %d not found", id)
+	}
+	return &l, nil
+}
+
+func (xs *xormStore) GetActiveLockouts(ctx context.Context, after time.Time) ([]*lockout, error) {
+	lockouts := make([]*lockout, 0)
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("locked_until > ?", after.Unix()).Asc("locked_until").Find(&lockouts)
+	})
+	return lockouts, err
+}
+
+func (xs *xormStore) SaveLockout(ctx context.Context, l *lockout) error {
+	return xs.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		l.Updated = xs.now().Unix()
+		if l.ID != 0 {
+			_, err := sess.ID(l.ID).Cols("lockout_count", "locked_until", "updated").Update(l)
+			return err
+		}
+
+		l.Created = l.Updated
+		_, err := sess.Insert(l)
+		return err
+	})
+}
+
+func (xs *xormStore) DeleteLockout(ctx context.Context, id int64) error {
+	return xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec("DELETE FROM login_lockout WHERE id = ?", id)
+		return err
+	})
+}
+
+func (xs *xormStore) DeleteExpiredLockouts(ctx context.Context, olderThan time.Time) (int64, error) {
+	var deletedRows int64
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("DELETE FROM login_lockout WHERE locked_until < ?", olderThan.Unix())
+		if err != nil {
+			return err
+		}
+		deletedRows, err = res.RowsAffected()
+		return err
+	})
+	return deletedRows, err
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
 	"github.com/grafana/grafana/pkg/tests/testsuite"
 )
 
@@ -148,3 +149,108 @@ func TestIntegrationLoginAttemptsDelete(t *testing.T) {
 		require.Equal(t, test.DeletedRows, deletedRows, test.Name)
 	}
 }
+
+func TestIntegrationLoginAttemptsAddressQuery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	now := time.Date(2017, 10, 22, 8, 0, 0, 0, time.Local)
+	s := &xormStore{
+		db:  db.InitTestDB(t),
+		now: func() time.Time { return now },
+	}
+
+	for _, cmd := range []CreateLoginAttemptCommand{
+		{Username: "alice", IpAddress: "192.168.1.10", Subnet: "192.168.1.0/24"},
+		{Username: "bob", IpAddress: "192.168.1.10", Subnet: "192.168.1.0/24"},
+		{Username: "carol", IpAddress: "192.168.1.11", Subnet: "192.168.1.0/24"},
+		{Username: "dave", IpAddress: "2001:db8::1", Subnet: "2001:db8::/64"},
+	} {
+		_, err := s.CreateLoginAttempt(context.Background(), cmd)
+		require.NoError(t, err)
+	}
+
+	count, err := s.GetLoginAttemptCount(context.Background(), GetLoginAttemptCountQuery{IpAddress: "192.168.1.10", Since: now})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	count, err = s.GetLoginAttemptCount(context.Background(), GetLoginAttemptCountQuery{Subnet: "192.168.1.0/24", Since: now})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+
+	count, err = s.GetLoginAttemptCount(context.Background(), GetLoginAttemptCountQuery{Subnet: "192.168.1.0/24", Since: now.Add(time.Second)})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), count)
+
+	require.NoError(t, s.DeleteLoginAttempts(context.Background(), DeleteLoginAttemptsCommand{Subnet: "192.168.1.0/24"}))
+	count, err = s.GetLoginAttemptCount(context.Background(), GetLoginAttemptCountQuery{Subnet: "192.168.1.0/24", Since: now})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), count)
+
+	count, err = s.GetUserLoginAttemptCount(context.Background(), GetUserLoginAttemptCountQuery{Username: "dave", Since: now})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
+
+func TestIntegrationLoginLockouts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	now := time.Date(2017, 10, 22, 8, 0, 0, 0, time.Local)
+	s := &xormStore{
+		db:  db.InitTestDB(t),
+		now: func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	l := &lockout{LockoutType: string(loginattempt.LockoutTypeIP), Value: "192.168.1.10", Count: 1, LockedUntil: now.Add(5 * time.Minute).Unix()}
+	require.NoError(t, s.SaveLockout(ctx, l))
+	require.NotZero(t, l.ID)
+
+	expired := &lockout{LockoutType: string(loginattempt.LockoutTypeUsername), Value: "alice", Count: 1, LockedUntil: now.Add(-time.Hour).Unix()}
+	require.NoError(t, s.SaveLockout(ctx, expired))
+
+	t.Run("should get lockout by type and value", func(t *testing.T) {
+		result, err := s.GetLockout(ctx, loginattempt.LockoutTypeIP, "192.168.1.10")
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		require.Equal(t, l.ID, result.ID)
+
+		result, err = s.GetLockout(ctx, loginattempt.LockoutTypeSubnet, "192.168.1.10")
+		require.NoError(t, err)
+		require.Nil(t, result)
+	})
+
+	t.Run("should update lockout", func(t *testing.T) {
+		l.Count = 2
+		l.LockedUntil = now.Add(10 * time.Minute).Unix()
+		require.NoError(t, s.SaveLockout(ctx, l))
+
+		result, err := s.GetLockoutByID(ctx, l.ID)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), result.Count)
+		require.Equal(t, l.LockedUntil, result.LockedUntil)
+	})
+
+	t.Run("should only return active lockouts", func(t *testing.T) {
+		result, err := s.GetActiveLockouts(ctx, now)
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		require.Equal(t, l.ID, result[0].ID)
+	})
+
+	t.Run("should delete expired lockouts", func(t *testing.T) {
+		deleted, err := s.DeleteExpiredLockouts(ctx, now.Add(-30*time.Minute))
+		require.NoError(t, err)
+		require.Equal(t, int64(1), deleted)
+	})
+
+	t.Run("should delete lockout", func(t *testing.T) {
+		require.NoError(t, s.DeleteLockout(ctx, l.ID))
+
+		_, err := s.GetLockoutByID(ctx, l.ID)
+		require.ErrorIs(t, err, loginattempt.ErrLockoutNotFound)
+	})
+}
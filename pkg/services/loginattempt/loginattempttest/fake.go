@@ -9,8 +9,9 @@ import (
 var _ loginattempt.Service = new(FakeLoginAttemptService)
 
 type FakeLoginAttemptService struct {
-	ExpectedValid bool
-	ExpectedErr   error
+	ExpectedValid    bool
+	ExpectedLockouts []*loginattempt.Lockout
+	ExpectedErr      error
 }
 
 func (f FakeLoginAttemptService) Add(ctx context.Context, username, IPAddress string) error {
@@ -21,6 +22,14 @@ func (f FakeLoginAttemptService) Reset(ctx context.Context, username string) err
 	return f.ExpectedErr
 }
 
-func (f FakeLoginAttemptService) Validate(ctx context.Context, username string) (bool, error) {
+func (f FakeLoginAttemptService) Validate(ctx context.Context, username, IPAddress string) (bool, error) {
 	return f.ExpectedValid, f.ExpectedErr
 }
+
+func (f FakeLoginAttemptService) GetLockouts(ctx context.Context) ([]*loginattempt.Lockout, error) {
+	return f.ExpectedLockouts, f.ExpectedErr
+}
+
+func (f FakeLoginAttemptService) DeleteLockout(ctx context.Context, id int64) error {
+	return f.ExpectedErr
+}
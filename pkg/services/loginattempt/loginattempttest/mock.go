@@ -9,12 +9,17 @@ import (
 var _ loginattempt.Service = new(MockLoginAttemptService)
 
 type MockLoginAttemptService struct {
-	AddCalled      bool
-	ResetCalled    bool
-	ValidateCalled bool
-
-	ExpectedValid bool
-	ExpectedErr   error
+	AddCalled           bool
+	ResetCalled         bool
+	ValidateCalled      bool
+	GetLockoutsCalled   bool
+	DeleteLockoutCalled bool
+	// ValidatedIPAddress is the IP address of the last validated login
+	ValidatedIPAddress string
+
+	ExpectedValid    bool
+	ExpectedLockouts []*loginattempt.Lockout
+	ExpectedErr      error
 }
 
 func (f *MockLoginAttemptService) Add(ctx context.Context, username, IPAddress string) error {
@@ -27,7 +32,18 @@ func (f *MockLoginAttemptService) Reset(ctx context.Context, username string) er
 	return f.ExpectedErr
 }
 
-func (f *MockLoginAttemptService) Validate(ctx context.Context, username string) (bool, error) {
+func (f *MockLoginAttemptService) Validate(ctx context.Context, username, IPAddress string) (bool, error) {
 	f.ValidateCalled = true
+	f.ValidatedIPAddress = IPAddress
 	return f.ExpectedValid, f.ExpectedErr
 }
+
+func (f *MockLoginAttemptService) GetLockouts(ctx context.Context) ([]*loginattempt.Lockout, error) {
+	f.GetLockoutsCalled = true
+	return f.ExpectedLockouts, f.ExpectedErr
+}
+
+func (f *MockLoginAttemptService) DeleteLockout(ctx context.Context, id int64) error {
+	f.DeleteLockoutCalled = true
+	return f.ExpectedErr
+}
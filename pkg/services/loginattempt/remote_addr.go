@@ -0,0 +1,52 @@
+package loginattempt
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RemoteAddr returns the IP address a login comes from. The X-Forwarded-For and X-Real-IP headers are only used when
+// the request is sent by one of the trusted proxies, any client can set them otherwise to spread its attempts.
+func RemoteAddr(req *http.Request, trustedProxies []*net.IPNet) string {
+	addr := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil || !isTrusted(ip, trustedProxies) {
+		return addr
+	}
+
+	// proxies append the address of their client, the last one that isn't a trusted proxy is the client of the login.
+	// The addresses before it are sent by the client and can be forged.
+	if values := req.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		hops := strings.Split(strings.Join(values, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := net.ParseIP(strings.TrimSpace(hops[i]))
+			if hop == nil {
+				break
+			}
+			if !isTrusted(hop, trustedProxies) {
+				return hop.String()
+			}
+			ip = hop
+		}
+		return ip.String()
+	}
+
+	if realIP := net.ParseIP(strings.TrimSpace(req.Header.Get("X-Real-IP"))); realIP != nil {
+		return realIP.String()
+	}
+	return ip.String()
+}
+
+func isTrusted(ip net.IP, trustedProxies []*net.IPNet) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
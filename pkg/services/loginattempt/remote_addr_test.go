@@ -0,0 +1,90 @@
+package loginattempt
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteAddr(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	trustedProxies := []*net.IPNet{proxies}
+
+	tests := []struct {
+		desc          string
+		remoteAddr    string
+		forwardedFor  []string
+		realIP        string
+		expectedAddr  string
+		withoutTrusts bool
+	}{
+		{
+			desc:         "should use address of the connection",
+			remoteAddr:   "203.0.113.7:51234",
+			expectedAddr: "203.0.113.7",
+		},
+		{
+			desc:         "should ignore headers of untrusted clients",
+			remoteAddr:   "203.0.113.7:51234",
+			forwardedFor: []string{"198.51.100.1"},
+			realIP:       "198.51.100.2",
+			expectedAddr: "203.0.113.7",
+		},
+		{
+			desc:          "should ignore headers when no proxy is trusted",
+			remoteAddr:    "10.0.0.1:51234",
+			forwardedFor:  []string{"198.51.100.1"},
+			expectedAddr:  "10.0.0.1",
+			withoutTrusts: true,
+		},
+		{
+			desc:         "should use client address appended by trusted proxy",
+			remoteAddr:   "10.0.0.1:51234",
+			forwardedFor: []string{"198.51.100.1, 203.0.113.7"},
+			expectedAddr: "203.0.113.7",
+		},
+		{
+			desc:         "should skip trusted proxies in chain",
+			remoteAddr:   "10.0.0.1:51234",
+			forwardedFor: []string{"198.51.100.1, 203.0.113.7", "10.0.0.2"},
+			expectedAddr: "203.0.113.7",
+		},
+		{
+			desc:         "should stop at invalid address",
+			remoteAddr:   "10.0.0.1:51234",
+			forwardedFor: []string{"203.0.113.7, unknown, 10.0.0.2"},
+			expectedAddr: "10.0.0.2",
+		},
+		{
+			desc:         "should use X-Real-IP of trusted proxy",
+			remoteAddr:   "10.0.0.1:51234",
+			realIP:       "203.0.113.7",
+			expectedAddr: "203.0.113.7",
+		},
+		{
+			desc:         "should handle IPv6 address of the connection",
+			remoteAddr:   "[2001:db8::1]:51234",
+			expectedAddr: "2001:db8::1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			req := &http.Request{RemoteAddr: tt.remoteAddr, Header: http.Header{}}
+			for _, v := range tt.forwardedFor {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			proxies := trustedProxies
+			if tt.withoutTrusts {
+				proxies = nil
+			}
+			assert.Equal(t, tt.expectedAddr, RemoteAddr(req, proxies))
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package mfa
+
+import (
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+)
+
+var (
+	ErrDisabled = errutil.BadRequest(
+		"mfa.disabled",
+		errutil.WithPublicMessage("Multi-factor authentication is disabled"),
+	)
+	ErrNotEnrolled = errutil.BadRequest(
+		"mfa.not-enrolled",
+		errutil.WithPublicMessage("Multi-factor authentication is not enabled for this user"),
+	)
+	ErrAlreadyEnrolled = errutil.BadRequest(
+		"mfa.already-enrolled",
+		errutil.WithPublicMessage("Multi-factor authentication is already enabled for this user"),
+	)
+	ErrInvalidCode = errutil.BadRequest(
+		"mfa.invalid-code",
+		errutil.WithPublicMessage("Invalid verification code"),
+	)
+	ErrRequiredByPolicy = errutil.Forbidden(
+		"mfa.required-by-policy",
+		errutil.WithPublicMessage("Multi-factor authentication is required and cannot be disabled"),
+	)
+	ErrInvalidChallenge = errutil.Unauthorized(
+		"mfa.invalid-challenge",
+		errutil.WithPublicMessage("Invalid or expired multi-factor authentication challenge"),
+	)
+	// ErrChallengeRequired is returned by a password login when a second factor is needed to create the session.
+	// The challenge token and whether the user must enroll first are part of the public payload.
+	ErrChallengeRequired = errutil.Unauthorized("mfa.challenge-required").MustTemplate(
+		"second factor required for user {{ .Private.UserID }}",
+		errutil.WithPublic("Multi-factor authentication required"),
+	)
+)
+
+// ChallengeRequiredError returns the error a password login fails with until challenge is completed
+func ChallengeRequiredError(userID int64, challenge *Challenge) error {
+	return ErrChallengeRequired.Build(errutil.TemplateData{
+		Private: map[string]any{"UserID": userID},
+		Public: map[string]any{
+			"challenge":          challenge.Token,
+			"enrollmentRequired": challenge.EnrollmentRequired,
+		},
+	})
+}
@@ -0,0 +1,91 @@
+package mfa
+
+import (
+	"context"
+	"time"
+)
+
+// Service manages the TOTP second factor of users authenticating with a password managed by Grafana
+type Service interface {
+	// IsEnabled returns true when multi-factor authentication is enabled on the server
+	IsEnabled() bool
+	// GetStatus returns the second factor status of a user
+	GetStatus(ctx context.Context, userID int64) (*Status, error)
+	// BeginEnrollment generates a new secret and new recovery codes for a user.
+	// They are used to authenticate once the enrollment is confirmed.
+	BeginEnrollment(ctx context.Context, userID int64) (*Enrollment, error)
+	// ConfirmEnrollment enables the second factor of a user when code is valid for the secret of the enrollment
+	ConfirmEnrollment(ctx context.Context, userID int64, code string) error
+	// Verify checks a TOTP code or a recovery code of a user. Each recovery code can only be used once.
+	Verify(ctx context.Context, userID int64, code string) error
+	// RegenerateRecoveryCodes replaces the recovery codes of a user after checking code
+	RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error)
+	// Disable removes the second factor of a user after checking code
+	Disable(ctx context.Context, userID int64, code string) error
+	// Reset removes the second factor of a user without any verification, it is meant for administrators
+	Reset(ctx context.Context, userID int64) error
+	// Challenge returns the challenge a user must complete after a successful password authentication,
+	// or nil when the user doesn't need a second factor
+	Challenge(ctx context.Context, userID int64) (*Challenge, error)
+	// VerifyChallenge returns the user a challenge token was issued to
+	VerifyChallenge(ctx context.Context, token string) (int64, error)
+	// GetOrgPolicy returns the multi-factor authentication policy of an organization
+	GetOrgPolicy(ctx context.Context, orgID int64) (*OrgPolicy, error)
+	// SetOrgPolicy updates the multi-factor authentication policy of an organization
+	SetOrgPolicy(ctx context.Context, policy *OrgPolicy) error
+}
+
+// Status is the second factor status of a user
+type Status struct {
+	// Enabled is true when the user confirmed the enrollment of a second factor
+	Enabled bool `json:"enabled"`
+	// Required is true when the server or one of the organizations of the user requires a second factor
+	Required bool `json:"required"`
+	// RecoveryCodesRemaining is the number of unused recovery codes
+	RecoveryCodesRemaining int `json:"recoveryCodesRemaining"`
+}
+
+// Enrollment holds what a user needs to configure an authenticator app.
+// It is only returned once, when the enrollment begins.
+type Enrollment struct {
+	// Secret is the base32 encoded TOTP secret, for apps that can't scan QR codes
+	Secret string `json:"secret"`
+	// URL is the otpauth:// key URI of the secret
+	URL string `json:"url"`
+	// QRCode is URL encoded as a PNG data URI, for authenticator apps to scan
+	QRCode string `json:"qrCode"`
+	// RecoveryCodes can be used instead of a TOTP code when the authenticator app is lost
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// Challenge is issued after a successful password authentication, the session is only created once it is completed
+type Challenge struct {
+	// Token identifies the challenge, it is signed and expires after a few minutes
+	Token string
+	// EnrollmentRequired is true when the user must enroll a second factor to complete the challenge
+	EnrollmentRequired bool
+}
+
+// OrgPolicy is the multi-factor authentication policy of an organization
+type OrgPolicy struct {
+	ID       int64     `json:"-" xorm:"pk autoincr 'id'"`
+	OrgID    int64     `json:"orgId" xorm:"org_id"`
+	Required bool      `json:"required" xorm:"required"`
+	Updated  time.Time `json:"updated" xorm:"updated"`
+}
+
+func (OrgPolicy) TableName() string {
+	return "org_mfa_policy"
+}
+
+type CodeCommand struct {
+	Code string `json:"code" binding:"Required"`
+}
+
+type ChallengeCommand struct {
+	Challenge string `json:"challenge" binding:"Required"`
+}
+
+type UpdateOrgPolicyCommand struct {
+	Required bool `json:"required"`
+}
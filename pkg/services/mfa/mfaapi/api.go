@@ -0,0 +1,429 @@
+package mfaapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/middleware/requestmeta"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/authn"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+type API struct {
+	mfaService  mfa.Service
+	userService user.Service
+	logger      log.Logger
+}
+
+func ProvideAPI(
+	routeRegister routing.RouteRegister,
+	mfaService mfa.Service,
+	accessControl ac.AccessControl,
+	authnService authn.Service,
+	userService user.Service,
+) *API {
+	api := &API{
+		mfaService:  mfaService,
+		userService: userService,
+		logger:      log.New("mfa-api"),
+	}
+
+	api.registerRoutes(routeRegister, accessControl, authnService)
+	return api
+}
+
+func (api *API) registerRoutes(router routing.RouteRegister, accessControl ac.AccessControl, authnService authn.Service) {
+	authorize := ac.Middleware(accessControl)
+	authorizeInOrg := ac.AuthorizeInOrgMiddleware(accessControl, authnService)
+	reqSignedIn := middleware.ReqSignedIn
+	reqSignedInNoAnonymous := middleware.ReqSignedInNoAnonymous
+
+	// users who must enroll a second factor do it before their session is created, with the challenge of the login
+	router.Post("/login/mfa/enroll", requestmeta.SetOwner(requestmeta.TeamAuth), routing.Wrap(api.beginLoginEnrollment))
+
+	router.Group("/api/user/mfa", func(userRoute routing.RouteRegister) {
+		userRoute.Get("/", routing.Wrap(api.getSignedInUserStatus))
+		userRoute.Post("/enroll", routing.Wrap(api.beginEnrollment))
+		userRoute.Post("/enroll/confirm", routing.Wrap(api.confirmEnrollment))
+		userRoute.Post("/recovery-codes", routing.Wrap(api.regenerateRecoveryCodes))
+		userRoute.Post("/disable", routing.Wrap(api.disable))
+	}, requestmeta.SetOwner(requestmeta.TeamAuth), reqSignedInNoAnonymous)
+
+	router.Group("/api/admin/users/:id/mfa", func(adminUserRoute routing.RouteRegister) {
+		userIDScope := ac.Scope("global.users", "id", ac.Parameter(":id"))
+		adminUserRoute.Get("/", authorizeInOrg(ac.UseGlobalOrg, ac.EvalPermission(ac.ActionUsersRead, userIDScope)), routing.Wrap(api.getUserStatus))
+		adminUserRoute.Delete("/", authorizeInOrg(ac.UseGlobalOrg, ac.EvalPermission(ac.ActionUsersPasswordUpdate, userIDScope)), routing.Wrap(api.resetUser))
+	}, requestmeta.SetOwner(requestmeta.TeamAuth), reqSignedIn)
+
+	router.Group("/api/org/mfa", func(orgRoute routing.RouteRegister) {
+		orgRoute.Get("/", authorize(ac.EvalPermission(ac.ActionOrgsRead)), routing.Wrap(api.getOrgPolicy))
+		orgRoute.Put("/", authorize(ac.EvalPermission(ac.ActionOrgsWrite)), routing.Wrap(api.updateOrgPolicy))
+	}, requestmeta.SetOwner(requestmeta.TeamAuth), reqSignedIn)
+}
+
+// swagger:route GET /user/mfa signed_in_user getSignedInUserMFA
+//
+// Get the multi-factor authentication status of the signed in user.
+//
+// Responses:
+// 200: getMFAStatusResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (api *API) getSignedInUserStatus(c *contextmodel.ReqContext) response.Response {
+	userID, errResponse := getUserID(c)
+	if errResponse != nil {
+		return errResponse
+	}
+
+	status, err := api.mfaService.GetStatus(c.Req.Context(), userID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get multi-factor authentication status", err)
+	}
+
+	return response.JSON(http.StatusOK, status)
+}
+
+// swagger:route POST /user/mfa/enroll signed_in_user beginSignedInUserMFAEnrollment
+//
+// Begin the enrollment of a second factor.
+//
+// Returns the TOTP secret and the recovery codes of the signed in user.
+// The second factor is only enabled once the enrollment is confirmed with a code generated from the secret.
+//
+// Responses:
+// 200: beginMFAEnrollmentResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (api *API) beginEnrollment(c *contextmodel.ReqContext) response.Response {
+	userID, errResponse := getUserID(c)
+	if errResponse != nil {
+		return errResponse
+	}
+
+	return api.enroll(c, userID)
+}
+
+// swagger:route POST /user/mfa/enroll/confirm signed_in_user confirmSignedInUserMFAEnrollment
+//
+// Enable the second factor of the signed in user.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (api *API) confirmEnrollment(c *contextmodel.ReqContext) response.Response {
+	userID, errResponse := getUserID(c)
+	if errResponse != nil {
+		return errResponse
+	}
+
+	cmd := mfa.CodeCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	if err := api.mfaService.ConfirmEnrollment(c.Req.Context(), userID, cmd.Code); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to enable multi-factor authentication", err)
+	}
+
+	return response.Success("Multi-factor authentication enabled")
+}
+
+// swagger:route POST /user/mfa/recovery-codes signed_in_user regenerateSignedInUserMFARecoveryCodes
+//
+// Replace the recovery codes of the signed in user.
+//
+// The previous recovery codes can no longer be used.
+//
+// Responses:
+// 200: regenerateMFARecoveryCodesResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (api *API) regenerateRecoveryCodes(c *contextmodel.ReqContext) response.Response {
+	userID, errResponse := getUserID(c)
+	if errResponse != nil {
+		return errResponse
+	}
+
+	cmd := mfa.CodeCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	codes, err := api.mfaService.RegenerateRecoveryCodes(c.Req.Context(), userID, cmd.Code)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to regenerate recovery codes", err)
+	}
+
+	return response.JSON(http.StatusOK, util.DynMap{"recoveryCodes": codes})
+}
+
+// swagger:route POST /user/mfa/disable signed_in_user disableSignedInUserMFA
+//
+// Remove the second factor of the signed in user.
+//
+// It is refused when the server or one of the organizations of the user requires multi-factor authentication.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (api *API) disable(c *contextmodel.ReqContext) response.Response {
+	userID, errResponse := getUserID(c)
+	if errResponse != nil {
+		return errResponse
+	}
+
+	cmd := mfa.CodeCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	if err := api.mfaService.Disable(c.Req.Context(), userID, cmd.Code); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to disable multi-factor authentication", err)
+	}
+
+	return response.Success("Multi-factor authentication disabled")
+}
+
+// beginLoginEnrollment is used by users required to use a second factor who haven't enrolled one yet.
+// The enrollment is confirmed by completing the login challenge with a code.
+func (api *API) beginLoginEnrollment(c *contextmodel.ReqContext) response.Response {
+	cmd := mfa.ChallengeCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	userID, err := api.mfaService.VerifyChallenge(c.Req.Context(), cmd.Challenge)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusUnauthorized, "Invalid challenge", err)
+	}
+
+	return api.enroll(c, userID)
+}
+
+func (api *API) enroll(c *contextmodel.ReqContext, userID int64) response.Response {
+	enrollment, err := api.mfaService.BeginEnrollment(c.Req.Context(), userID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to begin multi-factor authentication enrollment", err)
+	}
+
+	return response.JSON(http.StatusOK, enrollment)
+}
+
+// swagger:route GET /admin/users/{user_id}/mfa admin_users adminGetUserMFA
+//
+// Get the multi-factor authentication status of a user.
+//
+// If you are running Grafana Enterprise and have Fine-grained access control enabled, you need to have a permission with action `users:read` and scope `global.users:*`.
+//
+// Responses:
+// 200: getMFAStatusResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (api *API) getUserStatus(c *contextmodel.ReqContext) response.Response {
+	userID, errResponse := api.getUserIDParam(c)
+	if errResponse != nil {
+		return errResponse
+	}
+
+	status, err := api.mfaService.GetStatus(c.Req.Context(), userID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get multi-factor authentication status", err)
+	}
+
+	return response.JSON(http.StatusOK, status)
+}
+
+// swagger:route DELETE /admin/users/{user_id}/mfa admin_users adminResetUserMFA
+//
+// Reset the second factor of a user.
+//
+// The user has to enroll again when multi-factor authentication is required.
+// If you are running Grafana Enterprise and have Fine-grained access control enabled, you need to have a permission with action `users.password:write` and scope `global.users:*`.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (api *API) resetUser(c *contextmodel.ReqContext) response.Response {
+	userID, errResponse := api.getUserIDParam(c)
+	if errResponse != nil {
+		return errResponse
+	}
+
+	if err := api.mfaService.Reset(c.Req.Context(), userID); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to reset multi-factor authentication", err)
+	}
+
+	api.logger.FromContext(c.Req.Context()).Info("Reset multi-factor authentication of user", "userId", userID, "by", c.SignedInUser.GetID())
+	return response.Success("Multi-factor authentication reset")
+}
+
+// swagger:route GET /org/mfa org getCurrentOrgMFAPolicy
+//
+// Get the multi-factor authentication policy of the current organization.
+//
+// Responses:
+// 200: getMFAPolicyResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (api *API) getOrgPolicy(c *contextmodel.ReqContext) response.Response {
+	orgID := c.SignedInUser.GetOrgID()
+	policy, err := api.mfaService.GetOrgPolicy(c.Req.Context(), orgID)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get multi-factor authentication policy", err)
+	}
+
+	return response.JSON(http.StatusOK, policy)
+}
+
+// swagger:route PUT /org/mfa org updateCurrentOrgMFAPolicy
+//
+// Update the multi-factor authentication policy of the current organization.
+//
+// When the policy is required, the members of the organization authenticating with a password must use a second factor.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (api *API) updateOrgPolicy(c *contextmodel.ReqContext) response.Response {
+	cmd := mfa.UpdateOrgPolicyCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	policy := &mfa.OrgPolicy{OrgID: c.SignedInUser.GetOrgID(), Required: cmd.Required}
+	if err := api.mfaService.SetOrgPolicy(c.Req.Context(), policy); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to update multi-factor authentication policy", err)
+	}
+
+	return response.Success("Multi-factor authentication policy updated")
+}
+
+func (api *API) getUserIDParam(c *contextmodel.ReqContext) (int64, response.Response) {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return 0, response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+
+	if _, err := api.userService.GetByID(c.Req.Context(), &user.GetUserByIDQuery{ID: userID}); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return 0, response.Error(http.StatusNotFound, user.ErrUserNotFound.Error(), nil)
+		}
+		return 0, response.Error(http.StatusInternalServerError, "Failed to get user", err)
+	}
+
+	return userID, nil
+}
+
+func getUserID(c *contextmodel.ReqContext) (int64, response.Response) {
+	if !c.SignedInUser.IsIdentityType(claims.TypeUser) {
+		return 0, response.Error(http.StatusForbidden, "Endpoint only available for users", nil)
+	}
+
+	userID, err := c.SignedInUser.GetInternalID()
+	if err != nil {
+		return 0, response.Error(http.StatusInternalServerError, "Failed to parse user id", err)
+	}
+
+	return userID, nil
+}
+
+// swagger:parameters confirmSignedInUserMFAEnrollment
+type ConfirmSignedInUserMFAEnrollmentParams struct {
+	// in:body
+	// required:true
+	Body mfa.CodeCommand `json:"body"`
+}
+
+// swagger:parameters regenerateSignedInUserMFARecoveryCodes
+type RegenerateSignedInUserMFARecoveryCodesParams struct {
+	// in:body
+	// required:true
+	Body mfa.CodeCommand `json:"body"`
+}
+
+// swagger:parameters disableSignedInUserMFA
+type DisableSignedInUserMFAParams struct {
+	// in:body
+	// required:true
+	Body mfa.CodeCommand `json:"body"`
+}
+
+// swagger:parameters adminGetUserMFA
+type AdminGetUserMFAParams struct {
+	// in:path
+	// required:true
+	UserID int64 `json:"user_id"`
+}
+
+// swagger:parameters adminResetUserMFA
+type AdminResetUserMFAParams struct {
+	// in:path
+	// required:true
+	UserID int64 `json:"user_id"`
+}
+
+// swagger:parameters updateCurrentOrgMFAPolicy
+type UpdateCurrentOrgMFAPolicyParams struct {
+	// in:body
+	// required:true
+	Body mfa.UpdateOrgPolicyCommand `json:"body"`
+}
+
+// swagger:response getMFAStatusResponse
+type GetMFAStatusResponse struct {
+	// in:body
+	Body mfa.Status `json:"body"`
+}
+
+// swagger:response beginMFAEnrollmentResponse
+type BeginMFAEnrollmentResponse struct {
+	// in:body
+	Body mfa.Enrollment `json:"body"`
+}
+
+// swagger:response regenerateMFARecoveryCodesResponse
+type RegenerateMFARecoveryCodesResponse struct {
+	// in:body
+	Body struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	} `json:"body"`
+}
+
+// swagger:response getMFAPolicyResponse
+type GetMFAPolicyResponse struct {
+	// in:body
+	Body mfa.OrgPolicy `json:"body"`
+}
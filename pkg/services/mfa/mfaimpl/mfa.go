@@ -0,0 +1,499 @@
+package mfaimpl
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	// challengeDuration is how long a user has to enter a code after a successful password authentication
+	challengeDuration  = 5 * time.Minute
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	// recoveryCodeAlphabet leaves out the characters that are easily mistaken for one another
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	// qrCodeSize is the width and height in pixels of the QR codes
+	qrCodeSize = 256
+)
+
+var _ mfa.Service = (*Service)(nil)
+
+func ProvideService(db db.DB, cfg *setting.Cfg, secretsService secrets.Service, userService user.Service) *Service {
+	return &Service{
+		store:          &xormStore{db: db, now: time.Now},
+		cfg:            cfg,
+		secretsService: secretsService,
+		userService:    userService,
+		log:            log.New("mfa"),
+		now:            time.Now,
+	}
+}
+
+type Service struct {
+	store          store
+	cfg            *setting.Cfg
+	secretsService secrets.Service
+	userService    user.Service
+	log            log.Logger
+	now            func() time.Time
+}
+
+func (s *Service) IsEnabled() bool {
+	return s.cfg.MFAEnabled
+}
+
+func (s *Service) GetStatus(ctx context.Context, userID int64) (*mfa.Status, error) {
+	userMFA, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	required, err := s.isRequired(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &mfa.Status{Required: required}
+	if userMFA != nil && userMFA.Enabled {
+		hashes, err := decodeRecoveryCodes(userMFA.RecoveryCodes)
+		if err != nil {
+			return nil, err
+		}
+		status.Enabled = true
+		status.RecoveryCodesRemaining = len(hashes)
+	}
+
+	return status, nil
+}
+
+func (s *Service) BeginEnrollment(ctx context.Context, userID int64) (*mfa.Enrollment, error) {
+	if !s.IsEnabled() {
+		return nil, mfa.ErrDisabled.Errorf("multi-factor authentication is disabled")
+	}
+
+	existing, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Enabled {
+		return nil, mfa.ErrAlreadyEnrolled.Errorf("user %d already enabled multi-factor authentication", userID)
+	}
+
+	usr, err := s.userService.GetByID(ctx, &user.GetUserByIDQuery{ID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	encrypted, err := s.secretsService.Encrypt(ctx, []byte(secret), secrets.WithoutScope())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+
+	salt, err := util.GetRandomString(10)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	codes, hashes, err := generateRecoveryCodes(salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+
+	err = s.store.Save(ctx, &UserMFA{
+		UserID:        userID,
+		Secret:        base64.StdEncoding.EncodeToString(encrypted),
+		RecoveryCodes: hashes,
+		Salt:          salt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment := &mfa.Enrollment{
+		Secret:        secret,
+		URL:           keyURI(s.cfg.MFAIssuer, usr.Login, secret),
+		RecoveryCodes: codes,
+	}
+
+	// the secret can still be typed in the authenticator app without the QR code
+	enrollment.QRCode, err = qrCodeDataURI(enrollment.URL)
+	if err != nil {
+		s.log.FromContext(ctx).Warn("Failed to generate QR code", "userId", userID, "error", err)
+	}
+
+	return enrollment, nil
+}
+
+func (s *Service) ConfirmEnrollment(ctx context.Context, userID int64, code string) error {
+	userMFA, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if userMFA == nil {
+		return mfa.ErrNotEnrolled.Errorf("user %d has no pending enrollment", userID)
+	}
+	if userMFA.Enabled {
+		return mfa.ErrAlreadyEnrolled.Errorf("user %d already enabled multi-factor authentication", userID)
+	}
+
+	secret, err := s.decryptSecret(ctx, userMFA)
+	if err != nil {
+		return err
+	}
+
+	step, ok := validateTOTP(secret, normalizeCode(code), s.now(), userMFA.LastUsedStep)
+	if !ok {
+		return mfa.ErrInvalidCode.Errorf("invalid code to confirm the enrollment of user %d", userID)
+	}
+
+	if err := s.store.Enable(ctx, userID, step); err != nil {
+		return err
+	}
+
+	s.log.FromContext(ctx).Info("Multi-factor authentication enabled", "userId", userID)
+	return nil
+}
+
+func (s *Service) Verify(ctx context.Context, userID int64, code string) error {
+	userMFA, err := s.getEnabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	code = normalizeCode(code)
+	if !isTOTPCode(code) {
+		return s.useRecoveryCode(ctx, userMFA, code)
+	}
+
+	secret, err := s.decryptSecret(ctx, userMFA)
+	if err != nil {
+		return err
+	}
+
+	step, ok := validateTOTP(secret, code, s.now(), userMFA.LastUsedStep)
+	if !ok {
+		return mfa.ErrInvalidCode.Errorf("invalid code for user %d", userID)
+	}
+
+	used, err := s.store.UseStep(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return mfa.ErrInvalidCode.Errorf("code of user %d was already used", userID)
+	}
+
+	return nil
+}
+
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	// read again as verifying a recovery code updates them
+	userMFA, err := s.getEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes(userMFA.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+
+	updated, err := s.store.UpdateRecoveryCodes(ctx, userID, userMFA.RecoveryCodes, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, mfa.ErrInvalidCode.Errorf("recovery codes of user %d changed while regenerating them", userID)
+	}
+
+	s.log.FromContext(ctx).Info("Multi-factor authentication recovery codes regenerated", "userId", userID)
+	return codes, nil
+}
+
+func (s *Service) Disable(ctx context.Context, userID int64, code string) error {
+	required, err := s.isRequired(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return mfa.ErrRequiredByPolicy.Errorf("user %d can't disable multi-factor authentication", userID)
+	}
+
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+
+	if err := s.store.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	s.log.FromContext(ctx).Info("Multi-factor authentication disabled", "userId", userID)
+	return nil
+}
+
+func (s *Service) Reset(ctx context.Context, userID int64) error {
+	if err := s.store.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	s.log.FromContext(ctx).Info("Multi-factor authentication reset", "userId", userID)
+	return nil
+}
+
+func (s *Service) Challenge(ctx context.Context, userID int64) (*mfa.Challenge, error) {
+	if !s.IsEnabled() {
+		return nil, nil
+	}
+
+	userMFA, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollmentRequired := false
+	if userMFA == nil || !userMFA.Enabled {
+		required, err := s.isRequired(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+		enrollmentRequired = true
+	}
+
+	return &mfa.Challenge{
+		Token:              s.signChallenge(userID, s.now().Add(challengeDuration)),
+		EnrollmentRequired: enrollmentRequired,
+	}, nil
+}
+
+func (s *Service) VerifyChallenge(ctx context.Context, token string) (int64, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, mfa.ErrInvalidChallenge.Errorf("malformed challenge")
+	}
+
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, mfa.ErrInvalidChallenge.Errorf("malformed challenge user: %w", err)
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, mfa.ErrInvalidChallenge.Errorf("malformed challenge expiration: %w", err)
+	}
+
+	expiresAt := time.Unix(expiry, 0)
+	if !hmac.Equal([]byte(token), []byte(s.signChallenge(userID, expiresAt))) {
+		return 0, mfa.ErrInvalidChallenge.Errorf("invalid challenge signature")
+	}
+	if !s.now().Before(expiresAt) {
+		return 0, mfa.ErrInvalidChallenge.Errorf("challenge of user %d expired", userID)
+	}
+
+	return userID, nil
+}
+
+func (s *Service) GetOrgPolicy(ctx context.Context, orgID int64) (*mfa.OrgPolicy, error) {
+	policy, err := s.store.GetOrgPolicy(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return &mfa.OrgPolicy{OrgID: orgID}, nil
+	}
+	return policy, nil
+}
+
+func (s *Service) SetOrgPolicy(ctx context.Context, policy *mfa.OrgPolicy) error {
+	if err := s.store.SaveOrgPolicy(ctx, policy); err != nil {
+		return err
+	}
+
+	s.log.FromContext(ctx).Info("Multi-factor authentication policy updated", "orgId", policy.OrgID, "required", policy.Required)
+	return nil
+}
+
+func (s *Service) isRequired(ctx context.Context, userID int64) (bool, error) {
+	if !s.IsEnabled() {
+		return false, nil
+	}
+	if s.cfg.MFARequired {
+		return true, nil
+	}
+	return s.store.IsRequiredByOrg(ctx, userID)
+}
+
+func (s *Service) getEnabled(ctx context.Context, userID int64) (*UserMFA, error) {
+	if !s.IsEnabled() {
+		return nil, mfa.ErrDisabled.Errorf("multi-factor authentication is disabled")
+	}
+
+	userMFA, err := s.store.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if userMFA == nil || !userMFA.Enabled {
+		return nil, mfa.ErrNotEnrolled.Errorf("user %d didn't enable multi-factor authentication", userID)
+	}
+	return userMFA, nil
+}
+
+func (s *Service) decryptSecret(ctx context.Context, userMFA *UserMFA) (string, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(userMFA.Secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret of user %d: %w", userMFA.UserID, err)
+	}
+	secret, err := s.secretsService.Decrypt(ctx, encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret of user %d: %w", userMFA.UserID, err)
+	}
+	return string(secret), nil
+}
+
+func (s *Service) useRecoveryCode(ctx context.Context, userMFA *UserMFA, code string) error {
+	hashes, err := decodeRecoveryCodes(userMFA.RecoveryCodes)
+	if err != nil {
+		return err
+	}
+
+	hash, err := util.EncodePassword(code, userMFA.Salt)
+	if err != nil {
+		return err
+	}
+
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) != 1 {
+			continue
+		}
+
+		remaining, err := json.Marshal(append(hashes[:i:i], hashes[i+1:]...))
+		if err != nil {
+			return err
+		}
+		// fails when the same code was used concurrently
+		updated, err := s.store.UpdateRecoveryCodes(ctx, userMFA.UserID, userMFA.RecoveryCodes, string(remaining))
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return mfa.ErrInvalidCode.Errorf("recovery code of user %d was already used", userMFA.UserID)
+		}
+
+		s.log.FromContext(ctx).Info("Multi-factor authentication recovery code used", "userId", userMFA.UserID, "remaining", len(hashes)-1)
+		return nil
+	}
+
+	return mfa.ErrInvalidCode.Errorf("invalid recovery code for user %d", userMFA.UserID)
+}
+
+// signChallenge returns a challenge token valid until expiresAt, formatted as <user>.<expiration>.<signature>
+func (s *Service) signChallenge(userID int64, expiresAt time.Time) string {
+	payload := strconv.FormatInt(userID, 10) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(s.cfg.SecretKey))
+	mac.Write([]byte("mfa-challenge\n" + payload))
+
+	return payload + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// generateRecoveryCodes returns new recovery codes, formatted to be easy to read, and their JSON encoded hashes
+func generateRecoveryCodes(salt string) ([]string, string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := util.GetRandomString(recoveryCodeLength, []byte(recoveryCodeAlphabet)...)
+		if err != nil {
+			return nil, "", err
+		}
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hash, err := util.EncodePassword(normalizeCode(code), salt)
+		if err != nil {
+			return nil, "", err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	encoded, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, "", err
+	}
+	return codes, string(encoded), nil
+}
+
+func decodeRecoveryCodes(encoded string) ([]string, error) {
+	var hashes []string
+	if encoded == "" {
+		return hashes, nil
+	}
+	if err := json.Unmarshal([]byte(encoded), &hashes); err != nil {
+		return nil, fmt.Errorf("failed to decode recovery codes: %w", err)
+	}
+	return hashes, nil
+}
+
+// normalizeCode removes the separators users may type, recovery codes are displayed with a dash
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// qrCodeDataURI returns text encoded as a QR code in a PNG data URI
+func qrCodeDataURI(text string) (string, error) {
+	code, err := qr.Encode(text, qr.M, qr.Auto)
+	if err != nil {
+		return "", err
+	}
+	code, err = barcode.Scale(code, qrCodeSize, qrCodeSize)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, code); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
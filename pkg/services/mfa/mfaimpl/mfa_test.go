@@ -0,0 +1,304 @@
+package mfaimpl
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestService_Enrollment(t *testing.T) {
+	ctx := context.Background()
+	s, _ := setupTestService(t)
+
+	enrollment, err := s.BeginEnrollment(ctx, 1)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URL, "otpauth://totp/Grafana:admin")
+	assert.Len(t, enrollment.RecoveryCodes, recoveryCodeCount)
+
+	data, ok := strings.CutPrefix(enrollment.QRCode, "data:image/png;base64,")
+	require.True(t, ok, "the QR code should be a PNG data URI")
+	image, err := base64.StdEncoding.DecodeString(data)
+	require.NoError(t, err)
+	qrCode, err := png.Decode(bytes.NewReader(image))
+	require.NoError(t, err)
+	assert.Equal(t, qrCodeSize, qrCode.Bounds().Dx())
+	assert.Equal(t, qrCodeSize, qrCode.Bounds().Dy())
+
+	status, err := s.GetStatus(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+
+	err = s.ConfirmEnrollment(ctx, 1, "000000")
+	assert.ErrorIs(t, err, mfa.ErrInvalidCode)
+
+	require.NoError(t, s.ConfirmEnrollment(ctx, 1, codeAt(t, enrollment.Secret, s.now())))
+
+	status, err = s.GetStatus(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, recoveryCodeCount, status.RecoveryCodesRemaining)
+
+	_, err = s.BeginEnrollment(ctx, 1)
+	assert.ErrorIs(t, err, mfa.ErrAlreadyEnrolled)
+}
+
+func TestService_Verify(t *testing.T) {
+	ctx := context.Background()
+	s, _ := setupTestService(t)
+	enrollment := enroll(t, s, 1)
+
+	t.Run("should reject the code used to confirm the enrollment", func(t *testing.T) {
+		err := s.Verify(ctx, 1, codeAt(t, enrollment.Secret, s.now()))
+		assert.ErrorIs(t, err, mfa.ErrInvalidCode)
+	})
+
+	t.Run("should accept the code of the next step once", func(t *testing.T) {
+		s.now = func() time.Time { return time.Unix(1111111111+totpPeriod, 0) }
+		code := codeAt(t, enrollment.Secret, s.now())
+
+		require.NoError(t, s.Verify(ctx, 1, code))
+		assert.ErrorIs(t, s.Verify(ctx, 1, code), mfa.ErrInvalidCode)
+	})
+
+	t.Run("should accept each recovery code once", func(t *testing.T) {
+		code := enrollment.RecoveryCodes[0]
+		require.NoError(t, s.Verify(ctx, 1, code))
+		assert.ErrorIs(t, s.Verify(ctx, 1, code), mfa.ErrInvalidCode)
+
+		// recovery codes can be typed without the separator
+		require.NoError(t, s.Verify(ctx, 1, normalizeCode(enrollment.RecoveryCodes[1])))
+
+		status, err := s.GetStatus(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, recoveryCodeCount-2, status.RecoveryCodesRemaining)
+	})
+
+	t.Run("should replace the recovery codes", func(t *testing.T) {
+		codes, err := s.RegenerateRecoveryCodes(ctx, 1, enrollment.RecoveryCodes[2])
+		require.NoError(t, err)
+		assert.Len(t, codes, recoveryCodeCount)
+
+		assert.ErrorIs(t, s.Verify(ctx, 1, enrollment.RecoveryCodes[3]), mfa.ErrInvalidCode)
+		require.NoError(t, s.Verify(ctx, 1, codes[0]))
+	})
+
+	t.Run("should fail for users without a second factor", func(t *testing.T) {
+		assert.ErrorIs(t, s.Verify(ctx, 2, "123456"), mfa.ErrNotEnrolled)
+	})
+}
+
+func TestService_Disable(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should remove the second factor", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		enrollment := enroll(t, s, 1)
+
+		require.NoError(t, s.Disable(ctx, 1, enrollment.RecoveryCodes[0]))
+
+		status, err := s.GetStatus(ctx, 1)
+		require.NoError(t, err)
+		assert.False(t, status.Enabled)
+	})
+
+	t.Run("should refuse when required by an organization", func(t *testing.T) {
+		s, store := setupTestService(t)
+		enrollment := enroll(t, s, 1)
+		store.requiredByOrg = true
+
+		err := s.Disable(ctx, 1, enrollment.RecoveryCodes[0])
+		assert.ErrorIs(t, err, mfa.ErrRequiredByPolicy)
+	})
+
+	t.Run("should reset without a code", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		enroll(t, s, 1)
+
+		require.NoError(t, s.Reset(ctx, 1))
+
+		status, err := s.GetStatus(ctx, 1)
+		require.NoError(t, err)
+		assert.False(t, status.Enabled)
+	})
+}
+
+func TestService_Challenge(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should not challenge users without a second factor", func(t *testing.T) {
+		s, _ := setupTestService(t)
+
+		challenge, err := s.Challenge(ctx, 1)
+		require.NoError(t, err)
+		assert.Nil(t, challenge)
+	})
+
+	t.Run("should challenge users with a second factor", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		enroll(t, s, 1)
+
+		challenge, err := s.Challenge(ctx, 1)
+		require.NoError(t, err)
+		require.NotNil(t, challenge)
+		assert.False(t, challenge.EnrollmentRequired)
+
+		userID, err := s.VerifyChallenge(ctx, challenge.Token)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), userID)
+	})
+
+	t.Run("should require enrollment when the server requires a second factor", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		s.cfg.MFARequired = true
+
+		challenge, err := s.Challenge(ctx, 1)
+		require.NoError(t, err)
+		require.NotNil(t, challenge)
+		assert.True(t, challenge.EnrollmentRequired)
+	})
+
+	t.Run("should not challenge when disabled", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		enroll(t, s, 1)
+		s.cfg.MFAEnabled = false
+
+		challenge, err := s.Challenge(ctx, 1)
+		require.NoError(t, err)
+		assert.Nil(t, challenge)
+	})
+
+	t.Run("should reject expired and tampered challenges", func(t *testing.T) {
+		s, _ := setupTestService(t)
+		enroll(t, s, 1)
+
+		challenge, err := s.Challenge(ctx, 1)
+		require.NoError(t, err)
+
+		_, err = s.VerifyChallenge(ctx, "2"+challenge.Token[1:])
+		assert.ErrorIs(t, err, mfa.ErrInvalidChallenge)
+
+		_, err = s.VerifyChallenge(ctx, "malformed")
+		assert.ErrorIs(t, err, mfa.ErrInvalidChallenge)
+
+		now := s.now()
+		s.now = func() time.Time { return now.Add(challengeDuration) }
+		_, err = s.VerifyChallenge(ctx, challenge.Token)
+		assert.ErrorIs(t, err, mfa.ErrInvalidChallenge)
+	})
+}
+
+func setupTestService(t *testing.T) (*Service, *fakeStore) {
+	t.Helper()
+
+	cfg := setting.NewCfg()
+	cfg.MFAEnabled = true
+	cfg.MFAIssuer = "Grafana"
+	cfg.SecretKey = "secret"
+
+	now := func() time.Time { return time.Unix(1111111111, 0) }
+	store := &fakeStore{userMFA: map[int64]*UserMFA{}, policies: map[int64]*mfa.OrgPolicy{}}
+	return &Service{
+		store:          store,
+		cfg:            cfg,
+		secretsService: fakes.NewFakeSecretsService(),
+		userService:    &usertest.FakeUserService{ExpectedUser: &user.User{ID: 1, Login: "admin"}},
+		log:            log.NewNopLogger(),
+		now:            now,
+	}, store
+}
+
+func enroll(t *testing.T, s *Service, userID int64) *mfa.Enrollment {
+	t.Helper()
+
+	enrollment, err := s.BeginEnrollment(context.Background(), userID)
+	require.NoError(t, err)
+	require.NoError(t, s.ConfirmEnrollment(context.Background(), userID, codeAt(t, enrollment.Secret, s.now())))
+	return enrollment
+}
+
+func codeAt(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+
+	key, err := secretEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	return totpCode(key, totpStep(now))
+}
+
+type fakeStore struct {
+	userMFA       map[int64]*UserMFA
+	policies      map[int64]*mfa.OrgPolicy
+	requiredByOrg bool
+}
+
+func (f *fakeStore) Get(ctx context.Context, userID int64) (*UserMFA, error) {
+	userMFA, ok := f.userMFA[userID]
+	if !ok {
+		return nil, nil
+	}
+	result := *userMFA
+	return &result, nil
+}
+
+func (f *fakeStore) Save(ctx context.Context, userMFA *UserMFA) error {
+	// the secret is stored as the service stores it
+	if _, err := base64.StdEncoding.DecodeString(userMFA.Secret); err != nil {
+		return err
+	}
+	saved := *userMFA
+	f.userMFA[userMFA.UserID] = &saved
+	return nil
+}
+
+func (f *fakeStore) Enable(ctx context.Context, userID int64, step int64) error {
+	f.userMFA[userID].Enabled = true
+	f.userMFA[userID].LastUsedStep = step
+	return nil
+}
+
+func (f *fakeStore) UseStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	if f.userMFA[userID].LastUsedStep >= step {
+		return false, nil
+	}
+	f.userMFA[userID].LastUsedStep = step
+	return true, nil
+}
+
+func (f *fakeStore) UpdateRecoveryCodes(ctx context.Context, userID int64, previous string, recoveryCodes string) (bool, error) {
+	if f.userMFA[userID].RecoveryCodes != previous {
+		return false, nil
+	}
+	f.userMFA[userID].RecoveryCodes = recoveryCodes
+	return true, nil
+}
+
+func (f *fakeStore) Delete(ctx context.Context, userID int64) error {
+	delete(f.userMFA, userID)
+	return nil
+}
+
+func (f *fakeStore) GetOrgPolicy(ctx context.Context, orgID int64) (*mfa.OrgPolicy, error) {
+	return f.policies[orgID], nil
+}
+
+func (f *fakeStore) SaveOrgPolicy(ctx context.Context, policy *mfa.OrgPolicy) error {
+	f.policies[policy.OrgID] = policy
+	return nil
+}
+
+func (f *fakeStore) IsRequiredByOrg(ctx context.Context, userID int64) (bool, error) {
+	return f.requiredByOrg, nil
+}
@@ -0,0 +1,26 @@
+package mfaimpl
+
+import (
+	"time"
+)
+
+// UserMFA is the second factor of a user
+type UserMFA struct {
+	ID     int64 `xorm:"pk autoincr 'id'"`
+	UserID int64 `xorm:"user_id"`
+	// Secret is the TOTP secret, encrypted with the secrets service and base64 encoded
+	Secret string `xorm:"secret"`
+	// RecoveryCodes is the JSON encoded list of the hashes of the unused recovery codes
+	RecoveryCodes string `xorm:"recovery_codes"`
+	// Salt is used to hash the recovery codes
+	Salt    string `xorm:"salt"`
+	Enabled bool   `xorm:"enabled"`
+	// LastUsedStep is the TOTP time step of the last accepted code, codes are only accepted once
+	LastUsedStep int64     `xorm:"last_used_step"`
+	Created      time.Time `xorm:"created"`
+	Updated      time.Time `xorm:"updated"`
+}
+
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
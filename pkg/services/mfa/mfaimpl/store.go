@@ -0,0 +1,162 @@
+package mfaimpl
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/mfa"
+)
+
+type store interface {
+	// Get returns the second factor of a user, or nil if the user never enrolled
+	Get(ctx context.Context, userID int64) (*UserMFA, error)
+	// Save creates or replaces the second factor of a user
+	Save(ctx context.Context, userMFA *UserMFA) error
+	// Enable marks the second factor of a user as enabled and records the time step of the code that confirmed it
+	Enable(ctx context.Context, userID int64, step int64) error
+	// UseStep records the time step of an accepted code, it returns false if a code of the same or a later step was already used
+	UseStep(ctx context.Context, userID int64, step int64) (bool, error)
+	// UpdateRecoveryCodes replaces the recovery codes of a user, it returns false if they changed since they were read as previous
+	UpdateRecoveryCodes(ctx context.Context, userID int64, previous string, recoveryCodes string) (bool, error)
+	// Delete removes the second factor of a user
+	Delete(ctx context.Context, userID int64) error
+	// GetOrgPolicy returns the policy of an organization, or nil if it was never set
+	GetOrgPolicy(ctx context.Context, orgID int64) (*mfa.OrgPolicy, error)
+	// SaveOrgPolicy creates or updates the policy of an organization
+	SaveOrgPolicy(ctx context.Context, policy *mfa.OrgPolicy) error
+	// IsRequiredByOrg returns true if one of the organizations of a user requires a second factor
+	IsRequiredByOrg(ctx context.Context, userID int64) (bool, error)
+}
+
+type xormStore struct {
+	db  db.DB
+	now func() time.Time
+}
+
+func (xs *xormStore) Get(ctx context.Context, userID int64) (*UserMFA, error) {
+	var result *UserMFA
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		userMFA := &UserMFA{}
+		exists, err := sess.Where("user_id = ?", userID).Get(userMFA)
+		if err != nil {
+			return err
+		}
+		if exists {
+			result = userMFA
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (xs *xormStore) Save(ctx context.Context, userMFA *UserMFA) error {
+	return xs.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		if _, err := sess.Exec("DELETE FROM user_mfa WHERE user_id = ?", userMFA.UserID); err != nil {
+			return err
+		}
+
+		now := xs.now()
+		userMFA.ID = 0
+		userMFA.Created = now
+		userMFA.Updated = now
+		_, err := sess.Insert(userMFA)
+		return err
+	})
+}
+
+func (xs *xormStore) Enable(ctx context.Context, userID int64, step int64) error {
+	return xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Where("user_id = ?", userID).Cols("enabled", "last_used_step", "updated").
+			Update(&UserMFA{Enabled: true, LastUsedStep: step, Updated: xs.now()})
+		return err
+	})
+}
+
+func (xs *xormStore) UseStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	var used bool
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		// the condition on last_used_step makes concurrent logins with the same code fail
+		affected, err := sess.Where("user_id = ? AND last_used_step < ?", userID, step).Cols("last_used_step", "updated").
+			Update(&UserMFA{LastUsedStep: step, Updated: xs.now()})
+		if err != nil {
+			return err
+		}
+		used = affected == 1
+		return nil
+	})
+	return used, err
+}
+
+func (xs *xormStore) UpdateRecoveryCodes(ctx context.Context, userID int64, previous string, recoveryCodes string) (bool, error) {
+	var updated bool
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		affected, err := sess.Where("user_id = ? AND recovery_codes = ?", userID, previous).Cols("recovery_codes", "updated").
+			Update(&UserMFA{RecoveryCodes: recoveryCodes, Updated: xs.now()})
+		if err != nil {
+			return err
+		}
+		updated = affected == 1
+		return nil
+	})
+	return updated, err
+}
+
+func (xs *xormStore) Delete(ctx context.Context, userID int64) error {
+	return xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Exec("DELETE FROM user_mfa WHERE user_id = ?", userID)
+		return err
+	})
+}
+
+func (xs *xormStore) GetOrgPolicy(ctx context.Context, orgID int64) (*mfa.OrgPolicy, error) {
+	var result *mfa.OrgPolicy
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		policy := &mfa.OrgPolicy{}
+		exists, err := sess.Where("org_id = ?", orgID).Get(policy)
+		if err != nil {
+			return err
+		}
+		if exists {
+			result = policy
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (xs *xormStore) SaveOrgPolicy(ctx context.Context, policy *mfa.OrgPolicy) error {
+	return xs.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		policy.Updated = xs.now()
+
+		existing := &mfa.OrgPolicy{}
+		exists, err := sess.Where("org_id = ?", policy.OrgID).Get(existing)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			_, err = sess.Insert(policy)
+			return err
+		}
+
+		policy.ID = existing.ID
+		_, err = sess.ID(existing.ID).Cols("required", "updated").Update(policy)
+		return err
+	})
+}
+
+func (xs *xormStore) IsRequiredByOrg(ctx context.Context, userID int64) (bool, error) {
+	var required bool
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		count, err := sess.Table("org_mfa_policy").
+			Join("INNER", "org_user", "org_user.org_id = org_mfa_policy.org_id").
+			Where("org_user.user_id = ? AND org_mfa_policy.required = ?", userID, true).
+			Count()
+		if err != nil {
+			return err
+		}
+		required = count > 0
+		return nil
+	})
+	return required, err
+}
@@ -0,0 +1,129 @@
+package mfaimpl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func TestIntegrationUserMFAStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	s := &xormStore{db: db.InitTestDB(t), now: func() time.Time { return now }}
+
+	t.Run("should return nil for users who never enrolled", func(t *testing.T) {
+		userMFA, err := s.Get(ctx, 1)
+		require.NoError(t, err)
+		assert.Nil(t, userMFA)
+	})
+
+	t.Run("should replace pending enrollments", func(t *testing.T) {
+		require.NoError(t, s.Save(ctx, &UserMFA{UserID: 1, Secret: "first", RecoveryCodes: "[]", Salt: "salt"}))
+		require.NoError(t, s.Save(ctx, &UserMFA{UserID: 1, Secret: "second", RecoveryCodes: "[]", Salt: "salt"}))
+
+		userMFA, err := s.Get(ctx, 1)
+		require.NoError(t, err)
+		require.NotNil(t, userMFA)
+		assert.Equal(t, "second", userMFA.Secret)
+		assert.False(t, userMFA.Enabled)
+	})
+
+	t.Run("should only use each time step once", func(t *testing.T) {
+		require.NoError(t, s.Enable(ctx, 1, 10))
+
+		used, err := s.UseStep(ctx, 1, 10)
+		require.NoError(t, err)
+		assert.False(t, used)
+
+		used, err = s.UseStep(ctx, 1, 11)
+		require.NoError(t, err)
+		assert.True(t, used)
+
+		userMFA, err := s.Get(ctx, 1)
+		require.NoError(t, err)
+		assert.True(t, userMFA.Enabled)
+		assert.Equal(t, int64(11), userMFA.LastUsedStep)
+	})
+
+	t.Run("should only update recovery codes that didn't change", func(t *testing.T) {
+		updated, err := s.UpdateRecoveryCodes(ctx, 1, "[]", `["a"]`)
+		require.NoError(t, err)
+		assert.True(t, updated)
+
+		updated, err = s.UpdateRecoveryCodes(ctx, 1, "[]", `["b"]`)
+		require.NoError(t, err)
+		assert.False(t, updated)
+
+		userMFA, err := s.Get(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, `["a"]`, userMFA.RecoveryCodes)
+	})
+
+	t.Run("should delete the second factor", func(t *testing.T) {
+		require.NoError(t, s.Delete(ctx, 1))
+
+		userMFA, err := s.Get(ctx, 1)
+		require.NoError(t, err)
+		assert.Nil(t, userMFA)
+	})
+}
+
+func TestIntegrationOrgMFAPolicyStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	sqlStore := db.InitTestDB(t)
+	s := &xormStore{db: sqlStore, now: time.Now}
+
+	err := sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Insert(
+			&org.OrgUser{OrgID: 1, UserID: 1, Role: org.RoleViewer, Created: time.Now(), Updated: time.Now()},
+			&org.OrgUser{OrgID: 2, UserID: 2, Role: org.RoleViewer, Created: time.Now(), Updated: time.Now()},
+		)
+		return err
+	})
+	require.NoError(t, err)
+
+	policy, err := s.GetOrgPolicy(ctx, 1)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+
+	require.NoError(t, s.SaveOrgPolicy(ctx, &mfa.OrgPolicy{OrgID: 1, Required: true}))
+
+	required, err := s.IsRequiredByOrg(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, required)
+
+	required, err = s.IsRequiredByOrg(ctx, 2)
+	require.NoError(t, err)
+	assert.False(t, required)
+
+	require.NoError(t, s.SaveOrgPolicy(ctx, &mfa.OrgPolicy{OrgID: 1, Required: false}))
+
+	policy, err = s.GetOrgPolicy(ctx, 1)
+	require.NoError(t, err)
+	require.NotNil(t, policy)
+	assert.False(t, policy.Required)
+
+	required, err = s.IsRequiredByOrg(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, required)
+}
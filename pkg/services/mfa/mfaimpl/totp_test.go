@@ -0,0 +1,98 @@
+package mfaimpl
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 secret of the RFC 6238 test vectors
+var rfcSecret = secretEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	// the RFC 6238 codes are 8 digits long, the last 6 ones are the codes of the same steps with 6 digits
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "287082"},
+		{unix: 1111111109, expected: "081804"},
+		{unix: 1111111111, expected: "050471"},
+		{unix: 1234567890, expected: "005924"},
+		{unix: 2000000000, expected: "279037"},
+	}
+
+	for _, tt := range tests {
+		step, ok := validateTOTP(rfcSecret, tt.expected, time.Unix(tt.unix, 0), 0)
+		require.True(t, ok, "code at %d", tt.unix)
+		assert.Equal(t, tt.unix/totpPeriod, step)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code := "050471"
+	current := totpStep(now)
+
+	t.Run("should accept codes of the adjacent steps", func(t *testing.T) {
+		step, ok := validateTOTP(rfcSecret, code, now.Add(totpPeriod*time.Second), 0)
+		require.True(t, ok)
+		assert.Equal(t, current, step)
+
+		step, ok = validateTOTP(rfcSecret, code, now.Add(-totpPeriod*time.Second), 0)
+		require.True(t, ok)
+		assert.Equal(t, current, step)
+	})
+
+	t.Run("should reject codes outside of the skew", func(t *testing.T) {
+		_, ok := validateTOTP(rfcSecret, code, now.Add(2*totpPeriod*time.Second), 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("should reject codes of used steps", func(t *testing.T) {
+		_, ok := validateTOTP(rfcSecret, code, now, current)
+		assert.False(t, ok)
+	})
+
+	t.Run("should reject invalid codes and secrets", func(t *testing.T) {
+		_, ok := validateTOTP(rfcSecret, "000000", now, 0)
+		assert.False(t, ok)
+
+		_, ok = validateTOTP("not base32!", code, now, 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("should accept lower case secrets", func(t *testing.T) {
+		_, ok := validateTOTP("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code, now, 0)
+		assert.True(t, ok)
+	})
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := generateSecret()
+	require.NoError(t, err)
+
+	key, err := secretEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	assert.Len(t, key, secretSize)
+
+	other, err := generateSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+}
+
+func TestKeyURI(t *testing.T) {
+	u, err := url.Parse(keyURI("Grafana", "admin@example.org", "SECRET"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Grafana:admin@example.org", u.Path)
+	assert.Equal(t, "SECRET", u.Query().Get("secret"))
+	assert.Equal(t, "Grafana", u.Query().Get("issuer"))
+	assert.Equal(t, "6", u.Query().Get("digits"))
+	assert.Equal(t, "30", u.Query().Get("period"))
+}
@@ -0,0 +1,77 @@
+package mfatest
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/mfa"
+)
+
+var _ mfa.Service = new(FakeService)
+
+type FakeService struct {
+	ExpectedEnabled       bool
+	ExpectedStatus        *mfa.Status
+	ExpectedEnrollment    *mfa.Enrollment
+	ExpectedRecoveryCodes []string
+	ExpectedChallenge     *mfa.Challenge
+	ExpectedUserID        int64
+	ExpectedOrgPolicy     *mfa.OrgPolicy
+	ExpectedErr           error
+	// ExpectedVerifyErr is returned by the methods checking a code, ExpectedErr is used when it's nil
+	ExpectedVerifyErr error
+}
+
+func (f *FakeService) IsEnabled() bool {
+	return f.ExpectedEnabled
+}
+
+func (f *FakeService) GetStatus(ctx context.Context, userID int64) (*mfa.Status, error) {
+	return f.ExpectedStatus, f.ExpectedErr
+}
+
+func (f *FakeService) BeginEnrollment(ctx context.Context, userID int64) (*mfa.Enrollment, error) {
+	return f.ExpectedEnrollment, f.ExpectedErr
+}
+
+func (f *FakeService) ConfirmEnrollment(ctx context.Context, userID int64, code string) error {
+	return f.verifyErr()
+}
+
+func (f *FakeService) Verify(ctx context.Context, userID int64, code string) error {
+	return f.verifyErr()
+}
+
+func (f *FakeService) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	return f.ExpectedRecoveryCodes, f.verifyErr()
+}
+
+func (f *FakeService) Disable(ctx context.Context, userID int64, code string) error {
+	return f.verifyErr()
+}
+
+func (f *FakeService) Reset(ctx context.Context, userID int64) error {
+	return f.ExpectedErr
+}
+
+func (f *FakeService) Challenge(ctx context.Context, userID int64) (*mfa.Challenge, error) {
+	return f.ExpectedChallenge, f.ExpectedErr
+}
+
+func (f *FakeService) VerifyChallenge(ctx context.Context, token string) (int64, error) {
+	return f.ExpectedUserID, f.ExpectedErr
+}
+
+func (f *FakeService) GetOrgPolicy(ctx context.Context, orgID int64) (*mfa.OrgPolicy, error) {
+	return f.ExpectedOrgPolicy, f.ExpectedErr
+}
+
+func (f *FakeService) SetOrgPolicy(ctx context.Context, policy *mfa.OrgPolicy) error {
+	return f.ExpectedErr
+}
+
+func (f *FakeService) verifyErr() error {
+	if f.ExpectedVerifyErr != nil {
+		return f.ExpectedVerifyErr
+	}
+	return f.ExpectedErr
+}
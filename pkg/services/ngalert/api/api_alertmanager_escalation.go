@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// RouteGetAlertGroupAcknowledgements is the acknowledgement list GET endpoint for Grafana AM.
+func (srv AlertmanagerSrv) RouteGetAlertGroupAcknowledgements(c *contextmodel.ReqContext) response.Response {
+	acks, err := srv.mam.ListAlertGroupAcknowledgements(c.Req.Context(), c.SignedInUser.GetOrgID())
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to list alert group acknowledgements", err)
+	}
+	return response.JSON(http.StatusOK, acks)
+}
+
+// RouteCreateAlertGroupAcknowledgement is the acknowledgement POST endpoint for Grafana AM.
+func (srv AlertmanagerSrv) RouteCreateAlertGroupAcknowledgement(c *contextmodel.ReqContext, body apimodels.PostableAlertGroupAcknowledgement) response.Response {
+	ack, err := srv.mam.AcknowledgeAlertGroup(c.Req.Context(), c.SignedInUser.GetOrgID(), body, c.SignedInUser.GetLogin())
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to acknowledge alert group", err)
+	}
+	return response.JSON(http.StatusAccepted, ack)
+}
+
+// RouteDeleteAlertGroupAcknowledgement is the acknowledgement DELETE endpoint for Grafana AM.
+func (srv AlertmanagerSrv) RouteDeleteAlertGroupAcknowledgement(c *contextmodel.ReqContext, groupKey string) response.Response {
+	if err := srv.mam.UnacknowledgeAlertGroup(c.Req.Context(), c.SignedInUser.GetOrgID(), groupKey); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "failed to delete alert group acknowledgement", err)
+	}
+	return response.JSON(http.StatusOK, util.DynMap{"message": "alert group acknowledgement deleted"})
+}
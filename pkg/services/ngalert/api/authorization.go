@@ -166,6 +166,14 @@ func (api *API) authorize(method, path string) web.Handler {
 		eval = ac.EvalPermission(ac.ActionAlertingInstanceRead)
 	case http.MethodGet + "/api/alertmanager/grafana/api/v2/alerts":
 		eval = ac.EvalPermission(ac.ActionAlertingInstanceRead)
+	case http.MethodGet + "/api/alertmanager/grafana/api/v2/alerts/groups/acknowledgements":
+		eval = ac.EvalPermission(ac.ActionAlertingInstanceRead)
+	case http.MethodPost + "/api/alertmanager/grafana/api/v2/alerts/groups/acknowledgements",
+		http.MethodDelete + "/api/alertmanager/grafana/api/v2/alerts/groups/acknowledgements/{GroupKey}":
+		eval = ac.EvalAll(
+			ac.EvalPermission(ac.ActionAlertingInstanceRead),
+			ac.EvalPermission(ac.ActionAlertingInstanceUpdate),
+		)
 
 	// Grafana Prometheus-compatible Paths
 	case http.MethodGet + "/api/prometheus/grafana/api/v1/alerts":
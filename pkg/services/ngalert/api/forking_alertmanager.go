@@ -193,3 +193,15 @@ func (f *AlertmanagerApiHandler) handleRoutePostTestGrafanaReceivers(ctx *contex
 func (f *AlertmanagerApiHandler) handleRoutePostTestGrafanaTemplates(ctx *contextmodel.ReqContext, conf apimodels.TestTemplatesConfigBodyParams) response.Response {
 	return f.GrafanaSvc.RoutePostTestTemplates(ctx, conf)
 }
+
+func (f *AlertmanagerApiHandler) handleRouteGetGrafanaAlertGroupAcknowledgements(ctx *contextmodel.ReqContext) response.Response {
+	return f.GrafanaSvc.RouteGetAlertGroupAcknowledgements(ctx)
+}
+
+func (f *AlertmanagerApiHandler) handleRouteCreateGrafanaAlertGroupAcknowledgement(ctx *contextmodel.ReqContext, body apimodels.PostableAlertGroupAcknowledgement) response.Response {
+	return f.GrafanaSvc.RouteCreateAlertGroupAcknowledgement(ctx, body)
+}
+
+func (f *AlertmanagerApiHandler) handleRouteDeleteGrafanaAlertGroupAcknowledgement(ctx *contextmodel.ReqContext, groupKey string) response.Response {
+	return f.GrafanaSvc.RouteDeleteAlertGroupAcknowledgement(ctx, groupKey)
+}
@@ -20,9 +20,11 @@ import (
 )
 
 type AlertmanagerApi interface {
+	RouteCreateGrafanaAlertGroupAcknowledgement(*contextmodel.ReqContext) response.Response
 	RouteCreateGrafanaSilence(*contextmodel.ReqContext) response.Response
 	RouteCreateSilence(*contextmodel.ReqContext) response.Response
 	RouteDeleteAlertingConfig(*contextmodel.ReqContext) response.Response
+	RouteDeleteGrafanaAlertGroupAcknowledgement(*contextmodel.ReqContext) response.Response
 	RouteDeleteGrafanaAlertingConfig(*contextmodel.ReqContext) response.Response
 	RouteDeleteGrafanaSilence(*contextmodel.ReqContext) response.Response
 	RouteDeleteSilence(*contextmodel.ReqContext) response.Response
@@ -33,6 +35,7 @@ type AlertmanagerApi interface {
 	RouteGetGrafanaAMAlertGroups(*contextmodel.ReqContext) response.Response
 	RouteGetGrafanaAMAlerts(*contextmodel.ReqContext) response.Response
 	RouteGetGrafanaAMStatus(*contextmodel.ReqContext) response.Response
+	RouteGetGrafanaAlertGroupAcknowledgements(*contextmodel.ReqContext) response.Response
 	RouteGetGrafanaAlertingConfig(*contextmodel.ReqContext) response.Response
 	RouteGetGrafanaAlertingConfigHistory(*contextmodel.ReqContext) response.Response
 	RouteGetGrafanaReceivers(*contextmodel.ReqContext) response.Response
@@ -48,6 +51,14 @@ type AlertmanagerApi interface {
 	RoutePostTestGrafanaTemplates(*contextmodel.ReqContext) response.Response
 }
 
+func (f *AlertmanagerApiHandler) RouteCreateGrafanaAlertGroupAcknowledgement(ctx *contextmodel.ReqContext) response.Response {
+	// Parse Request Body
+	conf := apimodels.PostableAlertGroupAcknowledgement{}
+	if err := web.Bind(ctx.Req, &conf); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	return f.handleRouteCreateGrafanaAlertGroupAcknowledgement(ctx, conf)
+}
 func (f *AlertmanagerApiHandler) RouteCreateGrafanaSilence(ctx *contextmodel.ReqContext) response.Response {
 	// Parse Request Body
 	conf := apimodels.PostableSilence{}
@@ -71,6 +82,11 @@ func (f *AlertmanagerApiHandler) RouteDeleteAlertingConfig(ctx *contextmodel.Req
 	datasourceUIDParam := web.Params(ctx.Req)[":DatasourceUID"]
 	return f.handleRouteDeleteAlertingConfig(ctx, datasourceUIDParam)
 }
+func (f *AlertmanagerApiHandler) RouteDeleteGrafanaAlertGroupAcknowledgement(ctx *contextmodel.ReqContext) response.Response {
+	// Parse Path Parameters
+	groupKeyParam := web.Params(ctx.Req)[":GroupKey"]
+	return f.handleRouteDeleteGrafanaAlertGroupAcknowledgement(ctx, groupKeyParam)
+}
 func (f *AlertmanagerApiHandler) RouteDeleteGrafanaAlertingConfig(ctx *contextmodel.ReqContext) response.Response {
 	return f.handleRouteDeleteGrafanaAlertingConfig(ctx)
 }
@@ -114,6 +130,9 @@ func (f *AlertmanagerApiHandler) RouteGetGrafanaAMAlerts(ctx *contextmodel.ReqCo
 func (f *AlertmanagerApiHandler) RouteGetGrafanaAMStatus(ctx *contextmodel.ReqContext) response.Response {
 	return f.handleRouteGetGrafanaAMStatus(ctx)
 }
+func (f *AlertmanagerApiHandler) RouteGetGrafanaAlertGroupAcknowledgements(ctx *contextmodel.ReqContext) response.Response {
+	return f.handleRouteGetGrafanaAlertGroupAcknowledgements(ctx)
+}
 func (f *AlertmanagerApiHandler) RouteGetGrafanaAlertingConfig(ctx *contextmodel.ReqContext) response.Response {
 	return f.handleRouteGetGrafanaAlertingConfig(ctx)
 }
@@ -194,6 +213,18 @@ func (f *AlertmanagerApiHandler) RoutePostTestGrafanaTemplates(ctx *contextmodel
 
 func (api *API) RegisterAlertmanagerApiEndpoints(srv AlertmanagerApi, m *metrics.API) {
 	api.RouteRegister.Group("", func(group routing.RouteRegister) {
+		group.Post(
+			toMacaronPath("/api/alertmanager/grafana/api/v2/alerts/groups/acknowledgements"),
+			requestmeta.SetOwner(requestmeta.TeamAlerting),
+			requestmeta.SetSLOGroup(requestmeta.SLOGroupHighSlow),
+			api.authorize(http.MethodPost, "/api/alertmanager/grafana/api/v2/alerts/groups/acknowledgements"),
+			metrics.Instrument(
+				http.MethodPost,
+				"/api/alertmanager/grafana/api/v2/alerts/groups/acknowledgements",
+				api.Hooks.Wrap(srv.RouteCreateGrafanaAlertGroupAcknowledgement),
+				m,
+			),
+		)
 		group.Post(
 			toMacaronPath("/api/alertmanager/grafana/api/v2/silences"),
 			requestmeta.SetOwner(requestmeta.TeamAlerting),
@@ -230,6 +261,18 @@ func (api *API) RegisterAlertmanagerApiEndpoints(srv AlertmanagerApi, m *metrics
 				m,
 			),
 		)
+		group.Delete(
+			toMacaronPath("/api/alertmanager/grafana/api/v2/alerts/groups/acknowledgements/{GroupKey}"),
+			requestmeta.SetOwner(requestmeta.TeamAlerting),
+			requestmeta.SetSLOGroup(requestmeta.SLOGroupHighSlow),
+			api.authorize(http.MethodDelete, "/api/alertmanager/grafana/api/v2/alerts/groups/acknowledgements/{GroupKey}"),
+			metrics.Instrument(
+				http.MethodDelete,
+				"/api/alertmanager/grafana/api/v2/alerts/groups/acknowledgements/{GroupKey}",
+				api.Hooks.Wrap(srv.RouteDeleteGrafanaAlertGroupAcknowledgement),
+				m,
+			),
+		)
 		group.Delete(
 			toMacaronPath("/api/alertmanager/grafana/config/api/v1/alerts"),
 			requestmeta.SetOwner(requestmeta.TeamAlerting),
@@ -350,6 +393,18 @@ func (api *API) RegisterAlertmanagerApiEndpoints(srv AlertmanagerApi, m *metrics
 				m,
 			),
 		)
+		group.Get(
+			toMacaronPath("/api/alertmanager/grafana/api/v2/alerts/groups/acknowledgements"),
+			requestmeta.SetOwner(requestmeta.TeamAlerting),
+			requestmeta.SetSLOGroup(requestmeta.SLOGroupHighSlow),
+			api.authorize(http.MethodGet, "/api/alertmanager/grafana/api/v2/alerts/groups/acknowledgements"),
+			metrics.Instrument(
+				http.MethodGet,
+				"/api/alertmanager/grafana/api/v2/alerts/groups/acknowledgements",
+				api.Hooks.Wrap(srv.RouteGetGrafanaAlertGroupAcknowledgements),
+				m,
+			),
+		)
 		group.Get(
 			toMacaronPath("/api/alertmanager/grafana/config/api/v1/alerts"),
 			requestmeta.SetOwner(requestmeta.TeamAlerting),
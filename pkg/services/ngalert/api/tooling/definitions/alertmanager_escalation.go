@@ -0,0 +1,76 @@
+package definitions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// swagger:route GET /alertmanager/grafana/api/v2/alerts/groups/acknowledgements alertmanager RouteGetGrafanaAlertGroupAcknowledgements
+//
+// get acknowledgements of active alert groups
+//
+//     Responses:
+//       200: gettableAlertGroupAcknowledgements
+
+// swagger:route POST /alertmanager/grafana/api/v2/alerts/groups/acknowledgements alertmanager RouteCreateGrafanaAlertGroupAcknowledgement
+//
+// acknowledge an active alert group
+//
+//     Responses:
+//       202: gettableAlertGroupAcknowledgement
+//       400: ValidationError
+//       404: NotFound
+
+// swagger:route DELETE /alertmanager/grafana/api/v2/alerts/groups/acknowledgements/{GroupKey} alertmanager RouteDeleteGrafanaAlertGroupAcknowledgement
+//
+// remove the acknowledgement of an alert group
+//
+//     Responses:
+//       200: Ack
+//       404: NotFound
+
+// swagger:parameters RouteCreateGrafanaAlertGroupAcknowledgement
+type CreateAlertGroupAcknowledgementParams struct {
+	// in:body
+	Body PostableAlertGroupAcknowledgement
+}
+
+// swagger:parameters RouteDeleteGrafanaAlertGroupAcknowledgement
+type DeleteAlertGroupAcknowledgementParams struct {
+	// in:path
+	GroupKey string
+}
+
+// PostableAlertGroupAcknowledgement identifies an alert group the same way it is returned by the alert groups endpoint:
+// by the name of its receiver and the labels it is grouped by.
+// swagger:model postableAlertGroupAcknowledgement
+type PostableAlertGroupAcknowledgement struct {
+	// required: true
+	Receiver string `json:"receiver"`
+	// required: true
+	Labels  model.LabelSet `json:"labels"`
+	Comment string         `json:"comment,omitempty"`
+}
+
+func (a PostableAlertGroupAcknowledgement) Validate() error {
+	if a.Receiver == "" {
+		return fmt.Errorf("receiver must not be empty")
+	}
+	return a.Labels.Validate()
+}
+
+// swagger:model gettableAlertGroupAcknowledgement
+type GettableAlertGroupAcknowledgement struct {
+	// GroupKey uniquely identifies the acknowledged alert group within the organization.
+	GroupKey       string         `json:"groupKey"`
+	Receiver       string         `json:"receiver"`
+	Labels         model.LabelSet `json:"labels"`
+	Comment        string         `json:"comment,omitempty"`
+	AcknowledgedBy string         `json:"acknowledgedBy"`
+	AcknowledgedAt time.Time      `json:"acknowledgedAt"`
+}
+
+// swagger:model gettableAlertGroupAcknowledgements
+type GettableAlertGroupAcknowledgements []GettableAlertGroupAcknowledgement
@@ -0,0 +1,15 @@
+package definitions
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePostableAlertGroupAcknowledgement(t *testing.T) {
+	require.NoError(t, PostableAlertGroupAcknowledgement{Receiver: "team", Labels: model.LabelSet{"alertname": "HighLatency"}}.Validate())
+	require.NoError(t, PostableAlertGroupAcknowledgement{Receiver: "team"}.Validate(), "alert groups grouped by nothing have no labels")
+	require.EqualError(t, PostableAlertGroupAcknowledgement{Labels: model.LabelSet{"alertname": "HighLatency"}}.Validate(), "receiver must not be empty")
+	require.Error(t, PostableAlertGroupAcknowledgement{Receiver: "team", Labels: model.LabelSet{"0invalid": "value"}}.Validate())
+}
@@ -154,6 +154,12 @@ const (
 	// AutogeneratedRouteSettingsHashLabel a label name that contains the hash of the notification settings that will be used to send notifications for the alert.
 	// This should uniquely identify the notification settings (group_by, group_wait, group_interval, repeat_interval, mute_time_intervals) for the alert.
 	AutogeneratedRouteSettingsHashLabel = "__grafana_route_settings_hash__"
+
+	// EscalateAfterLabel a label name matched by nested notification policies that escalate the alert groups of their parent policy.
+	// The value of the matcher is how long a group can stay unacknowledged before it is escalated, e.g. `15m`.
+	EscalateAfterLabel = "__grafana_escalate_after__"
+	// EscalationRouteLabel a label name that contains the ID of the escalation step that an escalation alert is sent for.
+	EscalationRouteLabel = "__grafana_escalation__"
 )
 
 const (
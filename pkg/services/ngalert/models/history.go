@@ -18,3 +18,58 @@ type HistoryQuery struct {
 	Limit        int
 	SignedInUser identity.Requester
 }
+
+// StateHistoryEntry is a single state transition of an alert instance, as recorded by the SQL state history backend.
+type StateHistoryEntry struct {
+	ID             int64  `xorm:"pk autoincr 'id'"`
+	OrgID          int64  `xorm:"org_id"`
+	RuleUID        string `xorm:"rule_uid"`
+	RuleID         int64  `xorm:"rule_id"`
+	RuleTitle      string `xorm:"rule_title"`
+	RuleGroup      string `xorm:"rule_group"`
+	RuleCondition  string `xorm:"rule_condition"`
+	NamespaceUID   string `xorm:"namespace_uid"`
+	DashboardUID   string `xorm:"dashboard_uid"`
+	PanelID        int64  `xorm:"panel_id"`
+	Labels         string `xorm:"labels"` // JSON-encoded instance labels.
+	LabelsHash     string `xorm:"labels_hash"`
+	PreviousState  string `xorm:"previous_state"`
+	PreviousReason string `xorm:"previous_reason"`
+	CurrentState   string `xorm:"current_state"`
+	CurrentReason  string `xorm:"current_reason"`
+	Values         string `xorm:"state_values"` // JSON-encoded evaluation values.
+	Error          string `xorm:"error_message"`
+	EvaluatedAt    int64  `xorm:"evaluated_at"` // Unix milliseconds.
+}
+
+// A XORM interface that defines the used table for this struct.
+func (e *StateHistoryEntry) TableName() string {
+	return "alert_state_history"
+}
+
+// StateHistoryLabel records that the label set identified by LabelsHash contains the label pair identified by PairHash.
+type StateHistoryLabel struct {
+	ID         int64  `xorm:"pk autoincr 'id'"`
+	OrgID      int64  `xorm:"org_id"`
+	LabelsHash string `xorm:"labels_hash"`
+	PairHash   string `xorm:"pair_hash"`
+}
+
+// A XORM interface that defines the used table for this struct.
+func (l *StateHistoryLabel) TableName() string {
+	return "alert_state_history_label"
+}
+
+// ListStateHistoryQuery is a query for entries recorded by the SQL state history backend.
+type ListStateHistoryQuery struct {
+	OrgID         int64
+	RuleUID       string
+	DashboardUID  string
+	PanelID       int64
+	NamespaceUIDs []string
+	// PairHashes restricts the result to label sets that contain every one of the given label pairs.
+	PairHashes []string
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
@@ -213,6 +213,7 @@ func (am *alertmanager) SaveAndApplyConfig(ctx context.Context, cfg *apimodels.P
 	// Remove autogenerated config from the user config before saving it, may not be necessary as we already remove
 	// the autogenerated config before provenance guard. However, this is low impact and a good safety net.
 	RemoveAutogenConfigIfExists(cfg.AlertmanagerConfig.Route)
+	RemoveEscalationRoutesIfExist(cfg.AlertmanagerConfig.Route)
 	rawConfig, err := json.Marshal(&cfg)
 	if err != nil {
 		return fmt.Errorf("failed to serialize to the Alertmanager configuration: %w", err)
@@ -327,6 +328,10 @@ func (am *alertmanager) aggregateInhibitMatchers(rules []config.InhibitRule, amu
 // It returns a boolean indicating whether the user config was changed and an error.
 // It is not safe to call concurrently.
 func (am *alertmanager) applyConfig(cfg *apimodels.PostableUserConfig) (bool, error) {
+	if err := AddEscalationRoutes(cfg.AlertmanagerConfig.Route); err != nil {
+		return false, err
+	}
+
 	// First, let's make sure this config is not already loaded
 	rawConfig, err := json.Marshal(cfg)
 	if err != nil {
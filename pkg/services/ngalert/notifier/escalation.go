@@ -0,0 +1,608 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+const (
+	AcknowledgementsFilename = "acknowledgements"
+	EscalationsFilename      = "escalations"
+
+	// escalationInterval is how often active alert groups are checked for pending escalation steps.
+	escalationInterval = 30 * time.Second
+	// escalationAlertTTL is how long escalation alerts stay firing without being sent again. They are sent on every
+	// check, so they resolve shortly after their alert group is acknowledged or resolves.
+	escalationAlertTTL = 4 * escalationInterval
+
+	// groupKeyReceiverLabel is mixed into the labels of an alert group to tell apart groups with the same labels
+	// that are notified by different receivers.
+	groupKeyReceiverLabel = "__receiver__"
+)
+
+var (
+	ErrAlertGroupNotFound                  = errutil.NotFound("alerting.notifications.alertGroups.notFound")
+	ErrAlertGroupAcknowledgementNotFound   = errutil.NotFound("alerting.notifications.acknowledgements.notFound")
+	ErrAlertGroupAcknowledgementBadRequest = errutil.BadRequest("alerting.notifications.acknowledgements.badRequest")
+	ErrEscalationPolicyInvalid             = errutil.BadRequest("alerting.notifications.escalation.invalid")
+)
+
+// escalation tracks how far an active alert group has been escalated.
+type escalation struct {
+	// ActiveSince is when the oldest alert of the group started firing, at the time the group was first seen.
+	ActiveSince time.Time `json:"activeSince"`
+	// Notified is the number of escalation steps that are due.
+	Notified int `json:"notified"`
+}
+
+// escalationStep is a nested notification policy that escalates the alert groups of its parent policy.
+type escalationStep struct {
+	// ID identifies the step in the labels of the escalation alerts sent for it.
+	ID       string
+	After    time.Duration
+	Receiver string
+	// route is the escalation policy, with the settings it inherits from its parents.
+	route *dispatch.Route
+}
+
+// escalationTree is the routing tree of an Alertmanager configuration, along with the escalation steps of its policies.
+type escalationTree struct {
+	root *dispatch.Route
+	// steps are the escalation steps of the policies that have any, ordered by their delay.
+	steps map[*dispatch.Route][]escalationStep
+	// ordered are all the steps in the order of the routing tree.
+	ordered []escalationStep
+}
+
+// newEscalationTree finds the escalation steps of a routing tree. An escalation step is a nested policy with a single
+// matcher of the label models.EscalateAfterLabel equal to a duration, and no nested policies.
+func newEscalationTree(route *apimodels.Route) (*escalationTree, error) {
+	tree := &escalationTree{
+		root:  dispatch.NewRoute(route.AsAMRoute(), nil),
+		steps: make(map[*dispatch.Route][]escalationStep),
+	}
+	if err := tree.walk(route, tree.root); err != nil {
+		return nil, err
+	}
+	for _, steps := range tree.steps {
+		sort.SliceStable(steps, func(i, j int) bool {
+			return steps[i].After < steps[j].After
+		})
+	}
+	return tree, nil
+}
+
+func (t *escalationTree) walk(route *apimodels.Route, r *dispatch.Route) error {
+	if len(route.Routes) != len(r.Routes) {
+		return fmt.Errorf("unexpected routing tree: %d nested policies, expected %d", len(r.Routes), len(route.Routes))
+	}
+	for i, child := range route.Routes {
+		after, ok, err := escalateAfter(child)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := t.walk(child, r.Routes[i]); err != nil {
+				return err
+			}
+			continue
+		}
+		step := escalationStep{
+			ID:       escalationStepID(r.Key(), after, child.Receiver),
+			After:    after,
+			Receiver: child.Receiver,
+			route:    r.Routes[i],
+		}
+		t.steps[r] = append(t.steps[r], step)
+		t.ordered = append(t.ordered, step)
+	}
+	return nil
+}
+
+// stepsFor returns the escalation steps of the policy that notifies the alert group.
+func (t *escalationTree) stepsFor(g *apimodels.AlertGroup) []escalationStep {
+	ls := alertGroupAlertLabels(g)
+	for _, r := range t.root.Match(ls) {
+		if r.RouteOpts.Receiver != *g.Receiver.Name {
+			continue
+		}
+		if steps := t.steps[r]; len(steps) > 0 {
+			return steps
+		}
+	}
+	return nil
+}
+
+// escalateAfter returns the delay of a route that is an escalation step, and false if it is a regular route.
+func escalateAfter(route *apimodels.Route) (time.Duration, bool, error) {
+	idx := slices.IndexFunc(route.ObjectMatchers, func(m *labels.Matcher) bool {
+		return m.Name == models.EscalateAfterLabel
+	})
+	if idx < 0 {
+		return 0, false, nil
+	}
+	m := route.ObjectMatchers[idx]
+	if m.Type != labels.MatchEqual {
+		return 0, false, ErrEscalationPolicyInvalid.Errorf("escalation policies must match %s with =", models.EscalateAfterLabel)
+	}
+	after, err := model.ParseDuration(m.Value)
+	if err != nil || after <= 0 {
+		return 0, false, ErrEscalationPolicyInvalid.Errorf("invalid escalation delay %q: must be a positive duration such as 15m", m.Value)
+	}
+	if len(route.ObjectMatchers) > 1 || len(route.Matchers) > 0 || len(route.Match) > 0 || len(route.MatchRE) > 0 {
+		return 0, false, ErrEscalationPolicyInvalid.Errorf("escalation policies must have no other matcher than %s", models.EscalateAfterLabel)
+	}
+	if route.Receiver == "" {
+		return 0, false, ErrEscalationPolicyInvalid.Errorf("escalation policy after %s must have a contact point", m.Value)
+	}
+	if len(route.Routes) > 0 {
+		return 0, false, ErrEscalationPolicyInvalid.Errorf("escalation policy after %s must not have nested policies", m.Value)
+	}
+	return time.Duration(after), true, nil
+}
+
+func escalationStepID(routeKey string, after time.Duration, receiver string) string {
+	return model.LabelSet{
+		"route":    model.LabelValue(routeKey),
+		"after":    model.LabelValue(model.Duration(after).String()),
+		"receiver": model.LabelValue(receiver),
+	}.Fingerprint().String()
+}
+
+// AddEscalationRoutes adds a top-level route for every escalation step of the routing tree, ahead of the other routes.
+// Escalation alerts are copies of the alerts of unacknowledged groups labeled with the step they are sent for, so they
+// are notified like any other alert: with the templates, grouping and timing of the escalation policy, and recorded
+// in the notification log. The escalation routes are never saved.
+func AddEscalationRoutes(route *apimodels.Route) error {
+	if route == nil {
+		return nil
+	}
+	RemoveEscalationRoutesIfExist(route)
+
+	tree, err := newEscalationTree(route)
+	if err != nil {
+		return err
+	}
+	generated := make([]*apimodels.Route, 0, len(tree.ordered))
+	for _, step := range tree.ordered {
+		matcher, err := labels.NewMatcher(labels.MatchEqual, models.EscalationRouteLabel, step.ID)
+		if err != nil {
+			return err
+		}
+		opts := step.route.RouteOpts
+		groupByStr := make([]string, 0, len(opts.GroupBy))
+		if opts.GroupByAll {
+			groupByStr = append(groupByStr, models.GroupByAll)
+		}
+		for l := range opts.GroupBy {
+			groupByStr = append(groupByStr, string(l))
+		}
+		sort.Strings(groupByStr)
+		groupByAll, groupBy := toGroupBy(groupByStr...)
+		groupWait := model.Duration(opts.GroupWait)
+		groupInterval := model.Duration(opts.GroupInterval)
+		repeatInterval := model.Duration(opts.RepeatInterval)
+		generated = append(generated, &apimodels.Route{
+			Receiver:            step.Receiver,
+			ObjectMatchers:      apimodels.ObjectMatchers{matcher},
+			Continue:            false,
+			GroupByStr:          groupByStr,
+			GroupBy:             groupBy,
+			GroupByAll:          groupByAll,
+			GroupWait:           &groupWait,
+			GroupInterval:       &groupInterval,
+			RepeatInterval:      &repeatInterval,
+			MuteTimeIntervals:   opts.MuteTimeIntervals,
+			ActiveTimeIntervals: opts.ActiveTimeIntervals,
+		})
+	}
+	route.Routes = append(generated, route.Routes...)
+	return nil
+}
+
+// RemoveEscalationRoutesIfExist removes the top-level routes added by AddEscalationRoutes.
+func RemoveEscalationRoutesIfExist(route *apimodels.Route) {
+	route.Routes = slices.DeleteFunc(route.Routes, func(r *apimodels.Route) bool {
+		return len(r.ObjectMatchers) == 1 && r.ObjectMatchers[0].Name == models.EscalationRouteLabel
+	})
+}
+
+// AlertGroupKey returns the key that identifies an alert group within an organization.
+func AlertGroupKey(receiver string, labels model.LabelSet) string {
+	ls := labels.Clone()
+	ls[groupKeyReceiverLabel] = model.LabelValue(receiver)
+	return ls.Fingerprint().String()
+}
+
+// ListAlertGroupAcknowledgements returns the acknowledgements of the active alert groups of the organization.
+func (moa *MultiOrgAlertmanager) ListAlertGroupAcknowledgements(ctx context.Context, orgID int64) (apimodels.GettableAlertGroupAcknowledgements, error) {
+	moa.escalationMtx.Lock()
+	defer moa.escalationMtx.Unlock()
+
+	acks := map[string]apimodels.GettableAlertGroupAcknowledgement{}
+	if err := moa.getEscalationFile(ctx, orgID, AcknowledgementsFilename, &acks); err != nil {
+		return nil, err
+	}
+
+	result := make(apimodels.GettableAlertGroupAcknowledgements, 0, len(acks))
+	for _, ack := range acks {
+		result = append(result, ack)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].AcknowledgedAt.Before(result[j].AcknowledgedAt)
+	})
+	return result, nil
+}
+
+// AcknowledgeAlertGroup acknowledges an active alert group, which stops its escalation. The acknowledgement is
+// removed once the alert group resolves.
+func (moa *MultiOrgAlertmanager) AcknowledgeAlertGroup(ctx context.Context, orgID int64, pa apimodels.PostableAlertGroupAcknowledgement, acknowledgedBy string) (apimodels.GettableAlertGroupAcknowledgement, error) {
+	if err := pa.Validate(); err != nil {
+		return apimodels.GettableAlertGroupAcknowledgement{}, WithPublicError(ErrAlertGroupAcknowledgementBadRequest.Errorf("invalid acknowledgement: %w", err))
+	}
+
+	moa.alertmanagersMtx.RLock()
+	orgAM, err := moa.alertmanagerForOrg(orgID)
+	moa.alertmanagersMtx.RUnlock()
+	if err != nil {
+		return apimodels.GettableAlertGroupAcknowledgement{}, err
+	}
+
+	groups, err := orgAM.GetAlertGroups(ctx, true, true, true, nil, "")
+	if err != nil {
+		return apimodels.GettableAlertGroupAcknowledgement{}, fmt.Errorf("failed to get alert groups: %w", err)
+	}
+	key := AlertGroupKey(pa.Receiver, pa.Labels)
+	found := false
+	for _, g := range groups {
+		if groupKey, ok := alertGroupKey(g); ok && groupKey == key {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return apimodels.GettableAlertGroupAcknowledgement{}, WithPublicError(ErrAlertGroupNotFound.Errorf("alert group of receiver %s with labels %s not found", pa.Receiver, pa.Labels))
+	}
+
+	moa.escalationMtx.Lock()
+	defer moa.escalationMtx.Unlock()
+
+	acks := map[string]apimodels.GettableAlertGroupAcknowledgement{}
+	if err := moa.getEscalationFile(ctx, orgID, AcknowledgementsFilename, &acks); err != nil {
+		return apimodels.GettableAlertGroupAcknowledgement{}, err
+	}
+	ack := apimodels.GettableAlertGroupAcknowledgement{
+		GroupKey:       key,
+		Receiver:       pa.Receiver,
+		Labels:         pa.Labels,
+		Comment:        pa.Comment,
+		AcknowledgedBy: acknowledgedBy,
+		AcknowledgedAt: time.Now().UTC(),
+	}
+	acks[key] = ack
+	if err := moa.saveEscalationFile(ctx, orgID, AcknowledgementsFilename, acks); err != nil {
+		return apimodels.GettableAlertGroupAcknowledgement{}, err
+	}
+	return ack, nil
+}
+
+// UnacknowledgeAlertGroup removes the acknowledgement of an alert group, which resumes its escalation.
+func (moa *MultiOrgAlertmanager) UnacknowledgeAlertGroup(ctx context.Context, orgID int64, groupKey string) error {
+	moa.escalationMtx.Lock()
+	defer moa.escalationMtx.Unlock()
+
+	acks := map[string]apimodels.GettableAlertGroupAcknowledgement{}
+	if err := moa.getEscalationFile(ctx, orgID, AcknowledgementsFilename, &acks); err != nil {
+		return err
+	}
+	if _, ok := acks[groupKey]; !ok {
+		return WithPublicError(ErrAlertGroupAcknowledgementNotFound.Errorf("acknowledgement of alert group %s not found", groupKey))
+	}
+	delete(acks, groupKey)
+	return moa.saveEscalationFile(ctx, orgID, AcknowledgementsFilename, acks)
+}
+
+// runEscalations periodically escalates the unacknowledged alert groups of all organizations until the context is done.
+func (moa *MultiOrgAlertmanager) runEscalations(ctx context.Context) {
+	ticker := time.NewTicker(escalationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			moa.escalateAlertGroups(ctx, time.Now())
+		}
+	}
+}
+
+func (moa *MultiOrgAlertmanager) escalateAlertGroups(ctx context.Context, now time.Time) {
+	// All members of a cluster see the same alert groups, so only the first one sends escalations.
+	if moa.peer.Position() != 0 {
+		return
+	}
+
+	moa.alertmanagersMtx.RLock()
+	ams := make(map[int64]Alertmanager, len(moa.alertmanagers))
+	for orgID, am := range moa.alertmanagers {
+		if am.Ready() {
+			ams[orgID] = am
+		}
+	}
+	moa.alertmanagersMtx.RUnlock()
+
+	for orgID, am := range ams {
+		if err := moa.escalateAlertGroupsForOrg(ctx, orgID, am, now); err != nil {
+			moa.logger.Error("Failed to escalate alert groups", "org", orgID, "error", err)
+		}
+	}
+}
+
+// escalateAlertGroupsForOrg sends the escalation alerts of the steps that are due for every active, unacknowledged
+// alert group of the organization. It also forgets acknowledgements and escalations of groups that resolved.
+func (moa *MultiOrgAlertmanager) escalateAlertGroupsForOrg(ctx context.Context, orgID int64, am Alertmanager, now time.Time) error {
+	groups, err := am.GetAlertGroups(ctx, true, true, true, nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to get alert groups: %w", err)
+	}
+	// The groups that are silenced or inhibited are not resolved, they keep their acknowledgement but are not
+	// escalated.
+	unresolved := make(map[string]bool, len(groups))
+	active := make(map[string]*apimodels.AlertGroup, len(groups))
+	for _, g := range groups {
+		key, ok := alertGroupKey(g)
+		if !ok || len(g.Alerts) == 0 {
+			continue
+		}
+		unresolved[key] = true
+		if g := activeAlertGroup(g); len(g.Alerts) > 0 && isEscalable(g) {
+			active[key] = g
+		}
+	}
+
+	var tree *escalationTree
+	if len(active) > 0 {
+		cfg, err := moa.latestPostableConfig(ctx, orgID)
+		if err != nil {
+			return err
+		}
+		tree, err = newEscalationTree(cfg.AlertmanagerConfig.Route)
+		if err != nil {
+			return err
+		}
+	}
+
+	alerts, err := moa.updateEscalations(ctx, orgID, tree, active, unresolved, now)
+	if err != nil {
+		return err
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+	// The escalation alerts go through the notification pipeline of the Alertmanager, which deduplicates them
+	// with the notification log, so sending them again on every check does not notify again.
+	return am.PutAlerts(ctx, apimodels.PostableAlerts{PostableAlerts: alerts})
+}
+
+// updateEscalations records the escalation state of the active alert groups and returns the escalation alerts to send.
+// The acknowledgements of the groups that resolved are removed. The lock is released before the
+// alerts are sent, so that acknowledging a group does not wait for the Alertmanager.
+func (moa *MultiOrgAlertmanager) updateEscalations(ctx context.Context, orgID int64, tree *escalationTree, active map[string]*apimodels.AlertGroup, unresolved map[string]bool, now time.Time) ([]amv2.PostableAlert, error) {
+	moa.escalationMtx.Lock()
+	defer moa.escalationMtx.Unlock()
+
+	acks := map[string]apimodels.GettableAlertGroupAcknowledgement{}
+	if err := moa.getEscalationFile(ctx, orgID, AcknowledgementsFilename, &acks); err != nil {
+		return nil, err
+	}
+	escalations := map[string]*escalation{}
+	if err := moa.getEscalationFile(ctx, orgID, EscalationsFilename, &escalations); err != nil {
+		return nil, err
+	}
+
+	acksChanged := false
+	for key := range acks {
+		if !unresolved[key] {
+			delete(acks, key)
+			acksChanged = true
+		}
+	}
+	if acksChanged {
+		if err := moa.saveEscalationFile(ctx, orgID, AcknowledgementsFilename, acks); err != nil {
+			return nil, err
+		}
+	}
+
+	escalationsChanged := false
+	var alerts []amv2.PostableAlert
+	for key, g := range active {
+		steps := tree.stepsFor(g)
+		if len(steps) == 0 {
+			continue
+		}
+		e, ok := escalations[key]
+		if !ok {
+			e = &escalation{ActiveSince: alertGroupActiveSince(g, now)}
+			escalations[key] = e
+			escalationsChanged = true
+		}
+		if _, acked := acks[key]; acked {
+			continue
+		}
+
+		for i, step := range steps {
+			if now.Sub(e.ActiveSince) < step.After {
+				break
+			}
+			if i >= e.Notified {
+				moa.logger.Info("Escalating unacknowledged alert group", "org", orgID, "receiver", *g.Receiver.Name, "escalationReceiver", step.Receiver, "after", model.Duration(step.After))
+				e.Notified = i + 1
+				escalationsChanged = true
+			}
+			alerts = append(alerts, escalationAlerts(g, step, now)...)
+		}
+	}
+	for key := range escalations {
+		if g, ok := active[key]; !ok || len(tree.stepsFor(g)) == 0 {
+			delete(escalations, key)
+			escalationsChanged = true
+		}
+	}
+
+	if escalationsChanged {
+		if err := moa.saveEscalationFile(ctx, orgID, EscalationsFilename, escalations); err != nil {
+			return nil, err
+		}
+	}
+	return alerts, nil
+}
+
+// escalationAlerts returns copies of the alerts of the group, labeled with the escalation step they are sent for
+// so that they are routed to the escalation route of the step.
+func escalationAlerts(g *apimodels.AlertGroup, step escalationStep, now time.Time) []amv2.PostableAlert {
+	alerts := make([]amv2.PostableAlert, 0, len(g.Alerts))
+	for _, a := range g.Alerts {
+		if a == nil {
+			continue
+		}
+		ls := make(amv2.LabelSet, len(a.Labels)+1)
+		for k, v := range a.Labels {
+			ls[k] = v
+		}
+		ls[models.EscalationRouteLabel] = step.ID
+		pa := amv2.PostableAlert{
+			Annotations: a.Annotations,
+			EndsAt:      strfmt.DateTime(now.Add(escalationAlertTTL)),
+			Alert: amv2.Alert{
+				Labels:       ls,
+				GeneratorURL: a.GeneratorURL,
+			},
+		}
+		if a.StartsAt != nil {
+			pa.StartsAt = *a.StartsAt
+		}
+		alerts = append(alerts, pa)
+	}
+	return alerts
+}
+
+func (moa *MultiOrgAlertmanager) latestPostableConfig(ctx context.Context, orgID int64) (*apimodels.PostableUserConfig, error) {
+	amConfig, err := moa.configStore.GetLatestAlertmanagerConfiguration(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest configuration: %w", err)
+	}
+	cfg, err := Load([]byte(amConfig.AlertmanagerConfiguration))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal latest configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// getEscalationFile reads a JSON document stored by the escalation subsystem from the kvstore into v.
+// v is left untouched if the document does not exist.
+func (moa *MultiOrgAlertmanager) getEscalationFile(ctx context.Context, orgID int64, filename string, v any) error {
+	content, exists, err := moa.kvStore.Get(ctx, orgID, KVNamespace, filename)
+	if err != nil {
+		return fmt.Errorf("error reading file '%s' from database: %w", filename, err)
+	}
+	if !exists {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(content), v); err != nil {
+		return fmt.Errorf("error decoding file '%s': %w", filename, err)
+	}
+	return nil
+}
+
+func (moa *MultiOrgAlertmanager) saveEscalationFile(ctx context.Context, orgID int64, filename string, v any) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error encoding file '%s': %w", filename, err)
+	}
+	if err := moa.kvStore.Set(ctx, orgID, KVNamespace, filename, string(content)); err != nil {
+		return fmt.Errorf("error writing file '%s' to database: %w", filename, err)
+	}
+	return nil
+}
+
+func alertGroupKey(g *apimodels.AlertGroup) (string, bool) {
+	if g == nil || g.Receiver == nil || g.Receiver.Name == nil {
+		return "", false
+	}
+	return AlertGroupKey(*g.Receiver.Name, alertGroupLabels(g)), true
+}
+
+func alertGroupLabels(g *apimodels.AlertGroup) model.LabelSet {
+	labels := make(model.LabelSet, len(g.Labels))
+	for k, v := range g.Labels {
+		labels[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return labels
+}
+
+// alertGroupAlertLabels returns the labels of the first alert of the group, which are routed like those of every
+// other alert of the group.
+func alertGroupAlertLabels(g *apimodels.AlertGroup) model.LabelSet {
+	labels := model.LabelSet{}
+	for _, a := range g.Alerts {
+		if a == nil {
+			continue
+		}
+		for k, v := range a.Labels {
+			labels[model.LabelName(k)] = model.LabelValue(v)
+		}
+		break
+	}
+	return labels
+}
+
+// isEscalable returns false for the groups of escalation alerts, and for the groups of the autogenerated routes of
+// simplified routing, which have no escalation policies.
+// activeAlertGroup returns the group with only its alerts that are neither silenced nor inhibited.
+func activeAlertGroup(g *apimodels.AlertGroup) *apimodels.AlertGroup {
+	active := *g
+	active.Alerts = make([]*amv2.GettableAlert, 0, len(g.Alerts))
+	for _, a := range g.Alerts {
+		if a != nil && (a.Status == nil || a.Status.State == nil || *a.Status.State == amv2.AlertStatusStateActive) {
+			active.Alerts = append(active.Alerts, a)
+		}
+	}
+	return &active
+}
+
+func isEscalable(g *apimodels.AlertGroup) bool {
+	ls := alertGroupAlertLabels(g)
+	if _, ok := ls[models.EscalationRouteLabel]; ok {
+		return false
+	}
+	_, ok := ls[models.AutogeneratedRouteLabel]
+	return !ok
+}
+
+// alertGroupActiveSince returns when the oldest alert of the group started firing.
+func alertGroupActiveSince(g *apimodels.AlertGroup, now time.Time) time.Time {
+	since := now
+	for _, a := range g.Alerts {
+		if a == nil || a.StartsAt == nil {
+			continue
+		}
+		if startsAt := time.Time(*a.StartsAt); startsAt.Before(since) {
+			since = startsAt
+		}
+	}
+	return since.UTC()
+}
@@ -0,0 +1,326 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+const escalationTestConfig = `{
+	"alertmanager_config": {
+		"route": {
+			"receiver": "team",
+			"group_by": ["alertname"],
+			"repeat_interval": "4h",
+			"routes": [
+				{"receiver": "lead", "object_matchers": [["__grafana_escalate_after__", "=", "15m"]], "repeat_interval": "1h"},
+				{"receiver": "manager", "object_matchers": [["__grafana_escalate_after__", "=", "1h"]]}
+			]
+		},
+		"receivers": [
+			{
+				"name": "team",
+				"grafana_managed_receiver_configs": [{"uid": "team-uid", "name": "team", "type": "email", "settings": {"addresses": "team@example.com"}}]
+			},
+			{
+				"name": "lead",
+				"grafana_managed_receiver_configs": [{"uid": "lead-uid", "name": "lead", "type": "email", "settings": {"addresses": "lead@example.com"}}]
+			},
+			{
+				"name": "manager",
+				"grafana_managed_receiver_configs": [{"uid": "manager-uid", "name": "manager", "type": "email", "settings": {"addresses": "manager@example.com"}}]
+			}
+		]
+	}
+}`
+
+func TestMultiOrgAlertmanager_AlertGroupAcknowledgements(t *testing.T) {
+	ctx := context.Background()
+	mam, am := setupEscalationMam(t)
+	groupLabels := model.LabelSet{"alertname": "HighLatency"}
+	am.groups = apimodels.AlertGroups{escalationTestGroup("team", time.Now(), groupLabels)}
+
+	t.Run("should fail to acknowledge unknown alert group", func(t *testing.T) {
+		_, err := mam.AcknowledgeAlertGroup(ctx, 1, apimodels.PostableAlertGroupAcknowledgement{
+			Receiver: "lead",
+			Labels:   groupLabels,
+		}, "admin")
+		require.ErrorIs(t, err, ErrAlertGroupNotFound)
+	})
+
+	t.Run("should fail to acknowledge without receiver", func(t *testing.T) {
+		_, err := mam.AcknowledgeAlertGroup(ctx, 1, apimodels.PostableAlertGroupAcknowledgement{Labels: groupLabels}, "admin")
+		require.ErrorIs(t, err, ErrAlertGroupAcknowledgementBadRequest)
+	})
+
+	t.Run("should acknowledge and unacknowledge active alert group", func(t *testing.T) {
+		ack, err := mam.AcknowledgeAlertGroup(ctx, 1, apimodels.PostableAlertGroupAcknowledgement{
+			Receiver: "team",
+			Labels:   groupLabels,
+			Comment:  "looking into it",
+		}, "admin")
+		require.NoError(t, err)
+		require.Equal(t, AlertGroupKey("team", groupLabels), ack.GroupKey)
+		require.Equal(t, "admin", ack.AcknowledgedBy)
+
+		acks, err := mam.ListAlertGroupAcknowledgements(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, apimodels.GettableAlertGroupAcknowledgements{ack}, acks)
+
+		// Acknowledgements are per organization.
+		acks, err = mam.ListAlertGroupAcknowledgements(ctx, 2)
+		require.NoError(t, err)
+		require.Empty(t, acks)
+
+		require.NoError(t, mam.UnacknowledgeAlertGroup(ctx, 1, ack.GroupKey))
+		require.ErrorIs(t, mam.UnacknowledgeAlertGroup(ctx, 1, ack.GroupKey), ErrAlertGroupAcknowledgementNotFound)
+	})
+
+	t.Run("should forget acknowledgement once the alert group resolves", func(t *testing.T) {
+		_, err := mam.AcknowledgeAlertGroup(ctx, 1, apimodels.PostableAlertGroupAcknowledgement{Receiver: "team", Labels: groupLabels}, "admin")
+		require.NoError(t, err)
+
+		am.groups = nil
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, time.Now()))
+
+		acks, err := mam.ListAlertGroupAcknowledgements(ctx, 1)
+		require.NoError(t, err)
+		require.Empty(t, acks)
+	})
+}
+
+func TestAddEscalationRoutes(t *testing.T) {
+	t.Run("should add a route for every escalation step", func(t *testing.T) {
+		cfg, err := Load([]byte(escalationTestConfig))
+		require.NoError(t, err)
+		route := cfg.AlertmanagerConfig.Route
+
+		require.NoError(t, AddEscalationRoutes(route))
+		require.Len(t, route.Routes, 4)
+		for i, receiver := range []string{"lead", "manager"} {
+			r := route.Routes[i]
+			require.Equal(t, receiver, r.Receiver)
+			require.Len(t, r.ObjectMatchers, 1)
+			require.Equal(t, models.EscalationRouteLabel, r.ObjectMatchers[0].Name)
+			require.Equal(t, []string{"alertname"}, r.GroupByStr)
+		}
+		// The repeat interval of the escalation policy overrides the one of its parent.
+		require.Equal(t, model.Duration(time.Hour), *route.Routes[0].RepeatInterval)
+		require.Equal(t, model.Duration(4*time.Hour), *route.Routes[1].RepeatInterval)
+
+		// Adding the routes again replaces them.
+		require.NoError(t, AddEscalationRoutes(route))
+		require.Len(t, route.Routes, 4)
+
+		RemoveEscalationRoutesIfExist(route)
+		require.Len(t, route.Routes, 2)
+	})
+
+	t.Run("should reject invalid escalation policies", func(t *testing.T) {
+		testCases := map[string]string{
+			"not a duration":    `{"receiver":"team","routes":[{"receiver":"lead","object_matchers":[["__grafana_escalate_after__","=","soon"]]}]}`,
+			"negative duration": `{"receiver":"team","routes":[{"receiver":"lead","object_matchers":[["__grafana_escalate_after__","=","0s"]]}]}`,
+			"regex matcher":     `{"receiver":"team","routes":[{"receiver":"lead","object_matchers":[["__grafana_escalate_after__","=~","1h"]]}]}`,
+			"other matchers":    `{"receiver":"team","routes":[{"receiver":"lead","object_matchers":[["__grafana_escalate_after__","=","1h"],["team","=","a"]]}]}`,
+			"no contact point":  `{"receiver":"team","routes":[{"object_matchers":[["__grafana_escalate_after__","=","1h"]]}]}`,
+			"nested policies":   `{"receiver":"team","routes":[{"receiver":"lead","object_matchers":[["__grafana_escalate_after__","=","1h"]],"routes":[{"receiver":"manager"}]}]}`,
+		}
+		for name, routeJSON := range testCases {
+			t.Run(name, func(t *testing.T) {
+				route := &apimodels.Route{}
+				require.NoError(t, json.Unmarshal([]byte(routeJSON), route))
+				require.ErrorIs(t, AddEscalationRoutes(route), ErrEscalationPolicyInvalid)
+			})
+		}
+	})
+}
+
+func TestMultiOrgAlertmanager_EscalateAlertGroups(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	groupLabels := model.LabelSet{"alertname": "HighLatency"}
+
+	t.Run("should send escalation alerts of the steps that are due", func(t *testing.T) {
+		mam, am := setupEscalationMam(t)
+		am.groups = apimodels.AlertGroups{escalationTestGroup("team", now.Add(-10*time.Minute), groupLabels)}
+
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now))
+		require.Empty(t, am.sent)
+
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now.Add(5*time.Minute)))
+		require.Equal(t, []string{"lead"}, am.sentReceivers(t))
+		sent := am.sent[0]
+		require.Equal(t, "HighLatency", sent.Labels["alertname"])
+		require.Equal(t, strfmt.DateTime(now.Add(5*time.Minute+escalationAlertTTL)), sent.EndsAt)
+
+		// Escalation alerts are sent again while the group is unacknowledged, the notification log of the
+		// Alertmanager prevents notifying again before the repeat interval.
+		am.sent = nil
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now.Add(10*time.Minute)))
+		require.Equal(t, []string{"lead"}, am.sentReceivers(t))
+
+		am.sent = nil
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now.Add(time.Hour)))
+		require.Equal(t, []string{"lead", "manager"}, am.sentReceivers(t))
+	})
+
+	t.Run("should not escalate acknowledged alert groups", func(t *testing.T) {
+		mam, am := setupEscalationMam(t)
+		am.groups = apimodels.AlertGroups{escalationTestGroup("team", now.Add(-time.Hour), groupLabels)}
+		ack, err := mam.AcknowledgeAlertGroup(ctx, 1, apimodels.PostableAlertGroupAcknowledgement{Receiver: "team", Labels: groupLabels}, "admin")
+		require.NoError(t, err)
+
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now))
+		require.Empty(t, am.sent)
+
+		// Escalation resumes once the acknowledgement is removed.
+		require.NoError(t, mam.UnacknowledgeAlertGroup(ctx, 1, ack.GroupKey))
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now))
+		require.Equal(t, []string{"lead", "manager"}, am.sentReceivers(t))
+	})
+
+	t.Run("should keep acknowledgement of silenced alert group", func(t *testing.T) {
+		mam, am := setupEscalationMam(t)
+		active := escalationTestGroup("team", now.Add(-time.Hour), groupLabels)
+		am.groups = apimodels.AlertGroups{active}
+		_, err := mam.AcknowledgeAlertGroup(ctx, 1, apimodels.PostableAlertGroupAcknowledgement{Receiver: "team", Labels: groupLabels}, "admin")
+		require.NoError(t, err)
+
+		silenced := escalationTestGroup("team", now.Add(-time.Hour), groupLabels)
+		state := amv2.AlertStatusStateSuppressed
+		silenced.Alerts[0].Status = &amv2.AlertStatus{State: &state, SilencedBy: []string{"silence-id"}}
+		am.groups = apimodels.AlertGroups{silenced}
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now))
+		require.Empty(t, am.sent)
+
+		acks, err := mam.ListAlertGroupAcknowledgements(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, acks, 1)
+
+		// The group is still acknowledged once the silence expires.
+		am.groups = apimodels.AlertGroups{active}
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now.Add(time.Minute)))
+		require.Empty(t, am.sent)
+	})
+
+	t.Run("should not escalate silenced alert groups", func(t *testing.T) {
+		mam, am := setupEscalationMam(t)
+		silenced := escalationTestGroup("team", now.Add(-time.Hour), groupLabels)
+		state := amv2.AlertStatusStateSuppressed
+		silenced.Alerts[0].Status = &amv2.AlertStatus{State: &state, SilencedBy: []string{"silence-id"}}
+		am.groups = apimodels.AlertGroups{silenced}
+
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now))
+		require.Empty(t, am.sent)
+	})
+
+	t.Run("should not escalate alert groups of other policies", func(t *testing.T) {
+		mam, am := setupEscalationMam(t)
+		am.groups = apimodels.AlertGroups{escalationTestGroup("lead", now.Add(-time.Hour), groupLabels)}
+
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now))
+		require.Empty(t, am.sent)
+	})
+
+	t.Run("should not escalate the groups of escalation alerts", func(t *testing.T) {
+		mam, am := setupEscalationMam(t)
+		escalated := groupLabels.Clone()
+		escalated[models.EscalationRouteLabel] = "1"
+		am.groups = apimodels.AlertGroups{escalationTestGroup("team", now.Add(-time.Hour), escalated)}
+
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now))
+		require.Empty(t, am.sent)
+	})
+
+	t.Run("should restart escalation once the alert group fires again", func(t *testing.T) {
+		mam, am := setupEscalationMam(t)
+		am.groups = apimodels.AlertGroups{escalationTestGroup("team", now.Add(-20*time.Minute), groupLabels)}
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now))
+		require.Equal(t, []string{"lead"}, am.sentReceivers(t))
+
+		// The group resolves, and fires again later.
+		am.groups = nil
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now))
+		am.sent = nil
+		am.groups = apimodels.AlertGroups{escalationTestGroup("team", now.Add(time.Minute), groupLabels)}
+		require.NoError(t, mam.escalateAlertGroupsForOrg(ctx, 1, am, now.Add(2*time.Minute)))
+		require.Empty(t, am.sent)
+	})
+}
+
+func setupEscalationMam(t *testing.T) (*MultiOrgAlertmanager, *fakeEscalationAlertmanager) {
+	t.Helper()
+	mam := setupMam(t, nil)
+	mam.configStore.(*fakeConfigStore).configs[1] = &models.AlertConfiguration{
+		OrgID:                     1,
+		AlertmanagerConfiguration: escalationTestConfig,
+	}
+	am := &fakeEscalationAlertmanager{}
+	mam.alertmanagers[1] = am
+	return mam, am
+}
+
+func escalationTestGroup(receiver string, startsAt time.Time, labels model.LabelSet) *apimodels.AlertGroup {
+	groupLabels := make(amv2.LabelSet, len(labels))
+	for k, v := range labels {
+		groupLabels[string(k)] = string(v)
+	}
+	startsAtDT := strfmt.DateTime(startsAt)
+	return &apimodels.AlertGroup{
+		Labels:   groupLabels,
+		Receiver: &amv2.Receiver{Name: &receiver},
+		Alerts:   []*amv2.GettableAlert{{StartsAt: &startsAtDT, Alert: amv2.Alert{Labels: groupLabels}}},
+	}
+}
+
+// fakeEscalationAlertmanager is an Alertmanager that returns fixed alert groups and records the alerts sent to it.
+type fakeEscalationAlertmanager struct {
+	Alertmanager
+	groups apimodels.AlertGroups
+	sent   []amv2.PostableAlert
+}
+
+func (f *fakeEscalationAlertmanager) Ready() bool {
+	return true
+}
+
+func (f *fakeEscalationAlertmanager) GetAlertGroups(_ context.Context, _, _, _ bool, _ []string, _ string) (apimodels.AlertGroups, error) {
+	return f.groups, nil
+}
+
+func (f *fakeEscalationAlertmanager) PutAlerts(_ context.Context, alerts apimodels.PostableAlerts) error {
+	f.sent = append(f.sent, alerts.PostableAlerts...)
+	return nil
+}
+
+// sentReceivers returns the receivers of the escalation routes that the sent alerts are routed to.
+func (f *fakeEscalationAlertmanager) sentReceivers(t *testing.T) []string {
+	t.Helper()
+	cfg, err := Load([]byte(escalationTestConfig))
+	require.NoError(t, err)
+	route := cfg.AlertmanagerConfig.Route
+	require.NoError(t, AddEscalationRoutes(route))
+
+	receivers := make([]string, 0, len(f.sent))
+	for _, a := range f.sent {
+		id := a.Labels[models.EscalationRouteLabel]
+		idx := slices.IndexFunc(route.Routes, func(r *apimodels.Route) bool {
+			return len(r.ObjectMatchers) == 1 && r.ObjectMatchers[0].Name == models.EscalationRouteLabel && r.ObjectMatchers[0].Value == id
+		})
+		require.GreaterOrEqual(t, idx, 0, "no escalation route for %s", id)
+		receivers = append(receivers, route.Routes[idx].Receiver)
+	}
+	sort.Strings(receivers)
+	return receivers
+}
@@ -84,6 +84,9 @@ type MultiOrgAlertmanager struct {
 	alertmanagersMtx sync.RWMutex
 	alertmanagers    map[int64]Alertmanager
 
+	// escalationMtx serializes changes to the acknowledgements and escalations in the kvstore.
+	escalationMtx sync.Mutex
+
 	settings       *setting.Cfg
 	featureManager featuremgmt.FeatureToggles
 	logger         log.Logger
@@ -238,6 +241,8 @@ func (moa *MultiOrgAlertmanager) setupClustering(cfg *setting.Cfg) error {
 func (moa *MultiOrgAlertmanager) Run(ctx context.Context) error {
 	moa.logger.Info("Starting MultiOrg Alertmanager")
 
+	go moa.runEscalations(ctx)
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -358,13 +363,13 @@ func (moa *MultiOrgAlertmanager) SyncAlertmanagersForOrgs(ctx context.Context, o
 	moa.cleanupOrphanLocalOrgState(ctx, orgsFound)
 }
 
-// cleanupOrphanLocalOrgState will remove all orphaned nflog and silence states in kvstore by existing to currently
+// cleanupOrphanLocalOrgState will remove all orphaned nflog, silence and escalation states in kvstore by existing to currently
 // active organizations. The original intention for this was the cleanup deleted orgs, that have had their states
 // saved to the kvstore after deletion on instance shutdown.
 func (moa *MultiOrgAlertmanager) cleanupOrphanLocalOrgState(ctx context.Context,
 	activeOrganizations map[int64]struct{},
 ) {
-	storedFiles := []string{NotificationLogFilename, SilencesFilename}
+	storedFiles := []string{NotificationLogFilename, SilencesFilename, AcknowledgementsFilename, EscalationsFilename}
 	for _, fileName := range storedFiles {
 		keys, err := moa.kvStore.Keys(ctx, kvstore.AllOrganizations, KVNamespace, fileName)
 		if err != nil {
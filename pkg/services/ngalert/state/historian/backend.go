@@ -3,6 +3,8 @@ package historian
 import (
 	"fmt"
 	"strings"
+
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 // BackendType identifies different kinds of state history backends.
@@ -18,6 +20,7 @@ const (
 	BackendTypeLoki        BackendType = "loki"
 	BackendTypeMultiple    BackendType = "multiple"
 	BackendTypeNoop        BackendType = "noop"
+	BackendTypeSQL         BackendType = "sql"
 )
 
 func ParseBackendType(s string) (BackendType, error) {
@@ -28,6 +31,7 @@ func ParseBackendType(s string) (BackendType, error) {
 		BackendTypeLoki:        {},
 		BackendTypeMultiple:    {},
 		BackendTypeNoop:        {},
+		BackendTypeSQL:         {},
 	}
 	p := BackendType(norm)
 	if _, ok := types[p]; !ok {
@@ -35,3 +39,30 @@ func ParseBackendType(s string) (BackendType, error) {
 	}
 	return p, nil
 }
+
+// SQLBackendEnabled returns whether state history is recorded by the SQL backend, on its own or as one of the
+// backends of the multiple backend.
+func SQLBackendEnabled(cfg setting.UnifiedAlertingStateHistorySettings) bool {
+	if !cfg.Enabled {
+		return false
+	}
+	backend, err := ParseBackendType(cfg.Backend)
+	if err != nil {
+		return false
+	}
+	if backend == BackendTypeSQL {
+		return true
+	}
+	if backend != BackendTypeMultiple {
+		return false
+	}
+	if primary, err := ParseBackendType(cfg.MultiPrimary); err == nil && primary == BackendTypeSQL {
+		return true
+	}
+	for _, secondary := range cfg.MultiSecondaries {
+		if b, err := ParseBackendType(secondary); err == nil && b == BackendTypeSQL {
+			return true
+		}
+	}
+	return false
+}
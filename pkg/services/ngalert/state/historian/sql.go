@@ -0,0 +1,304 @@
+package historian
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	history_model "github.com/grafana/grafana/pkg/services/ngalert/state/historian/model"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+type SQLStore interface {
+	SaveStateHistory(ctx context.Context, entries []models.StateHistoryEntry, labels []models.StateHistoryLabel) error
+	ListStateHistory(ctx context.Context, query *models.ListStateHistoryQuery) ([]models.StateHistoryEntry, error)
+}
+
+// DeleteExpiredService is a service to delete expired state history recorded by the SQL backend.
+type DeleteExpiredService struct {
+	store store.StateHistoryAdminStore
+}
+
+func (s *DeleteExpiredService) DeleteExpired(ctx context.Context) (int64, error) {
+	return s.store.DeleteExpiredStateHistory(ctx)
+}
+
+func ProvideDeleteExpiredService(store *store.DBstore) *DeleteExpiredService {
+	return &DeleteExpiredService{store: store}
+}
+
+// SQLBackend is an implementation of state.Historian that records state transitions of every alert instance
+// to a dedicated table in the Grafana database.
+type SQLBackend struct {
+	store     SQLStore
+	ruleStore RuleStore
+	metrics   *metrics.Historian
+	log       log.Logger
+	ac        AccessControl
+}
+
+func NewSQLBackend(logger log.Logger, store SQLStore, ruleStore RuleStore, metrics *metrics.Historian, ac AccessControl) *SQLBackend {
+	return &SQLBackend{
+		store:     store,
+		ruleStore: ruleStore,
+		metrics:   metrics,
+		log:       logger,
+		ac:        ac,
+	}
+}
+
+// Record writes a number of state transitions for a given rule to the database.
+func (h *SQLBackend) Record(ctx context.Context, rule history_model.RuleMeta, states []state.StateTransition) <-chan error {
+	logger := h.log.FromContext(ctx)
+	// Build entries before starting goroutine, to make sure all data is copied and won't mutate underneath us.
+	entries, labels := buildStateHistoryEntries(rule, states, logger)
+
+	errCh := make(chan error, 1)
+	if len(entries) == 0 {
+		close(errCh)
+		return errCh
+	}
+
+	// This is a new background job, so let's create a brand new context for it.
+	// We want it to be isolated, i.e. we don't want grafana shutdowns to interrupt this work
+	// immediately but rather try to flush writes.
+	// This also prevents timeouts or other lingering objects (like transactions) from being
+	// incorrectly propagated here from other areas.
+	writeCtx := context.Background()
+	writeCtx, cancel := context.WithTimeout(writeCtx, StateHistoryWriteTimeout)
+	writeCtx = history_model.WithRuleData(writeCtx, rule)
+	writeCtx = trace.ContextWithSpan(writeCtx, trace.SpanFromContext(ctx))
+
+	go func(ctx context.Context) {
+		defer cancel()
+		defer close(errCh)
+		logger := h.log.FromContext(ctx)
+		logger.Debug("Saving state history batch", "samples", len(entries))
+		org := fmt.Sprint(rule.OrgID)
+		h.metrics.WritesTotal.WithLabelValues(org, "sql").Inc()
+		h.metrics.TransitionsTotal.WithLabelValues(org).Add(float64(len(entries)))
+
+		if err := h.store.SaveStateHistory(ctx, entries, labels); err != nil {
+			logger.Error("Failed to save alert state history batch", "samples", len(entries), "error", err)
+			h.metrics.WritesFailed.WithLabelValues(org, "sql").Inc()
+			h.metrics.TransitionsFailed.WithLabelValues(org).Add(float64(len(entries)))
+			errCh <- fmt.Errorf("failed to save alert state history batch: %w", err)
+			return
+		}
+		logger.Debug("Done saving state history batch", "samples", len(entries))
+	}(writeCtx)
+	return errCh
+}
+
+// Query retrieves state history entries from the database and formats the results into a dataframe.
+// The dataframe has the same shape as the one returned by the Loki backend.
+func (h *SQLBackend) Query(ctx context.Context, query models.HistoryQuery) (*data.Frame, error) {
+	uids, err := getFolderUIDsForFilter(ctx, h.ac, h.ruleStore, query)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if query.To.IsZero() {
+		query.To = now
+	}
+	if query.From.IsZero() {
+		query.From = now.Add(-defaultQueryRange)
+	}
+
+	pairHashes := make([]string, 0, len(query.Labels))
+	for k, v := range query.Labels {
+		pairHashes = append(pairHashes, labelPairFingerprint(k, v))
+	}
+	// Ensure that all queries we build are deterministic.
+	sort.Strings(pairHashes)
+
+	entries, err := h.store.ListStateHistory(ctx, &models.ListStateHistoryQuery{
+		OrgID:         query.OrgID,
+		RuleUID:       query.RuleUID,
+		DashboardUID:  query.DashboardUID,
+		PanelID:       query.PanelID,
+		NamespaceUIDs: uids,
+		PairHashes:    pairHashes,
+		From:          query.From,
+		To:            query.To,
+		Limit:         query.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query state history: %w", err)
+	}
+
+	return h.entriesToFrame(ctx, entries, query.Labels)
+}
+
+// entriesToFrame converts state history entries, ordered by time descending, into a dataframe ordered by time ascending.
+func (h *SQLBackend) entriesToFrame(ctx context.Context, entries []models.StateHistoryEntry, matchers map[string]string) (*data.Frame, error) {
+	logger := h.log.FromContext(ctx)
+	frame := data.NewFrame("states")
+
+	// We merge all series into a single linear history.
+	lbls := data.Labels(map[string]string{})
+
+	// We represent state history as a single merged history, in the same format as the Loki backend:
+	//   1. `time` - timestamp - when the transition happened
+	//   2. `line` - JSON - the full data of the transition
+	//   3. `labels` - JSON - the labels associated with that state transition
+	times := make([]time.Time, 0, len(entries))
+	lines := make([]json.RawMessage, 0, len(entries))
+	labels := make([]json.RawMessage, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		var instanceLabels map[string]string
+		if err := json.Unmarshal([]byte(e.Labels), &instanceLabels); err != nil {
+			logger.Error("State history entry has unparseable labels, skipping", "id", e.ID, "error", err)
+			continue
+		}
+		// Label pairs are matched by their hashes in the database, so verify the actual values to rule out collisions.
+		if !labelsMatch(instanceLabels, matchers) {
+			continue
+		}
+
+		values := simplejson.New()
+		if e.Values != "" {
+			v, err := simplejson.NewJson([]byte(e.Values))
+			if err != nil {
+				logger.Error("State history entry has unparseable values, skipping", "id", e.ID, "error", err)
+				continue
+			}
+			values = v
+		}
+
+		entry := LokiEntry{
+			SchemaVersion:  1,
+			Previous:       formatStoredState(e.PreviousState, e.PreviousReason),
+			Current:        formatStoredState(e.CurrentState, e.CurrentReason),
+			Error:          e.Error,
+			Values:         values,
+			Condition:      e.RuleCondition,
+			DashboardUID:   e.DashboardUID,
+			PanelID:        e.PanelID,
+			Fingerprint:    e.LabelsHash,
+			RuleTitle:      e.RuleTitle,
+			RuleID:         e.RuleID,
+			RuleUID:        e.RuleUID,
+			InstanceLabels: instanceLabels,
+		}
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize state history entry: %w", err)
+		}
+		streamLbls, err := json.Marshal(map[string]string{
+			StateHistoryLabelKey: StateHistoryLabelValue,
+			OrgIDLabel:           fmt.Sprint(e.OrgID),
+			GroupLabel:           e.RuleGroup,
+			FolderUIDLabel:       e.NamespaceUID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize state history labels: %w", err)
+		}
+
+		times = append(times, time.UnixMilli(e.EvaluatedAt))
+		lines = append(lines, line)
+		labels = append(labels, streamLbls)
+	}
+
+	frame.Fields = append(frame.Fields, data.NewField(dfTime, lbls, times))
+	frame.Fields = append(frame.Fields, data.NewField(dfLine, lbls, lines))
+	frame.Fields = append(frame.Fields, data.NewField(dfLabels, lbls, labels))
+
+	return frame, nil
+}
+
+func buildStateHistoryEntries(rule history_model.RuleMeta, states []state.StateTransition, logger log.Logger) ([]models.StateHistoryEntry, []models.StateHistoryLabel) {
+	entries := make([]models.StateHistoryEntry, 0, len(states))
+	labels := make([]models.StateHistoryLabel, 0)
+	indexed := make(map[string]struct{})
+	for _, state := range states {
+		if !shouldRecord(state) {
+			continue
+		}
+
+		sanitizedLabels := removePrivateLabels(state.Labels)
+		labelsJSON, err := json.Marshal(sanitizedLabels)
+		if err != nil {
+			logger.Error("Failed to serialize labels of state, skipping", "error", err)
+			continue
+		}
+		values, err := json.Marshal(valuesAsDataBlob(state.State))
+		if err != nil {
+			logger.Error("Failed to serialize values of state, skipping", "error", err)
+			continue
+		}
+
+		fingerprint := labelFingerprint(sanitizedLabels)
+		entry := models.StateHistoryEntry{
+			OrgID:          rule.OrgID,
+			RuleUID:        rule.UID,
+			RuleID:         rule.ID,
+			RuleTitle:      rule.Title,
+			RuleGroup:      rule.Group,
+			RuleCondition:  rule.Condition,
+			NamespaceUID:   rule.NamespaceUID,
+			DashboardUID:   rule.DashboardUID,
+			PanelID:        rule.PanelID,
+			Labels:         string(labelsJSON),
+			LabelsHash:     fingerprint,
+			PreviousState:  state.PreviousState.String(),
+			PreviousReason: state.PreviousStateReason,
+			CurrentState:   state.State.State.String(),
+			CurrentReason:  state.StateReason,
+			Values:         string(values),
+			EvaluatedAt:    state.LastEvaluationTime.UnixMilli(),
+		}
+		if state.State.State == eval.Error && state.Error != nil {
+			entry.Error = state.Error.Error()
+		}
+		entries = append(entries, entry)
+
+		if _, ok := indexed[fingerprint]; ok {
+			continue
+		}
+		indexed[fingerprint] = struct{}{}
+		for k, v := range sanitizedLabels {
+			labels = append(labels, models.StateHistoryLabel{
+				OrgID:      rule.OrgID,
+				LabelsHash: fingerprint,
+				PairHash:   labelPairFingerprint(k, v),
+			})
+		}
+	}
+	return entries, labels
+}
+
+// labelPairFingerprint calculates a stable signature of a single label pair.
+func labelPairFingerprint(name, value string) string {
+	return labelFingerprint(data.Labels{name: value})
+}
+
+func labelsMatch(labels map[string]string, matchers map[string]string) bool {
+	for k, v := range matchers {
+		if lv, ok := labels[k]; !ok || lv != v {
+			return false
+		}
+	}
+	return true
+}
+
+func formatStoredState(s, reason string) string {
+	st, err := eval.ParseStateString(s)
+	if err != nil {
+		return s
+	}
+	return state.FormatStateAndReason(st, reason)
+}
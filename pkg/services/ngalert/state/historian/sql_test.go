@@ -0,0 +1,232 @@
+package historian
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/folder"
+	acfakes "github.com/grafana/grafana/pkg/services/ngalert/accesscontrol/fakes"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests/fakes"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestSQLBackend(t *testing.T) {
+	t.Run("writes state transitions and label index", func(t *testing.T) {
+		store := &fakeSQLStore{}
+		sql := createTestSQLBackend(t, store, metrics.NewHistorianMetrics(prometheus.NewRegistry(), metrics.Subsystem), &acfakes.FakeRuleService{})
+		rule := createTestRule()
+		now := time.Now()
+		states := singleFromNormal(&state.State{
+			State:              eval.Alerting,
+			Labels:             data.Labels{"a": "b", "__private__": "x"},
+			Values:             map[string]float64{"A": 1},
+			LastEvaluationTime: now,
+		})
+
+		err := <-sql.Record(context.Background(), rule, states)
+
+		require.NoError(t, err)
+		require.Len(t, store.entries, 1)
+		entry := store.entries[0]
+		require.Equal(t, rule.UID, entry.RuleUID)
+		require.Equal(t, rule.NamespaceUID, entry.NamespaceUID)
+		require.Equal(t, "Normal", entry.PreviousState)
+		require.Equal(t, "Alerting", entry.CurrentState)
+		require.Equal(t, now.UnixMilli(), entry.EvaluatedAt)
+		require.JSONEq(t, `{"a":"b"}`, entry.Labels)
+		require.JSONEq(t, `{"A":1}`, entry.Values)
+		require.Equal(t, labelFingerprint(data.Labels{"a": "b"}), entry.LabelsHash)
+		require.Equal(t, []models.StateHistoryLabel{
+			{OrgID: rule.OrgID, LabelsHash: entry.LabelsHash, PairHash: labelPairFingerprint("a", "b")},
+		}, store.labels)
+	})
+
+	t.Run("elides write if nothing to record", func(t *testing.T) {
+		store := &fakeSQLStore{}
+		sql := createTestSQLBackend(t, store, metrics.NewHistorianMetrics(prometheus.NewRegistry(), metrics.Subsystem), &acfakes.FakeRuleService{})
+
+		err := <-sql.Record(context.Background(), createTestRule(), []state.StateTransition{})
+
+		require.NoError(t, err)
+		require.Equal(t, 0, store.saves)
+	})
+
+	t.Run("emits expected write metrics", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		met := metrics.NewHistorianMetrics(reg, metrics.Subsystem)
+		sql := createTestSQLBackend(t, &fakeSQLStore{}, met, &acfakes.FakeRuleService{})
+		errSQL := createTestSQLBackend(t, &fakeSQLStore{err: errors.New("boom")}, met, &acfakes.FakeRuleService{})
+		rule := createTestRule()
+		states := singleFromNormal(&state.State{
+			State:  eval.Alerting,
+			Labels: data.Labels{"a": "b"},
+		})
+
+		<-sql.Record(context.Background(), rule, states)
+		<-errSQL.Record(context.Background(), rule, states)
+
+		exp := bytes.NewBufferString(`
+# HELP grafana_alerting_state_history_writes_failed_total The total number of failed writes of state history batches.
+# TYPE grafana_alerting_state_history_writes_failed_total counter
+grafana_alerting_state_history_writes_failed_total{backend="sql",org="1"} 1
+# HELP grafana_alerting_state_history_writes_total The total number of state history batches that were attempted to be written.
+# TYPE grafana_alerting_state_history_writes_total counter
+grafana_alerting_state_history_writes_total{backend="sql",org="1"} 2
+`)
+		err := testutil.GatherAndCompare(reg, exp,
+			"grafana_alerting_state_history_writes_total",
+			"grafana_alerting_state_history_writes_failed_total",
+		)
+		require.NoError(t, err)
+	})
+
+	t.Run("query returns entries in loki format ordered by time", func(t *testing.T) {
+		store := &fakeSQLStore{}
+		ac := &acfakes.FakeRuleService{
+			CanReadAllRulesFunc: func(ctx context.Context, user identity.Requester) (bool, error) {
+				return true, nil
+			},
+		}
+		sql := createTestSQLBackend(t, store, metrics.NewHistorianMetrics(prometheus.NewRegistry(), metrics.Subsystem), ac)
+		rule := createTestRule()
+		now := time.Now()
+		for i, lbls := range []data.Labels{{"a": "b"}, {"a": "c"}, {"a": "b", "x": "y"}} {
+			err := <-sql.Record(context.Background(), rule, singleFromNormal(&state.State{
+				State:              eval.Alerting,
+				Labels:             lbls,
+				LastEvaluationTime: now.Add(time.Duration(i) * time.Minute),
+			}))
+			require.NoError(t, err)
+		}
+
+		frame, err := sql.Query(context.Background(), models.HistoryQuery{
+			OrgID:  1,
+			Labels: map[string]string{"a": "b"},
+			From:   now.Add(-time.Hour),
+			To:     now.Add(time.Hour),
+		})
+
+		require.NoError(t, err)
+		require.Len(t, frame.Fields, 3)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, now.UnixMilli(), frame.Fields[0].At(0).(time.Time).UnixMilli())
+		require.Equal(t, now.Add(2*time.Minute).UnixMilli(), frame.Fields[0].At(1).(time.Time).UnixMilli())
+
+		var entry LokiEntry
+		require.NoError(t, json.Unmarshal(frame.Fields[1].At(1).(json.RawMessage), &entry))
+		require.Equal(t, "Normal", entry.Previous)
+		require.Equal(t, "Alerting", entry.Current)
+		require.Equal(t, rule.UID, entry.RuleUID)
+		require.Equal(t, map[string]string{"a": "b", "x": "y"}, entry.InstanceLabels)
+
+		var streamLabels map[string]string
+		require.NoError(t, json.Unmarshal(frame.Fields[2].At(1).(json.RawMessage), &streamLabels))
+		require.Equal(t, rule.NamespaceUID, streamLabels[FolderUIDLabel])
+		require.Equal(t, rule.Group, streamLabels[GroupLabel])
+	})
+
+	t.Run("query filters by folders the user can access", func(t *testing.T) {
+		store := &fakeSQLStore{}
+		ac := &acfakes.FakeRuleService{
+			HasAccessInFolderFunc: func(ctx context.Context, user identity.Requester, namespaced models.Namespaced) (bool, error) {
+				return namespaced.GetNamespaceUID() == "folder-1", nil
+			},
+		}
+		rules := fakes.NewRuleStore(t)
+		rules.Folders = map[int64][]*folder.Folder{
+			1: {{UID: "folder-1", OrgID: 1}, {UID: "folder-2", OrgID: 1}},
+		}
+		rules.Rules = map[int64][]*models.AlertRule{
+			1: {models.RuleGen.With(models.RuleMuts.WithNamespaceUID("folder-1")).GenerateRef()},
+		}
+		sql := NewSQLBackend(log.NewNopLogger(), store, rules, metrics.NewHistorianMetrics(prometheus.NewRegistry(), metrics.Subsystem), ac)
+
+		_, err := sql.Query(context.Background(), models.HistoryQuery{OrgID: 1})
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"folder-1"}, store.lastQuery.NamespaceUIDs)
+	})
+}
+
+func createTestSQLBackend(t *testing.T, store SQLStore, met *metrics.Historian, ac AccessControl) *SQLBackend {
+	t.Helper()
+	rules := fakes.NewRuleStore(t)
+	sqlBackendLogger := log.New("ngalert.state.historian", "backend", "sql")
+	return NewSQLBackend(sqlBackendLogger, store, rules, met, ac)
+}
+
+// fakeSQLStore is an in-memory SQLStore that mimics the filtering done by the database.
+type fakeSQLStore struct {
+	entries   []models.StateHistoryEntry
+	labels    []models.StateHistoryLabel
+	saves     int
+	lastQuery *models.ListStateHistoryQuery
+	err       error
+}
+
+func (f *fakeSQLStore) SaveStateHistory(_ context.Context, entries []models.StateHistoryEntry, labels []models.StateHistoryLabel) error {
+	f.saves++
+	if f.err != nil {
+		return f.err
+	}
+	f.entries = append(f.entries, entries...)
+	f.labels = append(f.labels, labels...)
+	return nil
+}
+
+func (f *fakeSQLStore) ListStateHistory(_ context.Context, query *models.ListStateHistoryQuery) ([]models.StateHistoryEntry, error) {
+	f.lastQuery = query
+	if f.err != nil {
+		return nil, f.err
+	}
+	pairs := make(map[string]map[string]struct{})
+	for _, l := range f.labels {
+		if pairs[l.LabelsHash] == nil {
+			pairs[l.LabelsHash] = make(map[string]struct{})
+		}
+		pairs[l.LabelsHash][l.PairHash] = struct{}{}
+	}
+	var result []models.StateHistoryEntry
+	for _, e := range f.entries {
+		if e.OrgID != query.OrgID || e.EvaluatedAt < query.From.UnixMilli() || e.EvaluatedAt > query.To.UnixMilli() {
+			continue
+		}
+		matches := true
+		for _, h := range query.PairHashes {
+			if _, ok := pairs[e.LabelsHash][h]; !ok {
+				matches = false
+			}
+		}
+		if matches {
+			result = append(result, e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].EvaluatedAt > result[j].EvaluatedAt
+	})
+	return result, nil
+}
+
+func TestSQLBackendEnabled(t *testing.T) {
+	require.True(t, SQLBackendEnabled(setting.UnifiedAlertingStateHistorySettings{Enabled: true, Backend: "sql"}))
+	require.True(t, SQLBackendEnabled(setting.UnifiedAlertingStateHistorySettings{Enabled: true, Backend: "multiple", MultiPrimary: "annotations", MultiSecondaries: []string{"sql"}}))
+	require.False(t, SQLBackendEnabled(setting.UnifiedAlertingStateHistorySettings{Enabled: false, Backend: "sql"}))
+	require.False(t, SQLBackendEnabled(setting.UnifiedAlertingStateHistorySettings{Enabled: true, Backend: "annotations"}))
+	require.False(t, SQLBackendEnabled(setting.UnifiedAlertingStateHistorySettings{Enabled: true, Backend: "multiple", MultiPrimary: "annotations", MultiSecondaries: []string{"loki"}}))
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+type StateHistoryAdminStore interface {
+	// DeleteExpiredStateHistory deletes state history entries older than the configured maximum age.
+	DeleteExpiredStateHistory(ctx context.Context) (int64, error)
+}
+
+// SaveStateHistory stores state history entries along with the label pairs of their label sets.
+func (st DBstore) SaveStateHistory(ctx context.Context, entries []models.StateHistoryEntry, labels []models.StateHistoryLabel) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		opts := sqlstore.NativeSettingsForDialect(st.SQLStore.GetDialect())
+		if _, err := sess.BulkInsert("alert_state_history", entries, opts); err != nil {
+			return fmt.Errorf("failed to insert state history: %w", err)
+		}
+
+		return st.saveStateHistoryLabels(sess, labels)
+	})
+}
+
+// stateHistoryLabelsBatchSize is the number of label pairs upserted by a single statement.
+const stateHistoryLabelsBatchSize = 100
+
+// saveStateHistoryLabels upserts the label pairs of label sets in batches. A batch must not contain the same
+// pair twice, PostgreSQL rejects upserts that affect a row more than once.
+func (st DBstore) saveStateHistoryLabels(sess *db.Session, labels []models.StateHistoryLabel) error {
+	type key struct {
+		orgID      int64
+		labelsHash string
+		pairHash   string
+	}
+	seen := make(map[key]struct{}, len(labels))
+	unique := make([]models.StateHistoryLabel, 0, len(labels))
+	for _, l := range labels {
+		k := key{orgID: l.OrgID, labelsHash: l.LabelsHash, pairHash: l.PairHash}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		unique = append(unique, l)
+	}
+
+	cols := []string{"org_id", "labels_hash", "pair_hash"}
+	for start := 0; start < len(unique); start += stateHistoryLabelsBatchSize {
+		batch := unique[start:min(start+stateHistoryLabelsBatchSize, len(unique))]
+		upsertSQL, err := st.SQLStore.GetDialect().UpsertMultipleSQL("alert_state_history_label", cols, cols, len(batch))
+		if err != nil {
+			return fmt.Errorf("failed to index state history labels: %w", err)
+		}
+		args := make([]any, 0, len(batch)*len(cols)+1)
+		args = append(args, upsertSQL)
+		for _, l := range batch {
+			args = append(args, l.OrgID, l.LabelsHash, l.PairHash)
+		}
+		if _, err := sess.Exec(args...); err != nil {
+			return fmt.Errorf("failed to index state history labels: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListStateHistory returns the most recent state history entries that match the query, ordered by time descending.
+func (st DBstore) ListStateHistory(ctx context.Context, query *models.ListStateHistoryQuery) ([]models.StateHistoryEntry, error) {
+	var result []models.StateHistoryEntry
+	err := st.SQLStore.WithDbSession(ctx, func(sess *db.Session) error {
+		s := strings.Builder{}
+		params := make([]any, 0)
+
+		addToQuery := func(stmt string, p ...any) {
+			s.WriteString(stmt)
+			params = append(params, p...)
+		}
+
+		addToQuery("SELECT * FROM alert_state_history WHERE org_id = ?", query.OrgID)
+
+		if !query.From.IsZero() {
+			addToQuery(" AND evaluated_at >= ?", query.From.UnixMilli())
+		}
+		if !query.To.IsZero() {
+			addToQuery(" AND evaluated_at <= ?", query.To.UnixMilli())
+		}
+		if query.RuleUID != "" {
+			addToQuery(" AND rule_uid = ?", query.RuleUID)
+		}
+		if query.DashboardUID != "" {
+			addToQuery(" AND dashboard_uid = ?", query.DashboardUID)
+		}
+		if query.PanelID != 0 {
+			addToQuery(" AND panel_id = ?", query.PanelID)
+		}
+		if len(query.NamespaceUIDs) > 0 {
+			placeholders := strings.TrimSuffix(strings.Repeat("?,", len(query.NamespaceUIDs)), ",")
+			args := make([]any, 0, len(query.NamespaceUIDs))
+			for _, uid := range query.NamespaceUIDs {
+				args = append(args, uid)
+			}
+			addToQuery(" AND namespace_uid IN ("+placeholders+")", args...)
+		}
+		for _, h := range query.PairHashes {
+			addToQuery(" AND labels_hash IN (SELECT labels_hash FROM alert_state_history_label WHERE org_id = ? AND pair_hash = ?)", query.OrgID, h)
+		}
+
+		addToQuery(" ORDER BY evaluated_at DESC, id DESC")
+		if query.Limit > 0 {
+			s.WriteString(st.SQLStore.GetDialect().Limit(int64(query.Limit)))
+		}
+
+		return sess.SQL(s.String(), params...).Find(&result)
+	})
+	return result, err
+}
+
+// DeleteExpiredStateHistory deletes state history entries older than the configured maximum age,
+// as well as the label index of label sets that no longer have any entries.
+// It is a no-op if the maximum age is not positive.
+func (st DBstore) DeleteExpiredStateHistory(ctx context.Context) (int64, error) {
+	maxAge := st.Cfg.StateHistory.SQLMaxAge
+	if maxAge <= 0 {
+		return 0, nil
+	}
+	var n int64
+	if err := st.SQLStore.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		cutoff := TimeNow().Add(-maxAge).UnixMilli()
+		rows, err := sess.Where("evaluated_at < ?", cutoff).Delete(&models.StateHistoryEntry{})
+		if err != nil {
+			return fmt.Errorf("failed to delete expired state history: %w", err)
+		}
+		n = rows
+		if rows == 0 {
+			return nil
+		}
+		_, err = sess.Exec(`DELETE FROM alert_state_history_label WHERE NOT EXISTS (
+			SELECT 1 FROM alert_state_history h WHERE h.org_id = alert_state_history_label.org_id AND h.labels_hash = alert_state_history_label.labels_hash
+		)`)
+		if err != nil {
+			return fmt.Errorf("failed to delete orphaned state history labels: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return -1, err
+	}
+	return n, nil
+}
@@ -0,0 +1,111 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests"
+)
+
+func TestIntegrationStateHistory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	_, dbstore := tests.SetupTestEnv(t, baseIntervalSeconds)
+
+	now := time.Now()
+	entry := func(ruleUID, labelsHash string, at time.Time) models.StateHistoryEntry {
+		return models.StateHistoryEntry{
+			OrgID:         1,
+			RuleUID:       ruleUID,
+			NamespaceUID:  "folder",
+			Labels:        "{}",
+			LabelsHash:    labelsHash,
+			PreviousState: "Normal",
+			CurrentState:  "Alerting",
+			EvaluatedAt:   at.UnixMilli(),
+		}
+	}
+	entries := []models.StateHistoryEntry{
+		entry("rule-1", "hash-1", now.Add(-2*time.Hour)),
+		entry("rule-1", "hash-2", now.Add(-time.Hour)),
+		entry("rule-2", "hash-1", now),
+	}
+	labels := []models.StateHistoryLabel{
+		{OrgID: 1, LabelsHash: "hash-1", PairHash: "pair-a"},
+		{OrgID: 1, LabelsHash: "hash-1", PairHash: "pair-b"},
+		{OrgID: 1, LabelsHash: "hash-2", PairHash: "pair-a"},
+	}
+	require.NoError(t, dbstore.SaveStateHistory(ctx, entries, labels))
+	// Indexing the same label sets again must not fail.
+	require.NoError(t, dbstore.SaveStateHistory(ctx, entries[:1], labels[:2]))
+	// Label pairs are upserted in batches, that may repeat the same pair.
+	many := make([]models.StateHistoryLabel, 0, 250)
+	for i := 0; i < 250; i++ {
+		many = append(many, models.StateHistoryLabel{OrgID: 2, LabelsHash: "hash-3", PairHash: fmt.Sprintf("pair-%d", i%150)})
+	}
+	require.NoError(t, dbstore.SaveStateHistory(ctx, []models.StateHistoryEntry{{OrgID: 2, RuleUID: "rule-3", Labels: "{}", LabelsHash: "hash-3", EvaluatedAt: now.UnixMilli()}}, many))
+
+	t.Run("should return entries ordered by time descending", func(t *testing.T) {
+		result, err := dbstore.ListStateHistory(ctx, &models.ListStateHistoryQuery{OrgID: 1, RuleUID: "rule-1"})
+		require.NoError(t, err)
+		require.Len(t, result, 3)
+		require.Equal(t, "hash-2", result[0].LabelsHash)
+		require.GreaterOrEqual(t, result[1].EvaluatedAt, result[2].EvaluatedAt)
+	})
+
+	t.Run("should filter by label pairs", func(t *testing.T) {
+		result, err := dbstore.ListStateHistory(ctx, &models.ListStateHistoryQuery{OrgID: 1, PairHashes: []string{"pair-a", "pair-b"}})
+		require.NoError(t, err)
+		require.Len(t, result, 3)
+		for _, e := range result {
+			require.Equal(t, "hash-1", e.LabelsHash)
+		}
+
+		result, err = dbstore.ListStateHistory(ctx, &models.ListStateHistoryQuery{OrgID: 1, PairHashes: []string{"pair-c"}})
+		require.NoError(t, err)
+		require.Empty(t, result)
+	})
+
+	t.Run("should filter by time range, namespace and limit", func(t *testing.T) {
+		result, err := dbstore.ListStateHistory(ctx, &models.ListStateHistoryQuery{
+			OrgID: 1,
+			From:  now.Add(-90 * time.Minute),
+			To:    now.Add(time.Minute),
+			Limit: 1,
+		})
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		require.Equal(t, "rule-2", result[0].RuleUID)
+
+		result, err = dbstore.ListStateHistory(ctx, &models.ListStateHistoryQuery{OrgID: 1, NamespaceUIDs: []string{"other"}})
+		require.NoError(t, err)
+		require.Empty(t, result)
+	})
+
+	t.Run("should delete expired entries", func(t *testing.T) {
+		store.TimeNow = func() time.Time {
+			return now
+		}
+		t.Cleanup(func() {
+			store.TimeNow = time.Now
+		})
+		dbstore.Cfg.StateHistory.SQLMaxAge = 90 * time.Minute
+
+		deleted, err := dbstore.DeleteExpiredStateHistory(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), deleted)
+
+		result, err := dbstore.ListStateHistory(ctx, &models.ListStateHistoryQuery{OrgID: 1})
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+	})
+}
@@ -0,0 +1,94 @@
+package ngalert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/supportbundles"
+)
+
+// ruleStatusReader is the part of the scheduler the support bundle collector reads
+type ruleStatusReader interface {
+	Rules() ([]*models.AlertRule, map[models.FolderKey]string)
+	Status(key models.AlertRuleKey) (models.RuleStatus, bool)
+}
+
+func supportBundleCollector(scheduler ruleStatusReader) supportbundles.Collector {
+	return supportbundles.Collector{
+		UID:               "alerting-rule-status",
+		DisplayName:       "Alert rule evaluation status",
+		Description:       "Health and last evaluation of the alert rules run by the scheduler of this instance",
+		IncludedByDefault: false,
+		Default:           true,
+		Fn: func(ctx context.Context) (*supportbundles.SupportItem, error) {
+			return &supportbundles.SupportItem{
+				Filename:  "alerting-rule-status.md",
+				FileBytes: ruleStatusMarkdown(scheduler),
+			}, nil
+		},
+	}
+}
+
+func ruleStatusMarkdown(scheduler ruleStatusReader) []byte {
+	rules, folderTitles := scheduler.Rules()
+	folderTitle := func(rule *models.AlertRule) string {
+		if title, ok := folderTitles[models.FolderKey{OrgID: rule.OrgID, UID: rule.NamespaceUID}]; ok {
+			return title
+		}
+		return rule.NamespaceUID
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if a.OrgID != b.OrgID {
+			return a.OrgID < b.OrgID
+		}
+		if fa, fb := folderTitle(a), folderTitle(b); fa != fb {
+			return fa < fb
+		}
+		if a.RuleGroup != b.RuleGroup {
+			return a.RuleGroup < b.RuleGroup
+		}
+		return a.Title < b.Title
+	})
+
+	bWriter := bytes.NewBuffer(nil)
+	bWriter.WriteString("# Alert rule evaluation status\n\n")
+	bWriter.WriteString(fmt.Sprintf("Scheduled rules: %d\n\n", len(rules)))
+	bWriter.WriteString("| Org | Folder | Group | Rule | UID | Type | Health | Last evaluation | Duration | Last error |\n")
+	bWriter.WriteString("| --- | --- | --- | --- | --- | --- | --- | --- | --- | --- |\n")
+	for _, rule := range rules {
+		ruleType := "alerting"
+		if rule.Record != nil {
+			ruleType = "recording"
+		}
+		health, lastEvaluation, duration, lastError := "not scheduled", "", "", ""
+		if status, ok := scheduler.Status(rule.GetKey()); ok {
+			health = status.Health
+			if !status.EvaluationTimestamp.IsZero() {
+				lastEvaluation = status.EvaluationTimestamp.UTC().Format(time.RFC3339)
+				duration = status.EvaluationDuration.String()
+			}
+			if status.LastError != nil {
+				lastError = status.LastError.Error()
+			}
+		}
+		if rule.IsPaused {
+			health = "paused"
+		}
+		bWriter.WriteString(fmt.Sprintf("| %d | %s | %s | %s | %s | %s | %s | %s | %s | %s |\n",
+			rule.OrgID, markdownCell(folderTitle(rule)), markdownCell(rule.RuleGroup), markdownCell(rule.Title), rule.UID,
+			ruleType, health, lastEvaluation, duration, markdownCell(lastError)))
+	}
+	return bWriter.Bytes()
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,48 @@
+package ngalert
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+type fakeRuleStatusReader struct {
+	rules    []*models.AlertRule
+	folders  map[models.FolderKey]string
+	statuses map[models.AlertRuleKey]models.RuleStatus
+}
+
+func (f *fakeRuleStatusReader) Rules() ([]*models.AlertRule, map[models.FolderKey]string) {
+	return f.rules, f.folders
+}
+
+func (f *fakeRuleStatusReader) Status(key models.AlertRuleKey) (models.RuleStatus, bool) {
+	status, ok := f.statuses[key]
+	return status, ok
+}
+
+func TestRuleStatusMarkdown(t *testing.T) {
+	failing := &models.AlertRule{OrgID: 1, UID: "b", NamespaceUID: "f1", RuleGroup: "group", Title: "Failing | rule"}
+	healthy := &models.AlertRule{OrgID: 1, UID: "a", NamespaceUID: "f1", RuleGroup: "group", Title: "Healthy", Record: &models.Record{Metric: "m", From: "A"}}
+	paused := &models.AlertRule{OrgID: 2, UID: "c", NamespaceUID: "f2", RuleGroup: "group", Title: "Paused", IsPaused: true}
+
+	evaluated := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	reader := &fakeRuleStatusReader{
+		rules:   []*models.AlertRule{paused, healthy, failing},
+		folders: map[models.FolderKey]string{{OrgID: 1, UID: "f1"}: "Folder"},
+		statuses: map[models.AlertRuleKey]models.RuleStatus{
+			failing.GetKey(): {Health: "error", LastError: errors.New("query failed:\ntimeout"), EvaluationTimestamp: evaluated, EvaluationDuration: time.Second},
+			healthy.GetKey(): {Health: "ok", EvaluationTimestamp: evaluated, EvaluationDuration: 2 * time.Second},
+		},
+	}
+
+	lines := string(ruleStatusMarkdown(reader))
+	require.Contains(t, lines, "Scheduled rules: 3\n")
+	require.Contains(t, lines, "| 1 | Folder | group | Failing \\| rule | b | alerting | error | 2024-05-01T10:00:00Z | 1s | query failed: timeout |\n"+
+		"| 1 | Folder | group | Healthy | a | recording | ok | 2024-05-01T10:00:00Z | 2s |  |\n"+
+		"| 2 | f2 | group | Paused | c | alerting | paused |  |  |  |\n")
+}
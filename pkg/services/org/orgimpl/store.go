@@ -241,9 +241,15 @@ func (ss *sqlStore) Delete(ctx context.Context, cmd *org.DeleteOrgCommand) error
 			"DELETE FROM kv_store WHERE org_id = ?",
 			"DELETE FROM team WHERE org_id = ?",
 			"DELETE FROM team_member WHERE org_id = ?",
+			"DELETE FROM team_external_group WHERE org_id = ?",
 			"DELETE FROM team_role WHERE org_id = ?",
 			"DELETE FROM user_role WHERE org_id = ?",
 			"DELETE FROM builtin_role WHERE org_id = ?",
+			"DELETE FROM org_mfa_policy WHERE org_id = ?",
+			"DELETE FROM scim_resource WHERE org_id = ?",
+			"DELETE FROM slow_query WHERE org_id = ?",
+			"DELETE FROM scheduled_report WHERE org_id = ?",
+			"DELETE FROM scheduled_report_delivery WHERE org_id = ?",
 		}
 
 		// Add registered deletes
@@ -817,6 +823,8 @@ func (ss *sqlStore) userDeletions() []string {
 		"DELETE FROM user_auth WHERE user_id = ?",
 		"DELETE FROM user_auth_token WHERE user_id = ?",
 		"DELETE FROM quota WHERE user_id = ?",
+		"DELETE FROM user_mfa WHERE user_id = ?",
+		"DELETE FROM scim_resource WHERE resource_type = 'User' AND resource_id = ?",
 	}
 	return deletes
 }
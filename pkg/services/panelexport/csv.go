@@ -0,0 +1,59 @@
+package panelexport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvTimeFormat is RFC 3339 with milliseconds, which spreadsheets and pandas both parse
+const csvTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+func writeCSV(w io.Writer, t *table, location *time.Location) error {
+	cw := csv.NewWriter(w)
+
+	record := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		record[i] = c.name
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+
+	for row := 0; row < t.rows; row++ {
+		for i, c := range t.columns {
+			record[i] = ""
+			if v, ok := c.value(row); ok {
+				record[i] = formatValue(v, location)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatValue(v any, location *time.Location) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.In(location).Format(csvTimeFormat)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case bool:
+		return strconv.FormatBool(v)
+	case json.RawMessage:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,45 @@
+package panelexport
+
+import (
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+)
+
+var (
+	ErrBadRequest = errutil.BadRequest("panelexport.bad-request").MustTemplate(
+		"invalid export request: {{ .Public.Reason }}",
+		errutil.WithPublic("Invalid export request: {{ .Public.Reason }}"),
+	)
+	ErrPanelNotFound = errutil.NotFound(
+		"panelexport.panel-not-found",
+		errutil.WithPublicMessage("Panel not found"),
+	)
+	ErrTooLarge = errutil.BadRequest(
+		"panelexport.too-large",
+		errutil.WithPublicMessage("The panel data doesn't fit in an XLSX sheet, export to CSV or Parquet instead"),
+	)
+	ErrNoTimeField = errutil.BadRequest(
+		"panelexport.no-time-field",
+		errutil.WithPublicMessage("The panel returned several frames and some of them have no time field to join them by, select a single frame with frameIndex"),
+	)
+	ErrQueryFailed = errutil.BadGateway("panelexport.query-failed").MustTemplate(
+		"query {{ .Public.RefID }} failed: {{ .Private.Error }}",
+		errutil.WithPublic("Query {{ .Public.RefID }} failed: {{ .Public.Error }}"),
+	)
+)
+
+func badRequestError(format string, args ...any) error {
+	return ErrBadRequest.Build(errutil.TemplateData{
+		Public: map[string]any{"Reason": fmt.Sprintf(format, args...)},
+	})
+}
+
+// QueryFailedError returns the error an export fails with when a query of the panel fails
+func QueryFailedError(refID string, err error) error {
+	return ErrQueryFailed.Build(errutil.TemplateData{
+		Public:  map[string]any{"RefID": refID, "Error": err.Error()},
+		Private: map[string]any{"Error": err},
+		Error:   err,
+	})
+}
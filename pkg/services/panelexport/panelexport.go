@@ -0,0 +1,320 @@
+// Package panelexport runs the queries of a dashboard panel on the server and writes the resulting data frames as
+// CSV, XLSX or Parquet files, without the size limits of the export of the inspect drawer.
+package panelexport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/slugify"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/dashboards/panelquery"
+	"github.com/grafana/grafana/pkg/services/dashboards/templating"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/query"
+)
+
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatXLSX    Format = "xlsx"
+	FormatParquet Format = "parquet"
+)
+
+// ExportQuery selects the data of a panel to export
+type ExportQuery struct {
+	OrgID        int64
+	DashboardUID string
+	PanelID      int64
+	// From and To default to the time range saved in the dashboard
+	From string
+	To   string
+	// Variables override the values of the template variables saved in the dashboard
+	Variables map[string][]string
+	// Timezone is the IANA time zone the relative time range is resolved in and the times are written in,
+	// it defaults to the time zone of the dashboard
+	Timezone string
+	Format   Format
+	// FrameIndex selects a single data frame of the response, by default the frames are joined by time
+	// in CSV and Parquet, and written to a sheet each in XLSX
+	FrameIndex *int
+}
+
+// Export is the data of a panel ready to be written
+type Export struct {
+	Format   Format
+	Filename string
+	Location *time.Location
+	Tables   []*table
+}
+
+// Empty returns true when the panel returned no data
+func (e *Export) Empty() bool {
+	return len(e.Tables) == 0
+}
+
+// ContentType returns the media type of the file
+func (e *Export) ContentType() string {
+	switch e.Format {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// Write writes the file, it must not be called on an empty export
+func (e *Export) Write(w io.Writer) error {
+	switch e.Format {
+	case FormatXLSX:
+		return writeXLSX(w, e.Tables, e.Location)
+	case FormatParquet:
+		return writeParquet(w, e.Tables[0])
+	default:
+		return writeCSV(w, e.Tables[0], e.Location)
+	}
+}
+
+type Service struct {
+	log              log.Logger
+	dashboardService dashboards.DashboardService
+	queryService     query.Service
+	queryBuilder     *panelquery.Builder
+}
+
+func ProvideService(
+	dashboardService dashboards.DashboardService,
+	dataSourceService datasources.DataSourceService,
+	queryService query.Service,
+) *Service {
+	return &Service{
+		log:              log.New("panelexport"),
+		dashboardService: dashboardService,
+		queryService:     queryService,
+		queryBuilder:     panelquery.NewBuilder(dataSourceService),
+	}
+}
+
+// Export runs the queries and expressions of a panel on behalf of user and returns their data frames, it fails when
+// any of the queries fails rather than exporting partial data.
+func (s *Service) Export(ctx context.Context, user identity.Requester, q *ExportQuery) (*Export, error) {
+	if q.Format == "" {
+		q.Format = FormatCSV
+	}
+	if q.Format != FormatCSV && q.Format != FormatXLSX && q.Format != FormatParquet {
+		return nil, badRequestError("unknown format %q, use csv, xlsx or parquet", q.Format)
+	}
+
+	dashboard, err := s.dashboardService.GetDashboard(ctx, &dashboards.GetDashboardQuery{UID: q.DashboardUID, OrgID: q.OrgID})
+	if err != nil {
+		return nil, err
+	}
+
+	// work on a copy, the dashboard may be cached
+	raw, err := dashboard.Data.Encode()
+	if err != nil {
+		return nil, err
+	}
+	model, err := simplejson.NewJson(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	panel, ok := panelquery.FindPanel(model, q.PanelID)
+	if !ok {
+		return nil, ErrPanelNotFound.Errorf("panel %d not found in dashboard %s", q.PanelID, q.DashboardUID)
+	}
+
+	location, err := exportLocation(model, q.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := exportTimeRange(model, q.From, q.To, location)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := templating.CurrentValues(model)
+	for name := range q.Variables {
+		if _, ok := vars[name]; !ok {
+			return nil, badRequestError("dashboard %s has no template variable named %q", q.DashboardUID, name)
+		}
+	}
+	vars = vars.Merge(q.Variables)
+
+	frames, err := s.queryPanel(ctx, user, panel, &panelquery.Request{OrgID: q.OrgID, From: from, To: to, Vars: vars})
+	if err != nil {
+		return nil, err
+	}
+
+	if q.FrameIndex != nil {
+		if *q.FrameIndex < 0 || *q.FrameIndex >= len(frames) {
+			return nil, badRequestError("frameIndex %d is out of range, the panel returned %d frames", *q.FrameIndex, len(frames))
+		}
+		frames = data.Frames{frames[*q.FrameIndex]}
+	}
+
+	export := &Export{
+		Format:   q.Format,
+		Filename: exportFilename(dashboard, panel, q.Format),
+		Location: location,
+	}
+	if len(frames) == 0 {
+		return export, nil
+	}
+
+	switch {
+	case q.Format == FormatXLSX:
+		for i, frame := range frames {
+			export.Tables = append(export.Tables, newTable(frameName(frame, i), frame))
+		}
+	case len(frames) == 1:
+		export.Tables = []*table{newTable(frameName(frames[0], 0), frames[0])}
+	default:
+		joined, err := joinByTime(frames)
+		if err != nil {
+			return nil, err
+		}
+		export.Tables = []*table{joined}
+	}
+
+	for _, t := range export.Tables {
+		if err := checkLimits(q.Format, t); err != nil {
+			return nil, err
+		}
+	}
+
+	s.log.Debug("Exporting panel data", "dashboardUID", q.DashboardUID, "panelID", q.PanelID, "format", q.Format, "frames", len(frames))
+	return export, nil
+}
+
+// queryPanel returns the frames of the queries of a panel in the order of the queries, without the frames of the
+// hidden queries an expression depends on
+func (s *Service) queryPanel(ctx context.Context, user identity.Requester, panel *simplejson.Json, req *panelquery.Request) (data.Frames, error) {
+	queries, err := s.queryBuilder.Queries(ctx, req, panel)
+	if err != nil || len(queries) == 0 {
+		return nil, err
+	}
+
+	res, err := s.queryService.QueryData(ctx, user, false, dtos.MetricRequest{
+		From:    strconv.FormatInt(req.From.UnixMilli(), 10),
+		To:      strconv.FormatInt(req.To.UnixMilli(), 10),
+		Queries: queries,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var frames data.Frames
+	for _, q := range queries {
+		refID := q.Get("refId").MustString()
+		if q.Get("hide").MustBool() {
+			continue
+		}
+		dr, ok := res.Responses[refID]
+		if !ok {
+			continue
+		}
+		if dr.Error != nil {
+			return nil, QueryFailedError(refID, dr.Error)
+		}
+		for _, frame := range dr.Frames {
+			if frame.RefID == "" {
+				frame.RefID = refID
+			}
+			// frames without fields only carry notices, like "no data"
+			if len(frame.Fields) > 0 {
+				frames = append(frames, frame)
+			}
+		}
+	}
+	return frames, nil
+}
+
+// exportLocation returns the requested time zone, or the one of the dashboard. The browser time zone of the
+// dashboards is unknown to the server, UTC is used instead.
+func exportLocation(dashboard *simplejson.Json, timezone string) (*time.Location, error) {
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil || strings.EqualFold(timezone, "local") {
+			return nil, badRequestError("unknown timezone %q", timezone)
+		}
+		return location, nil
+	}
+
+	switch tz := dashboard.Get("timezone").MustString(); strings.ToLower(tz) {
+	case "", "browser", "local", "utc":
+		return time.UTC, nil
+	default:
+		location, err := time.LoadLocation(tz)
+		if err != nil {
+			return time.UTC, nil
+		}
+		return location, nil
+	}
+}
+
+// exportTimeRange resolves the requested time range, or the one saved in the dashboard, to absolute times.
+func exportTimeRange(dashboard *simplejson.Json, from string, to string, location *time.Location) (time.Time, time.Time, error) {
+	if from == "" && to == "" {
+		from = dashboard.GetPath("time", "from").MustString("now-6h")
+		to = dashboard.GetPath("time", "to").MustString("now")
+	}
+	if from == "" || to == "" {
+		return time.Time{}, time.Time{}, badRequestError("the time range needs both from and to")
+	}
+
+	timeRange := gtime.NewTimeRange(from, to)
+	fromTime, err := timeRange.ParseFrom(gtime.WithLocation(location))
+	if err != nil {
+		return time.Time{}, time.Time{}, badRequestError("invalid from: %v", err)
+	}
+	toTime, err := timeRange.ParseTo(gtime.WithLocation(location))
+	if err != nil {
+		return time.Time{}, time.Time{}, badRequestError("invalid to: %v", err)
+	}
+	if !fromTime.Before(toTime) {
+		return time.Time{}, time.Time{}, badRequestError("from must be before to")
+	}
+
+	return fromTime, toTime, nil
+}
+
+func exportFilename(dashboard *dashboards.Dashboard, panel *simplejson.Json, format Format) string {
+	name := slugify.Slugify(panel.Get("title").MustString())
+	if name == "" {
+		name = fmt.Sprintf("panel-%d", panel.Get("id").MustInt64())
+	}
+	if dashboard.Slug != "" {
+		name = dashboard.Slug + "-" + name
+	}
+	return name + "." + string(format)
+}
+
+func checkLimits(format Format, t *table) error {
+	if format != FormatXLSX {
+		return nil
+	}
+	// the header takes a row
+	if t.rows+1 > xlsxMaxRows {
+		return ErrTooLarge.Errorf("%d rows don't fit in an XLSX sheet, export to CSV or Parquet instead", t.rows)
+	}
+	if len(t.columns) > xlsxMaxColumns {
+		return ErrTooLarge.Errorf("%d columns don't fit in an XLSX sheet, export to CSV or Parquet instead", len(t.columns))
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package panelexport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/query"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+const dashboardJSON = `{
+	"uid": "status",
+	"title": "Status",
+	"timezone": "Europe/Paris",
+	"time": {"from": "now-6h", "to": "now"},
+	"templating": {
+		"list": [
+			{"name": "region", "type": "query", "current": {"text": "eu", "value": "eu"}}
+		]
+	},
+	"panels": [
+		{
+			"id": 1,
+			"title": "Requests",
+			"type": "timeseries",
+			"datasource": {"type": "prometheus", "uid": "prom"},
+			"targets": [
+				{"refId": "A", "expr": "rate(requests{region=\"$region\"}[5m])"},
+				{"refId": "B", "expr": "rate(errors[5m])", "hide": true},
+				{"refId": "C", "datasource": {"type": "__expr__", "uid": "__expr__"}, "type": "math", "expression": "$B * 100"}
+			]
+		}
+	]
+}`
+
+func setupService(t *testing.T) (*Service, *query.FakeQueryService) {
+	t.Helper()
+
+	dashboardData, err := simplejson.NewJson([]byte(dashboardJSON))
+	require.NoError(t, err)
+
+	dashboardService := &dashboards.FakeDashboardService{}
+	dashboardService.On("GetDashboard", mock.Anything, &dashboards.GetDashboardQuery{UID: "status", OrgID: 1}).
+		Return(&dashboards.Dashboard{UID: "status", Slug: "status", OrgID: 1, Title: "Status", Data: dashboardData}, nil)
+	dashboardService.On("GetDashboard", mock.Anything, mock.Anything).Return(nil, dashboards.ErrDashboardNotFound)
+
+	dataSourceService := &fakes.FakeDataSourceService{DataSources: []*datasources.DataSource{
+		{OrgID: 1, UID: "prom", Type: "prometheus", IsDefault: true},
+	}}
+
+	queryService := &query.FakeQueryService{}
+	return ProvideService(dashboardService, dataSourceService, queryService), queryService
+}
+
+func TestExport(t *testing.T) {
+	signedInUser := &user.SignedInUser{UserID: 3, OrgID: 1}
+	t0 := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	responses := backend.Responses{
+		"A": {Frames: data.Frames{data.NewFrame("requests",
+			data.NewField("time", nil, []time.Time{t0, t0.Add(time.Minute)}),
+			data.NewField("value", data.Labels{"region": "us"}, []float64{1.5, 2}),
+		)}},
+		"B": {Frames: data.Frames{data.NewFrame("errors",
+			data.NewField("time", nil, []time.Time{t0}),
+			data.NewField("value", nil, []float64{0.1}),
+		)}},
+		"C": {Frames: data.Frames{data.NewFrame("",
+			data.NewField("time", nil, []time.Time{t0, t0.Add(2 * time.Minute)}),
+			data.NewField("C", nil, []float64{10, 20}),
+		)}},
+	}
+
+	t.Run("should query the panel and join its frames", func(t *testing.T) {
+		s, queryService := setupService(t)
+
+		var request dtos.MetricRequest
+		queryService.On("QueryData", mock.Anything, signedInUser, false, mock.Anything).
+			Run(func(args mock.Arguments) {
+				request = args.Get(3).(dtos.MetricRequest)
+			}).
+			Return(&backend.QueryDataResponse{Responses: responses}, nil)
+
+		export, err := s.Export(context.Background(), signedInUser, &ExportQuery{
+			OrgID:        1,
+			DashboardUID: "status",
+			PanelID:      1,
+			From:         "1714557600000",
+			To:           "1714561200000",
+			Variables:    map[string][]string{"region": {"us"}},
+			Timezone:     "America/New_York",
+		})
+		require.NoError(t, err)
+
+		// the hidden query runs for the expression but is not exported
+		require.Len(t, request.Queries, 3)
+		require.Equal(t, `rate(requests{region="us"}[5m])`, request.Queries[0].Get("expr").MustString())
+		require.Equal(t, "1714557600000", request.From)
+
+		require.Equal(t, FormatCSV, export.Format)
+		require.Equal(t, "status-requests.csv", export.Filename)
+		require.Equal(t, "text/csv; charset=utf-8", export.ContentType())
+
+		var buf bytes.Buffer
+		require.NoError(t, export.Write(&buf))
+		require.Equal(t, `Time,"requests {region=""us""}",C
+2024-05-01T06:00:00.000-04:00,1.5,10
+2024-05-01T06:01:00.000-04:00,2,
+2024-05-01T06:02:00.000-04:00,,20
+`, buf.String())
+	})
+
+	t.Run("should write a sheet per frame", func(t *testing.T) {
+		s, queryService := setupService(t)
+		queryService.On("QueryData", mock.Anything, signedInUser, false, mock.Anything).
+			Return(&backend.QueryDataResponse{Responses: responses}, nil)
+
+		export, err := s.Export(context.Background(), signedInUser, &ExportQuery{OrgID: 1, DashboardUID: "status", PanelID: 1, Format: FormatXLSX})
+		require.NoError(t, err)
+		require.Len(t, export.Tables, 2)
+		require.Equal(t, "requests", export.Tables[0].name)
+		require.Equal(t, "C", export.Tables[1].name)
+		require.Equal(t, "Europe/Paris", export.Location.String())
+	})
+
+	t.Run("should select a single frame", func(t *testing.T) {
+		s, queryService := setupService(t)
+		queryService.On("QueryData", mock.Anything, signedInUser, false, mock.Anything).
+			Return(&backend.QueryDataResponse{Responses: responses}, nil)
+
+		frameIndex := 1
+		export, err := s.Export(context.Background(), signedInUser, &ExportQuery{OrgID: 1, DashboardUID: "status", PanelID: 1, FrameIndex: &frameIndex})
+		require.NoError(t, err)
+		require.Len(t, export.Tables, 1)
+		require.Equal(t, 2, export.Tables[0].rows)
+		require.Equal(t, "C", export.Tables[0].columns[1].name)
+
+		frameIndex = 2
+		_, err = s.Export(context.Background(), signedInUser, &ExportQuery{OrgID: 1, DashboardUID: "status", PanelID: 1, FrameIndex: &frameIndex})
+		require.ErrorIs(t, err, ErrBadRequest)
+	})
+
+	t.Run("should fail when a query fails", func(t *testing.T) {
+		s, queryService := setupService(t)
+		queryService.On("QueryData", mock.Anything, signedInUser, false, mock.Anything).
+			Return(&backend.QueryDataResponse{Responses: backend.Responses{
+				"A": {Error: errors.New("timeout")},
+			}}, nil)
+
+		_, err := s.Export(context.Background(), signedInUser, &ExportQuery{OrgID: 1, DashboardUID: "status", PanelID: 1})
+		require.ErrorIs(t, err, ErrQueryFailed)
+		require.ErrorContains(t, err, "timeout")
+	})
+
+	t.Run("should return an empty export without data", func(t *testing.T) {
+		s, queryService := setupService(t)
+		queryService.On("QueryData", mock.Anything, signedInUser, false, mock.Anything).
+			Return(&backend.QueryDataResponse{Responses: backend.Responses{
+				"A": {Frames: data.Frames{data.NewFrame("")}},
+			}}, nil)
+
+		export, err := s.Export(context.Background(), signedInUser, &ExportQuery{OrgID: 1, DashboardUID: "status", PanelID: 1})
+		require.NoError(t, err)
+		require.True(t, export.Empty())
+	})
+
+	t.Run("should reject invalid requests", func(t *testing.T) {
+		s, _ := setupService(t)
+
+		for name, q := range map[string]*ExportQuery{
+			"unknown format":   {Format: "json"},
+			"unknown variable": {Variables: map[string][]string{"env": {"prod"}}},
+			"unknown timezone": {Timezone: "Mars/Olympus"},
+			"missing to":       {From: "now-1h"},
+			"inverted range":   {From: "now", To: "now-1h"},
+		} {
+			q.OrgID, q.DashboardUID, q.PanelID = 1, "status", 1
+			_, err := s.Export(context.Background(), signedInUser, q)
+			require.ErrorIs(t, err, ErrBadRequest, name)
+		}
+
+		_, err := s.Export(context.Background(), signedInUser, &ExportQuery{OrgID: 1, DashboardUID: "status", PanelID: 7})
+		require.ErrorIs(t, err, ErrPanelNotFound)
+
+		_, err = s.Export(context.Background(), signedInUser, &ExportQuery{OrgID: 1, DashboardUID: "missing", PanelID: 1})
+		require.ErrorIs(t, err, dashboards.ErrDashboardNotFound)
+	})
+}
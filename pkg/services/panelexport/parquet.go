@@ -0,0 +1,131 @@
+package panelexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet"
+	"github.com/apache/arrow/go/v15/parquet/compress"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// arrowTypeOf returns the type of the Parquet column of a field type, the times are UTC timestamps in
+// microseconds as the format expects
+func arrowTypeOf(typ data.FieldType) arrow.DataType {
+	switch typ {
+	case data.FieldTypeInt8:
+		return arrow.PrimitiveTypes.Int8
+	case data.FieldTypeInt16:
+		return arrow.PrimitiveTypes.Int16
+	case data.FieldTypeInt32:
+		return arrow.PrimitiveTypes.Int32
+	case data.FieldTypeInt64:
+		return arrow.PrimitiveTypes.Int64
+	case data.FieldTypeUint8:
+		return arrow.PrimitiveTypes.Uint8
+	case data.FieldTypeUint16:
+		return arrow.PrimitiveTypes.Uint16
+	case data.FieldTypeUint32:
+		return arrow.PrimitiveTypes.Uint32
+	case data.FieldTypeUint64:
+		return arrow.PrimitiveTypes.Uint64
+	case data.FieldTypeFloat32:
+		return arrow.PrimitiveTypes.Float32
+	case data.FieldTypeFloat64:
+		return arrow.PrimitiveTypes.Float64
+	case data.FieldTypeBool:
+		return arrow.FixedWidthTypes.Boolean
+	case data.FieldTypeTime:
+		return &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+// writeParquet writes a table as a Parquet file with a single row group. The columns are nullable and compressed
+// with snappy.
+func writeParquet(w io.Writer, t *table) error {
+	fields := make([]arrow.Field, len(t.columns))
+	for i, c := range t.columns {
+		fields[i] = arrow.Field{Name: c.name, Type: arrowTypeOf(c.typ), Nullable: true}
+	}
+	schema := arrow.NewSchema(fields, nil)
+
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	b.Reserve(t.rows)
+	for i, c := range t.columns {
+		appendColumn(b.Field(i), c, t.rows)
+	}
+	record := b.NewRecord()
+	defer record.Release()
+
+	props := parquet.NewWriterProperties(
+		parquet.WithCompression(compress.Codecs.Snappy),
+		parquet.WithCreatedBy("grafana"),
+		// a single row group keeps the rows of the export together
+		parquet.WithMaxRowGroupLength(int64(max(t.rows, 1))),
+	)
+	fw, err := pqarrow.NewFileWriter(schema, w, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return err
+	}
+	if err := fw.Write(record); err != nil {
+		_ = fw.Close()
+		return err
+	}
+	return fw.Close()
+}
+
+// appendColumn appends the values of a column to the builder of its type, see arrowTypeOf
+func appendColumn(b array.Builder, c *column, rows int) {
+	for row := 0; row < rows; row++ {
+		v, ok := c.value(row)
+		if !ok {
+			b.AppendNull()
+			continue
+		}
+
+		switch b := b.(type) {
+		case *array.Int8Builder:
+			b.Append(v.(int8))
+		case *array.Int16Builder:
+			b.Append(v.(int16))
+		case *array.Int32Builder:
+			b.Append(v.(int32))
+		case *array.Int64Builder:
+			b.Append(v.(int64))
+		case *array.Uint8Builder:
+			b.Append(v.(uint8))
+		case *array.Uint16Builder:
+			b.Append(v.(uint16))
+		case *array.Uint32Builder:
+			b.Append(v.(uint32))
+		case *array.Uint64Builder:
+			b.Append(v.(uint64))
+		case *array.Float32Builder:
+			b.Append(v.(float32))
+		case *array.Float64Builder:
+			b.Append(v.(float64))
+		case *array.BooleanBuilder:
+			b.Append(v.(bool))
+		case *array.TimestampBuilder:
+			b.Append(arrow.Timestamp(v.(time.Time).UnixMicro()))
+		case *array.StringBuilder:
+			switch v := v.(type) {
+			case string:
+				b.Append(v)
+			case json.RawMessage:
+				b.Append(string(v))
+			default:
+				b.Append(fmt.Sprint(v))
+			}
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package panelexport
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// table is the tabular view of one or more data frames the writers work on
+type table struct {
+	name    string
+	columns []*column
+	rows    int
+}
+
+type column struct {
+	name  string
+	typ   data.FieldType
+	field *data.Field
+	// index maps the rows of the table to the rows of the field when frames are joined, -1 is a null
+	index []int
+}
+
+// value returns the value of the column in a row, false when it is null
+func (c *column) value(row int) (any, bool) {
+	i := row
+	if c.index != nil {
+		if i = c.index[row]; i < 0 {
+			return nil, false
+		}
+	}
+	return c.field.ConcreteAt(i)
+}
+
+func newTable(name string, frame *data.Frame) *table {
+	names := fieldNames(frame)
+	t := &table{name: name, rows: frame.Rows()}
+	for i, field := range frame.Fields {
+		t.columns = append(t.columns, &column{name: names[i], typ: field.Type().NonNullableType(), field: field})
+	}
+	dedupeColumnNames(t.columns)
+	return t
+}
+
+// joinByTime joins frames on their first time field. A time repeated in a frame is matched with the same
+// occurrence of that time in the other frames, so that no row is lost.
+func joinByTime(frames data.Frames) (*table, error) {
+	type key struct {
+		null       bool
+		nanos      int64
+		occurrence int
+	}
+
+	rows := map[key]int{}
+	var keys []key
+	frameKeys := make([][]key, len(frames))
+	timeFields := make([]int, len(frames))
+
+	for i, frame := range frames {
+		indices := frame.TypeIndices(data.FieldTypeTime, data.FieldTypeNullableTime)
+		if len(indices) == 0 {
+			return nil, ErrNoTimeField.Errorf("frame %d (%s) has no time field", i, frameName(frame, i))
+		}
+		timeFields[i] = indices[0]
+
+		field := frame.Fields[indices[0]]
+		seen := map[key]int{}
+		frameKeys[i] = make([]key, field.Len())
+		for row := 0; row < field.Len(); row++ {
+			k := key{null: true}
+			if v, ok := field.ConcreteAt(row); ok {
+				k = key{nanos: v.(time.Time).UnixNano()}
+			}
+			k.occurrence = seen[k]
+			seen[key{null: k.null, nanos: k.nanos}]++
+
+			frameKeys[i][row] = k
+			if _, ok := rows[k]; !ok {
+				rows[k] = len(keys)
+				keys = append(keys, k)
+			}
+		}
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i].null != keys[j].null {
+			return keys[i].null
+		}
+		if keys[i].nanos != keys[j].nanos {
+			return keys[i].nanos < keys[j].nanos
+		}
+		return keys[i].occurrence < keys[j].occurrence
+	})
+	times := make([]*time.Time, len(keys))
+	for row, k := range keys {
+		rows[k] = row
+		if !k.null {
+			t := time.Unix(0, k.nanos)
+			times[row] = &t
+		}
+	}
+
+	t := &table{rows: len(keys)}
+	t.columns = append(t.columns, &column{name: "Time", typ: data.FieldTypeTime, field: data.NewField("Time", nil, times)})
+
+	for i, frame := range frames {
+		index := make([]int, len(keys))
+		for row := range index {
+			index[row] = -1
+		}
+		for row, k := range frameKeys[i] {
+			index[rows[k]] = row
+		}
+
+		names := fieldNames(frame)
+		for j, field := range frame.Fields {
+			if j == timeFields[i] {
+				continue
+			}
+			t.columns = append(t.columns, &column{name: names[j], typ: field.Type().NonNullableType(), field: field, index: index})
+		}
+	}
+
+	dedupeColumnNames(t.columns)
+	return t, nil
+}
+
+// fieldNames returns the names the frontend displays for the fields of a frame
+func fieldNames(frame *data.Frame) []string {
+	names := make([]string, len(frame.Fields))
+	for i, field := range frame.Fields {
+		if field.Config != nil && field.Config.DisplayNameFromDS != "" {
+			names[i] = field.Config.DisplayNameFromDS
+			continue
+		}
+
+		name := field.Name
+		if (name == "" || strings.EqualFold(name, "value")) && frame.Name != "" {
+			name = frame.Name
+		}
+		if name == "" {
+			name = "Field " + strconv.Itoa(i+1)
+		}
+		if len(field.Labels) > 0 {
+			name += " " + formatLabels(field.Labels)
+		}
+		names[i] = name
+	}
+	return names
+}
+
+func formatLabels(labels data.Labels) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%q", k, labels[k])
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// dedupeColumnNames suffixes the names that appear more than once with their occurrence, like "Value 2"
+func dedupeColumnNames(columns []*column) {
+	seen := map[string]bool{}
+	for _, c := range columns {
+		seen[c.name] = true
+	}
+	count := map[string]int{}
+	for _, c := range columns {
+		count[c.name]++
+		if n := count[c.name]; n > 1 {
+			name := fmt.Sprintf("%s %d", c.name, n)
+			for seen[name] {
+				n++
+				name = fmt.Sprintf("%s %d", c.name, n)
+			}
+			count[c.name] = n
+			seen[name] = true
+			c.name = name
+		}
+	}
+}
+
+func frameName(frame *data.Frame, i int) string {
+	switch {
+	case frame.Name != "":
+		return frame.Name
+	case frame.RefID != "":
+		return frame.RefID
+	default:
+		return "Frame " + strconv.Itoa(i+1)
+	}
+}
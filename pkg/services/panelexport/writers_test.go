@@ -0,0 +1,164 @@
+package panelexport
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func testFrames() data.Frames {
+	t0 := time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC)
+	value := func(f float64) *float64 { return &f }
+
+	return data.Frames{
+		data.NewFrame("cpu",
+			data.NewField("time", nil, []time.Time{t0, t0.Add(time.Hour), t0.Add(time.Hour)}),
+			data.NewField("value", data.Labels{"host": "a"}, []*float64{value(1.5), nil, value(math.NaN())}),
+		),
+		data.NewFrame("memory",
+			data.NewField("time", nil, []time.Time{t0.Add(time.Hour), t0.Add(2 * time.Hour)}),
+			data.NewField("value", nil, []int64{7, 8}),
+			data.NewField("ok", nil, []bool{true, false}),
+			data.NewField("note", nil, []string{`a "quoted" <note>`, "héllo"}),
+		),
+	}
+}
+
+func TestJoinByTime(t *testing.T) {
+	joined, err := joinByTime(testFrames())
+	require.NoError(t, err)
+
+	names := make([]string, len(joined.columns))
+	for i, c := range joined.columns {
+		names[i] = c.name
+	}
+	require.Equal(t, []string{"Time", `cpu {host="a"}`, "memory", "ok", "note"}, names)
+
+	// the repeated time is matched with its first occurrence in the other frame
+	require.Equal(t, 4, joined.rows)
+	_, ok := joined.columns[1].value(1)
+	require.False(t, ok)
+	v, ok := joined.columns[2].value(1)
+	require.True(t, ok)
+	require.Equal(t, int64(7), v)
+	_, ok = joined.columns[2].value(2)
+	require.False(t, ok)
+
+	_, err = joinByTime(data.Frames{testFrames()[0], data.NewFrame("table", data.NewField("value", nil, []int64{1}))})
+	require.ErrorIs(t, err, ErrNoTimeField)
+}
+
+func TestDedupeColumnNames(t *testing.T) {
+	columns := []*column{{name: "Value"}, {name: "Value"}, {name: "Value 2"}, {name: "Value"}}
+	dedupeColumnNames(columns)
+	require.Equal(t, []string{"Value", "Value 3", "Value 2", "Value 4"}, []string{columns[0].name, columns[1].name, columns[2].name, columns[3].name})
+}
+
+func TestWriteCSV(t *testing.T) {
+	joined, err := joinByTime(testFrames())
+	require.NoError(t, err)
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeCSV(&buf, joined, paris))
+
+	// Paris switches to summer time during the export
+	require.Equal(t, `Time,"cpu {host=""a""}",memory,ok,note
+2024-03-31T01:30:00.000+01:00,1.5,,,
+2024-03-31T03:30:00.000+02:00,,7,true,"a ""quoted"" <note>"
+2024-03-31T03:30:00.000+02:00,NaN,,,
+2024-03-31T04:30:00.000+02:00,,8,false,héllo
+`, buf.String())
+}
+
+func TestWriteXLSX(t *testing.T) {
+	frames := testFrames()
+	tables := []*table{newTable("cpu", frames[0]), newTable("cpu", frames[1]), newTable("a/b: [c]", frames[1])}
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeXLSX(&buf, tables, paris))
+
+	f, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, f.Close()) }()
+
+	require.Equal(t, []string{"cpu", "cpu (2)", "a-b- (c)"}, f.GetSheetList())
+
+	rows, err := f.GetRows("cpu")
+	require.NoError(t, err)
+	// the times are the wall times in Paris, null and NaN cells are left empty
+	require.Equal(t, [][]string{
+		{"time", `cpu {host="a"}`},
+		{"2024-03-31 01:30:00.000", "1.5"},
+		{"2024-03-31 03:30:00.000"},
+		{"2024-03-31 03:30:00.000"},
+	}, rows)
+
+	serial, err := f.GetCellValue("cpu", "A2", excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+	require.Equal(t, "45382.0625", serial)
+
+	style, err := f.GetCellStyle("cpu", "B1")
+	require.NoError(t, err)
+	header, err := f.GetStyle(style)
+	require.NoError(t, err)
+	require.True(t, header.Font.Bold)
+
+	panes, err := f.GetPanes("cpu")
+	require.NoError(t, err)
+	require.True(t, panes.Freeze)
+	require.Equal(t, 1, panes.YSplit)
+
+	rows, err = f.GetRows("cpu (2)")
+	require.NoError(t, err)
+	require.Equal(t, []string{"2024-03-31 03:30:00.000", "7", "TRUE", `a "quoted" <note>`}, rows[1])
+	typ, err := f.GetCellType("cpu (2)", "C2")
+	require.NoError(t, err)
+	require.Equal(t, excelize.CellTypeBool, typ)
+}
+
+func TestWriteParquet(t *testing.T) {
+	joined, err := joinByTime(testFrames())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeParquet(&buf, joined))
+
+	read, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(buf.Bytes()), parquet.NewReaderProperties(memory.DefaultAllocator), pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	require.NoError(t, err)
+	defer read.Release()
+
+	require.Equal(t, int64(joined.rows), read.NumRows())
+	names := make([]string, read.NumCols())
+	for i := range names {
+		names[i] = read.Column(i).Name()
+	}
+	require.Equal(t, []string{"Time", `cpu {host="a"}`, "memory", "ok", "note"}, names)
+
+	times := read.Column(0).Data().Chunk(0).(*array.Timestamp)
+	require.Equal(t, arrow.Timestamp(time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC).UnixMicro()), times.Value(0))
+
+	values := read.Column(2).Data().Chunk(0).(*array.Int64)
+	require.True(t, values.IsNull(0))
+	require.Equal(t, int64(7), values.Value(1))
+	require.True(t, values.IsNull(2))
+	require.Equal(t, int64(8), values.Value(3))
+
+	notes := read.Column(4).Data().Chunk(0).(*array.String)
+	require.Equal(t, `a "quoted" <note>`, notes.Value(1))
+	require.Equal(t, "héllo", notes.Value(3))
+}
@@ -0,0 +1,163 @@
+package panelexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	xlsxMaxRows       = excelize.TotalRows
+	xlsxMaxColumns    = excelize.MaxColumns
+	xlsxMaxCellLength = excelize.TotalCellChars
+	xlsxMaxSheetName  = excelize.MaxSheetNameLength
+	xlsxDateFormat    = "yyyy-mm-dd hh:mm:ss.000"
+)
+
+// the serial numbers of the dates in spreadsheets count the days since 1899-12-30
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// writeXLSX writes a workbook with a sheet per table. The sheets are streamed row by row to temporary files when
+// they get large, so the rows aren't all kept in memory.
+func writeXLSX(w io.Writer, tables []*table, location *time.Location) error {
+	f := excelize.NewFile()
+	defer func() { _ = f.Close() }()
+
+	dateFormat := xlsxDateFormat
+	dateStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return err
+	}
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	names := sheetNames(tables)
+	for i, t := range tables {
+		// a new workbook comes with a first sheet
+		if i == 0 {
+			err = f.SetSheetName(f.GetSheetName(0), names[i])
+		} else {
+			_, err = f.NewSheet(names[i])
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeSheet(f, names[i], t, location, headerStyle, dateStyle); err != nil {
+			return err
+		}
+	}
+
+	_, err = f.WriteTo(w)
+	return err
+}
+
+func writeSheet(f *excelize.File, name string, t *table, location *time.Location, headerStyle, dateStyle int) error {
+	sw, err := f.NewStreamWriter(name)
+	if err != nil {
+		return err
+	}
+
+	// freeze the header
+	if err := sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+
+	cells := make([]any, len(t.columns))
+	for i, c := range t.columns {
+		cells[i] = excelize.Cell{StyleID: headerStyle, Value: c.name}
+	}
+	if err := sw.SetRow("A1", cells); err != nil {
+		return err
+	}
+
+	for row := 0; row < t.rows; row++ {
+		for i, c := range t.columns {
+			cells[i] = nil
+			if v, ok := c.value(row); ok {
+				cells[i] = cellValue(v, location, dateStyle)
+			}
+		}
+		ref, err := excelize.CoordinatesToCellName(1, row+2)
+		if err != nil {
+			return err
+		}
+		if err := sw.SetRow(ref, cells); err != nil {
+			return err
+		}
+	}
+
+	return sw.Flush()
+}
+
+// cellValue returns the value of a cell, nil leaves the cell empty
+func cellValue(v any, location *time.Location, dateStyle int) any {
+	switch v := v.(type) {
+	case time.Time:
+		return excelize.Cell{StyleID: dateStyle, Value: dateSerial(v, location)}
+	case float64:
+		// spreadsheets have no NaN nor infinities
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil
+		}
+		return v
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return nil
+		}
+		return v
+	case bool, int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		return v
+	case string:
+		return truncate(v, xlsxMaxCellLength)
+	case json.RawMessage:
+		return truncate(string(v), xlsxMaxCellLength)
+	default:
+		return truncate(fmt.Sprint(v), xlsxMaxCellLength)
+	}
+}
+
+// dateSerial returns the serial number of the wall time of t in location, the spreadsheets have no time zones
+func dateSerial(t time.Time, location *time.Location) float64 {
+	wall := t.In(location)
+	_, offset := wall.Zone()
+	return float64(wall.UnixMilli()+int64(offset)*1000-xlsxEpoch.UnixMilli()) / float64(24*time.Hour/time.Millisecond)
+}
+
+// sheetNames returns unique names that spreadsheets accept for the tables
+func sheetNames(tables []*table) []string {
+	replacer := strings.NewReplacer("[", "(", "]", ")", ":", "-", "*", "-", "?", "", "/", "-", "\\", "-")
+
+	seen := map[string]bool{}
+	names := make([]string, len(tables))
+	for i, t := range tables {
+		name := strings.Trim(replacer.Replace(t.name), "'")
+		if name == "" || strings.EqualFold(name, "history") {
+			name = fmt.Sprintf("Sheet%d", i+1)
+		}
+		name = truncate(name, xlsxMaxSheetName)
+
+		unique := name
+		for n := 2; seen[strings.ToLower(unique)]; n++ {
+			suffix := fmt.Sprintf(" (%d)", n)
+			unique = truncate(name, xlsxMaxSheetName-len(suffix)) + suffix
+		}
+		seen[strings.ToLower(unique)] = true
+		names[i] = unique
+	}
+	return names
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) > n {
+		return string([]rune(s)[:n])
+	}
+	return s
+}
@@ -0,0 +1,143 @@
+package clientmiddleware
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/plugins/instrumentationutils"
+	"github.com/grafana/grafana/pkg/services/contexthandler"
+	"github.com/grafana/grafana/pkg/services/query"
+	"github.com/grafana/grafana/pkg/services/slowquerylog"
+)
+
+// NewSlowQueryLogMiddleware creates a new backend.HandlerMiddleware that will
+// record the QueryData calls taking longer than threshold in the slow query log,
+// with the dashboard, panel and user that issued them.
+func NewSlowQueryLogMiddleware(slowQueryLog slowquerylog.Service, threshold time.Duration) backend.HandlerMiddleware {
+	return backend.HandlerMiddlewareFunc(func(next backend.Handler) backend.Handler {
+		return &SlowQueryLogMiddleware{
+			BaseHandler:  backend.NewBaseHandler(next),
+			slowQueryLog: slowQueryLog,
+			threshold:    threshold,
+		}
+	})
+}
+
+type SlowQueryLogMiddleware struct {
+	backend.BaseHandler
+	slowQueryLog slowquerylog.Service
+	threshold    time.Duration
+}
+
+func (m *SlowQueryLogMiddleware) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	if req == nil {
+		return m.BaseHandler.QueryData(ctx, req)
+	}
+
+	start := time.Now()
+	resp, err := m.BaseHandler.QueryData(ctx, req)
+	duration := time.Since(start)
+	if duration < m.threshold {
+		return resp, err
+	}
+
+	dashboardUID, panelID := queryOrigin(ctx, req)
+	slowQuery := &slowquerylog.SlowQuery{
+		OrgID:          req.PluginContext.OrgID,
+		DatasourceType: req.PluginContext.PluginID,
+		DashboardUID:   dashboardUID,
+		PanelID:        panelID,
+		QueryCount:     len(req.Queries),
+		Duration:       duration,
+		ResponseBytes:  responseSize(resp),
+		Status:         instrumentationutils.RequestStatusFromQueryDataResponse(resp, err).String(),
+		Created:        start,
+	}
+	if settings := req.PluginContext.DataSourceInstanceSettings; settings != nil {
+		slowQuery.DatasourceUID = settings.UID
+	}
+	if u := req.PluginContext.User; u != nil {
+		slowQuery.UserLogin = u.Login
+	}
+	m.slowQueryLog.Record(ctx, slowQuery)
+
+	return resp, err
+}
+
+// queryOrigin returns the dashboard and panel that issued the queries, from the headers
+// sent by the frontend.
+func queryOrigin(ctx context.Context, req *backend.QueryDataRequest) (string, int64) {
+	header := func(name string) string {
+		if reqCtx := contexthandler.FromContext(ctx); reqCtx != nil && reqCtx.Req != nil {
+			if v := reqCtx.Req.Header.Get(name); v != "" {
+				return v
+			}
+		}
+		return req.GetHTTPHeader(name)
+	}
+
+	panelID, err := strconv.ParseInt(header(query.HeaderPanelID), 10, 64)
+	if err != nil {
+		panelID = 0
+	}
+	return header(query.HeaderDashboardUID), panelID
+}
+
+// responseSize estimates the size of the data frames of the response without encoding them.
+func responseSize(resp *backend.QueryDataResponse) int64 {
+	if resp == nil {
+		return 0
+	}
+
+	var size int64
+	for _, dr := range resp.Responses {
+		for _, frame := range dr.Frames {
+			if frame == nil {
+				continue
+			}
+			for _, field := range frame.Fields {
+				size += fieldSize(field)
+			}
+		}
+	}
+	return size
+}
+
+func fieldSize(field *data.Field) int64 {
+	switch field.Type() {
+	case data.FieldTypeInt8, data.FieldTypeNullableInt8, data.FieldTypeUint8, data.FieldTypeNullableUint8,
+		data.FieldTypeBool, data.FieldTypeNullableBool:
+		return int64(field.Len())
+	case data.FieldTypeInt16, data.FieldTypeNullableInt16, data.FieldTypeUint16, data.FieldTypeNullableUint16:
+		return int64(field.Len()) * 2
+	case data.FieldTypeInt32, data.FieldTypeNullableInt32, data.FieldTypeUint32, data.FieldTypeNullableUint32,
+		data.FieldTypeFloat32, data.FieldTypeNullableFloat32:
+		return int64(field.Len()) * 4
+	case data.FieldTypeString, data.FieldTypeNullableString, data.FieldTypeJSON, data.FieldTypeNullableJSON:
+		var size int64
+		for i := 0; i < field.Len(); i++ {
+			switch v := field.At(i).(type) {
+			case string:
+				size += int64(len(v))
+			case *string:
+				if v != nil {
+					size += int64(len(*v))
+				}
+			case json.RawMessage:
+				size += int64(len(v))
+			case *json.RawMessage:
+				if v != nil {
+					size += int64(len(*v))
+				}
+			}
+		}
+		return size
+	}
+	// 64 bit numbers and times
+	return int64(field.Len()) * 8
+}
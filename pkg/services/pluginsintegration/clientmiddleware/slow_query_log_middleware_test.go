@@ -0,0 +1,92 @@
+package clientmiddleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/handlertest"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/query"
+	"github.com/grafana/grafana/pkg/services/slowquerylog/slowquerylogtest"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestSlowQueryLogMiddleware(t *testing.T) {
+	pluginCtx := backend.PluginContext{
+		OrgID:    2,
+		PluginID: "prometheus",
+		User:     &backend.User{Login: "editor"},
+		DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{
+			UID: "prom",
+		},
+	}
+	frame := data.NewFrame("A",
+		data.NewField("time", nil, []time.Time{time.Unix(1, 0), time.Unix(2, 0)}),
+		data.NewField("value", nil, []float64{1, 2}),
+		data.NewField("host", nil, []string{"edge-1", "edge-22"}),
+	)
+
+	t.Run("Should record slow queries with the dashboard and panel that issued them", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/api/ds/query", nil)
+		require.NoError(t, err)
+		req.Header.Set(query.HeaderDashboardUID, "dash")
+		req.Header.Set(query.HeaderPanelID, "12")
+
+		slowQueryLog := &slowquerylogtest.FakeService{}
+		cdt := handlertest.NewHandlerMiddlewareTest(t,
+			WithReqContext(req, &user.SignedInUser{Login: "editor"}),
+			handlertest.WithMiddlewares(NewSlowQueryLogMiddleware(slowQueryLog, 0)),
+		)
+		cdt.TestHandler.QueryDataFunc = func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+			return &backend.QueryDataResponse{Responses: backend.Responses{"A": {Frames: data.Frames{frame}}}}, nil
+		}
+
+		_, err = cdt.MiddlewareHandler.QueryData(req.Context(), &backend.QueryDataRequest{
+			PluginContext: pluginCtx,
+			Queries:       []backend.DataQuery{{RefID: "A"}},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, slowQueryLog.Recorded, 1)
+		recorded := slowQueryLog.Recorded[0]
+		require.Equal(t, int64(2), recorded.OrgID)
+		require.Equal(t, "prom", recorded.DatasourceUID)
+		require.Equal(t, "prometheus", recorded.DatasourceType)
+		require.Equal(t, "dash", recorded.DashboardUID)
+		require.Equal(t, int64(12), recorded.PanelID)
+		require.Equal(t, "editor", recorded.UserLogin)
+		require.Equal(t, 1, recorded.QueryCount)
+		require.Equal(t, "ok", recorded.Status)
+		// two times and two floats of 8 bytes, and the strings
+		require.Equal(t, int64(2*8+2*8+len("edge-1")+len("edge-22")), recorded.ResponseBytes)
+	})
+
+	t.Run("Should not record queries faster than the threshold", func(t *testing.T) {
+		slowQueryLog := &slowquerylogtest.FakeService{}
+		cdt := handlertest.NewHandlerMiddlewareTest(t,
+			handlertest.WithMiddlewares(NewSlowQueryLogMiddleware(slowQueryLog, time.Hour)),
+		)
+
+		_, err := cdt.MiddlewareHandler.QueryData(context.Background(), &backend.QueryDataRequest{PluginContext: pluginCtx})
+		require.NoError(t, err)
+		require.Empty(t, slowQueryLog.Recorded)
+	})
+
+	t.Run("Should record queries without a dashboard", func(t *testing.T) {
+		slowQueryLog := &slowquerylogtest.FakeService{}
+		cdt := handlertest.NewHandlerMiddlewareTest(t,
+			handlertest.WithMiddlewares(NewSlowQueryLogMiddleware(slowQueryLog, 0)),
+		)
+
+		_, err := cdt.MiddlewareHandler.QueryData(context.Background(), &backend.QueryDataRequest{PluginContext: pluginCtx})
+		require.NoError(t, err)
+		require.Len(t, slowQueryLog.Recorded, 1)
+		require.Empty(t, slowQueryLog.Recorded[0].DashboardUID)
+		require.Zero(t, slowQueryLog.Recorded[0].PanelID)
+	})
+}
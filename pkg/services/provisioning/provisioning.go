@@ -31,9 +31,14 @@ import (
 	"github.com/grafana/grafana/pkg/services/provisioning/dashboards"
 	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
 	"github.com/grafana/grafana/pkg/services/provisioning/plugins"
+	prov_reports "github.com/grafana/grafana/pkg/services/provisioning/reports"
+	prov_teamsync "github.com/grafana/grafana/pkg/services/provisioning/teamsync"
 	"github.com/grafana/grafana/pkg/services/quota"
+	"github.com/grafana/grafana/pkg/services/reporting"
 	"github.com/grafana/grafana/pkg/services/searchV2"
 	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/teamsync"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
@@ -57,6 +62,9 @@ func ProvideService(
 	orgService org.Service,
 	resourcePermissions accesscontrol.ReceiverPermissionsService,
 	tracer tracing.Tracer,
+	teamService team.Service,
+	teamSyncService teamsync.Service,
+	reportingService reporting.Service,
 ) (*ProvisioningServiceImpl, error) {
 	s := &ProvisioningServiceImpl{
 		Cfg:                          cfg,
@@ -70,6 +78,8 @@ func ProvideService(
 		provisionDatasources:         datasources.Provision,
 		provisionPlugins:             plugins.Provision,
 		provisionAlerting:            prov_alerting.Provision,
+		provisionTeamSync:            prov_teamsync.Provision,
+		provisionReports:             prov_reports.Provision,
 		dashboardProvisioningService: dashboardProvisioningService,
 		dashboardService:             dashboardService,
 		datasourceService:            datasourceService,
@@ -83,6 +93,9 @@ func ProvideService(
 		folderService:                folderService,
 		resourcePermissions:          resourcePermissions,
 		tracer:                       tracer,
+		teamService:                  teamService,
+		teamSyncService:              teamSyncService,
+		reportingService:             reportingService,
 	}
 
 	if err := s.setDashboardProvisioner(); err != nil {
@@ -109,6 +122,8 @@ type ProvisioningService interface {
 	ProvisionPlugins(ctx context.Context) error
 	ProvisionDashboards(ctx context.Context) error
 	ProvisionAlerting(ctx context.Context) error
+	ProvisionTeamSync(ctx context.Context) error
+	ProvisionReports(ctx context.Context) error
 	GetDashboardProvisionerResolvedPath(name string) string
 	GetAllowUIUpdatesFromConfig(name string) bool
 }
@@ -125,6 +140,7 @@ func newProvisioningServiceImpl(
 		newDashboardProvisioner: newDashboardProvisioner,
 		provisionDatasources:    provisionDatasources,
 		provisionPlugins:        provisionPlugins,
+		provisionReports:        prov_reports.Provision,
 		Cfg:                     setting.NewCfg(),
 		searchService:           searchService,
 	}
@@ -152,6 +168,8 @@ type ProvisioningServiceImpl struct {
 	provisionDatasources         func(context.Context, string, datasources.BaseDataSourceService, datasources.CorrelationsStore, org.Service) error
 	provisionPlugins             func(context.Context, string, pluginstore.Store, pluginsettings.Service, org.Service) error
 	provisionAlerting            func(context.Context, prov_alerting.ProvisionerConfig) error
+	provisionTeamSync            func(context.Context, string, team.Service, teamsync.Service, org.Service) error
+	provisionReports             func(context.Context, string, reporting.Service, org.Service) error
 	mutex                        sync.Mutex
 	dashboardProvisioningService dashboardservice.DashboardProvisioningService
 	dashboardService             dashboardservice.DashboardService
@@ -164,6 +182,9 @@ type ProvisioningServiceImpl struct {
 	folderService                folder.Service
 	resourcePermissions          accesscontrol.ReceiverPermissionsService
 	tracer                       tracing.Tracer
+	teamService                  team.Service
+	teamSyncService              teamsync.Service
+	reportingService             reporting.Service
 }
 
 func (ps *ProvisioningServiceImpl) RunInitProvisioners(ctx context.Context) error {
@@ -179,6 +200,12 @@ func (ps *ProvisioningServiceImpl) RunInitProvisioners(ctx context.Context) erro
 		return err
 	}
 
+	err = ps.ProvisionTeamSync(ctx)
+	if err != nil {
+		ps.log.Error("Failed to provision team sync", "error", err)
+		return err
+	}
+
 	err = ps.ProvisionAlerting(ctx)
 	if err != nil {
 		ps.log.Error("Failed to provision alerting", "error", err)
@@ -202,6 +229,11 @@ func (ps *ProvisioningServiceImpl) Run(ctx context.Context) error {
 		ps.searchService.TriggerReIndex()
 	}
 
+	// reports are provisioned after the dashboards they render
+	if err := ps.ProvisionReports(ctx); err != nil {
+		return err
+	}
+
 	for {
 		// Wait for unlock. This is tied to new dashboardProvisioner to be instantiated before we start polling.
 		ps.mutex.Lock()
@@ -244,6 +276,26 @@ func (ps *ProvisioningServiceImpl) ProvisionPlugins(ctx context.Context) error {
 	return nil
 }
 
+func (ps *ProvisioningServiceImpl) ProvisionTeamSync(ctx context.Context) error {
+	teamSyncPath := filepath.Join(ps.Cfg.ProvisioningPath, "teamsync")
+	if err := ps.provisionTeamSync(ctx, teamSyncPath, ps.teamService, ps.teamSyncService, ps.orgService); err != nil {
+		err = fmt.Errorf("%v: %w", "team sync provisioning error", err)
+		ps.log.Error("Failed to provision team sync", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (ps *ProvisioningServiceImpl) ProvisionReports(ctx context.Context) error {
+	reportsPath := filepath.Join(ps.Cfg.ProvisioningPath, "reports")
+	if err := ps.provisionReports(ctx, reportsPath, ps.reportingService, ps.orgService); err != nil {
+		err = fmt.Errorf("%v: %w", "reports provisioning error", err)
+		ps.log.Error("Failed to provision reports", "error", err)
+		return err
+	}
+	return nil
+}
+
 func (ps *ProvisioningServiceImpl) ProvisionDashboards(ctx context.Context) error {
 	err := ps.setDashboardProvisioner()
 	if err != nil {
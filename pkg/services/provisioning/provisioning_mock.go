@@ -8,6 +8,8 @@ type Calls struct {
 	ProvisionPlugins                    []any
 	ProvisionDashboards                 []any
 	ProvisionAlerting                   []any
+	ProvisionTeamSync                   []any
+	ProvisionReports                    []any
 	GetDashboardProvisionerResolvedPath []any
 	GetAllowUIUpdatesFromConfig         []any
 	Run                                 []any
@@ -67,6 +69,16 @@ func (mock *ProvisioningServiceMock) ProvisionAlerting(ctx context.Context) erro
 	return nil
 }
 
+func (mock *ProvisioningServiceMock) ProvisionTeamSync(ctx context.Context) error {
+	mock.Calls.ProvisionTeamSync = append(mock.Calls.ProvisionTeamSync, nil)
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) ProvisionReports(ctx context.Context) error {
+	mock.Calls.ProvisionReports = append(mock.Calls.ProvisionReports, nil)
+	return nil
+}
+
 func (mock *ProvisioningServiceMock) GetDashboardProvisionerResolvedPath(name string) string {
 	mock.Calls.GetDashboardProvisionerResolvedPath = append(mock.Calls.GetDashboardProvisionerResolvedPath, name)
 	if mock.GetDashboardProvisionerResolvedPathFunc != nil {
@@ -0,0 +1,132 @@
+package reports
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+type configReader interface {
+	readConfig(path string) ([]*reportsAsConfig, error)
+}
+
+type configReaderImpl struct {
+	log log.Logger
+}
+
+func newConfigReader(logger log.Logger) configReader {
+	return &configReaderImpl{log: logger}
+}
+
+func (cr *configReaderImpl) readConfig(path string) ([]*reportsAsConfig, error) {
+	var reports []*reportsAsConfig
+	cr.log.Debug("Looking for report provisioning files", "path", path)
+
+	files, err := os.ReadDir(path)
+	if err != nil {
+		cr.log.Error("Failed to read report provisioning files from directory", "path", path, "error", err)
+		return reports, nil
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml") {
+			cr.log.Debug("Parsing report provisioning file", "path", path, "file.Name", file.Name())
+			cfg, err := cr.parseReportConfig(path, file)
+			if err != nil {
+				return nil, err
+			}
+
+			if cfg != nil {
+				reports = append(reports, cfg)
+			}
+		}
+	}
+
+	cr.log.Debug("Validating reports")
+	if err := validateRequiredField(reports); err != nil {
+		return nil, err
+	}
+
+	checkOrgIDAndOrgName(reports)
+
+	return reports, nil
+}
+
+func (cr *configReaderImpl) parseReportConfig(path string, file fs.DirEntry) (*reportsAsConfig, error) {
+	filename, err := filepath.Abs(filepath.Join(path, file.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+	yamlFile, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *reportsAsConfigV0
+	err = yaml.Unmarshal(yamlFile, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.mapToReportsFromConfig(), nil
+}
+
+// validateRequiredField checks the fields a report needs to be found again on the next provisioning,
+// the rest of the configuration is validated by the reporting service.
+func validateRequiredField(reports []*reportsAsConfig) error {
+	for i := range reports {
+		errs := []error{}
+		for index, report := range reports[i].Reports {
+			if report.UID == "" {
+				errs = append(errs, fmt.Errorf("report item %d in configuration doesn't contain required field uid", index+1))
+			}
+			if report.Name == "" {
+				errs = append(errs, fmt.Errorf("report item %d in configuration doesn't contain required field name", index+1))
+			}
+		}
+		for index, report := range reports[i].DeleteReports {
+			if report.UID == "" {
+				errs = append(errs, fmt.Errorf("delete report item %d in configuration doesn't contain required field uid", index+1))
+			}
+		}
+
+		if len(errs) != 0 {
+			return errors.Join(errs...)
+		}
+	}
+
+	return nil
+}
+
+func checkOrgIDAndOrgName(reports []*reportsAsConfig) {
+	for i := range reports {
+		for _, report := range reports[i].Reports {
+			report.OrgID = orgIDFromConfig(report.OrgID, report.OrgName)
+		}
+		for _, report := range reports[i].DeleteReports {
+			report.OrgID = orgIDFromConfig(report.OrgID, report.OrgName)
+		}
+	}
+}
+
+// orgIDFromConfig defaults to the main organization when neither the organization id nor its name are set,
+// 0 means the organization is looked up by name.
+func orgIDFromConfig(orgID int64, orgName string) int64 {
+	if orgID >= 1 {
+		return orgID
+	}
+	if orgName == "" {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package reports
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/reporting"
+)
+
+const (
+	brokenYaml        = "./testdata/test-configs/broken-yaml"
+	missingUID        = "./testdata/test-configs/missing-uid"
+	emptyFolder       = "./testdata/test-configs/empty_folder"
+	correctProperties = "./testdata/test-configs/correct-properties"
+)
+
+func TestConfigReader(t *testing.T) {
+	t.Run("Broken yaml should return error", func(t *testing.T) {
+		reader := newConfigReader(log.New("test logger"))
+		_, err := reader.readConfig(brokenYaml)
+		require.Error(t, err)
+	})
+
+	t.Run("Skip invalid directory", func(t *testing.T) {
+		reader := newConfigReader(log.New("test logger"))
+		cfg, err := reader.readConfig(emptyFolder)
+		require.NoError(t, err)
+		require.Len(t, cfg, 0)
+	})
+
+	t.Run("Report without uid should return error", func(t *testing.T) {
+		reader := newConfigReader(log.New("test logger"))
+		_, err := reader.readConfig(missingUID)
+		require.Error(t, err)
+		require.Equal(t, "report item 1 in configuration doesn't contain required field uid", err.Error())
+	})
+
+	t.Run("Can read correct properties", func(t *testing.T) {
+		reader := newConfigReader(log.New("test logger"))
+		cfg, err := reader.readConfig(correctProperties)
+		require.NoError(t, err)
+		require.Len(t, cfg, 1)
+
+		reports := cfg[0].Reports
+		require.Len(t, reports, 2)
+
+		require.Equal(t, "weekly-management", reports[0].UID)
+		require.Equal(t, int64(2), reports[0].OrgID)
+		require.Equal(t, reporting.ReportConfig{
+			Name:         "Weekly management report",
+			DashboardUID: "business-overview",
+			TimeRange:    reporting.TimeRange{From: "now-7d/d", To: "now/d"},
+			Variables:    map[string][]string{"region": {"eu", "us"}},
+			Schedule:     "0 8 * * 1",
+			Timezone:     "Europe/Paris",
+			Recipients:   []string{"management@example.com"},
+			Message:      "Last week's figures",
+			Enabled:      true,
+		}, reports[0].ReportConfig)
+
+		require.Equal(t, "daily-errors", reports[1].UID)
+		require.Equal(t, int64(0), reports[1].OrgID)
+		require.Equal(t, "Main Org.", reports[1].OrgName)
+		require.Equal(t, []int64{2, 4}, reports[1].PanelIDs)
+		require.Equal(t, reporting.FormatPNG, reports[1].Format)
+		require.Equal(t, "https://chat.example.com/hooks/reports", reports[1].WebhookURL)
+		require.False(t, reports[1].Enabled)
+
+		require.Len(t, cfg[0].DeleteReports, 1)
+		require.Equal(t, "old-report", cfg[0].DeleteReports[0].UID)
+		require.Equal(t, int64(1), cfg[0].DeleteReports[0].OrgID)
+	})
+}
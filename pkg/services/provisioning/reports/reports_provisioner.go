@@ -0,0 +1,141 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/reporting"
+)
+
+// Provision scans a directory for provisioning config files
+// and provisions the reports in those files.
+func Provision(ctx context.Context, configDirectory string, reportingService reporting.Service, orgService org.Service) error {
+	logger := log.New("provisioning.reports")
+	rp := ReportsProvisioner{
+		log:              logger,
+		cfgProvider:      newConfigReader(logger),
+		reportingService: reportingService,
+		orgService:       orgService,
+	}
+	return rp.applyChanges(ctx, configDirectory)
+}
+
+// ReportsProvisioner is responsible for provisioning reports based on
+// configuration read by the `configReader`
+type ReportsProvisioner struct {
+	log              log.Logger
+	cfgProvider      configReader
+	reportingService reporting.Service
+	orgService       org.Service
+}
+
+func (rp *ReportsProvisioner) apply(ctx context.Context, cfg *reportsAsConfig) error {
+	for _, r := range cfg.DeleteReports {
+		orgID, err := rp.resolveOrgID(ctx, r.OrgID, r.OrgName)
+		if err != nil {
+			return err
+		}
+
+		rp.log.Info("Deleting report from configuration", "uid", r.UID, "orgId", orgID)
+		err = rp.reportingService.DeleteReport(ctx, orgID, r.UID)
+		if err != nil && !errors.Is(err, reporting.ErrReportNotFound) {
+			return err
+		}
+	}
+
+	for _, r := range cfg.Reports {
+		orgID, err := rp.resolveOrgID(ctx, r.OrgID, r.OrgName)
+		if err != nil {
+			return err
+		}
+
+		existing, err := rp.reportingService.GetReport(ctx, orgID, r.UID)
+		if errors.Is(err, reporting.ErrReportNotFound) {
+			rp.log.Info("Creating report from configuration", "uid", r.UID, "name", r.Name, "orgId", orgID)
+			if _, err := rp.reportingService.CreateReport(ctx, &reporting.CreateReportCommand{
+				UID:          r.UID,
+				OrgID:        orgID,
+				ReportConfig: r.ReportConfig,
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// saving a report schedules its next delivery again, unchanged reports keep the delivery they are due for
+		if existing.UserID == 0 && sameConfig(existing.ReportConfig, r.ReportConfig) {
+			continue
+		}
+
+		rp.log.Info("Updating report from configuration", "uid", r.UID, "name", r.Name, "orgId", orgID)
+		if _, err := rp.reportingService.UpdateReport(ctx, &reporting.UpdateReportCommand{
+			UID:          r.UID,
+			OrgID:        orgID,
+			ReportConfig: r.ReportConfig,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (rp *ReportsProvisioner) resolveOrgID(ctx context.Context, orgID int64, orgName string) (int64, error) {
+	if orgID != 0 || orgName == "" {
+		return orgID, nil
+	}
+
+	res, err := rp.orgService.GetByName(ctx, &org.GetOrgByNameQuery{Name: orgName})
+	if err != nil {
+		return 0, err
+	}
+	return res.ID, nil
+}
+
+func (rp *ReportsProvisioner) applyChanges(ctx context.Context, configPath string) error {
+	configs, err := rp.cfgProvider.readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	if len(configs) > 0 && !rp.reportingService.IsEnabled() {
+		rp.log.Warn("Reporting is disabled, skipping report provisioning")
+		return nil
+	}
+
+	for _, cfg := range configs {
+		if err := rp.apply(ctx, cfg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sameConfig compares a stored report configuration with a provisioned one, the empty values of the provisioned
+// one are replaced by the defaults the reporting service stores
+func sameConfig(stored reporting.ReportConfig, provisioned reporting.ReportConfig) bool {
+	if provisioned.Format == "" {
+		provisioned.Format = reporting.FormatPDF
+	}
+	return reflect.DeepEqual(normalizeConfig(stored), normalizeConfig(provisioned))
+}
+
+func normalizeConfig(config reporting.ReportConfig) reporting.ReportConfig {
+	if len(config.PanelIDs) == 0 {
+		config.PanelIDs = nil
+	}
+	if len(config.Variables) == 0 {
+		config.Variables = nil
+	}
+	if len(config.Recipients) == 0 {
+		config.Recipients = nil
+	}
+	return config
+}
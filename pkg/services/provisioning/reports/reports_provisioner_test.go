@@ -0,0 +1,126 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgtest"
+	"github.com/grafana/grafana/pkg/services/reporting"
+	"github.com/grafana/grafana/pkg/services/reporting/reportingtest"
+)
+
+func TestReportsProvisioner(t *testing.T) {
+	weekly := reporting.ReportConfig{
+		Name:         "Weekly management report",
+		DashboardUID: "business-overview",
+		Schedule:     "0 8 * * 1",
+		Recipients:   []string{"management@example.com"},
+		Enabled:      true,
+	}
+
+	t.Run("Should return error when config reader returns error", func(t *testing.T) {
+		expectedErr := errors.New("test")
+		rp := ReportsProvisioner{log: log.New("test"), cfgProvider: &testConfigReader{err: expectedErr}}
+		err := rp.applyChanges(context.Background(), "")
+		require.Equal(t, expectedErr, err)
+	})
+
+	t.Run("Should skip provisioning when reporting is disabled", func(t *testing.T) {
+		reportingSvc := &reportingtest.FakeService{}
+		rp := ReportsProvisioner{
+			log:              log.New("test"),
+			cfgProvider:      &testConfigReader{result: []*reportsAsConfig{{Reports: []*reportFromConfig{{UID: "weekly", OrgID: 1, ReportConfig: weekly}}}}},
+			reportingService: reportingSvc,
+		}
+
+		err := rp.applyChanges(context.Background(), "")
+		require.NoError(t, err)
+		require.Empty(t, reportingSvc.CreatedReports)
+	})
+
+	t.Run("Should create missing report in the organization looked up by name", func(t *testing.T) {
+		orgMock := orgtest.NewOrgServiceFake()
+		orgMock.ExpectedOrg = &org.Org{ID: 4}
+		reportingSvc := &reportingtest.FakeService{ExpectedEnabled: true}
+		rp := ReportsProvisioner{
+			log:              log.New("test"),
+			cfgProvider:      &testConfigReader{result: []*reportsAsConfig{{Reports: []*reportFromConfig{{UID: "weekly", OrgName: "Org 4", ReportConfig: weekly}}}}},
+			reportingService: reportingSvc,
+			orgService:       orgMock,
+		}
+
+		err := rp.applyChanges(context.Background(), "")
+		require.NoError(t, err)
+		require.Equal(t, []*reporting.CreateReportCommand{{UID: "weekly", OrgID: 4, ReportConfig: weekly}}, reportingSvc.CreatedReports)
+		require.Empty(t, reportingSvc.UpdatedReports)
+	})
+
+	t.Run("Should not save unchanged provisioned report", func(t *testing.T) {
+		stored := weekly
+		stored.Format = reporting.FormatPDF
+		stored.PanelIDs = []int64{}
+		reportingSvc := &reportingtest.FakeService{
+			ExpectedEnabled: true,
+			ExpectedReport:  &reporting.Report{UID: "weekly", OrgID: 1, ReportConfig: stored},
+		}
+		rp := ReportsProvisioner{
+			log:              log.New("test"),
+			cfgProvider:      &testConfigReader{result: []*reportsAsConfig{{Reports: []*reportFromConfig{{UID: "weekly", OrgID: 1, ReportConfig: weekly}}}}},
+			reportingService: reportingSvc,
+		}
+
+		err := rp.applyChanges(context.Background(), "")
+		require.NoError(t, err)
+		require.Empty(t, reportingSvc.CreatedReports)
+		require.Empty(t, reportingSvc.UpdatedReports)
+	})
+
+	t.Run("Should update report changed in the configuration or saved by a user", func(t *testing.T) {
+		changed := weekly
+		changed.Schedule = "0 9 * * 1"
+		for name, existing := range map[string]*reporting.Report{
+			"changed":       {UID: "weekly", OrgID: 1, ReportConfig: changed},
+			"saved by user": {UID: "weekly", OrgID: 1, UserID: 3, ReportConfig: weekly},
+		} {
+			t.Run(name, func(t *testing.T) {
+				reportingSvc := &reportingtest.FakeService{ExpectedEnabled: true, ExpectedReport: existing}
+				rp := ReportsProvisioner{
+					log:              log.New("test"),
+					cfgProvider:      &testConfigReader{result: []*reportsAsConfig{{Reports: []*reportFromConfig{{UID: "weekly", OrgID: 1, ReportConfig: weekly}}}}},
+					reportingService: reportingSvc,
+				}
+
+				err := rp.applyChanges(context.Background(), "")
+				require.NoError(t, err)
+				require.Equal(t, []*reporting.UpdateReportCommand{{UID: "weekly", OrgID: 1, ReportConfig: weekly}}, reportingSvc.UpdatedReports)
+			})
+		}
+	})
+
+	t.Run("Should delete reports and ignore the missing ones", func(t *testing.T) {
+		reportingSvc := &reportingtest.FakeService{ExpectedEnabled: true, ExpectedErr: reporting.ErrReportNotFound.Errorf("not found")}
+		rp := ReportsProvisioner{
+			log:              log.New("test"),
+			cfgProvider:      &testConfigReader{result: []*reportsAsConfig{{DeleteReports: []*deleteReportConfig{{UID: "old-report", OrgID: 1}}}}},
+			reportingService: reportingSvc,
+		}
+
+		err := rp.applyChanges(context.Background(), "")
+		require.NoError(t, err)
+		require.Equal(t, []string{"old-report"}, reportingSvc.DeletedReports)
+	})
+}
+
+type testConfigReader struct {
+	result []*reportsAsConfig
+	err    error
+}
+
+func (tcr *testConfigReader) readConfig(_ string) ([]*reportsAsConfig, error) {
+	return tcr.result, tcr.err
+}
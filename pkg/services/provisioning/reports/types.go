@@ -0,0 +1,124 @@
+package reports
+
+import (
+	"github.com/grafana/grafana/pkg/services/provisioning/values"
+	"github.com/grafana/grafana/pkg/services/reporting"
+)
+
+// reportsAsConfig is a normalized data object for reports config data. Any config version should be mappable
+// to this type.
+type reportsAsConfig struct {
+	Reports       []*reportFromConfig
+	DeleteReports []*deleteReportConfig
+}
+
+type reportFromConfig struct {
+	UID     string
+	OrgID   int64
+	OrgName string
+	reporting.ReportConfig
+}
+
+type deleteReportConfig struct {
+	UID     string
+	OrgID   int64
+	OrgName string
+}
+
+type reportFromConfigV0 struct {
+	UID          values.StringValue              `json:"uid" yaml:"uid"`
+	OrgID        values.Int64Value               `json:"orgId" yaml:"orgId"`
+	OrgName      values.StringValue              `json:"orgName" yaml:"orgName"`
+	Name         values.StringValue              `json:"name" yaml:"name"`
+	DashboardUID values.StringValue              `json:"dashboardUid" yaml:"dashboardUid"`
+	PanelIDs     []values.Int64Value             `json:"panelIds" yaml:"panelIds"`
+	From         values.StringValue              `json:"from" yaml:"from"`
+	To           values.StringValue              `json:"to" yaml:"to"`
+	Variables    map[string][]values.StringValue `json:"variables" yaml:"variables"`
+	Schedule     values.StringValue              `json:"schedule" yaml:"schedule"`
+	Timezone     values.StringValue              `json:"timezone" yaml:"timezone"`
+	Format       values.StringValue              `json:"format" yaml:"format"`
+	Recipients   []values.StringValue            `json:"recipients" yaml:"recipients"`
+	WebhookURL   values.StringValue              `json:"webhookUrl" yaml:"webhookUrl"`
+	Message      values.StringValue              `json:"message" yaml:"message"`
+	// Enabled defaults to true
+	Enabled *values.BoolValue `json:"enabled" yaml:"enabled"`
+}
+
+type deleteReportConfigV0 struct {
+	UID     values.StringValue `json:"uid" yaml:"uid"`
+	OrgID   values.Int64Value  `json:"orgId" yaml:"orgId"`
+	OrgName values.StringValue `json:"orgName" yaml:"orgName"`
+}
+
+// reportsAsConfigV0 is a mapping for zero version configs. This is mapped to its normalised version.
+type reportsAsConfigV0 struct {
+	Reports       []*reportFromConfigV0   `json:"reports" yaml:"reports"`
+	DeleteReports []*deleteReportConfigV0 `json:"deleteReports" yaml:"deleteReports"`
+}
+
+// mapToReportsFromConfig maps config syntax to a normalized reportsAsConfig object. Every version
+// of the config syntax should have this function.
+func (cfg *reportsAsConfigV0) mapToReportsFromConfig() *reportsAsConfig {
+	r := &reportsAsConfig{}
+	if cfg == nil {
+		return r
+	}
+
+	for _, report := range cfg.Reports {
+		var panelIDs []int64
+		for _, id := range report.PanelIDs {
+			panelIDs = append(panelIDs, id.Value())
+		}
+
+		var variables map[string][]string
+		if len(report.Variables) > 0 {
+			variables = make(map[string][]string, len(report.Variables))
+			for name, vals := range report.Variables {
+				for _, v := range vals {
+					variables[name] = append(variables[name], v.Value())
+				}
+			}
+		}
+
+		var recipients []string
+		for _, recipient := range report.Recipients {
+			recipients = append(recipients, recipient.Value())
+		}
+
+		enabled := true
+		if report.Enabled != nil {
+			enabled = report.Enabled.Value()
+		}
+
+		r.Reports = append(r.Reports, &reportFromConfig{
+			UID:     report.UID.Value(),
+			OrgID:   report.OrgID.Value(),
+			OrgName: report.OrgName.Value(),
+			ReportConfig: reporting.ReportConfig{
+				Name:         report.Name.Value(),
+				DashboardUID: report.DashboardUID.Value(),
+				PanelIDs:     panelIDs,
+				TimeRange:    reporting.TimeRange{From: report.From.Value(), To: report.To.Value()},
+				Variables:    variables,
+				Schedule:     report.Schedule.Value(),
+				Timezone:     report.Timezone.Value(),
+				Format:       reporting.Format(report.Format.Value()),
+				Recipients:   recipients,
+				WebhookURL:   report.WebhookURL.Value(),
+				Message:      report.Message.Value(),
+				Enabled:      enabled,
+			},
+		})
+	}
+
+	for _, report := range cfg.DeleteReports {
+		r.DeleteReports = append(r.DeleteReports, &deleteReportConfig{
+			UID:     report.UID.Value(),
+			OrgID:   report.OrgID.Value(),
+			OrgName: report.OrgName.Value(),
+		})
+	}
+
+	return r
+}
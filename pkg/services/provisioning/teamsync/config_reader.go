@@ -0,0 +1,119 @@
+package teamsync
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+type configReader interface {
+	readConfig(path string) ([]*teamsAsConfig, error)
+}
+
+type configReaderImpl struct {
+	log log.Logger
+}
+
+func newConfigReader(logger log.Logger) configReader {
+	return &configReaderImpl{log: logger}
+}
+
+func (cr *configReaderImpl) readConfig(path string) ([]*teamsAsConfig, error) {
+	var teams []*teamsAsConfig
+	cr.log.Debug("Looking for team sync provisioning files", "path", path)
+
+	files, err := os.ReadDir(path)
+	if err != nil {
+		cr.log.Error("Failed to read team sync provisioning files from directory", "path", path, "error", err)
+		return teams, nil
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml") {
+			cr.log.Debug("Parsing team sync provisioning file", "path", path, "file.Name", file.Name())
+			cfg, err := cr.parseTeamSyncConfig(path, file)
+			if err != nil {
+				return nil, err
+			}
+
+			if cfg != nil {
+				teams = append(teams, cfg)
+			}
+		}
+	}
+
+	cr.log.Debug("Validating team sync")
+	if err := validateRequiredField(teams); err != nil {
+		return nil, err
+	}
+
+	checkOrgIDAndOrgName(teams)
+
+	return teams, nil
+}
+
+func (cr *configReaderImpl) parseTeamSyncConfig(path string, file fs.DirEntry) (*teamsAsConfig, error) {
+	filename, err := filepath.Abs(filepath.Join(path, file.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+	yamlFile, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *teamsAsConfigV0
+	err = yaml.Unmarshal(yamlFile, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.mapToTeamsFromConfig(), nil
+}
+
+func validateRequiredField(teams []*teamsAsConfig) error {
+	for i := range teams {
+		errs := []error{}
+		for index, team := range teams[i].Teams {
+			if team.Name == "" {
+				errs = append(errs, fmt.Errorf("team item %d in configuration doesn't contain required field name", index+1))
+			}
+			for _, group := range team.Groups {
+				if strings.TrimSpace(group) == "" {
+					errs = append(errs, fmt.Errorf("team item %d in configuration contains an empty group", index+1))
+					break
+				}
+			}
+		}
+
+		if len(errs) != 0 {
+			return errors.Join(errs...)
+		}
+	}
+
+	return nil
+}
+
+func checkOrgIDAndOrgName(teams []*teamsAsConfig) {
+	for i := range teams {
+		for _, team := range teams[i].Teams {
+			if team.OrgID < 1 {
+				if team.OrgName == "" {
+					team.OrgID = 1
+				} else {
+					team.OrgID = 0
+				}
+			}
+		}
+	}
+}
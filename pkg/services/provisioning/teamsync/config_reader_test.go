@@ -0,0 +1,62 @@
+package teamsync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+const (
+	brokenYaml        = "./testdata/test-configs/broken-yaml"
+	missingName       = "./testdata/test-configs/missing-name"
+	emptyFolder       = "./testdata/test-configs/empty_folder"
+	correctProperties = "./testdata/test-configs/correct-properties"
+)
+
+func TestConfigReader(t *testing.T) {
+	t.Run("Broken yaml should return error", func(t *testing.T) {
+		reader := newConfigReader(log.New("test logger"))
+		_, err := reader.readConfig(brokenYaml)
+		require.Error(t, err)
+	})
+
+	t.Run("Skip invalid directory", func(t *testing.T) {
+		reader := newConfigReader(log.New("test logger"))
+		cfg, err := reader.readConfig(emptyFolder)
+		require.NoError(t, err)
+		require.Len(t, cfg, 0)
+	})
+
+	t.Run("Team without name should return error", func(t *testing.T) {
+		reader := newConfigReader(log.New("test logger"))
+		_, err := reader.readConfig(missingName)
+		require.Error(t, err)
+		require.Equal(t, "team item 1 in configuration doesn't contain required field name", err.Error())
+	})
+
+	t.Run("Can read correct properties", func(t *testing.T) {
+		reader := newConfigReader(log.New("test logger"))
+		cfg, err := reader.readConfig(correctProperties)
+		require.NoError(t, err)
+		require.Len(t, cfg, 1)
+
+		teams := cfg[0].Teams
+		require.Len(t, teams, 3)
+
+		require.Equal(t, "Engineering", teams[0].Name)
+		require.Equal(t, int64(2), teams[0].OrgID)
+		require.Equal(t, "engineering@example.com", teams[0].Email)
+		require.Equal(t, []string{"cn=engineering,ou=groups,dc=grafana,dc=org", "engineering"}, teams[0].Groups)
+
+		require.Equal(t, "Support", teams[1].Name)
+		require.Equal(t, int64(0), teams[1].OrgID)
+		require.Equal(t, "Main Org.", teams[1].OrgName)
+		require.Equal(t, []string{"support"}, teams[1].Groups)
+
+		require.Equal(t, "Operations", teams[2].Name)
+		require.Equal(t, int64(1), teams[2].OrgID)
+		require.Empty(t, teams[2].Groups)
+	})
+}
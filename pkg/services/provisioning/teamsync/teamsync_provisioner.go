@@ -0,0 +1,160 @@
+package teamsync
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/teamsync"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+// Provision scans a directory for provisioning config files
+// and provisions the teams and their groups in those files.
+func Provision(ctx context.Context, configDirectory string, teamService team.Service, teamSyncService teamsync.Service, orgService org.Service) error {
+	logger := log.New("provisioning.teamsync")
+	tp := TeamSyncProvisioner{
+		log:             logger,
+		cfgProvider:     newConfigReader(logger),
+		teamService:     teamService,
+		teamSyncService: teamSyncService,
+		orgService:      orgService,
+	}
+	return tp.applyChanges(ctx, configDirectory)
+}
+
+// TeamSyncProvisioner is responsible for provisioning teams and the external groups
+// mapped to them based on configuration read by the `configReader`
+type TeamSyncProvisioner struct {
+	log             log.Logger
+	cfgProvider     configReader
+	teamService     team.Service
+	teamSyncService teamsync.Service
+	orgService      org.Service
+}
+
+func (tp *TeamSyncProvisioner) apply(ctx context.Context, cfg *teamsAsConfig) error {
+	for _, t := range cfg.Teams {
+		if t.OrgID == 0 && t.OrgName != "" {
+			res, err := tp.orgService.GetByName(ctx, &org.GetOrgByNameQuery{Name: t.OrgName})
+			if err != nil {
+				return err
+			}
+			t.OrgID = res.ID
+		}
+
+		teamID, err := tp.getOrCreateTeam(ctx, t)
+		if err != nil {
+			return err
+		}
+
+		if err := tp.syncGroups(ctx, t.OrgID, teamID, t.Groups); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tp *TeamSyncProvisioner) getOrCreateTeam(ctx context.Context, t *teamFromConfig) (int64, error) {
+	res, err := tp.teamService.SearchTeams(ctx, &team.SearchTeamsQuery{
+		OrgID:        t.OrgID,
+		Name:         t.Name,
+		Limit:        1,
+		Page:         1,
+		SignedInUser: provisioningUser(t.OrgID),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(res.Teams) > 0 {
+		return res.Teams[0].ID, nil
+	}
+
+	tp.log.Info("Creating team from configuration", "name", t.Name, "orgId", t.OrgID)
+	created, err := tp.teamService.CreateTeam(ctx, t.Name, t.Email, t.OrgID)
+	if err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+// syncGroups makes the groups mapped to the team match the configured ones.
+func (tp *TeamSyncProvisioner) syncGroups(ctx context.Context, orgID, teamID int64, groups []string) error {
+	existing, err := tp.teamSyncService.GetTeamGroups(ctx, &teamsync.GetTeamGroupsQuery{OrgID: orgID, TeamID: teamID})
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		wanted[strings.ToLower(strings.TrimSpace(group))] = true
+	}
+
+	current := make(map[string]bool, len(existing))
+	for _, group := range existing {
+		key := strings.ToLower(group.GroupID)
+		current[key] = true
+		if wanted[key] {
+			continue
+		}
+
+		tp.log.Info("Removing group from team", "teamId", teamID, "orgId", orgID, "groupId", group.GroupID)
+		if err := tp.teamSyncService.RemoveTeamGroup(ctx, &teamsync.RemoveTeamGroupCommand{
+			OrgID:   orgID,
+			TeamID:  teamID,
+			GroupID: group.GroupID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, group := range groups {
+		group = strings.TrimSpace(group)
+		key := strings.ToLower(group)
+		if current[key] {
+			continue
+		}
+		current[key] = true
+
+		tp.log.Info("Adding group to team", "teamId", teamID, "orgId", orgID, "groupId", group)
+		if err := tp.teamSyncService.AddTeamGroup(ctx, &teamsync.AddTeamGroupCommand{
+			OrgID:   orgID,
+			TeamID:  teamID,
+			GroupID: group,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tp *TeamSyncProvisioner) applyChanges(ctx context.Context, configPath string) error {
+	configs, err := tp.cfgProvider.readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range configs {
+		if err := tp.apply(ctx, cfg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// provisioningUser is the identity teams are looked up with, provisioning manages every team of the organization.
+func provisioningUser(orgID int64) *user.SignedInUser {
+	return &user.SignedInUser{
+		OrgID: orgID,
+		Login: "provisioning",
+		Permissions: map[int64]map[string][]string{
+			orgID: {ac.ActionTeamsRead: {ac.ScopeTeamsAll}},
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package teamsync
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgtest"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/team/teamtest"
+	"github.com/grafana/grafana/pkg/services/teamsync"
+	"github.com/grafana/grafana/pkg/services/teamsync/teamsynctest"
+)
+
+func TestTeamSyncProvisioner(t *testing.T) {
+	t.Run("Should return error when config reader returns error", func(t *testing.T) {
+		expectedErr := errors.New("test")
+		tp := TeamSyncProvisioner{log: log.New("test"), cfgProvider: &testConfigReader{err: expectedErr}}
+		err := tp.applyChanges(context.Background(), "")
+		require.Equal(t, expectedErr, err)
+	})
+
+	t.Run("Should create missing team and make its groups match the configuration", func(t *testing.T) {
+		cfg := []*teamsAsConfig{
+			{
+				Teams: []*teamFromConfig{
+					{Name: "Engineering", OrgName: "Org 4", Groups: []string{"engineering", " Platform "}},
+				},
+			},
+		}
+		orgMock := orgtest.NewOrgServiceFake()
+		orgMock.ExpectedOrg = &org.Org{ID: 4}
+		teamSvc := &teamtest.FakeService{ExpectedTeam: team.Team{ID: 7, OrgID: 4}}
+		teamSyncSvc := &teamsynctest.FakeService{
+			ExpectedTeamGroups: []*teamsync.TeamGroupDTO{
+				{OrgID: 4, TeamID: 7, GroupID: "Engineering"},
+				{OrgID: 4, TeamID: 7, GroupID: "removed"},
+			},
+		}
+		tp := TeamSyncProvisioner{
+			log:             log.New("test"),
+			cfgProvider:     &testConfigReader{result: cfg},
+			teamService:     teamSvc,
+			teamSyncService: teamSyncSvc,
+			orgService:      orgMock,
+		}
+
+		err := tp.applyChanges(context.Background(), "")
+		require.NoError(t, err)
+
+		require.Equal(t, []*teamsync.RemoveTeamGroupCommand{{OrgID: 4, TeamID: 7, GroupID: "removed"}}, teamSyncSvc.RemoveTeamGroupCalls)
+		require.Equal(t, []*teamsync.AddTeamGroupCommand{{OrgID: 4, TeamID: 7, GroupID: "Platform"}}, teamSyncSvc.AddTeamGroupCalls)
+	})
+}
+
+type testConfigReader struct {
+	result []*teamsAsConfig
+	err    error
+}
+
+func (tcr *testConfigReader) readConfig(_ string) ([]*teamsAsConfig, error) {
+	return tcr.result, tcr.err
+}
@@ -0,0 +1,56 @@
+package teamsync
+
+import "github.com/grafana/grafana/pkg/services/provisioning/values"
+
+// teamsAsConfig is a normalized data object for team sync config data. Any config version should be mappable
+// to this type.
+type teamsAsConfig struct {
+	Teams []*teamFromConfig
+}
+
+type teamFromConfig struct {
+	OrgID   int64
+	OrgName string
+	Name    string
+	Email   string
+	Groups  []string
+}
+
+type teamFromConfigV0 struct {
+	OrgID   values.Int64Value    `json:"orgId" yaml:"orgId"`
+	OrgName values.StringValue   `json:"orgName" yaml:"orgName"`
+	Name    values.StringValue   `json:"name" yaml:"name"`
+	Email   values.StringValue   `json:"email" yaml:"email"`
+	Groups  []values.StringValue `json:"groups" yaml:"groups"`
+}
+
+// teamsAsConfigV0 is a mapping for zero version configs. This is mapped to its normalised version.
+type teamsAsConfigV0 struct {
+	Teams []*teamFromConfigV0 `json:"teams" yaml:"teams"`
+}
+
+// mapToTeamsFromConfig maps config syntax to a normalized teamsAsConfig object. Every version
+// of the config syntax should have this function.
+func (cfg *teamsAsConfigV0) mapToTeamsFromConfig() *teamsAsConfig {
+	r := &teamsAsConfig{}
+	if cfg == nil {
+		return r
+	}
+
+	for _, team := range cfg.Teams {
+		groups := make([]string, 0, len(team.Groups))
+		for _, group := range team.Groups {
+			groups = append(groups, group.Value())
+		}
+
+		r.Teams = append(r.Teams, &teamFromConfig{
+			OrgID:   team.OrgID.Value(),
+			OrgName: team.OrgName.Value(),
+			Name:    team.Name.Value(),
+			Email:   team.Email.Value(),
+			Groups:  groups,
+		})
+	}
+
+	return r
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/dashboards"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/licensing"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
 	"github.com/grafana/grafana/pkg/services/publicdashboards"
 	. "github.com/grafana/grafana/pkg/services/publicdashboards/models"
 	"github.com/grafana/grafana/pkg/services/publicdashboards/validation"
@@ -30,6 +31,7 @@ type Api struct {
 	cfg           *setting.Cfg
 	features      featuremgmt.FeatureToggles
 	license       licensing.Licensing
+	loginAttempts loginattempt.Service
 	log           log.Logger
 	routeRegister routing.RouteRegister
 }
@@ -42,6 +44,7 @@ func ProvideApi(
 	md publicdashboards.Middleware,
 	cfg *setting.Cfg,
 	license licensing.Licensing,
+	loginAttempts loginattempt.Service,
 ) *Api {
 	api := &Api{
 		PublicDashboardService: pd,
@@ -50,6 +53,7 @@ func ProvideApi(
 		cfg:                    cfg,
 		features:               features,
 		license:                license,
+		loginAttempts:          loginAttempts,
 		log:                    log.New("publicdashboards.api"),
 		routeRegister:          rr,
 	}
@@ -73,9 +77,11 @@ func (api *Api) RegisterAPIEndpoints() {
 	// because it is deeply dependent on the HTTPServer.Index() method and would result in a
 	// circular dependency
 	api.routeRegister.Group("/api/public/dashboards/:accessToken", func(apiRoute routing.RouteRegister) {
-		apiRoute.Get("/", routing.Wrap(api.ViewPublicDashboard))
-		apiRoute.Get("/annotations", routing.Wrap(api.GetPublicAnnotations))
-		apiRoute.Post("/panels/:panelId/query", routing.Wrap(api.QueryPublicDashboard))
+		requiresUnlocked := RequiresUnlockedPublicDashboard(api.PublicDashboardService)
+		apiRoute.Get("/", requiresUnlocked, routing.Wrap(api.ViewPublicDashboard))
+		apiRoute.Get("/annotations", requiresUnlocked, routing.Wrap(api.GetPublicAnnotations))
+		apiRoute.Post("/panels/:panelId/query", requiresUnlocked, routing.Wrap(api.QueryPublicDashboard))
+		apiRoute.Post("/unlock", routing.Wrap(api.UnlockPublicDashboard))
 	}, api.Middleware.HandleApi)
 
 	// Auth endpoints
@@ -103,6 +109,11 @@ func (api *Api) RegisterAPIEndpoints() {
 	api.routeRegister.Delete("/api/dashboards/uid/:dashboardUid/public-dashboards/:uid",
 		auth(accesscontrol.EvalPermission(dashboards.ActionDashboardsPublicWrite, uidScope)),
 		routing.Wrap(api.DeletePublicDashboard))
+
+	// List the views of a public dashboard
+	api.routeRegister.Get("/api/dashboards/uid/:dashboardUid/public-dashboards/:uid/views",
+		auth(accesscontrol.EvalPermission(dashboards.ActionDashboardsPublicWrite, uidScope)),
+		routing.Wrap(api.ListPublicDashboardViews))
 }
 
 // swagger:route GET /dashboards/public-dashboards dashboard_public listPublicDashboards
@@ -300,6 +311,46 @@ func (api *Api) DeletePublicDashboard(c *contextmodel.ReqContext) response.Respo
 	return response.Empty(http.StatusOK)
 }
 
+// swagger:route GET /dashboards/uid/{dashboardUid}/public-dashboards/{uid}/views dashboard_public listPublicDashboardViews
+//
+//	Get the latest views of a public dashboard
+//
+// Responses:
+// 200: listPublicDashboardViewsResponse
+// 400: badRequestPublicError
+// 401: unauthorisedPublicError
+// 403: forbiddenPublicError
+// 404: notFoundPublicError
+// 500: internalServerPublicError
+func (api *Api) ListPublicDashboardViews(c *contextmodel.ReqContext) response.Response {
+	uid := web.Params(c.Req)[":uid"]
+	if !validation.IsValidShortUID(uid) {
+		return response.Err(ErrInvalidUid.Errorf("ListPublicDashboardViews: invalid Uid %s", uid))
+	}
+
+	dashboardUid := web.Params(c.Req)[":dashboardUid"]
+	if !validation.IsValidShortUID(dashboardUid) {
+		return response.Err(ErrInvalidUid.Errorf("ListPublicDashboardViews: invalid dashboard Uid %s", dashboardUid))
+	}
+
+	limit := c.QueryInt("limit")
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	views, err := api.PublicDashboardService.FindViews(c.Req.Context(), &PublicDashboardViewsQuery{
+		OrgID:              c.SignedInUser.GetOrgID(),
+		DashboardUid:       dashboardUid,
+		PublicDashboardUid: uid,
+		Limit:              limit,
+	})
+	if err != nil {
+		return response.Err(err)
+	}
+
+	return response.JSON(http.StatusOK, views)
+}
+
 // Copied from pkg/api/metrics.go
 func toJsonStreamingResponse(ctx context.Context, features featuremgmt.FeatureToggles, qdr *backend.QueryDataResponse) response.Response {
 	statusCode := http.StatusOK
@@ -375,3 +426,23 @@ type DeletePublicDashboardParams struct {
 	// required:true
 	Uid string `json:"uid"`
 }
+
+// swagger:parameters listPublicDashboardViews
+type ListPublicDashboardViewsParams struct {
+	// in:path
+	// required:true
+	DashboardUid string `json:"dashboardUid"`
+	// in:path
+	// required:true
+	Uid string `json:"uid"`
+	// in:query
+	// required:false
+	// default:100
+	Limit int `json:"limit"`
+}
+
+// swagger:response listPublicDashboardViewsResponse
+type ListPublicDashboardViewsResponse struct {
+	// in: body
+	Body []PublicDashboardView `json:"body"`
+}
@@ -622,3 +622,44 @@ func TestAPIUpdatePublicDashboard(t *testing.T) {
 		})
 	}
 }
+
+func TestApiListPublicDashboardViews(t *testing.T) {
+	dashboardUid := "abc1234"
+	publicDashboardUid := "pubdash1234"
+	views := []*PublicDashboardView{{Id: 1, PublicDashboardUid: publicDashboardUid, IPAddress: "127.0.0.1", UserAgent: "test"}}
+
+	t.Run("User with public dashboards write permission can list the views", func(t *testing.T) {
+		service := publicdashboards.NewFakePublicDashboardService(t)
+		service.On("FindViews", mock.Anything, &PublicDashboardViewsQuery{
+			OrgID:              1,
+			DashboardUid:       dashboardUid,
+			PublicDashboardUid: publicDashboardUid,
+			Limit:              100,
+		}).Return(views, nil)
+
+		testServer := setupTestServer(t, nil, service, userAdmin, true)
+		response := callAPI(testServer, http.MethodGet, fmt.Sprintf("/api/dashboards/uid/%s/public-dashboards/%s/views", dashboardUid, publicDashboardUid), nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var result []*PublicDashboardView
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+		assert.Equal(t, "127.0.0.1", result[0].IPAddress)
+	})
+
+	t.Run("Returns 404 when the public dashboard does not exist", func(t *testing.T) {
+		service := publicdashboards.NewFakePublicDashboardService(t)
+		service.On("FindViews", mock.Anything, mock.Anything).Return(nil, ErrPublicDashboardNotFound.Errorf(""))
+
+		testServer := setupTestServer(t, nil, service, userAdmin, true)
+		response := callAPI(testServer, http.MethodGet, fmt.Sprintf("/api/dashboards/uid/%s/public-dashboards/%s/views?limit=10", dashboardUid, publicDashboardUid), nil, t)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("User Viewer cannot list the views", func(t *testing.T) {
+		service := publicdashboards.NewFakePublicDashboardService(t)
+
+		testServer := setupTestServer(t, nil, service, userViewer, true)
+		response := callAPI(testServer, http.MethodGet, fmt.Sprintf("/api/dashboards/uid/%s/public-dashboards/%s/views", dashboardUid, publicDashboardUid), nil, t)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+}
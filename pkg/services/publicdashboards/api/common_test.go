@@ -27,6 +27,8 @@ import (
 	datasourceService "github.com/grafana/grafana/pkg/services/datasources/service"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/licensing/licensingtest"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
+	"github.com/grafana/grafana/pkg/services/loginattempt/loginattempttest"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginconfig"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/plugincontext"
 	pluginSettings "github.com/grafana/grafana/pkg/services/pluginsintegration/pluginsettings/service"
@@ -53,6 +55,18 @@ func setupTestServer(
 	ffEnabled bool,
 ) *web.Mux {
 	t.Helper()
+	return setupTestServerWithLoginAttempts(t, cfg, service, user, ffEnabled, &loginattempttest.MockLoginAttemptService{ExpectedValid: true})
+}
+
+func setupTestServerWithLoginAttempts(
+	t *testing.T,
+	cfg *setting.Cfg,
+	service publicdashboards.Service,
+	user *user.SignedInUser,
+	ffEnabled bool,
+	loginAttempts loginattempt.Service,
+) *web.Mux {
+	t.Helper()
 
 	// build router to register routes
 	rr := routing.NewRouteRegister()
@@ -78,7 +92,7 @@ func setupTestServer(
 	// build api, this will mount the routes at the same time if the feature is enabled
 	license := licensingtest.NewFakeLicensing()
 	license.On("FeatureEnabled", publicdashboardModels.FeaturePublicDashboardsEmailSharing).Return(false)
-	ProvideApi(service, rr, ac, features, &Middleware{}, cfg, license)
+	ProvideApi(service, rr, ac, features, &Middleware{}, cfg, license, loginAttempts)
 
 	// connect routes to mux
 	rr.Register(m.Router)
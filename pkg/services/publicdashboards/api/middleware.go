@@ -6,6 +6,7 @@ import (
 	"github.com/grafana/grafana/pkg/infra/metrics"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
 	"github.com/grafana/grafana/pkg/services/publicdashboards"
+	. "github.com/grafana/grafana/pkg/services/publicdashboards/models"
 	"github.com/grafana/grafana/pkg/services/publicdashboards/validation"
 	"github.com/grafana/grafana/pkg/web"
 )
@@ -62,6 +63,33 @@ func RequiresExistingAccessToken(publicDashboardService publicdashboards.Service
 	}
 }
 
+// RequiresUnlockedPublicDashboard Middleware to enforce that viewers of a password protected public dashboard unlocked it
+// and hold a valid session cookie before continuing to handler.
+func RequiresUnlockedPublicDashboard(publicDashboardService publicdashboards.Service) func(c *contextmodel.ReqContext) {
+	return func(c *contextmodel.ReqContext) {
+		accessToken, ok := web.Params(c.Req)[":accessToken"]
+		// invalid access tokens are rejected by the handlers
+		if !ok || !validation.IsValidAccessToken(accessToken) {
+			return
+		}
+
+		var sessionToken string
+		if cookie, err := c.Req.Cookie(SessionCookieName); err == nil {
+			sessionToken = cookie.Value
+		}
+
+		unlocked, err := publicDashboardService.IsUnlocked(c.Req.Context(), accessToken, sessionToken)
+		if err != nil {
+			c.WriteErr(err)
+			return
+		}
+		if !unlocked {
+			c.WriteErr(ErrPublicDashboardLocked.Errorf("RequiresUnlockedPublicDashboard: public dashboard is locked"))
+			return
+		}
+	}
+}
+
 func CountPublicDashboardRequest() func(c *contextmodel.ReqContext) {
 	return func(c *contextmodel.ReqContext) {
 		metrics.MPublicDashboardRequestCount.Inc()
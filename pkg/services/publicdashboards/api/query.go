@@ -1,14 +1,18 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/middleware/cookies"
 	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/loginattempt"
 	. "github.com/grafana/grafana/pkg/services/publicdashboards/models"
 	"github.com/grafana/grafana/pkg/services/publicdashboards/validation"
 	"github.com/grafana/grafana/pkg/web"
@@ -36,9 +40,83 @@ func (api *Api) ViewPublicDashboard(c *contextmodel.ReqContext) response.Respons
 		return response.Err(err)
 	}
 
+	err = api.PublicDashboardService.RecordView(c.Req.Context(), RecordPublicDashboardViewCommand{
+		AccessToken: accessToken,
+		IPAddress:   loginattempt.RemoteAddr(c.Req, api.cfg.BruteForceLoginProtection.TrustedProxies),
+		UserAgent:   c.Req.UserAgent(),
+	})
+	if err != nil {
+		api.log.Error("Failed to record public dashboard view", "error", err)
+	}
+
 	return response.JSON(http.StatusOK, dto)
 }
 
+// swagger:route POST /public/dashboards/{accessToken}/unlock dashboard_public unlockPublicDashboard
+//
+//	Unlock a password protected public dashboard
+//
+// Sets a session cookie that allows to view the public dashboard.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestPublicError
+// 401: unauthorisedPublicError
+// 403: forbiddenPublicError
+// 404: notFoundPublicError
+// 500: internalServerPublicError
+func (api *Api) UnlockPublicDashboard(c *contextmodel.ReqContext) response.Response {
+	accessToken := web.Params(c.Req)[":accessToken"]
+	if !validation.IsValidAccessToken(accessToken) {
+		return response.Err(ErrInvalidAccessToken.Errorf("UnlockPublicDashboard: invalid access token"))
+	}
+
+	cmd := UnlockPublicDashboardCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Err(ErrBadRequest.Errorf("UnlockPublicDashboard: error parsing request: %v", err))
+	}
+
+	// wrong passwords are counted like failed logins, for the access token and for the IP address they come from
+	attemptKey := unlockAttemptKey(accessToken)
+	remoteAddr := loginattempt.RemoteAddr(c.Req, api.cfg.BruteForceLoginProtection.TrustedProxies)
+	ok, err := api.loginAttempts.Validate(c.Req.Context(), attemptKey, remoteAddr)
+	if err != nil {
+		return response.Err(ErrInternalServerError.Errorf("UnlockPublicDashboard: failed to check unlock attempts: %w", err))
+	}
+	if !ok {
+		return response.Err(ErrTooManyUnlockAttempts.Errorf("UnlockPublicDashboard: too many incorrect passwords for public dashboard"))
+	}
+
+	session, err := api.PublicDashboardService.Unlock(c.Req.Context(), accessToken, cmd.Password)
+	if errors.Is(err, ErrWrongPassword) {
+		if err := api.loginAttempts.Add(c.Req.Context(), attemptKey, remoteAddr); err != nil {
+			api.log.Error("Failed to record public dashboard unlock attempt", "error", err)
+		}
+	}
+	if err != nil {
+		return response.Err(err)
+	}
+
+	maxAge := int(time.Until(session.ExpiresAt).Seconds())
+	cookies.WriteCookie(c.Resp, SessionCookieName, session.Token, maxAge, func() cookies.CookieOptions {
+		return cookies.CookieOptions{
+			// the session only unlocks the api of this public dashboard
+			Path:             api.cfg.AppSubURL + "/api/public/dashboards/" + accessToken,
+			Secure:           api.cfg.CookieSecure,
+			SameSiteDisabled: api.cfg.CookieSameSiteDisabled,
+			SameSiteMode:     api.cfg.CookieSameSiteMode,
+		}
+	})
+
+	return response.Success("Public dashboard unlocked")
+}
+
+// unlockAttemptKey is the username the login attempts service counts the unlock attempts of a public dashboard for,
+// prefixed to tell it apart from the logins of users
+func unlockAttemptKey(accessToken string) string {
+	return "public-dashboard:" + accessToken
+}
+
 // swagger:route POST /public/dashboards/{accessToken}/panels/{panelId}/query dashboard_public queryPublicDashboard
 //
 //	Get results for a given panel on a public dashboard
@@ -117,6 +195,15 @@ type ViewPublicDashboardParams struct {
 	AccessToken string `json:"accessToken"`
 }
 
+// swagger:parameters unlockPublicDashboard
+type UnlockPublicDashboardParams struct {
+	// in: path
+	AccessToken string `json:"accessToken"`
+	// in: body
+	// required: true
+	Body UnlockPublicDashboardCommand
+}
+
 // swagger:response queryPublicDashboardResponse
 type QueryPublicDashboardResponse struct {
 	// in: body
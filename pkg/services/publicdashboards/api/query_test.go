@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -35,6 +37,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/folder/folderimpl"
 	"github.com/grafana/grafana/pkg/services/folder/foldertest"
 	"github.com/grafana/grafana/pkg/services/licensing/licensingtest"
+	"github.com/grafana/grafana/pkg/services/loginattempt/loginattempttest"
 	"github.com/grafana/grafana/pkg/services/publicdashboards"
 	publicdashboardsStore "github.com/grafana/grafana/pkg/services/publicdashboards/database"
 	. "github.com/grafana/grafana/pkg/services/publicdashboards/models"
@@ -101,6 +104,8 @@ func TestAPIViewPublicDashboard(t *testing.T) {
 			service := publicdashboards.NewFakePublicDashboardService(t)
 			service.On("GetPublicDashboardForView", mock.Anything, mock.AnythingOfType("string")).
 				Return(test.DashboardResult, test.Err).Maybe()
+			service.On("IsUnlocked", mock.Anything, mock.AnythingOfType("string"), "").Return(true, nil).Maybe()
+			service.On("RecordView", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 			testServer := setupTestServer(t, nil, service, anonymousUser, true)
 
@@ -135,6 +140,107 @@ func TestAPIViewPublicDashboard(t *testing.T) {
 	}
 }
 
+func TestAPIViewPasswordProtectedPublicDashboard(t *testing.T) {
+	t.Run("Returns 401 when the public dashboard is locked", func(t *testing.T) {
+		service := publicdashboards.NewFakePublicDashboardService(t)
+		service.On("IsUnlocked", mock.Anything, validAccessToken, "").Return(false, nil)
+
+		testServer := setupTestServer(t, nil, service, anonymousUser, true)
+		response := callAPI(testServer, http.MethodGet, fmt.Sprintf("/api/public/dashboards/%s", validAccessToken), nil, t)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		var errResp errutil.PublicError
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &errResp))
+		assert.Equal(t, "publicdashboards.locked", errResp.MessageID)
+	})
+
+	t.Run("Checks the session cookie and records the view", func(t *testing.T) {
+		service := publicdashboards.NewFakePublicDashboardService(t)
+		service.On("IsUnlocked", mock.Anything, validAccessToken, "session").Return(true, nil)
+		service.On("GetPublicDashboardForView", mock.Anything, validAccessToken).
+			Return(&dtos.DashboardFullWithMeta{Dashboard: simplejson.New()}, nil)
+		service.On("RecordView", mock.Anything, mock.MatchedBy(func(cmd RecordPublicDashboardViewCommand) bool {
+			// the forwarded address is ignored, the request doesn't come from a trusted proxy
+			return cmd.AccessToken == validAccessToken && cmd.UserAgent == "test-agent" && cmd.IPAddress == "192.0.2.10"
+		})).Return(nil)
+
+		testServer := setupTestServer(t, nil, service, anonymousUser, true)
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/public/dashboards/%s", validAccessToken), nil)
+		require.NoError(t, err)
+		req.Header.Set("User-Agent", "test-agent")
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		req.RemoteAddr = "192.0.2.10:51000"
+		req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: "session"})
+		response := httptest.NewRecorder()
+		testServer.ServeHTTP(response, req)
+
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+}
+
+func TestAPIUnlockPublicDashboard(t *testing.T) {
+	t.Run("Sets the session cookie when the password is valid", func(t *testing.T) {
+		service := publicdashboards.NewFakePublicDashboardService(t)
+		service.On("Unlock", mock.Anything, validAccessToken, "correct horse").
+			Return(&PublicDashboardSession{Token: "session", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+		testServer := setupTestServer(t, nil, service, anonymousUser, true)
+		response := callAPI(testServer, http.MethodPost, fmt.Sprintf("/api/public/dashboards/%s/unlock", validAccessToken), strings.NewReader(`{"password":"correct horse"}`), t)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		cookies := response.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, SessionCookieName, cookies[0].Name)
+		assert.Equal(t, "session", cookies[0].Value)
+		assert.Equal(t, "/api/public/dashboards/"+validAccessToken, cookies[0].Path)
+		assert.True(t, cookies[0].HttpOnly)
+		assert.Greater(t, cookies[0].MaxAge, 0)
+	})
+
+	t.Run("Returns 401 when the password is wrong", func(t *testing.T) {
+		service := publicdashboards.NewFakePublicDashboardService(t)
+		service.On("Unlock", mock.Anything, validAccessToken, "wrong password").
+			Return(nil, ErrWrongPassword.Errorf(""))
+
+		testServer := setupTestServer(t, nil, service, anonymousUser, true)
+		response := callAPI(testServer, http.MethodPost, fmt.Sprintf("/api/public/dashboards/%s/unlock", validAccessToken), strings.NewReader(`{"password":"wrong password"}`), t)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		assert.Empty(t, response.Result().Cookies())
+	})
+
+	t.Run("Counts wrong passwords as failed unlock attempts", func(t *testing.T) {
+		service := publicdashboards.NewFakePublicDashboardService(t)
+		service.On("Unlock", mock.Anything, validAccessToken, "wrong password").
+			Return(nil, ErrWrongPassword.Errorf(""))
+		loginAttempts := &loginattempttest.MockLoginAttemptService{ExpectedValid: true}
+
+		testServer := setupTestServerWithLoginAttempts(t, nil, service, anonymousUser, true, loginAttempts)
+		response := callAPI(testServer, http.MethodPost, fmt.Sprintf("/api/public/dashboards/%s/unlock", validAccessToken), strings.NewReader(`{"password":"wrong password"}`), t)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		assert.True(t, loginAttempts.ValidateCalled)
+		assert.True(t, loginAttempts.AddCalled)
+	})
+
+	t.Run("Returns 429 without checking the password when unlock attempts are locked out", func(t *testing.T) {
+		service := publicdashboards.NewFakePublicDashboardService(t)
+		loginAttempts := &loginattempttest.MockLoginAttemptService{ExpectedValid: false}
+
+		testServer := setupTestServerWithLoginAttempts(t, nil, service, anonymousUser, true, loginAttempts)
+		response := callAPI(testServer, http.MethodPost, fmt.Sprintf("/api/public/dashboards/%s/unlock", validAccessToken), strings.NewReader(`{"password":"correct horse"}`), t)
+		require.Equal(t, http.StatusTooManyRequests, response.Code)
+		assert.Empty(t, response.Result().Cookies())
+		service.AssertNotCalled(t, "Unlock", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Returns 400 when the password is missing", func(t *testing.T) {
+		service := publicdashboards.NewFakePublicDashboardService(t)
+
+		testServer := setupTestServer(t, nil, service, anonymousUser, true)
+		response := callAPI(testServer, http.MethodPost, fmt.Sprintf("/api/public/dashboards/%s/unlock", validAccessToken), strings.NewReader(`{}`), t)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
+
 // `/public/dashboards/:uid/query“ endpoint test
 func TestAPIQueryPublicDashboard(t *testing.T) {
 	mockedResponse := &backend.QueryDataResponse{
@@ -191,6 +297,7 @@ func TestAPIQueryPublicDashboard(t *testing.T) {
 
 	setup := func(enabled bool) (*web.Mux, *publicdashboards.FakePublicDashboardService) {
 		service := publicdashboards.NewFakePublicDashboardService(t)
+		service.On("IsUnlocked", mock.Anything, mock.AnythingOfType("string"), "").Return(true, nil).Maybe()
 		testServer := setupTestServer(t, nil, service, anonymousUser, true)
 
 		return testServer, service
@@ -412,6 +519,7 @@ func TestAPIGetAnnotations(t *testing.T) {
 	for _, test := range testCases {
 		t.Run(test.Name, func(t *testing.T) {
 			service := publicdashboards.NewFakePublicDashboardService(t)
+			service.On("IsUnlocked", mock.Anything, mock.AnythingOfType("string"), "").Return(true, nil).Maybe()
 
 			if test.ExpectedServiceCalled {
 				service.On("FindAnnotations", mock.Anything, mock.Anything, mock.AnythingOfType("string")).
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/grafana/grafana/pkg/infra/db"
 	"github.com/grafana/grafana/pkg/infra/log"
@@ -55,7 +56,7 @@ func (d *PublicDashboardStoreImpl) FindAllWithPagination(ctx context.Context, qu
 	}
 
 	pubdashBuilder := db.NewSqlBuilder(d.cfg, d.features, d.sqlStore.GetDialect(), recursiveQueriesAreSupported)
-	pubdashBuilder.Write("SELECT dashboard_public.uid, dashboard_public.access_token, dashboard.uid as dashboard_uid, dashboard_public.is_enabled, dashboard.title, dashboard.slug")
+	pubdashBuilder.Write("SELECT dashboard_public.uid, dashboard_public.access_token, dashboard.uid as dashboard_uid, dashboard_public.is_enabled, dashboard_public.expires_at, dashboard.title, dashboard.slug")
 	pubdashBuilder.Write(" FROM dashboard_public")
 	pubdashBuilder.Write(" JOIN dashboard ON dashboard.uid = dashboard_public.dashboard_uid AND dashboard.org_id = dashboard_public.org_id")
 	pubdashBuilder.Write(` WHERE dashboard_public.org_id = ?`, query.OrgID)
@@ -187,13 +188,13 @@ func (d *PublicDashboardStoreImpl) ExistsEnabledByDashboardUid(ctx context.Conte
 	return hasPublicDashboard, err
 }
 
-// ExistsEnabledByAccessToken Responds true if the accessToken exists and the public dashboard is enabled
+// ExistsEnabledByAccessToken Responds true if the accessToken exists and the public dashboard is enabled and not expired
 func (d *PublicDashboardStoreImpl) ExistsEnabledByAccessToken(ctx context.Context, accessToken string) (bool, error) {
 	hasPublicDashboard := false
 	err := d.sqlStore.WithDbSession(ctx, func(dbSession *db.Session) error {
-		sql := "SELECT COUNT(*) FROM dashboard_public WHERE access_token=? AND is_enabled=true"
+		sql := "SELECT COUNT(*) FROM dashboard_public WHERE access_token=? AND is_enabled=true AND (expires_at IS NULL OR expires_at > ?)"
 
-		result, err := dbSession.SQL(sql, accessToken).Count()
+		result, err := dbSession.SQL(sql, accessToken, formatTime(time.Now())).Count()
 		if err != nil {
 			return err
 		}
@@ -247,14 +248,28 @@ func (d *PublicDashboardStoreImpl) Update(ctx context.Context, cmd SavePublicDas
 			return err
 		}
 
-		sqlResult, err := sess.Exec("UPDATE dashboard_public SET is_enabled = ?, annotations_enabled = ?, time_selection_enabled = ?, share = ?, time_settings = ?, updated_by = ?, updated_at = ? WHERE uid = ?",
+		templateVariablesJSON, err := json.Marshal(cmd.PublicDashboard.TemplateVariables)
+		if err != nil {
+			return err
+		}
+
+		var expiresAt any
+		if cmd.PublicDashboard.ExpiresAt != nil {
+			expiresAt = formatTime(*cmd.PublicDashboard.ExpiresAt)
+		}
+
+		sqlResult, err := sess.Exec("UPDATE dashboard_public SET is_enabled = ?, annotations_enabled = ?, time_selection_enabled = ?, share = ?, time_settings = ?, template_variables = ?, expires_at = ?, password_hash = ?, password_salt = ?, updated_by = ?, updated_at = ? WHERE uid = ?",
 			cmd.PublicDashboard.IsEnabled,
 			cmd.PublicDashboard.AnnotationsEnabled,
 			cmd.PublicDashboard.TimeSelectionEnabled,
 			cmd.PublicDashboard.Share,
 			string(timeSettingsJSON),
+			string(templateVariablesJSON),
+			expiresAt,
+			cmd.PublicDashboard.PasswordHash,
+			cmd.PublicDashboard.PasswordSalt,
 			cmd.PublicDashboard.UpdatedBy,
-			cmd.PublicDashboard.UpdatedAt.UTC().Format("2006-01-02 15:04:05"),
+			formatTime(cmd.PublicDashboard.UpdatedAt),
 			cmd.PublicDashboard.Uid)
 
 		if err != nil {
@@ -309,3 +324,68 @@ func (d *PublicDashboardStoreImpl) GetMetrics(ctx context.Context) (*Metrics, er
 
 	return metrics, nil
 }
+
+// DisableExpired disables the enabled public dashboards that expired before now
+func (d *PublicDashboardStoreImpl) DisableExpired(ctx context.Context, now time.Time) (int64, error) {
+	var affectedRows int64
+	err := d.sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+		sqlResult, err := sess.Exec("UPDATE dashboard_public SET is_enabled = ?, updated_at = ? WHERE is_enabled = ? AND expires_at IS NOT NULL AND expires_at <= ?",
+			false, formatTime(now), true, formatTime(now))
+		if err != nil {
+			return err
+		}
+
+		affectedRows, err = sqlResult.RowsAffected()
+		return err
+	})
+
+	return affectedRows, err
+}
+
+// CreateViews adds views of public dashboards to the audit log
+func (d *PublicDashboardStoreImpl) CreateViews(ctx context.Context, views []*PublicDashboardView) error {
+	if len(views) == 0 {
+		return nil
+	}
+	return d.sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.InsertMulti(&views)
+		return err
+	})
+}
+
+// FindViews Returns the latest views of a public dashboard, most recent first
+func (d *PublicDashboardStoreImpl) FindViews(ctx context.Context, query *PublicDashboardViewsQuery) ([]*PublicDashboardView, error) {
+	views := make([]*PublicDashboardView, 0)
+	err := d.sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("org_id = ? AND public_dashboard_uid = ?", query.OrgID, query.PublicDashboardUid).
+			Desc("viewed_at").
+			Limit(query.Limit).
+			Find(&views)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
+
+// DeleteViewsBefore removes the views older than olderThan from the audit log
+func (d *PublicDashboardStoreImpl) DeleteViewsBefore(ctx context.Context, olderThan time.Time) (int64, error) {
+	var affectedRows int64
+	err := d.sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+		sqlResult, err := sess.Exec("DELETE FROM dashboard_public_view WHERE viewed_at < ?", formatTime(olderThan))
+		if err != nil {
+			return err
+		}
+
+		affectedRows, err = sqlResult.RowsAffected()
+		return err
+	})
+
+	return affectedRows, err
+}
+
+// formatTime formats t the way datetime columns are stored
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
@@ -230,6 +230,30 @@ func TestIntegrationExistsEnabledByAccessToken(t *testing.T) {
 		require.False(t, res)
 	})
 
+	t.Run("ExistsEnabledByAccessToken will return false when the public dashboard expired", func(t *testing.T) {
+		setup()
+
+		expiresAt := time.Now().Add(-time.Hour)
+		_, err := publicdashboardStore.Create(context.Background(), SavePublicDashboardCommand{
+			PublicDashboard: PublicDashboard{
+				IsEnabled:    true,
+				Uid:          "abc123",
+				DashboardUid: savedDashboard.UID,
+				OrgId:        savedDashboard.OrgID,
+				CreatedAt:    time.Now(),
+				CreatedBy:    7,
+				AccessToken:  "accessToken",
+				ExpiresAt:    &expiresAt,
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := publicdashboardStore.ExistsEnabledByAccessToken(context.Background(), "accessToken")
+		require.NoError(t, err)
+
+		require.False(t, res)
+	})
+
 	t.Run("ExistsEnabledByAccessToken will return false when no public dashboard has matching access token", func(t *testing.T) {
 		setup()
 
@@ -593,6 +617,9 @@ func TestIntegrationUpdatePublicDashboard(t *testing.T) {
 			TimeSelectionEnabled: true,
 			Share:                EmailShareType,
 			TimeSettings:         &TimeSettings{From: "now-8", To: "now"},
+			ExpiresAt:            util.Pointer(time.Now().Add(time.Hour).UTC().Round(time.Second)),
+			PasswordHash:         "hash",
+			PasswordSalt:         "salt",
 			UpdatedAt:            time.Now().UTC().Round(time.Second),
 			UpdatedBy:            8,
 		}
@@ -614,6 +641,10 @@ func TestIntegrationUpdatePublicDashboard(t *testing.T) {
 		assert.Equal(t, updatedPublicDashboard.AnnotationsEnabled, pdRetrieved.AnnotationsEnabled)
 		assert.Equal(t, updatedPublicDashboard.TimeSelectionEnabled, pdRetrieved.TimeSelectionEnabled)
 		assert.Equal(t, updatedPublicDashboard.Share, pdRetrieved.Share)
+		assert.Equal(t, updatedPublicDashboard.ExpiresAt.Unix(), pdRetrieved.ExpiresAt.Unix())
+		assert.Equal(t, "hash", pdRetrieved.PasswordHash)
+		assert.Equal(t, "salt", pdRetrieved.PasswordSalt)
+		assert.True(t, pdRetrieved.PasswordProtected)
 
 		// not updated dashboard shouldn't have changed
 		pdNotUpdatedRetrieved, err := publicdashboardStore.FindByDashboardUid(context.Background(), anotherSavedDashboard.OrgID, anotherSavedDashboard.UID)
@@ -740,6 +771,85 @@ func TestIntegrationDelete(t *testing.T) {
 	})
 }
 
+func TestIntegrationDisableExpired(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	sqlStore, cfg := db.InitTestDBWithCfg(t)
+	quotaService := quotatest.New(false, nil)
+	dashboardStore, err := dashboardsDB.ProvideDashboardStore(sqlStore, cfg, featuremgmt.WithFeatures(), tagimpl.ProvideService(sqlStore), quotaService)
+	require.NoError(t, err)
+	publicdashboardStore := ProvideStore(sqlStore, cfg, featuremgmt.WithFeatures())
+
+	expired := insertPublicDashboard(t, publicdashboardStore, insertTestDashboard(t, dashboardStore, "expired", 1, "", true).UID, 1, true, PublicShareType)
+	notExpired := insertPublicDashboard(t, publicdashboardStore, insertTestDashboard(t, dashboardStore, "not expired", 1, "", true).UID, 1, true, PublicShareType)
+	neverExpires := insertPublicDashboard(t, publicdashboardStore, insertTestDashboard(t, dashboardStore, "never expires", 1, "", true).UID, 1, true, PublicShareType)
+
+	for pubdash, expiresAt := range map[*PublicDashboard]time.Time{
+		expired:    time.Now().Add(-time.Hour),
+		notExpired: time.Now().Add(time.Hour),
+	} {
+		pubdash.ExpiresAt = &expiresAt
+		_, err := publicdashboardStore.Update(context.Background(), SavePublicDashboardCommand{PublicDashboard: *pubdash})
+		require.NoError(t, err)
+	}
+
+	affectedRows, err := publicdashboardStore.DisableExpired(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, affectedRows)
+
+	for uid, isEnabled := range map[string]bool{expired.Uid: false, notExpired.Uid: true, neverExpires.Uid: true} {
+		pubdash, err := publicdashboardStore.Find(context.Background(), uid)
+		require.NoError(t, err)
+		assert.Equal(t, isEnabled, pubdash.IsEnabled)
+	}
+}
+
+func TestIntegrationPublicDashboardViews(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	sqlStore, cfg := db.InitTestDBWithCfg(t)
+	publicdashboardStore := ProvideStore(sqlStore, cfg, featuremgmt.WithFeatures())
+	ctx := context.Background()
+
+	now := time.Now()
+	views := make([]*PublicDashboardView, 0)
+	for i, viewedAt := range []time.Time{now.Add(-48 * time.Hour), now.Add(-time.Hour), now} {
+		views = append(views, &PublicDashboardView{
+			OrgId:              1,
+			PublicDashboardUid: "pubdash",
+			AccessToken:        "accessToken",
+			IPAddress:          "127.0.0.1",
+			UserAgent:          fmt.Sprintf("agent %d", i),
+			ViewedAt:           viewedAt,
+		})
+	}
+	views = append(views, &PublicDashboardView{OrgId: 2, PublicDashboardUid: "pubdash", AccessToken: "another", ViewedAt: now})
+	err := publicdashboardStore.CreateViews(ctx, views)
+	require.NoError(t, err)
+
+	t.Run("FindViews returns the latest views of the public dashboard first", func(t *testing.T) {
+		views, err := publicdashboardStore.FindViews(ctx, &PublicDashboardViewsQuery{OrgID: 1, PublicDashboardUid: "pubdash", Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, views, 2)
+		assert.Equal(t, "agent 2", views[0].UserAgent)
+		assert.Equal(t, "agent 1", views[1].UserAgent)
+	})
+
+	t.Run("DeleteViewsBefore removes the old views", func(t *testing.T) {
+		affectedRows, err := publicdashboardStore.DeleteViewsBefore(ctx, now.Add(-24*time.Hour))
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, affectedRows)
+
+		views, err := publicdashboardStore.FindViews(ctx, &PublicDashboardViewsQuery{OrgID: 1, PublicDashboardUid: "pubdash", Limit: 10})
+		require.NoError(t, err)
+		assert.Len(t, views, 2)
+	})
+}
+
 func TestFindByFolder(t *testing.T) {
 	t.Run("returns nil when dashboard is not a folder", func(t *testing.T) {
 		sqlStore, cfg := db.InitTestDBWithCfg(t)
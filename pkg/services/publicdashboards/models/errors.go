@@ -20,9 +20,19 @@ var (
 	ErrInvalidMaxDataPoints                = errutil.BadRequest("publicdashboards.maxDataPoints", errutil.WithPublicMessage("maxDataPoints should be greater than 0"))
 	ErrInvalidTimeRange                    = errutil.BadRequest("publicdashboards.invalidTimeRange", errutil.WithPublicMessage("Invalid time range"))
 	ErrInvalidShareType                    = errutil.BadRequest("publicdashboards.invalidShareType", errutil.WithPublicMessage("Invalid share type"))
+	ErrInvalidTemplateVariables            = errutil.BadRequest("publicdashboards.invalidTemplateVariables", errutil.WithPublicMessage("Invalid template variables"))
+	ErrInvalidTemplateVariableValue        = errutil.BadRequest("publicdashboards.invalidTemplateVariableValue", errutil.WithPublicMessage("Invalid template variable value"))
+	ErrInvalidExpiration                   = errutil.BadRequest("publicdashboards.invalidExpiration", errutil.WithPublicMessage("Expiration should be a timestamp in the future"))
+	ErrInvalidPassword                     = errutil.BadRequest("publicdashboards.invalidPassword", errutil.WithPublicMessage("Password should be at least 8 characters long"))
 	ErrDashboardIsPublic                   = errutil.BadRequest("publicdashboards.dashboardIsPublic", errutil.WithPublicMessage("Dashboard is already public"))
 	ErrPublicDashboardUidExists            = errutil.BadRequest("publicdashboards.uidExists", errutil.WithPublicMessage("Dashboard Uid already exists"))
 	ErrPublicDashboardAccessTokenExists    = errutil.BadRequest("publicdashboards.accessTokenExists", errutil.WithPublicMessage("Dashboard Access Token already exists"))
 
 	ErrPublicDashboardNotEnabled = errutil.Forbidden("publicdashboards.notEnabled", errutil.WithPublicMessage("Dashboard paused"))
+	ErrPublicDashboardExpired    = errutil.Forbidden("publicdashboards.expired", errutil.WithPublicMessage("Dashboard link expired"))
+
+	ErrPublicDashboardLocked = errutil.Unauthorized("publicdashboards.locked", errutil.WithPublicMessage("Dashboard is password protected"))
+	ErrWrongPassword         = errutil.Unauthorized("publicdashboards.wrongPassword", errutil.WithPublicMessage("Invalid password"))
+
+	ErrTooManyUnlockAttempts = errutil.TooManyRequests("publicdashboards.tooManyUnlockAttempts", errutil.WithPublicMessage("Too many incorrect passwords, try again later"))
 )
@@ -29,6 +29,8 @@ const (
 	EmailShareType                      ShareType = "email"
 	PublicShareType                     ShareType = "public"
 	FeaturePublicDashboardsEmailSharing           = "publicDashboardsEmailSharing"
+	MinPasswordLength                             = 8
+	SessionCookieName                             = "grafana_public_dashboard_session"
 )
 
 var (
@@ -54,6 +56,24 @@ type PublicDashboard struct {
 	AnnotationsEnabled   bool          `json:"annotationsEnabled" xorm:"annotations_enabled"`
 	Share                ShareType     `json:"share" xorm:"share"`
 	Recipients           []EmailDTO    `json:"recipients,omitempty" xorm:"-"`
+	// TemplateVariables are the dashboard template variables viewers can change
+	TemplateVariables *TemplateVariables `json:"templateVariables,omitempty" xorm:"template_variables"`
+	// ExpiresAt is when the public dashboard gets disabled, it never expires when nil
+	ExpiresAt    *time.Time `json:"expiresAt" xorm:"expires_at"`
+	PasswordHash string     `json:"-" xorm:"password_hash"`
+	PasswordSalt string     `json:"-" xorm:"password_salt"`
+	// PasswordProtected is true when viewers need to unlock the public dashboard with a password
+	PasswordProtected bool `json:"passwordProtected" xorm:"-"`
+}
+
+// AfterLoad sets the fields derived from the database columns
+func (pd *PublicDashboard) AfterLoad() {
+	pd.PasswordProtected = pd.PasswordHash != ""
+}
+
+// IsExpired returns true when the public dashboard expired at the given time
+func (pd *PublicDashboard) IsExpired(now time.Time) bool {
+	return pd.ExpiresAt != nil && !now.Before(*pd.ExpiresAt)
 }
 
 type PublicDashboardDTO struct {
@@ -63,6 +83,12 @@ type PublicDashboardDTO struct {
 	IsEnabled            *bool     `json:"isEnabled"`
 	AnnotationsEnabled   *bool     `json:"annotationsEnabled"`
 	Share                ShareType `json:"share"`
+	// TemplateVariables replace the ones of the public dashboard when set
+	TemplateVariables *TemplateVariables `json:"templateVariables"`
+	// ExpiresAt replaces the expiration of the public dashboard when set to an RFC 3339 timestamp, an empty string removes it
+	ExpiresAt *string `json:"expiresAt"`
+	// Password replaces the password of the public dashboard when set, an empty password removes it
+	Password *string `json:"password"`
 }
 
 type EmailDTO struct {
@@ -113,12 +139,41 @@ type PublicDashboardListResponseWithPagination struct {
 }
 
 type PublicDashboardListResponse struct {
-	Uid          string `json:"uid" xorm:"uid"`
-	AccessToken  string `json:"accessToken" xorm:"access_token"`
-	Title        string `json:"title" xorm:"title"`
-	DashboardUid string `json:"dashboardUid" xorm:"dashboard_uid"`
-	IsEnabled    bool   `json:"isEnabled" xorm:"is_enabled"`
-	Slug         string `json:"slug" xorm:"slug"`
+	Uid          string     `json:"uid" xorm:"uid"`
+	AccessToken  string     `json:"accessToken" xorm:"access_token"`
+	Title        string     `json:"title" xorm:"title"`
+	DashboardUid string     `json:"dashboardUid" xorm:"dashboard_uid"`
+	IsEnabled    bool       `json:"isEnabled" xorm:"is_enabled"`
+	Slug         string     `json:"slug" xorm:"slug"`
+	ExpiresAt    *time.Time `json:"expiresAt" xorm:"expires_at"`
+}
+
+// PublicDashboardView is an entry of the audit log of the views of a public dashboard
+type PublicDashboardView struct {
+	Id                 int64     `json:"id" xorm:"pk autoincr 'id'"`
+	OrgId              int64     `json:"-" xorm:"org_id"`
+	PublicDashboardUid string    `json:"publicDashboardUid" xorm:"public_dashboard_uid"`
+	AccessToken        string    `json:"accessToken" xorm:"access_token"`
+	IPAddress          string    `json:"ipAddress" xorm:"ip_address"`
+	UserAgent          string    `json:"userAgent" xorm:"user_agent"`
+	ViewedAt           time.Time `json:"viewedAt" xorm:"viewed_at"`
+}
+
+func (v PublicDashboardView) TableName() string {
+	return "dashboard_public_view"
+}
+
+type PublicDashboardViewsQuery struct {
+	OrgID              int64
+	DashboardUid       string
+	PublicDashboardUid string
+	Limit              int
+}
+
+// PublicDashboardSession proves that a viewer unlocked a password protected public dashboard
+type PublicDashboardSession struct {
+	Token     string
+	ExpiresAt time.Time
 }
 
 type TimeSettings struct {
@@ -134,6 +189,36 @@ func (ts *TimeSettings) ToDB() ([]byte, error) {
 	return json.Marshal(ts)
 }
 
+// TemplateVariable allows the viewers of a public dashboard to select one of AllowedValues for the dashboard
+// template variable Name. The other template variables keep the value saved in the dashboard.
+type TemplateVariable struct {
+	Name          string   `json:"name"`
+	AllowedValues []string `json:"allowedValues"`
+}
+
+type TemplateVariables []TemplateVariable
+
+func (tv *TemplateVariables) FromDB(data []byte) error {
+	return json.Unmarshal(data, tv)
+}
+
+func (tv *TemplateVariables) ToDB() ([]byte, error) {
+	return json.Marshal(tv)
+}
+
+// Find returns the template variable named name, if viewers can change it
+func (tv *TemplateVariables) Find(name string) (TemplateVariable, bool) {
+	if tv == nil {
+		return TemplateVariable{}, false
+	}
+	for _, v := range *tv {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return TemplateVariable{}, false
+}
+
 // DTO for transforming user input in the api
 type SavePublicDashboardDTO struct {
 	Uid             string
@@ -154,6 +239,8 @@ type PublicDashboardQueryDTO struct {
 	MaxDataPoints   int64
 	QueryCachingTTL int64
 	TimeRange       TimeRangeDTO
+	// Variables are the values selected by the viewer for the template variables they can change
+	Variables map[string][]string
 }
 
 type AnnotationsQueryDTO struct {
@@ -168,3 +255,13 @@ type AnnotationsQueryDTO struct {
 type SavePublicDashboardCommand struct {
 	PublicDashboard PublicDashboard
 }
+
+type RecordPublicDashboardViewCommand struct {
+	AccessToken string
+	IPAddress   string
+	UserAgent   string
+}
+
+type UnlockPublicDashboardCommand struct {
+	Password string `json:"password" binding:"Required"`
+}
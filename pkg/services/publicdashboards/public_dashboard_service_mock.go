@@ -15,6 +15,8 @@ import (
 
 	models "github.com/grafana/grafana/pkg/services/publicdashboards/models"
 
+	time "time"
+
 	user "github.com/grafana/grafana/pkg/services/user"
 )
 
@@ -77,6 +79,54 @@ func (_m *FakePublicDashboardService) DeleteByDashboard(ctx context.Context, das
 	return r0
 }
 
+// DeleteViewsBefore provides a mock function with given fields: ctx, olderThan
+func (_m *FakePublicDashboardService) DeleteViewsBefore(ctx context.Context, olderThan time.Time) (int64, error) {
+	ret := _m.Called(ctx, olderThan)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, olderThan)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, olderThan)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, olderThan)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DisableExpired provides a mock function with given fields: ctx
+func (_m *FakePublicDashboardService) DisableExpired(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ExistsEnabledByAccessToken provides a mock function with given fields: ctx, accessToken
 func (_m *FakePublicDashboardService) ExistsEnabledByAccessToken(ctx context.Context, accessToken string) (bool, error) {
 	ret := _m.Called(ctx, accessToken)
@@ -351,6 +401,32 @@ func (_m *FakePublicDashboardService) FindPublicDashboardAndDashboardByAccessTok
 	return r0, r1, r2
 }
 
+// FindViews provides a mock function with given fields: ctx, query
+func (_m *FakePublicDashboardService) FindViews(ctx context.Context, query *models.PublicDashboardViewsQuery) ([]*models.PublicDashboardView, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 []*models.PublicDashboardView
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PublicDashboardViewsQuery) ([]*models.PublicDashboardView, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PublicDashboardViewsQuery) []*models.PublicDashboardView); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.PublicDashboardView)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.PublicDashboardViewsQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetMetricRequest provides a mock function with given fields: ctx, dashboard, publicDashboard, panelId, reqDTO
 func (_m *FakePublicDashboardService) GetMetricRequest(ctx context.Context, dashboard *dashboards.Dashboard, publicDashboard *models.PublicDashboard, panelId int64, reqDTO models.PublicDashboardQueryDTO) (dtos.MetricRequest, error) {
 	ret := _m.Called(ctx, dashboard, publicDashboard, panelId, reqDTO)
@@ -451,6 +527,30 @@ func (_m *FakePublicDashboardService) GetQueryDataResponse(ctx context.Context,
 	return r0, r1
 }
 
+// IsUnlocked provides a mock function with given fields: ctx, accessToken, sessionToken
+func (_m *FakePublicDashboardService) IsUnlocked(ctx context.Context, accessToken string, sessionToken string) (bool, error) {
+	ret := _m.Called(ctx, accessToken, sessionToken)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (bool, error)); ok {
+		return rf(ctx, accessToken, sessionToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) bool); ok {
+		r0 = rf(ctx, accessToken, sessionToken)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, accessToken, sessionToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewPublicDashboardAccessToken provides a mock function with given fields: ctx
 func (_m *FakePublicDashboardService) NewPublicDashboardAccessToken(ctx context.Context) (string, error) {
 	ret := _m.Called(ctx)
@@ -499,6 +599,46 @@ func (_m *FakePublicDashboardService) NewPublicDashboardUid(ctx context.Context)
 	return r0, r1
 }
 
+// RecordView provides a mock function with given fields: ctx, cmd
+func (_m *FakePublicDashboardService) RecordView(ctx context.Context, cmd models.RecordPublicDashboardViewCommand) error {
+	ret := _m.Called(ctx, cmd)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.RecordPublicDashboardViewCommand) error); ok {
+		r0 = rf(ctx, cmd)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Unlock provides a mock function with given fields: ctx, accessToken, password
+func (_m *FakePublicDashboardService) Unlock(ctx context.Context, accessToken string, password string) (*models.PublicDashboardSession, error) {
+	ret := _m.Called(ctx, accessToken, password)
+
+	var r0 *models.PublicDashboardSession
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.PublicDashboardSession, error)); ok {
+		return rf(ctx, accessToken, password)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.PublicDashboardSession); ok {
+		r0 = rf(ctx, accessToken, password)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PublicDashboardSession)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, accessToken, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: ctx, u, dto
 func (_m *FakePublicDashboardService) Update(ctx context.Context, u *user.SignedInUser, dto *models.SavePublicDashboardDTO) (*models.PublicDashboard, error) {
 	ret := _m.Called(ctx, u, dto)
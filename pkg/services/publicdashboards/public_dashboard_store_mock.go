@@ -7,6 +7,8 @@ import (
 
 	models "github.com/grafana/grafana/pkg/services/publicdashboards/models"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // FakePublicDashboardStore is an autogenerated mock type for the Store type
@@ -38,6 +40,20 @@ func (_m *FakePublicDashboardStore) Create(ctx context.Context, cmd models.SaveP
 	return r0, r1
 }
 
+// CreateViews provides a mock function with given fields: ctx, views
+func (_m *FakePublicDashboardStore) CreateViews(ctx context.Context, views []*models.PublicDashboardView) error {
+	ret := _m.Called(ctx, views)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*models.PublicDashboardView) error); ok {
+		r0 = rf(ctx, views)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Delete provides a mock function with given fields: ctx, uid
 func (_m *FakePublicDashboardStore) Delete(ctx context.Context, uid string) (int64, error) {
 	ret := _m.Called(ctx, uid)
@@ -62,6 +78,54 @@ func (_m *FakePublicDashboardStore) Delete(ctx context.Context, uid string) (int
 	return r0, r1
 }
 
+// DeleteViewsBefore provides a mock function with given fields: ctx, olderThan
+func (_m *FakePublicDashboardStore) DeleteViewsBefore(ctx context.Context, olderThan time.Time) (int64, error) {
+	ret := _m.Called(ctx, olderThan)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, olderThan)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, olderThan)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, olderThan)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DisableExpired provides a mock function with given fields: ctx, now
+func (_m *FakePublicDashboardStore) DisableExpired(ctx context.Context, now time.Time) (int64, error) {
+	ret := _m.Called(ctx, now)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, now)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, now)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ExistsEnabledByAccessToken provides a mock function with given fields: ctx, accessToken
 func (_m *FakePublicDashboardStore) ExistsEnabledByAccessToken(ctx context.Context, accessToken string) (bool, error) {
 	ret := _m.Called(ctx, accessToken)
@@ -240,6 +304,32 @@ func (_m *FakePublicDashboardStore) FindByFolder(ctx context.Context, orgId int6
 	return r0, r1
 }
 
+// FindViews provides a mock function with given fields: ctx, query
+func (_m *FakePublicDashboardStore) FindViews(ctx context.Context, query *models.PublicDashboardViewsQuery) ([]*models.PublicDashboardView, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 []*models.PublicDashboardView
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PublicDashboardViewsQuery) ([]*models.PublicDashboardView, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PublicDashboardViewsQuery) []*models.PublicDashboardView); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.PublicDashboardView)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.PublicDashboardViewsQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetMetrics provides a mock function with given fields: ctx
 func (_m *FakePublicDashboardStore) GetMetrics(ctx context.Context) (*models.Metrics, error) {
 	ret := _m.Called(ctx)
@@ -2,6 +2,7 @@ package publicdashboards
 
 import (
 	"context"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana/pkg/api/dtos"
@@ -38,6 +39,13 @@ type Service interface {
 
 	ExistsEnabledByAccessToken(ctx context.Context, accessToken string) (bool, error)
 	ExistsEnabledByDashboardUid(ctx context.Context, dashboardUid string) (bool, error)
+
+	Unlock(ctx context.Context, accessToken string, password string) (*PublicDashboardSession, error)
+	IsUnlocked(ctx context.Context, accessToken string, sessionToken string) (bool, error)
+	RecordView(ctx context.Context, cmd RecordPublicDashboardViewCommand) error
+	FindViews(ctx context.Context, query *PublicDashboardViewsQuery) ([]*PublicDashboardView, error)
+	DisableExpired(ctx context.Context) (int64, error)
+	DeleteViewsBefore(ctx context.Context, olderThan time.Time) (int64, error)
 }
 
 // ServiceWrapper these methods have different behavior between OSS and Enterprise. The latter would call the OSS service first
@@ -63,6 +71,11 @@ type Store interface {
 	ExistsEnabledByAccessToken(ctx context.Context, accessToken string) (bool, error)
 	ExistsEnabledByDashboardUid(ctx context.Context, dashboardUid string) (bool, error)
 	GetMetrics(ctx context.Context) (*Metrics, error)
+	DisableExpired(ctx context.Context, now time.Time) (int64, error)
+
+	CreateViews(ctx context.Context, views []*PublicDashboardView) error
+	FindViews(ctx context.Context, query *PublicDashboardViewsQuery) ([]*PublicDashboardView, error)
+	DeleteViewsBefore(ctx context.Context, olderThan time.Time) (int64, error)
 }
 
 //go:generate mockery --name Middleware --structname FakePublicDashboardMiddleware --inpackage --filename public_dashboard_middleware_mock.go
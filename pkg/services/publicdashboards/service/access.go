@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/grafana/grafana/pkg/services/publicdashboards/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	// sessionDuration is how long a viewer can see a password protected public dashboard after unlocking it
+	sessionDuration = 24 * time.Hour
+	// maxUserAgentLength is the size of the user_agent column of the audit log
+	maxUserAgentLength = 255
+	// viewQueueSize is the number of views waiting to be stored in the audit log, more are dropped
+	viewQueueSize = 1000
+	// viewBatchSize is the maximum number of views stored at once
+	viewBatchSize     = 100
+	viewFlushInterval = 5 * time.Second
+)
+
+// Unlock checks the password of a public dashboard and returns a session that proves the viewer knows it
+func (pd *PublicDashboardServiceImpl) Unlock(ctx context.Context, accessToken string, password string) (*PublicDashboardSession, error) {
+	ctx, span := tracer.Start(ctx, "publicdashboards.Unlock")
+	defer span.End()
+	pubdash, _, err := pd.FindEnabledPublicDashboardAndDashboardByAccessToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if pubdash.PasswordHash == "" {
+		return nil, ErrBadRequest.Errorf("Unlock: public dashboard is not password protected")
+	}
+
+	hash, err := util.EncodePassword(password, pubdash.PasswordSalt)
+	if err != nil {
+		return nil, ErrInternalServerError.Errorf("Unlock: failed to hash password: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(pubdash.PasswordHash)) != 1 {
+		pd.log.Warn("Invalid public dashboard password", "publicDashboardUid", pubdash.Uid)
+		return nil, ErrWrongPassword.Errorf("Unlock: invalid password for public dashboard %s", pubdash.Uid)
+	}
+
+	expiresAt := time.Now().Add(sessionDuration)
+	if pubdash.ExpiresAt != nil && pubdash.ExpiresAt.Before(expiresAt) {
+		expiresAt = *pubdash.ExpiresAt
+	}
+
+	return &PublicDashboardSession{
+		Token:     pd.signSession(pubdash, expiresAt),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// IsUnlocked returns true when the public dashboard has no password or the session token was issued by Unlock.
+// Sessions are invalidated when the password changes.
+func (pd *PublicDashboardServiceImpl) IsUnlocked(ctx context.Context, accessToken string, sessionToken string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "publicdashboards.IsUnlocked")
+	defer span.End()
+	pubdash, err := pd.FindByAccessToken(ctx, accessToken)
+	if err != nil {
+		return false, err
+	}
+
+	if pubdash.PasswordHash == "" {
+		return true, nil
+	}
+
+	expiry, _, ok := strings.Cut(sessionToken, ".")
+	if !ok {
+		return false, nil
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	expiresAt := time.Unix(unix, 0)
+	if !time.Now().Before(expiresAt) {
+		return false, nil
+	}
+
+	return hmac.Equal([]byte(sessionToken), []byte(pd.signSession(pubdash, expiresAt))), nil
+}
+
+// signSession returns a session token valid until expiresAt, formatted as <expiration>.<signature>
+func (pd *PublicDashboardServiceImpl) signSession(pubdash *PublicDashboard, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(pd.cfg.SecretKey))
+	mac.Write([]byte(pubdash.AccessToken + "\n" + expiry + "\n" + pubdash.PasswordHash))
+
+	return expiry + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RecordView queues a view of the public dashboard, Run adds it to the audit log
+func (pd *PublicDashboardServiceImpl) RecordView(ctx context.Context, cmd RecordPublicDashboardViewCommand) error {
+	ctx, span := tracer.Start(ctx, "publicdashboards.RecordView")
+	defer span.End()
+	pubdash, err := pd.FindByAccessToken(ctx, cmd.AccessToken)
+	if err != nil {
+		return err
+	}
+
+	userAgent := cmd.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	view := &PublicDashboardView{
+		OrgId:              pubdash.OrgId,
+		PublicDashboardUid: pubdash.Uid,
+		AccessToken:        pubdash.AccessToken,
+		IPAddress:          cmd.IPAddress,
+		UserAgent:          userAgent,
+		ViewedAt:           time.Now(),
+	}
+	select {
+	case pd.views <- view:
+	default:
+		pd.log.FromContext(ctx).Warn("Public dashboard view queue is full, dropping view", "publicDashboardUid", pubdash.Uid)
+	}
+
+	return nil
+}
+
+// Run stores the recorded views in the audit log in batches, so that viewing a public dashboard doesn't wait for
+// the database
+func (pd *PublicDashboardServiceImpl) Run(ctx context.Context) error {
+	flush := time.NewTicker(viewFlushInterval)
+	defer flush.Stop()
+
+	batch := make([]*PublicDashboardView, 0, viewBatchSize)
+	for {
+		select {
+		case view := <-pd.views:
+			batch = append(batch, view)
+			if len(batch) >= viewBatchSize {
+				pd.flushViews(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-flush.C:
+			pd.flushViews(ctx, batch)
+			batch = batch[:0]
+		case <-ctx.Done():
+			// the server is shutting down, store the views recorded so far
+			for len(pd.views) > 0 {
+				batch = append(batch, <-pd.views)
+			}
+			pd.flushViews(context.WithoutCancel(ctx), batch)
+			return ctx.Err()
+		}
+	}
+}
+
+func (pd *PublicDashboardServiceImpl) IsDisabled() bool {
+	return !pd.cfg.PublicDashboardsEnabled
+}
+
+func (pd *PublicDashboardServiceImpl) flushViews(ctx context.Context, batch []*PublicDashboardView) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := pd.store.CreateViews(ctx, batch); err != nil {
+		pd.log.Error("Failed to record views of public dashboards", "count", len(batch), "error", err)
+	}
+}
+
+// FindViews returns the latest views of a public dashboard
+func (pd *PublicDashboardServiceImpl) FindViews(ctx context.Context, query *PublicDashboardViewsQuery) ([]*PublicDashboardView, error) {
+	ctx, span := tracer.Start(ctx, "publicdashboards.FindViews")
+	defer span.End()
+	existingPubdash, err := pd.store.Find(ctx, query.PublicDashboardUid)
+	if err != nil {
+		return nil, ErrInternalServerError.Errorf("FindViews: failed to find public dashboard by uid: %s: %w", query.PublicDashboardUid, err)
+	}
+	if existingPubdash == nil || existingPubdash.OrgId != query.OrgID {
+		return nil, ErrPublicDashboardNotFound.Errorf("FindViews: public dashboard not found by uid: %s", query.PublicDashboardUid)
+	}
+
+	// validate the public dashboard belongs to the dashboard
+	if existingPubdash.DashboardUid != query.DashboardUid {
+		return nil, ErrInvalidUid.Errorf("FindViews: the public dashboard does not belong to the dashboard")
+	}
+
+	views, err := pd.store.FindViews(ctx, query)
+	if err != nil {
+		return nil, ErrInternalServerError.Errorf("FindViews: failed to find views of public dashboard %s: %w", query.PublicDashboardUid, err)
+	}
+
+	return views, nil
+}
+
+// DisableExpired disables the public dashboards whose expiration passed
+func (pd *PublicDashboardServiceImpl) DisableExpired(ctx context.Context) (int64, error) {
+	ctx, span := tracer.Start(ctx, "publicdashboards.DisableExpired")
+	defer span.End()
+	affectedRows, err := pd.store.DisableExpired(ctx, time.Now())
+	if err != nil {
+		return 0, ErrInternalServerError.Errorf("DisableExpired: failed to disable expired public dashboards: %w", err)
+	}
+
+	if affectedRows > 0 {
+		pd.log.Info("Disabled expired public dashboards", "count", affectedRows)
+	}
+
+	return affectedRows, nil
+}
+
+// DeleteViewsBefore removes the views older than olderThan from the audit log
+func (pd *PublicDashboardServiceImpl) DeleteViewsBefore(ctx context.Context, olderThan time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "publicdashboards.DeleteViewsBefore")
+	defer span.End()
+	affectedRows, err := pd.store.DeleteViewsBefore(ctx, olderThan)
+	if err != nil {
+		return 0, ErrInternalServerError.Errorf("DeleteViewsBefore: failed to delete public dashboard views: %w", err)
+	}
+
+	return affectedRows, nil
+}
+
+// hashPassword returns the hash and the salt to store for password, or empty strings to remove the password
+func hashPassword(password string) (string, string, error) {
+	if password == "" {
+		return "", "", nil
+	}
+
+	salt, err := util.GetRandomString(10)
+	if err != nil {
+		return "", "", err
+	}
+
+	hash, err := util.EncodePassword(password, salt)
+	if err != nil {
+		return "", "", err
+	}
+
+	return hash, salt, nil
+}
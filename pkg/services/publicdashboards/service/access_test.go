@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	. "github.com/grafana/grafana/pkg/services/publicdashboards"
+	. "github.com/grafana/grafana/pkg/services/publicdashboards/models"
+)
+
+func newPasswordProtectedPublicDashboard(t *testing.T, password string) *PublicDashboard {
+	t.Helper()
+
+	hash, salt, err := hashPassword(password)
+	require.NoError(t, err)
+
+	return &PublicDashboard{
+		Uid:          "pubdash",
+		OrgId:        1,
+		AccessToken:  "abc123",
+		DashboardUid: "dash",
+		IsEnabled:    true,
+		PasswordHash: hash,
+		PasswordSalt: salt,
+	}
+}
+
+func setupAccessTest(t *testing.T, pubdash *PublicDashboard) *PublicDashboardServiceImpl {
+	t.Helper()
+
+	fakeStore := &FakePublicDashboardStore{}
+	fakeStore.On("FindByAccessToken", mock.Anything, pubdash.AccessToken).Return(pubdash, nil)
+	fakeDashboardService := &dashboards.FakeDashboardService{}
+	fakeDashboardService.On("GetDashboard", mock.Anything, mock.Anything).Return(&dashboards.Dashboard{UID: "dash", Data: dashboardData}, nil).Maybe()
+
+	service, _, cfg := newPublicDashboardServiceImpl(t, fakeStore, fakeDashboardService, nil)
+	cfg.SecretKey = "secret"
+	return service
+}
+
+func TestUnlockPublicDashboard(t *testing.T) {
+	t.Run("returns a session when the password is valid", func(t *testing.T) {
+		pubdash := newPasswordProtectedPublicDashboard(t, "correct horse")
+		service := setupAccessTest(t, pubdash)
+
+		session, err := service.Unlock(context.Background(), pubdash.AccessToken, "correct horse")
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(sessionDuration), session.ExpiresAt, time.Minute)
+
+		unlocked, err := service.IsUnlocked(context.Background(), pubdash.AccessToken, session.Token)
+		require.NoError(t, err)
+		assert.True(t, unlocked)
+	})
+
+	t.Run("returns ErrWrongPassword when the password is invalid", func(t *testing.T) {
+		pubdash := newPasswordProtectedPublicDashboard(t, "correct horse")
+		service := setupAccessTest(t, pubdash)
+
+		_, err := service.Unlock(context.Background(), pubdash.AccessToken, "battery staple")
+		require.ErrorIs(t, err, ErrWrongPassword)
+	})
+
+	t.Run("session ends when the public dashboard expires", func(t *testing.T) {
+		pubdash := newPasswordProtectedPublicDashboard(t, "correct horse")
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+		pubdash.ExpiresAt = &expiresAt
+		service := setupAccessTest(t, pubdash)
+
+		session, err := service.Unlock(context.Background(), pubdash.AccessToken, "correct horse")
+		require.NoError(t, err)
+		assert.Equal(t, expiresAt, session.ExpiresAt)
+	})
+
+	t.Run("returns ErrPublicDashboardExpired when the public dashboard expired", func(t *testing.T) {
+		pubdash := newPasswordProtectedPublicDashboard(t, "correct horse")
+		expiresAt := time.Now().Add(-time.Hour)
+		pubdash.ExpiresAt = &expiresAt
+		service := setupAccessTest(t, pubdash)
+
+		_, err := service.Unlock(context.Background(), pubdash.AccessToken, "correct horse")
+		require.ErrorIs(t, err, ErrPublicDashboardExpired)
+	})
+
+	t.Run("returns ErrBadRequest when the public dashboard has no password", func(t *testing.T) {
+		pubdash := newPasswordProtectedPublicDashboard(t, "")
+		service := setupAccessTest(t, pubdash)
+
+		_, err := service.Unlock(context.Background(), pubdash.AccessToken, "correct horse")
+		require.ErrorIs(t, err, ErrBadRequest)
+	})
+}
+
+func TestIsPublicDashboardUnlocked(t *testing.T) {
+	t.Run("public dashboards without password are always unlocked", func(t *testing.T) {
+		pubdash := newPasswordProtectedPublicDashboard(t, "")
+		service := setupAccessTest(t, pubdash)
+
+		unlocked, err := service.IsUnlocked(context.Background(), pubdash.AccessToken, "")
+		require.NoError(t, err)
+		assert.True(t, unlocked)
+	})
+
+	t.Run("rejects missing, forged and expired sessions", func(t *testing.T) {
+		pubdash := newPasswordProtectedPublicDashboard(t, "correct horse")
+		service := setupAccessTest(t, pubdash)
+
+		expired := service.signSession(pubdash, time.Now().Add(-time.Minute))
+		forged := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + "." + strings.Repeat("0", 64)
+
+		for _, token := range []string{"", "garbage", expired, forged} {
+			unlocked, err := service.IsUnlocked(context.Background(), pubdash.AccessToken, token)
+			require.NoError(t, err)
+			assert.False(t, unlocked, token)
+		}
+	})
+
+	t.Run("sessions are invalidated when the password changes", func(t *testing.T) {
+		pubdash := newPasswordProtectedPublicDashboard(t, "correct horse")
+		service := setupAccessTest(t, pubdash)
+		token := service.signSession(pubdash, time.Now().Add(time.Hour))
+
+		pubdash.PasswordHash, pubdash.PasswordSalt, _ = hashPassword("battery staple")
+
+		unlocked, err := service.IsUnlocked(context.Background(), pubdash.AccessToken, token)
+		require.NoError(t, err)
+		assert.False(t, unlocked)
+	})
+}
+
+func TestRecordPublicDashboardView(t *testing.T) {
+	pubdash := &PublicDashboard{Uid: "pubdash", OrgId: 1, AccessToken: "abc123"}
+	fakeStore := &FakePublicDashboardStore{}
+	fakeStore.On("FindByAccessToken", mock.Anything, pubdash.AccessToken).Return(pubdash, nil)
+
+	var recorded []*PublicDashboardView
+	fakeStore.On("CreateViews", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		recorded = append(recorded, args.Get(1).([]*PublicDashboardView)...)
+	}).Return(nil)
+
+	service, _, _ := newPublicDashboardServiceImpl(t, fakeStore, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = service.Run(ctx)
+		close(done)
+	}()
+
+	userAgent := make([]byte, 300)
+	for i := range userAgent {
+		userAgent[i] = 'a'
+	}
+	err := service.RecordView(context.Background(), RecordPublicDashboardViewCommand{
+		AccessToken: pubdash.AccessToken,
+		IPAddress:   "127.0.0.1",
+		UserAgent:   string(userAgent),
+	})
+	require.NoError(t, err)
+	fakeStore.AssertNotCalled(t, "CreateViews", mock.Anything, mock.Anything)
+
+	// the views are stored asynchronously, at the latest when the server shuts down
+	cancel()
+	<-done
+	require.Len(t, recorded, 1)
+	assert.Equal(t, int64(1), recorded[0].OrgId)
+	assert.Equal(t, "pubdash", recorded[0].PublicDashboardUid)
+	assert.Equal(t, "127.0.0.1", recorded[0].IPAddress)
+	assert.Len(t, recorded[0].UserAgent, maxUserAgentLength)
+	assert.WithinDuration(t, time.Now(), recorded[0].ViewedAt, time.Minute)
+}
+
+func TestFindPublicDashboardViews(t *testing.T) {
+	pubdash := &PublicDashboard{Uid: "pubdash", OrgId: 1, DashboardUid: "dash"}
+
+	t.Run("returns the views of the public dashboard", func(t *testing.T) {
+		fakeStore := &FakePublicDashboardStore{}
+		fakeStore.On("Find", mock.Anything, "pubdash").Return(pubdash, nil)
+		fakeStore.On("FindViews", mock.Anything, mock.Anything).Return([]*PublicDashboardView{{Id: 1}}, nil)
+		service, _, _ := newPublicDashboardServiceImpl(t, fakeStore, nil, nil)
+
+		views, err := service.FindViews(context.Background(), &PublicDashboardViewsQuery{OrgID: 1, DashboardUid: "dash", PublicDashboardUid: "pubdash", Limit: 10})
+		require.NoError(t, err)
+		assert.Len(t, views, 1)
+	})
+
+	t.Run("returns ErrPublicDashboardNotFound for public dashboards of another org", func(t *testing.T) {
+		fakeStore := &FakePublicDashboardStore{}
+		fakeStore.On("Find", mock.Anything, "pubdash").Return(pubdash, nil)
+		service, _, _ := newPublicDashboardServiceImpl(t, fakeStore, nil, nil)
+
+		_, err := service.FindViews(context.Background(), &PublicDashboardViewsQuery{OrgID: 2, DashboardUid: "dash", PublicDashboardUid: "pubdash", Limit: 10})
+		require.ErrorIs(t, err, ErrPublicDashboardNotFound)
+	})
+
+	t.Run("returns ErrInvalidUid when the public dashboard belongs to another dashboard", func(t *testing.T) {
+		fakeStore := &FakePublicDashboardStore{}
+		fakeStore.On("Find", mock.Anything, "pubdash").Return(pubdash, nil)
+		service, _, _ := newPublicDashboardServiceImpl(t, fakeStore, nil, nil)
+
+		_, err := service.FindViews(context.Background(), &PublicDashboardViewsQuery{OrgID: 1, DashboardUid: "other", PublicDashboardUid: "pubdash", Limit: 10})
+		require.ErrorIs(t, err, ErrInvalidUid)
+	})
+}
+
+func TestNewUpdatePublicDashboardAccessControls(t *testing.T) {
+	existing := newPasswordProtectedPublicDashboard(t, "correct horse")
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	existing.ExpiresAt = &expiresAt
+
+	t.Run("keeps the password and the expiration when they are not set", func(t *testing.T) {
+		pd, err := newUpdatePublicDashboard(&SavePublicDashboardDTO{PublicDashboard: &PublicDashboardDTO{}}, existing)
+		require.NoError(t, err)
+		assert.Equal(t, existing.PasswordHash, pd.PasswordHash)
+		assert.Equal(t, existing.PasswordSalt, pd.PasswordSalt)
+		assert.Equal(t, existing.ExpiresAt, pd.ExpiresAt)
+	})
+
+	t.Run("removes the password and the expiration when they are empty", func(t *testing.T) {
+		empty := ""
+		pd, err := newUpdatePublicDashboard(&SavePublicDashboardDTO{PublicDashboard: &PublicDashboardDTO{Password: &empty, ExpiresAt: &empty}}, existing)
+		require.NoError(t, err)
+		assert.Empty(t, pd.PasswordHash)
+		assert.Empty(t, pd.PasswordSalt)
+		assert.Nil(t, pd.ExpiresAt)
+	})
+
+	t.Run("replaces the password and the expiration", func(t *testing.T) {
+		password := "battery staple"
+		newExpiresAt := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+		value := newExpiresAt.Format(time.RFC3339)
+		pd, err := newUpdatePublicDashboard(&SavePublicDashboardDTO{PublicDashboard: &PublicDashboardDTO{Password: &password, ExpiresAt: &value}}, existing)
+		require.NoError(t, err)
+		assert.NotEqual(t, existing.PasswordHash, pd.PasswordHash)
+		assert.NotEmpty(t, pd.PasswordSalt)
+		assert.True(t, newExpiresAt.Equal(*pd.ExpiresAt))
+	})
+}
@@ -44,11 +44,13 @@ func newPublicDashboardServiceImpl(
 	return &PublicDashboardServiceImpl{
 		AnnotationsRepo:    annotationsRepo,
 		log:                log.New("test.logger"),
+		cfg:                cfg,
 		intervalCalculator: intervalv2.NewCalculator(),
 		dashboardService:   dashboardService,
 		store:              publicDashboardStore,
 		serviceWrapper:     serviceWrapper,
 		license:            license,
 		features:           featuremgmt.WithFeatures(),
+		views:              make(chan *PublicDashboardView, viewQueueSize),
 	}, db, cfg
 }
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -13,6 +15,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/annotations"
 	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/dashboards/templating"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/publicdashboards/models"
@@ -105,7 +108,7 @@ func (pd *PublicDashboardServiceImpl) FindAnnotations(ctx context.Context, reqDT
 
 // GetMetricRequest returns a metric request for the given panel and query
 func (pd *PublicDashboardServiceImpl) GetMetricRequest(ctx context.Context, dashboard *dashboards.Dashboard, publicDashboard *models.PublicDashboard, panelId int64, queryDto models.PublicDashboardQueryDTO) (dtos.MetricRequest, error) {
-	err := validation.ValidateQueryPublicDashboardRequest(queryDto, publicDashboard)
+	err := validation.ValidateQueryPublicDashboardRequest(queryDto, publicDashboard, dashboard.Data)
 	if err != nil {
 		return dtos.MetricRequest{}, err
 	}
@@ -165,9 +168,13 @@ func (pd *PublicDashboardServiceImpl) buildMetricRequest(dashboard *dashboards.D
 
 	ts := buildTimeSettings(dashboard, reqDTO, publicDashboard)
 
+	// the values requested by the viewer have been validated against the allowed ones of the public dashboard
+	vars := templating.CurrentValues(dashboard.Data).Merge(reqDTO.Variables)
+
 	// determine safe resolution to query data at
 	safeInterval, safeResolution := pd.getSafeIntervalAndMaxDataPoints(reqDTO, ts)
 	for i := range queries {
+		templating.Interpolate(queries[i], vars)
 		queries[i].Set("intervalMs", safeInterval)
 		queries[i].Set("maxDataPoints", safeResolution)
 		queries[i].Set("queryCachingTTL", reqDTO.QueryCachingTTL)
@@ -368,6 +375,54 @@ func sanitizeData(data *simplejson.Json) {
 	}
 }
 
+// sanitizeTemplateVariables turns the template variables viewers can change into custom variables limited to their
+// allowed values, and hides the others. In both cases, the queries of the variables are removed.
+func sanitizeTemplateVariables(data *simplejson.Json, allowed *models.TemplateVariables) {
+	for _, obj := range data.GetPath("templating", "list").MustArray() {
+		variable := simplejson.NewFromAny(obj)
+		variable.Del("definition")
+		variable.Del("datasource")
+		variable.Set("refresh", 0)
+		variable.Set("includeAll", false)
+
+		allowedVariable, ok := allowed.Find(variable.Get("name").MustString())
+		if !ok {
+			variable.Set("query", "")
+			variable.Set("options", []any{variable.Get("current").Interface()})
+			// hide the variable and its label
+			variable.Set("hide", 2)
+			continue
+		}
+
+		var selected []string
+		for _, value := range templating.SelectedValues(variable) {
+			if slices.Contains(allowedVariable.AllowedValues, value) {
+				selected = append(selected, value)
+			}
+		}
+		if len(selected) == 0 {
+			selected = allowedVariable.AllowedValues[:1]
+		}
+		if !variable.Get("multi").MustBool() {
+			selected = selected[:1]
+		}
+
+		options := make([]any, 0, len(allowedVariable.AllowedValues))
+		for _, value := range allowedVariable.AllowedValues {
+			options = append(options, map[string]any{
+				"text":     value,
+				"value":    value,
+				"selected": slices.Contains(selected, value),
+			})
+		}
+
+		variable.Set("type", "custom")
+		variable.Set("query", strings.Join(allowedVariable.AllowedValues, ","))
+		variable.Set("options", options)
+		templating.SetSelectedValues(variable, selected)
+	}
+}
+
 // NewTimeRange declared to be able to stub this function in tests
 var NewTimeRange = gtime.NewTimeRange
 
@@ -1319,6 +1319,97 @@ func TestBuildMetricRequest(t *testing.T) {
 	})
 }
 
+func TestBuildMetricRequestWithTemplateVariables(t *testing.T) {
+	service, _, _ := newPublicDashboardServiceImpl(t, nil, nil, nil)
+
+	data, err := simplejson.NewJson([]byte(`{
+		"time": {"from": "2022-09-01T00:00:00.000Z", "to": "2022-09-01T12:00:00.000Z"},
+		"templating": {
+			"list": [
+				{"name": "region", "type": "custom", "current": {"value": "eu"}},
+				{"name": "job", "type": "query", "current": {"value": "api"}}
+			]
+		},
+		"panels": [
+			{
+				"id": 1,
+				"datasource": {"uid": "ds1"},
+				"targets": [{"refId": "A", "datasource": {"uid": "ds1"}, "expr": "up{region=\"$region\", job=\"$job\"}"}]
+			}
+		]
+	}`))
+	require.NoError(t, err)
+	dashboard := &dashboards.Dashboard{UID: "dash1", OrgID: 1, Data: data}
+	pubdash := &PublicDashboard{
+		TemplateVariables: &TemplateVariables{{Name: "region", AllowedValues: []string{"eu", "us"}}},
+	}
+
+	reqDTO, err := service.buildMetricRequest(dashboard, pubdash, 1, PublicDashboardQueryDTO{
+		Variables: map[string][]string{"region": {"us"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, reqDTO.Queries, 1)
+	// the selected value replaces the saved one, the other variables keep the saved value
+	require.Equal(t, `up{region="us", job="api"}`, reqDTO.Queries[0].Get("expr").MustString())
+}
+
+func TestSanitizeTemplateVariables(t *testing.T) {
+	data, err := simplejson.NewJson([]byte(`{
+		"templating": {
+			"list": [
+				{
+					"name": "region",
+					"type": "query",
+					"query": "label_values(region)",
+					"definition": "label_values(region)",
+					"datasource": {"uid": "ds1"},
+					"current": {"value": "internal"},
+					"refresh": 1
+				},
+				{
+					"name": "job",
+					"type": "query",
+					"query": "label_values(job)",
+					"current": {"text": "api", "value": "api"}
+				},
+				{
+					"name": "site",
+					"type": "custom",
+					"query": "paris,berlin",
+					"current": {"value": ["paris", "berlin"]}
+				}
+			]
+		}
+	}`))
+	require.NoError(t, err)
+
+	sanitizeTemplateVariables(data, &TemplateVariables{
+		{Name: "region", AllowedValues: []string{"eu", "us"}},
+		{Name: "site", AllowedValues: []string{"paris", "berlin"}},
+	})
+
+	region := data.GetPath("templating", "list").GetIndex(0)
+	require.Equal(t, "custom", region.Get("type").MustString())
+	require.Equal(t, "eu,us", region.Get("query").MustString())
+	require.Len(t, region.Get("options").MustArray(), 2)
+	// the saved value is not allowed, the first allowed one is selected instead
+	require.Equal(t, "eu", region.GetPath("current", "value").MustString())
+	require.Equal(t, 0, region.Get("refresh").MustInt())
+	_, hasDefinition := region.CheckGet("definition")
+	require.False(t, hasDefinition)
+	_, hasDatasource := region.CheckGet("datasource")
+	require.False(t, hasDatasource)
+
+	job := data.GetPath("templating", "list").GetIndex(1)
+	require.Equal(t, "", job.Get("query").MustString())
+	require.Equal(t, 2, job.Get("hide").MustInt())
+	require.Equal(t, "api", job.GetPath("current", "value").MustString())
+
+	// a single value template variable keeps only the first of its saved values
+	site := data.GetPath("templating", "list").GetIndex(2)
+	require.Equal(t, "paris", site.GetPath("current", "value").MustString())
+}
+
 func TestBuildAnonymousUser(t *testing.T) {
 	sqlStore, cfg := db.InitTestDBWithCfg(t)
 	dashboardStore, err := dashboardsDB.ProvideDashboardStore(sqlStore, cfg, featuremgmt.WithFeatures(), tagimpl.ProvideService(sqlStore), quotatest.New(false, nil))
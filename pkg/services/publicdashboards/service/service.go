@@ -43,6 +43,8 @@ type PublicDashboardServiceImpl struct {
 	serviceWrapper     publicdashboards.ServiceWrapper
 	dashboardService   dashboards.DashboardService
 	license            licensing.Licensing
+	// views are the views recorded by RecordView, waiting to be stored by Run
+	views chan *PublicDashboardView
 }
 
 var LogPrefix = "publicdashboards.service"
@@ -77,6 +79,7 @@ func ProvideService(
 		serviceWrapper:     serviceWrapper,
 		dashboardService:   dashboardService,
 		license:            license,
+		views:              make(chan *PublicDashboardView, viewQueueSize),
 	}
 }
 
@@ -109,6 +112,7 @@ func (pd *PublicDashboardServiceImpl) GetPublicDashboardForView(ctx context.Cont
 	dash.Data.Get("timepicker").Set("hidden", !pubdash.TimeSelectionEnabled)
 
 	sanitizeData(dash.Data)
+	sanitizeTemplateVariables(dash.Data, pubdash.TemplateVariables)
 
 	return &dtos.DashboardFullWithMeta{Meta: meta, Dashboard: dash.Data}, nil
 }
@@ -177,6 +181,11 @@ func (pd *PublicDashboardServiceImpl) FindEnabledPublicDashboardAndDashboardByAc
 		return nil, nil, ErrPublicDashboardNotEnabled.Errorf("FindEnabledPublicDashboardAndDashboardByAccessToken: Public dashboard is not enabled accessToken: %s", accessToken)
 	}
 
+	// expired public dashboards are disabled by the cleanup job, until then they must not be served
+	if pubdash.IsExpired(time.Now()) {
+		return nil, nil, ErrPublicDashboardExpired.Errorf("FindEnabledPublicDashboardAndDashboardByAccessToken: Public dashboard expired accessToken: %s", accessToken)
+	}
+
 	if !pd.license.FeatureEnabled(FeaturePublicDashboardsEmailSharing) && pubdash.Share == EmailShareType {
 		return nil, nil, ErrPublicDashboardNotFound.Errorf("FindEnabledPublicDashboardAndDashboardByAccessToken: Dashboard not found accessToken: %s", accessToken)
 	}
@@ -216,7 +225,12 @@ func (pd *PublicDashboardServiceImpl) Create(ctx context.Context, u *user.Signed
 	}
 
 	// ensure dashboard exists
-	_, err = pd.FindDashboard(ctx, u.OrgID, dto.DashboardUid)
+	dash, err := pd.FindDashboard(ctx, u.OrgID, dto.DashboardUid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validation.ValidateTemplateVariables(dto.PublicDashboard.TemplateVariables, dash.Data)
 	if err != nil {
 		return nil, err
 	}
@@ -275,7 +289,12 @@ func (pd *PublicDashboardServiceImpl) Update(ctx context.Context, u *user.Signed
 	}
 
 	// validate dashboard exists
-	_, err = pd.FindDashboard(ctx, u.OrgID, dto.DashboardUid)
+	dash, err := pd.FindDashboard(ctx, u.OrgID, dto.DashboardUid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validation.ValidateTemplateVariables(dto.PublicDashboard.TemplateVariables, dash.Data)
 	if err != nil {
 		return nil, err
 	}
@@ -293,7 +312,10 @@ func (pd *PublicDashboardServiceImpl) Update(ctx context.Context, u *user.Signed
 		return nil, ErrInvalidUid.Errorf("Update: the public dashboard does not belong to the dashboard")
 	}
 
-	publicDashboard := newUpdatePublicDashboard(dto, existingPubdash)
+	publicDashboard, err := newUpdatePublicDashboard(dto, existingPubdash)
+	if err != nil {
+		return nil, err
+	}
 
 	// set values to update
 	cmd := SavePublicDashboardCommand{
@@ -542,6 +564,20 @@ func (pd *PublicDashboardServiceImpl) newCreatePublicDashboard(ctx context.Conte
 		share = PublicShareType
 	}
 
+	expiresAt, err := parseExpiresAt(dto.PublicDashboard.ExpiresAt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var password string
+	if dto.PublicDashboard.Password != nil {
+		password = *dto.PublicDashboard.Password
+	}
+	passwordHash, passwordSalt, err := hashPassword(password)
+	if err != nil {
+		return nil, ErrInternalServerError.Errorf("Create: failed to hash the public dashboard password: %w", err)
+	}
+
 	now := time.Now()
 
 	return &PublicDashboard{
@@ -552,6 +588,10 @@ func (pd *PublicDashboardServiceImpl) newCreatePublicDashboard(ctx context.Conte
 		AnnotationsEnabled:   annotationsEnabled,
 		TimeSelectionEnabled: timeSelectionEnabled,
 		TimeSettings:         &TimeSettings{},
+		TemplateVariables:    dto.PublicDashboard.TemplateVariables,
+		ExpiresAt:            expiresAt,
+		PasswordHash:         passwordHash,
+		PasswordSalt:         passwordSalt,
 		Share:                share,
 		CreatedBy:            dto.UserId,
 		CreatedAt:            now,
@@ -561,7 +601,7 @@ func (pd *PublicDashboardServiceImpl) newCreatePublicDashboard(ctx context.Conte
 	}, nil
 }
 
-func newUpdatePublicDashboard(dto *SavePublicDashboardDTO, pd *PublicDashboard) *PublicDashboard {
+func newUpdatePublicDashboard(dto *SavePublicDashboardDTO, pd *PublicDashboard) (*PublicDashboard, error) {
 	pubdashDTO := dto.PublicDashboard
 	timeSelectionEnabled := returnValueOrDefault(pubdashDTO.TimeSelectionEnabled, pd.TimeSelectionEnabled)
 	isEnabled := returnValueOrDefault(pubdashDTO.IsEnabled, pd.IsEnabled)
@@ -572,16 +612,54 @@ func newUpdatePublicDashboard(dto *SavePublicDashboardDTO, pd *PublicDashboard)
 		share = pd.Share
 	}
 
+	templateVariables := pubdashDTO.TemplateVariables
+	if templateVariables == nil {
+		templateVariables = pd.TemplateVariables
+	}
+
+	expiresAt, err := parseExpiresAt(pubdashDTO.ExpiresAt, pd.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHash, passwordSalt := pd.PasswordHash, pd.PasswordSalt
+	if pubdashDTO.Password != nil {
+		passwordHash, passwordSalt, err = hashPassword(*pubdashDTO.Password)
+		if err != nil {
+			return nil, ErrInternalServerError.Errorf("Update: failed to hash the public dashboard password: %w", err)
+		}
+	}
+
 	return &PublicDashboard{
 		Uid:                  pd.Uid,
 		IsEnabled:            isEnabled,
 		AnnotationsEnabled:   annotationsEnabled,
 		TimeSelectionEnabled: timeSelectionEnabled,
 		TimeSettings:         pd.TimeSettings,
+		TemplateVariables:    templateVariables,
+		ExpiresAt:            expiresAt,
+		PasswordHash:         passwordHash,
+		PasswordSalt:         passwordSalt,
 		Share:                share,
 		UpdatedBy:            dto.UserId,
 		UpdatedAt:            time.Now(),
+	}, nil
+}
+
+// parseExpiresAt returns the expiration set by the user, an empty value removes it and nil keeps defaultValue
+func parseExpiresAt(value *string, defaultValue *time.Time) (*time.Time, error) {
+	if value == nil {
+		return defaultValue, nil
+	}
+	if *value == "" {
+		return nil, nil
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, ErrInvalidExpiration.Errorf("parseExpiresAt: invalid expiration: %w", err)
 	}
+	return &expiresAt, nil
 }
 
 func returnValueOrDefault(value *bool, defaultValue bool) bool {
@@ -1,22 +1,81 @@
 package validation
 
 import (
+	"slices"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	. "github.com/grafana/grafana/pkg/services/publicdashboards/models"
 	"github.com/grafana/grafana/pkg/util"
 )
 
+// template variables that can't be changed by viewers: the anonymous user of a public dashboard can only query the
+// data sources referenced by its panels, and ad hoc filters can't be restricted to a list of values
+var unsupportedTemplateVariableTypes = map[string]bool{"datasource": true, "adhoc": true}
+
 func ValidatePublicDashboard(dto *SavePublicDashboardDTO) error {
 	// if it is empty we override it in the service with public for retro compatibility
 	if dto.PublicDashboard.Share != "" && !IsValidShareType(dto.PublicDashboard.Share) {
 		return ErrInvalidShareType.Errorf("ValidateSavePublicDashboard: invalid share type")
 	}
 
+	if expiresAt := dto.PublicDashboard.ExpiresAt; expiresAt != nil && *expiresAt != "" {
+		t, err := time.Parse(time.RFC3339, *expiresAt)
+		if err != nil {
+			return ErrInvalidExpiration.Errorf("ValidateSavePublicDashboard: invalid expiration: %w", err)
+		}
+		if !t.After(time.Now()) {
+			return ErrInvalidExpiration.Errorf("ValidateSavePublicDashboard: expiration %s is in the past", *expiresAt)
+		}
+	}
+
+	// an empty password removes the password protection
+	if password := dto.PublicDashboard.Password; password != nil && *password != "" && len(*password) < MinPasswordLength {
+		return ErrInvalidPassword.Errorf("ValidateSavePublicDashboard: password is too short")
+	}
+
 	return nil
 }
 
-func ValidateQueryPublicDashboardRequest(req PublicDashboardQueryDTO, pd *PublicDashboard) error {
+// ValidateTemplateVariables checks the template variables viewers can change exist in the dashboard, and that they
+// can select at least one value
+func ValidateTemplateVariables(vars *TemplateVariables, dashboard *simplejson.Json) error {
+	if vars == nil {
+		return nil
+	}
+
+	types := make(map[string]string)
+	for _, obj := range dashboard.GetPath("templating", "list").MustArray() {
+		variable := simplejson.NewFromAny(obj)
+		types[variable.Get("name").MustString()] = variable.Get("type").MustString()
+	}
+
+	seen := make(map[string]bool, len(*vars))
+	for _, v := range *vars {
+		varType, ok := types[v.Name]
+		if !ok {
+			return ErrInvalidTemplateVariables.Errorf("ValidateTemplateVariables: dashboard has no template variable named %q", v.Name)
+		}
+		if unsupportedTemplateVariableTypes[varType] {
+			return ErrInvalidTemplateVariables.Errorf("ValidateTemplateVariables: template variable %q of type %s is not supported", v.Name, varType)
+		}
+		if seen[v.Name] {
+			return ErrInvalidTemplateVariables.Errorf("ValidateTemplateVariables: duplicate template variable %q", v.Name)
+		}
+		seen[v.Name] = true
+		if len(v.AllowedValues) == 0 {
+			return ErrInvalidTemplateVariables.Errorf("ValidateTemplateVariables: template variable %q has no allowed values", v.Name)
+		}
+	}
+
+	return nil
+}
+
+// ValidateQueryPublicDashboardRequest checks the query of a panel of the public dashboard, dashboard is the JSON model
+// of the dashboard used to check the template variable values
+func ValidateQueryPublicDashboardRequest(req PublicDashboardQueryDTO, pd *PublicDashboard, dashboard *simplejson.Json) error {
 	if req.IntervalMs < 0 {
 		return ErrInvalidInterval.Errorf("ValidateQueryPublicDashboardRequest: intervalMS should be greater than 0")
 	}
@@ -38,6 +97,24 @@ func ValidateQueryPublicDashboardRequest(req PublicDashboardQueryDTO, pd *Public
 		}
 	}
 
+	for name, values := range req.Variables {
+		variable, ok := pd.TemplateVariables.Find(name)
+		if !ok {
+			return ErrInvalidTemplateVariableValue.Errorf("ValidateQueryPublicDashboardRequest: template variable %q cannot be changed", name)
+		}
+		if len(values) == 0 {
+			return ErrInvalidTemplateVariableValue.Errorf("ValidateQueryPublicDashboardRequest: template variable %q has no value", name)
+		}
+		if len(values) > 1 && !isMultiValue(dashboard, name) {
+			return ErrInvalidTemplateVariableValue.Errorf("ValidateQueryPublicDashboardRequest: template variable %q accepts a single value", name)
+		}
+		for _, value := range values {
+			if !slices.Contains(variable.AllowedValues, value) {
+				return ErrInvalidTemplateVariableValue.Errorf("ValidateQueryPublicDashboardRequest: value %q is not allowed for template variable %q", value, name)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -61,3 +138,14 @@ func IsValidShareType(shareType ShareType) bool {
 	}
 	return false
 }
+
+// isMultiValue returns true when several values can be selected for the dashboard template variable name
+func isMultiValue(dashboard *simplejson.Json, name string) bool {
+	for _, obj := range dashboard.GetPath("templating", "list").MustArray() {
+		variable := simplejson.NewFromAny(obj)
+		if variable.Get("name").MustString() == name {
+			return variable.Get("multi").MustBool()
+		}
+	}
+	return false
+}
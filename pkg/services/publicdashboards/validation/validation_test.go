@@ -2,7 +2,9 @@ package validation
 
 import (
 	"testing"
+	"time"
 
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	. "github.com/grafana/grafana/pkg/services/publicdashboards/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -22,9 +24,106 @@ func TestValidatePublicDashboard(t *testing.T) {
 		err := ValidatePublicDashboard(dto)
 		require.Error(t, err)
 	})
+
+	t.Run("Returns no error when the expiration is in the future", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+		dto := &SavePublicDashboardDTO{DashboardUid: "abc123", UserId: 1, PublicDashboard: &PublicDashboardDTO{ExpiresAt: &expiresAt}}
+
+		err := ValidatePublicDashboard(dto)
+		require.NoError(t, err)
+	})
+
+	t.Run("Returns no error when the expiration is removed", func(t *testing.T) {
+		expiresAt := ""
+		dto := &SavePublicDashboardDTO{DashboardUid: "abc123", UserId: 1, PublicDashboard: &PublicDashboardDTO{ExpiresAt: &expiresAt}}
+
+		err := ValidatePublicDashboard(dto)
+		require.NoError(t, err)
+	})
+
+	t.Run("Returns error when the expiration is in the past", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Hour).Format(time.RFC3339)
+		dto := &SavePublicDashboardDTO{DashboardUid: "abc123", UserId: 1, PublicDashboard: &PublicDashboardDTO{ExpiresAt: &expiresAt}}
+
+		err := ValidatePublicDashboard(dto)
+		require.ErrorIs(t, err, ErrInvalidExpiration)
+	})
+
+	t.Run("Returns error when the expiration is not a timestamp", func(t *testing.T) {
+		expiresAt := "tomorrow"
+		dto := &SavePublicDashboardDTO{DashboardUid: "abc123", UserId: 1, PublicDashboard: &PublicDashboardDTO{ExpiresAt: &expiresAt}}
+
+		err := ValidatePublicDashboard(dto)
+		require.ErrorIs(t, err, ErrInvalidExpiration)
+	})
+
+	t.Run("Returns error when the password is too short", func(t *testing.T) {
+		password := "secret"
+		dto := &SavePublicDashboardDTO{DashboardUid: "abc123", UserId: 1, PublicDashboard: &PublicDashboardDTO{Password: &password}}
+
+		err := ValidatePublicDashboard(dto)
+		require.ErrorIs(t, err, ErrInvalidPassword)
+	})
+
+	t.Run("Returns no error when the password is removed", func(t *testing.T) {
+		password := ""
+		dto := &SavePublicDashboardDTO{DashboardUid: "abc123", UserId: 1, PublicDashboard: &PublicDashboardDTO{Password: &password}}
+
+		err := ValidatePublicDashboard(dto)
+		require.NoError(t, err)
+	})
+}
+
+func TestValidateTemplateVariables(t *testing.T) {
+	dashboard, err := simplejson.NewJson([]byte(`{
+		"templating": {
+			"list": [
+				{"name": "region", "type": "custom"},
+				{"name": "ds", "type": "datasource"}
+			]
+		}
+	}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		vars    *TemplateVariables
+		wantErr bool
+	}{
+		{name: "Returns no error when template variables are not set", vars: nil},
+		{name: "Returns no error when template variables are valid", vars: &TemplateVariables{{Name: "region", AllowedValues: []string{"eu"}}}},
+		{name: "Returns error when the dashboard has no such template variable", vars: &TemplateVariables{{Name: "env", AllowedValues: []string{"prod"}}}, wantErr: true},
+		{name: "Returns error when the template variable type is not supported", vars: &TemplateVariables{{Name: "ds", AllowedValues: []string{"prom"}}}, wantErr: true},
+		{name: "Returns error when there are no allowed values", vars: &TemplateVariables{{Name: "region"}}, wantErr: true},
+		{
+			name:    "Returns error when a template variable is duplicated",
+			vars:    &TemplateVariables{{Name: "region", AllowedValues: []string{"eu"}}, {Name: "region", AllowedValues: []string{"us"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTemplateVariables(tt.vars, dashboard)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidTemplateVariables)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
 }
 
 func TestValidateQueryPublicDashboardRequest(t *testing.T) {
+	dashboard, err := simplejson.NewJson([]byte(`{
+		"templating": {
+			"list": [
+				{"name": "region", "type": "custom", "multi": true},
+				{"name": "site", "type": "custom"}
+			]
+		}
+	}`))
+	require.NoError(t, err)
+
 	type args struct {
 		req PublicDashboardQueryDTO
 		pd  *PublicDashboard
@@ -114,6 +213,76 @@ func TestValidateQueryPublicDashboardRequest(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Returns no error when template variable values are allowed",
+			args: args{
+				req: PublicDashboardQueryDTO{
+					Variables: map[string][]string{"region": {"eu", "us"}},
+				},
+				pd: &PublicDashboard{
+					TemplateVariables: &TemplateVariables{{Name: "region", AllowedValues: []string{"eu", "us"}}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Returns validation error when template variable value is not allowed",
+			args: args{
+				req: PublicDashboardQueryDTO{
+					Variables: map[string][]string{"region": {"eu", "internal"}},
+				},
+				pd: &PublicDashboard{
+					TemplateVariables: &TemplateVariables{{Name: "region", AllowedValues: []string{"eu", "us"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Returns validation error when several values are selected for a single value template variable",
+			args: args{
+				req: PublicDashboardQueryDTO{
+					Variables: map[string][]string{"site": {"paris", "berlin"}},
+				},
+				pd: &PublicDashboard{
+					TemplateVariables: &TemplateVariables{{Name: "site", AllowedValues: []string{"paris", "berlin"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Returns no error when a single value is selected for a single value template variable",
+			args: args{
+				req: PublicDashboardQueryDTO{
+					Variables: map[string][]string{"site": {"berlin"}},
+				},
+				pd: &PublicDashboard{
+					TemplateVariables: &TemplateVariables{{Name: "site", AllowedValues: []string{"paris", "berlin"}}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Returns validation error when template variable cannot be changed",
+			args: args{
+				req: PublicDashboardQueryDTO{
+					Variables: map[string][]string{"env": {"prod"}},
+				},
+				pd: &PublicDashboard{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Returns validation error when template variable has no value",
+			args: args{
+				req: PublicDashboardQueryDTO{
+					Variables: map[string][]string{"region": {}},
+				},
+				pd: &PublicDashboard{
+					TemplateVariables: &TemplateVariables{{Name: "region", AllowedValues: []string{"eu"}}},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "Returns validation error when time range from or to is blank",
 			args: args{
@@ -132,7 +301,7 @@ func TestValidateQueryPublicDashboardRequest(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := ValidateQueryPublicDashboardRequest(tt.args.req, tt.args.pd); (err != nil) != tt.wantErr {
+			if err := ValidateQueryPublicDashboardRequest(tt.args.req, tt.args.pd, dashboard); (err != nil) != tt.wantErr {
 				t.Errorf("ValidateQueryPublicDashboardRequest() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -0,0 +1,174 @@
+package query
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/apimachinery/identity"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/tsdb/grafanads"
+)
+
+var queriesCoalesced = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "grafana",
+	Subsystem: "query",
+	Name:      "coalesced_total",
+	Help:      "Number of data source queries answered by an identical query that was already in flight",
+}, []string{"datasource_type"})
+
+// queryCoalescer runs identical in-flight queries only once and shares the response.
+type queryCoalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done    chan struct{}
+	resp    *backend.QueryDataResponse
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+func newQueryCoalescer() *queryCoalescer {
+	return &queryCoalescer{
+		calls: map[string]*coalescedCall{},
+	}
+}
+
+// do runs fn unless a call with the same key is already in flight, in which case it waits for
+// that call instead. The call is not bound to the context of the caller that started it, it is
+// only canceled once every caller waiting for it went away.
+func (c *queryCoalescer) do(ctx context.Context, key string, fn func(ctx context.Context) (*backend.QueryDataResponse, error)) (*backend.QueryDataResponse, bool, error) {
+	c.mu.Lock()
+	call, shared := c.calls[key]
+	if !shared {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &coalescedCall{
+			done:   make(chan struct{}),
+			cancel: cancel,
+		}
+		c.calls[key] = call
+
+		go func() {
+			defer cancel()
+			resp, err := fn(callCtx)
+
+			c.mu.Lock()
+			call.resp, call.err = resp, err
+			if c.calls[key] == call {
+				delete(c.calls, key)
+			}
+			c.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil || call.resp == nil {
+			return call.resp, shared, call.err
+		}
+		// Every caller gets its own response map, the frames are shared and must not be modified.
+		return &backend.QueryDataResponse{Responses: maps.Clone(call.resp.Responses)}, shared, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// Nobody is interested in the response anymore, later callers start a new call.
+			call.cancel()
+			if c.calls[key] == call {
+				delete(c.calls, key)
+			}
+		}
+		c.mu.Unlock()
+		return nil, shared, ctx.Err()
+	}
+}
+
+// coalesceKey identifies queries returning the same response: the same queries with the same
+// time range against the same version of a data source. Unless the data source is identity agnostic,
+// queries are only coalesced for the same user, with the same user and headers sent to the plugin.
+func coalesceKey(user identity.Requester, req *backend.QueryDataRequest, ds *datasources.DataSource, queries []parsedQuery, identityAgnostic bool) (string, error) {
+	type keyQuery struct {
+		RefID         string `json:"refId"`
+		From          int64  `json:"from"`
+		To            int64  `json:"to"`
+		MaxDataPoints int64  `json:"maxDataPoints"`
+		IntervalMS    int64  `json:"intervalMs"`
+		QueryType     string `json:"queryType"`
+		Model         any    `json:"model"`
+	}
+
+	key := struct {
+		OrgID      int64             `json:"orgId"`
+		UID        string            `json:"uid"`
+		Updated    int64             `json:"updated"`
+		User       string            `json:"user,omitempty"`
+		PluginUser *backend.User     `json:"pluginUser,omitempty"`
+		Headers    map[string]string `json:"headers,omitempty"`
+		Queries    []keyQuery        `json:"queries"`
+	}{
+		OrgID:   ds.OrgID,
+		UID:     ds.UID,
+		Updated: ds.Updated.UnixNano(),
+	}
+	if !identityAgnostic || forwardsIdentity(ds) {
+		if user != nil {
+			key.User = user.GetCacheKey()
+		}
+		key.PluginUser = req.PluginContext.User
+		key.Headers = req.Headers
+	}
+
+	for _, pq := range queries {
+		// Decoding and encoding the model again normalizes the key order and whitespace.
+		var model any
+		if err := json.Unmarshal(pq.query.JSON, &model); err != nil {
+			return "", err
+		}
+		key.Queries = append(key.Queries, keyQuery{
+			RefID:         pq.query.RefID,
+			From:          pq.query.TimeRange.From.UnixMilli(),
+			To:            pq.query.TimeRange.To.UnixMilli(),
+			MaxDataPoints: pq.query.MaxDataPoints,
+			IntervalMS:    pq.query.Interval.Milliseconds(),
+			QueryType:     pq.query.QueryType,
+			Model:         model,
+		})
+	}
+
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// forwardsIdentity reports whether the response of the data source can depend on the user
+// running the query, because it forwards the user's token, cookies or team headers, or
+// checks the user's permissions like the Grafana data source.
+func forwardsIdentity(ds *datasources.DataSource) bool {
+	if ds.UID == grafanads.DatasourceUID {
+		return true
+	}
+	if ds.JsonData == nil {
+		return false
+	}
+	if ds.JsonData.Get("oauthPassThru").MustBool() || len(ds.AllowedCookies()) > 0 {
+		return true
+	}
+	_, hasTeamHeaders := ds.JsonData.CheckGet("teamHttpHeaders")
+	return hasTeamHeaders
+}
@@ -0,0 +1,168 @@
+package query
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestQueryCoalescer(t *testing.T) {
+	t.Run("identical in-flight calls run once", func(t *testing.T) {
+		c := newQueryCoalescer()
+		var calls atomic.Int32
+		unblock := make(chan struct{})
+		fn := func(ctx context.Context) (*backend.QueryDataResponse, error) {
+			calls.Add(1)
+			<-unblock
+			return &backend.QueryDataResponse{Responses: backend.Responses{"A": {}}}, nil
+		}
+
+		var wg sync.WaitGroup
+		var sharedCount atomic.Int32
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, shared, err := c.do(context.Background(), "key", fn)
+				assert.NoError(t, err)
+				assert.Contains(t, resp.Responses, "A")
+				if shared {
+					sharedCount.Add(1)
+				}
+			}()
+		}
+		require.Eventually(t, func() bool {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.calls["key"] != nil && c.calls["key"].waiters == 5
+		}, time.Second, time.Millisecond)
+		close(unblock)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), calls.Load())
+		assert.Equal(t, int32(4), sharedCount.Load())
+		assert.Empty(t, c.calls)
+	})
+
+	t.Run("the call is canceled once all callers went away", func(t *testing.T) {
+		c := newQueryCoalescer()
+		canceled := make(chan struct{})
+		fn := func(ctx context.Context) (*backend.QueryDataResponse, error) {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		}
+
+		ctx1, cancel1 := context.WithCancel(context.Background())
+		ctx2, cancel2 := context.WithCancel(context.Background())
+		errs := make(chan error, 2)
+		go func() {
+			_, _, err := c.do(ctx1, "key", fn)
+			errs <- err
+		}()
+		require.Eventually(t, func() bool {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.calls["key"] != nil
+		}, time.Second, time.Millisecond)
+		go func() {
+			_, _, err := c.do(ctx2, "key", fn)
+			errs <- err
+		}()
+		require.Eventually(t, func() bool {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.calls["key"] != nil && c.calls["key"].waiters == 2
+		}, time.Second, time.Millisecond)
+
+		cancel1()
+		require.ErrorIs(t, <-errs, context.Canceled)
+		select {
+		case <-canceled:
+			t.Fatal("the call must keep running while a caller waits for it")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		cancel2()
+		require.ErrorIs(t, <-errs, context.Canceled)
+		<-canceled
+	})
+}
+
+func TestCoalesceKey(t *testing.T) {
+	ds := &datasources.DataSource{UID: "ds", OrgID: 1, JsonData: simplejson.New()}
+	from := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	query := func(model string, to time.Time) parsedQuery {
+		return parsedQuery{query: backend.DataQuery{
+			RefID:     "A",
+			JSON:      []byte(model),
+			TimeRange: backend.TimeRange{From: from, To: to},
+		}}
+	}
+	user1 := &user.SignedInUser{UserID: 1, OrgID: 1, Login: "alice"}
+	user2 := &user.SignedInUser{UserID: 2, OrgID: 1, Login: "bob"}
+
+	key := func(u *user.SignedInUser, ds *datasources.DataSource, q parsedQuery, identityAgnostic bool) string {
+		req := &backend.QueryDataRequest{
+			PluginContext: backend.PluginContext{User: &backend.User{Login: u.Login}},
+			Headers:       map[string]string{},
+		}
+		k, err := coalesceKey(u, req, ds, []parsedQuery{q}, identityAgnostic)
+		require.NoError(t, err)
+		return k
+	}
+
+	base := key(user1, ds, query(`{"refId": "A", "expr": "up"}`, from.Add(time.Hour)), true)
+
+	t.Run("ignores key order and whitespace", func(t *testing.T) {
+		assert.Equal(t, base, key(user1, ds, query(`{"expr":"up","refId":"A"}`, from.Add(time.Hour)), true))
+	})
+
+	t.Run("ignores the user when the data source is identity agnostic", func(t *testing.T) {
+		assert.Equal(t, base, key(user2, ds, query(`{"refId": "A", "expr": "up"}`, from.Add(time.Hour)), true))
+	})
+
+	t.Run("depends on the query and the time range", func(t *testing.T) {
+		assert.NotEqual(t, base, key(user1, ds, query(`{"refId": "A", "expr": "down"}`, from.Add(time.Hour)), true))
+		assert.NotEqual(t, base, key(user1, ds, query(`{"refId": "A", "expr": "up"}`, from.Add(2*time.Hour)), true))
+	})
+
+	t.Run("depends on the user by default", func(t *testing.T) {
+		q := query(`{"refId": "A", "expr": "up"}`, from.Add(time.Hour))
+		assert.NotEqual(t, key(user1, ds, q, false), key(user2, ds, q, false))
+	})
+
+	t.Run("depends on the user and headers sent to the plugin", func(t *testing.T) {
+		q := query(`{"refId": "A", "expr": "up"}`, from.Add(time.Hour))
+		req := func(login, header string) *backend.QueryDataRequest {
+			return &backend.QueryDataRequest{
+				PluginContext: backend.PluginContext{User: &backend.User{Login: login}},
+				Headers:       map[string]string{"X-Grafana-User": header},
+			}
+		}
+		k1, err := coalesceKey(user1, req("alice", "alice"), ds, []parsedQuery{q}, false)
+		require.NoError(t, err)
+		k2, err := coalesceKey(user1, req("bob", "alice"), ds, []parsedQuery{q}, false)
+		require.NoError(t, err)
+		k3, err := coalesceKey(user1, req("alice", "bob"), ds, []parsedQuery{q}, false)
+		require.NoError(t, err)
+		assert.NotEqual(t, k1, k2)
+		assert.NotEqual(t, k1, k3)
+	})
+
+	t.Run("depends on the user when the data source forwards the identity", func(t *testing.T) {
+		forwarding := &datasources.DataSource{UID: "ds", OrgID: 1, JsonData: simplejson.NewFromAny(map[string]any{"oauthPassThru": true})}
+		q := query(`{"refId": "A", "expr": "up"}`, from.Add(time.Hour))
+		assert.NotEqual(t, key(user1, forwarding, q, true), key(user2, forwarding, q, true))
+	})
+}
@@ -0,0 +1,218 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+)
+
+var ErrQueryQueueTimeout = errutil.TooManyRequests("query.queueTimeout", errutil.WithPublicMessage("Too many concurrent queries, try again later")).Errorf("query waited too long for a free slot")
+
+var (
+	queryQueueDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "grafana",
+		Subsystem: "query",
+		Name:      "queue_duration_seconds",
+		Help:      "Time data source queries waited for a free slot because of the concurrency limits",
+		Buckets:   []float64{.001, .01, .05, .1, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"datasource_type", "limit"})
+
+	queryQueueLength = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "grafana",
+		Subsystem: "query",
+		Name:      "queued_queries",
+		Help:      "Number of data source queries waiting for a free slot",
+	}, []string{"limit"})
+
+	queryQueueTimeouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "query",
+		Name:      "queue_timeouts_total",
+		Help:      "Number of data source queries rejected because they waited too long for a free slot",
+	}, []string{"datasource_type", "limit"})
+)
+
+const (
+	limitDatasource = "datasource"
+	limitUser       = "user"
+)
+
+// queryLimiter bounds the number of concurrent data source queries per data source and per user.
+// Queries waiting for a data source slot are served round-robin per user, so a single user
+// sending many queries can't starve everybody else.
+type queryLimiter struct {
+	mu              sync.Mutex
+	datasourceLimit int
+	userLimit       int
+	maxQueueWait    time.Duration
+	queues          map[string]*slotQueue
+}
+
+// slotQueue hands out a limited number of slots and keeps the queries waiting for one.
+type slotQueue struct {
+	inFlight int
+	waiting  map[string][]*queuedQuery
+	// order of the keys with waiting queries, the next slot goes to the first one
+	order []string
+}
+
+type queuedQuery struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func newQueryLimiter(datasourceLimit, userLimit int, maxQueueWait time.Duration) *queryLimiter {
+	return &queryLimiter{
+		datasourceLimit: datasourceLimit,
+		userLimit:       userLimit,
+		maxQueueWait:    maxQueueWait,
+		queues:          map[string]*slotQueue{},
+	}
+}
+
+// acquire waits for a free slot for the user and the data source. The returned function
+// releases the slots and must be called once the query finished.
+func (l *queryLimiter) acquire(ctx context.Context, userKey string, dsKey string, dsType string) (func(), error) {
+	if l.maxQueueWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.maxQueueWait)
+		defer cancel()
+	}
+
+	// The user slot is taken first so a query doesn't hold a data source slot while it
+	// waits for one of its own user's queries to finish.
+	releaseUser, err := l.acquireSlot(ctx, "user:"+userKey, l.userLimit, "", dsType, limitUser)
+	if err != nil {
+		return nil, err
+	}
+	releaseDatasource, err := l.acquireSlot(ctx, "ds:"+dsKey, l.datasourceLimit, userKey, dsType, limitDatasource)
+	if err != nil {
+		releaseUser()
+		return nil, err
+	}
+
+	return func() {
+		releaseDatasource()
+		releaseUser()
+	}, nil
+}
+
+func (l *queryLimiter) acquireSlot(ctx context.Context, key string, limit int, fairKey string, dsType string, limitType string) (func(), error) {
+	if limit <= 0 {
+		return func() {}, nil
+	}
+	release := func() { l.release(key) }
+
+	l.mu.Lock()
+	q, ok := l.queues[key]
+	if !ok {
+		q = &slotQueue{waiting: map[string][]*queuedQuery{}}
+		l.queues[key] = q
+	}
+	if q.inFlight < limit && len(q.order) == 0 {
+		q.inFlight++
+		l.mu.Unlock()
+		return release, nil
+	}
+
+	waiter := &queuedQuery{ready: make(chan struct{})}
+	if len(q.waiting[fairKey]) == 0 {
+		q.order = append(q.order, fairKey)
+	}
+	q.waiting[fairKey] = append(q.waiting[fairKey], waiter)
+	l.mu.Unlock()
+
+	queueLength := queryQueueLength.WithLabelValues(limitType)
+	queueLength.Inc()
+	defer queueLength.Dec()
+
+	start := time.Now()
+	select {
+	case <-waiter.ready:
+		queryQueueDuration.WithLabelValues(dsType, limitType).Observe(time.Since(start).Seconds())
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	if waiter.granted {
+		// The slot was handed over while the context was canceled, pass it on.
+		l.mu.Unlock()
+		release()
+	} else {
+		q.remove(fairKey, waiter)
+		l.mu.Unlock()
+	}
+
+	queryQueueDuration.WithLabelValues(dsType, limitType).Observe(time.Since(start).Seconds())
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		queryQueueTimeouts.WithLabelValues(dsType, limitType).Inc()
+		return nil, ErrQueryQueueTimeout
+	}
+	return nil, ctx.Err()
+}
+
+// release hands the slot over to the next waiting query, or frees it.
+func (l *queryLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	q, ok := l.queues[key]
+	if !ok {
+		return
+	}
+	if next := q.next(); next != nil {
+		next.granted = true
+		close(next.ready)
+		return
+	}
+	q.inFlight--
+	if q.inFlight <= 0 {
+		delete(l.queues, key)
+	}
+}
+
+// next pops the next waiting query, rotating between the keys with waiting queries.
+func (q *slotQueue) next() *queuedQuery {
+	if len(q.order) == 0 {
+		return nil
+	}
+	key := q.order[0]
+	q.order = q.order[1:]
+	waiting := q.waiting[key]
+	next := waiting[0]
+	if len(waiting) > 1 {
+		q.waiting[key] = waiting[1:]
+		q.order = append(q.order, key)
+	} else {
+		delete(q.waiting, key)
+	}
+	return next
+}
+
+func (q *slotQueue) remove(key string, waiter *queuedQuery) {
+	waiting := q.waiting[key]
+	for i, w := range waiting {
+		if w == waiter {
+			waiting = append(waiting[:i], waiting[i+1:]...)
+			break
+		}
+	}
+	if len(waiting) > 0 {
+		q.waiting[key] = waiting
+		return
+	}
+	delete(q.waiting, key)
+	for i, k := range q.order {
+		if k == key {
+			q.order = append(q.order[:i], q.order[i+1:]...)
+			break
+		}
+	}
+}
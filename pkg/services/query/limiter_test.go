@@ -0,0 +1,121 @@
+package query
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLimiter(t *testing.T) {
+	t.Run("does not limit without limits", func(t *testing.T) {
+		l := newQueryLimiter(0, 0, 0)
+		for i := 0; i < 10; i++ {
+			_, err := l.acquire(context.Background(), "user-1", "1/ds", "prometheus")
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("limits the queries per data source", func(t *testing.T) {
+		l := newQueryLimiter(1, 0, 50*time.Millisecond)
+
+		release, err := l.acquire(context.Background(), "user-1", "1/ds", "prometheus")
+		require.NoError(t, err)
+
+		_, err = l.acquire(context.Background(), "user-2", "1/ds", "prometheus")
+		require.ErrorIs(t, err, ErrQueryQueueTimeout)
+
+		// other data sources are not affected
+		releaseOther, err := l.acquire(context.Background(), "user-2", "1/other", "prometheus")
+		require.NoError(t, err)
+		releaseOther()
+
+		release()
+		release, err = l.acquire(context.Background(), "user-2", "1/ds", "prometheus")
+		require.NoError(t, err)
+		release()
+		require.Empty(t, l.queues)
+	})
+
+	t.Run("limits the queries per user", func(t *testing.T) {
+		l := newQueryLimiter(0, 1, 50*time.Millisecond)
+
+		release, err := l.acquire(context.Background(), "user-1", "1/ds", "prometheus")
+		require.NoError(t, err)
+
+		_, err = l.acquire(context.Background(), "user-1", "1/other", "prometheus")
+		require.ErrorIs(t, err, ErrQueryQueueTimeout)
+
+		releaseOther, err := l.acquire(context.Background(), "user-2", "1/ds", "prometheus")
+		require.NoError(t, err)
+		releaseOther()
+		release()
+	})
+
+	t.Run("queued queries are served round-robin per user", func(t *testing.T) {
+		l := newQueryLimiter(1, 0, 0)
+
+		release, err := l.acquire(context.Background(), "busy", "1/ds", "prometheus")
+		require.NoError(t, err)
+
+		var mu sync.Mutex
+		var order []string
+		var wg sync.WaitGroup
+		enqueue := func(user string) {
+			queued := queuedCount(l, "ds:1/ds", user)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				release, err := l.acquire(context.Background(), user, "1/ds", "prometheus")
+				assert.NoError(t, err)
+				mu.Lock()
+				order = append(order, user)
+				mu.Unlock()
+				release()
+			}()
+			require.Eventually(t, func() bool { return queuedCount(l, "ds:1/ds", user) > queued }, time.Second, time.Millisecond)
+		}
+
+		enqueue("busy")
+		enqueue("busy")
+		enqueue("busy")
+		enqueue("other")
+
+		release()
+		wg.Wait()
+
+		assert.Equal(t, []string{"busy", "other", "busy", "busy"}, order)
+	})
+
+	t.Run("canceled queries leave the queue", func(t *testing.T) {
+		l := newQueryLimiter(1, 0, 0)
+
+		release, err := l.acquire(context.Background(), "user-1", "1/ds", "prometheus")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error)
+		go func() {
+			_, err := l.acquire(ctx, "user-2", "1/ds", "prometheus")
+			errs <- err
+		}()
+		require.Eventually(t, func() bool { return queuedCount(l, "ds:1/ds", "user-2") == 1 }, time.Second, time.Millisecond)
+		cancel()
+		require.ErrorIs(t, <-errs, context.Canceled)
+
+		release()
+		require.Empty(t, l.queues)
+	})
+}
+
+func queuedCount(l *queryLimiter, key string, fairKey string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if q, ok := l.queues[key]; ok {
+		return len(q.waiting[fairKey])
+	}
+	return 0
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/validations"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/tsdb/grafanads"
+	"github.com/grafana/grafana/pkg/util"
 )
 
 const (
@@ -46,6 +47,7 @@ func ProvideService(
 	pluginClient plugins.Client,
 	pCtxProvider *plugincontext.Provider,
 ) *ServiceImpl {
+	section := cfg.SectionWithEnvOverrides("query")
 	g := &ServiceImpl{
 		cfg:                    cfg,
 		dataSourceCache:        dataSourceCache,
@@ -54,7 +56,19 @@ func ProvideService(
 		pluginClient:           pluginClient,
 		pCtxProvider:           pCtxProvider,
 		log:                    log.New("query_data"),
-		concurrentQueryLimit:   cfg.SectionWithEnvOverrides("query").Key("concurrent_query_limit").MustInt(runtime.NumCPU()),
+		concurrentQueryLimit:   section.Key("concurrent_query_limit").MustInt(runtime.NumCPU()),
+		limiter: newQueryLimiter(
+			section.Key("datasource_concurrency_limit").MustInt(0),
+			section.Key("user_concurrency_limit").MustInt(0),
+			section.Key("max_queue_wait").MustDuration(0),
+		),
+	}
+	if section.Key("coalesce_identical_queries").MustBool(false) {
+		g.coalescer = newQueryCoalescer()
+		g.identityAgnosticTypes = map[string]bool{}
+		for _, dsType := range util.SplitString(section.Key("coalesce_identity_agnostic_datasource_types").String()) {
+			g.identityAgnosticTypes[dsType] = true
+		}
 	}
 	g.log.Info("Query Service initialization")
 	return g
@@ -78,6 +92,11 @@ type ServiceImpl struct {
 	pCtxProvider           *plugincontext.Provider
 	log                    log.Logger
 	concurrentQueryLimit   int
+	limiter                *queryLimiter
+	coalescer              *queryCoalescer
+	// identityAgnosticTypes are the data source types whose responses don't depend on the user,
+	// their queries are coalesced between users
+	identityAgnosticTypes map[string]bool
 }
 
 // Run ServiceImpl.
@@ -269,7 +288,37 @@ func (s *ServiceImpl) handleQuerySingleDatasource(ctx context.Context, user iden
 		req.Queries = append(req.Queries, q.query)
 	}
 
-	return s.pluginClient.QueryData(ctx, req)
+	queryData := func(ctx context.Context) (*backend.QueryDataResponse, error) {
+		release, err := s.limiter.acquire(ctx, userLimitKey(user), fmt.Sprintf("%d/%s", ds.OrgID, ds.UID), ds.Type)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return s.pluginClient.QueryData(ctx, req)
+	}
+
+	if s.coalescer == nil {
+		return queryData(ctx)
+	}
+
+	key, err := coalesceKey(user, req, ds, queries, s.identityAgnosticTypes[ds.Type])
+	if err != nil {
+		s.log.Debug("Failed to build the coalescing key, running the query on its own", "error", err)
+		return queryData(ctx)
+	}
+	resp, shared, err := s.coalescer.do(ctx, key, queryData)
+	if shared {
+		queriesCoalesced.WithLabelValues(ds.Type).Inc()
+	}
+	return resp, err
+}
+
+// userLimitKey identifies the user for the per user concurrency limit.
+func userLimitKey(user identity.Requester) string {
+	if user == nil {
+		return ""
+	}
+	return user.GetCacheKey()
 }
 
 // parseRequest parses a request into parsed queries grouped by datasource uid
@@ -0,0 +1,35 @@
+package reporting
+
+import (
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+)
+
+var (
+	ErrDisabled = errutil.BadRequest(
+		"reporting.disabled",
+		errutil.WithPublicMessage("Reporting is disabled"),
+	)
+	ErrReportNotFound = errutil.NotFound(
+		"reporting.not-found",
+		errutil.WithPublicMessage("Report not found"),
+	)
+	ErrReportUIDExists = errutil.Conflict(
+		"reporting.uid-exists",
+		errutil.WithPublicMessage("A report with the same uid already exists"),
+	)
+	ErrRenderingUnavailable = errutil.BadRequest(
+		"reporting.rendering-unavailable",
+		errutil.WithPublicMessage("Reports need an image renderer, install the image renderer plugin or configure a remote rendering service"),
+	)
+	ErrReportInvalid = errutil.BadRequest("reporting.invalid").MustTemplate(
+		"invalid report: {{ .Public.Reason }}",
+		errutil.WithPublic("Invalid report: {{ .Public.Reason }}"),
+	)
+)
+
+// InvalidReportError returns the error a report fails its validation with
+func InvalidReportError(reason string) error {
+	return ErrReportInvalid.Build(errutil.TemplateData{
+		Public: map[string]any{"Reason": reason},
+	})
+}
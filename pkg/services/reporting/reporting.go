@@ -0,0 +1,135 @@
+package reporting
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	ActionReportsRead   = "scheduledreports:read"
+	ActionReportsWrite  = "scheduledreports:write"
+	ActionReportsDelete = "scheduledreports:delete"
+	ActionReportsSend   = "scheduledreports:send"
+)
+
+// Service manages the dashboard reports rendered with the image renderer and delivered by email or webhook on a schedule
+type Service interface {
+	// IsEnabled returns true when reports are enabled on the server
+	IsEnabled() bool
+	// GetReports returns the reports of an organization, sorted by name
+	GetReports(ctx context.Context, orgID int64) ([]*Report, error)
+	// GetReport returns a report by uid
+	GetReport(ctx context.Context, orgID int64, uid string) (*Report, error)
+	// CreateReport validates and stores a new report, its first delivery is scheduled from the current time
+	CreateReport(ctx context.Context, cmd *CreateReportCommand) (*Report, error)
+	// UpdateReport replaces the configuration of a report and schedules its next delivery again
+	UpdateReport(ctx context.Context, cmd *UpdateReportCommand) (*Report, error)
+	// DeleteReport deletes a report and the history of its deliveries
+	DeleteReport(ctx context.Context, orgID int64, uid string) error
+	// SendReport renders and delivers a report immediately, regardless of its schedule
+	SendReport(ctx context.Context, orgID int64, uid string) (*Delivery, error)
+	// GetDeliveries returns the history of the deliveries of a report, the most recent first
+	GetDeliveries(ctx context.Context, query *GetDeliveriesQuery) ([]*Delivery, error)
+}
+
+type Format string
+
+const (
+	// FormatPDF delivers a single PDF document with one page per rendered image
+	FormatPDF Format = "pdf"
+	// FormatPNG delivers the rendered images as separate PNG files
+	FormatPNG Format = "png"
+)
+
+// TimeRange is the time range a report is rendered for, in the format of the dashboard time picker
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ReportConfig is what a report renders, when and where it is delivered
+type ReportConfig struct {
+	Name         string `json:"name"`
+	DashboardUID string `json:"dashboardUid"`
+	// PanelIDs are the panels rendered one by one, the whole dashboard is rendered when empty
+	PanelIDs []int64 `json:"panelIds"`
+	// TimeRange defaults to the time range saved in the dashboard when empty
+	TimeRange TimeRange `json:"timeRange"`
+	// Variables override the values of the template variables saved in the dashboard
+	Variables map[string][]string `json:"variables"`
+	// Schedule is a standard five fields cron expression, or a descriptor like @weekly
+	Schedule string `json:"schedule"`
+	// Timezone is the IANA time zone the schedule and the rendered dashboard use, UTC when empty
+	Timezone string `json:"timezone"`
+	Format   Format `json:"format"`
+	// Recipients are the email addresses the report is sent to
+	Recipients []string `json:"recipients"`
+	// WebhookURL receives the report as JSON, with the files base64 encoded
+	WebhookURL string `json:"webhookUrl"`
+	// Message is added to the body of the email
+	Message string `json:"message"`
+	Enabled bool   `json:"enabled"`
+}
+
+type Report struct {
+	ID    int64  `json:"id"`
+	UID   string `json:"uid"`
+	OrgID int64  `json:"orgId"`
+	ReportConfig
+	// UserID is the user the report is rendered as, the last user who saved it. Provisioned reports have no user.
+	UserID  int64     `json:"userId"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	// NextRun is the time of the next scheduled delivery, nil when the report is disabled
+	NextRun *time.Time `json:"nextRun,omitempty"`
+}
+
+type CreateReportCommand struct {
+	// UID is generated when empty
+	UID    string `json:"uid"`
+	OrgID  int64  `json:"-"`
+	UserID int64  `json:"-"`
+	ReportConfig
+}
+
+type UpdateReportCommand struct {
+	UID    string `json:"-"`
+	OrgID  int64  `json:"-"`
+	UserID int64  `json:"-"`
+	ReportConfig
+}
+
+type Trigger string
+
+const (
+	TriggerSchedule Trigger = "schedule"
+	TriggerManual   Trigger = "manual"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusSuccess DeliveryStatus = "success"
+	DeliveryStatusFailed  DeliveryStatus = "failed"
+)
+
+// Delivery is a past rendering and delivery of a report
+type Delivery struct {
+	ID        int64          `json:"id"`
+	ReportUID string         `json:"reportUid"`
+	Trigger   Trigger        `json:"trigger"`
+	Status    DeliveryStatus `json:"status"`
+	Error     string         `json:"error,omitempty"`
+	// Files is the number of delivered files and Bytes their total size
+	Files    int       `json:"files"`
+	Bytes    int64     `json:"bytes"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+}
+
+type GetDeliveriesQuery struct {
+	OrgID     int64
+	ReportUID string
+	// Limit defaults to 100
+	Limit int
+}
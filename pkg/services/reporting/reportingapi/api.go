@@ -0,0 +1,327 @@
+package reportingapi
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/middleware"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/reporting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+type API struct {
+	reportingService reporting.Service
+	accessControl    ac.AccessControl
+	logger           log.Logger
+}
+
+func ProvideAPI(
+	routeRegister routing.RouteRegister,
+	reportingService reporting.Service,
+	accessControl ac.AccessControl,
+) *API {
+	api := &API{
+		reportingService: reportingService,
+		accessControl:    accessControl,
+		logger:           log.New("reporting-api"),
+	}
+
+	api.registerRoutes(routeRegister)
+	return api
+}
+
+func (api *API) registerRoutes(router routing.RouteRegister) {
+	authorize := ac.Middleware(api.accessControl)
+
+	router.Group("/api/scheduled-reports", func(reportRoute routing.RouteRegister) {
+		reportRoute.Get("/", authorize(ac.EvalPermission(reporting.ActionReportsRead)), routing.Wrap(api.getReports))
+		reportRoute.Post("/", authorize(ac.EvalPermission(reporting.ActionReportsWrite)), routing.Wrap(api.createReport))
+		reportRoute.Get("/:uid", authorize(ac.EvalPermission(reporting.ActionReportsRead)), routing.Wrap(api.getReport))
+		reportRoute.Put("/:uid", authorize(ac.EvalPermission(reporting.ActionReportsWrite)), routing.Wrap(api.updateReport))
+		reportRoute.Delete("/:uid", authorize(ac.EvalPermission(reporting.ActionReportsDelete)), routing.Wrap(api.deleteReport))
+		reportRoute.Post("/:uid/send", authorize(ac.EvalPermission(reporting.ActionReportsSend)), routing.Wrap(api.sendReport))
+		reportRoute.Get("/:uid/deliveries", authorize(ac.EvalPermission(reporting.ActionReportsRead)), routing.Wrap(api.getDeliveries))
+	}, middleware.ReqSignedIn)
+}
+
+// swagger:route GET /scheduled-reports scheduled_reports getScheduledReports
+//
+// Get the reports of the current organization.
+//
+// Responses:
+// 200: getScheduledReportsResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (api *API) getReports(c *contextmodel.ReqContext) response.Response {
+	reports, err := api.reportingService.GetReports(c.Req.Context(), c.SignedInUser.GetOrgID())
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get reports", err)
+	}
+
+	return response.JSON(http.StatusOK, reports)
+}
+
+// swagger:route GET /scheduled-reports/{report_uid} scheduled_reports getScheduledReport
+//
+// Get a report by uid.
+//
+// Responses:
+// 200: getScheduledReportResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (api *API) getReport(c *contextmodel.ReqContext) response.Response {
+	report, err := api.reportingService.GetReport(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":uid"])
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get report", err)
+	}
+
+	return response.JSON(http.StatusOK, report)
+}
+
+// swagger:route POST /scheduled-reports scheduled_reports createScheduledReport
+//
+// Create a report.
+//
+// The report is rendered as the signed in user, who must be able to read its dashboard.
+// The first delivery is scheduled from the current time.
+//
+// Responses:
+// 200: getScheduledReportResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 409: conflictError
+// 500: internalServerError
+func (api *API) createReport(c *contextmodel.ReqContext) response.Response {
+	userID, errResponse := getUserID(c)
+	if errResponse != nil {
+		return errResponse
+	}
+
+	cmd := reporting.CreateReportCommand{ReportConfig: reporting.ReportConfig{Enabled: true}}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if errResponse := api.canReadDashboard(c, cmd.DashboardUID); errResponse != nil {
+		return errResponse
+	}
+
+	cmd.OrgID = c.SignedInUser.GetOrgID()
+	cmd.UserID = userID
+	report, err := api.reportingService.CreateReport(c.Req.Context(), &cmd)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to create report", err)
+	}
+
+	return response.JSON(http.StatusOK, report)
+}
+
+// swagger:route PUT /scheduled-reports/{report_uid} scheduled_reports updateScheduledReport
+//
+// Update a report.
+//
+// The whole configuration of the report is replaced. From now on the report is rendered as the signed in user,
+// who must be able to read its dashboard.
+//
+// Responses:
+// 200: getScheduledReportResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (api *API) updateReport(c *contextmodel.ReqContext) response.Response {
+	userID, errResponse := getUserID(c)
+	if errResponse != nil {
+		return errResponse
+	}
+
+	cmd := reporting.UpdateReportCommand{ReportConfig: reporting.ReportConfig{Enabled: true}}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if errResponse := api.canReadDashboard(c, cmd.DashboardUID); errResponse != nil {
+		return errResponse
+	}
+
+	cmd.UID = web.Params(c.Req)[":uid"]
+	cmd.OrgID = c.SignedInUser.GetOrgID()
+	cmd.UserID = userID
+	report, err := api.reportingService.UpdateReport(c.Req.Context(), &cmd)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to update report", err)
+	}
+
+	return response.JSON(http.StatusOK, report)
+}
+
+// swagger:route DELETE /scheduled-reports/{report_uid} scheduled_reports deleteScheduledReport
+//
+// Delete a report and the history of its deliveries.
+//
+// Responses:
+// 200: okResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (api *API) deleteReport(c *contextmodel.ReqContext) response.Response {
+	if err := api.reportingService.DeleteReport(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":uid"]); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to delete report", err)
+	}
+
+	return response.Success("Report deleted")
+}
+
+// swagger:route POST /scheduled-reports/{report_uid}/send scheduled_reports sendScheduledReport
+//
+// Send a report now.
+//
+// Renders and delivers the report regardless of its schedule, then returns the delivery.
+// A failed delivery is returned with the failed status and its error.
+//
+// Responses:
+// 200: sendScheduledReportResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (api *API) sendReport(c *contextmodel.ReqContext) response.Response {
+	delivery, err := api.reportingService.SendReport(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":uid"])
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to send report", err)
+	}
+
+	return response.JSON(http.StatusOK, delivery)
+}
+
+// swagger:route GET /scheduled-reports/{report_uid}/deliveries scheduled_reports getScheduledReportDeliveries
+//
+// Get the history of the deliveries of a report, the most recent first.
+//
+// Responses:
+// 200: getScheduledReportDeliveriesResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (api *API) getDeliveries(c *contextmodel.ReqContext) response.Response {
+	query := &reporting.GetDeliveriesQuery{
+		OrgID:     c.SignedInUser.GetOrgID(),
+		ReportUID: web.Params(c.Req)[":uid"],
+	}
+	if limit := c.Query("limit"); limit != "" {
+		var err error
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			return response.Error(http.StatusBadRequest, "limit is invalid", err)
+		}
+	}
+
+	deliveries, err := api.reportingService.GetDeliveries(c.Req.Context(), query)
+	if err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to get report deliveries", err)
+	}
+
+	return response.JSON(http.StatusOK, deliveries)
+}
+
+// canReadDashboard checks that the signed in user, who the report is rendered as, can read its dashboard
+func (api *API) canReadDashboard(c *contextmodel.ReqContext, dashboardUID string) response.Response {
+	if dashboardUID == "" {
+		return response.Err(reporting.InvalidReportError("dashboardUid is required"))
+	}
+
+	evaluator := ac.EvalPermission(dashboards.ActionDashboardsRead, dashboards.ScopeDashboardsProvider.GetResourceScopeUID(dashboardUID))
+	ok, err := api.accessControl.Evaluate(c.Req.Context(), c.SignedInUser, evaluator)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to check dashboard permissions", err)
+	}
+	if !ok {
+		return response.Error(http.StatusForbidden, "You don't have access to the dashboard of the report", nil)
+	}
+	return nil
+}
+
+func getUserID(c *contextmodel.ReqContext) (int64, response.Response) {
+	if !c.SignedInUser.IsIdentityType(claims.TypeUser) {
+		return 0, response.Error(http.StatusForbidden, "Reports can only be saved by users", nil)
+	}
+
+	userID, err := c.SignedInUser.GetInternalID()
+	if err != nil {
+		return 0, response.Error(http.StatusInternalServerError, "Failed to parse user id", err)
+	}
+
+	return userID, nil
+}
+
+// swagger:parameters getScheduledReport deleteScheduledReport sendScheduledReport
+type ScheduledReportUIDParams struct {
+	// in:path
+	// required:true
+	ReportUID string `json:"report_uid"`
+}
+
+// swagger:parameters createScheduledReport
+type CreateScheduledReportParams struct {
+	// in:body
+	// required:true
+	Body reporting.CreateReportCommand `json:"body"`
+}
+
+// swagger:parameters updateScheduledReport
+type UpdateScheduledReportParams struct {
+	// in:path
+	// required:true
+	ReportUID string `json:"report_uid"`
+	// in:body
+	// required:true
+	Body reporting.ReportConfig `json:"body"`
+}
+
+// swagger:parameters getScheduledReportDeliveries
+type GetScheduledReportDeliveriesParams struct {
+	// in:path
+	// required:true
+	ReportUID string `json:"report_uid"`
+	// Maximum number of deliveries to return, 100 by default and at most 1000
+	// in:query
+	// required:false
+	Limit int `json:"limit"`
+}
+
+// swagger:response getScheduledReportsResponse
+type GetScheduledReportsResponse struct {
+	// in:body
+	Body []*reporting.Report `json:"body"`
+}
+
+// swagger:response getScheduledReportResponse
+type GetScheduledReportResponse struct {
+	// in:body
+	Body *reporting.Report `json:"body"`
+}
+
+// swagger:response sendScheduledReportResponse
+type SendScheduledReportResponse struct {
+	// in:body
+	Body *reporting.Delivery `json:"body"`
+}
+
+// swagger:response getScheduledReportDeliveriesResponse
+type GetScheduledReportDeliveriesResponse struct {
+	// in:body
+	Body []*reporting.Delivery `json:"body"`
+}
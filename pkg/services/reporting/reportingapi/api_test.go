@@ -0,0 +1,148 @@
+package reportingapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/acimpl"
+	"github.com/grafana/grafana/pkg/services/authz/zanzana"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/reporting"
+	"github.com/grafana/grafana/pkg/services/reporting/reportingtest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+const reportBody = `{"name": "Weekly", "dashboardUid": "dash", "schedule": "@weekly", "recipients": ["management@example.com"]}`
+
+var writerPermissions = []accesscontrol.Permission{
+	{Action: reporting.ActionReportsRead},
+	{Action: reporting.ActionReportsWrite},
+	{Action: dashboards.ActionDashboardsRead, Scope: "dashboards:uid:dash"},
+}
+
+func TestReportingAPI(t *testing.T) {
+	t.Run("should create report as the signed in user", func(t *testing.T) {
+		fake := &reportingtest.FakeService{ExpectedReport: &reporting.Report{UID: "weekly"}}
+		server := setupTestServer(t, fake)
+
+		req := webtest.RequestWithSignedInUser(server.NewRequest(http.MethodPost, "/api/scheduled-reports", strings.NewReader(reportBody)), signedInUser(writerPermissions))
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		require.Len(t, fake.CreatedReports, 1)
+		assert.Equal(t, int64(1), fake.CreatedReports[0].OrgID)
+		assert.Equal(t, int64(3), fake.CreatedReports[0].UserID)
+		assert.Equal(t, "Weekly", fake.CreatedReports[0].Name)
+		assert.True(t, fake.CreatedReports[0].Enabled)
+	})
+
+	t.Run("should update report without enabling it", func(t *testing.T) {
+		fake := &reportingtest.FakeService{ExpectedReport: &reporting.Report{UID: "weekly"}}
+		server := setupTestServer(t, fake)
+
+		body := strings.NewReader(`{"name": "Weekly", "dashboardUid": "dash", "enabled": false}`)
+		req := webtest.RequestWithSignedInUser(server.NewRequest(http.MethodPut, "/api/scheduled-reports/weekly", body), signedInUser(writerPermissions))
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		require.Len(t, fake.UpdatedReports, 1)
+		assert.Equal(t, "weekly", fake.UpdatedReports[0].UID)
+		assert.False(t, fake.UpdatedReports[0].Enabled)
+	})
+
+	t.Run("should not save report of a dashboard the user can't read", func(t *testing.T) {
+		fake := &reportingtest.FakeService{}
+		server := setupTestServer(t, fake)
+
+		body := strings.NewReader(`{"name": "Weekly", "dashboardUid": "secret"}`)
+		req := webtest.RequestWithSignedInUser(server.NewRequest(http.MethodPost, "/api/scheduled-reports", body), signedInUser(writerPermissions))
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		assert.Empty(t, fake.CreatedReports)
+	})
+
+	t.Run("should not save report as a service account", func(t *testing.T) {
+		fake := &reportingtest.FakeService{}
+		server := setupTestServer(t, fake)
+
+		sa := signedInUser(writerPermissions)
+		sa.IsServiceAccount = true
+		req := webtest.RequestWithSignedInUser(server.NewRequest(http.MethodPost, "/api/scheduled-reports", strings.NewReader(reportBody)), sa)
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		assert.Empty(t, fake.CreatedReports)
+	})
+
+	t.Run("should require the send permission", func(t *testing.T) {
+		fake := &reportingtest.FakeService{ExpectedDelivery: &reporting.Delivery{Status: reporting.DeliveryStatusSuccess}}
+		server := setupTestServer(t, fake)
+
+		req := webtest.RequestWithSignedInUser(server.NewPostRequest("/api/scheduled-reports/weekly/send", nil), signedInUser(writerPermissions))
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		permissions := append([]accesscontrol.Permission{{Action: reporting.ActionReportsSend}}, writerPermissions...)
+		req = webtest.RequestWithSignedInUser(server.NewPostRequest("/api/scheduled-reports/weekly/send", nil), signedInUser(permissions))
+		res, err = server.Send(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, []string{"weekly"}, fake.SentReports)
+	})
+
+	t.Run("should return the service errors", func(t *testing.T) {
+		fake := &reportingtest.FakeService{ExpectedErr: reporting.ErrReportNotFound.Errorf("not found")}
+		server := setupTestServer(t, fake)
+
+		req := webtest.RequestWithSignedInUser(server.NewGetRequest("/api/scheduled-reports/missing"), signedInUser(writerPermissions))
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		req = webtest.RequestWithSignedInUser(server.NewGetRequest("/api/scheduled-reports/missing/deliveries?limit=ten"), signedInUser(writerPermissions))
+		res, err = server.Send(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func setupTestServer(t *testing.T, reportingService reporting.Service) *webtest.Server {
+	t.Helper()
+
+	router := routing.NewRouteRegister()
+	ProvideAPI(router, reportingService, acimpl.ProvideAccessControl(featuremgmt.WithFeatures(), zanzana.NewNoopClient()))
+	return webtest.NewServer(t, router)
+}
+
+func signedInUser(permissions []accesscontrol.Permission) *user.SignedInUser {
+	return &user.SignedInUser{
+		UserID:      3,
+		OrgID:       1,
+		OrgRole:     org.RoleEditor,
+		Permissions: map[int64]map[string][]string{1: accesscontrol.GroupScopesByActionContext(context.Background(), permissions)},
+	}
+}
@@ -0,0 +1,150 @@
+package reportingimpl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/slugify"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/reporting"
+)
+
+const (
+	emailTemplate = "report"
+	// timeRangeLayout formats the time range of a report in the emails and the PDF documents
+	timeRangeLayout = "2006-01-02 15:04 MST"
+)
+
+// file is a document delivered with a report
+type file struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	// Content is base64 encoded in the webhook payload
+	Content []byte `json:"content"`
+}
+
+// webhookPayload is the JSON body posted to the webhook of a report
+type webhookPayload struct {
+	UID            string    `json:"uid"`
+	Name           string    `json:"name"`
+	Message        string    `json:"message,omitempty"`
+	DashboardUID   string    `json:"dashboardUid"`
+	DashboardTitle string    `json:"dashboardTitle"`
+	DashboardURL   string    `json:"dashboardUrl"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	Files          []*file   `json:"files"`
+}
+
+// buildFiles turns the rendered pages of a report into a PDF document or a set of PNG images
+func buildFiles(r *reporting.Report, rendered *renderedReport) ([]*file, error) {
+	baseName := slugify.Slugify(r.Name)
+	if baseName == "" {
+		baseName = "report"
+	}
+
+	if r.Format == reporting.FormatPNG {
+		files := make([]*file, 0, len(rendered.Pages))
+		for i, p := range rendered.Pages {
+			name := baseName + ".png"
+			if len(rendered.Pages) > 1 {
+				name = baseName + "-" + strconv.Itoa(i+1) + ".png"
+			}
+			files = append(files, &file{Name: name, ContentType: "image/png", Content: p.PNG})
+		}
+		return files, nil
+	}
+
+	content, err := buildPDF(r.Name, formatTimeRange(rendered.From, rendered.To), rendered.Pages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build PDF: %w", err)
+	}
+	return []*file{{Name: baseName + ".pdf", ContentType: "application/pdf", Content: content}}, nil
+}
+
+// deliver sends the files of a report to its recipients and to its webhook, it returns the errors of both
+func (s *Service) deliver(ctx context.Context, r *reporting.Report, rendered *renderedReport, files []*file) error {
+	var errs []error
+	if len(r.Recipients) > 0 {
+		if err := s.sendEmail(ctx, r, rendered, files); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send email: %w", err))
+		}
+	}
+	if r.WebhookURL != "" {
+		if err := s.sendWebhook(ctx, r, rendered, files); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send webhook: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) sendEmail(ctx context.Context, r *reporting.Report, rendered *renderedReport, files []*file) error {
+	attachments := make([]*notifications.SendEmailAttachFile, 0, len(files))
+	for _, f := range files {
+		attachments = append(attachments, &notifications.SendEmailAttachFile{Name: f.Name, Content: f.Content})
+	}
+
+	return s.emailSender.SendEmailCommandHandlerSync(ctx, &notifications.SendEmailCommandSync{
+		SendEmailCommand: notifications.SendEmailCommand{
+			To:       r.Recipients,
+			Template: emailTemplate,
+			Data: map[string]any{
+				"Name":           r.Name,
+				"Message":        r.Message,
+				"DashboardTitle": rendered.Dashboard.Title,
+				"DashboardURL":   s.dashboardURL(r, rendered),
+				"From":           rendered.From.Format(timeRangeLayout),
+				"To":             rendered.To.Format(timeRangeLayout),
+			},
+			AttachedFiles: attachments,
+		},
+	})
+}
+
+func (s *Service) sendWebhook(ctx context.Context, r *reporting.Report, rendered *renderedReport, files []*file) error {
+	body, err := json.Marshal(&webhookPayload{
+		UID:            r.UID,
+		Name:           r.Name,
+		Message:        r.Message,
+		DashboardUID:   rendered.Dashboard.UID,
+		DashboardTitle: rendered.Dashboard.Title,
+		DashboardURL:   s.dashboardURL(r, rendered),
+		From:           rendered.From,
+		To:             rendered.To,
+		Files:          files,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.webhookSender.SendWebhookSync(ctx, &notifications.SendWebhookSync{
+		Url:         r.WebhookURL,
+		Body:        string(body),
+		HttpMethod:  http.MethodPost,
+		ContentType: "application/json",
+	})
+}
+
+// dashboardURL links to the dashboard of a report with the time range and the variables it was rendered with
+func (s *Service) dashboardURL(r *reporting.Report, rendered *renderedReport) string {
+	query := url.Values{}
+	query.Set("orgId", strconv.FormatInt(r.OrgID, 10))
+	query.Set("from", strconv.FormatInt(rendered.From.UnixMilli(), 10))
+	query.Set("to", strconv.FormatInt(rendered.To.UnixMilli(), 10))
+	for _, name := range sortedKeys(r.Variables) {
+		for _, value := range r.Variables[name] {
+			query.Add("var-"+name, value)
+		}
+	}
+	return fmt.Sprintf("%sd/%s/%s?%s", s.cfg.AppURL, rendered.Dashboard.UID, rendered.Dashboard.Slug, query.Encode())
+}
+
+func formatTimeRange(from time.Time, to time.Time) string {
+	return from.Format(timeRangeLayout) + " to " + to.Format(timeRangeLayout)
+}
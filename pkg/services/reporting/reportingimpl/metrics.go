@@ -0,0 +1,42 @@
+package reportingimpl
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsSubSystem = "reporting"
+	metricsNamespace = "grafana"
+)
+
+type metrics struct {
+	deliveries *prometheus.CounterVec
+	duration   prometheus.Histogram
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	m := &metrics{
+		deliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "deliveries_total",
+			Help:      "Number of report deliveries by trigger and status",
+		}, []string{"trigger", "status"}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "delivery_duration_seconds",
+			Help:      "Time taken to render and deliver a report",
+			Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600},
+		}),
+	}
+
+	if reg != nil {
+		reg.MustRegister(
+			m.deliveries,
+			m.duration,
+		)
+	}
+
+	return m
+}
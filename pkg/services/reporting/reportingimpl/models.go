@@ -0,0 +1,52 @@
+package reportingimpl
+
+import (
+	"time"
+)
+
+// report is the stored form of a report, the lists and the variables are JSON encoded
+type report struct {
+	ID           int64  `xorm:"pk autoincr 'id'"`
+	UID          string `xorm:"uid"`
+	OrgID        int64  `xorm:"org_id"`
+	Name         string `xorm:"name"`
+	DashboardUID string `xorm:"dashboard_uid"`
+	PanelIDs     string `xorm:"panel_ids"`
+	TimeFrom     string `xorm:"time_from"`
+	TimeTo       string `xorm:"time_to"`
+	Variables    string `xorm:"variables"`
+	Schedule     string `xorm:"schedule"`
+	Timezone     string `xorm:"timezone"`
+	Format       string `xorm:"format"`
+	Recipients   string `xorm:"recipients"`
+	WebhookURL   string `xorm:"webhook_url"`
+	Message      string `xorm:"message"`
+	Enabled      bool   `xorm:"enabled"`
+	// UserID is the user the report is rendered as, 0 for the provisioned reports
+	UserID int64 `xorm:"user_id"`
+	// NextRun is the unix time of the next scheduled delivery, 0 when the report is disabled
+	NextRun int64     `xorm:"next_run"`
+	Created time.Time `xorm:"created"`
+	Updated time.Time `xorm:"updated"`
+}
+
+func (report) TableName() string {
+	return "scheduled_report"
+}
+
+type reportDelivery struct {
+	ID       int64     `xorm:"pk autoincr 'id'"`
+	OrgID    int64     `xorm:"org_id"`
+	ReportID int64     `xorm:"report_id"`
+	Trigger  string    `xorm:"triggered_by"`
+	Status   string    `xorm:"status"`
+	Error    string    `xorm:"error"`
+	Files    int       `xorm:"files"`
+	Bytes    int64     `xorm:"bytes"`
+	Started  time.Time `xorm:"started"`
+	Finished time.Time `xorm:"finished"`
+}
+
+func (reportDelivery) TableName() string {
+	return "scheduled_report_delivery"
+}
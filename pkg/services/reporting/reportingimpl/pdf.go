@@ -0,0 +1,61 @@
+package reportingimpl
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/grafana/gofpdf"
+)
+
+const (
+	// the pages are as wide as a landscape A4 page and at least as tall, tall dashboards get taller pages
+	pdfPageWidth     = 297.0
+	pdfMinPageHeight = 210.0
+	pdfMargin        = 10.0
+	pdfTitleHeight   = 8.0
+	pdfSubtitleSize  = 6.0
+)
+
+// buildPDF assembles the pages of a report in a PDF document, each image on its own page under its title
+func buildPDF(title string, subtitle string, pages []*page) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("Grafana", true)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, 0)
+
+	imageOptions := gofpdf.ImageOptions{ImageType: "PNG", ReadDpi: false}
+	imageWidth := pdfPageWidth - 2*pdfMargin
+	for i, p := range pages {
+		name := "page-" + strconv.Itoa(i)
+		info := pdf.RegisterImageOptionsReader(name, imageOptions, bytes.NewReader(p.PNG))
+		if pdf.Err() {
+			return nil, fmt.Errorf("invalid image for %s: %w", p.Title, pdf.Error())
+		}
+
+		imageHeight := imageWidth
+		if info.Width() > 0 {
+			imageHeight = imageWidth * info.Height() / info.Width()
+		}
+		imageTop := pdfMargin + pdfTitleHeight + pdfSubtitleSize + 2
+		pageHeight := max(pdfMinPageHeight, imageTop+imageHeight+pdfMargin)
+		pdf.AddPageFormat("P", gofpdf.SizeType{Wd: pdfPageWidth, Ht: pageHeight})
+
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.CellFormat(imageWidth, pdfTitleHeight, tr(p.Title), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(100, 100, 100)
+		pdf.CellFormat(imageWidth, pdfSubtitleSize, tr(subtitle), "", 1, "L", false, 0, "")
+
+		pdf.ImageOptions(name, pdfMargin, imageTop, imageWidth, imageHeight, false, imageOptions, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,255 @@
+package reportingimpl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/rendering"
+	"github.com/grafana/grafana/pkg/services/reporting"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+// fallbackDashboardHeight is the height of the rendered dashboards when the image renderer can't capture the whole page
+const fallbackDashboardHeight = 1200
+
+// page is a rendered image of a report
+type page struct {
+	Title string
+	PNG   []byte
+}
+
+// renderedReport holds the pages of a report rendered for an absolute time range
+type renderedReport struct {
+	Dashboard *dashboards.Dashboard
+	From      time.Time
+	To        time.Time
+	Pages     []*page
+}
+
+// render renders the dashboard of a report, or each of its panels, as the user of the report
+func (s *Service) render(ctx context.Context, r *reporting.Report) (*renderedReport, error) {
+	if !s.renderingService.IsAvailable(ctx) {
+		return nil, reporting.ErrRenderingUnavailable
+	}
+
+	dashboard, err := s.dashboardService.GetDashboard(ctx, &dashboards.GetDashboardQuery{UID: r.DashboardUID, OrgID: r.OrgID})
+	if err != nil {
+		return nil, err
+	}
+
+	location := time.UTC
+	if r.Timezone != "" {
+		if location, err = time.LoadLocation(r.Timezone); err != nil {
+			return nil, reporting.InvalidReportError(fmt.Sprintf("unknown timezone %q", r.Timezone))
+		}
+	}
+
+	// every page shows the same absolute time range, however long the rendering takes
+	from, to, err := resolveTimeRange(dashboard.Data, r.TimeRange, location, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	authOpts, err := s.renderAuth(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := s.renderTargets(ctx, r, dashboard)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.renderingService.CreateRenderingSession(ctx, authOpts, rendering.SessionOpts{
+		Expiry:                     s.settings.RenderTimeout * 2,
+		RefreshExpiryOnEachRequest: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer session.Dispose(ctx)
+
+	result := &renderedReport{Dashboard: dashboard, From: from, To: to}
+	for _, target := range targets {
+		target.Query.Set("orgId", strconv.FormatInt(r.OrgID, 10))
+		target.Query.Set("from", strconv.FormatInt(from.UnixMilli(), 10))
+		target.Query.Set("to", strconv.FormatInt(to.UnixMilli(), 10))
+		target.Query.Set("theme", string(models.ThemeLight))
+		for _, name := range sortedKeys(r.Variables) {
+			for _, value := range r.Variables[name] {
+				target.Query.Add("var-"+name, value)
+			}
+		}
+
+		png, err := s.renderPage(ctx, session, authOpts, target, r.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", target.Title, err)
+		}
+		result.Pages = append(result.Pages, &page{Title: target.Title, PNG: png})
+	}
+
+	return result, nil
+}
+
+// renderTarget is a page of a report before it is rendered
+type renderTarget struct {
+	Title  string
+	Path   string
+	Query  url.Values
+	Width  int
+	Height int
+}
+
+func (s *Service) renderTargets(ctx context.Context, r *reporting.Report, dashboard *dashboards.Dashboard) ([]*renderTarget, error) {
+	if len(r.PanelIDs) == 0 {
+		height := -1
+		if err := s.renderingService.IsCapabilitySupported(ctx, rendering.FullHeightImages); err != nil {
+			height = fallbackDashboardHeight
+		}
+		return []*renderTarget{{
+			Title:  dashboard.Title,
+			Path:   path.Join("d", dashboard.UID, dashboard.Slug),
+			Query:  url.Values{"kiosk": {"true"}},
+			Width:  s.settings.DashboardWidth,
+			Height: height,
+		}}, nil
+	}
+
+	titles := panelTitles(dashboard.Data)
+	targets := make([]*renderTarget, 0, len(r.PanelIDs))
+	for _, id := range r.PanelIDs {
+		title, ok := titles[id]
+		if !ok {
+			return nil, reporting.InvalidReportError(fmt.Sprintf("panel %d not found in dashboard %s", id, dashboard.UID))
+		}
+		if title == "" {
+			title = fmt.Sprintf("%s - panel %d", dashboard.Title, id)
+		}
+		targets = append(targets, &renderTarget{
+			Title:  title,
+			Path:   path.Join("d-solo", dashboard.UID, dashboard.Slug),
+			Query:  url.Values{"panelId": {strconv.FormatInt(id, 10)}},
+			Width:  s.settings.PanelWidth,
+			Height: s.settings.PanelHeight,
+		})
+	}
+	return targets, nil
+}
+
+func (s *Service) renderPage(ctx context.Context, session rendering.Session, authOpts rendering.AuthOpts, target *renderTarget, timezone string) ([]byte, error) {
+	result, err := s.renderingService.Render(ctx, rendering.RenderPNG, rendering.Opts{
+		CommonOpts: rendering.CommonOpts{
+			TimeoutOpts: rendering.TimeoutOpts{
+				Timeout: s.settings.RenderTimeout,
+			},
+			AuthOpts:        authOpts,
+			Path:            target.Path + "?" + target.Query.Encode(),
+			Timezone:        timezone,
+			ConcurrentLimit: s.cfg.RendererConcurrentRequestLimit,
+		},
+		ErrorOpts: rendering.ErrorOpts{
+			ErrorConcurrentLimitReached: true,
+			ErrorRenderUnavailable:      true,
+		},
+		Width:             target.Width,
+		Height:            target.Height,
+		DeviceScaleFactor: 1,
+		Theme:             models.ThemeLight,
+	}, session)
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:gosec // the path is generated by the rendering service
+	png, err := os.ReadFile(result.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(result.FilePath); err != nil {
+		s.logger.Warn("Failed to remove rendered image", "path", result.FilePath, "error", err)
+	}
+	return png, nil
+}
+
+// renderAuth returns the identity reports are rendered with. Reports saved through the API are rendered as the user
+// who last saved them, so they only show what this user can see. Provisioned reports have no user and are rendered
+// as an administrator of the organization, like the screenshots of alert notifications.
+func (s *Service) renderAuth(ctx context.Context, r *reporting.Report) (rendering.AuthOpts, error) {
+	if r.UserID == 0 {
+		return rendering.AuthOpts{OrgID: r.OrgID, OrgRole: org.RoleAdmin}, nil
+	}
+
+	owner, err := s.userService.GetSignedInUser(ctx, &user.GetSignedInUserQuery{UserID: r.UserID, OrgID: r.OrgID})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return rendering.AuthOpts{}, fmt.Errorf("the user of the report no longer exists: %w", err)
+		}
+		return rendering.AuthOpts{}, err
+	}
+	if owner.OrgID != r.OrgID || owner.OrgRole == "" || owner.OrgRole == org.RoleNone {
+		return rendering.AuthOpts{}, fmt.Errorf("the user of the report is no longer a member of organization %d", r.OrgID)
+	}
+	return rendering.AuthOpts{OrgID: r.OrgID, UserID: owner.UserID, OrgRole: owner.OrgRole}, nil
+}
+
+// resolveTimeRange resolves the time range of a report, or the one saved in the dashboard, to absolute times
+func resolveTimeRange(dashboard *simplejson.Json, timeRange reporting.TimeRange, location *time.Location, now time.Time) (time.Time, time.Time, error) {
+	from, to := timeRange.From, timeRange.To
+	if from == "" && to == "" {
+		from = dashboard.GetPath("time", "from").MustString("now-6h")
+		to = dashboard.GetPath("time", "to").MustString("now")
+	}
+
+	tr := gtime.TimeRange{From: from, To: to, Now: now}
+	fromTime, err := tr.ParseFrom(gtime.WithLocation(location))
+	if err != nil {
+		return time.Time{}, time.Time{}, reporting.InvalidReportError(fmt.Sprintf("invalid time range from %q", from))
+	}
+	toTime, err := tr.ParseTo(gtime.WithLocation(location))
+	if err != nil {
+		return time.Time{}, time.Time{}, reporting.InvalidReportError(fmt.Sprintf("invalid time range to %q", to))
+	}
+	if !fromTime.Before(toTime) {
+		return time.Time{}, time.Time{}, reporting.InvalidReportError("the start of the time range must be before its end")
+	}
+	return fromTime.In(location), toTime.In(location), nil
+}
+
+// panelTitles returns the titles of the panels of a dashboard by id, including the panels of collapsed rows
+func panelTitles(dashboard *simplejson.Json) map[int64]string {
+	titles := make(map[int64]string)
+	var walk func(panels []any)
+	walk = func(panels []any) {
+		for _, p := range panels {
+			panel := simplejson.NewFromAny(p)
+			if id, err := panel.Get("id").Int64(); err == nil && panel.Get("type").MustString() != "row" {
+				titles[id] = panel.Get("title").MustString()
+			}
+			walk(panel.Get("panels").MustArray())
+		}
+	}
+	walk(dashboard.Get("panels").MustArray())
+	return titles
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
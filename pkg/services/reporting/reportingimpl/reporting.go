@@ -0,0 +1,449 @@
+package reportingimpl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robfig/cron/v3"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/rendering"
+	"github.com/grafana/grafana/pkg/services/reporting"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	maxNameLength        = 190
+	defaultDeliveryLimit = 100
+	maxDeliveryLimit     = 1000
+)
+
+var _ reporting.Service = (*Service)(nil)
+
+func ProvideService(
+	cfg *setting.Cfg,
+	db db.DB,
+	renderingService rendering.Service,
+	dashboardService dashboards.DashboardService,
+	userService user.Service,
+	notificationService notifications.Service,
+	accessControl accesscontrol.Service,
+	reg prometheus.Registerer,
+) (*Service, error) {
+	s := &Service{
+		cfg:              cfg,
+		settings:         cfg.Reporting,
+		store:            &xormStore{db: db},
+		renderingService: renderingService,
+		dashboardService: dashboardService,
+		userService:      userService,
+		emailSender:      notificationService,
+		webhookSender:    notificationService,
+		logger:           log.New("reporting"),
+		metrics:          newMetrics(reg),
+		now:              time.Now,
+	}
+
+	if err := declareFixedRoles(accessControl); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type Service struct {
+	cfg              *setting.Cfg
+	settings         setting.ReportingSettings
+	store            store
+	renderingService rendering.Service
+	dashboardService dashboards.DashboardService
+	userService      user.Service
+	emailSender      notifications.EmailSender
+	webhookSender    notifications.WebhookSender
+	logger           log.Logger
+	metrics          *metrics
+	now              func() time.Time
+}
+
+func (s *Service) IsEnabled() bool {
+	return s.settings.Enabled
+}
+
+func (s *Service) GetReports(ctx context.Context, orgID int64) ([]*reporting.Report, error) {
+	rows, err := s.store.List(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*reporting.Report, 0, len(rows))
+	for _, row := range rows {
+		r, err := toReport(row)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+func (s *Service) GetReport(ctx context.Context, orgID int64, uid string) (*reporting.Report, error) {
+	row, err := s.store.Get(ctx, orgID, uid)
+	if err != nil {
+		return nil, err
+	}
+	return toReport(row)
+}
+
+func (s *Service) CreateReport(ctx context.Context, cmd *reporting.CreateReportCommand) (*reporting.Report, error) {
+	if !s.settings.Enabled {
+		return nil, reporting.ErrDisabled
+	}
+
+	if cmd.UID == "" {
+		cmd.UID = util.GenerateShortUID()
+	} else if err := util.ValidateUID(cmd.UID); err != nil {
+		return nil, reporting.InvalidReportError(fmt.Sprintf("invalid uid: %s", err))
+	}
+
+	now := s.now()
+	row := &report{
+		UID:     cmd.UID,
+		OrgID:   cmd.OrgID,
+		UserID:  cmd.UserID,
+		Created: now,
+		Updated: now,
+	}
+	if err := s.setConfig(ctx, row, &cmd.ReportConfig, now); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.Insert(ctx, row); err != nil {
+		return nil, err
+	}
+	return toReport(row)
+}
+
+func (s *Service) UpdateReport(ctx context.Context, cmd *reporting.UpdateReportCommand) (*reporting.Report, error) {
+	if !s.settings.Enabled {
+		return nil, reporting.ErrDisabled
+	}
+
+	row, err := s.store.Get(ctx, cmd.OrgID, cmd.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	row.UserID = cmd.UserID
+	row.Updated = now
+	if err := s.setConfig(ctx, row, &cmd.ReportConfig, now); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.Update(ctx, row); err != nil {
+		return nil, err
+	}
+	return toReport(row)
+}
+
+func (s *Service) DeleteReport(ctx context.Context, orgID int64, uid string) error {
+	return s.store.Delete(ctx, orgID, uid)
+}
+
+func (s *Service) SendReport(ctx context.Context, orgID int64, uid string) (*reporting.Delivery, error) {
+	if !s.settings.Enabled {
+		return nil, reporting.ErrDisabled
+	}
+
+	row, err := s.store.Get(ctx, orgID, uid)
+	if err != nil {
+		return nil, err
+	}
+	r, err := toReport(row)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.send(ctx, row.ID, r, reporting.TriggerManual)
+}
+
+func (s *Service) GetDeliveries(ctx context.Context, query *reporting.GetDeliveriesQuery) ([]*reporting.Delivery, error) {
+	row, err := s.store.Get(ctx, query.OrgID, query.ReportUID)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultDeliveryLimit
+	}
+	limit = min(limit, maxDeliveryLimit)
+
+	rows, err := s.store.GetDeliveries(ctx, row.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*reporting.Delivery, 0, len(rows))
+	for _, d := range rows {
+		deliveries = append(deliveries, toDelivery(row.UID, d))
+	}
+	return deliveries, nil
+}
+
+// send renders a report and delivers it, the delivery is stored in the history of the report whether it succeeds or not
+func (s *Service) send(ctx context.Context, reportID int64, r *reporting.Report, trigger reporting.Trigger) (*reporting.Delivery, error) {
+	logger := s.logger.FromContext(ctx).New("report", r.UID, "dashboard", r.DashboardUID, "trigger", trigger)
+	started := s.now()
+
+	var files []*file
+	rendered, err := s.render(ctx, r)
+	if err == nil {
+		files, err = buildFiles(r, rendered)
+	}
+	if err == nil {
+		err = s.deliver(ctx, r, rendered, files)
+	}
+
+	row := &reportDelivery{
+		OrgID:    r.OrgID,
+		ReportID: reportID,
+		Trigger:  string(trigger),
+		Status:   string(reporting.DeliveryStatusSuccess),
+		Started:  started,
+		Finished: s.now(),
+	}
+	for _, f := range files {
+		row.Files++
+		row.Bytes += int64(len(f.Content))
+	}
+	if err != nil {
+		row.Status = string(reporting.DeliveryStatusFailed)
+		row.Error = err.Error()
+		logger.Error("Failed to deliver report", "error", err)
+	} else {
+		logger.Info("Delivered report", "files", row.Files, "bytes", row.Bytes, "duration", row.Finished.Sub(started))
+	}
+
+	s.metrics.deliveries.WithLabelValues(row.Trigger, row.Status).Inc()
+	s.metrics.duration.Observe(row.Finished.Sub(started).Seconds())
+
+	// the delivery happened even if the server is shutting down, keep its history
+	if err := s.store.InsertDelivery(context.WithoutCancel(ctx), row); err != nil {
+		return nil, err
+	}
+	return toDelivery(r.UID, row), nil
+}
+
+// setConfig validates config and copies it to row, along with the time of the next scheduled delivery
+func (s *Service) setConfig(ctx context.Context, row *report, config *reporting.ReportConfig, now time.Time) error {
+	if err := s.validate(ctx, row.OrgID, config); err != nil {
+		return err
+	}
+
+	panelIDs, err := json.Marshal(config.PanelIDs)
+	if err != nil {
+		return err
+	}
+	variables, err := json.Marshal(config.Variables)
+	if err != nil {
+		return err
+	}
+	recipients, err := json.Marshal(config.Recipients)
+	if err != nil {
+		return err
+	}
+
+	row.Name = config.Name
+	row.DashboardUID = config.DashboardUID
+	row.PanelIDs = string(panelIDs)
+	row.TimeFrom = config.TimeRange.From
+	row.TimeTo = config.TimeRange.To
+	row.Variables = string(variables)
+	row.Schedule = config.Schedule
+	row.Timezone = config.Timezone
+	row.Format = string(config.Format)
+	row.Recipients = string(recipients)
+	row.WebhookURL = config.WebhookURL
+	row.Message = config.Message
+	row.Enabled = config.Enabled
+	row.NextRun = 0
+	if config.Enabled {
+		next, err := nextRun(config.Schedule, config.Timezone, now)
+		if err != nil {
+			return err
+		}
+		row.NextRun = next.Unix()
+	}
+	return nil
+}
+
+// validate checks a report configuration and sets the defaults of the fields left empty
+func (s *Service) validate(ctx context.Context, orgID int64, config *reporting.ReportConfig) error {
+	config.Name = strings.TrimSpace(config.Name)
+	if config.Name == "" {
+		return reporting.InvalidReportError("name is required")
+	}
+	if len(config.Name) > maxNameLength {
+		return reporting.InvalidReportError(fmt.Sprintf("name is longer than %d characters", maxNameLength))
+	}
+
+	if config.Format == "" {
+		config.Format = reporting.FormatPDF
+	}
+	if config.Format != reporting.FormatPDF && config.Format != reporting.FormatPNG {
+		return reporting.InvalidReportError(fmt.Sprintf("unknown format %q, use pdf or png", config.Format))
+	}
+
+	location := time.UTC
+	if config.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(config.Timezone); err != nil {
+			return reporting.InvalidReportError(fmt.Sprintf("unknown timezone %q", config.Timezone))
+		}
+	}
+	if _, err := parseSchedule(config.Schedule); err != nil {
+		return err
+	}
+
+	if len(config.Recipients) == 0 && config.WebhookURL == "" {
+		return reporting.InvalidReportError("at least one recipient or a webhook URL is required")
+	}
+	for i, recipient := range config.Recipients {
+		config.Recipients[i] = strings.TrimSpace(recipient)
+		if !util.IsEmail(config.Recipients[i]) {
+			return reporting.InvalidReportError(fmt.Sprintf("invalid recipient %q", recipient))
+		}
+	}
+	if config.WebhookURL != "" {
+		u, err := url.Parse(config.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return reporting.InvalidReportError("the webhook URL must be an absolute http or https URL")
+		}
+	}
+
+	if len(config.PanelIDs) > s.settings.MaxPanels {
+		return reporting.InvalidReportError(fmt.Sprintf("a report can render at most %d panels", s.settings.MaxPanels))
+	}
+	if (config.TimeRange.From == "") != (config.TimeRange.To == "") {
+		return reporting.InvalidReportError("the time range needs both from and to")
+	}
+
+	if config.DashboardUID == "" {
+		return reporting.InvalidReportError("dashboardUid is required")
+	}
+	dashboard, err := s.dashboardService.GetDashboard(ctx, &dashboards.GetDashboardQuery{UID: config.DashboardUID, OrgID: orgID})
+	if err != nil {
+		return err
+	}
+	titles := panelTitles(dashboard.Data)
+	for _, id := range config.PanelIDs {
+		if _, ok := titles[id]; !ok {
+			return reporting.InvalidReportError(fmt.Sprintf("panel %d not found in dashboard %s", id, config.DashboardUID))
+		}
+	}
+	if _, _, err := resolveTimeRange(dashboard.Data, config.TimeRange, location, s.now()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// parseSchedule parses a standard cron expression. The time zone of the schedule is the one of the report,
+// so the TZ= prefixes are not accepted.
+func parseSchedule(schedule string) (cron.Schedule, error) {
+	schedule = strings.TrimSpace(schedule)
+	if schedule == "" {
+		return nil, reporting.InvalidReportError("schedule is required")
+	}
+	if strings.HasPrefix(schedule, "TZ=") || strings.HasPrefix(schedule, "CRON_TZ=") {
+		return nil, reporting.InvalidReportError("the time zone of the schedule is set with the timezone field")
+	}
+
+	parsed, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return nil, reporting.InvalidReportError(fmt.Sprintf("invalid schedule %q: %s", schedule, err))
+	}
+	return parsed, nil
+}
+
+// nextRun returns the first time after now matching the schedule of a report in its time zone
+func nextRun(schedule string, timezone string, now time.Time) (time.Time, error) {
+	parsed, err := parseSchedule(schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	location := time.UTC
+	if timezone != "" {
+		if location, err = time.LoadLocation(timezone); err != nil {
+			return time.Time{}, reporting.InvalidReportError(fmt.Sprintf("unknown timezone %q", timezone))
+		}
+	}
+
+	next := parsed.Next(now.In(location))
+	if next.IsZero() {
+		return time.Time{}, reporting.InvalidReportError(fmt.Sprintf("schedule %q never runs", schedule))
+	}
+	return next, nil
+}
+
+func toReport(row *report) (*reporting.Report, error) {
+	r := &reporting.Report{
+		ID:    row.ID,
+		UID:   row.UID,
+		OrgID: row.OrgID,
+		ReportConfig: reporting.ReportConfig{
+			Name:         row.Name,
+			DashboardUID: row.DashboardUID,
+			TimeRange:    reporting.TimeRange{From: row.TimeFrom, To: row.TimeTo},
+			Schedule:     row.Schedule,
+			Timezone:     row.Timezone,
+			Format:       reporting.Format(row.Format),
+			WebhookURL:   row.WebhookURL,
+			Message:      row.Message,
+			Enabled:      row.Enabled,
+		},
+		UserID:  row.UserID,
+		Created: row.Created,
+		Updated: row.Updated,
+	}
+	if err := json.Unmarshal([]byte(row.PanelIDs), &r.PanelIDs); err != nil {
+		return nil, fmt.Errorf("invalid panel ids of report %s: %w", row.UID, err)
+	}
+	if err := json.Unmarshal([]byte(row.Variables), &r.Variables); err != nil {
+		return nil, fmt.Errorf("invalid variables of report %s: %w", row.UID, err)
+	}
+	if err := json.Unmarshal([]byte(row.Recipients), &r.Recipients); err != nil {
+		return nil, fmt.Errorf("invalid recipients of report %s: %w", row.UID, err)
+	}
+	if row.NextRun > 0 {
+		next := time.Unix(row.NextRun, 0)
+		r.NextRun = &next
+	}
+	return r, nil
+}
+
+func toDelivery(reportUID string, row *reportDelivery) *reporting.Delivery {
+	return &reporting.Delivery{
+		ID:        row.ID,
+		ReportUID: reportUID,
+		Trigger:   reporting.Trigger(row.Trigger),
+		Status:    reporting.DeliveryStatus(row.Status),
+		Error:     row.Error,
+		Files:     row.Files,
+		Bytes:     row.Bytes,
+		Started:   row.Started,
+		Finished:  row.Finished,
+	}
+}
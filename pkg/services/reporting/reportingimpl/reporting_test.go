@@ -0,0 +1,523 @@
+package reportingimpl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/rendering"
+	"github.com/grafana/grafana/pkg/services/reporting"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+var testNow = time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+
+func TestReports(t *testing.T) {
+	t.Run("creates a report and schedules its first delivery", func(t *testing.T) {
+		s, store, _, _ := newTestService(t)
+
+		r, err := s.CreateReport(context.Background(), &reporting.CreateReportCommand{
+			UID:          "weekly",
+			OrgID:        1,
+			UserID:       3,
+			ReportConfig: testConfig(),
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "weekly", r.UID)
+		assert.Equal(t, int64(3), r.UserID)
+		assert.Equal(t, reporting.FormatPDF, r.Format)
+		assert.Equal(t, []string{"management@example.com"}, r.Recipients)
+		require.NotNil(t, r.NextRun)
+		// the first monday at 8:00 in Paris after the creation
+		assert.Equal(t, time.Date(2024, 5, 6, 6, 0, 0, 0, time.UTC), r.NextRun.UTC())
+
+		stored, err := s.GetReport(context.Background(), 1, "weekly")
+		require.NoError(t, err)
+		assert.Equal(t, r, stored)
+		assert.Len(t, store.reports, 1)
+	})
+
+	t.Run("generates the uid and does not schedule disabled reports", func(t *testing.T) {
+		s, _, _, _ := newTestService(t)
+
+		config := testConfig()
+		config.Enabled = false
+		r, err := s.CreateReport(context.Background(), &reporting.CreateReportCommand{OrgID: 1, ReportConfig: config})
+		require.NoError(t, err)
+		assert.NotEmpty(t, r.UID)
+		assert.Nil(t, r.NextRun)
+	})
+
+	t.Run("rejects invalid reports", func(t *testing.T) {
+		s, _, _, _ := newTestService(t)
+
+		testCases := map[string]func(*reporting.ReportConfig){
+			"missing name":          func(c *reporting.ReportConfig) { c.Name = " " },
+			"unknown format":        func(c *reporting.ReportConfig) { c.Format = "docx" },
+			"unknown timezone":      func(c *reporting.ReportConfig) { c.Timezone = "Mars/Olympus" },
+			"invalid schedule":      func(c *reporting.ReportConfig) { c.Schedule = "every monday" },
+			"schedule time zone":    func(c *reporting.ReportConfig) { c.Schedule = "CRON_TZ=UTC 0 8 * * 1" },
+			"no destination":        func(c *reporting.ReportConfig) { c.Recipients = nil },
+			"invalid recipient":     func(c *reporting.ReportConfig) { c.Recipients = []string{"management"} },
+			"invalid webhook":       func(c *reporting.ReportConfig) { c.WebhookURL = "file:///etc/passwd" },
+			"unknown panel":         func(c *reporting.ReportConfig) { c.PanelIDs = []int64{42} },
+			"half time range":       func(c *reporting.ReportConfig) { c.TimeRange = reporting.TimeRange{From: "now-7d"} },
+			"invalid time range":    func(c *reporting.ReportConfig) { c.TimeRange = reporting.TimeRange{From: "yesterday", To: "now"} },
+			"reversed time range":   func(c *reporting.ReportConfig) { c.TimeRange = reporting.TimeRange{From: "now", To: "now-7d"} },
+			"too many panels":       func(c *reporting.ReportConfig) { c.PanelIDs = []int64{1, 2, 3, 4} },
+			"missing dashboard uid": func(c *reporting.ReportConfig) { c.DashboardUID = "" },
+		}
+		for name, modify := range testCases {
+			t.Run(name, func(t *testing.T) {
+				config := testConfig()
+				modify(&config)
+				_, err := s.CreateReport(context.Background(), &reporting.CreateReportCommand{OrgID: 1, ReportConfig: config})
+				require.ErrorIs(t, err, reporting.ErrReportInvalid)
+			})
+		}
+	})
+
+	t.Run("updates a report as the user who saves it", func(t *testing.T) {
+		s, _, _, _ := newTestService(t)
+		_, err := s.CreateReport(context.Background(), &reporting.CreateReportCommand{UID: "weekly", OrgID: 1, ReportConfig: testConfig()})
+		require.NoError(t, err)
+
+		config := testConfig()
+		config.PanelIDs = []int64{2}
+		config.Schedule = "@daily"
+		r, err := s.UpdateReport(context.Background(), &reporting.UpdateReportCommand{UID: "weekly", OrgID: 1, UserID: 5, ReportConfig: config})
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), r.UserID)
+		assert.Equal(t, []int64{2}, r.PanelIDs)
+		assert.Equal(t, time.Date(2024, 5, 2, 22, 0, 0, 0, time.UTC), r.NextRun.UTC())
+
+		_, err = s.UpdateReport(context.Background(), &reporting.UpdateReportCommand{UID: "missing", OrgID: 1, ReportConfig: config})
+		require.ErrorIs(t, err, reporting.ErrReportNotFound)
+	})
+
+	t.Run("refuses changes when reporting is disabled", func(t *testing.T) {
+		s, _, _, _ := newTestService(t)
+		s.settings.Enabled = false
+
+		_, err := s.CreateReport(context.Background(), &reporting.CreateReportCommand{OrgID: 1, ReportConfig: testConfig()})
+		require.ErrorIs(t, err, reporting.ErrDisabled)
+		_, err = s.SendReport(context.Background(), 1, "weekly")
+		require.ErrorIs(t, err, reporting.ErrDisabled)
+	})
+}
+
+func TestSendReport(t *testing.T) {
+	t.Run("renders the dashboard to a PDF sent by email", func(t *testing.T) {
+		s, store, renderer, sender := newTestService(t)
+		_, err := s.CreateReport(context.Background(), &reporting.CreateReportCommand{UID: "weekly", OrgID: 1, ReportConfig: testConfig()})
+		require.NoError(t, err)
+
+		renderer.EXPECT().IsCapabilitySupported(gomock.Any(), rendering.FullHeightImages).Return(nil)
+		renderer.EXPECT().Render(gomock.Any(), rendering.RenderPNG, gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ rendering.RenderType, opts rendering.Opts, _ rendering.Session) (*rendering.RenderResult, error) {
+				assert.Equal(t, rendering.AuthOpts{OrgID: 1, OrgRole: org.RoleAdmin}, opts.AuthOpts)
+				assert.Equal(t, "d/dash/business-overview?from=1714035600000&kiosk=true&orgId=1&theme=light&to=1714640400000&var-region=eu&var-region=us", opts.Path)
+				assert.Equal(t, -1, opts.Height)
+				return writeTestImage(t), nil
+			})
+
+		delivery, err := s.SendReport(context.Background(), 1, "weekly")
+		require.NoError(t, err)
+		assert.Equal(t, reporting.DeliveryStatusSuccess, delivery.Status)
+		assert.Equal(t, reporting.TriggerManual, delivery.Trigger)
+		assert.Equal(t, 1, delivery.Files)
+
+		require.Equal(t, []string{"management@example.com"}, sender.EmailSync.To)
+		require.Equal(t, emailTemplate, sender.EmailSync.Template)
+		require.Len(t, sender.EmailSync.AttachedFiles, 1)
+		assert.Equal(t, "weekly-management-report.pdf", sender.EmailSync.AttachedFiles[0].Name)
+		assert.True(t, bytes.HasPrefix(sender.EmailSync.AttachedFiles[0].Content, []byte("%PDF")))
+		assert.Equal(t, "2024-04-25 11:00 CEST", sender.EmailSync.Data["From"])
+
+		deliveries, err := s.GetDeliveries(context.Background(), &reporting.GetDeliveriesQuery{OrgID: 1, ReportUID: "weekly"})
+		require.NoError(t, err)
+		assert.Equal(t, []*reporting.Delivery{delivery}, deliveries)
+		assert.Len(t, store.deliveries, 1)
+	})
+
+	t.Run("renders the panels to PNG images posted to the webhook", func(t *testing.T) {
+		s, _, renderer, sender := newTestService(t)
+		config := testConfig()
+		config.PanelIDs = []int64{2, 3}
+		config.Format = reporting.FormatPNG
+		config.Recipients = nil
+		config.WebhookURL = "https://example.com/hook"
+		_, err := s.CreateReport(context.Background(), &reporting.CreateReportCommand{UID: "weekly", OrgID: 1, ReportConfig: config})
+		require.NoError(t, err)
+
+		var paths []string
+		renderer.EXPECT().Render(gomock.Any(), rendering.RenderPNG, gomock.Any(), gomock.Any()).Times(2).
+			DoAndReturn(func(_ context.Context, _ rendering.RenderType, opts rendering.Opts, _ rendering.Session) (*rendering.RenderResult, error) {
+				paths = append(paths, opts.Path)
+				return writeTestImage(t), nil
+			})
+
+		delivery, err := s.SendReport(context.Background(), 1, "weekly")
+		require.NoError(t, err)
+		assert.Equal(t, reporting.DeliveryStatusSuccess, delivery.Status)
+		assert.Equal(t, 2, delivery.Files)
+		require.Len(t, paths, 2)
+		assert.Contains(t, paths[0], "d-solo/dash/business-overview?")
+		assert.Contains(t, paths[0], "panelId=2")
+		assert.Contains(t, paths[1], "panelId=3")
+
+		assert.Equal(t, "https://example.com/hook", sender.Webhook.Url)
+		var payload webhookPayload
+		require.NoError(t, json.Unmarshal([]byte(sender.Webhook.Body), &payload))
+		assert.Equal(t, "weekly", payload.UID)
+		require.Len(t, payload.Files, 2)
+		assert.Equal(t, "weekly-management-report-1.png", payload.Files[0].Name)
+		assert.Equal(t, "image/png", payload.Files[0].ContentType)
+	})
+
+	t.Run("stores the failed deliveries", func(t *testing.T) {
+		s, store, renderer, _ := newTestService(t)
+		_, err := s.CreateReport(context.Background(), &reporting.CreateReportCommand{UID: "weekly", OrgID: 1, ReportConfig: testConfig()})
+		require.NoError(t, err)
+
+		renderer.EXPECT().IsCapabilitySupported(gomock.Any(), rendering.FullHeightImages).Return(nil)
+		renderer.EXPECT().Render(gomock.Any(), rendering.RenderPNG, gomock.Any(), gomock.Any()).Return(nil, rendering.ErrTimeout)
+
+		delivery, err := s.SendReport(context.Background(), 1, "weekly")
+		require.NoError(t, err)
+		assert.Equal(t, reporting.DeliveryStatusFailed, delivery.Status)
+		assert.Contains(t, delivery.Error, rendering.ErrTimeout.Error())
+		assert.Len(t, store.deliveries, 1)
+	})
+
+	t.Run("renders as the user of the report while they are a member of the organization", func(t *testing.T) {
+		s, _, _, _ := newTestService(t)
+		users := &usertest.FakeUserService{ExpectedSignedInUser: &user.SignedInUser{UserID: 3, OrgID: 1, OrgRole: org.RoleEditor}}
+		s.userService = users
+
+		authOpts, err := s.renderAuth(context.Background(), &reporting.Report{OrgID: 1, UserID: 3})
+		require.NoError(t, err)
+		assert.Equal(t, rendering.AuthOpts{OrgID: 1, UserID: 3, OrgRole: org.RoleEditor}, authOpts)
+
+		users.ExpectedSignedInUser = &user.SignedInUser{UserID: 3, OrgID: 1, OrgRole: org.RoleNone}
+		_, err = s.renderAuth(context.Background(), &reporting.Report{OrgID: 1, UserID: 3})
+		require.Error(t, err)
+	})
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("claims and delivers the due reports once", func(t *testing.T) {
+		s, store, renderer, sender := newTestService(t)
+		_, err := s.CreateReport(context.Background(), &reporting.CreateReportCommand{UID: "weekly", OrgID: 1, ReportConfig: testConfig()})
+		require.NoError(t, err)
+
+		// nothing is due yet
+		s.runDueReports(context.Background())
+		assert.Empty(t, store.deliveries)
+
+		renderer.EXPECT().IsCapabilitySupported(gomock.Any(), rendering.FullHeightImages).Return(nil)
+		renderer.EXPECT().Render(gomock.Any(), rendering.RenderPNG, gomock.Any(), gomock.Any()).Return(writeTestImage(t), nil)
+
+		dueAt := time.Date(2024, 5, 6, 6, 0, 30, 0, time.UTC)
+		s.now = func() time.Time { return dueAt }
+		s.runDueReports(context.Background())
+		s.runDueReports(context.Background())
+
+		require.Len(t, store.deliveries, 1)
+		assert.Equal(t, string(reporting.TriggerSchedule), store.deliveries[0].Trigger)
+		assert.Equal(t, []string{"management@example.com"}, sender.EmailSync.To)
+		assert.Equal(t, time.Date(2024, 5, 13, 6, 0, 0, 0, time.UTC).Unix(), store.reports[0].NextRun)
+	})
+
+	t.Run("does not deliver the reports claimed by another instance", func(t *testing.T) {
+		s, store, _, _ := newTestService(t)
+		_, err := s.CreateReport(context.Background(), &reporting.CreateReportCommand{UID: "weekly", OrgID: 1, ReportConfig: testConfig()})
+		require.NoError(t, err)
+
+		due := *store.reports[0]
+		store.reports[0].NextRun = time.Date(2024, 5, 13, 6, 0, 0, 0, time.UTC).Unix()
+		_, ok := s.claim(context.Background(), &due, time.Date(2024, 5, 6, 6, 0, 30, 0, time.UTC))
+		assert.False(t, ok)
+	})
+
+	t.Run("unschedules the reports whose schedule is invalid", func(t *testing.T) {
+		s, store, _, _ := newTestService(t)
+		_, err := s.CreateReport(context.Background(), &reporting.CreateReportCommand{UID: "weekly", OrgID: 1, ReportConfig: testConfig()})
+		require.NoError(t, err)
+
+		store.reports[0].Schedule = "invalid"
+		_, ok := s.claim(context.Background(), store.reports[0], testNow)
+		assert.False(t, ok)
+		assert.Zero(t, store.reports[0].NextRun)
+	})
+
+	t.Run("deletes the old deliveries", func(t *testing.T) {
+		s, store, _, _ := newTestService(t)
+		store.deliveries = []*reportDelivery{
+			{ID: 1, Started: testNow.Add(-100 * 24 * time.Hour)},
+			{ID: 2, Started: testNow.Add(-time.Hour)},
+		}
+
+		s.cleanupHistory(context.Background())
+		require.Len(t, store.deliveries, 1)
+		assert.Equal(t, int64(2), store.deliveries[0].ID)
+	})
+}
+
+func TestNextRun(t *testing.T) {
+	next, err := nextRun("@weekly", "America/New_York", testNow)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 5, 4, 0, 0, 0, time.UTC), next.UTC())
+
+	next, err = nextRun("30 6 * * *", "", testNow)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 3, 6, 30, 0, 0, time.UTC), next.UTC())
+
+	_, err = nextRun("0 8 31 2 *", "", testNow)
+	require.ErrorIs(t, err, reporting.ErrReportInvalid)
+}
+
+func TestBuildPDF(t *testing.T) {
+	content, err := buildPDF("Weekly management report", "2024-04-25 09:00 CEST to 2024-05-02 09:00 CEST", []*page{
+		{Title: "Revenue", PNG: testImage(t, 100, 50)},
+		{Title: "Très long tableau de bord", PNG: testImage(t, 100, 400)},
+	})
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(content, []byte("%PDF")))
+
+	_, err = buildPDF("Broken", "", []*page{{Title: "Broken", PNG: []byte("not an image")}})
+	require.Error(t, err)
+}
+
+func testConfig() reporting.ReportConfig {
+	return reporting.ReportConfig{
+		Name:         "Weekly management report",
+		DashboardUID: "dash",
+		TimeRange:    reporting.TimeRange{From: "now-7d", To: "now"},
+		Variables:    map[string][]string{"region": {"eu", "us"}},
+		Schedule:     "0 8 * * 1",
+		Timezone:     "Europe/Paris",
+		Recipients:   []string{" management@example.com"},
+		Enabled:      true,
+	}
+}
+
+func newTestService(t *testing.T) (*Service, *fakeStore, *rendering.MockService, *notifications.NotificationServiceMock) {
+	t.Helper()
+
+	dashboardService := &dashboards.FakeDashboardService{}
+	dashboardService.On("GetDashboard", mock.Anything, mock.AnythingOfType("*dashboards.GetDashboardQuery")).Return(&dashboards.Dashboard{
+		UID:   "dash",
+		OrgID: 1,
+		Title: "Business overview",
+		Slug:  "business-overview",
+		Data: simplejson.NewFromAny(map[string]any{
+			"panels": []any{
+				map[string]any{"id": 1, "type": "row", "panels": []any{
+					map[string]any{"id": 2, "type": "timeseries", "title": "Revenue"},
+				}},
+				map[string]any{"id": 3, "type": "stat"},
+			},
+		}),
+	}, nil)
+
+	renderer := rendering.NewMockService(gomock.NewController(t))
+	renderer.EXPECT().IsAvailable(gomock.Any()).Return(true).AnyTimes()
+	renderer.EXPECT().CreateRenderingSession(gomock.Any(), gomock.Any(), gomock.Any()).Return(&fakeSession{}, nil).AnyTimes()
+
+	sender := notifications.MockNotificationService()
+	store := &fakeStore{}
+	cfg := setting.NewCfg()
+	cfg.AppURL = "http://localhost:3000/"
+	s := &Service{
+		cfg: cfg,
+		settings: setting.ReportingSettings{
+			Enabled:        true,
+			RenderTimeout:  time.Minute,
+			DashboardWidth: 1600,
+			PanelWidth:     1000,
+			PanelHeight:    500,
+			MaxPanels:      3,
+			HistoryMaxAge:  90 * 24 * time.Hour,
+		},
+		store:            store,
+		renderingService: renderer,
+		dashboardService: dashboardService,
+		userService:      &usertest.FakeUserService{},
+		emailSender:      sender,
+		webhookSender:    sender,
+		logger:           log.NewNopLogger(),
+		metrics:          newMetrics(nil),
+		now:              func() time.Time { return testNow },
+	}
+	return s, store, renderer, sender
+}
+
+func testImage(t *testing.T, width int, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+// writeTestImage writes an image like the rendering service does, the report removes it once read
+func writeTestImage(t *testing.T) *rendering.RenderResult {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "render.png")
+	require.NoError(t, os.WriteFile(path, testImage(t, 100, 50), 0o600))
+	return &rendering.RenderResult{FilePath: path}
+}
+
+type fakeSession struct {
+	rendering.Session
+}
+
+func (f *fakeSession) Dispose(ctx context.Context) {}
+
+type fakeStore struct {
+	mu         sync.Mutex
+	reports    []*report
+	deliveries []*reportDelivery
+}
+
+func (f *fakeStore) List(ctx context.Context, orgID int64) ([]*report, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var reports []*report
+	for _, r := range f.reports {
+		if r.OrgID == orgID {
+			reports = append(reports, r)
+		}
+	}
+	return reports, nil
+}
+
+func (f *fakeStore) Get(ctx context.Context, orgID int64, uid string) (*report, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.reports {
+		if r.OrgID == orgID && r.UID == uid {
+			cp := *r
+			return &cp, nil
+		}
+	}
+	return nil, reporting.ErrReportNotFound.Errorf("not found")
+}
+
+func (f *fakeStore) Insert(ctx context.Context, r *report) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, existing := range f.reports {
+		if existing.OrgID == r.OrgID && existing.UID == r.UID {
+			return reporting.ErrReportUIDExists.Errorf("exists")
+		}
+	}
+	r.ID = int64(len(f.reports) + 1)
+	cp := *r
+	f.reports = append(f.reports, &cp)
+	return nil
+}
+
+func (f *fakeStore) Update(ctx context.Context, r *report) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, existing := range f.reports {
+		if existing.ID == r.ID {
+			cp := *r
+			f.reports[i] = &cp
+			return nil
+		}
+	}
+	return reporting.ErrReportNotFound.Errorf("not found")
+}
+
+func (f *fakeStore) Delete(ctx context.Context, orgID int64, uid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, r := range f.reports {
+		if r.OrgID == orgID && r.UID == uid {
+			f.reports = append(f.reports[:i], f.reports[i+1:]...)
+			return nil
+		}
+	}
+	return reporting.ErrReportNotFound.Errorf("not found")
+}
+
+func (f *fakeStore) GetDue(ctx context.Context, now int64, limit int) ([]*report, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var due []*report
+	for _, r := range f.reports {
+		if r.Enabled && r.NextRun > 0 && r.NextRun <= now {
+			cp := *r
+			due = append(due, &cp)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeStore) ClaimRun(ctx context.Context, id int64, previous int64, next int64) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.reports {
+		if r.ID == id && r.NextRun == previous {
+			r.NextRun = next
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeStore) InsertDelivery(ctx context.Context, delivery *reportDelivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delivery.ID = int64(len(f.deliveries) + 1)
+	f.deliveries = append(f.deliveries, delivery)
+	return nil
+}
+
+func (f *fakeStore) GetDeliveries(ctx context.Context, reportID int64, limit int) ([]*reportDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var deliveries []*reportDelivery
+	for i := len(f.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if f.deliveries[i].ReportID == reportID {
+			deliveries = append(deliveries, f.deliveries[i])
+		}
+	}
+	return deliveries, nil
+}
+
+func (f *fakeStore) DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kept := f.deliveries[:0]
+	for _, d := range f.deliveries {
+		if !d.Started.Before(before) {
+			kept = append(kept, d)
+		}
+	}
+	deleted := int64(len(f.deliveries) - len(kept))
+	f.deliveries = kept
+	return deleted, nil
+}
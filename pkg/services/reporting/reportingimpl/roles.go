@@ -0,0 +1,45 @@
+package reportingimpl
+
+import (
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/reporting"
+)
+
+var (
+	reportsReaderRole = accesscontrol.RoleDTO{
+		Name:        "fixed:scheduledreports:reader",
+		DisplayName: "Scheduled report reader",
+		Description: "List scheduled reports and the history of their deliveries",
+		Group:       "Scheduled reports",
+		Permissions: []accesscontrol.Permission{
+			{Action: reporting.ActionReportsRead},
+		},
+	}
+
+	reportsWriterRole = accesscontrol.RoleDTO{
+		Name:        "fixed:scheduledreports:writer",
+		DisplayName: "Scheduled report writer",
+		Description: "Create, update, delete and send scheduled reports",
+		Group:       "Scheduled reports",
+		Permissions: []accesscontrol.Permission{
+			{Action: reporting.ActionReportsRead},
+			{Action: reporting.ActionReportsWrite},
+			{Action: reporting.ActionReportsDelete},
+			{Action: reporting.ActionReportsSend},
+		},
+	}
+)
+
+func declareFixedRoles(ac accesscontrol.Service) error {
+	reportsReader := accesscontrol.RoleRegistration{
+		Role:   reportsReaderRole,
+		Grants: []string{string(org.RoleAdmin)},
+	}
+	reportsWriter := accesscontrol.RoleRegistration{
+		Role:   reportsWriterRole,
+		Grants: []string{string(org.RoleAdmin)},
+	}
+
+	return ac.DeclareFixedRoles(reportsReader, reportsWriter)
+}
@@ -0,0 +1,131 @@
+package reportingimpl
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/reporting"
+)
+
+const (
+	// scheduleCheckInterval is how often the due reports are looked up, schedules have a minute granularity
+	scheduleCheckInterval = 30 * time.Second
+	// historyCleanupInterval is how often the deliveries older than the history retention are deleted
+	historyCleanupInterval = time.Hour
+	// maxDueReports bounds the number of reports claimed by an instance at each check
+	maxDueReports = 50
+	// maxConcurrentDeliveries bounds the number of reports rendered at the same time by an instance
+	maxConcurrentDeliveries = 4
+)
+
+// IsDisabled returns true when reports are disabled
+func (s *Service) IsDisabled() bool {
+	return !s.settings.Enabled
+}
+
+// Run delivers the reports on their schedule. Every instance of a high availability setup runs the scheduler,
+// each due report is claimed by a single instance.
+func (s *Service) Run(ctx context.Context) error {
+	check := time.NewTicker(scheduleCheckInterval)
+	defer check.Stop()
+	cleanup := time.NewTicker(historyCleanupInterval)
+	defer cleanup.Stop()
+
+	s.cleanupHistory(ctx)
+
+	for {
+		select {
+		case <-check.C:
+			s.runDueReports(ctx)
+		case <-cleanup.C:
+			s.cleanupHistory(ctx)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// runDueReports claims the reports whose next scheduled delivery has passed and delivers them
+func (s *Service) runDueReports(ctx context.Context) {
+	now := s.now()
+	due, err := s.store.GetDue(ctx, now.Unix(), maxDueReports)
+	if err != nil {
+		s.logger.Error("Failed to get the reports to deliver", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentDeliveries)
+	for _, row := range due {
+		r, ok := s.claim(ctx, row, now)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if _, err := s.send(ctx, row.ID, r, reporting.TriggerSchedule); err != nil {
+				s.logger.Error("Failed to store report delivery", "report", r.UID, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// claim schedules the next delivery of a due report, it returns false when the report is not to be delivered by
+// this instance. Reports whose schedule can no longer be computed are disabled.
+func (s *Service) claim(ctx context.Context, row *report, now time.Time) (*reporting.Report, bool) {
+	logger := s.logger.New("report", row.UID)
+
+	var next int64
+	r, err := toReport(row)
+	if err == nil {
+		var nextTime time.Time
+		if nextTime, err = nextRun(row.Schedule, row.Timezone, now); err == nil {
+			next = nextTime.Unix()
+		}
+	}
+	if err != nil {
+		logger.Error("Invalid report, it won't be delivered until it is updated", "error", err)
+		if _, err := s.store.ClaimRun(ctx, row.ID, row.NextRun, 0); err != nil {
+			logger.Error("Failed to unschedule report", "error", err)
+		}
+		return nil, false
+	}
+
+	claimed, err := s.store.ClaimRun(ctx, row.ID, row.NextRun, next)
+	if err != nil {
+		logger.Error("Failed to claim report delivery", "error", err)
+		return nil, false
+	}
+	if !claimed {
+		logger.Debug("Report delivery claimed by another instance")
+		return nil, false
+	}
+
+	// the report was due at row.NextRun, deliveries missed while the server was down are only sent once
+	nextTime := time.Unix(next, 0)
+	r.NextRun = &nextTime
+	return r, true
+}
+
+func (s *Service) cleanupHistory(ctx context.Context) {
+	if s.settings.HistoryMaxAge <= 0 {
+		return
+	}
+
+	deleted, err := s.store.DeleteDeliveriesBefore(ctx, s.now().Add(-s.settings.HistoryMaxAge))
+	if err != nil {
+		s.logger.Error("Failed to delete the old report deliveries", "error", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger.Debug("Deleted the old report deliveries", "count", deleted)
+	}
+}
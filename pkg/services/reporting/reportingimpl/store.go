@@ -0,0 +1,167 @@
+package reportingimpl
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/reporting"
+)
+
+type store interface {
+	// List returns the reports of an organization sorted by name
+	List(ctx context.Context, orgID int64) ([]*report, error)
+	// Get returns a report by uid, or reporting.ErrReportNotFound
+	Get(ctx context.Context, orgID int64, uid string) (*report, error)
+	// Insert stores a new report, it fails with reporting.ErrReportUIDExists if the uid is already used in the organization
+	Insert(ctx context.Context, r *report) error
+	// Update replaces every column of a report but its uid, organization and creation time
+	Update(ctx context.Context, r *report) error
+	// Delete removes a report and its deliveries
+	Delete(ctx context.Context, orgID int64, uid string) error
+	// GetDue returns the enabled reports whose next scheduled delivery is at or before now
+	GetDue(ctx context.Context, now int64, limit int) ([]*report, error)
+	// ClaimRun moves the next scheduled delivery of a report from previous to next, it returns false if another
+	// instance did it first
+	ClaimRun(ctx context.Context, id int64, previous int64, next int64) (bool, error)
+	InsertDelivery(ctx context.Context, delivery *reportDelivery) error
+	// GetDeliveries returns the deliveries of a report, the most recent first
+	GetDeliveries(ctx context.Context, reportID int64, limit int) ([]*reportDelivery, error)
+	// DeleteDeliveriesBefore deletes the deliveries started before a time
+	DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type xormStore struct {
+	db db.DB
+}
+
+func (xs *xormStore) List(ctx context.Context, orgID int64) ([]*report, error) {
+	reports := make([]*report, 0)
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("org_id = ?", orgID).Asc("name").Find(&reports)
+	})
+	return reports, err
+}
+
+func (xs *xormStore) Get(ctx context.Context, orgID int64, uid string) (*report, error) {
+	r := &report{}
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		exists, err := sess.Where("org_id = ? AND uid = ?", orgID, uid).Get(r)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return reporting.ErrReportNotFound.Errorf("report %s not found in organization %d", uid, orgID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (xs *xormStore) Insert(ctx context.Context, r *report) error {
+	return xs.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		exists, err := sess.Table("scheduled_report").Where("org_id = ? AND uid = ?", r.OrgID, r.UID).Exist()
+		if err != nil {
+			return err
+		}
+		if exists {
+			return reporting.ErrReportUIDExists.Errorf("report %s already exists in organization %d", r.UID, r.OrgID)
+		}
+
+		_, err = sess.Insert(r)
+		return err
+	})
+}
+
+func (xs *xormStore) Update(ctx context.Context, r *report) error {
+	return xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		affected, err := sess.ID(r.ID).
+			Cols("name", "dashboard_uid", "panel_ids", "time_from", "time_to", "variables", "schedule", "timezone",
+				"format", "recipients", "webhook_url", "message", "enabled", "user_id", "next_run", "updated").
+			Update(r)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return reporting.ErrReportNotFound.Errorf("report %s not found in organization %d", r.UID, r.OrgID)
+		}
+		return nil
+	})
+}
+
+func (xs *xormStore) Delete(ctx context.Context, orgID int64, uid string) error {
+	return xs.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		r := &report{}
+		exists, err := sess.Where("org_id = ? AND uid = ?", orgID, uid).Get(r)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return reporting.ErrReportNotFound.Errorf("report %s not found in organization %d", uid, orgID)
+		}
+
+		if _, err := sess.Exec("DELETE FROM scheduled_report_delivery WHERE report_id = ?", r.ID); err != nil {
+			return err
+		}
+		_, err = sess.Exec("DELETE FROM scheduled_report WHERE id = ?", r.ID)
+		return err
+	})
+}
+
+func (xs *xormStore) GetDue(ctx context.Context, now int64, limit int) ([]*report, error) {
+	reports := make([]*report, 0)
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("enabled = ? AND next_run > 0 AND next_run <= ?", true, now).
+			Asc("next_run").Limit(limit).Find(&reports)
+	})
+	return reports, err
+}
+
+func (xs *xormStore) ClaimRun(ctx context.Context, id int64, previous int64, next int64) (bool, error) {
+	var claimed bool
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		// the condition on next_run makes the instances that read the same due report race for it, only one wins
+		res, err := sess.Exec("UPDATE scheduled_report SET next_run = ? WHERE id = ? AND next_run = ?", next, id, previous)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		claimed = affected == 1
+		return nil
+	})
+	return claimed, err
+}
+
+func (xs *xormStore) InsertDelivery(ctx context.Context, delivery *reportDelivery) error {
+	return xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Insert(delivery)
+		return err
+	})
+}
+
+func (xs *xormStore) GetDeliveries(ctx context.Context, reportID int64, limit int) ([]*reportDelivery, error) {
+	deliveries := make([]*reportDelivery, 0)
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("report_id = ?", reportID).Desc("started").Desc("id").Limit(limit).Find(&deliveries)
+	})
+	return deliveries, err
+}
+
+func (xs *xormStore) DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("DELETE FROM scheduled_report_delivery WHERE started < ?", before)
+		if err != nil {
+			return err
+		}
+		deleted, err = res.RowsAffected()
+		return err
+	})
+	return deleted, err
+}
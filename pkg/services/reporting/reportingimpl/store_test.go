@@ -0,0 +1,144 @@
+package reportingimpl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/reporting"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func TestIntegrationReportStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0).UTC()
+	s := &xormStore{db: db.InitTestDB(t)}
+
+	row := func(orgID int64, uid string, name string, nextRun int64) *report {
+		return &report{
+			UID:          uid,
+			OrgID:        orgID,
+			Name:         name,
+			DashboardUID: "dash",
+			PanelIDs:     "null",
+			Variables:    "null",
+			Schedule:     "@daily",
+			Format:       "pdf",
+			Recipients:   `["management@example.com"]`,
+			Enabled:      nextRun > 0,
+			NextRun:      nextRun,
+			Created:      now,
+			Updated:      now,
+		}
+	}
+
+	weekly := row(1, "weekly", "Weekly", now.Unix()-60)
+	require.NoError(t, s.Insert(ctx, weekly))
+	require.NoError(t, s.Insert(ctx, row(1, "daily", "Daily", now.Unix()+60)))
+	require.NoError(t, s.Insert(ctx, row(1, "disabled", "Disabled", 0)))
+	require.NoError(t, s.Insert(ctx, row(2, "weekly", "Weekly", now.Unix()-120)))
+
+	t.Run("uids are unique per organization", func(t *testing.T) {
+		err := s.Insert(ctx, row(1, "weekly", "Other", 0))
+		require.ErrorIs(t, err, reporting.ErrReportUIDExists)
+	})
+
+	t.Run("get and list", func(t *testing.T) {
+		r, err := s.Get(ctx, 1, "weekly")
+		require.NoError(t, err)
+		assert.Equal(t, weekly.ID, r.ID)
+		assert.Equal(t, `["management@example.com"]`, r.Recipients)
+
+		_, err = s.Get(ctx, 3, "weekly")
+		require.ErrorIs(t, err, reporting.ErrReportNotFound)
+
+		reports, err := s.List(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, reports, 3)
+		assert.Equal(t, []string{"Daily", "Disabled", "Weekly"}, []string{reports[0].Name, reports[1].Name, reports[2].Name})
+	})
+
+	t.Run("update", func(t *testing.T) {
+		r, err := s.Get(ctx, 1, "daily")
+		require.NoError(t, err)
+		r.Name = "Every day"
+		r.UserID = 3
+		require.NoError(t, s.Update(ctx, r))
+
+		r, err = s.Get(ctx, 1, "daily")
+		require.NoError(t, err)
+		assert.Equal(t, "Every day", r.Name)
+		assert.Equal(t, int64(3), r.UserID)
+
+		err = s.Update(ctx, &report{ID: 1000, UID: "missing", OrgID: 1})
+		require.ErrorIs(t, err, reporting.ErrReportNotFound)
+	})
+
+	t.Run("due reports are claimed once", func(t *testing.T) {
+		due, err := s.GetDue(ctx, now.Unix(), 10)
+		require.NoError(t, err)
+		require.Len(t, due, 2)
+		assert.Equal(t, int64(2), due[0].OrgID)
+		assert.Equal(t, weekly.ID, due[1].ID)
+
+		claimed, err := s.ClaimRun(ctx, weekly.ID, weekly.NextRun, now.Unix()+3600)
+		require.NoError(t, err)
+		assert.True(t, claimed)
+		claimed, err = s.ClaimRun(ctx, weekly.ID, weekly.NextRun, now.Unix()+3600)
+		require.NoError(t, err)
+		assert.False(t, claimed)
+
+		due, err = s.GetDue(ctx, now.Unix(), 10)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+	})
+
+	t.Run("deliveries", func(t *testing.T) {
+		for i, started := range []time.Time{now.Add(-48 * time.Hour), now.Add(-time.Hour), now} {
+			require.NoError(t, s.InsertDelivery(ctx, &reportDelivery{
+				OrgID:    1,
+				ReportID: weekly.ID,
+				Trigger:  string(reporting.TriggerSchedule),
+				Status:   string(reporting.DeliveryStatusSuccess),
+				Files:    i,
+				Started:  started,
+				Finished: started.Add(time.Second),
+			}))
+		}
+
+		deliveries, err := s.GetDeliveries(ctx, weekly.ID, 2)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 2)
+		assert.Equal(t, 2, deliveries[0].Files)
+		assert.Equal(t, 1, deliveries[1].Files)
+
+		deleted, err := s.DeleteDeliveriesBefore(ctx, now.Add(-24*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+	})
+
+	t.Run("delete removes the deliveries", func(t *testing.T) {
+		require.NoError(t, s.Delete(ctx, 1, "weekly"))
+		_, err := s.Get(ctx, 1, "weekly")
+		require.ErrorIs(t, err, reporting.ErrReportNotFound)
+
+		deliveries, err := s.GetDeliveries(ctx, weekly.ID, 10)
+		require.NoError(t, err)
+		assert.Empty(t, deliveries)
+
+		err = s.Delete(ctx, 1, "weekly")
+		require.ErrorIs(t, err, reporting.ErrReportNotFound)
+	})
+}
@@ -0,0 +1,63 @@
+package reportingtest
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/reporting"
+)
+
+var _ reporting.Service = new(FakeService)
+
+type FakeService struct {
+	ExpectedEnabled    bool
+	ExpectedReports    []*reporting.Report
+	ExpectedReport     *reporting.Report
+	ExpectedDelivery   *reporting.Delivery
+	ExpectedDeliveries []*reporting.Delivery
+	ExpectedErr        error
+
+	CreatedReports []*reporting.CreateReportCommand
+	UpdatedReports []*reporting.UpdateReportCommand
+	DeletedReports []string
+	SentReports    []string
+}
+
+func (f *FakeService) IsEnabled() bool {
+	return f.ExpectedEnabled
+}
+
+func (f *FakeService) GetReports(ctx context.Context, orgID int64) ([]*reporting.Report, error) {
+	return f.ExpectedReports, f.ExpectedErr
+}
+
+// GetReport returns reporting.ErrReportNotFound when no report or error is expected
+func (f *FakeService) GetReport(ctx context.Context, orgID int64, uid string) (*reporting.Report, error) {
+	if f.ExpectedReport == nil && f.ExpectedErr == nil {
+		return nil, reporting.ErrReportNotFound
+	}
+	return f.ExpectedReport, f.ExpectedErr
+}
+
+func (f *FakeService) CreateReport(ctx context.Context, cmd *reporting.CreateReportCommand) (*reporting.Report, error) {
+	f.CreatedReports = append(f.CreatedReports, cmd)
+	return f.ExpectedReport, f.ExpectedErr
+}
+
+func (f *FakeService) UpdateReport(ctx context.Context, cmd *reporting.UpdateReportCommand) (*reporting.Report, error) {
+	f.UpdatedReports = append(f.UpdatedReports, cmd)
+	return f.ExpectedReport, f.ExpectedErr
+}
+
+func (f *FakeService) DeleteReport(ctx context.Context, orgID int64, uid string) error {
+	f.DeletedReports = append(f.DeletedReports, uid)
+	return f.ExpectedErr
+}
+
+func (f *FakeService) SendReport(ctx context.Context, orgID int64, uid string) (*reporting.Delivery, error) {
+	f.SentReports = append(f.SentReports, uid)
+	return f.ExpectedDelivery, f.ExpectedErr
+}
+
+func (f *FakeService) GetDeliveries(ctx context.Context, query *reporting.GetDeliveriesQuery) ([]*reporting.Delivery, error) {
+	return f.ExpectedDeliveries, f.ExpectedErr
+}
@@ -0,0 +1,87 @@
+package scim
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+)
+
+// SCIM error types, see https://datatracker.ietf.org/doc/html/rfc7644#section-3.12
+const (
+	ScimTypeInvalidFilter = "invalidFilter"
+	ScimTypeUniqueness    = "uniqueness"
+	ScimTypeMutability    = "mutability"
+	ScimTypeInvalidSyntax = "invalidSyntax"
+	ScimTypeInvalidPath   = "invalidPath"
+	ScimTypeNoTarget      = "noTarget"
+	ScimTypeInvalidValue  = "invalidValue"
+)
+
+const (
+	invalidFilterTemplate = "Invalid filter: {{ .Public.Reason }}"
+	invalidPathTemplate   = "Invalid path: {{ .Public.Reason }}"
+	invalidValueTemplate  = "Invalid value: {{ .Public.Reason }}"
+	invalidSyntaxTemplate = "Invalid request: {{ .Public.Reason }}"
+)
+
+var (
+	ErrUserNotFound  = errutil.NotFound("scim.user-not-found", errutil.WithPublicMessage("User not found"))
+	ErrGroupNotFound = errutil.NotFound("scim.group-not-found", errutil.WithPublicMessage("Group not found"))
+	ErrUserExists    = errutil.Conflict("scim.user-exists", errutil.WithPublicMessage("User already exists in the organization"))
+	// ErrUserNotProvisionable is returned for users who belong to other organizations or are server admins
+	ErrUserNotProvisionable = errutil.Conflict("scim.user-not-provisionable", errutil.WithPublicMessage("User belongs to other organizations or is a server admin"))
+	ErrGroupExists          = errutil.Conflict("scim.group-exists", errutil.WithPublicMessage("Group with the same name already exists"))
+	ErrLastOrgAdmin         = errutil.BadRequest("scim.last-org-admin", errutil.WithPublicMessage("Cannot remove the last admin of the organization"))
+	ErrNoTarget             = errutil.BadRequest("scim.no-target", errutil.WithPublicMessage("Path did not match any value"))
+	ErrForbidden            = errutil.Forbidden("scim.forbidden", errutil.WithPublicMessage("SCIM requests must be authenticated with a service account of the provisioned organization"))
+
+	errInvalidFilter = errutil.BadRequest("scim.invalid-filter").MustTemplate(invalidFilterTemplate, errutil.WithPublic(invalidFilterTemplate))
+	errInvalidPath   = errutil.BadRequest("scim.invalid-path").MustTemplate(invalidPathTemplate, errutil.WithPublic(invalidPathTemplate))
+	errInvalidValue  = errutil.BadRequest("scim.invalid-value").MustTemplate(invalidValueTemplate, errutil.WithPublic(invalidValueTemplate))
+	errInvalidSyntax = errutil.BadRequest("scim.invalid-syntax").MustTemplate(invalidSyntaxTemplate, errutil.WithPublic(invalidSyntaxTemplate))
+)
+
+func ErrInvalidFilter(reason string) error {
+	return errInvalidFilter.Build(errutil.TemplateData{Public: map[string]any{"Reason": reason}})
+}
+
+func ErrInvalidPath(reason string) error {
+	return errInvalidPath.Build(errutil.TemplateData{Public: map[string]any{"Reason": reason}})
+}
+
+func ErrInvalidValue(reason string) error {
+	return errInvalidValue.Build(errutil.TemplateData{Public: map[string]any{"Reason": reason}})
+}
+
+func ErrInvalidSyntax(reason string) error {
+	return errInvalidSyntax.Build(errutil.TemplateData{Public: map[string]any{"Reason": reason}})
+}
+
+// scimTypes maps error message IDs to the SCIM error type returned to the client
+var scimTypes = map[string]string{
+	"scim.user-exists":    ScimTypeUniqueness,
+	"scim.group-exists":   ScimTypeUniqueness,
+	"scim.last-org-admin": ScimTypeMutability,
+	"scim.no-target":      ScimTypeNoTarget,
+	"scim.invalid-filter": ScimTypeInvalidFilter,
+	"scim.invalid-path":   ScimTypeInvalidPath,
+	"scim.invalid-value":  ScimTypeInvalidValue,
+	"scim.invalid-syntax": ScimTypeInvalidSyntax,
+}
+
+// NewErrorResponse converts an error to a SCIM error response
+func NewErrorResponse(err error) *ErrorResponse {
+	var grafanaErr errutil.Error
+	if !errors.As(err, &grafanaErr) {
+		return &ErrorResponse{Schemas: []string{SchemaError}, Status: "500", Detail: "Internal server error"}
+	}
+
+	public := grafanaErr.Public()
+	return &ErrorResponse{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(public.StatusCode),
+		ScimType: scimTypes[public.MessageID],
+		Detail:   public.Message,
+	}
+}
@@ -0,0 +1,224 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaBulkRequest           = "urn:ietf:params:scim:api:messages:2.0:BulkRequest"
+	SchemaBulkResponse          = "urn:ietf:params:scim:api:messages:2.0:BulkResponse"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+const (
+	ResourceTypeUser  = "User"
+	ResourceTypeGroup = "Group"
+)
+
+const (
+	// MaxBulkOperations is the maximum number of operations of a bulk request
+	MaxBulkOperations = 1000
+	// MaxBulkPayloadSize is the maximum size of a bulk request in bytes
+	MaxBulkPayloadSize = 1 << 20
+	// MaxResults is the maximum number of resources returned by a list request
+	MaxResults = 1000
+)
+
+// Service implements the SCIM 2.0 Users, Groups and Bulk endpoints on top of Grafana users and teams.
+// Users are provisioned as members of an organization, groups are teams of that organization.
+type Service interface {
+	ListUsers(ctx context.Context, orgID int64, query *ListQuery) (*ListResponse, error)
+	GetUser(ctx context.Context, orgID int64, id string) (*User, error)
+	CreateUser(ctx context.Context, orgID int64, u *User) (*User, error)
+	ReplaceUser(ctx context.Context, orgID int64, id string, u *User) (*User, error)
+	PatchUser(ctx context.Context, orgID int64, id string, patch *PatchRequest) (*User, error)
+	DeleteUser(ctx context.Context, orgID int64, id string) error
+
+	ListGroups(ctx context.Context, orgID int64, query *ListQuery) (*ListResponse, error)
+	GetGroup(ctx context.Context, orgID int64, id string) (*Group, error)
+	CreateGroup(ctx context.Context, orgID int64, g *Group) (*Group, error)
+	ReplaceGroup(ctx context.Context, orgID int64, id string, g *Group) (*Group, error)
+	PatchGroup(ctx context.Context, orgID int64, id string, patch *PatchRequest) (*Group, error)
+	DeleteGroup(ctx context.Context, orgID int64, id string) error
+
+	Bulk(ctx context.Context, orgID int64, req *BulkRequest) (*BulkResponse, error)
+}
+
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary Bool   `json:"primary,omitempty"`
+}
+
+type User struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *Name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []Email  `json:"emails,omitempty"`
+	// Active is nil when the client doesn't set it, users are active by default
+	Active   *Bool  `json:"active,omitempty"`
+	Password string `json:"password,omitempty"`
+	Meta     *Meta  `json:"meta,omitempty"`
+}
+
+// FullName returns the name of the user in Grafana
+func (u *User) FullName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name == nil {
+		return ""
+	}
+	if u.Name.Formatted != "" {
+		return u.Name.Formatted
+	}
+	return strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+}
+
+// PrimaryEmail returns the primary email of the user, or its first email when none is primary
+func (u *User) PrimaryEmail() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+// IsActive returns false when the client deactivated the user
+func (u *User) IsActive() bool {
+	return u.Active == nil || bool(*u.Active)
+}
+
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+type ListQuery struct {
+	Filter string
+	// StartIndex is the 1-based index of the first result
+	StartIndex int
+	// Count is the maximum number of results, 0 only returns the total number of results
+	Count int
+	// ExcludedAttributes are attributes left out of the results, e.g. members
+	ExcludedAttributes []string
+}
+
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	// Op is add, replace or remove, compared case insensitively
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type BulkRequest struct {
+	Schemas []string `json:"schemas"`
+	// FailOnErrors is the number of errors after which the remaining operations are skipped, 0 means no limit
+	FailOnErrors int             `json:"failOnErrors,omitempty"`
+	Operations   []BulkOperation `json:"Operations"`
+}
+
+type BulkOperation struct {
+	Method  string          `json:"method"`
+	BulkID  string          `json:"bulkId,omitempty"`
+	Version string          `json:"version,omitempty"`
+	Path    string          `json:"path"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+type BulkResponse struct {
+	Schemas    []string              `json:"schemas"`
+	Operations []BulkOperationResult `json:"Operations"`
+}
+
+type BulkOperationResult struct {
+	Method   string `json:"method"`
+	BulkID   string `json:"bulkId,omitempty"`
+	Location string `json:"location,omitempty"`
+	Status   string `json:"status"`
+	Response any    `json:"response,omitempty"`
+}
+
+type ErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// Bool is a boolean that also accepts the "True" and "False" strings sent by some identity providers
+type Bool bool
+
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		switch strings.ToLower(s) {
+		case "true":
+			*b = true
+		case "false":
+			*b = false
+		default:
+			return ErrInvalidValue("expected a boolean, got " + s)
+		}
+		return nil
+	}
+
+	var v bool
+	if err := json.Unmarshal(data, &v); err != nil {
+		return ErrInvalidValue("expected a boolean")
+	}
+	*b = Bool(v)
+	return nil
+}
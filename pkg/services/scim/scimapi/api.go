@@ -0,0 +1,377 @@
+package scimapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/middleware/requestmeta"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/scim"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+const contentType = "application/scim+json"
+
+// maxPayloadSize is the maximum size of the users and groups requests in bytes
+const maxPayloadSize = 1 << 20
+
+var errPayloadTooLarge = errors.New("request payload too large")
+
+type API struct {
+	cfg         *setting.Cfg
+	scimService scim.Service
+	logger      log.Logger
+}
+
+func ProvideAPI(
+	cfg *setting.Cfg,
+	routeRegister routing.RouteRegister,
+	accessControl ac.AccessControl,
+	scimService scim.Service,
+) *API {
+	api := &API{
+		cfg:         cfg,
+		scimService: scimService,
+		logger:      log.New("scim-api"),
+	}
+
+	if cfg.SCIM.Enabled {
+		api.registerRoutes(routeRegister, accessControl)
+	}
+	return api
+}
+
+func (api *API) registerRoutes(router routing.RouteRegister, accessControl ac.AccessControl) {
+	authorize := ac.Middleware(accessControl)
+	usersRead := ac.EvalPermission(ac.ActionOrgUsersRead, ac.ScopeUsersAll)
+	usersWrite := ac.EvalAll(
+		ac.EvalPermission(ac.ActionOrgUsersAdd, ac.ScopeUsersAll),
+		ac.EvalPermission(ac.ActionOrgUsersWrite, ac.ScopeUsersAll),
+	)
+	usersRemove := ac.EvalPermission(ac.ActionOrgUsersRemove, ac.ScopeUsersAll)
+	teamsRead := ac.EvalPermission(ac.ActionTeamsRead, ac.ScopeTeamsAll)
+	teamsWrite := ac.EvalAll(
+		ac.EvalPermission(ac.ActionTeamsWrite, ac.ScopeTeamsAll),
+		ac.EvalPermission(ac.ActionTeamsPermissionsWrite, ac.ScopeTeamsAll),
+	)
+	teamsCreate := ac.EvalAll(ac.EvalPermission(ac.ActionTeamsCreate), teamsWrite)
+	teamsDelete := ac.EvalPermission(ac.ActionTeamsDelete, ac.ScopeTeamsAll)
+
+	router.Group("/api/scim/v2", func(scimRoute routing.RouteRegister) {
+		scimRoute.Get("/ServiceProviderConfig", routing.Wrap(api.getServiceProviderConfig))
+		scimRoute.Get("/ResourceTypes", routing.Wrap(api.getResourceTypes))
+
+		scimRoute.Group("/Users", func(usersRoute routing.RouteRegister) {
+			usersRoute.Get("/", authorize(usersRead), routing.Wrap(api.listUsers))
+			usersRoute.Post("/", authorize(usersWrite), routing.Wrap(api.createUser))
+			usersRoute.Get("/:id", authorize(usersRead), routing.Wrap(api.getUser))
+			usersRoute.Put("/:id", authorize(usersWrite), routing.Wrap(api.replaceUser))
+			usersRoute.Patch("/:id", authorize(usersWrite), routing.Wrap(api.patchUser))
+			usersRoute.Delete("/:id", authorize(usersRemove), routing.Wrap(api.deleteUser))
+		})
+
+		scimRoute.Group("/Groups", func(groupsRoute routing.RouteRegister) {
+			groupsRoute.Get("/", authorize(teamsRead), routing.Wrap(api.listGroups))
+			groupsRoute.Post("/", authorize(teamsCreate), routing.Wrap(api.createGroup))
+			groupsRoute.Get("/:id", authorize(teamsRead), routing.Wrap(api.getGroup))
+			groupsRoute.Put("/:id", authorize(teamsWrite), routing.Wrap(api.replaceGroup))
+			groupsRoute.Patch("/:id", authorize(teamsWrite), routing.Wrap(api.patchGroup))
+			groupsRoute.Delete("/:id", authorize(teamsDelete), routing.Wrap(api.deleteGroup))
+		})
+
+		scimRoute.Post("/Bulk", authorize(ac.EvalAll(usersRead, usersWrite, usersRemove, teamsRead, teamsCreate, teamsDelete)), routing.Wrap(api.bulk))
+	}, requestmeta.SetOwner(requestmeta.TeamAuth), middleware.ReqSignedIn, api.reqServiceAccount)
+}
+
+// reqServiceAccount only lets service accounts of the provisioned organization through
+func (api *API) reqServiceAccount(c *contextmodel.ReqContext) {
+	if !c.SignedInUser.IsIdentityType(claims.TypeServiceAccount) || c.SignedInUser.GetOrgID() != api.cfg.SCIM.OrgID {
+		api.errorResponse(c, scim.ErrForbidden.Errorf("identity %s is not a service account of organization %d", c.SignedInUser.GetID(), api.cfg.SCIM.OrgID)).WriteTo(c)
+	}
+}
+
+func (api *API) listUsers(c *contextmodel.ReqContext) response.Response {
+	query, err := listQuery(c)
+	if err != nil {
+		return api.errorResponse(c, err)
+	}
+
+	res, err := api.scimService.ListUsers(c.Req.Context(), c.SignedInUser.GetOrgID(), query)
+	if err != nil {
+		return api.errorResponse(c, err)
+	}
+	return jsonResponse(http.StatusOK, res)
+}
+
+func (api *API) getUser(c *contextmodel.ReqContext) response.Response {
+	u, err := api.scimService.GetUser(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"])
+	if err != nil {
+		return api.errorResponse(c, err)
+	}
+	return jsonResponse(http.StatusOK, u)
+}
+
+func (api *API) createUser(c *contextmodel.ReqContext) response.Response {
+	var in scim.User
+	if err := bind(c, &in, maxPayloadSize); err != nil {
+		return api.errorResponse(c, err)
+	}
+
+	u, err := api.scimService.CreateUser(c.Req.Context(), c.SignedInUser.GetOrgID(), &in)
+	if err != nil {
+		return api.errorResponse(c, err)
+	}
+	return jsonResponse(http.StatusCreated, u).SetHeader("Location", u.Meta.Location)
+}
+
+func (api *API) replaceUser(c *contextmodel.ReqContext) response.Response {
+	var in scim.User
+	if err := bind(c, &in, maxPayloadSize); err != nil {
+		return api.errorResponse(c, err)
+	}
+
+	u, err := api.scimService.ReplaceUser(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"], &in)
+	if err != nil {
+		return api.errorResponse(c, err)
+	}
+	return jsonResponse(http.StatusOK, u)
+}
+
+func (api *API) patchUser(c *contextmodel.ReqContext) response.Response {
+	var patch scim.PatchRequest
+	if err := bind(c, &patch, maxPayloadSize); err != nil {
+		return api.errorResponse(c, err)
+	}
+
+	u, err := api.scimService.PatchUser(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"], &patch)
+	if err != nil {
+		return api.errorResponse(c, err)
+	}
+	return jsonResponse(http.StatusOK, u)
+}
+
+func (api *API) deleteUser(c *contextmodel.ReqContext) response.Response {
+	if err := api.scimService.DeleteUser(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"]); err != nil {
+		return api.errorResponse(c, err)
+	}
+	return response.Empty(http.StatusNoContent)
+}
+
+func (api *API) listGroups(c *contextmodel.ReqContext) response.Response {
+	query, err := listQuery(c)
+	if err != nil {
+		return api.errorResponse(c, err)
+	}
+
+	res, err := api.scimService.ListGroups(c.Req.Context(), c.SignedInUser.GetOrgID(), query)
+	if err != nil {
+		return api.errorResponse(c, err)
+	}
+	return jsonResponse(http.StatusOK, res)
+}
+
+func (api *API) getGroup(c *contextmodel.ReqContext) response.Response {
+	g, err := api.scimService.GetGroup(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"])
+	if err != nil {
+		return api.errorResponse(c, err)
+	}
+	return jsonResponse(http.StatusOK, g)
+}
+
+func (api *API) createGroup(c *contextmodel.ReqContext) response.Response {
+	var in scim.Group
+	if err := bind(c, &in, maxPayloadSize); err != nil {
+		return api.errorResponse(c, err)
+	}
+
+	g, err := api.scimService.CreateGroup(c.Req.Context(), c.SignedInUser.GetOrgID(), &in)
+	if err != nil {
+		return api.errorResponse(c, err)
+	}
+	return jsonResponse(http.StatusCreated, g).SetHeader("Location", g.Meta.Location)
+}
+
+func (api *API) replaceGroup(c *contextmodel.ReqContext) response.Response {
+	var in scim.Group
+	if err := bind(c, &in, maxPayloadSize); err != nil {
+		return api.errorResponse(c, err)
+	}
+
+	g, err := api.scimService.ReplaceGroup(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"], &in)
+	if err != nil {
+		return api.errorResponse(c, err)
+	}
+	return jsonResponse(http.StatusOK, g)
+}
+
+func (api *API) patchGroup(c *contextmodel.ReqContext) response.Response {
+	var patch scim.PatchRequest
+	if err := bind(c, &patch, maxPayloadSize); err != nil {
+		return api.errorResponse(c, err)
+	}
+
+	g, err := api.scimService.PatchGroup(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"], &patch)
+	if err != nil {
+		return api.errorResponse(c, err)
+	}
+	return jsonResponse(http.StatusOK, g)
+}
+
+func (api *API) deleteGroup(c *contextmodel.ReqContext) response.Response {
+	if err := api.scimService.DeleteGroup(c.Req.Context(), c.SignedInUser.GetOrgID(), web.Params(c.Req)[":id"]); err != nil {
+		return api.errorResponse(c, err)
+	}
+	return response.Empty(http.StatusNoContent)
+}
+
+func (api *API) bulk(c *contextmodel.ReqContext) response.Response {
+	var req scim.BulkRequest
+	if err := bind(c, &req, scim.MaxBulkPayloadSize); err != nil {
+		return api.errorResponse(c, err)
+	}
+	if len(req.Operations) > scim.MaxBulkOperations {
+		return tooLargeResponse("The number of operations exceeds the maximum of " + strconv.Itoa(scim.MaxBulkOperations))
+	}
+
+	res, err := api.scimService.Bulk(c.Req.Context(), c.SignedInUser.GetOrgID(), &req)
+	if err != nil {
+		return api.errorResponse(c, err)
+	}
+	return jsonResponse(http.StatusOK, res)
+}
+
+func (api *API) getServiceProviderConfig(c *contextmodel.ReqContext) response.Response {
+	return jsonResponse(http.StatusOK, map[string]any{
+		"schemas": []string{scim.SchemaServiceProviderConfig},
+		"patch":   map[string]any{"supported": true},
+		"bulk": map[string]any{
+			"supported":      true,
+			"maxOperations":  scim.MaxBulkOperations,
+			"maxPayloadSize": scim.MaxBulkPayloadSize,
+		},
+		"filter":         map[string]any{"supported": true, "maxResults": scim.MaxResults},
+		"changePassword": map[string]any{"supported": true},
+		"sort":           map[string]any{"supported": false},
+		"etag":           map[string]any{"supported": false},
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "Service account token",
+			"description": "Authentication with the token of a Grafana service account",
+			"primary":     true,
+		}},
+	})
+}
+
+func (api *API) getResourceTypes(c *contextmodel.ReqContext) response.Response {
+	resourceTypes := []map[string]any{
+		{
+			"schemas":  []string{scim.SchemaResourceType},
+			"id":       scim.ResourceTypeUser,
+			"name":     scim.ResourceTypeUser,
+			"endpoint": "/Users",
+			"schema":   scim.SchemaUser,
+		},
+		{
+			"schemas":  []string{scim.SchemaResourceType},
+			"id":       scim.ResourceTypeGroup,
+			"name":     scim.ResourceTypeGroup,
+			"endpoint": "/Groups",
+			"schema":   scim.SchemaGroup,
+		},
+	}
+	return jsonResponse(http.StatusOK, &scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: len(resourceTypes),
+		StartIndex:   1,
+		ItemsPerPage: len(resourceTypes),
+		Resources:    []any{resourceTypes[0], resourceTypes[1]},
+	})
+}
+
+func (api *API) errorResponse(c *contextmodel.ReqContext, err error) *response.NormalResponse {
+	if errors.Is(err, errPayloadTooLarge) {
+		return tooLargeResponse(err.Error())
+	}
+
+	res := scim.NewErrorResponse(err)
+	status, _ := strconv.Atoi(res.Status)
+	if status >= http.StatusInternalServerError {
+		api.logger.FromContext(c.Req.Context()).Error("SCIM request failed", "method", c.Req.Method, "path", c.Req.URL.Path, "error", err)
+	} else {
+		api.logger.FromContext(c.Req.Context()).Debug("SCIM request rejected", "method", c.Req.Method, "path", c.Req.URL.Path, "error", err)
+	}
+	return jsonResponse(status, res)
+}
+
+func tooLargeResponse(detail string) *response.NormalResponse {
+	return jsonResponse(http.StatusRequestEntityTooLarge, &scim.ErrorResponse{
+		Schemas: []string{scim.SchemaError},
+		Status:  strconv.Itoa(http.StatusRequestEntityTooLarge),
+		Detail:  detail,
+	})
+}
+
+func jsonResponse(status int, body any) *response.NormalResponse {
+	return response.JSON(status, body).SetHeader("Content-Type", contentType)
+}
+
+// bind decodes the body of requests sent with the application/scim+json or the application/json content type
+func bind(c *contextmodel.ReqContext, v any, maxSize int64) error {
+	body, err := io.ReadAll(io.LimitReader(c.Req.Body, maxSize+1))
+	if err != nil {
+		return scim.ErrInvalidSyntax("failed to read the request body")
+	}
+	if int64(len(body)) > maxSize {
+		return fmt.Errorf("%w: the maximum size is %d bytes", errPayloadTooLarge, maxSize)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		var grafanaErr errutil.Error
+		if errors.As(err, &grafanaErr) {
+			return err
+		}
+		return scim.ErrInvalidSyntax(err.Error())
+	}
+	return nil
+}
+
+// listQuery parses the query parameters of list requests, see https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2
+func listQuery(c *contextmodel.ReqContext) (*scim.ListQuery, error) {
+	query := &scim.ListQuery{
+		Filter:     c.Query("filter"),
+		StartIndex: 1,
+		Count:      scim.MaxResults,
+	}
+
+	if v := c.Query("startIndex"); v != "" {
+		startIndex, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, scim.ErrInvalidValue("startIndex must be an integer")
+		}
+		query.StartIndex = startIndex
+	}
+	if v := c.Query("count"); v != "" {
+		count, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, scim.ErrInvalidValue("count must be an integer")
+		}
+		query.Count = count
+	}
+	if v := c.Query("excludedAttributes"); v != "" {
+		query.ExcludedAttributes = strings.Split(v, ",")
+	}
+	return query, nil
+}
@@ -0,0 +1,228 @@
+package scimapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/acimpl"
+	"github.com/grafana/grafana/pkg/services/authz/zanzana"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/scim"
+	"github.com/grafana/grafana/pkg/services/scim/scimtest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+var usersPermissions = []accesscontrol.Permission{
+	{Action: accesscontrol.ActionOrgUsersRead, Scope: accesscontrol.ScopeUsersAll},
+	{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll},
+	{Action: accesscontrol.ActionOrgUsersWrite, Scope: accesscontrol.ScopeUsersAll},
+	{Action: accesscontrol.ActionOrgUsersRemove, Scope: accesscontrol.ScopeUsersAll},
+}
+
+func TestSCIMAPI_Users(t *testing.T) {
+	t.Run("should list users with query parameters", func(t *testing.T) {
+		fake := &scimtest.FakeService{ExpectedListResponse: &scim.ListResponse{Schemas: []string{scim.SchemaListResponse}, TotalResults: 0}}
+		server := setupTestServer(t, fake, true)
+
+		req := webtest.RequestWithSignedInUser(server.NewGetRequest(`/api/scim/v2/Users?filter=userName+eq+%22alice%22&startIndex=2&count=10`), serviceAccount(1, usersPermissions))
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/scim+json", res.Header.Get("Content-Type"))
+		assert.Equal(t, &scim.ListQuery{Filter: `userName eq "alice"`, StartIndex: 2, Count: 10}, fake.ListQuery)
+	})
+
+	t.Run("should create user", func(t *testing.T) {
+		fake := &scimtest.FakeService{ExpectedUser: &scim.User{ID: "2", UserName: "alice", Meta: &scim.Meta{Location: "http://localhost:3000/api/scim/v2/Users/2"}}}
+		server := setupTestServer(t, fake, true)
+
+		req := webtest.RequestWithSignedInUser(server.NewRequest(http.MethodPost, "/api/scim/v2/Users", strings.NewReader(`{"userName": "alice"}`)), serviceAccount(1, usersPermissions))
+		req.Header.Set("Content-Type", "application/scim+json")
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, "http://localhost:3000/api/scim/v2/Users/2", res.Header.Get("Location"))
+	})
+
+	t.Run("should return SCIM errors", func(t *testing.T) {
+		fake := &scimtest.FakeService{ExpectedErr: scim.ErrUserExists.Errorf("conflict")}
+		server := setupTestServer(t, fake, true)
+
+		req := webtest.RequestWithSignedInUser(server.NewRequest(http.MethodPost, "/api/scim/v2/Users", strings.NewReader(`{"userName": "alice"}`)), serviceAccount(1, usersPermissions))
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+
+		var errResponse scim.ErrorResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&errResponse))
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+		assert.Equal(t, "409", errResponse.Status)
+		assert.Equal(t, scim.ScimTypeUniqueness, errResponse.ScimType)
+	})
+
+	t.Run("should reject invalid JSON", func(t *testing.T) {
+		server := setupTestServer(t, &scimtest.FakeService{}, true)
+
+		req := webtest.RequestWithSignedInUser(server.NewRequest(http.MethodPut, "/api/scim/v2/Users/2", strings.NewReader(`{"userName": `)), serviceAccount(1, usersPermissions))
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should delete user", func(t *testing.T) {
+		fake := &scimtest.FakeService{}
+		server := setupTestServer(t, fake, true)
+
+		req := webtest.RequestWithSignedInUser(server.NewRequest(http.MethodDelete, "/api/scim/v2/Users/2", nil), serviceAccount(1, usersPermissions))
+		res, err := server.Send(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+		assert.Equal(t, []string{"2"}, fake.DeletedIDs)
+	})
+}
+
+func TestSCIMAPI_Access(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		user     *user.SignedInUser
+		expected int
+	}{
+		{
+			name:     "should allow service accounts of the provisioned organization",
+			enabled:  true,
+			user:     serviceAccount(1, usersPermissions),
+			expected: http.StatusOK,
+		},
+		{
+			name:     "should reject users",
+			enabled:  true,
+			user:     &user.SignedInUser{UserID: 1, OrgID: 1, OrgRole: org.RoleAdmin, Permissions: map[int64]map[string][]string{1: accesscontrol.GroupScopesByActionContext(context.Background(), usersPermissions)}},
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "should reject service accounts of other organizations",
+			enabled:  true,
+			user:     serviceAccount(2, usersPermissions),
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "should reject service accounts without permissions",
+			enabled:  true,
+			user:     serviceAccount(1, nil),
+			expected: http.StatusForbidden,
+		},
+		{
+			name:     "should not register routes when disabled",
+			enabled:  false,
+			user:     serviceAccount(1, usersPermissions),
+			expected: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &scimtest.FakeService{ExpectedUser: &scim.User{ID: "2", UserName: "alice"}}
+			server := setupTestServer(t, fake, tt.enabled)
+
+			req := webtest.RequestWithSignedInUser(server.NewGetRequest("/api/scim/v2/Users/2"), tt.user)
+			res, err := server.Send(req)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+
+			assert.Equal(t, tt.expected, res.StatusCode)
+		})
+	}
+}
+
+func TestSCIMAPI_Bulk(t *testing.T) {
+	permissions := append([]accesscontrol.Permission{
+		{Action: accesscontrol.ActionTeamsRead, Scope: accesscontrol.ScopeTeamsAll},
+		{Action: accesscontrol.ActionTeamsCreate},
+		{Action: accesscontrol.ActionTeamsWrite, Scope: accesscontrol.ScopeTeamsAll},
+		{Action: accesscontrol.ActionTeamsPermissionsWrite, Scope: accesscontrol.ScopeTeamsAll},
+		{Action: accesscontrol.ActionTeamsDelete, Scope: accesscontrol.ScopeTeamsAll},
+	}, usersPermissions...)
+
+	t.Run("should apply bulk request", func(t *testing.T) {
+		fake := &scimtest.FakeService{ExpectedBulkResponse: &scim.BulkResponse{Schemas: []string{scim.SchemaBulkResponse}}}
+		server := setupTestServer(t, fake, true)
+
+		body := `{"schemas": ["` + scim.SchemaBulkRequest + `"], "Operations": [{"method": "DELETE", "path": "/Users/2"}]}`
+		req := webtest.RequestWithSignedInUser(server.NewRequest(http.MethodPost, "/api/scim/v2/Bulk", strings.NewReader(body)), serviceAccount(1, permissions))
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should reject too many operations", func(t *testing.T) {
+		server := setupTestServer(t, &scimtest.FakeService{}, true)
+
+		operations := make([]string, scim.MaxBulkOperations+1)
+		for i := range operations {
+			operations[i] = `{"method":"DELETE","path":"/Users/2"}`
+		}
+		body := `{"Operations": [` + strings.Join(operations, ",") + `]}`
+		req := webtest.RequestWithSignedInUser(server.NewRequest(http.MethodPost, "/api/scim/v2/Bulk", strings.NewReader(body)), serviceAccount(1, permissions))
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+	})
+
+	t.Run("should reject too large payloads", func(t *testing.T) {
+		server := setupTestServer(t, &scimtest.FakeService{}, true)
+
+		body := `{"Operations": [{"method": "POST", "bulkId": "1", "path": "/Users", "data": {"userName": "` + strings.Repeat("a", scim.MaxBulkPayloadSize) + `"}}]}`
+		req := webtest.RequestWithSignedInUser(server.NewRequest(http.MethodPost, "/api/scim/v2/Bulk", strings.NewReader(body)), serviceAccount(1, permissions))
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+	})
+}
+
+func setupTestServer(t *testing.T, scimService scim.Service, enabled bool) *webtest.Server {
+	t.Helper()
+
+	cfg := setting.NewCfg()
+	cfg.SCIM = setting.SCIMSettings{Enabled: enabled, OrgID: 1, DefaultOrgRole: "Viewer"}
+
+	router := routing.NewRouteRegister()
+	ProvideAPI(cfg, router, acimpl.ProvideAccessControl(featuremgmt.WithFeatures(), zanzana.NewNoopClient()), scimService)
+	return webtest.NewServer(t, router)
+}
+
+func serviceAccount(orgID int64, permissions []accesscontrol.Permission) *user.SignedInUser {
+	return &user.SignedInUser{
+		UserID:           10,
+		OrgID:            orgID,
+		OrgRole:          org.RoleNone,
+		IsServiceAccount: true,
+		Permissions:      map[int64]map[string][]string{orgID: accesscontrol.GroupScopesByActionContext(context.Background(), permissions)},
+	}
+}
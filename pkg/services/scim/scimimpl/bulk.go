@@ -0,0 +1,196 @@
+package scimimpl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/services/scim"
+)
+
+// bulkIDRef matches references to resources created earlier in the same bulk request
+var bulkIDRef = regexp.MustCompile(`bulkId:([^"/\s]+)`)
+
+// Bulk applies the operations of a bulk request in order, see https://datatracker.ietf.org/doc/html/rfc7644#section-3.7.
+// Operations may reference a resource created by an earlier operation with bulkId:<id>.
+func (s *Service) Bulk(ctx context.Context, orgID int64, req *scim.BulkRequest) (*scim.BulkResponse, error) {
+	if len(req.Operations) > scim.MaxBulkOperations {
+		return nil, scim.ErrInvalidValue("too many operations, the maximum is " + strconv.Itoa(scim.MaxBulkOperations))
+	}
+
+	res := &scim.BulkResponse{
+		Schemas:    []string{scim.SchemaBulkResponse},
+		Operations: make([]scim.BulkOperationResult, 0, len(req.Operations)),
+	}
+
+	// created maps the bulk ids to the ids of the created resources
+	created := map[string]string{}
+	errCount := 0
+	for _, op := range req.Operations {
+		if req.FailOnErrors > 0 && errCount >= req.FailOnErrors {
+			break
+		}
+
+		result := s.bulkOperation(ctx, orgID, op, created)
+		if status, _ := strconv.Atoi(result.Status); status >= http.StatusBadRequest {
+			errCount++
+		}
+		res.Operations = append(res.Operations, result)
+	}
+
+	s.log.FromContext(ctx).Info("Applied bulk request", "orgId", orgID, "operations", len(res.Operations), "errors", errCount)
+	return res, nil
+}
+
+func (s *Service) bulkOperation(ctx context.Context, orgID int64, op scim.BulkOperation, created map[string]string) scim.BulkOperationResult {
+	method := strings.ToUpper(op.Method)
+	result := scim.BulkOperationResult{Method: method, BulkID: op.BulkID}
+
+	location, status, err := s.applyBulkOperation(ctx, orgID, method, op, created)
+	if err != nil {
+		errResponse := scim.NewErrorResponse(err)
+		if errResponse.Status == "500" {
+			s.log.FromContext(ctx).Error("Failed to apply bulk operation", "orgId", orgID, "method", method, "path", op.Path, "error", err)
+		}
+		result.Status = errResponse.Status
+		result.Response = errResponse
+		return result
+	}
+
+	result.Location = location
+	result.Status = strconv.Itoa(status)
+	return result
+}
+
+func (s *Service) applyBulkOperation(ctx context.Context, orgID int64, method string, op scim.BulkOperation, created map[string]string) (string, int, error) {
+	path, err := resolveBulkIDs(op.Path, created)
+	if err != nil {
+		return "", 0, err
+	}
+	data, err := resolveBulkIDs(string(op.Data), created)
+	if err != nil {
+		return "", 0, err
+	}
+
+	resource, id, ok := strings.Cut(strings.Trim(path, "/"), "/")
+	if resource != "Users" && resource != "Groups" {
+		return "", 0, scim.ErrInvalidPath("unsupported bulk path " + op.Path)
+	}
+	if method == http.MethodPost {
+		if ok {
+			return "", 0, scim.ErrInvalidPath("POST operations must target /" + resource)
+		}
+		if op.BulkID == "" {
+			return "", 0, scim.ErrInvalidValue("bulkId is required for POST operations")
+		}
+	} else if !ok || id == "" {
+		return "", 0, scim.ErrInvalidPath(method + " operations must target /" + resource + "/{id}")
+	}
+
+	switch method {
+	case http.MethodPost:
+		if resource == "Users" {
+			var u scim.User
+			if err := decodeBulkData(data, &u); err != nil {
+				return "", 0, err
+			}
+			createdUser, err := s.CreateUser(ctx, orgID, &u)
+			if err != nil {
+				return "", 0, err
+			}
+			created[op.BulkID] = createdUser.ID
+			return s.location(resource, createdUser.ID), http.StatusCreated, nil
+		}
+		var g scim.Group
+		if err := decodeBulkData(data, &g); err != nil {
+			return "", 0, err
+		}
+		group, err := s.CreateGroup(ctx, orgID, &g)
+		if err != nil {
+			return "", 0, err
+		}
+		created[op.BulkID] = group.ID
+		return s.location(resource, group.ID), http.StatusCreated, nil
+	case http.MethodPut:
+		if resource == "Users" {
+			var u scim.User
+			if err := decodeBulkData(data, &u); err != nil {
+				return "", 0, err
+			}
+			if _, err := s.ReplaceUser(ctx, orgID, id, &u); err != nil {
+				return "", 0, err
+			}
+		} else {
+			var g scim.Group
+			if err := decodeBulkData(data, &g); err != nil {
+				return "", 0, err
+			}
+			if _, err := s.ReplaceGroup(ctx, orgID, id, &g); err != nil {
+				return "", 0, err
+			}
+		}
+		return s.location(resource, id), http.StatusOK, nil
+	case http.MethodPatch:
+		var patch scim.PatchRequest
+		if err := decodeBulkData(data, &patch); err != nil {
+			return "", 0, err
+		}
+		if resource == "Users" {
+			if _, err := s.PatchUser(ctx, orgID, id, &patch); err != nil {
+				return "", 0, err
+			}
+		} else if _, err := s.PatchGroup(ctx, orgID, id, &patch); err != nil {
+			return "", 0, err
+		}
+		return s.location(resource, id), http.StatusOK, nil
+	case http.MethodDelete:
+		if resource == "Users" {
+			err = s.DeleteUser(ctx, orgID, id)
+		} else {
+			err = s.DeleteGroup(ctx, orgID, id)
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		return "", http.StatusNoContent, nil
+	default:
+		return "", 0, scim.ErrInvalidValue("unsupported bulk method " + op.Method)
+	}
+}
+
+// resolveBulkIDs replaces the bulkId:<id> references with the ids of the resources created in the same request
+func resolveBulkIDs(s string, created map[string]string) (string, error) {
+	var unresolved string
+	resolved := bulkIDRef.ReplaceAllStringFunc(s, func(ref string) string {
+		bulkID := strings.TrimPrefix(ref, "bulkId:")
+		id, ok := created[bulkID]
+		if !ok || id == "" {
+			unresolved = bulkID
+			return ref
+		}
+		return id
+	})
+	if unresolved != "" {
+		return "", scim.ErrInvalidValue("unresolved bulkId reference " + unresolved)
+	}
+	return resolved, nil
+}
+
+func decodeBulkData(data string, v any) error {
+	if data == "" {
+		return scim.ErrInvalidSyntax("operation data is required")
+	}
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		var grafanaErr errutil.Error
+		if errors.As(err, &grafanaErr) {
+			return err
+		}
+		return scim.ErrInvalidSyntax(err.Error())
+	}
+	return nil
+}
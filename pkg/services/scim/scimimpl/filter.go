@@ -0,0 +1,543 @@
+package scimimpl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/grafana/grafana/pkg/services/scim"
+)
+
+// filter is a parsed SCIM filter, see https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2.2
+// Filters are evaluated on resources converted to their JSON representation.
+type filter interface {
+	match(resource map[string]any) bool
+}
+
+type logicalFilter struct {
+	and         bool
+	left, right filter
+}
+
+func (f *logicalFilter) match(resource map[string]any) bool {
+	if f.and {
+		return f.left.match(resource) && f.right.match(resource)
+	}
+	return f.left.match(resource) || f.right.match(resource)
+}
+
+type notFilter struct {
+	filter filter
+}
+
+func (f *notFilter) match(resource map[string]any) bool {
+	return !f.filter.match(resource)
+}
+
+type attrFilter struct {
+	path  attrPath
+	op    string
+	value any
+}
+
+func (f *attrFilter) match(resource map[string]any) bool {
+	values := f.path.values(resource)
+	switch f.op {
+	case "pr":
+		for _, v := range values {
+			if isPresent(v) {
+				return true
+			}
+		}
+		return false
+	case "ne":
+		return !(&attrFilter{path: f.path, op: "eq", value: f.value}).match(resource)
+	}
+
+	if f.value == nil {
+		// only "eq null" is meaningful, it matches absent attributes
+		return f.op == "eq" && len(values) == 0
+	}
+
+	for _, v := range values {
+		if compare(v, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// valuePathFilter matches resources having at least one value of a multi-valued attribute matching the filter,
+// for example emails[type eq "work" and value co "@example.com"]
+type valuePathFilter struct {
+	attr   string
+	filter filter
+}
+
+func (f *valuePathFilter) match(resource map[string]any) bool {
+	for _, elem := range elements(resource, f.attr) {
+		if f.filter.match(elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// attrPath references an attribute and optionally one of its sub-attributes
+type attrPath struct {
+	attr    string
+	subAttr string
+}
+
+func parseAttrPath(s string) (attrPath, error) {
+	// attributes can be prefixed with the URN of their schema
+	if strings.HasPrefix(strings.ToLower(s), "urn:") {
+		s = s[strings.LastIndex(s, ":")+1:]
+	}
+
+	attr, sub, _ := strings.Cut(s, ".")
+	if !isAttrName(attr) || (sub != "" && !isAttrName(sub)) {
+		return attrPath{}, fmt.Errorf("invalid attribute %q", s)
+	}
+	return attrPath{attr: attr, subAttr: sub}, nil
+}
+
+// values returns the values of the attribute, values of multi-valued attributes are returned individually.
+// Multi-valued complex attributes without sub-attribute are compared on their "value" sub-attribute.
+func (p attrPath) values(resource map[string]any) []any {
+	v, ok := lookup(resource, p.attr)
+	if !ok || v == nil {
+		return nil
+	}
+
+	sub := p.subAttr
+	var result []any
+	appendValue := func(v any) {
+		m, isMap := v.(map[string]any)
+		switch {
+		case sub != "" && isMap:
+			if sv, ok := lookup(m, sub); ok && sv != nil {
+				result = append(result, sv)
+			}
+		case sub == "" && isMap:
+			if sv, ok := lookup(m, "value"); ok && sv != nil {
+				result = append(result, sv)
+			}
+		case sub == "":
+			result = append(result, v)
+		}
+	}
+
+	if arr, ok := v.([]any); ok {
+		for _, elem := range arr {
+			appendValue(elem)
+		}
+		return result
+	}
+	if m, ok := v.(map[string]any); ok && sub == "" {
+		// single-valued complex attributes are present, but can't be compared
+		return []any{m}
+	}
+	appendValue(v)
+	return result
+}
+
+func isAttrName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r == '$' && i == 0 {
+			continue
+		}
+		if !unicode.IsLetter(r) && !(i > 0 && (unicode.IsDigit(r) || r == '_' || r == '-')) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the value of an attribute, attribute names are case insensitive
+func lookup(m map[string]any, name string) (any, bool) {
+	if v, ok := m[name]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// lookupKey returns the key of an attribute in the map, or the name when the attribute is absent
+func lookupKey(m map[string]any, name string) string {
+	if _, ok := m[name]; ok {
+		return name
+	}
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
+
+func elements(resource map[string]any, attr string) []map[string]any {
+	v, _ := lookup(resource, attr)
+	arr, _ := v.([]any)
+	result := make([]map[string]any, 0, len(arr))
+	for _, elem := range arr {
+		if m, ok := elem.(map[string]any); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+func isPresent(v any) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case string:
+		return t != ""
+	case []any:
+		return len(t) > 0
+	case map[string]any:
+		return len(t) > 0
+	}
+	return true
+}
+
+func compare(v any, op string, expected any) bool {
+	switch e := expected.(type) {
+	case string:
+		s, ok := v.(string)
+		if !ok {
+			return false
+		}
+		s, e = strings.ToLower(s), strings.ToLower(e)
+		switch op {
+		case "eq":
+			return s == e
+		case "co":
+			return strings.Contains(s, e)
+		case "sw":
+			return strings.HasPrefix(s, e)
+		case "ew":
+			return strings.HasSuffix(s, e)
+		case "gt":
+			return s > e
+		case "ge":
+			return s >= e
+		case "lt":
+			return s < e
+		case "le":
+			return s <= e
+		}
+	case bool:
+		b, ok := v.(bool)
+		return ok && op == "eq" && b == e
+	case float64:
+		n, ok := v.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return n == e
+		case "gt":
+			return n > e
+		case "ge":
+			return n >= e
+		case "lt":
+			return n < e
+		case "le":
+			return n <= e
+		}
+	}
+	return false
+}
+
+var compareOps = map[string]bool{"eq": true, "ne": true, "co": true, "sw": true, "ew": true, "gt": true, "ge": true, "lt": true, "le": true}
+
+// parseFilter parses a SCIM filter expression
+func parseFilter(s string) (filter, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, scim.ErrInvalidFilter(err.Error())
+	}
+
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, scim.ErrInvalidFilter(err.Error())
+	}
+	if !p.done() {
+		return nil, scim.ErrInvalidFilter(fmt.Sprintf("unexpected %q", p.peek().text))
+	}
+	return f, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOpenParen
+	tokenCloseParen
+	tokenOpenBracket
+	tokenCloseBracket
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpenParen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenCloseParen, text: ")"})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokenOpenBracket, text: "["})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokenCloseBracket, text: "]"})
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(s); end++ {
+				if s[end] == '\\' {
+					end++
+					continue
+				}
+				if s[end] == '"' {
+					break
+				}
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			var value string
+			if err := json.Unmarshal([]byte(s[i:end+1]), &value); err != nil {
+				return nil, fmt.Errorf("invalid string %s", s[i:end+1])
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value})
+			i = end + 1
+		default:
+			end := i
+			for end < len(s) && !strings.ContainsRune(" \t\n()[]\"", rune(s[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: s[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *filterParser) peek() token {
+	if p.done() {
+		return token{kind: tokenWord}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() (token, error) {
+	if p.done() {
+		return token{}, fmt.Errorf("unexpected end of filter")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *filterParser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func (p *filterParser) expect(kind tokenKind, text string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.kind != kind {
+		return fmt.Errorf("expected %q, got %q", text, t.text)
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filter, error) {
+	if p.isKeyword("not") {
+		p.pos++
+		if err := p.expect(tokenOpenParen, "("); err != nil {
+			return nil, err
+		}
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseParen, ")"); err != nil {
+			return nil, err
+		}
+		return &notFilter{filter: f}, nil
+	}
+
+	if p.peek().kind == tokenOpenParen && !p.done() {
+		p.pos++
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseParen, ")"); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+
+	return p.parseAttrExpression()
+}
+
+func (p *filterParser) parseAttrExpression() (filter, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if t.kind != tokenWord {
+		return nil, fmt.Errorf("expected attribute, got %q", t.text)
+	}
+	path, err := parseAttrPath(t.text)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind == tokenOpenBracket && !p.done() {
+		if path.subAttr != "" {
+			return nil, fmt.Errorf("invalid attribute %q", t.text)
+		}
+		p.pos++
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseBracket, "]"); err != nil {
+			return nil, err
+		}
+		return &valuePathFilter{attr: path.attr, filter: f}, nil
+	}
+
+	opToken, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	op := strings.ToLower(opToken.text)
+	if opToken.kind != tokenWord || (op != "pr" && !compareOps[op]) {
+		return nil, fmt.Errorf("invalid operator %q", opToken.text)
+	}
+	if op == "pr" {
+		return &attrFilter{path: path, op: op}, nil
+	}
+
+	valueToken, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	value, err := parseCompValue(valueToken)
+	if err != nil {
+		return nil, err
+	}
+	if _, isBool := value.(bool); isBool && op != "eq" && op != "ne" {
+		return nil, fmt.Errorf("operator %q can't be used with booleans", op)
+	}
+	return &attrFilter{path: path, op: op, value: value}, nil
+}
+
+func parseCompValue(t token) (any, error) {
+	if t.kind == tokenString {
+		return t.text, nil
+	}
+	if t.kind != tokenWord {
+		return nil, fmt.Errorf("expected value, got %q", t.text)
+	}
+
+	switch strings.ToLower(t.text) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q", t.text)
+	}
+	return n, nil
+}
+
+// toMap returns the JSON representation of a resource filters and patches are applied to
+func toMap(resource any) (map[string]any, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,87 @@
+package scimimpl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/scim"
+)
+
+func TestParseFilter(t *testing.T) {
+	active := true
+	u := &scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          "2",
+		ExternalID:  "00u1abcd",
+		UserName:    "Alice",
+		DisplayName: "Alice Smith",
+		Name:        &scim.Name{GivenName: "Alice", FamilyName: "Smith"},
+		Emails:      []scim.Email{{Value: "alice@example.com", Type: "work", Primary: true}},
+		Active:      (*scim.Bool)(&active),
+		Meta:        &scim.Meta{ResourceType: scim.ResourceTypeUser},
+	}
+	resource, err := toMap(u)
+	require.NoError(t, err)
+
+	tests := []struct {
+		filter string
+		match  bool
+	}{
+		{filter: `userName eq "alice"`, match: true},
+		{filter: `USERNAME Eq "ALICE"`, match: true},
+		{filter: `userName eq "bob"`, match: false},
+		{filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName eq "alice"`, match: true},
+		{filter: `userName ne "bob"`, match: true},
+		{filter: `userName sw "al"`, match: true},
+		{filter: `userName ew "ce"`, match: true},
+		{filter: `displayName co "smi"`, match: true},
+		{filter: `externalId pr`, match: true},
+		{filter: `nickName pr`, match: false},
+		{filter: `nickName eq null`, match: true},
+		{filter: `name.familyName eq "Smith"`, match: true},
+		{filter: `name.givenName gt "Aaron"`, match: true},
+		{filter: `name.givenName lt "Aaron"`, match: false},
+		{filter: `active eq true`, match: true},
+		{filter: `active eq false`, match: false},
+		{filter: `emails.value eq "alice@example.com"`, match: true},
+		{filter: `emails[type eq "work" and value co "@example.com"]`, match: true},
+		{filter: `emails[type eq "home"]`, match: false},
+		{filter: `meta.resourceType eq "User"`, match: true},
+		{filter: `userName eq "bob" or externalId eq "00u1abcd"`, match: true},
+		{filter: `userName eq "alice" and not (active eq false)`, match: true},
+		{filter: `(userName eq "bob" or userName eq "carol") and active eq true`, match: false},
+		{filter: `userName eq "a\"b"`, match: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := parseFilter(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.match, f.match(resource))
+		})
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	filters := []string{
+		`userName`,
+		`userName eq`,
+		`userName foo "alice"`,
+		`userName eq "alice`,
+		`(userName eq "alice"`,
+		`userName eq "alice" and`,
+		`emails[type eq "work"`,
+		`active gt true`,
+		`userName eq alice`,
+	}
+
+	for _, filter := range filters {
+		t.Run(filter, func(t *testing.T) {
+			_, err := parseFilter(filter)
+			require.Error(t, err)
+			assert.Equal(t, scim.ScimTypeInvalidFilter, scim.NewErrorResponse(err).ScimType)
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package scimimpl
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/scim"
+	"github.com/grafana/grafana/pkg/services/team"
+)
+
+// memberPermission is the permission of the team members added through SCIM
+const memberPermission = "Member"
+
+func (s *Service) ListGroups(ctx context.Context, orgID int64, query *scim.ListQuery) (*scim.ListResponse, error) {
+	res, err := s.teamService.SearchTeams(ctx, &team.SearchTeamsQuery{OrgID: orgID, SignedInUser: requester(orgID)})
+	if err != nil {
+		return nil, err
+	}
+
+	externalIDs, err := s.store.GetExternalIDs(ctx, orgID, scim.ResourceTypeGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	// members are only needed when they are returned or filtered on
+	withMembers := !excludes(query.ExcludedAttributes, "members") || strings.Contains(strings.ToLower(query.Filter), "members")
+
+	groups := make([]*scim.Group, 0, len(res.Teams))
+	for _, t := range res.Teams {
+		var members []*team.TeamMemberDTO
+		if withMembers {
+			if members, err = s.getTeamMembers(ctx, orgID, t.ID); err != nil {
+				return nil, err
+			}
+		}
+		groups = append(groups, s.toSCIMGroup(t, members, externalIDs[t.ID]))
+	}
+
+	result, err := listResources(groups, query)
+	if err != nil {
+		return nil, err
+	}
+	if excludes(query.ExcludedAttributes, "members") {
+		for _, r := range result.Resources {
+			r.(*scim.Group).Members = nil
+		}
+	}
+	return result, nil
+}
+
+func (s *Service) GetGroup(ctx context.Context, orgID int64, id string) (*scim.Group, error) {
+	t, err := s.getTeam(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.getTeamMembers(ctx, orgID, t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	externalID, err := s.store.GetExternalID(ctx, orgID, scim.ResourceTypeGroup, t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toSCIMGroup(t, members, externalID), nil
+}
+
+func (s *Service) CreateGroup(ctx context.Context, orgID int64, in *scim.Group) (*scim.Group, error) {
+	if err := validateGroup(in); err != nil {
+		return nil, err
+	}
+
+	wanted, err := s.memberIDs(ctx, orgID, in.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := s.teamService.CreateTeam(ctx, strings.TrimSpace(in.DisplayName), "", orgID)
+	if err != nil {
+		if errors.Is(err, team.ErrTeamNameTaken) {
+			return nil, scim.ErrGroupExists.Errorf("team %q already exists in organization %d", in.DisplayName, orgID)
+		}
+		return nil, err
+	}
+
+	if err := s.setMembers(ctx, orgID, t.ID, nil, wanted); err != nil {
+		return nil, err
+	}
+	if err := s.store.SetExternalID(ctx, orgID, scim.ResourceTypeGroup, t.ID, in.ExternalID); err != nil {
+		return nil, err
+	}
+
+	s.log.FromContext(ctx).Info("Provisioned team", "orgId", orgID, "teamId", t.ID, "name", t.Name, "members", len(wanted))
+	return s.GetGroup(ctx, orgID, strconv.FormatInt(t.ID, 10))
+}
+
+func (s *Service) ReplaceGroup(ctx context.Context, orgID int64, id string, in *scim.Group) (*scim.Group, error) {
+	if err := validateGroup(in); err != nil {
+		return nil, err
+	}
+
+	t, err := s.getTeam(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted, err := s.memberIDs(ctx, orgID, in.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(in.DisplayName); name != t.Name {
+		if err := s.teamService.UpdateTeam(ctx, &team.UpdateTeamCommand{ID: t.ID, OrgID: orgID, Name: name, Email: t.Email}); err != nil {
+			if errors.Is(err, team.ErrTeamNameTaken) {
+				return nil, scim.ErrGroupExists.Errorf("team %q already exists in organization %d", name, orgID)
+			}
+			return nil, err
+		}
+		s.log.FromContext(ctx).Info("Renamed team", "orgId", orgID, "teamId", t.ID, "from", t.Name, "to", name)
+	}
+
+	members, err := s.getTeamMembers(ctx, orgID, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	current := make([]int64, 0, len(members))
+	for _, m := range members {
+		current = append(current, m.UserID)
+	}
+
+	if err := s.setMembers(ctx, orgID, t.ID, current, wanted); err != nil {
+		return nil, err
+	}
+	if err := s.store.SetExternalID(ctx, orgID, scim.ResourceTypeGroup, t.ID, in.ExternalID); err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(ctx, orgID, id)
+}
+
+func (s *Service) PatchGroup(ctx context.Context, orgID int64, id string, patch *scim.PatchRequest) (*scim.Group, error) {
+	current, err := s.GetGroup(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resource, err := toMap(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPatch(resource, patch.Operations); err != nil {
+		return nil, err
+	}
+
+	var patched scim.Group
+	if err := fromMap(resource, &patched); err != nil {
+		return nil, err
+	}
+	return s.ReplaceGroup(ctx, orgID, id, &patched)
+}
+
+func (s *Service) DeleteGroup(ctx context.Context, orgID int64, id string) error {
+	t, err := s.getTeam(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.teamService.DeleteTeam(ctx, &team.DeleteTeamCommand{OrgID: orgID, ID: t.ID}); err != nil {
+		return err
+	}
+	if err := s.accessControlService.DeleteTeamPermissions(ctx, orgID, t.ID); err != nil {
+		return err
+	}
+
+	s.log.FromContext(ctx).Info("Deprovisioned team", "orgId", orgID, "teamId", t.ID, "name", t.Name)
+	return nil
+}
+
+// setMembers adds and removes team members so that the members of the team are the wanted users
+func (s *Service) setMembers(ctx context.Context, orgID, teamID int64, current, wanted []int64) error {
+	isWanted := make(map[int64]bool, len(wanted))
+	for _, id := range wanted {
+		isWanted[id] = true
+	}
+	isCurrent := make(map[int64]bool, len(current))
+	for _, id := range current {
+		isCurrent[id] = true
+	}
+
+	teamIDStr := strconv.FormatInt(teamID, 10)
+	for _, id := range current {
+		if isWanted[id] {
+			continue
+		}
+		if _, err := s.teamPermissionsService.SetUserPermission(ctx, orgID, accesscontrol.User{ID: id}, teamIDStr, ""); err != nil {
+			return err
+		}
+		s.log.FromContext(ctx).Info("Removed user from team", "orgId", orgID, "teamId", teamID, "userId", id)
+	}
+
+	for _, id := range wanted {
+		if isCurrent[id] {
+			continue
+		}
+		if _, err := s.teamPermissionsService.SetUserPermission(ctx, orgID, accesscontrol.User{ID: id}, teamIDStr, memberPermission); err != nil {
+			return err
+		}
+		s.log.FromContext(ctx).Info("Added user to team", "orgId", orgID, "teamId", teamID, "userId", id)
+	}
+	return nil
+}
+
+// memberIDs returns the IDs of the members, which must be users of the organization
+func (s *Service) memberIDs(ctx context.Context, orgID int64, members []scim.Member) ([]int64, error) {
+	ids := make([]int64, 0, len(members))
+	seen := make(map[int64]bool, len(members))
+	for _, m := range members {
+		u, err := s.getOrgUser(ctx, orgID, m.Value)
+		if err != nil {
+			if errors.Is(err, scim.ErrUserNotFound) {
+				return nil, scim.ErrInvalidValue("member " + m.Value + " is not a user of the organization")
+			}
+			return nil, err
+		}
+		if !seen[u.ID] {
+			seen[u.ID] = true
+			ids = append(ids, u.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (s *Service) getTeam(ctx context.Context, orgID int64, id string) (*team.TeamDTO, error) {
+	teamID, ok := parseID(id)
+	if !ok {
+		return nil, scim.ErrGroupNotFound.Errorf("invalid group id %q", id)
+	}
+
+	t, err := s.teamService.GetTeamByID(ctx, &team.GetTeamByIDQuery{OrgID: orgID, ID: teamID, SignedInUser: requester(orgID)})
+	if err != nil {
+		if errors.Is(err, team.ErrTeamNotFound) {
+			return nil, scim.ErrGroupNotFound.Errorf("team %d not found in organization %d", teamID, orgID)
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *Service) getTeamMembers(ctx context.Context, orgID, teamID int64) ([]*team.TeamMemberDTO, error) {
+	return s.teamService.GetTeamMembers(ctx, &team.GetTeamMembersQuery{OrgID: orgID, TeamID: teamID, SignedInUser: requester(orgID)})
+}
+
+func (s *Service) toSCIMGroup(t *team.TeamDTO, members []*team.TeamMemberDTO, externalID string) *scim.Group {
+	id := strconv.FormatInt(t.ID, 10)
+	result := &scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          id,
+		ExternalID:  externalID,
+		DisplayName: t.Name,
+		Meta: &scim.Meta{
+			ResourceType: scim.ResourceTypeGroup,
+			Location:     s.location("Groups", id),
+		},
+	}
+	for _, m := range members {
+		userID := strconv.FormatInt(m.UserID, 10)
+		result.Members = append(result.Members, scim.Member{
+			Value:   userID,
+			Display: m.Login,
+			Ref:     s.location("Users", userID),
+		})
+	}
+	return result
+}
+
+func validateGroup(g *scim.Group) error {
+	if strings.TrimSpace(g.DisplayName) == "" {
+		return scim.ErrInvalidValue("displayName is required")
+	}
+	return nil
+}
+
+func excludes(attributes []string, attr string) bool {
+	for _, a := range attributes {
+		if strings.EqualFold(strings.TrimSpace(a), attr) {
+			return true
+		}
+	}
+	return false
+}
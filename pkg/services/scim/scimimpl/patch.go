@@ -0,0 +1,304 @@
+package scimimpl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+	"github.com/grafana/grafana/pkg/services/scim"
+)
+
+// patchPath is the target of a PATCH operation, for example members, name.givenName or emails[type eq "work"].value
+// see https://datatracker.ietf.org/doc/html/rfc7644#section-3.5.2
+type patchPath struct {
+	attr    string
+	filter  filter
+	subAttr string
+}
+
+func parsePatchPath(s string) (patchPath, error) {
+	open := strings.Index(s, "[")
+	if open < 0 {
+		path, err := parseAttrPath(s)
+		if err != nil {
+			return patchPath{}, scim.ErrInvalidPath(err.Error())
+		}
+		return patchPath{attr: path.attr, subAttr: path.subAttr}, nil
+	}
+
+	closing := strings.LastIndex(s, "]")
+	if closing < open {
+		return patchPath{}, scim.ErrInvalidPath(fmt.Sprintf("missing closing bracket in %q", s))
+	}
+
+	path, err := parseAttrPath(s[:open])
+	if err != nil || path.subAttr != "" {
+		return patchPath{}, scim.ErrInvalidPath(fmt.Sprintf("invalid attribute in %q", s))
+	}
+	f, err := parseFilter(s[open+1 : closing])
+	if err != nil {
+		return patchPath{}, scim.ErrInvalidPath(fmt.Sprintf("invalid filter in %q", s))
+	}
+
+	result := patchPath{attr: path.attr, filter: f}
+	if rest := s[closing+1:]; rest != "" {
+		if !strings.HasPrefix(rest, ".") || !isAttrName(rest[1:]) {
+			return patchPath{}, scim.ErrInvalidPath(fmt.Sprintf("invalid sub-attribute in %q", s))
+		}
+		result.subAttr = rest[1:]
+	}
+	return result, nil
+}
+
+// applyPatch applies PATCH operations to the JSON representation of a resource
+func applyPatch(resource map[string]any, ops []scim.PatchOperation) error {
+	for _, op := range ops {
+		var value any
+		if len(op.Value) > 0 {
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return scim.ErrInvalidValue(fmt.Sprintf("invalid value for %q", op.Path))
+			}
+		}
+
+		kind := strings.ToLower(op.Op)
+		if kind != "add" && kind != "replace" && kind != "remove" {
+			return scim.ErrInvalidSyntax(fmt.Sprintf("unknown operation %q", op.Op))
+		}
+
+		if op.Path == "" {
+			if kind == "remove" {
+				return scim.ErrNoTarget.Errorf("remove operation without path")
+			}
+			// without path the value is an object of the attributes to add or replace
+			attrs, ok := value.(map[string]any)
+			if !ok {
+				return scim.ErrInvalidValue("operations without path expect an object")
+			}
+			for name, v := range attrs {
+				path, err := parsePatchPath(name)
+				if err != nil {
+					return err
+				}
+				if err := applyOperation(resource, kind, path, v); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		path, err := parsePatchPath(op.Path)
+		if err != nil {
+			return err
+		}
+		if err := applyOperation(resource, kind, path, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyOperation(resource map[string]any, kind string, path patchPath, value any) error {
+	key := lookupKey(resource, path.attr)
+
+	if path.filter != nil {
+		return applyFilteredOperation(resource, key, kind, path, value)
+	}
+
+	if path.subAttr != "" {
+		current, _ := resource[key].(map[string]any)
+		if kind == "remove" {
+			if current != nil {
+				delete(current, lookupKey(current, path.subAttr))
+			}
+			return nil
+		}
+		if current == nil {
+			if _, isArray := resource[key].([]any); isArray {
+				return scim.ErrInvalidPath(fmt.Sprintf("%s is multi-valued", path.attr))
+			}
+			current = map[string]any{}
+			resource[key] = current
+		}
+		current[lookupKey(current, path.subAttr)] = value
+		return nil
+	}
+
+	switch kind {
+	case "remove":
+		// values identify the elements of multi-valued attributes to remove, e.g. members
+		if values, ok := value.([]any); ok {
+			if current, isArray := resource[key].([]any); isArray {
+				resource[key] = removeValues(current, values)
+				return nil
+			}
+		}
+		delete(resource, key)
+	case "add":
+		current, currentIsArray := resource[key].([]any)
+		if values, ok := value.([]any); ok || currentIsArray {
+			if !ok {
+				values = []any{value}
+			}
+			resource[key] = appendValues(current, values)
+			return nil
+		}
+		resource[key] = mergeValue(resource[key], value)
+	case "replace":
+		if _, ok := value.([]any); ok {
+			resource[key] = value
+			return nil
+		}
+		resource[key] = mergeValue(resource[key], value)
+	}
+	return nil
+}
+
+func applyFilteredOperation(resource map[string]any, key, kind string, path patchPath, value any) error {
+	current, _ := resource[key].([]any)
+
+	matched := false
+	kept := make([]any, 0, len(current))
+	for _, elem := range current {
+		m, ok := elem.(map[string]any)
+		if !ok || !path.filter.match(m) {
+			kept = append(kept, elem)
+			continue
+		}
+		matched = true
+
+		switch {
+		case kind == "remove" && path.subAttr == "":
+			continue
+		case kind == "remove":
+			delete(m, lookupKey(m, path.subAttr))
+		case path.subAttr != "":
+			m[lookupKey(m, path.subAttr)] = value
+		default:
+			if v, ok := mergeValue(m, value).(map[string]any); ok {
+				m = v
+			}
+		}
+		kept = append(kept, m)
+	}
+
+	if !matched {
+		if kind == "remove" {
+			return nil
+		}
+		// some identity providers set values that don't exist yet with a filter, e.g. emails[type eq "work"].value
+		elem, ok := seedFromFilter(path.filter)
+		if !ok {
+			return scim.ErrNoTarget.Errorf("no value of %s matches the filter", path.attr)
+		}
+		if path.subAttr != "" {
+			elem[path.subAttr] = value
+		} else if v, ok := value.(map[string]any); ok {
+			for k, sv := range v {
+				elem[k] = sv
+			}
+		}
+		kept = append(kept, elem)
+	}
+
+	resource[key] = kept
+	return nil
+}
+
+// seedFromFilter builds the element matching a filter made of equality expressions
+func seedFromFilter(f filter) (map[string]any, bool) {
+	switch t := f.(type) {
+	case *attrFilter:
+		if t.op != "eq" || t.path.subAttr != "" || t.value == nil {
+			return nil, false
+		}
+		return map[string]any{t.path.attr: t.value}, true
+	case *logicalFilter:
+		if !t.and {
+			return nil, false
+		}
+		left, ok := seedFromFilter(t.left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := seedFromFilter(t.right)
+		if !ok {
+			return nil, false
+		}
+		for k, v := range right {
+			left[k] = v
+		}
+		return left, true
+	}
+	return nil, false
+}
+
+// mergeValue merges objects into complex attributes, other values replace the current value
+func mergeValue(current, value any) any {
+	currentMap, ok := current.(map[string]any)
+	valueMap, isMap := value.(map[string]any)
+	if !ok || !isMap {
+		return value
+	}
+	for k, v := range valueMap {
+		currentMap[lookupKey(currentMap, k)] = v
+	}
+	return currentMap
+}
+
+func appendValues(current, values []any) []any {
+	for _, v := range values {
+		if !containsValue(current, v) {
+			current = append(current, v)
+		}
+	}
+	return current
+}
+
+func removeValues(current, values []any) []any {
+	result := make([]any, 0, len(current))
+	for _, elem := range current {
+		if !containsValue(values, elem) {
+			result = append(result, elem)
+		}
+	}
+	return result
+}
+
+// containsValue compares elements of multi-valued attributes on their "value" sub-attribute when they have one
+func containsValue(values []any, v any) bool {
+	key := elementValue(v)
+	for _, elem := range values {
+		if elementValue(elem) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func elementValue(v any) string {
+	if m, ok := v.(map[string]any); ok {
+		if value, ok := lookup(m, "value"); ok {
+			v = value
+		}
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// fromMap converts the JSON representation of a patched resource back to the resource
+func fromMap(resource map[string]any, v any) error {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		var grafanaErr errutil.Error
+		if errors.As(err, &grafanaErr) {
+			return err
+		}
+		return scim.ErrInvalidValue(err.Error())
+	}
+	return nil
+}
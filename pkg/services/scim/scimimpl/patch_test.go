@@ -0,0 +1,176 @@
+package scimimpl
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/scim"
+)
+
+func TestApplyPatch(t *testing.T) {
+	newUser := func() map[string]any {
+		active := true
+		m, err := toMap(&scim.User{
+			Schemas:     []string{scim.SchemaUser},
+			ID:          "2",
+			UserName:    "alice",
+			DisplayName: "Alice Smith",
+			Name:        &scim.Name{GivenName: "Alice", FamilyName: "Smith"},
+			Emails:      []scim.Email{{Value: "alice@example.com", Type: "work", Primary: true}},
+			Active:      (*scim.Bool)(&active),
+		})
+		require.NoError(t, err)
+		return m
+	}
+
+	t.Run("replace attribute", func(t *testing.T) {
+		u := applyUserPatch(t, newUser(), `[{"op": "replace", "path": "active", "value": false}]`)
+		assert.False(t, u.IsActive())
+	})
+
+	t.Run("replace attribute with string boolean", func(t *testing.T) {
+		u := applyUserPatch(t, newUser(), `[{"op": "Replace", "path": "active", "value": "False"}]`)
+		assert.False(t, u.IsActive())
+	})
+
+	t.Run("replace without path", func(t *testing.T) {
+		u := applyUserPatch(t, newUser(), `[{"op": "replace", "value": {"displayName": "Alice Jones", "name.familyName": "Jones"}}]`)
+		assert.Equal(t, "Alice Jones", u.DisplayName)
+		assert.Equal(t, "Jones", u.Name.FamilyName)
+		assert.Equal(t, "Alice", u.Name.GivenName)
+	})
+
+	t.Run("replace sub-attribute", func(t *testing.T) {
+		u := applyUserPatch(t, newUser(), `[{"op": "replace", "path": "name.givenName", "value": "Alicia"}]`)
+		assert.Equal(t, "Alicia", u.Name.GivenName)
+		assert.Equal(t, "Smith", u.Name.FamilyName)
+	})
+
+	t.Run("replace filtered value", func(t *testing.T) {
+		u := applyUserPatch(t, newUser(), `[{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "alice@corp.example.com"}]`)
+		assert.Equal(t, "alice@corp.example.com", u.PrimaryEmail())
+		assert.Len(t, u.Emails, 1)
+	})
+
+	t.Run("add filtered value without match", func(t *testing.T) {
+		resource := newUser()
+		delete(resource, "emails")
+		u := applyUserPatch(t, resource, `[{"op": "add", "path": "emails[type eq \"work\"].value", "value": "alice@example.org"}]`)
+		require.Len(t, u.Emails, 1)
+		assert.Equal(t, "work", u.Emails[0].Type)
+		assert.Equal(t, "alice@example.org", u.Emails[0].Value)
+	})
+
+	t.Run("add to multi-valued attribute", func(t *testing.T) {
+		u := applyUserPatch(t, newUser(), `[{"op": "add", "path": "emails", "value": [{"value": "alice@home.example", "type": "home"}, {"value": "alice@example.com", "type": "work"}]}]`)
+		assert.Len(t, u.Emails, 2)
+	})
+
+	t.Run("remove attribute", func(t *testing.T) {
+		u := applyUserPatch(t, newUser(), `[{"op": "remove", "path": "name"}]`)
+		assert.Nil(t, u.Name)
+	})
+
+	t.Run("remove without path", func(t *testing.T) {
+		err := applyPatch(newUser(), patchOperations(t, `[{"op": "remove"}]`))
+		require.Error(t, err)
+		assert.Equal(t, scim.ScimTypeNoTarget, scim.NewErrorResponse(err).ScimType)
+	})
+
+	t.Run("unknown operation", func(t *testing.T) {
+		err := applyPatch(newUser(), patchOperations(t, `[{"op": "move", "path": "name"}]`))
+		require.Error(t, err)
+		assert.Equal(t, scim.ScimTypeInvalidSyntax, scim.NewErrorResponse(err).ScimType)
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		err := applyPatch(newUser(), patchOperations(t, `[{"op": "replace", "path": "emails[type eq", "value": "x"}]`))
+		require.Error(t, err)
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		resource := newUser()
+		require.NoError(t, applyPatch(resource, patchOperations(t, `[{"op": "replace", "path": "active", "value": "maybe"}]`)))
+		err := fromMap(resource, &scim.User{})
+		require.Error(t, err)
+		assert.Equal(t, scim.ScimTypeInvalidValue, scim.NewErrorResponse(err).ScimType)
+	})
+}
+
+func TestApplyPatch_Members(t *testing.T) {
+	newGroup := func() map[string]any {
+		m, err := toMap(&scim.Group{
+			Schemas:     []string{scim.SchemaGroup},
+			ID:          "1",
+			DisplayName: "Engineering",
+			Members:     []scim.Member{{Value: "2"}, {Value: "3"}},
+		})
+		require.NoError(t, err)
+		return m
+	}
+
+	tests := []struct {
+		name     string
+		ops      string
+		expected []string
+	}{
+		{
+			name:     "add members",
+			ops:      `[{"op": "add", "path": "members", "value": [{"value": "3"}, {"value": "4"}]}]`,
+			expected: []string{"2", "3", "4"},
+		},
+		{
+			name:     "remove members by value",
+			ops:      `[{"op": "remove", "path": "members", "value": [{"value": "2"}]}]`,
+			expected: []string{"3"},
+		},
+		{
+			name:     "remove members by filter",
+			ops:      `[{"op": "remove", "path": "members[value eq \"3\"]"}]`,
+			expected: []string{"2"},
+		},
+		{
+			name:     "replace members",
+			ops:      `[{"op": "replace", "path": "members", "value": [{"value": "5"}]}]`,
+			expected: []string{"5"},
+		},
+		{
+			name:     "remove all members",
+			ops:      `[{"op": "remove", "path": "members"}]`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := newGroup()
+			require.NoError(t, applyPatch(resource, patchOperations(t, tt.ops)))
+
+			var g scim.Group
+			require.NoError(t, fromMap(resource, &g))
+			var members []string
+			for _, m := range g.Members {
+				members = append(members, m.Value)
+			}
+			assert.Equal(t, tt.expected, members)
+		})
+	}
+}
+
+func patchOperations(t *testing.T, ops string) []scim.PatchOperation {
+	t.Helper()
+	var operations []scim.PatchOperation
+	require.NoError(t, json.Unmarshal([]byte(ops), &operations))
+	return operations
+}
+
+func applyUserPatch(t *testing.T, resource map[string]any, ops string) *scim.User {
+	t.Helper()
+	require.NoError(t, applyPatch(resource, patchOperations(t, ops)))
+	var u scim.User
+	require.NoError(t, fromMap(resource, &u))
+	return &u
+}
@@ -0,0 +1,602 @@
+package scimimpl
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/actest"
+	"github.com/grafana/grafana/pkg/services/auth/authtest"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgtest"
+	"github.com/grafana/grafana/pkg/services/scim"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/team/teamtest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const testOrgID = 1
+
+func TestService_CreateUser(t *testing.T) {
+	t.Run("should create and add user to the organization", func(t *testing.T) {
+		s, env := setupTestService(t)
+
+		u, err := s.CreateUser(context.Background(), testOrgID, &scim.User{
+			UserName:   "alice",
+			ExternalID: "00u1",
+			Name:       &scim.Name{GivenName: "Alice", FamilyName: "Smith"},
+			Emails:     []scim.Email{{Value: "alice@example.com", Primary: true}},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "alice", u.UserName)
+		assert.Equal(t, "Alice Smith", u.DisplayName)
+		assert.Equal(t, "alice@example.com", u.PrimaryEmail())
+		assert.Equal(t, "00u1", u.ExternalID)
+		assert.True(t, u.IsActive())
+		assert.Equal(t, "https://grafana.example.com/api/scim/v2/Users/"+u.ID, u.Meta.Location)
+		assert.Equal(t, org.RoleEditor, env.orgs.roles[orgUser{testOrgID, env.users.byLogin("alice").ID}])
+	})
+
+	t.Run("should add existing user without organization and keep their email and password", func(t *testing.T) {
+		s, env := setupTestService(t)
+		existing := env.users.add(&user.User{Login: "alice", Email: "alice@example.com", Password: "hashed"})
+
+		u, err := s.CreateUser(context.Background(), testOrgID, &scim.User{
+			UserName:    "alice",
+			DisplayName: "Alice",
+			Emails:      []scim.Email{{Value: "attacker@example.com", Primary: true}},
+			Password:    "new-password",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, strconv.FormatInt(existing.ID, 10), u.ID)
+		assert.Contains(t, env.orgs.roles, orgUser{testOrgID, existing.ID})
+		assert.Equal(t, "alice@example.com", env.users.users[existing.ID].Email)
+		assert.Equal(t, user.Password("hashed"), env.users.users[existing.ID].Password)
+	})
+
+	t.Run("should fail when existing user belongs to another organization", func(t *testing.T) {
+		s, env := setupTestService(t)
+		existing := env.users.add(&user.User{Login: "alice", Email: "alice@example.com"})
+		env.orgs.roles[orgUser{2, existing.ID}] = org.RoleViewer
+
+		_, err := s.CreateUser(context.Background(), testOrgID, &scim.User{UserName: "alice", DisplayName: "Alice"})
+		require.ErrorIs(t, err, scim.ErrUserNotProvisionable)
+		assert.NotContains(t, env.orgs.roles, orgUser{testOrgID, existing.ID})
+	})
+
+	t.Run("should fail when existing user is a server admin", func(t *testing.T) {
+		s, env := setupTestService(t)
+		existing := env.users.add(&user.User{Login: "admin", Email: "admin@example.com", IsAdmin: true})
+
+		_, err := s.CreateUser(context.Background(), testOrgID, &scim.User{UserName: "admin"})
+		require.ErrorIs(t, err, scim.ErrUserNotProvisionable)
+		assert.NotContains(t, env.orgs.roles, orgUser{testOrgID, existing.ID})
+	})
+
+	t.Run("should fail when user is already a member", func(t *testing.T) {
+		s, env := setupTestService(t)
+		existing := env.users.add(&user.User{Login: "alice", Email: "alice@example.com"})
+		env.orgs.roles[orgUser{testOrgID, existing.ID}] = org.RoleViewer
+
+		_, err := s.CreateUser(context.Background(), testOrgID, &scim.User{UserName: "alice"})
+		require.ErrorIs(t, err, scim.ErrUserExists)
+	})
+
+	t.Run("should fail without user name", func(t *testing.T) {
+		s, _ := setupTestService(t)
+
+		_, err := s.CreateUser(context.Background(), testOrgID, &scim.User{DisplayName: "Alice"})
+		require.Error(t, err)
+		assert.Equal(t, scim.ScimTypeInvalidValue, scim.NewErrorResponse(err).ScimType)
+	})
+}
+
+func TestService_PatchUser(t *testing.T) {
+	t.Run("should deactivate user and revoke sessions", func(t *testing.T) {
+		s, env := setupTestService(t)
+		existing := env.users.add(&user.User{Login: "alice", Email: "alice@example.com", Name: "Alice"})
+		env.orgs.roles[orgUser{testOrgID, existing.ID}] = org.RoleViewer
+
+		u, err := s.PatchUser(context.Background(), testOrgID, strconv.FormatInt(existing.ID, 10), &scim.PatchRequest{
+			Operations: []scim.PatchOperation{{Op: "replace", Path: "active", Value: json.RawMessage(`false`)}},
+		})
+		require.NoError(t, err)
+
+		assert.False(t, u.IsActive())
+		assert.True(t, env.users.users[existing.ID].IsDisabled)
+		assert.Equal(t, []int64{existing.ID}, env.revoked)
+	})
+
+	t.Run("should update display name with name", func(t *testing.T) {
+		s, env := setupTestService(t)
+		existing := env.users.add(&user.User{Login: "alice", Email: "alice@example.com", Name: "Alice Smith"})
+		env.orgs.roles[orgUser{testOrgID, existing.ID}] = org.RoleViewer
+
+		u, err := s.PatchUser(context.Background(), testOrgID, strconv.FormatInt(existing.ID, 10), &scim.PatchRequest{
+			Operations: []scim.PatchOperation{
+				{Op: "replace", Path: "name.givenName", Value: json.RawMessage(`"Alice"`)},
+				{Op: "replace", Path: "name.familyName", Value: json.RawMessage(`"Jones"`)},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "Alice Jones", u.DisplayName)
+		assert.Empty(t, env.revoked)
+	})
+}
+
+func TestService_ReplaceUser(t *testing.T) {
+	t.Run("should not change email and password", func(t *testing.T) {
+		s, env := setupTestService(t)
+		existing := env.users.add(&user.User{Login: "alice", Email: "alice@example.com", Password: "hashed"})
+		env.orgs.roles[orgUser{testOrgID, existing.ID}] = org.RoleViewer
+
+		_, err := s.ReplaceUser(context.Background(), testOrgID, strconv.FormatInt(existing.ID, 10), &scim.User{
+			UserName:    "alice",
+			DisplayName: "Alice Smith",
+			Emails:      []scim.Email{{Value: "attacker@example.com", Primary: true}},
+			Password:    "new-password",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "Alice Smith", env.users.users[existing.ID].Name)
+		assert.Equal(t, "alice@example.com", env.users.users[existing.ID].Email)
+		assert.Equal(t, user.Password("hashed"), env.users.users[existing.ID].Password)
+	})
+
+	t.Run("should fail when user belongs to another organization", func(t *testing.T) {
+		s, env := setupTestService(t)
+		existing := env.users.add(&user.User{Login: "alice", Email: "alice@example.com", Name: "Alice"})
+		env.orgs.roles[orgUser{testOrgID, existing.ID}] = org.RoleViewer
+		env.orgs.roles[orgUser{2, existing.ID}] = org.RoleViewer
+
+		_, err := s.ReplaceUser(context.Background(), testOrgID, strconv.FormatInt(existing.ID, 10), &scim.User{UserName: "alice", DisplayName: "Mallory"})
+		require.ErrorIs(t, err, scim.ErrUserNotProvisionable)
+		assert.Equal(t, "Alice", env.users.users[existing.ID].Name)
+	})
+
+	t.Run("should fail when user is a server admin", func(t *testing.T) {
+		s, env := setupTestService(t)
+		existing := env.users.add(&user.User{Login: "admin", Name: "Admin", IsAdmin: true})
+		env.orgs.roles[orgUser{testOrgID, existing.ID}] = org.RoleAdmin
+
+		_, err := s.PatchUser(context.Background(), testOrgID, strconv.FormatInt(existing.ID, 10), &scim.PatchRequest{
+			Operations: []scim.PatchOperation{{Op: "replace", Path: "active", Value: json.RawMessage(`false`)}},
+		})
+		require.ErrorIs(t, err, scim.ErrUserNotProvisionable)
+		assert.False(t, env.users.users[existing.ID].IsDisabled)
+	})
+}
+
+func TestService_DeleteUser(t *testing.T) {
+	t.Run("should delete user and revoke their sessions", func(t *testing.T) {
+		s, env := setupTestService(t)
+		existing := env.users.add(&user.User{Login: "alice"})
+		env.orgs.roles[orgUser{testOrgID, existing.ID}] = org.RoleViewer
+		require.NoError(t, env.store.SetExternalID(context.Background(), testOrgID, scim.ResourceTypeUser, existing.ID, "00u1"))
+
+		require.NoError(t, s.DeleteUser(context.Background(), testOrgID, strconv.FormatInt(existing.ID, 10)))
+
+		assert.NotContains(t, env.orgs.roles, orgUser{testOrgID, existing.ID})
+		assert.NotContains(t, env.users.users, existing.ID)
+		assert.Equal(t, []int64{existing.ID}, env.revoked)
+		externalID, err := env.store.GetExternalID(context.Background(), testOrgID, scim.ResourceTypeUser, existing.ID)
+		require.NoError(t, err)
+		assert.Empty(t, externalID)
+
+		_, err = s.GetUser(context.Background(), testOrgID, strconv.FormatInt(existing.ID, 10))
+		require.ErrorIs(t, err, scim.ErrUserNotFound)
+	})
+
+	t.Run("should keep sessions of user only removed from the organization", func(t *testing.T) {
+		s, env := setupTestService(t)
+		s.cfg.SCIM.DeleteOrphanedUsers = false
+		existing := env.users.add(&user.User{Login: "alice"})
+		env.orgs.roles[orgUser{testOrgID, existing.ID}] = org.RoleViewer
+
+		require.NoError(t, s.DeleteUser(context.Background(), testOrgID, strconv.FormatInt(existing.ID, 10)))
+
+		assert.NotContains(t, env.orgs.roles, orgUser{testOrgID, existing.ID})
+		assert.Contains(t, env.users.users, existing.ID)
+		assert.Empty(t, env.revoked)
+	})
+
+	t.Run("should not find users of other organizations", func(t *testing.T) {
+		s, env := setupTestService(t)
+		existing := env.users.add(&user.User{Login: "alice"})
+		env.orgs.roles[orgUser{2, existing.ID}] = org.RoleViewer
+
+		err := s.DeleteUser(context.Background(), testOrgID, strconv.FormatInt(existing.ID, 10))
+		require.ErrorIs(t, err, scim.ErrUserNotFound)
+	})
+
+	t.Run("should fail when user is a server admin", func(t *testing.T) {
+		s, env := setupTestService(t)
+		existing := env.users.add(&user.User{Login: "admin", IsAdmin: true})
+		env.orgs.roles[orgUser{testOrgID, existing.ID}] = org.RoleAdmin
+
+		err := s.DeleteUser(context.Background(), testOrgID, strconv.FormatInt(existing.ID, 10))
+		require.ErrorIs(t, err, scim.ErrUserNotProvisionable)
+		assert.Contains(t, env.orgs.roles, orgUser{testOrgID, existing.ID})
+		assert.Contains(t, env.users.users, existing.ID)
+		assert.Empty(t, env.revoked)
+	})
+
+	t.Run("should fail when user belongs to another organization", func(t *testing.T) {
+		s, env := setupTestService(t)
+		existing := env.users.add(&user.User{Login: "alice"})
+		env.orgs.roles[orgUser{testOrgID, existing.ID}] = org.RoleViewer
+		env.orgs.roles[orgUser{2, existing.ID}] = org.RoleViewer
+
+		err := s.DeleteUser(context.Background(), testOrgID, strconv.FormatInt(existing.ID, 10))
+		require.ErrorIs(t, err, scim.ErrUserNotProvisionable)
+		assert.Contains(t, env.orgs.roles, orgUser{testOrgID, existing.ID})
+		assert.Empty(t, env.revoked)
+	})
+}
+
+func TestService_ListUsers(t *testing.T) {
+	s, env := setupTestService(t)
+	for _, login := range []string{"alice", "bob", "carol"} {
+		u := env.users.add(&user.User{Login: login, Email: login + "@example.com"})
+		env.orgs.roles[orgUser{testOrgID, u.ID}] = org.RoleViewer
+	}
+
+	res, err := s.ListUsers(context.Background(), testOrgID, &scim.ListQuery{Filter: `userName eq "Bob"`, StartIndex: 1, Count: scim.MaxResults})
+	require.NoError(t, err)
+	require.Equal(t, 1, res.TotalResults)
+	assert.Equal(t, "bob", res.Resources[0].(*scim.User).UserName)
+
+	res, err = s.ListUsers(context.Background(), testOrgID, &scim.ListQuery{StartIndex: 2, Count: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.TotalResults)
+	assert.Equal(t, 2, res.StartIndex)
+	require.Equal(t, 1, res.ItemsPerPage)
+	assert.Equal(t, "bob", res.Resources[0].(*scim.User).UserName)
+
+	_, err = s.ListUsers(context.Background(), testOrgID, &scim.ListQuery{Filter: `userName eq`})
+	require.Error(t, err)
+	assert.Equal(t, scim.ScimTypeInvalidFilter, scim.NewErrorResponse(err).ScimType)
+}
+
+func TestService_CreateGroup(t *testing.T) {
+	t.Run("should create team with members", func(t *testing.T) {
+		s, env := setupTestService(t)
+		alice := env.users.add(&user.User{Login: "alice"})
+		env.orgs.roles[orgUser{testOrgID, alice.ID}] = org.RoleViewer
+		env.teams.ExpectedTeam = team.Team{ID: 3, OrgID: testOrgID, Name: "Engineering"}
+		env.teams.ExpectedTeamDTO = &team.TeamDTO{ID: 3, OrgID: testOrgID, Name: "Engineering"}
+		env.teams.ExpectedMembers = []*team.TeamMemberDTO{{UserID: alice.ID, Login: "alice"}}
+
+		g, err := s.CreateGroup(context.Background(), testOrgID, &scim.Group{
+			DisplayName: "Engineering",
+			Members:     []scim.Member{{Value: strconv.FormatInt(alice.ID, 10)}},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "3", g.ID)
+		require.Len(t, g.Members, 1)
+		assert.Equal(t, "alice", g.Members[0].Display)
+		assert.Equal(t, []permissionCall{{userID: alice.ID, teamID: "3", permission: "Member"}}, env.permissions.calls)
+	})
+
+	t.Run("should fail when member is not a user of the organization", func(t *testing.T) {
+		s, env := setupTestService(t)
+		bob := env.users.add(&user.User{Login: "bob"})
+
+		_, err := s.CreateGroup(context.Background(), testOrgID, &scim.Group{
+			DisplayName: "Engineering",
+			Members:     []scim.Member{{Value: strconv.FormatInt(bob.ID, 10)}},
+		})
+		require.Error(t, err)
+		assert.Equal(t, scim.ScimTypeInvalidValue, scim.NewErrorResponse(err).ScimType)
+		assert.Empty(t, env.permissions.calls)
+	})
+
+	t.Run("should fail when team exists", func(t *testing.T) {
+		s, env := setupTestService(t)
+		env.teams.ExpectedError = team.ErrTeamNameTaken
+
+		_, err := s.CreateGroup(context.Background(), testOrgID, &scim.Group{DisplayName: "Engineering"})
+		require.ErrorIs(t, err, scim.ErrGroupExists)
+	})
+}
+
+func TestService_PatchGroup(t *testing.T) {
+	s, env := setupTestService(t)
+	alice := env.users.add(&user.User{Login: "alice"})
+	bob := env.users.add(&user.User{Login: "bob"})
+	env.orgs.roles[orgUser{testOrgID, alice.ID}] = org.RoleViewer
+	env.orgs.roles[orgUser{testOrgID, bob.ID}] = org.RoleViewer
+	env.teams.ExpectedTeamDTO = &team.TeamDTO{ID: 3, OrgID: testOrgID, Name: "Engineering"}
+	env.teams.ExpectedMembers = []*team.TeamMemberDTO{{UserID: alice.ID, Login: "alice"}}
+
+	_, err := s.PatchGroup(context.Background(), testOrgID, "3", &scim.PatchRequest{
+		Operations: []scim.PatchOperation{
+			{Op: "add", Path: "members", Value: json.RawMessage(`[{"value": "` + strconv.FormatInt(bob.ID, 10) + `"}]`)},
+			{Op: "remove", Path: `members[value eq "` + strconv.FormatInt(alice.ID, 10) + `"]`},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []permissionCall{
+		{userID: alice.ID, teamID: "3", permission: ""},
+		{userID: bob.ID, teamID: "3", permission: "Member"},
+	}, env.permissions.calls)
+}
+
+func TestService_Bulk(t *testing.T) {
+	s, env := setupTestService(t)
+	env.teams.ExpectedTeam = team.Team{ID: 3, OrgID: testOrgID, Name: "Engineering"}
+	env.teams.ExpectedTeamDTO = &team.TeamDTO{ID: 3, OrgID: testOrgID, Name: "Engineering"}
+
+	res, err := s.Bulk(context.Background(), testOrgID, &scim.BulkRequest{
+		Operations: []scim.BulkOperation{
+			{Method: "POST", BulkID: "u1", Path: "/Users", Data: json.RawMessage(`{"userName": "alice"}`)},
+			{Method: "POST", BulkID: "g1", Path: "/Groups", Data: json.RawMessage(`{"displayName": "Engineering", "members": [{"value": "bulkId:u1"}]}`)},
+			{Method: "PATCH", Path: "/Users/bulkId:u1", Data: json.RawMessage(`{"Operations": [{"op": "replace", "path": "displayName", "value": "Alice"}]}`)},
+			{Method: "DELETE", Path: "/Users/bulkId:unknown"},
+			{Method: "POST", Path: "/Users", Data: json.RawMessage(`{"userName": "bob"}`)},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.Operations, 5)
+
+	alice := env.users.byLogin("alice")
+	require.NotNil(t, alice)
+	aliceID := strconv.FormatInt(alice.ID, 10)
+
+	assert.Equal(t, "201", res.Operations[0].Status)
+	assert.Equal(t, "https://grafana.example.com/api/scim/v2/Users/"+aliceID, res.Operations[0].Location)
+	assert.Equal(t, "201", res.Operations[1].Status)
+	assert.Equal(t, []permissionCall{{userID: alice.ID, teamID: "3", permission: "Member"}}, env.permissions.calls)
+	assert.Equal(t, "200", res.Operations[2].Status)
+	assert.Equal(t, "Alice", env.users.users[alice.ID].Name)
+	assert.Equal(t, "400", res.Operations[3].Status)
+	assert.Equal(t, "400", res.Operations[4].Status)
+
+	t.Run("should stop after failOnErrors errors", func(t *testing.T) {
+		s, _ := setupTestService(t)
+
+		res, err := s.Bulk(context.Background(), testOrgID, &scim.BulkRequest{
+			FailOnErrors: 1,
+			Operations: []scim.BulkOperation{
+				{Method: "DELETE", Path: "/Users/42"},
+				{Method: "POST", BulkID: "u1", Path: "/Users", Data: json.RawMessage(`{"userName": "alice"}`)},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, res.Operations, 1)
+		assert.Equal(t, "404", res.Operations[0].Status)
+	})
+}
+
+type testEnv struct {
+	users       *fakeUserService
+	orgs        *fakeOrgService
+	teams       *teamtest.FakeService
+	permissions *fakeTeamPermissionsService
+	store       *fakeStore
+	revoked     []int64
+}
+
+func setupTestService(t *testing.T) (*Service, *testEnv) {
+	t.Helper()
+
+	cfg := setting.NewCfg()
+	cfg.AppURL = "https://grafana.example.com/"
+	cfg.SCIM = setting.SCIMSettings{Enabled: true, OrgID: testOrgID, DefaultOrgRole: "Editor", DeleteOrphanedUsers: true}
+
+	env := &testEnv{
+		users:       &fakeUserService{users: map[int64]*user.User{}},
+		orgs:        &fakeOrgService{roles: map[orgUser]org.RoleType{}},
+		teams:       teamtest.NewFakeService(),
+		permissions: &fakeTeamPermissionsService{},
+		store:       &fakeStore{ids: map[string]string{}},
+	}
+	env.orgs.users = env.users
+
+	authTokenService := authtest.NewFakeUserAuthTokenService()
+	authTokenService.RevokeAllUserTokensProvider = func(ctx context.Context, userID int64) error {
+		env.revoked = append(env.revoked, userID)
+		return nil
+	}
+
+	return &Service{
+		cfg:                    cfg,
+		store:                  env.store,
+		userService:            env.users,
+		orgService:             env.orgs,
+		teamService:            env.teams,
+		teamPermissionsService: env.permissions,
+		accessControlService:   actest.FakeService{},
+		authTokenService:       authTokenService,
+		log:                    log.NewNopLogger(),
+	}, env
+}
+
+type fakeUserService struct {
+	usertest.FakeUserService
+	users  map[int64]*user.User
+	nextID int64
+}
+
+func (f *fakeUserService) add(u *user.User) *user.User {
+	f.nextID++
+	u.ID = f.nextID
+	f.users[u.ID] = u
+	return u
+}
+
+func (f *fakeUserService) byLogin(loginOrEmail string) *user.User {
+	for _, u := range f.users {
+		if strings.EqualFold(u.Login, loginOrEmail) || (u.Email != "" && strings.EqualFold(u.Email, loginOrEmail)) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (f *fakeUserService) Create(ctx context.Context, cmd *user.CreateUserCommand) (*user.User, error) {
+	if f.byLogin(cmd.Login) != nil {
+		return nil, user.ErrUserAlreadyExists
+	}
+	return f.add(&user.User{Login: cmd.Login, Email: cmd.Email, Name: cmd.Name, IsDisabled: cmd.IsDisabled}), nil
+}
+
+func (f *fakeUserService) GetByID(ctx context.Context, query *user.GetUserByIDQuery) (*user.User, error) {
+	if u, ok := f.users[query.ID]; ok {
+		copied := *u
+		return &copied, nil
+	}
+	return nil, user.ErrUserNotFound
+}
+
+func (f *fakeUserService) GetByLogin(ctx context.Context, query *user.GetUserByLoginQuery) (*user.User, error) {
+	if u := f.byLogin(query.LoginOrEmail); u != nil {
+		copied := *u
+		return &copied, nil
+	}
+	return nil, user.ErrUserNotFound
+}
+
+func (f *fakeUserService) Update(ctx context.Context, cmd *user.UpdateUserCommand) error {
+	u, ok := f.users[cmd.UserID]
+	if !ok {
+		return user.ErrUserNotFound
+	}
+	if cmd.Login != "" {
+		u.Login = cmd.Login
+	}
+	if cmd.Email != "" {
+		u.Email = cmd.Email
+	}
+	if cmd.Name != "" {
+		u.Name = cmd.Name
+	}
+	if cmd.IsDisabled != nil {
+		u.IsDisabled = *cmd.IsDisabled
+	}
+	if cmd.Password != nil {
+		u.Password = *cmd.Password
+	}
+	return nil
+}
+
+func (f *fakeUserService) Delete(ctx context.Context, cmd *user.DeleteUserCommand) error {
+	delete(f.users, cmd.UserID)
+	return nil
+}
+
+type orgUser struct {
+	orgID  int64
+	userID int64
+}
+
+type fakeOrgService struct {
+	orgtest.FakeOrgService
+	users *fakeUserService
+	roles map[orgUser]org.RoleType
+}
+
+func (f *fakeOrgService) AddOrgUser(ctx context.Context, cmd *org.AddOrgUserCommand) error {
+	f.roles[orgUser{cmd.OrgID, cmd.UserID}] = cmd.Role
+	return nil
+}
+
+func (f *fakeOrgService) RemoveOrgUser(ctx context.Context, cmd *org.RemoveOrgUserCommand) error {
+	delete(f.roles, orgUser{cmd.OrgID, cmd.UserID})
+	if !cmd.ShouldDeleteOrphanedUser {
+		return nil
+	}
+	for ou := range f.roles {
+		if ou.userID == cmd.UserID {
+			return nil
+		}
+	}
+	cmd.UserWasDeleted = true
+	return f.users.Delete(ctx, &user.DeleteUserCommand{UserID: cmd.UserID})
+}
+
+func (f *fakeOrgService) GetUserOrgList(ctx context.Context, query *org.GetUserOrgListQuery) ([]*org.UserOrgDTO, error) {
+	var orgs []*org.UserOrgDTO
+	for ou, role := range f.roles {
+		if ou.userID == query.UserID {
+			orgs = append(orgs, &org.UserOrgDTO{OrgID: ou.orgID, Role: role})
+		}
+	}
+	return orgs, nil
+}
+
+func (f *fakeOrgService) SearchOrgUsers(ctx context.Context, query *org.SearchOrgUsersQuery) (*org.SearchOrgUsersQueryResult, error) {
+	res := &org.SearchOrgUsersQueryResult{}
+	for id := int64(1); id <= f.users.nextID; id++ {
+		u, ok := f.users.users[id]
+		if _, isMember := f.roles[orgUser{query.OrgID, id}]; !ok || !isMember {
+			continue
+		}
+		res.OrgUsers = append(res.OrgUsers, &org.OrgUserDTO{OrgID: query.OrgID, UserID: u.ID, Login: u.Login, Email: u.Email, Name: u.Name})
+	}
+	res.TotalCount = int64(len(res.OrgUsers))
+	return res, nil
+}
+
+type permissionCall struct {
+	userID     int64
+	teamID     string
+	permission string
+}
+
+type fakeTeamPermissionsService struct {
+	actest.FakePermissionsService
+	calls []permissionCall
+}
+
+func (f *fakeTeamPermissionsService) SetUserPermission(ctx context.Context, orgID int64, u accesscontrol.User, resourceID, permission string) (*accesscontrol.ResourcePermission, error) {
+	f.calls = append(f.calls, permissionCall{userID: u.ID, teamID: resourceID, permission: permission})
+	return &accesscontrol.ResourcePermission{}, nil
+}
+
+type fakeStore struct {
+	ids map[string]string
+}
+
+func (f *fakeStore) key(orgID int64, resourceType string, resourceID int64) string {
+	return strconv.FormatInt(orgID, 10) + "/" + resourceType + "/" + strconv.FormatInt(resourceID, 10)
+}
+
+func (f *fakeStore) GetExternalIDs(ctx context.Context, orgID int64, resourceType string) (map[int64]string, error) {
+	ids := map[int64]string{}
+	prefix := strconv.FormatInt(orgID, 10) + "/" + resourceType + "/"
+	for k, v := range f.ids {
+		if strings.HasPrefix(k, prefix) {
+			id, _ := strconv.ParseInt(strings.TrimPrefix(k, prefix), 10, 64)
+			ids[id] = v
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeStore) GetExternalID(ctx context.Context, orgID int64, resourceType string, resourceID int64) (string, error) {
+	return f.ids[f.key(orgID, resourceType, resourceID)], nil
+}
+
+func (f *fakeStore) SetExternalID(ctx context.Context, orgID int64, resourceType string, resourceID int64, externalID string) error {
+	if externalID == "" {
+		delete(f.ids, f.key(orgID, resourceType, resourceID))
+		return nil
+	}
+	f.ids[f.key(orgID, resourceType, resourceID)] = externalID
+	return nil
+}
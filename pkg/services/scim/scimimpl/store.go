@@ -0,0 +1,98 @@
+package scimimpl
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+)
+
+// externalID is the identifier of a user or team in the identity provider
+type externalID struct {
+	ID           int64     `xorm:"pk autoincr 'id'"`
+	OrgID        int64     `xorm:"org_id"`
+	ResourceType string    `xorm:"resource_type"`
+	ResourceID   int64     `xorm:"resource_id"`
+	ExternalID   string    `xorm:"external_id"`
+	Created      time.Time `xorm:"created"`
+	Updated      time.Time `xorm:"updated"`
+}
+
+func (externalID) TableName() string {
+	return "scim_resource"
+}
+
+type store interface {
+	// GetExternalIDs returns the external IDs of the resources of a type by resource ID
+	GetExternalIDs(ctx context.Context, orgID int64, resourceType string) (map[int64]string, error)
+	// GetExternalID returns the external ID of a resource, or an empty string when it has none
+	GetExternalID(ctx context.Context, orgID int64, resourceType string, resourceID int64) (string, error)
+	// SetExternalID sets the external ID of a resource, an empty external ID removes it
+	SetExternalID(ctx context.Context, orgID int64, resourceType string, resourceID int64, id string) error
+}
+
+type xormStore struct {
+	db  db.DB
+	now func() time.Time
+}
+
+func (ss *xormStore) GetExternalIDs(ctx context.Context, orgID int64, resourceType string) (map[int64]string, error) {
+	var rows []externalID
+	err := ss.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Where("org_id = ? AND resource_type = ?", orgID, resourceType).Find(&rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int64]string, len(rows))
+	for _, row := range rows {
+		result[row.ResourceID] = row.ExternalID
+	}
+	return result, nil
+}
+
+func (ss *xormStore) GetExternalID(ctx context.Context, orgID int64, resourceType string, resourceID int64) (string, error) {
+	var row externalID
+	err := ss.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Where("org_id = ? AND resource_type = ? AND resource_id = ?", orgID, resourceType, resourceID).Get(&row)
+		return err
+	})
+	return row.ExternalID, err
+}
+
+func (ss *xormStore) SetExternalID(ctx context.Context, orgID int64, resourceType string, resourceID int64, id string) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		if id == "" {
+			_, err := sess.Exec("DELETE FROM scim_resource WHERE org_id = ? AND resource_type = ? AND resource_id = ?", orgID, resourceType, resourceID)
+			return err
+		}
+
+		var existing externalID
+		has, err := sess.Where("org_id = ? AND resource_type = ? AND resource_id = ?", orgID, resourceType, resourceID).Get(&existing)
+		if err != nil {
+			return err
+		}
+
+		now := ss.now()
+		if has {
+			if existing.ExternalID == id {
+				return nil
+			}
+			existing.ExternalID = id
+			existing.Updated = now
+			_, err = sess.ID(existing.ID).Cols("external_id", "updated").Update(&existing)
+			return err
+		}
+
+		_, err = sess.Insert(&externalID{
+			OrgID:        orgID,
+			ResourceType: resourceType,
+			ResourceID:   resourceID,
+			ExternalID:   id,
+			Created:      now,
+			Updated:      now,
+		})
+		return err
+	})
+}
@@ -0,0 +1,61 @@
+package scimimpl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/scim"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func TestIntegrationSCIMStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	s := &xormStore{db: db.InitTestDB(t), now: time.Now}
+
+	t.Run("should return empty external id for unknown resources", func(t *testing.T) {
+		externalID, err := s.GetExternalID(ctx, 1, scim.ResourceTypeUser, 1)
+		require.NoError(t, err)
+		assert.Empty(t, externalID)
+	})
+
+	t.Run("should set and update external ids", func(t *testing.T) {
+		require.NoError(t, s.SetExternalID(ctx, 1, scim.ResourceTypeUser, 1, "00u1"))
+		require.NoError(t, s.SetExternalID(ctx, 1, scim.ResourceTypeUser, 2, "00u2"))
+		require.NoError(t, s.SetExternalID(ctx, 1, scim.ResourceTypeUser, 1, "00u1-renamed"))
+		require.NoError(t, s.SetExternalID(ctx, 1, scim.ResourceTypeGroup, 1, "00g1"))
+		require.NoError(t, s.SetExternalID(ctx, 2, scim.ResourceTypeUser, 1, "other-org"))
+
+		externalID, err := s.GetExternalID(ctx, 1, scim.ResourceTypeUser, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "00u1-renamed", externalID)
+
+		externalIDs, err := s.GetExternalIDs(ctx, 1, scim.ResourceTypeUser)
+		require.NoError(t, err)
+		assert.Equal(t, map[int64]string{1: "00u1-renamed", 2: "00u2"}, externalIDs)
+
+		externalIDs, err = s.GetExternalIDs(ctx, 1, scim.ResourceTypeGroup)
+		require.NoError(t, err)
+		assert.Equal(t, map[int64]string{1: "00g1"}, externalIDs)
+	})
+
+	t.Run("should remove external id when empty", func(t *testing.T) {
+		require.NoError(t, s.SetExternalID(ctx, 1, scim.ResourceTypeUser, 2, ""))
+
+		externalIDs, err := s.GetExternalIDs(ctx, 1, scim.ResourceTypeUser)
+		require.NoError(t, err)
+		assert.Equal(t, map[int64]string{1: "00u1-renamed"}, externalIDs)
+	})
+}
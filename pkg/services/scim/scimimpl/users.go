@@ -0,0 +1,388 @@
+package scimimpl
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/scim"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func (s *Service) ListUsers(ctx context.Context, orgID int64, query *scim.ListQuery) (*scim.ListResponse, error) {
+	res, err := s.orgService.SearchOrgUsers(ctx, &org.SearchOrgUsersQuery{OrgID: orgID, DontEnforceAccessControl: true})
+	if err != nil {
+		return nil, err
+	}
+
+	externalIDs, err := s.store.GetExternalIDs(ctx, orgID, scim.ResourceTypeUser)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*scim.User, 0, len(res.OrgUsers))
+	for _, ou := range res.OrgUsers {
+		users = append(users, s.toSCIMUser(&user.User{
+			ID:         ou.UserID,
+			Login:      ou.Login,
+			Email:      ou.Email,
+			Name:       ou.Name,
+			IsDisabled: ou.IsDisabled,
+			Created:    ou.Created,
+			Updated:    ou.Updated,
+		}, externalIDs[ou.UserID]))
+	}
+
+	return listResources(users, query)
+}
+
+func (s *Service) GetUser(ctx context.Context, orgID int64, id string) (*scim.User, error) {
+	u, err := s.getOrgUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	externalID, err := s.store.GetExternalID(ctx, orgID, scim.ResourceTypeUser, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toSCIMUser(u, externalID), nil
+}
+
+// CreateUser adds a user to the organization. Users already existing in Grafana, e.g. because they
+// signed in with another provider, are added with their account, keeping their email and password.
+// Users who belong to another organization or are server admins can't be provisioned.
+func (s *Service) CreateUser(ctx context.Context, orgID int64, in *scim.User) (*scim.User, error) {
+	if err := validateUser(in); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.findUser(ctx, in.UserName, in.PrimaryEmail())
+	if err != nil {
+		return nil, err
+	}
+
+	var userID int64
+	if existing != nil {
+		isMember, err := s.isOrgMember(ctx, orgID, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		if isMember || existing.IsServiceAccount {
+			return nil, scim.ErrUserExists.Errorf("user %q already exists in organization %d", in.UserName, orgID)
+		}
+		if err := s.checkProvisionable(ctx, orgID, existing); err != nil {
+			return nil, err
+		}
+
+		if err := s.addOrgUser(ctx, orgID, existing.ID); err != nil {
+			return nil, err
+		}
+		if err := s.updateUser(ctx, existing, in); err != nil {
+			return nil, err
+		}
+		userID = existing.ID
+	} else {
+		cmd := &user.CreateUserCommand{
+			Login:        strings.TrimSpace(in.UserName),
+			Email:        in.PrimaryEmail(),
+			Name:         in.FullName(),
+			IsDisabled:   !in.IsActive(),
+			SkipOrgSetup: true,
+		}
+		if in.Password != "" {
+			cmd.Password = user.Password(in.Password)
+		}
+
+		created, err := s.userService.Create(ctx, cmd)
+		if err != nil {
+			if errors.Is(err, user.ErrUserAlreadyExists) {
+				return nil, scim.ErrUserExists.Errorf("user %q already exists", in.UserName)
+			}
+			return nil, err
+		}
+
+		if err := s.addOrgUser(ctx, orgID, created.ID); err != nil {
+			if deleteErr := s.userService.Delete(ctx, &user.DeleteUserCommand{UserID: created.ID}); deleteErr != nil {
+				s.log.FromContext(ctx).Error("Failed to delete user not added to the organization", "userId", created.ID, "error", deleteErr)
+			}
+			return nil, err
+		}
+		userID = created.ID
+	}
+
+	if err := s.store.SetExternalID(ctx, orgID, scim.ResourceTypeUser, userID, in.ExternalID); err != nil {
+		return nil, err
+	}
+
+	s.log.FromContext(ctx).Info("Provisioned user", "orgId", orgID, "userId", userID, "login", in.UserName, "existing", existing != nil)
+	return s.GetUser(ctx, orgID, strconv.FormatInt(userID, 10))
+}
+
+func (s *Service) ReplaceUser(ctx context.Context, orgID int64, id string, in *scim.User) (*scim.User, error) {
+	if err := validateUser(in); err != nil {
+		return nil, err
+	}
+
+	u, err := s.getOrgUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkProvisionable(ctx, orgID, u); err != nil {
+		return nil, err
+	}
+
+	if err := s.updateUser(ctx, u, in); err != nil {
+		return nil, err
+	}
+	if err := s.store.SetExternalID(ctx, orgID, scim.ResourceTypeUser, u.ID, in.ExternalID); err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(ctx, orgID, id)
+}
+
+func (s *Service) PatchUser(ctx context.Context, orgID int64, id string, patch *scim.PatchRequest) (*scim.User, error) {
+	current, err := s.GetUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resource, err := toMap(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPatch(resource, patch.Operations); err != nil {
+		return nil, err
+	}
+
+	var patched scim.User
+	if err := fromMap(resource, &patched); err != nil {
+		return nil, err
+	}
+
+	// the display name takes precedence, it only changes with the other name attributes when it's patched itself
+	if patched.DisplayName == current.DisplayName && patched.Name != nil {
+		if current.Name != nil && patched.Name.Formatted != current.Name.Formatted {
+			patched.DisplayName = patched.Name.Formatted
+		} else if name := strings.TrimSpace(patched.Name.GivenName + " " + patched.Name.FamilyName); name != "" {
+			patched.DisplayName = name
+		}
+	}
+
+	return s.ReplaceUser(ctx, orgID, id, &patched)
+}
+
+// DeleteUser removes a user from the organization. Users who aren't a member of any other organization are deleted
+// when delete_orphaned_users is enabled, and their sessions are revoked.
+func (s *Service) DeleteUser(ctx context.Context, orgID int64, id string) error {
+	u, err := s.getOrgUser(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkProvisionable(ctx, orgID, u); err != nil {
+		return err
+	}
+
+	cmd := &org.RemoveOrgUserCommand{UserID: u.ID, OrgID: orgID, ShouldDeleteOrphanedUser: s.cfg.SCIM.DeleteOrphanedUsers}
+	if err := s.orgService.RemoveOrgUser(ctx, cmd); err != nil {
+		if errors.Is(err, org.ErrLastOrgAdmin) {
+			return scim.ErrLastOrgAdmin.Errorf("user %d is the last admin of organization %d", u.ID, orgID)
+		}
+		return err
+	}
+
+	// deleted users are signed out right away, the sessions of users only removed from the organization are kept
+	permissionsOrgID := orgID
+	if cmd.UserWasDeleted {
+		permissionsOrgID = accesscontrol.GlobalOrgID
+		if err := s.authTokenService.RevokeAllUserTokens(ctx, u.ID); err != nil {
+			return err
+		}
+	}
+	if err := s.accessControlService.DeleteUserPermissions(ctx, permissionsOrgID, u.ID); err != nil {
+		s.log.FromContext(ctx).Warn("Failed to delete permissions for user", "userId", u.ID, "orgId", permissionsOrgID, "error", err)
+	}
+
+	if err := s.store.SetExternalID(ctx, orgID, scim.ResourceTypeUser, u.ID, ""); err != nil {
+		return err
+	}
+
+	s.log.FromContext(ctx).Info("Deprovisioned user", "orgId", orgID, "userId", u.ID, "login", u.Login, "deleted", cmd.UserWasDeleted)
+	return nil
+}
+
+// updateUser applies the attributes of the SCIM user to the Grafana user.
+// The email and password of existing users are never changed, they could be used to take over the account.
+// Sessions of deactivated users are revoked so that they are signed out right away.
+func (s *Service) updateUser(ctx context.Context, u *user.User, in *scim.User) error {
+	login := strings.ToLower(strings.TrimSpace(in.UserName))
+
+	if err := s.checkConflict(ctx, u.ID, login, ""); err != nil {
+		return err
+	}
+
+	isDisabled := !in.IsActive()
+	cmd := &user.UpdateUserCommand{
+		UserID:     u.ID,
+		Login:      login,
+		Email:      u.Email,
+		Name:       in.FullName(),
+		IsDisabled: &isDisabled,
+	}
+
+	if err := s.userService.Update(ctx, cmd); err != nil {
+		return err
+	}
+
+	if isDisabled && !u.IsDisabled {
+		if err := s.authTokenService.RevokeAllUserTokens(ctx, u.ID); err != nil {
+			return err
+		}
+		s.log.FromContext(ctx).Info("Deactivated user", "userId", u.ID, "login", u.Login)
+	} else if !isDisabled && u.IsDisabled {
+		s.log.FromContext(ctx).Info("Reactivated user", "userId", u.ID, "login", u.Login)
+	}
+	return nil
+}
+
+// checkProvisionable returns an error when the user belongs to an organization other than orgID or is a server admin.
+// These users can't be adopted or modified by the identity provider of a single organization.
+func (s *Service) checkProvisionable(ctx context.Context, orgID int64, u *user.User) error {
+	if u.IsAdmin {
+		return scim.ErrUserNotProvisionable.Errorf("user %d is a server admin", u.ID)
+	}
+
+	orgs, err := s.orgService.GetUserOrgList(ctx, &org.GetUserOrgListQuery{UserID: u.ID})
+	if err != nil {
+		return err
+	}
+	for _, o := range orgs {
+		if o.OrgID != orgID {
+			return scim.ErrUserNotProvisionable.Errorf("user %d is a member of organization %d", u.ID, o.OrgID)
+		}
+	}
+	return nil
+}
+
+// checkConflict returns an error when the login or email is used by another user
+func (s *Service) checkConflict(ctx context.Context, userID int64, login, email string) error {
+	for _, loginOrEmail := range []string{login, email} {
+		if loginOrEmail == "" {
+			continue
+		}
+		other, err := s.userService.GetByLogin(ctx, &user.GetUserByLoginQuery{LoginOrEmail: loginOrEmail})
+		if err != nil {
+			if errors.Is(err, user.ErrUserNotFound) {
+				continue
+			}
+			return err
+		}
+		if other.ID != userID {
+			return scim.ErrUserExists.Errorf("login or email %q is used by user %d", loginOrEmail, other.ID)
+		}
+	}
+	return nil
+}
+
+func (s *Service) findUser(ctx context.Context, login, email string) (*user.User, error) {
+	for _, loginOrEmail := range []string{strings.TrimSpace(login), email} {
+		if loginOrEmail == "" {
+			continue
+		}
+		u, err := s.userService.GetByLogin(ctx, &user.GetUserByLoginQuery{LoginOrEmail: loginOrEmail})
+		if err == nil {
+			return u, nil
+		}
+		if !errors.Is(err, user.ErrUserNotFound) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (s *Service) getOrgUser(ctx context.Context, orgID int64, id string) (*user.User, error) {
+	userID, ok := parseID(id)
+	if !ok {
+		return nil, scim.ErrUserNotFound.Errorf("invalid user id %q", id)
+	}
+
+	u, err := s.userService.GetByID(ctx, &user.GetUserByIDQuery{ID: userID})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, scim.ErrUserNotFound.Errorf("user %d not found", userID)
+		}
+		return nil, err
+	}
+	if u.IsServiceAccount {
+		return nil, scim.ErrUserNotFound.Errorf("user %d is a service account", userID)
+	}
+
+	isMember, err := s.isOrgMember(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, scim.ErrUserNotFound.Errorf("user %d is not a member of organization %d", userID, orgID)
+	}
+	return u, nil
+}
+
+func (s *Service) isOrgMember(ctx context.Context, orgID, userID int64) (bool, error) {
+	orgs, err := s.orgService.GetUserOrgList(ctx, &org.GetUserOrgListQuery{UserID: userID})
+	if err != nil {
+		return false, err
+	}
+	for _, o := range orgs {
+		if o.OrgID == orgID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Service) addOrgUser(ctx context.Context, orgID, userID int64) error {
+	return s.orgService.AddOrgUser(ctx, &org.AddOrgUserCommand{
+		OrgID:  orgID,
+		UserID: userID,
+		Role:   org.RoleType(s.cfg.SCIM.DefaultOrgRole),
+	})
+}
+
+func (s *Service) toSCIMUser(u *user.User, externalID string) *scim.User {
+	id := strconv.FormatInt(u.ID, 10)
+	active := scim.Bool(!u.IsDisabled)
+
+	result := &scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          id,
+		ExternalID:  externalID,
+		UserName:    u.Login,
+		DisplayName: u.Name,
+		Active:      &active,
+		Meta: &scim.Meta{
+			ResourceType: scim.ResourceTypeUser,
+			Created:      timePtr(u.Created),
+			LastModified: timePtr(u.Updated),
+			Location:     s.location("Users", id),
+		},
+	}
+	if u.Name != "" {
+		result.Name = &scim.Name{Formatted: u.Name}
+	}
+	if u.Email != "" {
+		result.Emails = []scim.Email{{Value: u.Email, Type: "work", Primary: true}}
+	}
+	return result
+}
+
+func validateUser(u *scim.User) error {
+	if strings.TrimSpace(u.UserName) == "" {
+		return scim.ErrInvalidValue("userName is required")
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package scimtest
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/scim"
+)
+
+var _ scim.Service = new(FakeService)
+
+type FakeService struct {
+	ExpectedUser         *scim.User
+	ExpectedGroup        *scim.Group
+	ExpectedListResponse *scim.ListResponse
+	ExpectedBulkResponse *scim.BulkResponse
+	ExpectedErr          error
+
+	// ListQuery is the query of the last list request
+	ListQuery *scim.ListQuery
+	// DeletedIDs are the ids of the users and groups deleted
+	DeletedIDs []string
+}
+
+func (f *FakeService) ListUsers(ctx context.Context, orgID int64, query *scim.ListQuery) (*scim.ListResponse, error) {
+	f.ListQuery = query
+	return f.ExpectedListResponse, f.ExpectedErr
+}
+
+func (f *FakeService) GetUser(ctx context.Context, orgID int64, id string) (*scim.User, error) {
+	return f.ExpectedUser, f.ExpectedErr
+}
+
+func (f *FakeService) CreateUser(ctx context.Context, orgID int64, u *scim.User) (*scim.User, error) {
+	return f.ExpectedUser, f.ExpectedErr
+}
+
+func (f *FakeService) ReplaceUser(ctx context.Context, orgID int64, id string, u *scim.User) (*scim.User, error) {
+	return f.ExpectedUser, f.ExpectedErr
+}
+
+func (f *FakeService) PatchUser(ctx context.Context, orgID int64, id string, patch *scim.PatchRequest) (*scim.User, error) {
+	return f.ExpectedUser, f.ExpectedErr
+}
+
+func (f *FakeService) DeleteUser(ctx context.Context, orgID int64, id string) error {
+	if f.ExpectedErr == nil {
+		f.DeletedIDs = append(f.DeletedIDs, id)
+	}
+	return f.ExpectedErr
+}
+
+func (f *FakeService) ListGroups(ctx context.Context, orgID int64, query *scim.ListQuery) (*scim.ListResponse, error) {
+	f.ListQuery = query
+	return f.ExpectedListResponse, f.ExpectedErr
+}
+
+func (f *FakeService) GetGroup(ctx context.Context, orgID int64, id string) (*scim.Group, error) {
+	return f.ExpectedGroup, f.ExpectedErr
+}
+
+func (f *FakeService) CreateGroup(ctx context.Context, orgID int64, g *scim.Group) (*scim.Group, error) {
+	return f.ExpectedGroup, f.ExpectedErr
+}
+
+func (f *FakeService) ReplaceGroup(ctx context.Context, orgID int64, id string, g *scim.Group) (*scim.Group, error) {
+	return f.ExpectedGroup, f.ExpectedErr
+}
+
+func (f *FakeService) PatchGroup(ctx context.Context, orgID int64, id string, patch *scim.PatchRequest) (*scim.Group, error) {
+	return f.ExpectedGroup, f.ExpectedErr
+}
+
+func (f *FakeService) DeleteGroup(ctx context.Context, orgID int64, id string) error {
+	if f.ExpectedErr == nil {
+		f.DeletedIDs = append(f.DeletedIDs, id)
+	}
+	return f.ExpectedErr
+}
+
+func (f *FakeService) Bulk(ctx context.Context, orgID int64, req *scim.BulkRequest) (*scim.BulkResponse, error) {
+	return f.ExpectedBulkResponse, f.ExpectedErr
+}
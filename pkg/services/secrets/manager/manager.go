@@ -161,6 +161,26 @@ func (s *SecretsService) encryptedWithEnvelopeEncryption(payload []byte) bool {
 
 var b64 = base64.RawStdEncoding
 
+// DataKeyID returns the id of the data key a payload is encrypted with,
+// false if the payload isn't encrypted with envelope encryption.
+func DataKeyID(payload []byte) (string, bool) {
+	if len(payload) == 0 || payload[0] != keyIdDelimiter {
+		return "", false
+	}
+
+	endOfKey := bytes.IndexByte(payload[1:], keyIdDelimiter)
+	if endOfKey == -1 {
+		return "", false
+	}
+
+	id, err := b64.DecodeString(string(payload[1 : endOfKey+1]))
+	if err != nil {
+		return "", false
+	}
+
+	return string(id), true
+}
+
 func (s *SecretsService) Encrypt(ctx context.Context, payload []byte, opt secrets.EncryptionOptions) ([]byte, error) {
 	ctx, span := s.tracer.Start(ctx, "secretsService.Encrypt")
 	defer span.End()
@@ -457,6 +477,11 @@ func (s *SecretsService) GetProviders() map[secrets.ProviderID]secrets.Provider
 	return s.providers
 }
 
+// CurrentProviderID returns the provider the new data keys are encrypted with
+func (s *SecretsService) CurrentProviderID() secrets.ProviderID {
+	return s.currentProviderID
+}
+
 func (s *SecretsService) RotateDataKeys(ctx context.Context) error {
 	s.log.Info("Data keys rotation triggered, acquiring lock...")
 
@@ -433,6 +433,30 @@ func TestSecretsService_Decrypt(t *testing.T) {
 	})
 }
 
+func TestDataKeyID(t *testing.T) {
+	testDB := db.InitTestDB(t)
+	store := database.ProvideSecretsStore(testDB)
+	svc := SetupTestService(t, store)
+
+	ciphertext, err := svc.Encrypt(context.Background(), []byte("grafana"), secrets.WithoutScope())
+	require.NoError(t, err)
+
+	keys, err := store.GetAllDataKeys(context.Background())
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+
+	id, ok := DataKeyID(ciphertext)
+	require.True(t, ok)
+	assert.Equal(t, keys[0].Id, id)
+
+	_, ok = DataKeyID([]byte("legacy"))
+	assert.False(t, ok)
+	_, ok = DataKeyID([]byte("#no-end"))
+	assert.False(t, ok)
+	_, ok = DataKeyID(nil)
+	assert.False(t, ok)
+}
+
 func TestIntegration_SecretsService(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
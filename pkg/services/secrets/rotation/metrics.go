@@ -0,0 +1,49 @@
+package rotation
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "grafana"
+	metricsSubSystem = "encryption"
+)
+
+type metrics struct {
+	rotations   prometheus.Counter
+	reEncrypted *prometheus.CounterVec
+	pending     *prometheus.GaugeVec
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	m := &metrics{
+		rotations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "scheduled_data_keys_rotations_total",
+			Help:      "Number of scheduled data keys rotations",
+		}),
+		reEncrypted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "rotated_secrets_reencrypted_total",
+			Help:      "Number of rows whose secrets were re-encrypted after a data keys rotation",
+		}, []string{"table", "success"}),
+		pending: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "rotated_secrets_pending",
+			Help:      "Number of rows whose secrets are still encrypted with a rotated data key, at the end of the last scan of the table",
+		}, []string{"table"}),
+	}
+
+	if reg != nil {
+		reg.MustRegister(
+			m.rotations,
+			m.reEncrypted,
+			m.pending,
+		)
+	}
+
+	return m
+}
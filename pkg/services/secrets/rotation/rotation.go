@@ -0,0 +1,349 @@
+// Package rotation rotates the data keys of envelope encryption on a schedule and re-encrypts, batch after batch, the
+// secrets still encrypted with the rotated data keys. A single instance does the work at a time, the others skip it
+// while the server lock is taken.
+package rotation
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/secrets/manager"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	lockActionName = "rotate and re-encrypt data keys"
+	// lockMaxInterval is how long the lock is held at most, if the instance holding it stopped before releasing it
+	lockMaxInterval = 10 * time.Minute
+
+	defaultBatchSize     = 100
+	defaultBatchInterval = time.Minute
+
+	// maxPagesPerRun bounds the rows read by a run when few of them are encrypted with a rotated data key, the scan
+	// resumes where it stopped on the next run
+	maxPagesPerRun = 10
+	// the rows that couldn't be re-encrypted are retried after minRetryBackoff, doubled after each failure
+	minRetryBackoff = 10 * time.Minute
+	maxRetryBackoff = 24 * time.Hour
+)
+
+type Service struct {
+	rotationInterval time.Duration
+	batchSize        int
+	batchInterval    time.Duration
+
+	secretsSrv *manager.SecretsService
+	store      secrets.Store
+	sqlStore   db.DB
+	serverLock *serverlock.ServerLockService
+	features   featuremgmt.FeatureToggles
+	tables     []secretTable
+	metrics    *metrics
+	log        log.Logger
+	now        func() time.Time
+
+	// progress is the progress of the re-encryption of the current rotated data keys, it is only read and written
+	// while the server lock is held
+	progress *reEncryption
+}
+
+func ProvideService(
+	cfg *setting.Cfg,
+	secretsSrv *manager.SecretsService,
+	store secrets.Store,
+	sqlStore db.DB,
+	serverLock *serverlock.ServerLockService,
+	features featuremgmt.FeatureToggles,
+	reg prometheus.Registerer,
+) *Service {
+	section := cfg.SectionWithEnvOverrides("security.encryption")
+
+	batchSize := section.Key("data_keys_reencryption_batch_size").MustInt(defaultBatchSize)
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	batchInterval := section.Key("data_keys_reencryption_interval").MustDuration(defaultBatchInterval)
+	if batchInterval <= 0 {
+		batchInterval = defaultBatchInterval
+	}
+
+	return &Service{
+		rotationInterval: section.Key("data_keys_rotation_interval").MustDuration(0),
+		batchSize:        batchSize,
+		batchInterval:    batchInterval,
+		secretsSrv:       secretsSrv,
+		store:            store,
+		sqlStore:         sqlStore,
+		serverLock:       serverLock,
+		features:         features,
+		tables: []secretTable{
+			jsonDataTable{tableName: "data_source"},
+			jsonDataTable{tableName: "plugin_setting"},
+			alertingTable{},
+			ssoSettingsTable{},
+		},
+		metrics: newMetrics(reg),
+		log:     log.New("secrets.rotation"),
+		now:     time.Now,
+	}
+}
+
+// IsDisabled returns true when no rotation interval is configured or envelope encryption is disabled.
+func (s *Service) IsDisabled() bool {
+	return s.rotationInterval <= 0 || s.features.IsEnabledGlobally(featuremgmt.FlagDisableEnvelopeEncryption)
+}
+
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.batchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := s.serverLock.LockExecuteAndRelease(ctx, lockActionName, lockMaxInterval, s.rotateAndReEncrypt)
+			var lockedErr *serverlock.ServerLockExistsError
+			if err != nil && !errors.As(err, &lockedErr) {
+				s.log.Error("Failed to lock the data keys rotation", "error", err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// rotateAndReEncrypt rotates the data keys when the oldest active one is older than the rotation interval, then
+// re-encrypts a batch of the secrets encrypted with the inactive data keys
+func (s *Service) rotateAndReEncrypt(ctx context.Context) {
+	keys, err := s.store.GetAllDataKeys(ctx)
+	if err != nil {
+		s.log.Error("Failed to list the data keys", "error", err)
+		return
+	}
+
+	if s.rotationDue(keys) {
+		if err := s.secretsSrv.RotateDataKeys(ctx); err != nil {
+			s.log.Error("Scheduled data keys rotation failed", "error", err)
+			return
+		}
+		s.metrics.rotations.Inc()
+		s.log.Info("Rotated the data keys, re-encrypting the secrets in batches", "interval", s.rotationInterval, "batchSize", s.batchSize)
+
+		if keys, err = s.store.GetAllDataKeys(ctx); err != nil {
+			s.log.Error("Failed to list the data keys", "error", err)
+			return
+		}
+	}
+
+	rotated := make(map[string]bool)
+	for _, key := range keys {
+		if !key.Active {
+			rotated[key.Id] = true
+		}
+	}
+	s.reEncrypt(ctx, rotated)
+}
+
+func (s *Service) rotationDue(keys []*secrets.DataKey) bool {
+	for _, key := range keys {
+		if key.Active && s.now().Sub(key.Created) >= s.rotationInterval {
+			return true
+		}
+	}
+	return false
+}
+
+// reEncryption is the progress of the re-encryption of the secrets of a set of rotated data keys. It is kept in
+// memory, an instance taking over from another one scans the tables once more.
+type reEncryption struct {
+	rotated map[string]bool
+	tables  map[string]*tableProgress
+}
+
+// tableProgress is the progress of the scan of a table for the rows encrypted with a rotated data key
+type tableProgress struct {
+	// after is the id of the last row scanned, nil when the scan starts from the beginning
+	after any
+	// pending counts the rows still encrypted with a rotated data key found by the current scan
+	pending int
+	// done is true once a scan reached the end of the table, the table is only scanned again to retry the failures
+	done     bool
+	retryAt  time.Time
+	failures map[any]*failure
+}
+
+// failure is a row that couldn't be re-encrypted, like when its secrets can't be decrypted anymore
+type failure struct {
+	attempts int
+	retryAt  time.Time
+	// seen is true when the current scan found the row still encrypted with a rotated data key
+	seen bool
+}
+
+func (p *reEncryption) table(name string) *tableProgress {
+	t, ok := p.tables[name]
+	if !ok {
+		t = &tableProgress{failures: map[any]*failure{}}
+		p.tables[name] = t
+	}
+	return t
+}
+
+// due returns true when the table has rows left to scan, or failures to retry
+func (t *tableProgress) due(now time.Time) bool {
+	return !t.done || (len(t.failures) > 0 && !now.Before(t.retryAt))
+}
+
+// reEncrypt re-encrypts at most batchSize rows encrypted with the rotated data keys, it continues the scan of the
+// tables where the previous run stopped
+func (s *Service) reEncrypt(ctx context.Context, rotated map[string]bool) {
+	if len(rotated) == 0 {
+		for _, table := range s.tables {
+			s.metrics.pending.WithLabelValues(table.name()).Set(0)
+		}
+		s.progress = nil
+		return
+	}
+	if s.progress == nil || !maps.Equal(s.progress.rotated, rotated) {
+		s.progress = &reEncryption{rotated: rotated, tables: map[string]*tableProgress{}}
+	}
+
+	budget, pages := s.batchSize, maxPagesPerRun
+	var reEncrypted, failed int
+	for _, table := range s.tables {
+		if budget == 0 || pages == 0 {
+			break
+		}
+		progress := s.progress.table(table.name())
+		if !progress.due(s.now()) {
+			continue
+		}
+		if progress.done {
+			// the failures are retried by scanning the table again
+			progress.done = false
+		}
+
+		r, f, err := s.reEncryptTable(ctx, table, progress, &budget, &pages)
+		reEncrypted += r
+		failed += f
+		if err != nil {
+			s.log.Error("Failed to re-encrypt secrets", "table", table.name(), "error", err)
+		}
+	}
+
+	if reEncrypted > 0 || failed > 0 {
+		s.log.Info("Re-encrypted secrets of rotated data keys", "reEncrypted", reEncrypted, "failed", failed)
+	}
+}
+
+// reEncryptTable continues the scan of a table until the budget of rows to re-encrypt or of pages to read is spent,
+// it returns the number of re-encrypted and failed rows
+func (s *Service) reEncryptTable(ctx context.Context, table secretTable, progress *tableProgress, budget, pages *int) (int, int, error) {
+	var reEncrypted, failed int
+	for *budget > 0 && *pages > 0 {
+		rows, err := table.page(ctx, s.sqlStore, progress.after, s.batchSize)
+		if err != nil {
+			return reEncrypted, failed, err
+		}
+		*pages--
+
+		for _, r := range rows {
+			if *budget == 0 {
+				// the row is read again by the next run
+				return reEncrypted, failed, nil
+			}
+			progress.after = r.id
+
+			ids, err := table.keyIDs(r)
+			if err != nil {
+				s.log.Warn("Could not read the secrets to re-encrypt", "table", table.name(), "id", r.id, "error", err)
+				continue
+			}
+			if !anyRotated(ids, s.progress.rotated) {
+				delete(progress.failures, r.id)
+				continue
+			}
+			if f, ok := progress.failures[r.id]; ok && s.now().Before(f.retryAt) {
+				f.seen = true
+				progress.pending++
+				continue
+			}
+			*budget--
+
+			updated, err := table.reEncrypt(ctx, s.secretsSrv, s.sqlStore, r)
+			switch {
+			case err != nil:
+				f := progress.fail(r.id, s.now())
+				s.log.Warn("Could not re-encrypt secrets", "table", table.name(), "id", r.id, "attempts", f.attempts, "retryAt", f.retryAt, "error", err)
+				s.metrics.reEncrypted.WithLabelValues(table.name(), "false").Inc()
+				progress.pending++
+				failed++
+			case updated:
+				delete(progress.failures, r.id)
+				s.metrics.reEncrypted.WithLabelValues(table.name(), "true").Inc()
+				reEncrypted++
+			}
+			// a row updated in the meantime has been encrypted with the current data key
+		}
+
+		if len(rows) < s.batchSize {
+			s.metrics.pending.WithLabelValues(table.name()).Set(float64(progress.pending))
+			progress.finish()
+			return reEncrypted, failed, nil
+		}
+	}
+	return reEncrypted, failed, nil
+}
+
+// fail records a failure of a row, the row is retried after a backoff that doubles with each failure
+func (t *tableProgress) fail(id any, now time.Time) *failure {
+	f, ok := t.failures[id]
+	if !ok {
+		f = &failure{}
+		t.failures[id] = f
+	}
+	f.attempts++
+	f.seen = true
+	backoff := maxRetryBackoff
+	if shift := f.attempts - 1; shift < 16 {
+		backoff = min(minRetryBackoff<<shift, maxRetryBackoff)
+	}
+	f.retryAt = now.Add(backoff)
+	return f
+}
+
+// finish ends the scan of the table, the next one starts when the first failure is to be retried. The failures of
+// the rows the scan didn't find, like deleted rows, are forgotten.
+func (t *tableProgress) finish() {
+	t.after = nil
+	t.pending = 0
+	t.done = true
+	t.retryAt = time.Time{}
+	for id, f := range t.failures {
+		if !f.seen {
+			delete(t.failures, id)
+			continue
+		}
+		f.seen = false
+		if t.retryAt.IsZero() || f.retryAt.Before(t.retryAt) {
+			t.retryAt = f.retryAt
+		}
+	}
+}
+
+func anyRotated(ids []string, rotated map[string]bool) bool {
+	for _, id := range ids {
+		if rotated[id] {
+			return true
+		}
+	}
+	return false
+}
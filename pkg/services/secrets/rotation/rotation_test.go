@@ -0,0 +1,255 @@
+package rotation
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	dsservice "github.com/grafana/grafana/pkg/services/datasources/service"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/secrets/database"
+	"github.com/grafana/grafana/pkg/services/secrets/manager"
+	"github.com/grafana/grafana/pkg/services/ssosettings/models"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func TestIntegrationRotateAndReEncrypt(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	sqlStore, cfg := db.InitTestDBWithCfg(t)
+	cfg.Raw.Section("security.encryption").Key("data_keys_rotation_interval").SetValue("24h")
+	cfg.Raw.Section("security.encryption").Key("data_keys_reencryption_batch_size").SetValue("2")
+
+	store := database.ProvideSecretsStore(sqlStore)
+	secretsSrv := manager.SetupTestService(t, store)
+	s := ProvideService(cfg, secretsSrv, store, sqlStore, serverlock.ProvideService(sqlStore, tracing.InitializeTracerForTest()),
+		featuremgmt.WithFeatures(), prometheus.NewRegistry())
+	require.False(t, s.IsDisabled())
+
+	dsStore := dsservice.CreateStore(sqlStore, log.NewNopLogger())
+	for i := 0; i < 3; i++ {
+		sjd, err := secretsSrv.EncryptJsonData(ctx, map[string]string{"password": fmt.Sprintf("password-%d", i)}, secrets.WithoutScope())
+		require.NoError(t, err)
+		_, err = dsStore.AddDataSource(ctx, &datasources.AddDataSourceCommand{
+			OrgID:                   1,
+			Name:                    fmt.Sprintf("ds-%d", i),
+			Type:                    datasources.DS_PROMETHEUS,
+			Access:                  datasources.DS_ACCESS_PROXY,
+			EncryptedSecureJsonData: sjd,
+		})
+		require.NoError(t, err)
+	}
+
+	clientSecret, err := secretsSrv.Encrypt(ctx, []byte("client secret"), secrets.WithoutScope())
+	require.NoError(t, err)
+	require.NoError(t, sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.Insert(&models.SSOSettings{
+			ID:       "sso-1",
+			Provider: "github",
+			Settings: map[string]any{
+				"clientId":     "client id",
+				"clientSecret": base64.RawStdEncoding.EncodeToString(clientSecret),
+			},
+			Created: time.Now(),
+			Updated: time.Now(),
+		})
+		return err
+	}))
+
+	// the data keys aren't rotated before the interval
+	s.rotateAndReEncrypt(ctx)
+	require.Equal(t, 0.0, testutil.ToFloat64(s.metrics.rotations))
+	oldKeys := dataKeyIDs(t, store, true)
+	require.Len(t, oldKeys, 1)
+
+	t.Run("should rotate the data keys and re-encrypt a batch", func(t *testing.T) {
+		s.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+		s.rotateAndReEncrypt(ctx)
+		s.now = time.Now
+
+		require.Equal(t, 1.0, testutil.ToFloat64(s.metrics.rotations))
+		require.Empty(t, intersect(oldKeys, dataKeyIDs(t, store, true)))
+		require.Equal(t, 2.0, testutil.ToFloat64(s.metrics.reEncrypted.WithLabelValues("data_source", "true")))
+		// the run stops once the batch is spent
+		require.Equal(t, 0.0, testutil.ToFloat64(s.metrics.reEncrypted.WithLabelValues("sso_setting", "true")))
+	})
+
+	t.Run("should re-encrypt the next batch", func(t *testing.T) {
+		s.rotateAndReEncrypt(ctx)
+
+		require.Equal(t, 1.0, testutil.ToFloat64(s.metrics.rotations))
+		require.Equal(t, 3.0, testutil.ToFloat64(s.metrics.reEncrypted.WithLabelValues("data_source", "true")))
+		require.Equal(t, 1.0, testutil.ToFloat64(s.metrics.reEncrypted.WithLabelValues("sso_setting", "true")))
+		require.Equal(t, 0.0, testutil.ToFloat64(s.metrics.pending.WithLabelValues("data_source")))
+		require.Equal(t, 0.0, testutil.ToFloat64(s.metrics.pending.WithLabelValues("sso_setting")))
+
+		for i := 0; i < 3; i++ {
+			ds, err := dsStore.GetDataSource(ctx, &datasources.GetDataSourceQuery{OrgID: 1, Name: fmt.Sprintf("ds-%d", i)})
+			require.NoError(t, err)
+			id, ok := manager.DataKeyID(ds.SecureJsonData["password"])
+			require.True(t, ok)
+			require.NotContains(t, oldKeys, id)
+
+			decrypted, err := secretsSrv.DecryptJsonData(ctx, ds.SecureJsonData)
+			require.NoError(t, err)
+			require.Equal(t, fmt.Sprintf("password-%d", i), decrypted["password"])
+		}
+
+		var settings string
+		_, err := sqlStore.GetEngine().Table("sso_setting").Where("id = ?", "sso-1").Cols("settings").Get(&settings)
+		require.NoError(t, err)
+		var parsed map[string]any
+		require.NoError(t, json.Unmarshal([]byte(settings), &parsed))
+		require.Equal(t, "client id", parsed["clientId"])
+		encrypted, err := base64.RawStdEncoding.DecodeString(parsed["clientSecret"].(string))
+		require.NoError(t, err)
+		decrypted, err := secretsSrv.Decrypt(ctx, encrypted)
+		require.NoError(t, err)
+		require.Equal(t, "client secret", string(decrypted))
+	})
+}
+
+func TestIntegrationReEncryptFailures(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	sqlStore, cfg := db.InitTestDBWithCfg(t)
+	cfg.Raw.Section("security.encryption").Key("data_keys_rotation_interval").SetValue("24h")
+
+	store := database.ProvideSecretsStore(sqlStore)
+	secretsSrv := manager.SetupTestService(t, store)
+	s := ProvideService(cfg, secretsSrv, store, sqlStore, serverlock.ProvideService(sqlStore, tracing.InitializeTracerForTest()),
+		featuremgmt.WithFeatures(), prometheus.NewRegistry())
+
+	sjd, err := secretsSrv.EncryptJsonData(ctx, map[string]string{"password": "password"}, secrets.WithoutScope())
+	require.NoError(t, err)
+	oldKey, ok := manager.DataKeyID(sjd["password"])
+	require.True(t, ok)
+	require.NoError(t, secretsSrv.RotateDataKeys(ctx))
+
+	dsStore := dsservice.CreateStore(sqlStore, log.NewNopLogger())
+	for name, sjd := range map[string]map[string][]byte{
+		"ds":     sjd,
+		"broken": {"password": []byte("#" + base64.RawStdEncoding.EncodeToString([]byte(oldKey)) + "#not a ciphertext")},
+	} {
+		_, err := dsStore.AddDataSource(ctx, &datasources.AddDataSourceCommand{
+			OrgID:                   1,
+			Name:                    name,
+			Type:                    datasources.DS_PROMETHEUS,
+			Access:                  datasources.DS_ACCESS_PROXY,
+			EncryptedSecureJsonData: sjd,
+		})
+		require.NoError(t, err)
+	}
+
+	failures := func() float64 {
+		return testutil.ToFloat64(s.metrics.reEncrypted.WithLabelValues("data_source", "false"))
+	}
+
+	s.rotateAndReEncrypt(ctx)
+	require.Equal(t, 1.0, testutil.ToFloat64(s.metrics.reEncrypted.WithLabelValues("data_source", "true")))
+	require.Equal(t, 1.0, failures())
+	require.Equal(t, 1.0, testutil.ToFloat64(s.metrics.pending.WithLabelValues("data_source")))
+
+	t.Run("should not scan the table again before the failure is retried", func(t *testing.T) {
+		s.rotateAndReEncrypt(ctx)
+		require.Equal(t, 1.0, failures())
+	})
+
+	t.Run("should retry the failure after a backoff that doubles", func(t *testing.T) {
+		start := time.Now()
+		s.now = func() time.Time { return start.Add(minRetryBackoff + time.Minute) }
+		s.rotateAndReEncrypt(ctx)
+		require.Equal(t, 2.0, failures())
+
+		s.now = func() time.Time { return start.Add(2*minRetryBackoff + 2*time.Minute) }
+		s.rotateAndReEncrypt(ctx)
+		require.Equal(t, 2.0, failures())
+
+		s.now = func() time.Time { return start.Add(3*minRetryBackoff + 2*time.Minute) }
+		s.rotateAndReEncrypt(ctx)
+		require.Equal(t, 3.0, failures())
+		require.Equal(t, 1.0, testutil.ToFloat64(s.metrics.pending.WithLabelValues("data_source")))
+	})
+}
+
+func TestJSONDataTable_ReEncryptChangedRow(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	sqlStore := db.InitTestDB(t)
+	secretsSrv := manager.SetupTestService(t, database.ProvideSecretsStore(sqlStore))
+
+	sjd, err := secretsSrv.EncryptJsonData(ctx, map[string]string{"password": "before"}, secrets.WithoutScope())
+	require.NoError(t, err)
+	ds, err := dsservice.CreateStore(sqlStore, log.NewNopLogger()).AddDataSource(ctx, &datasources.AddDataSourceCommand{
+		OrgID:                   1,
+		Name:                    "ds",
+		Type:                    datasources.DS_PROMETHEUS,
+		Access:                  datasources.DS_ACCESS_PROXY,
+		EncryptedSecureJsonData: sjd,
+	})
+	require.NoError(t, err)
+
+	table := jsonDataTable{tableName: "data_source"}
+	rows, err := table.page(ctx, sqlStore, nil, 10)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+
+	// the secrets saved after the row was read aren't overwritten
+	_, err = sqlStore.GetEngine().Exec("UPDATE data_source SET secure_json_data = ? WHERE id = ?", "{}", ds.ID)
+	require.NoError(t, err)
+	updated, err := table.reEncrypt(ctx, secretsSrv, sqlStore, rows[0])
+	require.NoError(t, err)
+	require.False(t, updated)
+}
+
+func dataKeyIDs(t *testing.T, store secrets.Store, active bool) []string {
+	t.Helper()
+	keys, err := store.GetAllDataKeys(context.Background())
+	require.NoError(t, err)
+
+	var ids []string
+	for _, key := range keys {
+		if key.Active == active {
+			ids = append(ids, key.Id)
+		}
+	}
+	return ids
+}
+
+func intersect(a, b []string) []string {
+	var res []string
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				res = append(res, x)
+			}
+		}
+	}
+	return res
+}
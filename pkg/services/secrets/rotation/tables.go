@@ -0,0 +1,282 @@
+package rotation
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/secrets/manager"
+	"github.com/grafana/grafana/pkg/services/ssosettings/ssosettingsimpl"
+)
+
+// secretTable is a table whose secrets are re-encrypted when they are encrypted with a rotated data key. The rows
+// are updated only if they didn't change since they were read, so that the secrets saved in the meantime aren't
+// overwritten.
+type secretTable interface {
+	name() string
+	// page returns at most limit rows ordered by id, after the row with the given id unless it's nil
+	page(ctx context.Context, sqlStore db.DB, after any, limit int) ([]row, error)
+	// keyIDs returns the ids of the data keys the secrets of the row are encrypted with
+	keyIDs(r row) ([]string, error)
+	// reEncrypt encrypts the secrets of the row with the current data key, it returns false if the row changed
+	reEncrypt(ctx context.Context, secretsSrv *manager.SecretsService, sqlStore db.DB, r row) (bool, error)
+}
+
+type row struct {
+	id    any
+	value string
+	// version is compared when updating the row, for the tables with a version column
+	version string
+}
+
+func page[ID any](ctx context.Context, sqlStore db.DB, table, columns, notNull string, after any, limit int) ([]row, error) {
+	var results []struct {
+		Id      ID
+		Value   string
+		Version string
+	}
+	err := sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+		q := sess.Table(table).Select(columns).Where(notNull + " IS NOT NULL")
+		if after != nil {
+			q = q.And("id > ?", after)
+		}
+		return q.OrderBy("id").Limit(limit).Find(&results)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]row, 0, len(results))
+	for _, r := range results {
+		rows = append(rows, row{id: r.Id, value: r.Value, version: r.Version})
+	}
+	return rows, nil
+}
+
+func update(ctx context.Context, sqlStore db.DB, sql string, args ...any) (bool, error) {
+	var affected int64
+	err := sqlStore.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec(append([]any{sql}, args...)...)
+		if err != nil {
+			return err
+		}
+		affected, err = res.RowsAffected()
+		return err
+	})
+	return affected == 1, err
+}
+
+func nowInUTC() string {
+	return time.Now().UTC().Format("2006-01-02 15:04:05")
+}
+
+// jsonDataTable is a table with a secure_json_data column, like data_source and plugin_setting
+type jsonDataTable struct {
+	tableName string
+}
+
+func (t jsonDataTable) name() string {
+	return t.tableName
+}
+
+func (t jsonDataTable) page(ctx context.Context, sqlStore db.DB, after any, limit int) ([]row, error) {
+	return page[int64](ctx, sqlStore, t.tableName, "id, secure_json_data AS value", "secure_json_data", after, limit)
+}
+
+func (t jsonDataTable) keyIDs(r row) ([]string, error) {
+	var sjd map[string][]byte
+	if err := json.Unmarshal([]byte(r.value), &sjd); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(sjd))
+	for _, v := range sjd {
+		if id, ok := manager.DataKeyID(v); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (t jsonDataTable) reEncrypt(ctx context.Context, secretsSrv *manager.SecretsService, sqlStore db.DB, r row) (bool, error) {
+	var sjd map[string][]byte
+	if err := json.Unmarshal([]byte(r.value), &sjd); err != nil {
+		return false, err
+	}
+
+	decrypted, err := secretsSrv.DecryptJsonData(ctx, sjd)
+	if err != nil {
+		return false, fmt.Errorf("could not decrypt secrets: %w", err)
+	}
+	encrypted, err := secretsSrv.EncryptJsonData(ctx, decrypted, secrets.WithoutScope())
+	if err != nil {
+		return false, fmt.Errorf("could not encrypt secrets: %w", err)
+	}
+	value, err := json.Marshal(encrypted)
+	if err != nil {
+		return false, err
+	}
+
+	updateSQL := fmt.Sprintf("UPDATE %s SET secure_json_data = ?, updated = ? WHERE id = ? AND secure_json_data = ?", t.tableName)
+	return update(ctx, sqlStore, updateSQL, string(value), nowInUTC(), r.id, r.value)
+}
+
+// alertingTable holds the secure settings of the receivers of the Alertmanager configurations
+type alertingTable struct{}
+
+func (alertingTable) name() string {
+	return "alert_configuration"
+}
+
+func (t alertingTable) page(ctx context.Context, sqlStore db.DB, after any, limit int) ([]row, error) {
+	return page[int64](ctx, sqlStore, t.name(), "id, alertmanager_configuration AS value, configuration_hash AS version", "alertmanager_configuration", after, limit)
+}
+
+// walkSecureSettings calls fn with the decoded value of the secure settings of the receivers, and replaces them with
+// the result when it isn't nil
+func (alertingTable) walkSecureSettings(value string, fn func(encrypted []byte) ([]byte, error)) ([]byte, error) {
+	cfg, err := notifier.Load([]byte(value))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, receiver := range cfg.AlertmanagerConfig.Receivers {
+		for _, gmr := range receiver.GrafanaManagedReceivers {
+			for k, v := range gmr.SecureSettings {
+				decoded, err := base64.StdEncoding.DecodeString(v)
+				if err != nil {
+					return nil, fmt.Errorf("could not decode secure setting %s of receiver %s: %w", k, gmr.UID, err)
+				}
+				res, err := fn(decoded)
+				if err != nil {
+					return nil, fmt.Errorf("secure setting %s of receiver %s: %w", k, gmr.UID, err)
+				}
+				if res != nil {
+					gmr.SecureSettings[k] = base64.StdEncoding.EncodeToString(res)
+				}
+			}
+		}
+	}
+
+	return json.Marshal(cfg)
+}
+
+func (t alertingTable) keyIDs(r row) ([]string, error) {
+	var ids []string
+	_, err := t.walkSecureSettings(r.value, func(encrypted []byte) ([]byte, error) {
+		if id, ok := manager.DataKeyID(encrypted); ok {
+			ids = append(ids, id)
+		}
+		return nil, nil
+	})
+	return ids, err
+}
+
+func (t alertingTable) reEncrypt(ctx context.Context, secretsSrv *manager.SecretsService, sqlStore db.DB, r row) (bool, error) {
+	value, err := t.walkSecureSettings(r.value, func(encrypted []byte) ([]byte, error) {
+		decrypted, err := secretsSrv.Decrypt(ctx, encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("could not decrypt: %w", err)
+		}
+		return secretsSrv.Encrypt(ctx, decrypted, secrets.WithoutScope())
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// the hash is left as is, like when secrets are re-encrypted by the CLI, so that the configurations fetched
+	// before can still be saved
+	return update(ctx, sqlStore, "UPDATE alert_configuration SET alertmanager_configuration = ? WHERE id = ? AND configuration_hash = ?", string(value), r.id, r.version)
+}
+
+// ssoSettingsTable holds the secret fields of the SSO settings, at any depth of the settings
+type ssoSettingsTable struct{}
+
+func (ssoSettingsTable) name() string {
+	return "sso_setting"
+}
+
+func (t ssoSettingsTable) page(ctx context.Context, sqlStore db.DB, after any, limit int) ([]row, error) {
+	return page[string](ctx, sqlStore, t.name(), "id, settings AS value", "settings", after, limit)
+}
+
+// walkSecrets calls fn with the decoded value of the secret fields, and replaces them with the result when it isn't nil
+func (t ssoSettingsTable) walkSecrets(v any, fn func(encrypted []byte) ([]byte, error)) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, inner := range v {
+			if s, ok := inner.(string); ok && ssosettingsimpl.IsSecretField(k) {
+				if s == "" {
+					continue
+				}
+				decoded, err := base64.RawStdEncoding.DecodeString(s)
+				if err != nil {
+					return fmt.Errorf("could not decode secret field %s: %w", k, err)
+				}
+				res, err := fn(decoded)
+				if err != nil {
+					return fmt.Errorf("secret field %s: %w", k, err)
+				}
+				if res != nil {
+					v[k] = base64.RawStdEncoding.EncodeToString(res)
+				}
+				continue
+			}
+			if err := t.walkSecrets(inner, fn); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, inner := range v {
+			if err := t.walkSecrets(inner, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t ssoSettingsTable) keyIDs(r row) ([]string, error) {
+	var settings map[string]any
+	if err := json.Unmarshal([]byte(r.value), &settings); err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	err := t.walkSecrets(settings, func(encrypted []byte) ([]byte, error) {
+		if id, ok := manager.DataKeyID(encrypted); ok {
+			ids = append(ids, id)
+		}
+		return nil, nil
+	})
+	return ids, err
+}
+
+func (t ssoSettingsTable) reEncrypt(ctx context.Context, secretsSrv *manager.SecretsService, sqlStore db.DB, r row) (bool, error) {
+	var settings map[string]any
+	if err := json.Unmarshal([]byte(r.value), &settings); err != nil {
+		return false, err
+	}
+
+	err := t.walkSecrets(settings, func(encrypted []byte) ([]byte, error) {
+		decrypted, err := secretsSrv.Decrypt(ctx, encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("could not decrypt: %w", err)
+		}
+		return secretsSrv.Encrypt(ctx, decrypted, secrets.WithoutScope())
+	})
+	if err != nil {
+		return false, err
+	}
+	value, err := json.Marshal(settings)
+	if err != nil {
+		return false, err
+	}
+
+	return update(ctx, sqlStore, "UPDATE sso_setting SET settings = ?, updated = ? WHERE id = ? AND settings = ?", string(value), nowInUTC(), r.id, r.value)
+}
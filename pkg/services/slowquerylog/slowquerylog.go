@@ -0,0 +1,71 @@
+package slowquerylog
+
+import (
+	"context"
+	"time"
+)
+
+// Service logs the data source queries taking longer than the configured threshold, with the
+// dashboard, panel and user that issued them.
+type Service interface {
+	// Record logs a slow query. It doesn't wait for the query to be stored and drops it when
+	// the log can't keep up.
+	Record(ctx context.Context, query *SlowQuery)
+	// GetTopPanels returns the panels that spent the most time in slow queries
+	GetTopPanels(ctx context.Context, query GetTopQuery) ([]*PanelLoad, error)
+	// GetTopDatasources returns the data sources that spent the most time in slow queries
+	GetTopDatasources(ctx context.Context, query GetTopQuery) ([]*DatasourceLoad, error)
+}
+
+// SlowQuery is a QueryData call that took longer than the threshold.
+type SlowQuery struct {
+	OrgID          int64
+	DatasourceUID  string
+	DatasourceType string
+	// DashboardUID and PanelID are empty for queries not issued by a dashboard panel,
+	// for example from Explore or alert rules.
+	DashboardUID string
+	PanelID      int64
+	UserLogin    string
+	// QueryCount is the number of queries of the call
+	QueryCount    int
+	Duration      time.Duration
+	ResponseBytes int64
+	Status        string
+	Created       time.Time
+}
+
+type GetTopQuery struct {
+	// OrgID restricts the results to an organization, 0 returns all of them
+	OrgID int64
+	Since time.Time
+	Limit int
+}
+
+// PanelLoad sums the slow queries of a dashboard panel.
+type PanelLoad struct {
+	OrgID              int64     `json:"orgId"`
+	DashboardUID       string    `json:"dashboardUid"`
+	PanelID            int64     `json:"panelId"`
+	Count              int64     `json:"count"`
+	TotalDurationMs    int64     `json:"totalDurationMs"`
+	AvgDurationMs      int64     `json:"avgDurationMs"`
+	MaxDurationMs      int64     `json:"maxDurationMs"`
+	TotalResponseBytes int64     `json:"totalResponseBytes"`
+	Users              int64     `json:"users"`
+	LastSeen           time.Time `json:"lastSeen"`
+}
+
+// DatasourceLoad sums the slow queries of a data source.
+type DatasourceLoad struct {
+	OrgID              int64     `json:"orgId"`
+	DatasourceUID      string    `json:"datasourceUid"`
+	DatasourceType     string    `json:"datasourceType"`
+	Count              int64     `json:"count"`
+	TotalDurationMs    int64     `json:"totalDurationMs"`
+	AvgDurationMs      int64     `json:"avgDurationMs"`
+	MaxDurationMs      int64     `json:"maxDurationMs"`
+	TotalResponseBytes int64     `json:"totalResponseBytes"`
+	Dashboards         int64     `json:"dashboards"`
+	LastSeen           time.Time `json:"lastSeen"`
+}
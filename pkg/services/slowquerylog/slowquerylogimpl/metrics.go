@@ -0,0 +1,41 @@
+package slowquerylogimpl
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsSubSystem = "slow_query_log"
+	metricsNamespace = "grafana"
+)
+
+type metrics struct {
+	recorded *prometheus.CounterVec
+	dropped  prometheus.Counter
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	m := &metrics{
+		recorded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "recorded_total",
+			Help:      "Number of data source queries taking longer than the slow query threshold",
+		}, []string{"datasource_type"}),
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubSystem,
+			Name:      "dropped_total",
+			Help:      "Number of slow queries not stored because the slow query log couldn't keep up",
+		}),
+	}
+
+	if reg != nil {
+		reg.MustRegister(
+			m.recorded,
+			m.dropped,
+		)
+	}
+
+	return m
+}
@@ -0,0 +1,45 @@
+package slowquerylogimpl
+
+// slowQuery is a row of the slow_query table.
+type slowQuery struct {
+	ID             int64  `xorm:"pk autoincr 'id'"`
+	OrgID          int64  `xorm:"org_id"`
+	DatasourceUID  string `xorm:"datasource_uid"`
+	DatasourceType string `xorm:"datasource_type"`
+	DashboardUID   string `xorm:"dashboard_uid"`
+	PanelID        int64  `xorm:"panel_id"`
+	UserLogin      string `xorm:"user_login"`
+	QueryCount     int64  `xorm:"query_count"`
+	DurationMs     int64  `xorm:"duration_ms"`
+	ResponseBytes  int64  `xorm:"response_bytes"`
+	Status         string `xorm:"status"`
+	Created        int64  `xorm:"created"`
+}
+
+func (slowQuery) TableName() string {
+	return "slow_query"
+}
+
+type panelLoad struct {
+	OrgID              int64  `xorm:"org_id"`
+	DashboardUID       string `xorm:"dashboard_uid"`
+	PanelID            int64  `xorm:"panel_id"`
+	Count              int64  `xorm:"count"`
+	TotalDurationMs    int64  `xorm:"total_duration_ms"`
+	MaxDurationMs      int64  `xorm:"max_duration_ms"`
+	TotalResponseBytes int64  `xorm:"total_response_bytes"`
+	Users              int64  `xorm:"users"`
+	LastSeen           int64  `xorm:"last_seen"`
+}
+
+type datasourceLoad struct {
+	OrgID              int64  `xorm:"org_id"`
+	DatasourceUID      string `xorm:"datasource_uid"`
+	DatasourceType     string `xorm:"datasource_type"`
+	Count              int64  `xorm:"count"`
+	TotalDurationMs    int64  `xorm:"total_duration_ms"`
+	MaxDurationMs      int64  `xorm:"max_duration_ms"`
+	TotalResponseBytes int64  `xorm:"total_response_bytes"`
+	Dashboards         int64  `xorm:"dashboards"`
+	LastSeen           int64  `xorm:"last_seen"`
+}
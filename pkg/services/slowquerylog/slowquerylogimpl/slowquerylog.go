@@ -0,0 +1,210 @@
+package slowquerylogimpl
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/slowquerylog"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	// queueSize is the number of slow queries waiting to be stored, more are dropped
+	queueSize = 1000
+	// batchSize is the maximum number of slow queries stored at once
+	batchSize     = 100
+	flushInterval = 5 * time.Second
+	trimInterval  = 10 * time.Minute
+
+	defaultTopLimit = 10
+	maxTopLimit     = 100
+)
+
+var _ slowquerylog.Service = (*Service)(nil)
+
+func ProvideService(db db.DB, cfg *setting.Cfg, reg prometheus.Registerer) *Service {
+	return newService(&xormStore{db: db}, cfg.SlowQueryLog, reg)
+}
+
+func newService(store store, settings setting.SlowQueryLogSettings, reg prometheus.Registerer) *Service {
+	return &Service{
+		store:    store,
+		settings: settings,
+		logger:   log.New("slow_query_log"),
+		queue:    make(chan *slowQuery, queueSize),
+		metrics:  newMetrics(reg),
+		now:      time.Now,
+	}
+}
+
+type Service struct {
+	store    store
+	settings setting.SlowQueryLogSettings
+	logger   log.Logger
+	queue    chan *slowQuery
+	metrics  *metrics
+	now      func() time.Time
+}
+
+func (s *Service) IsDisabled() bool {
+	return !s.settings.Enabled
+}
+
+// Run stores the recorded slow queries in batches and keeps the number of rows
+// below the configured maximum.
+func (s *Service) Run(ctx context.Context) error {
+	flush := time.NewTicker(flushInterval)
+	defer flush.Stop()
+	trim := time.NewTicker(trimInterval)
+	defer trim.Stop()
+
+	s.trim(ctx)
+
+	batch := make([]*slowQuery, 0, batchSize)
+	for {
+		select {
+		case q := <-s.queue:
+			batch = append(batch, q)
+			if len(batch) >= batchSize {
+				s.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-flush.C:
+			s.flush(ctx, batch)
+			batch = batch[:0]
+		case <-trim.C:
+			s.trim(ctx)
+		case <-ctx.Done():
+			// The server is shutting down, store what was recorded so far.
+			for len(s.queue) > 0 {
+				batch = append(batch, <-s.queue)
+			}
+			s.flush(context.WithoutCancel(ctx), batch)
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Service) Record(ctx context.Context, query *slowquerylog.SlowQuery) {
+	if !s.settings.Enabled || query == nil {
+		return
+	}
+
+	created := query.Created
+	if created.IsZero() {
+		created = s.now()
+	}
+	row := &slowQuery{
+		OrgID:          query.OrgID,
+		DatasourceUID:  query.DatasourceUID,
+		DatasourceType: query.DatasourceType,
+		DashboardUID:   query.DashboardUID,
+		PanelID:        query.PanelID,
+		UserLogin:      query.UserLogin,
+		QueryCount:     int64(query.QueryCount),
+		DurationMs:     query.Duration.Milliseconds(),
+		ResponseBytes:  query.ResponseBytes,
+		Status:         query.Status,
+		Created:        created.Unix(),
+	}
+
+	s.metrics.recorded.WithLabelValues(query.DatasourceType).Inc()
+	select {
+	case s.queue <- row:
+	default:
+		s.metrics.dropped.Inc()
+		s.logger.FromContext(ctx).Debug("Slow query log queue is full, dropping query", "datasourceUid", query.DatasourceUID, "dashboardUid", query.DashboardUID, "panelId", query.PanelID)
+	}
+}
+
+func (s *Service) GetTopPanels(ctx context.Context, query slowquerylog.GetTopQuery) ([]*slowquerylog.PanelLoad, error) {
+	rows, err := s.store.GetTopPanels(ctx, s.normalizeTopQuery(query))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*slowquerylog.PanelLoad, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, &slowquerylog.PanelLoad{
+			OrgID:              r.OrgID,
+			DashboardUID:       r.DashboardUID,
+			PanelID:            r.PanelID,
+			Count:              r.Count,
+			TotalDurationMs:    r.TotalDurationMs,
+			AvgDurationMs:      average(r.TotalDurationMs, r.Count),
+			MaxDurationMs:      r.MaxDurationMs,
+			TotalResponseBytes: r.TotalResponseBytes,
+			Users:              r.Users,
+			LastSeen:           time.Unix(r.LastSeen, 0),
+		})
+	}
+	return result, nil
+}
+
+func (s *Service) GetTopDatasources(ctx context.Context, query slowquerylog.GetTopQuery) ([]*slowquerylog.DatasourceLoad, error) {
+	rows, err := s.store.GetTopDatasources(ctx, s.normalizeTopQuery(query))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*slowquerylog.DatasourceLoad, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, &slowquerylog.DatasourceLoad{
+			OrgID:              r.OrgID,
+			DatasourceUID:      r.DatasourceUID,
+			DatasourceType:     r.DatasourceType,
+			Count:              r.Count,
+			TotalDurationMs:    r.TotalDurationMs,
+			AvgDurationMs:      average(r.TotalDurationMs, r.Count),
+			MaxDurationMs:      r.MaxDurationMs,
+			TotalResponseBytes: r.TotalResponseBytes,
+			Dashboards:         r.Dashboards,
+			LastSeen:           time.Unix(r.LastSeen, 0),
+		})
+	}
+	return result, nil
+}
+
+func (s *Service) normalizeTopQuery(query slowquerylog.GetTopQuery) slowquerylog.GetTopQuery {
+	if query.Limit <= 0 {
+		query.Limit = defaultTopLimit
+	}
+	if query.Limit > maxTopLimit {
+		query.Limit = maxTopLimit
+	}
+	if query.Since.IsZero() {
+		query.Since = s.now().Add(-24 * time.Hour)
+	}
+	return query
+}
+
+func (s *Service) flush(ctx context.Context, batch []*slowQuery) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := s.store.Insert(ctx, batch); err != nil {
+		s.logger.Error("Failed to store slow queries", "count", len(batch), "error", err)
+	}
+}
+
+func (s *Service) trim(ctx context.Context) {
+	deleted, err := s.store.DeleteOldest(ctx, s.settings.MaxRows)
+	if err != nil {
+		s.logger.Error("Failed to delete the oldest slow queries", "error", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger.Debug("Deleted the oldest slow queries", "count", deleted)
+	}
+}
+
+func average(total, count int64) int64 {
+	if count == 0 {
+		return 0
+	}
+	return total / count
+}
@@ -0,0 +1,134 @@
+package slowquerylogimpl
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/slowquerylog"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestService(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	newTestService := func(store *fakeStore, enabled bool) *Service {
+		s := newService(store, setting.SlowQueryLogSettings{Enabled: enabled, Threshold: time.Second, MaxRows: 10}, nil)
+		s.now = func() time.Time { return now }
+		return s
+	}
+
+	t.Run("stores the recorded queries and trims the table", func(t *testing.T) {
+		store := &fakeStore{}
+		s := newTestService(store, true)
+
+		s.Record(context.Background(), &slowquerylog.SlowQuery{
+			OrgID:          1,
+			DatasourceUID:  "prom",
+			DatasourceType: "prometheus",
+			DashboardUID:   "dash",
+			PanelID:        2,
+			UserLogin:      "alice",
+			QueryCount:     3,
+			Duration:       1500 * time.Millisecond,
+			ResponseBytes:  100,
+			Status:         "ok",
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- s.Run(ctx) }()
+		require.Eventually(t, func() bool { return store.trimmed() > 0 }, time.Second, time.Millisecond)
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+
+		require.Len(t, store.rows(), 1)
+		assert.Equal(t, &slowQuery{
+			OrgID:          1,
+			DatasourceUID:  "prom",
+			DatasourceType: "prometheus",
+			DashboardUID:   "dash",
+			PanelID:        2,
+			UserLogin:      "alice",
+			QueryCount:     3,
+			DurationMs:     1500,
+			ResponseBytes:  100,
+			Status:         "ok",
+			Created:        now.Unix(),
+		}, store.rows()[0])
+	})
+
+	t.Run("drops queries when the queue is full", func(t *testing.T) {
+		s := newTestService(&fakeStore{}, true)
+		for i := 0; i < queueSize+10; i++ {
+			s.Record(context.Background(), &slowquerylog.SlowQuery{})
+		}
+		assert.Len(t, s.queue, queueSize)
+	})
+
+	t.Run("ignores queries when disabled", func(t *testing.T) {
+		s := newTestService(&fakeStore{}, false)
+		s.Record(context.Background(), &slowquerylog.SlowQuery{})
+		assert.Empty(t, s.queue)
+		assert.True(t, s.IsDisabled())
+	})
+
+	t.Run("defaults and bounds the top queries", func(t *testing.T) {
+		store := &fakeStore{}
+		s := newTestService(store, true)
+
+		_, err := s.GetTopPanels(context.Background(), slowquerylog.GetTopQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, slowquerylog.GetTopQuery{Since: now.Add(-24 * time.Hour), Limit: defaultTopLimit}, store.lastTopQuery)
+
+		_, err = s.GetTopDatasources(context.Background(), slowquerylog.GetTopQuery{OrgID: 1, Limit: 1000})
+		require.NoError(t, err)
+		assert.Equal(t, slowquerylog.GetTopQuery{OrgID: 1, Since: now.Add(-24 * time.Hour), Limit: maxTopLimit}, store.lastTopQuery)
+	})
+}
+
+type fakeStore struct {
+	mu           sync.Mutex
+	inserted     []*slowQuery
+	trimCalls    int
+	lastTopQuery slowquerylog.GetTopQuery
+}
+
+func (f *fakeStore) Insert(ctx context.Context, rows []*slowQuery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inserted = append(f.inserted, rows...)
+	return nil
+}
+
+func (f *fakeStore) DeleteOldest(ctx context.Context, keep int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.trimCalls++
+	return 0, nil
+}
+
+func (f *fakeStore) GetTopPanels(ctx context.Context, query slowquerylog.GetTopQuery) ([]*panelLoad, error) {
+	f.lastTopQuery = query
+	return nil, nil
+}
+
+func (f *fakeStore) GetTopDatasources(ctx context.Context, query slowquerylog.GetTopQuery) ([]*datasourceLoad, error) {
+	f.lastTopQuery = query
+	return nil, nil
+}
+
+func (f *fakeStore) rows() []*slowQuery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.inserted
+}
+
+func (f *fakeStore) trimmed() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.trimCalls
+}
@@ -0,0 +1,127 @@
+package slowquerylogimpl
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/slowquerylog"
+)
+
+// deleteBatchSize bounds the number of rows deleted by a single statement
+const deleteBatchSize = 10000
+
+type store interface {
+	Insert(ctx context.Context, rows []*slowQuery) error
+	// DeleteOldest deletes the oldest rows so that at most keep rows remain
+	DeleteOldest(ctx context.Context, keep int) (int64, error)
+	GetTopPanels(ctx context.Context, query slowquerylog.GetTopQuery) ([]*panelLoad, error)
+	GetTopDatasources(ctx context.Context, query slowquerylog.GetTopQuery) ([]*datasourceLoad, error)
+}
+
+type xormStore struct {
+	db db.DB
+}
+
+func (xs *xormStore) Insert(ctx context.Context, rows []*slowQuery) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		_, err := sess.InsertMulti(&rows)
+		return err
+	})
+}
+
+func (xs *xormStore) DeleteOldest(ctx context.Context, keep int) (int64, error) {
+	var deleted int64
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		count, err := sess.Count(&slowQuery{})
+		if err != nil {
+			return err
+		}
+
+		for count-deleted > int64(keep) {
+			limit := min(count-deleted-int64(keep), deleteBatchSize)
+			res, err := sess.Exec(`DELETE FROM slow_query
+				WHERE id IN (
+					SELECT id FROM (
+						SELECT id FROM slow_query
+						ORDER BY id ASC
+						LIMIT ?
+					) AS q
+				)`, limit)
+			if err != nil {
+				return err
+			}
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if affected == 0 {
+				break
+			}
+			deleted += affected
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+func (xs *xormStore) GetTopPanels(ctx context.Context, query slowquerylog.GetTopQuery) ([]*panelLoad, error) {
+	sql := strings.Builder{}
+	params := []any{query.Since.Unix()}
+	sql.WriteString(`SELECT org_id, dashboard_uid, panel_id,
+			COUNT(*) AS count,
+			SUM(duration_ms) AS total_duration_ms,
+			MAX(duration_ms) AS max_duration_ms,
+			SUM(response_bytes) AS total_response_bytes,
+			COUNT(DISTINCT user_login) AS users,
+			MAX(created) AS last_seen
+		FROM slow_query
+		WHERE created >= ? AND dashboard_uid <> ''`)
+	if query.OrgID != 0 {
+		sql.WriteString(` AND org_id = ?`)
+		params = append(params, query.OrgID)
+	}
+	sql.WriteString(`
+		GROUP BY org_id, dashboard_uid, panel_id
+		ORDER BY total_duration_ms DESC, org_id, dashboard_uid, panel_id
+		LIMIT ?`)
+	params = append(params, query.Limit)
+
+	result := make([]*panelLoad, 0)
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.SQL(sql.String(), params...).Find(&result)
+	})
+	return result, err
+}
+
+func (xs *xormStore) GetTopDatasources(ctx context.Context, query slowquerylog.GetTopQuery) ([]*datasourceLoad, error) {
+	sql := strings.Builder{}
+	params := []any{query.Since.Unix()}
+	sql.WriteString(`SELECT org_id, datasource_uid, datasource_type,
+			COUNT(*) AS count,
+			SUM(duration_ms) AS total_duration_ms,
+			MAX(duration_ms) AS max_duration_ms,
+			SUM(response_bytes) AS total_response_bytes,
+			COUNT(DISTINCT NULLIF(dashboard_uid, '')) AS dashboards,
+			MAX(created) AS last_seen
+		FROM slow_query
+		WHERE created >= ?`)
+	if query.OrgID != 0 {
+		sql.WriteString(` AND org_id = ?`)
+		params = append(params, query.OrgID)
+	}
+	sql.WriteString(`
+		GROUP BY org_id, datasource_uid, datasource_type
+		ORDER BY total_duration_ms DESC, org_id, datasource_uid
+		LIMIT ?`)
+	params = append(params, query.Limit)
+
+	result := make([]*datasourceLoad, 0)
+	err := xs.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.SQL(sql.String(), params...).Find(&result)
+	})
+	return result, err
+}
@@ -0,0 +1,113 @@
+package slowquerylogimpl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/slowquerylog"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func TestIntegrationSlowQueryStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	s := &xormStore{db: db.InitTestDB(t)}
+
+	row := func(dashboardUID string, panelID int64, datasourceUID string, user string, duration time.Duration, age time.Duration) *slowQuery {
+		return &slowQuery{
+			OrgID:          1,
+			DatasourceUID:  datasourceUID,
+			DatasourceType: "prometheus",
+			DashboardUID:   dashboardUID,
+			PanelID:        panelID,
+			UserLogin:      user,
+			QueryCount:     1,
+			DurationMs:     duration.Milliseconds(),
+			ResponseBytes:  100,
+			Status:         "ok",
+			Created:        now.Add(-age).Unix(),
+		}
+	}
+
+	require.NoError(t, s.Insert(ctx, []*slowQuery{
+		row("dash-a", 1, "prom", "alice", 10*time.Second, time.Minute),
+		row("dash-a", 1, "prom", "bob", 20*time.Second, time.Minute),
+		row("dash-a", 2, "prom", "alice", 6*time.Second, time.Minute),
+		row("dash-b", 1, "loki", "alice", 8*time.Second, time.Minute),
+		row("", 0, "loki", "alice", 30*time.Second, time.Minute),
+		// too old to be counted
+		row("dash-b", 1, "loki", "alice", time.Hour, 48*time.Hour),
+	}))
+
+	query := slowquerylog.GetTopQuery{Since: now.Add(-24 * time.Hour), Limit: 10}
+
+	t.Run("top panels", func(t *testing.T) {
+		panels, err := s.GetTopPanels(ctx, query)
+		require.NoError(t, err)
+		require.Len(t, panels, 3)
+
+		assert.Equal(t, "dash-a", panels[0].DashboardUID)
+		assert.Equal(t, int64(1), panels[0].PanelID)
+		assert.Equal(t, int64(2), panels[0].Count)
+		assert.Equal(t, int64(30000), panels[0].TotalDurationMs)
+		assert.Equal(t, int64(20000), panels[0].MaxDurationMs)
+		assert.Equal(t, int64(200), panels[0].TotalResponseBytes)
+		assert.Equal(t, int64(2), panels[0].Users)
+		assert.Equal(t, now.Add(-time.Minute).Unix(), panels[0].LastSeen)
+
+		assert.Equal(t, "dash-b", panels[1].DashboardUID)
+		assert.Equal(t, "dash-a", panels[2].DashboardUID)
+		assert.Equal(t, int64(2), panels[2].PanelID)
+	})
+
+	t.Run("top data sources", func(t *testing.T) {
+		datasources, err := s.GetTopDatasources(ctx, query)
+		require.NoError(t, err)
+		require.Len(t, datasources, 2)
+
+		assert.Equal(t, "loki", datasources[0].DatasourceUID)
+		assert.Equal(t, int64(38000), datasources[0].TotalDurationMs)
+		assert.Equal(t, int64(1), datasources[0].Dashboards)
+		assert.Equal(t, "prom", datasources[1].DatasourceUID)
+		assert.Equal(t, int64(3), datasources[1].Count)
+	})
+
+	t.Run("limit and organization", func(t *testing.T) {
+		panels, err := s.GetTopPanels(ctx, slowquerylog.GetTopQuery{Since: query.Since, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, panels, 1)
+
+		panels, err = s.GetTopPanels(ctx, slowquerylog.GetTopQuery{OrgID: 2, Since: query.Since, Limit: 10})
+		require.NoError(t, err)
+		require.Empty(t, panels)
+	})
+
+	t.Run("delete the oldest rows", func(t *testing.T) {
+		deleted, err := s.DeleteOldest(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), deleted)
+
+		deleted, err = s.DeleteOldest(ctx, 2)
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+
+		datasources, err := s.GetTopDatasources(ctx, slowquerylog.GetTopQuery{Since: time.Unix(0, 0), Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, datasources, 1)
+		assert.Equal(t, "loki", datasources[0].DatasourceUID)
+		assert.Equal(t, int64(2), datasources[0].Count)
+	})
+}
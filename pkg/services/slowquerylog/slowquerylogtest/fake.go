@@ -0,0 +1,33 @@
+package slowquerylogtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/grafana/grafana/pkg/services/slowquerylog"
+)
+
+var _ slowquerylog.Service = new(FakeService)
+
+type FakeService struct {
+	ExpectedPanels      []*slowquerylog.PanelLoad
+	ExpectedDatasources []*slowquerylog.DatasourceLoad
+	ExpectedErr         error
+
+	mu       sync.Mutex
+	Recorded []*slowquerylog.SlowQuery
+}
+
+func (f *FakeService) Record(ctx context.Context, query *slowquerylog.SlowQuery) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Recorded = append(f.Recorded, query)
+}
+
+func (f *FakeService) GetTopPanels(ctx context.Context, query slowquerylog.GetTopQuery) ([]*slowquerylog.PanelLoad, error) {
+	return f.ExpectedPanels, f.ExpectedErr
+}
+
+func (f *FakeService) GetTopDatasources(ctx context.Context, query slowquerylog.GetTopQuery) ([]*slowquerylog.DatasourceLoad, error) {
+	return f.ExpectedDatasources, f.ExpectedErr
+}
@@ -0,0 +1,98 @@
+package dbcopy
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"xorm.io/core"
+)
+
+// sqliteTimeLayouts are the layouts SQLite stores times with, the same as the ones of the SQLite driver
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	time.RFC3339Nano,
+}
+
+// ConvertValue converts a value read from a database to the type of a column of another database. SQLite stores booleans as
+// integers, and may return text as bytes and times as text depending on how they were written.
+func ConvertValue(value any, col *core.Column) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch {
+	case col.SQLType.Name == core.Bool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		case float64:
+			return v != 0, nil
+		case []byte:
+			return strconv.ParseBool(string(v))
+		case string:
+			return strconv.ParseBool(v)
+		}
+	case col.SQLType.IsTime():
+		switch v := value.(type) {
+		case time.Time:
+			return v, nil
+		case []byte:
+			return parseTime(string(v))
+		case string:
+			return parseTime(v)
+		case int64:
+			return time.Unix(v, 0).UTC(), nil
+		}
+	case col.SQLType.IsBlob():
+		switch v := value.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		}
+	case col.SQLType.IsText():
+		switch v := value.(type) {
+		case []byte:
+			// the PostgreSQL driver sends bytes as bytea, that text columns don't accept
+			return string(v), nil
+		case time.Time:
+			return v.Format("2006-01-02 15:04:05"), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+	case col.SQLType.IsNumeric():
+		switch v := value.(type) {
+		case bool:
+			if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		case []byte:
+			return string(v), nil
+		}
+	}
+	return value, nil
+}
+
+func parseTime(s string) (any, error) {
+	if s == "" {
+		return nil, nil
+	}
+	for _, layout := range sqliteTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid time %q", s)
+}
@@ -0,0 +1,353 @@
+// Package dbcopy copies the content of a Grafana database to a database of another type, like from SQLite to MySQL
+// or PostgreSQL. The tables of the target are created by the migrations of Grafana beforehand, the rows are copied
+// as they are, so the secrets stay encrypted with the same keys.
+package dbcopy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"xorm.io/core"
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+const (
+	migrationLogTable = "migration_log"
+	dataKeysTable     = "data_keys"
+	userTable         = "user"
+)
+
+var (
+	ErrTargetNotEmpty     = errors.New("the target database already contains Grafana data")
+	ErrMissingMigrations  = errors.New("the source database has migrations the target database doesn't have")
+	ErrMissingTables      = errors.New("the source database has tables with rows the target database doesn't have")
+	ErrVerificationFailed = errors.New("the copied data doesn't match the source database")
+	ErrUnsupportedTarget  = errors.New("the target database must be MySQL or PostgreSQL")
+)
+
+type Options struct {
+	// IgnoreMissingTables skips the tables of the source that don't exist in the target rather than failing, like the
+	// tables of plugins or of other Grafana versions
+	IgnoreMissingTables bool
+}
+
+// Report is the result of a copy
+type Report struct {
+	// Tables are the copied tables, in the order they were copied
+	Tables []TableReport
+	// Skipped are the tables of the source that don't exist in the target
+	Skipped []TableReport
+}
+
+type TableReport struct {
+	Name string
+	Rows int64
+}
+
+type copier struct {
+	src        *xorm.Engine
+	dst        *xorm.Engine
+	tx         *Tx
+	srcDialect migrator.Dialect
+	dstDialect migrator.Dialect
+	log        log.Logger
+}
+
+// EnsureEmpty fails when the target database already contains Grafana data. A target that only has the tables and
+// rows created by the migrations is accepted, like the target of a copy that failed and was rolled back, while the
+// first Grafana server started on a database creates its admin user.
+func EnsureEmpty(dst *xorm.Engine) error {
+	exists, err := dst.IsTableExist(userTable)
+	if err != nil || !exists {
+		return err
+	}
+	count, err := dst.Table(userTable).Count()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTargetNotEmpty
+	}
+	return nil
+}
+
+// Copy copies the rows of all the tables of src to dst, in the order of their foreign keys, then resets the
+// sequences of dst and verifies the number of rows of each table. The tables of dst must have been created by the
+// migrations of the same Grafana version than the one of src, the rows inserted by the migrations are replaced.
+// The rows are written in a single transaction, dst is left as it was when the copy fails.
+func Copy(ctx context.Context, src *xorm.Engine, dst *xorm.Engine, opts Options) (*Report, error) {
+	c := &copier{
+		src:        src,
+		dst:        dst,
+		srcDialect: migrator.NewDialect(src.DriverName()),
+		dstDialect: migrator.NewDialect(dst.DriverName()),
+		log:        log.New("dbcopy"),
+	}
+	if t := c.dstDialect.DriverName(); t != migrator.MySQL && t != migrator.Postgres {
+		return nil, ErrUnsupportedTarget
+	}
+
+	if err := c.checkMigrations(); err != nil {
+		return nil, err
+	}
+
+	tables, report, err := c.tables(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := c.dependencyOrder(ctx, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	c.tx, err = Begin(c.dst)
+	if err != nil {
+		return nil, err
+	}
+	defer c.tx.Close()
+
+	// delete the rows inserted by the migrations, dependents first
+	for i := len(order) - 1; i >= 0; i-- {
+		if err := c.tx.Empty(order[i]); err != nil {
+			return nil, fmt.Errorf("failed to empty table %s: %w", order[i], err)
+		}
+	}
+
+	for _, name := range order {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		rows, err := c.copyTable(ctx, tables[name].src, tables[name].dst)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy table %s: %w", name, err)
+		}
+		c.log.Info("Copied table", "table", name, "rows", rows)
+		report.Tables = append(report.Tables, TableReport{Name: name, Rows: rows})
+	}
+
+	for _, name := range order {
+		if err := c.tx.ResetSequence(tables[name].dst); err != nil {
+			return nil, fmt.Errorf("failed to reset the sequence of table %s: %w", name, err)
+		}
+	}
+
+	if err := c.verify(ctx, report.Tables); err != nil {
+		return nil, err
+	}
+
+	if err := c.tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit the copy: %w", err)
+	}
+	return report, nil
+}
+
+type tablePair struct {
+	src *core.Table
+	dst *core.Table
+}
+
+// tables returns the tables to copy by name, the migration log is left out, the target has its own
+func (c *copier) tables(ctx context.Context, opts Options) (map[string]tablePair, *Report, error) {
+	srcTables, err := c.src.DBMetas()
+	if err != nil {
+		return nil, nil, err
+	}
+	dstTables, err := c.dst.DBMetas()
+	if err != nil {
+		return nil, nil, err
+	}
+	dstByName := make(map[string]*core.Table, len(dstTables))
+	for _, t := range dstTables {
+		dstByName[t.Name] = t
+	}
+
+	report := &Report{}
+	tables := make(map[string]tablePair, len(srcTables))
+	var missing []string
+	for _, t := range srcTables {
+		if t.Name == "" || t.Name == migrationLogTable || strings.HasPrefix(t.Name, "sqlite_") {
+			continue
+		}
+		dst, ok := dstByName[t.Name]
+		if ok {
+			tables[t.Name] = tablePair{src: t, dst: dst}
+			continue
+		}
+
+		rows, err := c.count(ctx, t.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		report.Skipped = append(report.Skipped, TableReport{Name: t.Name, Rows: rows})
+		if rows > 0 && !opts.IgnoreMissingTables {
+			missing = append(missing, t.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrMissingTables, strings.Join(missing, ", "))
+	}
+
+	sort.Slice(report.Skipped, func(i, j int) bool { return report.Skipped[i].Name < report.Skipped[j].Name })
+	return tables, report, nil
+}
+
+// checkMigrations fails when the source has been migrated by another version of Grafana than the target, their
+// tables would differ
+func (c *copier) checkMigrations() error {
+	srcLog, err := migrator.NewMigrator(c.src, nil).GetMigrationLog()
+	if err != nil {
+		return err
+	}
+	dstLog, err := migrator.NewMigrator(c.dst, nil).GetMigrationLog()
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for id := range srcLog {
+		if _, ok := dstLog[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("%w, run the same Grafana version on both: %s", ErrMissingMigrations, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// copyTable copies the rows of a table in the transaction of the copy
+func (c *copier) copyTable(ctx context.Context, src *core.Table, dst *core.Table) (int64, error) {
+	columns := src.ColumnsSeq()
+	if len(columns) == 0 {
+		return 0, nil
+	}
+
+	w, err := c.tx.NewTableWriter(dst, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	srcColumns := make([]string, len(columns))
+	for i, name := range columns {
+		srcColumns[i] = c.srcDialect.Quote(name)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(srcColumns, ", "), c.srcDialect.Quote(src.Name))
+	if len(src.PrimaryKeys) > 0 {
+		keys := make([]string, len(src.PrimaryKeys))
+		for i, key := range src.PrimaryKeys {
+			keys[i] = c.srcDialect.Quote(key)
+		}
+		query += " ORDER BY " + strings.Join(keys, ", ")
+	}
+
+	rows, err := c.src.DB().DB.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return 0, err
+		}
+		if err := w.Write(values); err != nil {
+			return 0, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	return w.Flush()
+}
+
+// verify compares the number of rows of the tables, and the encrypted data keys the secrets depend on
+func (c *copier) verify(ctx context.Context, tables []TableReport) error {
+	var mismatches []string
+	for _, t := range tables {
+		srcRows, err := c.count(ctx, t.Name)
+		if err != nil {
+			return err
+		}
+		dstRows, err := c.tx.Count(ctx, t.Name)
+		if err != nil {
+			return err
+		}
+		if srcRows != dstRows || dstRows != t.Rows {
+			mismatches = append(mismatches, fmt.Sprintf("%s has %d rows in the source and %d in the target", t.Name, srcRows, dstRows))
+		}
+
+		if t.Name == dataKeysTable {
+			if err := c.verifyDataKeys(ctx); err != nil {
+				mismatches = append(mismatches, err.Error())
+			}
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%w: %s", ErrVerificationFailed, strings.Join(mismatches, "; "))
+	}
+	return nil
+}
+
+func (c *copier) verifyDataKeys(ctx context.Context) error {
+	rows, err := c.src.DB().DB.QueryContext(ctx, fmt.Sprintf("SELECT %s, %s FROM %s",
+		c.srcDialect.Quote("name"), c.srcDialect.Quote("encrypted_data"), c.srcDialect.Quote(dataKeysTable)))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	srcKeys := map[string][]byte{}
+	for rows.Next() {
+		var name string
+		var data []byte
+		if err := rows.Scan(&name, &data); err != nil {
+			return err
+		}
+		srcKeys[name] = data
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	dstRows, err := c.tx.Query(ctx, fmt.Sprintf("SELECT %s, %s FROM %s",
+		c.dstDialect.Quote("name"), c.dstDialect.Quote("encrypted_data"), c.dstDialect.Quote(dataKeysTable)))
+	if err != nil {
+		return err
+	}
+	dstKeys := make(map[string][]byte, len(dstRows))
+	for _, row := range dstRows {
+		dstKeys[string(row["name"])] = row["encrypted_data"]
+	}
+
+	for name, data := range srcKeys {
+		if !bytes.Equal(data, dstKeys[name]) {
+			return fmt.Errorf("the data key %s differs, the secrets it encrypts couldn't be decrypted", name)
+		}
+	}
+	return nil
+}
+
+// count returns the number of rows of a table of the source
+func (c *copier) count(ctx context.Context, table string) (int64, error) {
+	var count int64
+	row := c.src.DB().DB.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", c.srcDialect.Quote(table)))
+	if err := row.Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count the rows of table %s: %w", table, err)
+	}
+	return count, nil
+}
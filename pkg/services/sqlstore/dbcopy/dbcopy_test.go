@@ -0,0 +1,172 @@
+package dbcopy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"xorm.io/core"
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+	"github.com/grafana/grafana/pkg/services/sqlstore/sqlutil"
+)
+
+func TestConvertValue(t *testing.T) {
+	column := func(name string) *core.Column {
+		return &core.Column{Name: "col", SQLType: core.SQLType{Name: name}}
+	}
+	created := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    any
+		column   *core.Column
+		expected any
+	}{
+		{name: "null", value: nil, column: column(core.Bool), expected: nil},
+		{name: "integer to boolean", value: int64(1), column: column(core.Bool), expected: true},
+		{name: "text to boolean", value: []byte("false"), column: column(core.Bool), expected: false},
+		{name: "boolean to integer", value: true, column: column(core.TinyInt), expected: int64(1)},
+		{name: "bytes to text", value: []byte(`{"a":1}`), column: column(core.Text), expected: `{"a":1}`},
+		{name: "text to blob", value: "secret", column: column(core.Blob), expected: []byte("secret")},
+		{name: "blob", value: []byte{0, 1, 2}, column: column(core.Bytea), expected: []byte{0, 1, 2}},
+		{name: "time", value: created, column: column(core.DateTime), expected: created},
+		{name: "text to time", value: "2024-05-01 10:30:00", column: column(core.DateTime), expected: created},
+		{name: "text with zone to time", value: "2024-05-01 12:30:00+02:00", column: column(core.TimeStamp), expected: created},
+		{name: "empty text to time", value: "", column: column(core.DateTime), expected: nil},
+		{name: "integer", value: int64(42), column: column(core.BigInt), expected: int64(42)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := ConvertValue(tt.value, tt.column)
+			require.NoError(t, err)
+			if expected, ok := tt.expected.(time.Time); ok {
+				require.True(t, expected.Equal(actual.(time.Time)))
+				return
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+
+	_, err := ConvertValue("yesterday", column(core.DateTime))
+	require.Error(t, err)
+}
+
+func TestSortTables(t *testing.T) {
+	tables := []string{"user", "alert", "org", "dashboard", "folder"}
+
+	t.Run("should sort by name without foreign keys", func(t *testing.T) {
+		require.Equal(t, []string{"alert", "dashboard", "folder", "org", "user"}, sortTables(tables, nil))
+	})
+
+	t.Run("should copy referenced tables first", func(t *testing.T) {
+		order := sortTables(tables, []foreignKey{
+			{table: "alert", referenced: "dashboard"},
+			{table: "dashboard", referenced: "folder"},
+			{table: "folder", referenced: "org"},
+			{table: "user", referenced: "org"},
+			{table: "user", referenced: "user"},
+			{table: "org", referenced: "plugin_table"},
+		})
+		require.Equal(t, []string{"org", "folder", "dashboard", "alert", "user"}, order)
+	})
+
+	t.Run("should sort cycles by name", func(t *testing.T) {
+		order := sortTables(tables, []foreignKey{
+			{table: "dashboard", referenced: "folder"},
+			{table: "folder", referenced: "dashboard"},
+		})
+		require.Equal(t, []string{"alert", "org", "user", "dashboard", "folder"}, order)
+	})
+}
+
+func setupTestDB(t *testing.T) *xorm.Engine {
+	t.Helper()
+	testDB, err := sqlutil.GetTestDB(sqlutil.GetTestDBType())
+	require.NoError(t, err)
+	t.Cleanup(testDB.Cleanup)
+
+	x, err := xorm.NewEngine(testDB.DriverName, testDB.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = x.Close() })
+
+	require.NoError(t, migrator.NewDialect(x.DriverName()).CleanDB(x))
+	return x
+}
+
+func TestIntegrationEnsureEmpty(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	x := setupTestDB(t)
+	user := migrator.NewDialect(x.DriverName()).Quote(userTable)
+
+	require.NoError(t, EnsureEmpty(x))
+
+	// the migrations create the tables, and insert rows in some of them, but no user
+	_, err := x.Exec("CREATE TABLE " + user + " (id INTEGER NOT NULL PRIMARY KEY)")
+	require.NoError(t, err)
+	require.NoError(t, EnsureEmpty(x))
+
+	_, err = x.Exec("INSERT INTO " + user + " (id) VALUES (1)")
+	require.NoError(t, err)
+	require.ErrorIs(t, EnsureEmpty(x), ErrTargetNotEmpty)
+}
+
+func TestIntegrationTx(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	x := setupTestDB(t)
+
+	_, err := x.Exec("CREATE TABLE dbcopy_test (id INTEGER NOT NULL PRIMARY KEY, name VARCHAR(190) NOT NULL)")
+	require.NoError(t, err)
+	tables, err := x.DBMetas()
+	require.NoError(t, err)
+	var table *core.Table
+	for _, tt := range tables {
+		if tt.Name == "dbcopy_test" {
+			table = tt
+		}
+	}
+	require.NotNil(t, table)
+
+	write := func(commit bool) {
+		tx, err := Begin(x)
+		require.NoError(t, err)
+		defer tx.Close()
+
+		require.NoError(t, tx.Empty("dbcopy_test"))
+		w, err := tx.NewTableWriter(table, []string{"id", "name"})
+		require.NoError(t, err)
+		require.NoError(t, w.Write([]any{int64(1), "one"}))
+		require.NoError(t, w.Write([]any{int64(2), "two"}))
+		rows, err := w.Flush()
+		require.NoError(t, err)
+		require.Equal(t, int64(2), rows)
+
+		count, err := tx.Count(ctx, "dbcopy_test")
+		require.NoError(t, err)
+		require.Equal(t, int64(2), count)
+		if commit {
+			require.NoError(t, tx.Commit())
+		}
+	}
+
+	t.Run("should roll back the rows when the transaction isn't committed", func(t *testing.T) {
+		write(false)
+		count, err := x.Table("dbcopy_test").Count()
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
+
+	t.Run("should keep the rows when the transaction is committed", func(t *testing.T) {
+		write(true)
+		count, err := x.Table("dbcopy_test").Count()
+		require.NoError(t, err)
+		require.Equal(t, int64(2), count)
+	})
+}
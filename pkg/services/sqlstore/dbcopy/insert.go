@@ -0,0 +1,161 @@
+package dbcopy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"xorm.io/core"
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+const (
+	// maxBatchRows and maxBatchArgs bound the size of the insert statements, PostgreSQL and MySQL accept up to 65535
+	// placeholders per statement
+	maxBatchRows = 500
+	maxBatchArgs = 65535
+)
+
+// Tx is a transaction of the target database, the rows of a copy are all written or none is
+type Tx struct {
+	sess    *xorm.Session
+	dialect migrator.Dialect
+}
+
+// Begin starts a transaction on engine. On MySQL, the foreign keys aren't checked in the transaction so that the
+// tables can be filled in any order, and the rows with a zero id are kept.
+func Begin(engine *xorm.Engine) (*Tx, error) {
+	tx := &Tx{sess: engine.NewSession(), dialect: migrator.NewDialect(engine.DriverName())}
+	if err := tx.sess.Begin(); err != nil {
+		tx.sess.Close()
+		return nil, err
+	}
+	if tx.dialect.DriverName() == migrator.MySQL {
+		// keep the rows with a zero id, MySQL would replace it with the next value of the sequence otherwise
+		if err := tx.Exec("SET SESSION sql_mode = CONCAT(@@SESSION.sql_mode, ',NO_AUTO_VALUE_ON_ZERO')"); err != nil {
+			tx.sess.Close()
+			return nil, err
+		}
+		if err := tx.Exec("SET SESSION foreign_key_checks = 0"); err != nil {
+			tx.sess.Close()
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
+func (tx *Tx) Exec(query string, args ...any) error {
+	_, err := tx.sess.Exec(append([]any{query}, args...)...)
+	return err
+}
+
+// Empty deletes the rows of a table
+func (tx *Tx) Empty(table string) error {
+	return tx.Exec(fmt.Sprintf("DELETE FROM %s", tx.dialect.Quote(table)))
+}
+
+// Count returns the number of rows of a table, including the rows written in the transaction
+func (tx *Tx) Count(ctx context.Context, table string) (int64, error) {
+	var count int64
+	if _, err := tx.sess.Context(ctx).SQL(fmt.Sprintf("SELECT COUNT(*) FROM %s", tx.dialect.Quote(table))).Get(&count); err != nil {
+		return 0, fmt.Errorf("failed to count the rows of table %s: %w", table, err)
+	}
+	return count, nil
+}
+
+// Query runs a query in the transaction and returns its rows by column name
+func (tx *Tx) Query(ctx context.Context, query string, args ...any) ([]map[string][]byte, error) {
+	return tx.sess.Context(ctx).Query(append([]any{query}, args...)...)
+}
+
+func (tx *Tx) Commit() error {
+	return tx.sess.Commit()
+}
+
+// Close rolls the transaction back when it wasn't committed
+func (tx *Tx) Close() {
+	tx.sess.Close()
+}
+
+// TableWriter inserts rows in a table in a transaction, with multi-row insert statements. The values are converted
+// to the types of the columns with ConvertValue.
+type TableWriter struct {
+	tx        *Tx
+	columns   []*core.Column
+	insert    string
+	row       string
+	batchRows int
+	args      []any
+	rows      int64
+}
+
+// NewTableWriter inserts the given columns of table in the transaction, the columns must exist in the table
+func (tx *Tx) NewTableWriter(table *core.Table, columns []string) (*TableWriter, error) {
+	w := &TableWriter{
+		tx:        tx,
+		columns:   make([]*core.Column, len(columns)),
+		batchRows: min(maxBatchRows, maxBatchArgs/max(len(columns), 1)),
+	}
+	quoted := make([]string, len(columns))
+	for i, name := range columns {
+		col := table.GetColumn(name)
+		if col == nil {
+			return nil, fmt.Errorf("column %s doesn't exist in table %s", name, table.Name)
+		}
+		w.columns[i] = col
+		quoted[i] = tx.dialect.Quote(name)
+	}
+	w.insert = fmt.Sprintf("INSERT INTO %s (%s) VALUES ", tx.dialect.Quote(table.Name), strings.Join(quoted, ", "))
+	w.row = "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	w.args = make([]any, 0, w.batchRows*len(columns))
+	return w, nil
+}
+
+// Write adds a row, with a value per column
+func (w *TableWriter) Write(values []any) error {
+	for i, col := range w.columns {
+		v, err := ConvertValue(values[i], col)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		w.args = append(w.args, v)
+	}
+	if len(w.args) >= w.batchRows*len(w.columns) {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *TableWriter) flush() error {
+	if len(w.args) == 0 {
+		return nil
+	}
+	n := len(w.args) / len(w.columns)
+	stmt := w.insert + strings.TrimSuffix(strings.Repeat(w.row+", ", n), ", ")
+	if err := w.tx.Exec(stmt, w.args...); err != nil {
+		return err
+	}
+	w.rows += int64(n)
+	w.args = w.args[:0]
+	return nil
+}
+
+// Flush inserts the remaining rows, it returns the number of inserted rows
+func (w *TableWriter) Flush() (int64, error) {
+	if err := w.flush(); err != nil {
+		return 0, err
+	}
+	return w.rows, nil
+}
+
+// ResetSequence moves the sequence of the auto increment column of a PostgreSQL table after the inserted ids. MySQL
+// and SQLite move the auto increment when inserting explicit ids.
+func (tx *Tx) ResetSequence(table *core.Table) error {
+	if tx.dialect.DriverName() != migrator.Postgres || table.AutoIncrement == "" {
+		return nil
+	}
+	return tx.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+		tx.dialect.Quote(table.AutoIncrement), tx.dialect.Quote(table.Name)), tx.dialect.Quote(table.Name), table.AutoIncrement)
+}
@@ -0,0 +1,134 @@
+package dbcopy
+
+import (
+	"context"
+	"sort"
+
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func (c *copier) dependencyOrder(ctx context.Context, tables map[string]tablePair) ([]string, error) {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	return DependencyOrder(ctx, c.dst, names)
+}
+
+// DependencyOrder returns the tables sorted so that the tables referenced by a foreign key in engine come before the
+// tables referencing them, and by name otherwise. The tables of a reference cycle are sorted by name. Rows are
+// inserted in this order and deleted in the reverse order.
+func DependencyOrder(ctx context.Context, engine *xorm.Engine, tables []string) ([]string, error) {
+	references, err := foreignKeys(ctx, engine)
+	if err != nil {
+		return nil, err
+	}
+	return sortTables(tables, references), nil
+}
+
+func sortTables(tables []string, references []foreignKey) []string {
+	dependencies := make(map[string]map[string]struct{}, len(tables))
+	dependents := make(map[string][]string, len(tables))
+	for _, name := range tables {
+		dependencies[name] = map[string]struct{}{}
+	}
+	for _, ref := range references {
+		if ref.table == ref.referenced {
+			continue
+		}
+		if _, ok := dependencies[ref.table]; !ok {
+			continue
+		}
+		if _, ok := dependencies[ref.referenced]; !ok {
+			continue
+		}
+		if _, ok := dependencies[ref.table][ref.referenced]; ok {
+			continue
+		}
+		dependencies[ref.table][ref.referenced] = struct{}{}
+		dependents[ref.referenced] = append(dependents[ref.referenced], ref.table)
+	}
+
+	var ready []string
+	for name, deps := range dependencies {
+		if len(deps) == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	order := make([]string, 0, len(tables))
+	for len(order) < len(tables) {
+		if len(ready) == 0 {
+			// a cycle, take the remaining tables by name
+			for name, deps := range dependencies {
+				if len(deps) > 0 {
+					ready = append(ready, name)
+				}
+			}
+			sort.Strings(ready)
+			return append(order, ready...)
+		}
+
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+		delete(dependencies, name)
+
+		for _, dependent := range dependents[name] {
+			deps, ok := dependencies[dependent]
+			if !ok {
+				continue
+			}
+			delete(deps, name)
+			if len(deps) == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	return order
+}
+
+type foreignKey struct {
+	table      string
+	referenced string
+}
+
+// foreignKeys returns the foreign keys between the tables of a database. The migrations of Grafana don't create
+// any, but the ones added by hand or by the migrations of plugins have to be respected.
+func foreignKeys(ctx context.Context, engine *xorm.Engine) ([]foreignKey, error) {
+	var query string
+	switch migrator.NewDialect(engine.DriverName()).DriverName() {
+	case migrator.Postgres:
+		query = `SELECT tc.table_name, ccu.table_name
+			FROM information_schema.table_constraints tc
+			JOIN information_schema.constraint_column_usage ccu
+				ON tc.constraint_name = ccu.constraint_name AND tc.table_schema = ccu.table_schema
+			WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = current_schema()`
+	case migrator.MySQL:
+		query = `SELECT table_name, referenced_table_name
+			FROM information_schema.key_column_usage
+			WHERE table_schema = DATABASE() AND referenced_table_name IS NOT NULL`
+	default:
+		return nil, nil
+	}
+
+	rows, err := engine.DB().DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var keys []foreignKey
+	for rows.Next() {
+		var key foreignKey
+		if err := rows.Scan(&key.table, &key.referenced); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
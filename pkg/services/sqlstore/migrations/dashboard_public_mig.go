@@ -96,4 +96,45 @@ func addPublicDashboardMigration(mg *Migrator) {
 	mg.AddMigration("backfill empty share column fields with default of public", NewRawSQLMigration(
 		"UPDATE dashboard_public SET share='public' WHERE share=''",
 	))
+
+	mg.AddMigration("add expires_at column", NewAddColumnMigration(dashboardPublicCfgV2, &Column{
+		Name:     "expires_at",
+		Type:     DB_DateTime,
+		Nullable: true,
+	}))
+
+	mg.AddMigration("add password_hash column", NewAddColumnMigration(dashboardPublicCfgV2, &Column{
+		Name:     "password_hash",
+		Type:     DB_NVarchar,
+		Length:   255,
+		Nullable: true,
+	}))
+
+	mg.AddMigration("add password_salt column", NewAddColumnMigration(dashboardPublicCfgV2, &Column{
+		Name:     "password_salt",
+		Type:     DB_NVarchar,
+		Length:   50,
+		Nullable: true,
+	}))
+
+	dashboardPublicView := Table{
+		Name: "dashboard_public_view",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "public_dashboard_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "access_token", Type: DB_NVarchar, Length: 32, Nullable: false},
+			{Name: "ip_address", Type: DB_NVarchar, Length: 64, Nullable: true},
+			{Name: "user_agent", Type: DB_NVarchar, Length: 255, Nullable: true},
+			{Name: "viewed_at", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "public_dashboard_uid"}},
+			{Cols: []string{"access_token"}},
+			{Cols: []string{"viewed_at"}},
+		},
+	}
+
+	mg.AddMigration("create dashboard public view table", NewAddTableMigration(dashboardPublicView))
+	addTableIndicesMigrations(mg, "v1", dashboardPublicView)
 }
@@ -39,4 +39,40 @@ func addLoginAttemptMigrations(mg *Migrator) {
 		"username":   "username",
 		"ip_address": "ip_address",
 	})
+
+	// IPv6 addresses don't fit in 30 characters
+	mg.AddMigration("increase login_attempt.ip_address column length", NewRawSQLMigration("").
+		Postgres("ALTER TABLE login_attempt ALTER COLUMN ip_address TYPE VARCHAR(50);").
+		Mysql("ALTER TABLE login_attempt MODIFY ip_address VARCHAR(50) NOT NULL;"))
+
+	mg.AddMigration("add subnet column to login_attempt", NewAddColumnMigration(loginAttemptV2, &Column{
+		Name: "subnet", Type: DB_NVarchar, Length: 50, Nullable: true,
+	}))
+	mg.AddMigration("add index login_attempt.ip_address", NewAddIndexMigration(loginAttemptV2, &Index{
+		Cols: []string{"ip_address"},
+	}))
+	mg.AddMigration("add index login_attempt.subnet", NewAddIndexMigration(loginAttemptV2, &Index{
+		Cols: []string{"subnet"},
+	}))
+
+	loginLockoutV1 := Table{
+		Name: "login_lockout",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "lockout_type", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "value", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "lockout_count", Type: DB_BigInt, Nullable: false},
+			{Name: "locked_until", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_BigInt, Nullable: false},
+			{Name: "updated", Type: DB_BigInt, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"lockout_type", "value"}, Type: UniqueIndex},
+			{Cols: []string{"locked_until"}},
+		},
+	}
+
+	mg.AddMigration("create login_lockout table", NewAddTableMigration(loginLockoutV1))
+	mg.AddMigration("add unique index login_lockout.lockout_type_value", NewAddIndexMigration(loginLockoutV1, loginLockoutV1.Indices[0]))
+	mg.AddMigration("add index login_lockout.locked_until", NewAddIndexMigration(loginLockoutV1, loginLockoutV1.Indices[1]))
 }
@@ -138,6 +138,16 @@ func (oss *OSSMigrations) AddMigration(mg *Migrator) {
 	accesscontrol.AddActionSetPermissionsMigrator(mg)
 
 	externalsession.AddMigration(mg)
+
+	ualert.AddStateHistoryTables(mg)
+
+	addUserMFAMigrations(mg)
+
+	addSCIMMigrations(mg)
+
+	addSlowQueryMigrations(mg)
+
+	addReportMigrations(mg)
 }
 
 func addStarMigrations(mg *Migrator) {
@@ -0,0 +1,65 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+// addReportMigrations adds the tables of the scheduled reports. They are prefixed so they don't
+// clash with the report tables of Grafana Enterprise reporting.
+func addReportMigrations(mg *Migrator) {
+	scheduledReportV1 := Table{
+		Name: "scheduled_report",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "name", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "dashboard_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "panel_ids", Type: DB_Text, Nullable: false},
+			{Name: "time_from", Type: DB_NVarchar, Length: 100, Nullable: false},
+			{Name: "time_to", Type: DB_NVarchar, Length: 100, Nullable: false},
+			{Name: "variables", Type: DB_Text, Nullable: false},
+			{Name: "schedule", Type: DB_NVarchar, Length: 100, Nullable: false},
+			{Name: "timezone", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "format", Type: DB_NVarchar, Length: 10, Nullable: false},
+			{Name: "recipients", Type: DB_Text, Nullable: false},
+			{Name: "webhook_url", Type: DB_Text, Nullable: false},
+			{Name: "message", Type: DB_Text, Nullable: false},
+			{Name: "enabled", Type: DB_Bool, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "next_run", Type: DB_BigInt, Nullable: false, Default: "0"},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "uid"}, Type: UniqueIndex},
+			{Cols: []string{"enabled", "next_run"}},
+		},
+	}
+
+	mg.AddMigration("create scheduled report table", NewAddTableMigration(scheduledReportV1))
+	mg.AddMigration("add unique index scheduled_report.org_id_uid", NewAddIndexMigration(scheduledReportV1, scheduledReportV1.Indices[0]))
+	mg.AddMigration("add index scheduled_report.enabled_next_run", NewAddIndexMigration(scheduledReportV1, scheduledReportV1.Indices[1]))
+
+	scheduledReportDeliveryV1 := Table{
+		Name: "scheduled_report_delivery",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "report_id", Type: DB_BigInt, Nullable: false},
+			{Name: "triggered_by", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "status", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "error", Type: DB_Text, Nullable: false},
+			{Name: "files", Type: DB_Int, Nullable: false},
+			{Name: "bytes", Type: DB_BigInt, Nullable: false},
+			{Name: "started", Type: DB_DateTime, Nullable: false},
+			{Name: "finished", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"report_id", "started"}},
+			{Cols: []string{"started"}},
+		},
+	}
+
+	mg.AddMigration("create scheduled report delivery table", NewAddTableMigration(scheduledReportDeliveryV1))
+	mg.AddMigration("add index scheduled_report_delivery.report_id_started", NewAddIndexMigration(scheduledReportDeliveryV1, scheduledReportDeliveryV1.Indices[0]))
+	mg.AddMigration("add index scheduled_report_delivery.started", NewAddIndexMigration(scheduledReportDeliveryV1, scheduledReportDeliveryV1.Indices[1]))
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func addSlowQueryMigrations(mg *Migrator) {
+	slowQueryV1 := Table{
+		Name: "slow_query",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "datasource_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "datasource_type", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "dashboard_uid", Type: DB_NVarchar, Length: 40, Nullable: false, Default: "''"},
+			{Name: "panel_id", Type: DB_BigInt, Nullable: false, Default: "0"},
+			{Name: "user_login", Type: DB_NVarchar, Length: 190, Nullable: false, Default: "''"},
+			{Name: "query_count", Type: DB_BigInt, Nullable: false},
+			{Name: "duration_ms", Type: DB_BigInt, Nullable: false},
+			{Name: "response_bytes", Type: DB_BigInt, Nullable: false},
+			{Name: "status", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "created", Type: DB_BigInt, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"created"}},
+			{Cols: []string{"org_id", "created"}},
+		},
+	}
+
+	mg.AddMigration("create slow_query table", NewAddTableMigration(slowQueryV1))
+	mg.AddMigration("add index slow_query.created", NewAddIndexMigration(slowQueryV1, slowQueryV1.Indices[0]))
+	mg.AddMigration("add index slow_query.org_id_created", NewAddIndexMigration(slowQueryV1, slowQueryV1.Indices[1]))
+}
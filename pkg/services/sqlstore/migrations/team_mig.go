@@ -75,4 +75,27 @@ func addTeamMigrations(mg *Migrator) {
 		Name: "permission", Type: DB_SmallInt, Nullable: true,
 	}))
 	mg.AddMigration("add unique index team_member_user_id_org_id", NewAddIndexMigration(teamMemberV1, teamMemberV1.Indices[3]))
+
+	// external groups synced to teams, Grafana Enterprise has its own team_group table
+	teamExternalGroupV1 := Table{
+		Name: "team_external_group",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt},
+			{Name: "team_id", Type: DB_BigInt},
+			{Name: "group_id", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id"}},
+			{Cols: []string{"org_id", "team_id", "group_id"}, Type: UniqueIndex},
+			{Cols: []string{"group_id"}},
+		},
+	}
+
+	mg.AddMigration("create team external group table", NewAddTableMigration(teamExternalGroupV1))
+	mg.AddMigration("add index team_external_group.org_id", NewAddIndexMigration(teamExternalGroupV1, teamExternalGroupV1.Indices[0]))
+	mg.AddMigration("add unique index team_external_group_org_id_team_id_group_id", NewAddIndexMigration(teamExternalGroupV1, teamExternalGroupV1.Indices[1]))
+	mg.AddMigration("add index team_external_group.group_id", NewAddIndexMigration(teamExternalGroupV1, teamExternalGroupV1.Indices[2]))
 }
@@ -2,6 +2,7 @@ package migrator
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strconv"
 	"strings"
@@ -95,6 +96,9 @@ type Dialect interface {
 	// Implementations are not expected to quote the arguments
 	// therefore any callers should take care to quote arguments as necessary
 	Concat(...string) string
+	// BeginSnapshot starts a read-only transaction where all the reads see the database at the same point in time,
+	// while other connections keep writing to it
+	BeginSnapshot(ctx context.Context, db *sql.DB) (*sql.Tx, error)
 }
 
 type LockCfg struct {
@@ -458,3 +462,8 @@ func (b *BaseDialect) Update(ctx context.Context, tx *session.SessionTx, tableNa
 func (b *BaseDialect) Concat(strs ...string) string {
 	return fmt.Sprintf("CONCAT(%s)", strings.Join(strs, ", "))
 }
+
+func (b *BaseDialect) BeginSnapshot(ctx context.Context, db *sql.DB) (*sql.Tx, error) {
+	// PostgreSQL and MySQL (InnoDB) take the snapshot at the first read of a repeatable read transaction
+	return db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
@@ -1,6 +1,8 @@
 package migrator
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -28,7 +30,8 @@ func (m *MigrationBase) SkipMigrationLog() bool {
 type RawSQLMigration struct {
 	MigrationBase
 
-	sql map[string]string
+	sql  map[string]string
+	down map[string]string
 }
 
 // NewRawSQLMigration should be used carefully, the usage
@@ -85,6 +88,29 @@ func (m *RawSQLMigration) Mssql(sql string) *RawSQLMigration {
 	return m.Set(MSSQL, sql)
 }
 
+// SetDown sets the SQL reverting the migration for a dialect, the migration can't be rolled back without it
+func (m *RawSQLMigration) SetDown(dialect string, sql string) *RawSQLMigration {
+	if m.down == nil {
+		m.down = make(map[string]string)
+	}
+
+	m.down[dialect] = sql
+	return m
+}
+
+// Down sets the SQL reverting the migration for the dialects without a specific one
+func (m *RawSQLMigration) Down(sql string) *RawSQLMigration {
+	return m.SetDown("default", sql)
+}
+
+func (m *RawSQLMigration) DownSQL(dialect Dialect) string {
+	if val := m.down[dialect.DriverName()]; val != "" {
+		return val
+	}
+
+	return m.down["default"]
+}
+
 type AddColumnMigration struct {
 	MigrationBase
 	tableName string
@@ -111,6 +137,10 @@ func (m *AddColumnMigration) SQL(dialect Dialect) string {
 	return dialect.AddColumnSQL(m.tableName, m.column)
 }
 
+func (m *AddColumnMigration) DownSQL(dialect Dialect) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", dialect.Quote(m.tableName), dialect.Quote(m.column.Name))
+}
+
 type RenameColumnMigration struct {
 	MigrationBase
 	table   Table
@@ -164,6 +194,10 @@ func (m *AddIndexMigration) SQL(dialect Dialect) string {
 	return dialect.CreateIndexSQL(m.tableName, m.index)
 }
 
+func (m *AddIndexMigration) DownSQL(dialect Dialect) string {
+	return dialect.DropIndexSQL(m.tableName, m.index)
+}
+
 type DropIndexMigration struct {
 	MigrationBase
 	tableName string
@@ -183,6 +217,10 @@ func (m *DropIndexMigration) SQL(dialect Dialect) string {
 	return dialect.DropIndexSQL(m.tableName, m.index)
 }
 
+func (m *DropIndexMigration) DownSQL(dialect Dialect) string {
+	return dialect.CreateIndexSQL(m.tableName, m.index)
+}
+
 type AddTableMigration struct {
 	MigrationBase
 	table Table
@@ -201,6 +239,10 @@ func (m *AddTableMigration) SQL(d Dialect) string {
 	return d.CreateTableSQL(&m.table)
 }
 
+func (m *AddTableMigration) DownSQL(d Dialect) string {
+	return d.DropTable(m.table.Name)
+}
+
 type DropTableMigration struct {
 	MigrationBase
 	tableName string
@@ -247,9 +289,15 @@ type CopyTableDataMigration struct {
 
 func NewCopyTableDataMigration(targetTable string, sourceTable string, colMap map[string]string) *CopyTableDataMigration {
 	m := &CopyTableDataMigration{sourceTable: sourceTable, targetTable: targetTable}
-	for key, value := range colMap {
+	// the columns are sorted so that the SQL, and its checksum, is the same every time
+	keys := make([]string, 0, len(colMap))
+	for key := range colMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
 		m.targetCols = append(m.targetCols, key)
-		m.sourceCols = append(m.sourceCols, value)
+		m.sourceCols = append(m.sourceCols, colMap[key])
 	}
 	return m
 }
@@ -1,8 +1,11 @@
 package migrator
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -31,6 +34,8 @@ type Migrator struct {
 	isLocked     atomic.Bool
 	logMap       map[string]MigrationLog
 	tableName    string
+	// missingLogColumns are the columns of the migration log not added yet by their migrations
+	missingLogColumns []string
 }
 
 type MigrationLog struct {
@@ -40,8 +45,15 @@ type MigrationLog struct {
 	Success     bool
 	Error       string
 	Timestamp   time.Time
+	// Checksum of the SQL of the migration, empty for the migrations executed before it was recorded
+	Checksum string `xorm:"checksum"`
+	// Skipped is true when the condition of the migration wasn't fulfilled, the migration didn't change anything
+	Skipped bool `xorm:"skipped"`
 }
 
+// logColumns are the columns added to the migration log after it was created
+var logColumns = []string{"checksum", "skipped"}
+
 func NewMigrator(engine *xorm.Engine, cfg *setting.Cfg) *Migrator {
 	return NewScopedMigrator(engine, cfg, "")
 }
@@ -79,6 +91,25 @@ func (mg *Migrator) AddCreateMigration() {
 			{Name: "timestamp", Type: DB_DateTime},
 		},
 	}))
+
+	mg.AddMigration(mg.logColumnMigrationID("checksum"), NewAddColumnMigration(Table{Name: mg.tableName}, &Column{
+		Name: "checksum", Type: DB_NVarchar, Length: 64, Nullable: true,
+	}))
+
+	mg.AddMigration(mg.logColumnMigrationID("skipped"), NewAddColumnMigration(Table{Name: mg.tableName}, &Column{
+		Name: "skipped", Type: DB_Bool, Nullable: false, Default: "0",
+	}))
+}
+
+// logColumnMigrationID is the migration after which the migration log has the given column
+func (mg *Migrator) logColumnMigrationID(column string) string {
+	return "add " + column + " column to " + mg.tableName
+}
+
+// checksum returns the checksum of the SQL of a migration, it changes when the migration is modified
+func checksum(sql string) string {
+	sum := sha256.Sum256([]byte(sql))
+	return hex.EncodeToString(sum[:])
 }
 
 func (mg *Migrator) MigrationsCount() int {
@@ -118,7 +149,18 @@ func (mg *Migrator) GetMigrationLog() (map[string]MigrationLog, error) {
 		return logMap, nil
 	}
 
-	if err = mg.DBEngine.Table(mg.tableName).Find(&logItems); err != nil {
+	// the migration log is read before the migrations adding its columns run
+	mg.missingLogColumns = make([]string, 0)
+	for _, column := range logColumns {
+		exists, err := mg.DBEngine.Dialect().IsColumnExist(mg.tableName, column)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", "failed to check column existence", err)
+		}
+		if !exists {
+			mg.missingLogColumns = append(mg.missingLogColumns, column)
+		}
+	}
+	if err = mg.DBEngine.Table(mg.tableName).Omit(mg.missingLogColumns...).Find(&logItems); err != nil {
 		return nil, err
 	}
 
@@ -187,6 +229,14 @@ func (mg *Migrator) run() (err error) {
 		return err
 	}
 
+	modified, err := mg.ModifiedMigrations()
+	if err != nil {
+		return err
+	}
+	for _, id := range modified {
+		mg.Logger.Warn("Migration was modified after it was executed, its changes won't be applied", "id", id)
+	}
+
 	migrationsPerformed := 0
 	migrationsSkipped := 0
 	start := time.Now()
@@ -205,32 +255,34 @@ func (mg *Migrator) run() (err error) {
 			MigrationID: m.Id(),
 			SQL:         sql,
 			Timestamp:   time.Now(),
+			Checksum:    checksum(sql),
 		}
 
 		err := mg.InTransaction(func(sess *xorm.Session) error {
-			err := mg.exec(m, sess)
+			skipped, err := mg.exec(m, sess)
 			// if we get an sqlite busy/locked error, sleep 100ms and try again
 			cnt := 0
 			for cnt < 3 && (errors.Is(err, sqlite3.ErrLocked) || errors.Is(err, sqlite3.ErrBusy)) {
 				cnt++
 				mg.Logger.Debug("Database locked, sleeping then retrying", "error", err, "sql", sql)
 				time.Sleep(100 * time.Millisecond)
-				err = mg.exec(m, sess)
+				skipped, err = mg.exec(m, sess)
 			}
 
 			if err != nil {
 				mg.Logger.Error("Exec failed", "error", err, "sql", sql)
 				record.Error = err.Error()
 				if !m.SkipMigrationLog() {
-					if _, err := sess.Table(mg.tableName).Insert(&record); err != nil {
+					if err := mg.insertLog(sess, &record); err != nil {
 						return err
 					}
 				}
 				return err
 			}
 			record.Success = true
+			record.Skipped = skipped
 			if !m.SkipMigrationLog() {
-				_, err = sess.Table(mg.tableName).Insert(&record)
+				err = mg.insertLog(sess, &record)
 			}
 			if err == nil {
 				migrationsPerformed++
@@ -240,6 +292,10 @@ func (mg *Migrator) run() (err error) {
 		if err != nil {
 			return fmt.Errorf("%v: %w", fmt.Sprintf("migration failed (id = %s)", m.Id()), err)
 		}
+
+		mg.missingLogColumns = slices.DeleteFunc(mg.missingLogColumns, func(column string) bool {
+			return m.Id() == mg.logColumnMigrationID(column)
+		})
 	}
 
 	mg.Logger.Info("migrations completed", "performed", migrationsPerformed, "skipped", migrationsSkipped, "duration", time.Since(start))
@@ -248,7 +304,8 @@ func (mg *Migrator) run() (err error) {
 	return mg.DBEngine.Sync2()
 }
 
-func (mg *Migrator) exec(m Migration, sess *xorm.Session) error {
+// exec executes a migration, it returns true when the migration is skipped because its condition isn't fulfilled
+func (mg *Migrator) exec(m Migration, sess *xorm.Session) (bool, error) {
 	start := time.Now()
 	mg.Logger.Info("Executing migration", "id", m.Id())
 
@@ -261,12 +318,12 @@ func (mg *Migrator) exec(m Migration, sess *xorm.Session) error {
 			results, err := sess.SQL(sql, args...).Query()
 			if err != nil {
 				mg.Logger.Error("Executing migration condition failed", "id", m.Id(), "error", err)
-				return err
+				return false, err
 			}
 
 			if !condition.IsFulfilled(results) {
 				mg.Logger.Warn("Skipping migration: Already executed, but not recorded in migration log", "id", m.Id())
-				return nil
+				return true, nil
 			}
 		}
 	}
@@ -283,12 +340,17 @@ func (mg *Migrator) exec(m Migration, sess *xorm.Session) error {
 
 	if err != nil {
 		mg.Logger.Error("Executing migration failed", "id", m.Id(), "error", err, "duration", time.Since(start))
-		return err
+		return false, err
 	}
 
 	mg.Logger.Info("Migration successfully executed", "id", m.Id(), "duration", time.Since(start))
 
-	return nil
+	return false, nil
+}
+
+func (mg *Migrator) insertLog(sess *xorm.Session, record *MigrationLog) error {
+	_, err := sess.Table(mg.tableName).Omit(mg.missingLogColumns...).Insert(record)
+	return err
 }
 
 type dbTransactionFunc func(sess *xorm.Session) error
@@ -0,0 +1,184 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"xorm.io/xorm"
+)
+
+// PlannedMigration is a migration to execute or to roll back, with its SQL for a dialect
+type PlannedMigration struct {
+	ID  string
+	SQL string
+	// Code is true for the migrations running Go code, their SQL only describes them
+	Code bool
+	// Condition is the SQL of the check skipping the migration when it isn't fulfilled, if any
+	Condition string
+	// Executed is when a migration to roll back was executed
+	Executed time.Time
+	// Skipped is true for the migrations to roll back which were skipped by their condition, they have no SQL and are
+	// only removed from the migration log
+	Skipped bool
+}
+
+// Plan returns the migrations not executed yet with their SQL for the dialect, without executing them. The dialect
+// may not be the one of the database, to review the SQL of every dialect.
+func (mg *Migrator) Plan(dialect Dialect) ([]PlannedMigration, error) {
+	if _, err := mg.GetMigrationLog(); err != nil {
+		return nil, err
+	}
+
+	plan := make([]PlannedMigration, 0)
+	for _, m := range mg.migrations {
+		if _, exists := mg.logMap[m.Id()]; exists {
+			continue
+		}
+
+		planned := PlannedMigration{ID: m.Id(), SQL: m.SQL(dialect)}
+		if _, ok := m.(CodeMigration); ok {
+			planned.Code = true
+		}
+		if condition := m.GetCondition(); condition != nil {
+			sql, args := condition.SQL(dialect)
+			if sql != "" {
+				planned.Condition = interpolate(sql, args)
+			}
+		}
+		plan = append(plan, planned)
+	}
+
+	return plan, nil
+}
+
+// ModifiedMigrations returns the executed migrations whose SQL changed since they were executed, the migrations
+// executed before checksums were recorded are ignored. GetMigrationLog must be called before.
+func (mg *Migrator) ModifiedMigrations() ([]string, error) {
+	modified := make([]string, 0)
+	for _, m := range mg.migrations {
+		record, exists := mg.logMap[m.Id()]
+		if !exists || record.Checksum == "" {
+			continue
+		}
+		if record.Checksum != checksum(m.SQL(mg.Dialect)) {
+			modified = append(modified, m.Id())
+		}
+	}
+
+	return modified, nil
+}
+
+// RollbackPlan returns the migrations executed since the given time, in the order they are rolled back, with the SQL
+// reverting them. The migrations skipped by their condition didn't create anything, they aren't reverted. It fails if
+// one of them can't be rolled back.
+func (mg *Migrator) RollbackPlan(since time.Time) ([]PlannedMigration, error) {
+	if _, err := mg.GetMigrationLog(); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]Migration, len(mg.migrations))
+	for _, m := range mg.migrations {
+		byID[m.Id()] = m
+	}
+
+	records := make([]MigrationLog, 0)
+	for _, record := range mg.logMap {
+		if !record.Timestamp.Before(since) {
+			records = append(records, record)
+		}
+	}
+	// the last executed migration is rolled back first
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Id > records[j].Id
+	})
+
+	plan := make([]PlannedMigration, 0, len(records))
+	var irreversible []string
+	for _, record := range records {
+		if mg.isMigrationLogMigration(record.MigrationID) {
+			return nil, fmt.Errorf("migration %q can't be rolled back, the migration log is required", record.MigrationID)
+		}
+		if record.Skipped {
+			plan = append(plan, PlannedMigration{ID: record.MigrationID, Executed: record.Timestamp, Skipped: true})
+			continue
+		}
+
+		m, ok := byID[record.MigrationID]
+		if !ok {
+			return nil, fmt.Errorf("migration %q was executed by another version of Grafana, roll back with that version", record.MigrationID)
+		}
+
+		down, ok := m.(DownMigration)
+		if !ok || down.DownSQL(mg.Dialect) == "" {
+			irreversible = append(irreversible, m.Id())
+			continue
+		}
+		plan = append(plan, PlannedMigration{ID: m.Id(), SQL: down.DownSQL(mg.Dialect), Executed: record.Timestamp})
+	}
+
+	if len(irreversible) > 0 {
+		return nil, fmt.Errorf("the migrations %s can't be rolled back, restore a backup of the database instead", strings.Join(irreversible, ", "))
+	}
+
+	return plan, nil
+}
+
+// RollBack reverts the migrations executed since the given time, from the last one, and removes them from the
+// migration log. Nothing is reverted if one of them can't be rolled back. It stops at the first migration failing to
+// roll back, on MySQL the schema changes are committed as they are executed and that migration may be partially
+// reverted. Grafana must be stopped.
+func (mg *Migrator) RollBack(since time.Time) error {
+	plan, err := mg.RollbackPlan(since)
+	if err != nil {
+		return err
+	}
+
+	for i, m := range plan {
+		mg.Logger.Info("Rolling back migration", "id", m.ID, "skipped", m.Skipped)
+		err := mg.InTransaction(func(sess *xorm.Session) error {
+			if !m.Skipped {
+				if _, err := sess.Exec(m.SQL); err != nil {
+					return err
+				}
+			}
+			_, err := sess.Exec("DELETE FROM "+mg.Dialect.Quote(mg.tableName)+" WHERE migration_id = ?", m.ID)
+			return err
+		})
+		if err != nil {
+			if mg.Dialect.DriverName() == MySQL {
+				return fmt.Errorf("%v: %w", fmt.Sprintf("rollback stopped after %d migrations (id = %s), MySQL commits the schema changes as they are executed and the migration may be partially rolled back, restore a backup of the database", i, m.ID), err)
+			}
+			return fmt.Errorf("%v: %w", fmt.Sprintf("rollback failed after %d migrations (id = %s)", i, m.ID), err)
+		}
+		delete(mg.logMap, m.ID)
+	}
+
+	mg.Logger.Info("Rollback completed", "rolledBack", len(plan))
+	return nil
+}
+
+func (mg *Migrator) isMigrationLogMigration(id string) bool {
+	if id == "create "+mg.tableName+" table" {
+		return true
+	}
+	for _, column := range logColumns {
+		if id == mg.logColumnMigrationID(column) {
+			return true
+		}
+	}
+	return false
+}
+
+// interpolate replaces the placeholders of a condition with its arguments, to print it
+func interpolate(sql string, args []any) string {
+	for _, arg := range args {
+		value := fmt.Sprintf("%v", arg)
+		if s, ok := arg.(string); ok {
+			value = "'" + strings.ReplaceAll(s, "'", "''") + "'"
+		}
+		sql = strings.Replace(sql, "?", value, 1)
+	}
+	return sql
+}
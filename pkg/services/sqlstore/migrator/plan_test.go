@@ -0,0 +1,197 @@
+package migrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/sqlstore/sqlutil"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+var planTestTable = Table{
+	Name: "plan_test",
+	Columns: []*Column{
+		{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+		{Name: "name", Type: DB_NVarchar, Length: 190, Nullable: false},
+	},
+}
+
+func setupPlanTestDB(t *testing.T) *xorm.Engine {
+	t.Helper()
+	testDB, err := sqlutil.GetTestDB(sqlutil.GetTestDBType())
+	require.NoError(t, err)
+	t.Cleanup(testDB.Cleanup)
+
+	x, err := xorm.NewEngine(testDB.DriverName, testDB.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = x.Close() })
+
+	require.NoError(t, NewDialect(x.DriverName()).CleanDB(x))
+	return x
+}
+
+func newPlanTestMigrator(x *xorm.Engine, withUpgrade bool) *Migrator {
+	mg := NewScopedMigrator(x, &setting.Cfg{Logger: log.New("migrator.test"), Raw: ini.Empty()}, "plan_test")
+	mg.AddCreateMigration()
+	mg.AddMigration("create plan_test table", NewAddTableMigration(planTestTable))
+	if withUpgrade {
+		mg.AddMigration("add email column to plan_test", NewAddColumnMigration(planTestTable, &Column{
+			Name: "email", Type: DB_NVarchar, Length: 190, Nullable: true,
+		}))
+		mg.AddMigration("add unique index plan_test.name", NewAddIndexMigration(planTestTable, &Index{
+			Cols: []string{"name"}, Type: UniqueIndex,
+		}))
+	}
+	return mg
+}
+
+func TestPlan(t *testing.T) {
+	x := setupPlanTestDB(t)
+
+	mg := newPlanTestMigrator(x, false)
+	plan, err := mg.Plan(mg.Dialect)
+	require.NoError(t, err)
+	require.Len(t, plan, 3)
+	require.Equal(t, "create plan_test_migration_log table", plan[0].ID)
+
+	exists, err := x.IsTableExist("plan_test")
+	require.NoError(t, err)
+	require.False(t, exists, "the plan must not execute the migrations")
+
+	require.NoError(t, mg.Start(false, 0))
+
+	mg = newPlanTestMigrator(x, true)
+	plan, err = mg.Plan(NewDialect(Postgres))
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	require.Equal(t, "add email column to plan_test", plan[0].ID)
+	require.Contains(t, plan[0].SQL, `ALTER TABLE "plan_test" ADD COLUMN "email"`)
+	require.Equal(t, "add unique index plan_test.name", plan[1].ID)
+}
+
+func TestModifiedMigrations(t *testing.T) {
+	x := setupPlanTestDB(t)
+
+	mg := newPlanTestMigrator(x, false)
+	require.NoError(t, mg.Start(false, 0))
+
+	mg = newPlanTestMigrator(x, false)
+	_, err := mg.GetMigrationLog()
+	require.NoError(t, err)
+	modified, err := mg.ModifiedMigrations()
+	require.NoError(t, err)
+	require.Empty(t, modified)
+
+	mg = NewScopedMigrator(x, &setting.Cfg{Logger: log.New("migrator.test"), Raw: ini.Empty()}, "plan_test")
+	mg.AddCreateMigration()
+	mg.AddMigration("create plan_test table", NewRawSQLMigration("CREATE TABLE plan_test (id INTEGER)"))
+	_, err = mg.GetMigrationLog()
+	require.NoError(t, err)
+	modified, err = mg.ModifiedMigrations()
+	require.NoError(t, err)
+	require.Equal(t, []string{"create plan_test table"}, modified)
+}
+
+func TestRollBack(t *testing.T) {
+	x := setupPlanTestDB(t)
+
+	mg := newPlanTestMigrator(x, false)
+	require.NoError(t, mg.Start(false, 0))
+	// the migrations of the previous version were executed before the upgrade
+	_, err := x.Exec("UPDATE plan_test_migration_log SET timestamp = ?", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	mg = newPlanTestMigrator(x, true)
+	require.NoError(t, mg.Start(false, 0))
+	upgradedAt := time.Now().Add(-time.Minute)
+
+	t.Run("should fail without reverting anything if a migration can't be rolled back", func(t *testing.T) {
+		mg := newPlanTestMigrator(x, true)
+		mg.AddMigration("fill plan_test", NewRawSQLMigration("INSERT INTO plan_test (name) VALUES ('test')"))
+		require.NoError(t, mg.Start(false, 0))
+
+		err := mg.RollBack(upgradedAt)
+		require.ErrorContains(t, err, "the migrations fill plan_test can't be rolled back")
+
+		_, err = x.Exec("DELETE FROM plan_test_migration_log WHERE migration_id = ?", "fill plan_test")
+		require.NoError(t, err)
+	})
+
+	mg = newPlanTestMigrator(x, true)
+	plan, err := mg.RollbackPlan(upgradedAt)
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	require.Equal(t, "add unique index plan_test.name", plan[0].ID)
+	require.Equal(t, "add email column to plan_test", plan[1].ID)
+
+	require.NoError(t, mg.RollBack(upgradedAt))
+
+	exists, err := x.Dialect().IsColumnExist("plan_test", "email")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// the previous version sees the database as it left it
+	mg = newPlanTestMigrator(x, false)
+	plan, err = mg.Plan(mg.Dialect)
+	require.NoError(t, err)
+	require.Empty(t, plan)
+
+	// the upgrade can be executed again
+	mg = newPlanTestMigrator(x, true)
+	require.NoError(t, mg.Start(false, 0))
+}
+
+func TestRollBackSkippedMigrations(t *testing.T) {
+	x := setupPlanTestDB(t)
+
+	mg := newPlanTestMigrator(x, false)
+	require.NoError(t, mg.Start(false, 0))
+	_, err := x.Exec("UPDATE plan_test_migration_log SET timestamp = ?", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	// the index already exists, the migration adding it is skipped by its condition
+	index := &Index{Cols: []string{"name"}, Type: UniqueIndex}
+	_, err = x.Exec(mg.Dialect.CreateIndexSQL("plan_test", index))
+	require.NoError(t, err)
+	indexExists := func() bool {
+		sql, args := mg.Dialect.IndexCheckSQL("plan_test", index.XName("plan_test"))
+		results, err := x.SQL(sql, args...).Query()
+		require.NoError(t, err)
+		return len(results) > 0
+	}
+
+	mg = newPlanTestMigrator(x, true)
+	require.NoError(t, mg.Start(false, 0))
+	upgradedAt := time.Now().Add(-time.Minute)
+
+	logMap, err := mg.GetMigrationLog()
+	require.NoError(t, err)
+	require.True(t, logMap["add unique index plan_test.name"].Skipped)
+	require.False(t, logMap["add email column to plan_test"].Skipped)
+
+	plan, err := mg.RollbackPlan(upgradedAt)
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	require.Equal(t, "add unique index plan_test.name", plan[0].ID)
+	require.True(t, plan[0].Skipped)
+	require.Empty(t, plan[0].SQL)
+	require.False(t, plan[1].Skipped)
+
+	require.NoError(t, mg.RollBack(upgradedAt))
+	require.True(t, indexExists(), "the index wasn't created by the migration")
+
+	// the upgrade skips the migration again
+	mg = newPlanTestMigrator(x, true)
+	plan, err = mg.Plan(mg.Dialect)
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	require.NoError(t, mg.Start(false, 0))
+	logMap, err = mg.GetMigrationLog()
+	require.NoError(t, err)
+	require.True(t, logMap["add unique index plan_test.name"].Skipped)
+}
@@ -1,6 +1,8 @@
 package migrator
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
@@ -217,3 +219,18 @@ func (db *SQLite3) UpsertMultipleSQL(tableName string, keyCols, updateCols []str
 func (db *SQLite3) Concat(strs ...string) string {
 	return strings.Join(strs, " || ")
 }
+
+func (db *SQLite3) BeginSnapshot(ctx context.Context, sqlDB *sql.DB) (*sql.Tx, error) {
+	// SQLite doesn't support isolation levels, a deferred transaction keeps the snapshot, or the shared lock
+	// without WAL, from its first read
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	var tables int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master").Scan(&tables); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
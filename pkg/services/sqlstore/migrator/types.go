@@ -30,6 +30,13 @@ type CodeMigration interface {
 	Exec(sess *xorm.Session, migrator *Migrator) error
 }
 
+// DownMigration is implemented by the migrations that can be rolled back
+type DownMigration interface {
+	Migration
+	// DownSQL returns the SQL reverting the migration, empty if it can't be reverted
+	DownSQL(dialect Dialect) string
+}
+
 type SQLType string
 
 type ColumnType string
@@ -0,0 +1,131 @@
+package supportbundlesimpl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/plugincontext"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginstore"
+	"github.com/grafana/grafana/pkg/services/supportbundles"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+const (
+	healthCheckTimeout     = 30 * time.Second
+	healthCheckConcurrency = 5
+)
+
+type datasourceHealth struct {
+	ds      *datasources.DataSource
+	status  string
+	message string
+}
+
+func datasourceHealthCollector(
+	dataSourceService datasources.DataSourceService,
+	pluginStore pluginstore.Store,
+	pluginContextProvider *plugincontext.Provider,
+	pluginClient plugins.Client,
+) supportbundles.Collector {
+	checkHealth := func(ctx context.Context, ds *datasources.DataSource) (string, string) {
+		plugin, ok := pluginStore.Plugin(ctx, ds.Type)
+		if !ok {
+			return "PLUGIN NOT FOUND", ""
+		}
+		if !plugin.Backend {
+			return "NOT SUPPORTED", "frontend only data source"
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		defer cancel()
+
+		pCtx, err := pluginContextProvider.GetWithDataSource(ctx, ds.Type, supportBundleUser(ds.OrgID), ds)
+		if err != nil {
+			return "ERROR", err.Error()
+		}
+		resp, err := pluginClient.CheckHealth(ctx, &backend.CheckHealthRequest{
+			PluginContext: pCtx,
+			Headers:       map[string]string{},
+		})
+		if err != nil {
+			return "ERROR", err.Error()
+		}
+		return resp.Status.String(), resp.Message
+	}
+
+	collectorFn := func(ctx context.Context) (*supportbundles.SupportItem, error) {
+		dataSources, err := dataSourceService.GetAllDataSources(ctx, &datasources.GetAllDataSourcesQuery{})
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(dataSources, func(i, j int) bool {
+			if dataSources[i].OrgID != dataSources[j].OrgID {
+				return dataSources[i].OrgID < dataSources[j].OrgID
+			}
+			return dataSources[i].Name < dataSources[j].Name
+		})
+
+		results := make([]datasourceHealth, len(dataSources))
+		g, gCtx := errgroup.WithContext(ctx)
+		g.SetLimit(healthCheckConcurrency)
+		for i, ds := range dataSources {
+			g.Go(func() error {
+				status, message := checkHealth(gCtx, ds)
+				results[i] = datasourceHealth{ds: ds, status: status, message: message}
+				return nil
+			})
+		}
+		_ = g.Wait()
+
+		bWriter := bytes.NewBuffer(nil)
+		bWriter.WriteString("# Data source health\n\n")
+		bWriter.WriteString("| Org | Name | UID | Type | Status | Message |\n")
+		bWriter.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+		for _, r := range results {
+			bWriter.WriteString(fmt.Sprintf("| %d | %s | %s | %s | %s | %s |\n",
+				r.ds.OrgID, markdownCell(r.ds.Name), r.ds.UID, r.ds.Type, r.status, markdownCell(r.message)))
+		}
+
+		return &supportbundles.SupportItem{
+			Filename:  "datasource-health.md",
+			FileBytes: bWriter.Bytes(),
+		}, nil
+	}
+
+	return supportbundles.Collector{
+		UID:               "datasource-health",
+		DisplayName:       "Data source health",
+		Description:       "Results of the health checks of all the data sources, this queries every data source",
+		IncludedByDefault: false,
+		Default:           false,
+		Fn:                collectorFn,
+	}
+}
+
+// supportBundleUser is the identity the data sources are checked with, the health checks don't depend on the user
+func supportBundleUser(orgID int64) *user.SignedInUser {
+	return &user.SignedInUser{
+		OrgID: orgID,
+		Login: "support-bundle",
+		Permissions: map[int64]map[string][]string{
+			orgID: {
+				datasources.ActionRead:  {datasources.ScopeAll},
+				datasources.ActionQuery: {datasources.ScopeAll},
+			},
+		},
+	}
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.Join(strings.Fields(s), " ")
+}
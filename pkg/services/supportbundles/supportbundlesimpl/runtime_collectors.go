@@ -0,0 +1,128 @@
+package supportbundlesimpl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/supportbundles"
+)
+
+func goroutineCollector() supportbundles.Collector {
+	return supportbundles.Collector{
+		UID:               "goroutines",
+		DisplayName:       "Goroutine dump",
+		Description:       "Stack traces of all the goroutines of the Grafana server",
+		IncludedByDefault: false,
+		Default:           true,
+		Fn: func(ctx context.Context) (*supportbundles.SupportItem, error) {
+			var buf bytes.Buffer
+			// debug=2 prints the stacks in the format of an unrecovered panic, readable without tools
+			if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+				return nil, err
+			}
+
+			return &supportbundles.SupportItem{
+				Filename:  "goroutines.txt",
+				FileBytes: buf.Bytes(),
+			}, nil
+		},
+	}
+}
+
+func heapProfileCollector() supportbundles.Collector {
+	return supportbundles.Collector{
+		UID:               "heap-profile",
+		DisplayName:       "Heap profile",
+		Description:       "pprof profile of the memory allocations of the Grafana server",
+		IncludedByDefault: false,
+		Default:           true,
+		Fn: func(ctx context.Context) (*supportbundles.SupportItem, error) {
+			var buf bytes.Buffer
+			if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+				return nil, err
+			}
+
+			return &supportbundles.SupportItem{
+				Filename:  "heap.pprof",
+				FileBytes: buf.Bytes(),
+			}, nil
+		},
+	}
+}
+
+func cpuProfileCollector(duration time.Duration) supportbundles.Collector {
+	return supportbundles.Collector{
+		UID:               "cpu-profile",
+		DisplayName:       "CPU profile",
+		Description:       fmt.Sprintf("pprof profile of the CPU usage of the Grafana server, recorded for %s", duration),
+		IncludedByDefault: false,
+		Default:           false,
+		Fn: func(ctx context.Context) (*supportbundles.SupportItem, error) {
+			var buf bytes.Buffer
+			// fails when another CPU profile is being recorded, like one of the pprof endpoint
+			if err := pprof.StartCPUProfile(&buf); err != nil {
+				return nil, err
+			}
+
+			timer := time.NewTimer(duration)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+			pprof.StopCPUProfile()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			return &supportbundles.SupportItem{
+				Filename:  "cpu.pprof",
+				FileBytes: buf.Bytes(),
+			}, nil
+		},
+	}
+}
+
+func recentLogsCollector() supportbundles.Collector {
+	return supportbundles.Collector{
+		UID:               "logs",
+		DisplayName:       "Recent logs",
+		Description:       "Last lines logged by each logger of the Grafana server",
+		IncludedByDefault: false,
+		Default:           true,
+		EnabledFn:         func() bool { return log.RecentLines() != nil },
+		Fn: func(ctx context.Context) (*supportbundles.SupportItem, error) {
+			lines := log.RecentLines()
+
+			names := make([]string, 0, len(lines))
+			for name := range lines {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			bWriter := bytes.NewBuffer(nil)
+			bWriter.WriteString("# Recent logs\n")
+			for _, name := range names {
+				title := name
+				if title == "" {
+					title = "(no logger)"
+				}
+				bWriter.WriteString(fmt.Sprintf("\n## %s\n\n```\n", title))
+				for _, line := range lines[name] {
+					bWriter.WriteString(line + "\n")
+				}
+				bWriter.WriteString("```\n")
+			}
+
+			return &supportbundles.SupportItem{
+				Filename:  "logs.md",
+				FileBytes: bWriter.Bytes(),
+			}, nil
+		},
+	}
+}
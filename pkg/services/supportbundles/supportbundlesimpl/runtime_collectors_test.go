@@ -0,0 +1,56 @@
+package supportbundlesimpl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+func TestGoroutineCollector(t *testing.T) {
+	item, err := goroutineCollector().Fn(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "goroutines.txt", item.Filename)
+	require.Contains(t, string(item.FileBytes), "TestGoroutineCollector")
+}
+
+func TestCPUProfileCollector(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// the profile is dropped when the bundle times out
+	_, err := cpuProfileCollector(time.Hour).Fn(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+
+	item, err := cpuProfileCollector(10 * time.Millisecond).Fn(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "cpu.pprof", item.Filename)
+	require.NotEmpty(t, item.FileBytes)
+}
+
+func TestRecentLogsCollector(t *testing.T) {
+	cfg := ini.Empty()
+	_, err := cfg.Section("log").NewKey("recent_lines", "10")
+	require.NoError(t, err)
+	require.NoError(t, log.ReadLoggingConfig(nil, "", cfg))
+
+	collector := recentLogsCollector()
+	require.True(t, collector.EnabledFn())
+
+	log.New("supportbundle.test").Info("Collecting", "bundle", "abc")
+
+	item, err := collector.Fn(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "logs.md", item.Filename)
+	require.Contains(t, string(item.FileBytes), "## supportbundle.test\n\n```\n")
+	require.Contains(t, string(item.FileBytes), "msg=Collecting bundle=abc\n```\n")
+
+	_, err = cfg.Section("log").NewKey("recent_lines", "0")
+	require.NoError(t, err)
+	require.NoError(t, log.ReadLoggingConfig(nil, "", cfg))
+	require.False(t, collector.EnabledFn())
+}
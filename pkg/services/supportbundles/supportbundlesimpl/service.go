@@ -13,8 +13,11 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/tracing"
 	"github.com/grafana/grafana/pkg/infra/usagestats"
+	"github.com/grafana/grafana/pkg/plugins"
 	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/pluginsintegration/plugincontext"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginsettings"
 	"github.com/grafana/grafana/pkg/services/pluginsintegration/pluginstore"
 	"github.com/grafana/grafana/pkg/services/supportbundles"
@@ -58,7 +61,10 @@ func ProvideService(
 	settings setting.Provider,
 	sql db.DB,
 	usageStats usagestats.Service,
-	tracer tracing.Tracer) (*Service, error) {
+	tracer tracing.Tracer,
+	dataSourceService datasources.DataSourceService,
+	pluginContextProvider *plugincontext.Provider,
+	pluginClient plugins.Client) (*Service, error) {
 	section := cfg.SectionWithEnvOverrides("support_bundles")
 	s := &Service{
 		accessControl:        accessControl,
@@ -92,6 +98,11 @@ func ProvideService(
 	s.bundleRegistry.RegisterSupportItemCollector(settingsCollector(settings))
 	s.bundleRegistry.RegisterSupportItemCollector(dbCollector(sql))
 	s.bundleRegistry.RegisterSupportItemCollector(pluginInfoCollector(pluginStore, pluginSettings, s.log))
+	s.bundleRegistry.RegisterSupportItemCollector(goroutineCollector())
+	s.bundleRegistry.RegisterSupportItemCollector(heapProfileCollector())
+	s.bundleRegistry.RegisterSupportItemCollector(cpuProfileCollector(section.Key("cpu_profile_duration").MustDuration(10 * time.Second)))
+	s.bundleRegistry.RegisterSupportItemCollector(recentLogsCollector())
+	s.bundleRegistry.RegisterSupportItemCollector(datasourceHealthCollector(dataSourceService, pluginStore, pluginContextProvider, pluginClient))
 
 	return s, nil
 }
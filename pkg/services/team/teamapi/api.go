@@ -9,6 +9,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/licensing"
 	pref "github.com/grafana/grafana/pkg/services/preference"
 	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/teamsync"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
 )
@@ -22,6 +23,7 @@ type TeamAPI struct {
 	cfg                    *setting.Cfg
 	preferenceService      pref.Service
 	ds                     dashboards.DashboardService
+	teamSyncService        teamsync.Service
 	logger                 log.Logger
 }
 
@@ -36,6 +38,7 @@ func ProvideTeamAPI(
 	cfg *setting.Cfg,
 	preferenceService pref.Service,
 	ds dashboards.DashboardService,
+	teamSyncService teamsync.Service,
 ) *TeamAPI {
 	tapi := &TeamAPI{
 		teamService:            teamService,
@@ -46,6 +49,7 @@ func ProvideTeamAPI(
 		cfg:                    cfg,
 		preferenceService:      preferenceService,
 		ds:                     ds,
+		teamSyncService:        teamSyncService,
 		logger:                 log.New("team-api"),
 	}
 
@@ -79,6 +83,15 @@ func (tapi *TeamAPI) registerRoutes(router routing.RouteRegister, ac accesscontr
 				accesscontrol.ScopeTeamsID)), routing.Wrap(tapi.getTeamPreferences))
 			teamsRoute.Put("/:teamId/preferences", teamResolver, authorize(accesscontrol.EvalPermission(accesscontrol.ActionTeamsWrite,
 				accesscontrol.ScopeTeamsID)), routing.Wrap(tapi.updateTeamPreferences))
+			// the groups API of Grafana Enterprise team sync replaces this one when it's licensed
+			if !tapi.license.FeatureEnabled(teamsync.EnterpriseFeature) {
+				teamsRoute.Get("/:teamId/external-groups", teamResolver, authorize(accesscontrol.EvalPermission(accesscontrol.ActionTeamsPermissionsRead,
+					accesscontrol.ScopeTeamsID)), routing.Wrap(tapi.getTeamExternalGroups))
+				teamsRoute.Post("/:teamId/external-groups", teamResolver, authorize(accesscontrol.EvalPermission(accesscontrol.ActionTeamsPermissionsWrite,
+					accesscontrol.ScopeTeamsID)), routing.Wrap(tapi.addTeamExternalGroup))
+				teamsRoute.Delete("/:teamId/external-groups", teamResolver, authorize(accesscontrol.EvalPermission(accesscontrol.ActionTeamsPermissionsWrite,
+					accesscontrol.ScopeTeamsID)), routing.Wrap(tapi.removeTeamExternalGroup))
+			}
 		}, requestmeta.SetOwner(requestmeta.TeamAuth))
 
 		// team without requirement of user to be org admin
@@ -0,0 +1,139 @@
+package teamapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	contextmodel "github.com/grafana/grafana/pkg/services/contexthandler/model"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/teamsync"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// swagger:route GET /teams/{team_id}/external-groups team_external_groups getTeamExternalGroups
+//
+// Get External Groups.
+//
+// Responses:
+// 200: getTeamExternalGroupsResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (tapi *TeamAPI) getTeamExternalGroups(c *contextmodel.ReqContext) response.Response {
+	teamID, err := strconv.ParseInt(web.Params(c.Req)[":teamId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "teamId is invalid", err)
+	}
+
+	groups, err := tapi.teamSyncService.GetTeamGroups(c.Req.Context(), &teamsync.GetTeamGroupsQuery{OrgID: c.SignedInUser.GetOrgID(), TeamID: teamID})
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get team groups", err)
+	}
+
+	return response.JSON(http.StatusOK, groups)
+}
+
+// swagger:route POST /teams/{team_id}/external-groups team_external_groups addTeamExternalGroup
+//
+// Add External Group.
+//
+// Users authenticating through OAuth, JWT, LDAP or an auth proxy are added to the team when they are a member of the group.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (tapi *TeamAPI) addTeamExternalGroup(c *contextmodel.ReqContext) response.Response {
+	cmd := teamsync.AddTeamGroupCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	teamID, err := strconv.ParseInt(web.Params(c.Req)[":teamId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "teamId is invalid", err)
+	}
+	cmd.OrgID = c.SignedInUser.GetOrgID()
+	cmd.TeamID = teamID
+
+	if err := tapi.teamSyncService.AddTeamGroup(c.Req.Context(), &cmd); err != nil {
+		if errors.Is(err, team.ErrTeamNotFound) {
+			return response.Error(http.StatusNotFound, "Team not found", err)
+		}
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to add group to team", err)
+	}
+
+	return response.Success("Group added to Team")
+}
+
+// swagger:route DELETE /teams/{team_id}/external-groups team_external_groups removeTeamExternalGroup
+//
+// Remove External Group.
+//
+// Users who were added to the team for this group are removed from it the next time they authenticate.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (tapi *TeamAPI) removeTeamExternalGroup(c *contextmodel.ReqContext) response.Response {
+	teamID, err := strconv.ParseInt(web.Params(c.Req)[":teamId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "teamId is invalid", err)
+	}
+
+	groupID := c.Query("groupId")
+	if groupID == "" {
+		return response.Error(http.StatusBadRequest, "groupId is required", nil)
+	}
+
+	cmd := teamsync.RemoveTeamGroupCommand{OrgID: c.SignedInUser.GetOrgID(), TeamID: teamID, GroupID: groupID}
+	if err := tapi.teamSyncService.RemoveTeamGroup(c.Req.Context(), &cmd); err != nil {
+		return response.ErrOrFallback(http.StatusInternalServerError, "Failed to remove group from team", err)
+	}
+
+	return response.Success("Team Group removed")
+}
+
+// swagger:parameters getTeamExternalGroups
+type GetTeamExternalGroupsParams struct {
+	// in:path
+	// required:true
+	TeamID string `json:"team_id"`
+}
+
+// swagger:parameters addTeamExternalGroup
+type AddTeamExternalGroupParams struct {
+	// in:path
+	// required:true
+	TeamID string `json:"team_id"`
+	// in:body
+	// required:true
+	Body teamsync.AddTeamGroupCommand `json:"body"`
+}
+
+// swagger:parameters removeTeamExternalGroup
+type RemoveTeamExternalGroupParams struct {
+	// in:query
+	// required:true
+	GroupID string `json:"groupId"`
+	// in:path
+	// required:true
+	TeamID string `json:"team_id"`
+}
+
+// swagger:response getTeamExternalGroupsResponse
+type GetTeamExternalGroupsResponse struct {
+	// in: body
+	Body []*teamsync.TeamGroupDTO `json:"body"`
+}
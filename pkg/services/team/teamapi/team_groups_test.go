@@ -0,0 +1,151 @@
+package teamapi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/acimpl"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/actest"
+	"github.com/grafana/grafana/pkg/services/authz/zanzana"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/licensing/licensingtest"
+	"github.com/grafana/grafana/pkg/services/preference/preftest"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/team/teamtest"
+	"github.com/grafana/grafana/pkg/services/teamsync"
+	"github.com/grafana/grafana/pkg/services/teamsync/teamsynctest"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+func TestGetTeamExternalGroupsAPIEndpoint(t *testing.T) {
+	teamSyncService := &teamsynctest.FakeService{ExpectedTeamGroups: []*teamsync.TeamGroupDTO{{OrgID: 1, TeamID: 1, GroupID: "engineering"}}}
+	server := SetupAPITestServer(t, &teamtest.FakeService{ExpectedTeamDTO: &team.TeamDTO{ID: 1, UID: "a00001"}}, func(a *TeamAPI) {
+		a.teamSyncService = teamSyncService
+	})
+
+	t.Run("should be able to get team groups with correct permission", func(t *testing.T) {
+		req := webtest.RequestWithSignedInUser(
+			server.NewGetRequest("/api/teams/1/external-groups"),
+			authedUserWithPermissions(1, 1, []accesscontrol.Permission{{Action: accesscontrol.ActionTeamsPermissionsRead, Scope: "teams:id:1"}}),
+		)
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+
+	t.Run("should not be able to get team groups without correct permission", func(t *testing.T) {
+		req := webtest.RequestWithSignedInUser(
+			server.NewGetRequest("/api/teams/1/external-groups"),
+			authedUserWithPermissions(1, 1, []accesscontrol.Permission{{Action: accesscontrol.ActionTeamsPermissionsRead, Scope: "teams:id:2"}}),
+		)
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
+
+func TestAddTeamExternalGroupAPIEndpoint(t *testing.T) {
+	teamSyncService := &teamsynctest.FakeService{}
+	server := SetupAPITestServer(t, &teamtest.FakeService{ExpectedTeamDTO: &team.TeamDTO{ID: 1, UID: "a00001"}}, func(a *TeamAPI) {
+		a.teamSyncService = teamSyncService
+	})
+
+	t.Run("should be able to add team group with correct permission", func(t *testing.T) {
+		req := webtest.RequestWithSignedInUser(
+			server.NewRequest(http.MethodPost, "/api/teams/1/external-groups", strings.NewReader(`{"groupId": "engineering"}`)),
+			authedUserWithPermissions(1, 1, []accesscontrol.Permission{{Action: accesscontrol.ActionTeamsPermissionsWrite, Scope: "teams:id:1"}}),
+		)
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		require.Len(t, teamSyncService.AddTeamGroupCalls, 1)
+		assert.Equal(t, &teamsync.AddTeamGroupCommand{OrgID: 1, TeamID: 1, GroupID: "engineering"}, teamSyncService.AddTeamGroupCalls[0])
+	})
+
+	t.Run("should not be able to add team group without correct permission", func(t *testing.T) {
+		req := webtest.RequestWithSignedInUser(
+			server.NewRequest(http.MethodPost, "/api/teams/1/external-groups", strings.NewReader(`{"groupId": "engineering"}`)),
+			authedUserWithPermissions(1, 1, []accesscontrol.Permission{{Action: accesscontrol.ActionTeamsPermissionsRead, Scope: "teams:id:1"}}),
+		)
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
+
+func TestRemoveTeamExternalGroupAPIEndpoint(t *testing.T) {
+	teamSyncService := &teamsynctest.FakeService{}
+	server := SetupAPITestServer(t, &teamtest.FakeService{ExpectedTeamDTO: &team.TeamDTO{ID: 1, UID: "a00001"}}, func(a *TeamAPI) {
+		a.teamSyncService = teamSyncService
+	})
+
+	t.Run("should be able to remove team group with correct permission", func(t *testing.T) {
+		req := webtest.RequestWithSignedInUser(
+			server.NewRequest(http.MethodDelete, "/api/teams/1/external-groups?groupId=engineering", nil),
+			authedUserWithPermissions(1, 1, []accesscontrol.Permission{{Action: accesscontrol.ActionTeamsPermissionsWrite, Scope: "teams:id:1"}}),
+		)
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+
+		require.Len(t, teamSyncService.RemoveTeamGroupCalls, 1)
+		assert.Equal(t, &teamsync.RemoveTeamGroupCommand{OrgID: 1, TeamID: 1, GroupID: "engineering"}, teamSyncService.RemoveTeamGroupCalls[0])
+	})
+
+	t.Run("should return not found when the group isn't added to the team", func(t *testing.T) {
+		teamSyncService.ExpectedErr = teamsync.ErrGroupNotFound.Errorf("not found")
+		t.Cleanup(func() { teamSyncService.ExpectedErr = nil })
+
+		req := webtest.RequestWithSignedInUser(
+			server.NewRequest(http.MethodDelete, "/api/teams/1/external-groups?groupId=unknown", nil),
+			authedUserWithPermissions(1, 1, []accesscontrol.Permission{{Action: accesscontrol.ActionTeamsPermissionsWrite, Scope: "teams:id:1"}}),
+		)
+		res, err := server.SendJSON(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.NoError(t, res.Body.Close())
+	})
+}
+
+func TestTeamExternalGroupsAPIWithEnterpriseTeamSync(t *testing.T) {
+	router := routing.NewRouteRegister()
+	license := licensingtest.NewFakeLicensing()
+	license.On("FeatureEnabled", teamsync.EnterpriseFeature).Return(true)
+	ProvideTeamAPI(router,
+		&teamtest.FakeService{ExpectedTeamDTO: &team.TeamDTO{ID: 1, UID: "a00001"}},
+		actest.FakeService{},
+		acimpl.ProvideAccessControl(featuremgmt.WithFeatures(), zanzana.NewNoopClient()),
+		&actest.FakePermissionsService{},
+		&usertest.FakeUserService{},
+		license,
+		setting.NewCfg(),
+		preftest.NewPreferenceServiceFake(),
+		dashboards.NewFakeDashboardService(t),
+		&teamsynctest.FakeService{},
+	)
+	server := webtest.NewServer(t, router)
+
+	req := webtest.RequestWithSignedInUser(
+		server.NewGetRequest("/api/teams/1/external-groups"),
+		authedUserWithPermissions(1, 1, []accesscontrol.Permission{{Action: accesscontrol.ActionTeamsPermissionsRead, Scope: "teams:id:1"}}),
+	)
+	res, err := server.SendJSON(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "the API of Grafana Enterprise team sync is used instead")
+	require.NoError(t, res.Body.Close())
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/preference/preftest"
 	"github.com/grafana/grafana/pkg/services/team"
 	"github.com/grafana/grafana/pkg/services/team/teamtest"
+	"github.com/grafana/grafana/pkg/services/teamsync/teamsynctest"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/services/user/usertest"
 	"github.com/grafana/grafana/pkg/setting"
@@ -48,6 +49,7 @@ func SetupAPITestServer(t *testing.T, teamService team.Service, opts ...func(a *
 		cfg,
 		preftest.NewPreferenceServiceFake(),
 		dashboards.NewFakeDashboardService(t),
+		&teamsynctest.FakeService{},
 	)
 	for _, o := range opts {
 		o(a)
@@ -303,6 +305,7 @@ func Test_getTeamMembershipUpdates(t *testing.T) {
 				cfg,
 				preftest.NewPreferenceServiceFake(),
 				dashboards.NewFakeDashboardService(t),
+				&teamsynctest.FakeService{},
 			)
 
 			user := &user.SignedInUser{UserID: 1, OrgID: 1, OrgRole: org.RoleAdmin, Permissions: map[int64]map[string][]string{1: {accesscontrol.ActionOrgUsersRead: {"users:id:*"}}}}
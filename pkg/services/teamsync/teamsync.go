@@ -0,0 +1,92 @@
+package teamsync
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/apimachinery/errutil"
+)
+
+// EnterpriseFeature is the licensed feature of Grafana Enterprise team sync. When it's enabled, Grafana Enterprise
+// syncs the teams of users with its own groups, and this service is not used.
+const EnterpriseFeature = "teamgroupsync"
+
+var (
+	ErrGroupAlreadyAdded = errutil.BadRequest("teamsync.group-already-added", errutil.WithPublicMessage("Group is already added to this team"))
+	ErrGroupNotFound     = errutil.NotFound("teamsync.group-not-found", errutil.WithPublicMessage("Group not found"))
+	ErrEmptyGroupID      = errutil.BadRequest("teamsync.empty-group-id", errutil.WithPublicMessage("Group ID cannot be empty"))
+)
+
+// Service maps the groups of external identity providers to teams.
+// Users authenticating through OAuth, JWT, LDAP or an auth proxy are added to the teams of their groups.
+type Service interface {
+	// GetTeamGroups returns the external groups mapped to a team
+	GetTeamGroups(ctx context.Context, query *GetTeamGroupsQuery) ([]*TeamGroupDTO, error)
+	// AddTeamGroup maps an external group to a team
+	AddTeamGroup(ctx context.Context, cmd *AddTeamGroupCommand) error
+	// RemoveTeamGroup removes the mapping of an external group to a team
+	RemoveTeamGroup(ctx context.Context, cmd *RemoveTeamGroupCommand) error
+	// SyncUserTeams adds a user to the teams of its groups and removes it from the teams it was added to
+	// for groups it is no longer a member of. Memberships managed in Grafana are left untouched.
+	SyncUserTeams(ctx context.Context, cmd *SyncUserTeamsCommand) (*SyncResult, error)
+}
+
+// TeamGroup maps an external group to a team
+type TeamGroup struct {
+	ID      int64     `xorm:"pk autoincr 'id'"`
+	OrgID   int64     `xorm:"org_id"`
+	TeamID  int64     `xorm:"team_id"`
+	GroupID string    `xorm:"group_id"`
+	Created time.Time `xorm:"created"`
+	Updated time.Time `xorm:"updated"`
+}
+
+func (TeamGroup) TableName() string {
+	return "team_external_group"
+}
+
+type TeamGroupDTO struct {
+	OrgID   int64  `json:"orgId" xorm:"org_id"`
+	TeamID  int64  `json:"teamId" xorm:"team_id"`
+	GroupID string `json:"groupId" xorm:"group_id"`
+}
+
+// ---------------------
+// QUERIES
+
+type GetTeamGroupsQuery struct {
+	OrgID  int64
+	TeamID int64
+}
+
+// ---------------------
+// COMMANDS
+
+type AddTeamGroupCommand struct {
+	OrgID   int64  `json:"-"`
+	TeamID  int64  `json:"-"`
+	GroupID string `json:"groupId" binding:"Required"`
+}
+
+type RemoveTeamGroupCommand struct {
+	OrgID   int64
+	TeamID  int64
+	GroupID string
+}
+
+type SyncUserTeamsCommand struct {
+	UserID int64
+	// Groups are the groups of the user in the external identity provider
+	Groups []string
+}
+
+// SyncResult lists the team memberships changed by a sync
+type SyncResult struct {
+	Added   []TeamMembership
+	Removed []TeamMembership
+}
+
+type TeamMembership struct {
+	OrgID  int64
+	TeamID int64
+}
@@ -0,0 +1,95 @@
+package teamsyncimpl
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/teamsync"
+)
+
+type store interface {
+	GetTeamGroups(ctx context.Context, query *teamsync.GetTeamGroupsQuery) ([]*teamsync.TeamGroupDTO, error)
+	// GetByGroups returns the mappings of groups in every organization
+	GetByGroups(ctx context.Context, groups []string) ([]*teamsync.TeamGroupDTO, error)
+	Add(ctx context.Context, cmd *teamsync.AddTeamGroupCommand) error
+	Remove(ctx context.Context, cmd *teamsync.RemoveTeamGroupCommand) error
+}
+
+type xormStore struct {
+	db  db.DB
+	now func() time.Time
+}
+
+func (ss *xormStore) GetTeamGroups(ctx context.Context, query *teamsync.GetTeamGroupsQuery) ([]*teamsync.TeamGroupDTO, error) {
+	result := make([]*teamsync.TeamGroupDTO, 0)
+	err := ss.db.WithDbSession(ctx, func(sess *db.Session) error {
+		return sess.Table("team_external_group").
+			Cols("org_id", "team_id", "group_id").
+			Where("org_id = ? AND team_id = ?", query.OrgID, query.TeamID).
+			OrderBy("group_id").
+			Find(&result)
+	})
+	return result, err
+}
+
+func (ss *xormStore) GetByGroups(ctx context.Context, groups []string) ([]*teamsync.TeamGroupDTO, error) {
+	result := make([]*teamsync.TeamGroupDTO, 0)
+	if len(groups) == 0 {
+		return result, nil
+	}
+
+	err := ss.db.WithDbSession(ctx, func(sess *db.Session) error {
+		// groups are matched case insensitively
+		params := make([]any, 0, len(groups))
+		for _, group := range groups {
+			params = append(params, strings.ToLower(group))
+		}
+
+		return sess.Table("team_external_group").
+			Cols("org_id", "team_id", "group_id").
+			Where("LOWER(group_id) IN (?"+strings.Repeat(",?", len(groups)-1)+")", params...).
+			Find(&result)
+	})
+	return result, err
+}
+
+func (ss *xormStore) Add(ctx context.Context, cmd *teamsync.AddTeamGroupCommand) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		exists, err := sess.Where("org_id = ? AND team_id = ? AND group_id = ?", cmd.OrgID, cmd.TeamID, cmd.GroupID).Exist(&teamsync.TeamGroup{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return teamsync.ErrGroupAlreadyAdded.Errorf("group %q is already added to team %d", cmd.GroupID, cmd.TeamID)
+		}
+
+		now := ss.now()
+		_, err = sess.Insert(&teamsync.TeamGroup{
+			OrgID:   cmd.OrgID,
+			TeamID:  cmd.TeamID,
+			GroupID: cmd.GroupID,
+			Created: now,
+			Updated: now,
+		})
+		return err
+	})
+}
+
+func (ss *xormStore) Remove(ctx context.Context, cmd *teamsync.RemoveTeamGroupCommand) error {
+	return ss.db.WithDbSession(ctx, func(sess *db.Session) error {
+		res, err := sess.Exec("DELETE FROM team_external_group WHERE org_id = ? AND team_id = ? AND group_id = ?", cmd.OrgID, cmd.TeamID, cmd.GroupID)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return teamsync.ErrGroupNotFound.Errorf("group %q is not added to team %d", cmd.GroupID, cmd.TeamID)
+		}
+		return nil
+	})
+}
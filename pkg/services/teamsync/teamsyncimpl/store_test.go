@@ -0,0 +1,72 @@
+package teamsyncimpl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/services/teamsync"
+	"github.com/grafana/grafana/pkg/tests/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	testsuite.Run(m)
+}
+
+func TestIntegrationTeamGroupStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	s := &xormStore{db: db.InitTestDB(t), now: func() time.Time { return now }}
+
+	require.NoError(t, s.Add(ctx, &teamsync.AddTeamGroupCommand{OrgID: 1, TeamID: 1, GroupID: "Engineering"}))
+	require.NoError(t, s.Add(ctx, &teamsync.AddTeamGroupCommand{OrgID: 1, TeamID: 1, GroupID: "admins"}))
+	require.NoError(t, s.Add(ctx, &teamsync.AddTeamGroupCommand{OrgID: 2, TeamID: 3, GroupID: "engineering"}))
+
+	t.Run("should list the groups of a team", func(t *testing.T) {
+		groups, err := s.GetTeamGroups(ctx, &teamsync.GetTeamGroupsQuery{OrgID: 1, TeamID: 1})
+		require.NoError(t, err)
+		assert.Equal(t, []*teamsync.TeamGroupDTO{
+			{OrgID: 1, TeamID: 1, GroupID: "Engineering"},
+			{OrgID: 1, TeamID: 1, GroupID: "admins"},
+		}, groups)
+	})
+
+	t.Run("should not add a group twice", func(t *testing.T) {
+		err := s.Add(ctx, &teamsync.AddTeamGroupCommand{OrgID: 1, TeamID: 1, GroupID: "admins"})
+		assert.ErrorIs(t, err, teamsync.ErrGroupAlreadyAdded)
+	})
+
+	t.Run("should match groups case insensitively across organizations", func(t *testing.T) {
+		groups, err := s.GetByGroups(ctx, []string{"ENGINEERING"})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []*teamsync.TeamGroupDTO{
+			{OrgID: 1, TeamID: 1, GroupID: "Engineering"},
+			{OrgID: 2, TeamID: 3, GroupID: "engineering"},
+		}, groups)
+	})
+
+	t.Run("should return nothing without groups", func(t *testing.T) {
+		groups, err := s.GetByGroups(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, groups)
+	})
+
+	t.Run("should remove a group", func(t *testing.T) {
+		require.NoError(t, s.Remove(ctx, &teamsync.RemoveTeamGroupCommand{OrgID: 1, TeamID: 1, GroupID: "admins"}))
+
+		err := s.Remove(ctx, &teamsync.RemoveTeamGroupCommand{OrgID: 1, TeamID: 1, GroupID: "admins"})
+		assert.ErrorIs(t, err, teamsync.ErrGroupNotFound)
+
+		groups, err := s.GetTeamGroups(ctx, &teamsync.GetTeamGroupsQuery{OrgID: 1, TeamID: 1})
+		require.NoError(t, err)
+		assert.Len(t, groups, 1)
+	})
+}
@@ -0,0 +1,162 @@
+package teamsyncimpl
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/teamsync"
+)
+
+// memberPermission is the permission of the memberships added by team sync
+const memberPermission = "Member"
+
+var _ teamsync.Service = (*Service)(nil)
+
+func ProvideService(db db.DB, teamService team.Service, teamPermissionsService accesscontrol.TeamPermissionsService, orgService org.Service) *Service {
+	// mappings are removed with their team
+	teamService.RegisterDelete("DELETE FROM team_external_group WHERE org_id = ? AND team_id = ?")
+
+	return &Service{
+		store:                  &xormStore{db: db, now: time.Now},
+		teamService:            teamService,
+		teamPermissionsService: teamPermissionsService,
+		orgService:             orgService,
+		log:                    log.New("teamsync"),
+	}
+}
+
+type Service struct {
+	store                  store
+	teamService            team.Service
+	teamPermissionsService accesscontrol.TeamPermissionsService
+	orgService             org.Service
+	log                    log.Logger
+}
+
+func (s *Service) GetTeamGroups(ctx context.Context, query *teamsync.GetTeamGroupsQuery) ([]*teamsync.TeamGroupDTO, error) {
+	return s.store.GetTeamGroups(ctx, query)
+}
+
+func (s *Service) AddTeamGroup(ctx context.Context, cmd *teamsync.AddTeamGroupCommand) error {
+	cmd.GroupID = strings.TrimSpace(cmd.GroupID)
+	if cmd.GroupID == "" {
+		return teamsync.ErrEmptyGroupID.Errorf("empty group id for team %d", cmd.TeamID)
+	}
+
+	if _, err := s.teamService.GetTeamByID(ctx, &team.GetTeamByIDQuery{OrgID: cmd.OrgID, ID: cmd.TeamID}); err != nil {
+		return err
+	}
+
+	if err := s.store.Add(ctx, cmd); err != nil {
+		return err
+	}
+
+	s.log.FromContext(ctx).Info("Added group to team", "orgId", cmd.OrgID, "teamId", cmd.TeamID, "group", cmd.GroupID)
+	return nil
+}
+
+func (s *Service) RemoveTeamGroup(ctx context.Context, cmd *teamsync.RemoveTeamGroupCommand) error {
+	if err := s.store.Remove(ctx, cmd); err != nil {
+		return err
+	}
+
+	s.log.FromContext(ctx).Info("Removed group from team", "orgId", cmd.OrgID, "teamId", cmd.TeamID, "group", cmd.GroupID)
+	return nil
+}
+
+func (s *Service) SyncUserTeams(ctx context.Context, cmd *teamsync.SyncUserTeamsCommand) (*teamsync.SyncResult, error) {
+	ctxLogger := s.log.FromContext(ctx).New("userId", cmd.UserID)
+	result := &teamsync.SyncResult{}
+
+	mappings, err := s.store.GetByGroups(ctx, cmd.Groups)
+	if err != nil {
+		return nil, err
+	}
+
+	// groups of the user granting the membership of each team, by organization
+	wanted := map[int64]map[int64][]string{}
+	for _, m := range mappings {
+		if wanted[m.OrgID] == nil {
+			wanted[m.OrgID] = map[int64][]string{}
+		}
+		wanted[m.OrgID][m.TeamID] = append(wanted[m.OrgID][m.TeamID], m.GroupID)
+	}
+
+	orgs, err := s.orgService.GetUserOrgList(ctx, &org.GetUserOrgListQuery{UserID: cmd.UserID})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range orgs {
+		teams := wanted[o.OrgID]
+		delete(wanted, o.OrgID)
+
+		current, err := s.teamService.GetUserTeamMemberships(ctx, o.OrgID, cmd.UserID, true)
+		if err != nil {
+			return result, err
+		}
+
+		synced := make(map[int64]bool, len(current))
+		for _, m := range current {
+			synced[m.TeamID] = true
+			if _, ok := teams[m.TeamID]; ok {
+				continue
+			}
+
+			if err := s.setMembership(ctx, o.OrgID, m.TeamID, cmd.UserID, ""); err != nil {
+				return result, err
+			}
+			result.Removed = append(result.Removed, teamsync.TeamMembership{OrgID: o.OrgID, TeamID: m.TeamID})
+			ctxLogger.Info("Removed user from team, it is no longer a member of the groups of the team", "orgId", o.OrgID, "teamId", m.TeamID)
+		}
+
+		for _, teamID := range sortedTeamIDs(teams) {
+			if synced[teamID] {
+				continue
+			}
+
+			// memberships managed in Grafana are kept as they are
+			isMember, err := s.teamService.IsTeamMember(ctx, o.OrgID, teamID, cmd.UserID)
+			if err != nil {
+				return result, err
+			}
+			if isMember {
+				continue
+			}
+
+			if err := s.setMembership(ctx, o.OrgID, teamID, cmd.UserID, memberPermission); err != nil {
+				return result, err
+			}
+			result.Added = append(result.Added, teamsync.TeamMembership{OrgID: o.OrgID, TeamID: teamID})
+			ctxLogger.Info("Added user to team", "orgId", o.OrgID, "teamId", teamID, "groups", teams[teamID])
+		}
+	}
+
+	for orgID := range wanted {
+		ctxLogger.Debug("Skipping teams of an organization the user isn't a member of", "orgId", orgID)
+	}
+
+	return result, nil
+}
+
+func (s *Service) setMembership(ctx context.Context, orgID, teamID, userID int64, permission string) error {
+	_, err := s.teamPermissionsService.SetUserPermission(ctx, orgID, accesscontrol.User{ID: userID, IsExternal: true}, strconv.FormatInt(teamID, 10), permission)
+	return err
+}
+
+func sortedTeamIDs(teams map[int64][]string) []int64 {
+	ids := make([]int64, 0, len(teams))
+	for id := range teams {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
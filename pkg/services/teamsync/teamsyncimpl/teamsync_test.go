@@ -0,0 +1,156 @@
+package teamsyncimpl
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/actest"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgtest"
+	"github.com/grafana/grafana/pkg/services/team"
+	"github.com/grafana/grafana/pkg/services/team/teamtest"
+	"github.com/grafana/grafana/pkg/services/teamsync"
+)
+
+func TestService_AddTeamGroup(t *testing.T) {
+	t.Run("should reject empty groups", func(t *testing.T) {
+		s := setupTestService(&fakeStore{}, &teamtest.FakeService{}, nil)
+		err := s.AddTeamGroup(context.Background(), &teamsync.AddTeamGroupCommand{OrgID: 1, TeamID: 1, GroupID: "  "})
+		assert.ErrorIs(t, err, teamsync.ErrEmptyGroupID)
+	})
+
+	t.Run("should reject unknown teams", func(t *testing.T) {
+		s := setupTestService(&fakeStore{}, &teamtest.FakeService{ExpectedError: team.ErrTeamNotFound}, nil)
+		err := s.AddTeamGroup(context.Background(), &teamsync.AddTeamGroupCommand{OrgID: 1, TeamID: 1, GroupID: "admins"})
+		assert.ErrorIs(t, err, team.ErrTeamNotFound)
+	})
+
+	t.Run("should add trimmed group", func(t *testing.T) {
+		store := &fakeStore{}
+		s := setupTestService(store, &teamtest.FakeService{ExpectedTeamDTO: &team.TeamDTO{ID: 1}}, nil)
+		err := s.AddTeamGroup(context.Background(), &teamsync.AddTeamGroupCommand{OrgID: 1, TeamID: 1, GroupID: " admins "})
+		require.NoError(t, err)
+		assert.Equal(t, []*teamsync.TeamGroupDTO{{OrgID: 1, TeamID: 1, GroupID: "admins"}}, store.groups)
+	})
+}
+
+func TestService_SyncUserTeams(t *testing.T) {
+	store := &fakeStore{groups: []*teamsync.TeamGroupDTO{
+		{OrgID: 1, TeamID: 2, GroupID: "engineering"},
+		{OrgID: 1, TeamID: 3, GroupID: "Admins"},
+		{OrgID: 5, TeamID: 6, GroupID: "engineering"},
+	}}
+
+	t.Run("should add user to the teams of its groups", func(t *testing.T) {
+		perms := &recordingPermissionsService{}
+		s := setupTestService(store, &teamtest.FakeService{}, perms)
+
+		result, err := s.SyncUserTeams(context.Background(), &teamsync.SyncUserTeamsCommand{UserID: 10, Groups: []string{"engineering", "admins"}})
+		require.NoError(t, err)
+		assert.Equal(t, []teamsync.TeamMembership{{OrgID: 1, TeamID: 2}, {OrgID: 1, TeamID: 3}}, result.Added)
+		assert.Empty(t, result.Removed)
+		assert.Equal(t, []setPermissionCall{
+			{orgID: 1, userID: 10, external: true, teamID: "2", permission: memberPermission},
+			{orgID: 1, userID: 10, external: true, teamID: "3", permission: memberPermission},
+		}, perms.calls)
+	})
+
+	t.Run("should remove user from teams of groups it is no longer a member of", func(t *testing.T) {
+		perms := &recordingPermissionsService{}
+		teamSvc := &teamtest.FakeService{ExpectedMembers: []*team.TeamMemberDTO{
+			{OrgID: 1, TeamID: 2, UserID: 10, External: true},
+			{OrgID: 1, TeamID: 4, UserID: 10, External: true},
+		}}
+		s := setupTestService(store, teamSvc, perms)
+
+		result, err := s.SyncUserTeams(context.Background(), &teamsync.SyncUserTeamsCommand{UserID: 10, Groups: []string{"engineering"}})
+		require.NoError(t, err)
+		assert.Empty(t, result.Added)
+		assert.Equal(t, []teamsync.TeamMembership{{OrgID: 1, TeamID: 4}}, result.Removed)
+		assert.Equal(t, []setPermissionCall{
+			{orgID: 1, userID: 10, external: true, teamID: "4", permission: ""},
+		}, perms.calls)
+	})
+
+	t.Run("should keep memberships managed in Grafana", func(t *testing.T) {
+		perms := &recordingPermissionsService{}
+		s := setupTestService(store, &teamtest.FakeService{ExpectedIsMember: true}, perms)
+
+		result, err := s.SyncUserTeams(context.Background(), &teamsync.SyncUserTeamsCommand{UserID: 10, Groups: []string{"engineering"}})
+		require.NoError(t, err)
+		assert.Empty(t, result.Added)
+		assert.Empty(t, result.Removed)
+		assert.Empty(t, perms.calls)
+	})
+}
+
+func setupTestService(store store, teamSvc *teamtest.FakeService, perms accesscontrol.TeamPermissionsService) *Service {
+	orgSvc := orgtest.NewOrgServiceFake()
+	orgSvc.ExpectedUserOrgDTO = []*org.UserOrgDTO{{OrgID: 1}}
+	return &Service{
+		store:                  store,
+		teamService:            teamSvc,
+		teamPermissionsService: perms,
+		orgService:             orgSvc,
+		log:                    log.NewNopLogger(),
+	}
+}
+
+type fakeStore struct {
+	groups []*teamsync.TeamGroupDTO
+}
+
+func (f *fakeStore) GetTeamGroups(ctx context.Context, query *teamsync.GetTeamGroupsQuery) ([]*teamsync.TeamGroupDTO, error) {
+	result := make([]*teamsync.TeamGroupDTO, 0)
+	for _, g := range f.groups {
+		if g.OrgID == query.OrgID && g.TeamID == query.TeamID {
+			result = append(result, g)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeStore) GetByGroups(ctx context.Context, groups []string) ([]*teamsync.TeamGroupDTO, error) {
+	result := make([]*teamsync.TeamGroupDTO, 0)
+	for _, g := range f.groups {
+		for _, group := range groups {
+			if strings.EqualFold(g.GroupID, group) {
+				result = append(result, g)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeStore) Add(ctx context.Context, cmd *teamsync.AddTeamGroupCommand) error {
+	f.groups = append(f.groups, &teamsync.TeamGroupDTO{OrgID: cmd.OrgID, TeamID: cmd.TeamID, GroupID: cmd.GroupID})
+	return nil
+}
+
+func (f *fakeStore) Remove(ctx context.Context, cmd *teamsync.RemoveTeamGroupCommand) error {
+	return nil
+}
+
+type setPermissionCall struct {
+	orgID      int64
+	userID     int64
+	external   bool
+	teamID     string
+	permission string
+}
+
+type recordingPermissionsService struct {
+	actest.FakePermissionsService
+	calls []setPermissionCall
+}
+
+func (r *recordingPermissionsService) SetUserPermission(ctx context.Context, orgID int64, user accesscontrol.User, resourceID, permission string) (*accesscontrol.ResourcePermission, error) {
+	r.calls = append(r.calls, setPermissionCall{orgID: orgID, userID: user.ID, external: user.IsExternal, teamID: resourceID, permission: permission})
+	return nil, nil
+}
@@ -0,0 +1,38 @@
+package teamsynctest
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/teamsync"
+)
+
+var _ teamsync.Service = new(FakeService)
+
+type FakeService struct {
+	ExpectedTeamGroups []*teamsync.TeamGroupDTO
+	ExpectedSyncResult *teamsync.SyncResult
+	ExpectedErr        error
+
+	AddTeamGroupCalls    []*teamsync.AddTeamGroupCommand
+	RemoveTeamGroupCalls []*teamsync.RemoveTeamGroupCommand
+	SyncUserTeamsCalls   []*teamsync.SyncUserTeamsCommand
+}
+
+func (f *FakeService) GetTeamGroups(ctx context.Context, query *teamsync.GetTeamGroupsQuery) ([]*teamsync.TeamGroupDTO, error) {
+	return f.ExpectedTeamGroups, f.ExpectedErr
+}
+
+func (f *FakeService) AddTeamGroup(ctx context.Context, cmd *teamsync.AddTeamGroupCommand) error {
+	f.AddTeamGroupCalls = append(f.AddTeamGroupCalls, cmd)
+	return f.ExpectedErr
+}
+
+func (f *FakeService) RemoveTeamGroup(ctx context.Context, cmd *teamsync.RemoveTeamGroupCommand) error {
+	f.RemoveTeamGroupCalls = append(f.RemoveTeamGroupCalls, cmd)
+	return f.ExpectedErr
+}
+
+func (f *FakeService) SyncUserTeams(ctx context.Context, cmd *teamsync.SyncUserTeamsCommand) (*teamsync.SyncResult, error) {
+	f.SyncUserTeamsCalls = append(f.SyncUserTeamsCalls, cmd)
+	return f.ExpectedSyncResult, f.ExpectedErr
+}
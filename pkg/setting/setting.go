@@ -158,6 +158,7 @@ type Cfg struct {
 	// Security
 	DisableInitAdminCreation          bool
 	DisableBruteForceLoginProtection  bool
+	BruteForceLoginProtection         BruteForceLoginProtectionSettings
 	CookieSecure                      bool
 	CookieSameSiteDisabled            bool
 	CookieSameSiteMode                http.SameSite
@@ -181,6 +182,8 @@ type Cfg struct {
 	DisableGravatar                 bool
 	DataProxyWhiteList              map[string]bool
 	ActionsAllowPostURL             string
+	// BackupAPIEnabled exposes the backup of the instance, decrypted data keys included, to server admins
+	BackupAPIEnabled bool
 
 	TempDataLifetime time.Duration
 
@@ -236,6 +239,9 @@ type Cfg struct {
 	AzureSkipOrgRoleSync          bool
 	BasicAuthEnabled              bool
 	BasicAuthStrongPasswordPolicy bool
+	MFAEnabled                    bool
+	MFARequired                   bool
+	MFAIssuer                     string
 	AdminUser                     string
 	AdminPassword                 string
 	DisableLogin                  bool
@@ -271,6 +277,9 @@ type Cfg struct {
 	JWTAuth    AuthJWTSettings
 	ExtJWTAuth ExtJWTSettings
 
+	// SCIM provisioning
+	SCIM SCIMSettings
+
 	// SSO Settings Auth
 	SSOSettingsReloadInterval        time.Duration
 	SSOSettingsConfigurableProviders map[string]bool
@@ -344,6 +353,7 @@ type Cfg struct {
 	SqlDatasourceMaxOpenConnsDefault    int
 	SqlDatasourceMaxIdleConnsDefault    int
 	SqlDatasourceMaxConnLifetimeDefault int
+	SQLiteDatasourceAllowedPaths        []string
 
 	// Snapshots
 	SnapshotEnabled      bool
@@ -353,6 +363,8 @@ type Cfg struct {
 
 	// Only used in https://snapshots.raintank.io/
 	SnapshotPublicMode bool
+	// Dashboards captured periodically into server-side snapshots
+	SnapshotCaptureSchedules []SnapshotCaptureSchedule
 
 	ErrTemplateName string
 
@@ -454,6 +466,11 @@ type Cfg struct {
 	// Query history
 	QueryHistoryEnabled bool
 
+	SlowQueryLog SlowQueryLogSettings
+
+	// Scheduled dashboard reports
+	Reporting ReportingSettings
+
 	Storage StorageSettings
 
 	Search SearchSettings
@@ -497,6 +514,8 @@ type Cfg struct {
 
 	// Public dashboards
 	PublicDashboardsEnabled bool
+	// PublicDashboardsViewsRetention is how long the views of public dashboards are kept in the audit log
+	PublicDashboardsViewsRetention time.Duration
 
 	// Cloud Migration
 	CloudMigration CloudMigrationSettings
@@ -1136,6 +1155,10 @@ func (cfg *Cfg) parseINIFile(iniFile *ini.File) error {
 		return err
 	}
 
+	if err := cfg.readReportingSettings(iniFile); err != nil {
+		return err
+	}
+
 	cfg.TempDataLifetime = iniFile.Section("paths").Key("temp_data_lifetime").MustDuration(time.Second * 3600 * 24)
 	cfg.MetricsEndpointEnabled = iniFile.Section("metrics").Key("enabled").MustBool(true)
 	cfg.MetricsEndpointBasicAuthUsername = valueAsString(iniFile.Section("metrics"), "basic_auth_username", "")
@@ -1206,6 +1229,8 @@ func (cfg *Cfg) parseINIFile(iniFile *ini.File) error {
 	queryHistory := iniFile.Section("query_history")
 	cfg.QueryHistoryEnabled = queryHistory.Key("enabled").MustBool(true)
 
+	cfg.SlowQueryLog = readSlowQueryLogSettings(iniFile)
+
 	shortLinks := iniFile.Section("short_links")
 	cfg.ShortLinkExpiration = shortLinks.Key("expire_time").MustInt(7)
 
@@ -1241,6 +1266,7 @@ func (cfg *Cfg) parseINIFile(iniFile *ini.File) error {
 	cfg.readAzureSettings()
 	cfg.readAuthJWTSettings()
 	cfg.readAuthExtJWTSettings()
+	cfg.readSCIMSettings()
 	cfg.readAuthProxySettings()
 	cfg.readSessionConfig()
 	if err := cfg.readSmtpSettings(); err != nil {
@@ -1331,7 +1357,9 @@ func (cfg *Cfg) parseINIFile(iniFile *ini.File) error {
 	cfg.UserFacingDefaultError = logSection.Key("user_facing_default_error").MustString("please inspect Grafana server log for details")
 
 	cfg.readFeatureManagementConfig()
-	cfg.readPublicDashboardsSettings()
+	if err := cfg.readPublicDashboardsSettings(); err != nil {
+		return err
+	}
 	cfg.readCloudMigrationSettings()
 
 	// read experimental scopes settings.
@@ -1518,6 +1546,7 @@ func readSecuritySettings(iniFile *ini.File, cfg *Cfg) error {
 	cfg.SecretKey = valueAsString(security, "secret_key", "")
 	cfg.DisableGravatar = security.Key("disable_gravatar").MustBool(true)
 	cfg.DisableBruteForceLoginProtection = security.Key("disable_brute_force_login_protection").MustBool(false)
+	cfg.readBruteForceLoginProtectionSettings(security)
 
 	CookieSecure = security.Key("cookie_secure").MustBool(false)
 	cfg.CookieSecure = CookieSecure
@@ -1547,6 +1576,7 @@ func readSecuritySettings(iniFile *ini.File, cfg *Cfg) error {
 	cfg.ContentTypeProtectionHeader = security.Key("x_content_type_options").MustBool(true)
 	cfg.XSSProtectionHeader = security.Key("x_xss_protection").MustBool(true)
 	cfg.ActionsAllowPostURL = security.Key("actions_allow_post_url").MustString("")
+	cfg.BackupAPIEnabled = security.Key("backup_api_enabled").MustBool(false)
 	cfg.StrictTransportSecurity = security.Key("strict_transport_security").MustBool(false)
 	cfg.StrictTransportSecurityMaxAge = security.Key("strict_transport_security_max_age_seconds").MustInt(86400)
 	cfg.StrictTransportSecurityPreload = security.Key("strict_transport_security_preload").MustBool(false)
@@ -1665,6 +1695,12 @@ func readAuthSettings(iniFile *ini.File, cfg *Cfg) (err error) {
 	cfg.BasicAuthEnabled = authBasic.Key("enabled").MustBool(true)
 	cfg.BasicAuthStrongPasswordPolicy = authBasic.Key("password_policy").MustBool(false)
 
+	// multi-factor authentication
+	authMFA := iniFile.Section("auth.mfa")
+	cfg.MFAEnabled = authMFA.Key("enabled").MustBool(true)
+	cfg.MFARequired = authMFA.Key("required").MustBool(false)
+	cfg.MFAIssuer = valueAsString(authMFA, "issuer", "Grafana")
+
 	// SSO Settings
 	ssoSettings := iniFile.Section("sso_settings")
 	cfg.SSOSettingsReloadInterval = ssoSettings.Key("reload_interval").MustDuration(1 * time.Minute)
@@ -1887,7 +1923,7 @@ func readSnapshotsSettings(cfg *Cfg, iniFile *ini.File) error {
 	cfg.ExternalEnabled = snapshots.Key("external_enabled").MustBool(true)
 	cfg.SnapshotPublicMode = snapshots.Key("public_mode").MustBool(false)
 
-	return nil
+	return readSnapshotCaptureSettings(cfg, iniFile)
 }
 
 func (cfg *Cfg) readServerSettings(iniFile *ini.File) error {
@@ -2009,6 +2045,7 @@ func (cfg *Cfg) readSqlDataSourceSettings() {
 	cfg.SqlDatasourceMaxOpenConnsDefault = sqlDatasources.Key("max_open_conns_default").MustInt(100)
 	cfg.SqlDatasourceMaxIdleConnsDefault = sqlDatasources.Key("max_idle_conns_default").MustInt(100)
 	cfg.SqlDatasourceMaxConnLifetimeDefault = sqlDatasources.Key("max_conn_lifetime_default").MustInt(14400)
+	cfg.SQLiteDatasourceAllowedPaths = util.SplitString(sqlDatasources.Key("sqlite_allowed_paths").String())
 }
 
 func GetAllowedOriginGlobs(originPatterns []string) ([]glob.Glob, error) {
@@ -2061,9 +2098,17 @@ func (cfg *Cfg) readLiveSettings(iniFile *ini.File) error {
 	return nil
 }
 
-func (cfg *Cfg) readPublicDashboardsSettings() {
+func (cfg *Cfg) readPublicDashboardsSettings() error {
 	publicDashboards := cfg.Raw.Section("public_dashboards")
 	cfg.PublicDashboardsEnabled = publicDashboards.Key("enabled").MustBool(true)
+
+	viewsRetention, err := gtime.ParseDuration(valueAsString(publicDashboards, "views_retention", "90d"))
+	if err != nil {
+		return fmt.Errorf("invalid public_dashboards.views_retention: %w", err)
+	}
+	cfg.PublicDashboardsViewsRetention = viewsRetention
+
+	return nil
 }
 
 func (cfg *Cfg) DefaultOrgID() int64 {
@@ -0,0 +1,83 @@
+package setting
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// BruteForceLoginProtectionSettings are the limits of failed login attempts before logins are locked out.
+type BruteForceLoginProtectionSettings struct {
+	// MaxAttempts is the number of failed attempts per username
+	MaxAttempts int64
+	// IPMaxAttempts is the number of failed attempts from an IP address, whatever the username, 0 disables the limit
+	IPMaxAttempts int64
+	// SubnetMaxAttempts is the number of failed attempts from a subnet, whatever the username, 0 disables the limit
+	SubnetMaxAttempts int64
+	IPv4SubnetPrefix  int
+	IPv6SubnetPrefix  int
+	// LockoutDuration is the duration of the first lockout, it doubles with every consecutive lockout
+	LockoutDuration time.Duration
+	// MaxLockoutDuration is the maximum duration of a lockout
+	MaxLockoutDuration time.Duration
+	// TrustedProxies are the networks of the reverse proxies whose X-Forwarded-For and X-Real-IP headers
+	// are used to get the IP address of logins. Headers sent by other clients are ignored.
+	TrustedProxies []*net.IPNet
+}
+
+func (cfg *Cfg) readBruteForceLoginProtectionSettings(security *ini.Section) {
+	s := BruteForceLoginProtectionSettings{}
+	s.MaxAttempts = security.Key("brute_force_login_protection_max_attempts").MustInt64(5)
+	s.IPMaxAttempts = security.Key("brute_force_login_protection_ip_max_attempts").MustInt64(50)
+	s.SubnetMaxAttempts = security.Key("brute_force_login_protection_subnet_max_attempts").MustInt64(200)
+	s.IPv4SubnetPrefix = security.Key("brute_force_login_protection_ipv4_subnet_prefix").MustInt(24)
+	s.IPv6SubnetPrefix = security.Key("brute_force_login_protection_ipv6_subnet_prefix").MustInt(64)
+	s.LockoutDuration = security.Key("brute_force_login_protection_lockout_duration").MustDuration(5 * time.Minute)
+	s.MaxLockoutDuration = security.Key("brute_force_login_protection_max_lockout_duration").MustDuration(24 * time.Hour)
+
+	if s.MaxAttempts < 1 {
+		cfg.Logger.Warn("Invalid brute_force_login_protection_max_attempts, using 5", "value", s.MaxAttempts)
+		s.MaxAttempts = 5
+	}
+	if s.IPv4SubnetPrefix < 0 || s.IPv4SubnetPrefix > 32 {
+		cfg.Logger.Warn("Invalid brute_force_login_protection_ipv4_subnet_prefix, using 24", "value", s.IPv4SubnetPrefix)
+		s.IPv4SubnetPrefix = 24
+	}
+	if s.IPv6SubnetPrefix < 0 || s.IPv6SubnetPrefix > 128 {
+		cfg.Logger.Warn("Invalid brute_force_login_protection_ipv6_subnet_prefix, using 64", "value", s.IPv6SubnetPrefix)
+		s.IPv6SubnetPrefix = 64
+	}
+	if s.MaxLockoutDuration < s.LockoutDuration {
+		s.MaxLockoutDuration = s.LockoutDuration
+	}
+	for _, proxy := range util.SplitString(security.Key("brute_force_login_protection_trusted_proxies").String()) {
+		network, err := parseNetwork(proxy)
+		if err != nil {
+			cfg.Logger.Warn("Invalid brute_force_login_protection_trusted_proxies entry, ignoring it", "value", proxy, "error", err)
+			continue
+		}
+		s.TrustedProxies = append(s.TrustedProxies, network)
+	}
+
+	cfg.BruteForceLoginProtection = s
+}
+
+// parseNetwork parses an IP address or a network in CIDR notation
+func parseNetwork(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "IP address", Text: s}
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(8*net.IPv4len, 8*net.IPv4len)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(8*net.IPv6len, 8*net.IPv6len)}, nil
+	}
+	_, network, err := net.ParseCIDR(s)
+	return network, err
+}
@@ -0,0 +1,51 @@
+package setting
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"gopkg.in/ini.v1"
+)
+
+// ReportingSettings configures the dashboard reports rendered and delivered on a schedule.
+type ReportingSettings struct {
+	Enabled bool
+	// RenderTimeout is the timeout of the rendering of each image of a report
+	RenderTimeout time.Duration
+	// DashboardWidth is the width in pixels of the rendered dashboards, their height is the height of their content
+	DashboardWidth int
+	// PanelWidth and PanelHeight are the size in pixels of the rendered panels
+	PanelWidth  int
+	PanelHeight int
+	// MaxPanels is the maximum number of panels a report can render one by one
+	MaxPanels int
+	// HistoryMaxAge is how long the history of the deliveries is kept, 0 keeps it forever
+	HistoryMaxAge time.Duration
+}
+
+func (cfg *Cfg) readReportingSettings(iniFile *ini.File) error {
+	section := iniFile.Section("reporting")
+	s := ReportingSettings{
+		Enabled:        section.Key("enabled").MustBool(true),
+		RenderTimeout:  section.Key("render_timeout").MustDuration(time.Minute),
+		DashboardWidth: section.Key("dashboard_width").MustInt(1600),
+		PanelWidth:     section.Key("panel_width").MustInt(1000),
+		PanelHeight:    section.Key("panel_height").MustInt(500),
+		MaxPanels:      section.Key("max_panels").MustInt(20),
+	}
+
+	historyMaxAge, err := gtime.ParseDuration(valueAsString(section, "history_max_age", "90d"))
+	if err != nil {
+		return fmt.Errorf("invalid [reporting] history_max_age: %w", err)
+	}
+	s.HistoryMaxAge = historyMaxAge
+
+	if s.RenderTimeout <= 0 {
+		return errors.New("invalid [reporting] render_timeout: must be positive")
+	}
+
+	cfg.Reporting = s
+	return nil
+}
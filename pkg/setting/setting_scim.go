@@ -0,0 +1,23 @@
+package setting
+
+// SCIMSettings configures the SCIM 2.0 endpoints identity providers use to provision users and teams.
+type SCIMSettings struct {
+	Enabled bool
+	// OrgID is the organization users and teams are provisioned in
+	OrgID int64
+	// DefaultOrgRole is the role of the users added to the organization
+	DefaultOrgRole string
+	// DeleteOrphanedUsers deletes users removed from the organization when they aren't a member of any other organization
+	DeleteOrphanedUsers bool
+}
+
+func (cfg *Cfg) readSCIMSettings() {
+	section := cfg.SectionWithEnvOverrides("auth.scim")
+	scimSettings := SCIMSettings{}
+	scimSettings.Enabled = section.Key("enabled").MustBool(false)
+	scimSettings.OrgID = section.Key("org_id").MustInt64(1)
+	scimSettings.DefaultOrgRole = section.Key("default_org_role").In("Viewer", []string{"None", "Viewer", "Editor", "Admin"})
+	scimSettings.DeleteOrphanedUsers = section.Key("delete_orphaned_users").MustBool(true)
+
+	cfg.SCIM = scimSettings
+}
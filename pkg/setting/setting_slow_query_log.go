@@ -0,0 +1,29 @@
+package setting
+
+import (
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// SlowQueryLogSettings configures the log of the data source queries that took longer than a threshold.
+type SlowQueryLogSettings struct {
+	Enabled bool
+	// Threshold is the duration above which a query is logged
+	Threshold time.Duration
+	// MaxRows is the number of logged queries kept, the oldest ones are deleted first
+	MaxRows int
+}
+
+func readSlowQueryLogSettings(iniFile *ini.File) SlowQueryLogSettings {
+	section := iniFile.Section("slow_query_log")
+	s := SlowQueryLogSettings{
+		Enabled:   section.Key("enabled").MustBool(true),
+		Threshold: section.Key("threshold").MustDuration(5 * time.Second),
+		MaxRows:   section.Key("max_rows").MustInt(10000),
+	}
+	if s.MaxRows <= 0 {
+		s.Enabled = false
+	}
+	return s
+}
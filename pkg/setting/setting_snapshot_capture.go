@@ -0,0 +1,93 @@
+package setting
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"gopkg.in/ini.v1"
+)
+
+const snapshotCaptureSectionPrefix = "snapshot_capture."
+
+// SnapshotCaptureSchedule configures a dashboard captured periodically into a server-side snapshot.
+// Schedules are read from sections that look like:
+// [snapshot_capture.<name>]
+// dashboard_uid = <uid>
+// interval = 1h
+type SnapshotCaptureSchedule struct {
+	Name         string
+	OrgID        int64
+	DashboardUID string
+	Interval     time.Duration
+	// From and To default to the time range saved in the dashboard when empty.
+	From string
+	To   string
+	// Variables override the values of the template variables saved in the dashboard.
+	Variables map[string][]string
+	// SnapshotName defaults to the title of the dashboard when empty.
+	SnapshotName string
+	// Expires is the lifetime of the captured snapshots, 0 means they never expire.
+	Expires time.Duration
+}
+
+func readSnapshotCaptureSettings(cfg *Cfg, iniFile *ini.File) error {
+	cfg.SnapshotCaptureSchedules = nil
+	for _, section := range iniFile.Sections() {
+		if !strings.HasPrefix(section.Name(), snapshotCaptureSectionPrefix) {
+			continue
+		}
+
+		schedule, err := readSnapshotCaptureSchedule(section)
+		if err != nil {
+			return fmt.Errorf("invalid [%s] section: %w", section.Name(), err)
+		}
+		cfg.SnapshotCaptureSchedules = append(cfg.SnapshotCaptureSchedules, schedule)
+	}
+	return nil
+}
+
+func readSnapshotCaptureSchedule(section *ini.Section) (SnapshotCaptureSchedule, error) {
+	schedule := SnapshotCaptureSchedule{
+		Name:         strings.TrimPrefix(section.Name(), snapshotCaptureSectionPrefix),
+		OrgID:        section.Key("org_id").MustInt64(1),
+		DashboardUID: valueAsString(section, "dashboard_uid", ""),
+		From:         valueAsString(section, "from", ""),
+		To:           valueAsString(section, "to", ""),
+		SnapshotName: valueAsString(section, "snapshot_name", ""),
+	}
+
+	if schedule.DashboardUID == "" {
+		return schedule, fmt.Errorf("dashboard_uid is required")
+	}
+	if (schedule.From == "") != (schedule.To == "") {
+		return schedule, fmt.Errorf("from and to must be set together")
+	}
+
+	interval, err := gtime.ParseDuration(valueAsString(section, "interval", "24h"))
+	if err != nil {
+		return schedule, fmt.Errorf("interval: %w", err)
+	}
+	if interval < time.Minute {
+		return schedule, fmt.Errorf("interval must be at least 1m")
+	}
+	schedule.Interval = interval
+
+	if expires := valueAsString(section, "expires", ""); expires != "" {
+		schedule.Expires, err = gtime.ParseDuration(expires)
+		if err != nil {
+			return schedule, fmt.Errorf("expires: %w", err)
+		}
+	}
+
+	// variables use the query string syntax, e.g. region=eu&host=a&host=b
+	variables, err := url.ParseQuery(valueAsString(section, "variables", ""))
+	if err != nil {
+		return schedule, fmt.Errorf("variables: %w", err)
+	}
+	schedule.Variables = variables
+
+	return schedule, nil
+}
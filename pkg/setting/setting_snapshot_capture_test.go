@@ -0,0 +1,63 @@
+package setting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+)
+
+func TestReadSnapshotCaptureSettings(t *testing.T) {
+	t.Run("will load capture schedules", func(t *testing.T) {
+		f, err := ini.Load([]byte(`
+[snapshot_capture.status_eu]
+dashboard_uid = status
+interval = 1h
+from = now-24h
+to = now
+variables = region=eu&host=a&host=b
+expires = 7d
+
+[snapshot_capture.daily]
+org_id = 2
+dashboard_uid = daily
+`))
+		require.NoError(t, err)
+		cfg := NewCfg()
+
+		require.NoError(t, readSnapshotCaptureSettings(cfg, f))
+		require.Equal(t, []SnapshotCaptureSchedule{
+			{
+				Name:         "status_eu",
+				OrgID:        1,
+				DashboardUID: "status",
+				Interval:     time.Hour,
+				From:         "now-24h",
+				To:           "now",
+				Variables:    map[string][]string{"region": {"eu"}, "host": {"a", "b"}},
+				Expires:      7 * 24 * time.Hour,
+			},
+			{
+				Name:         "daily",
+				OrgID:        2,
+				DashboardUID: "daily",
+				Interval:     24 * time.Hour,
+				Variables:    map[string][]string{},
+			},
+		}, cfg.SnapshotCaptureSchedules)
+	})
+
+	t.Run("will reject invalid schedules", func(t *testing.T) {
+		for _, section := range []string{
+			"[snapshot_capture.a]\ninterval = 1h",
+			"[snapshot_capture.a]\ndashboard_uid = a\ninterval = 10s",
+			"[snapshot_capture.a]\ndashboard_uid = a\nfrom = now-1h",
+			"[snapshot_capture.a]\ndashboard_uid = a\nexpires = soon",
+		} {
+			f, err := ini.Load([]byte(section))
+			require.NoError(t, err)
+			require.Error(t, readSnapshotCaptureSettings(NewCfg(), f), section)
+		}
+	})
+}
@@ -65,6 +65,7 @@ const (
 	lokiDefaultMaxQueryLength      = 721 * time.Hour // 30d1h, matches the default value in Loki
 	defaultRecordingRequestTimeout = 10 * time.Second
 	lokiDefaultMaxQuerySize        = 65536 // 64kb
+	stateHistoryDefaultSQLMaxAge   = 30 * 24 * time.Hour
 )
 
 type UnifiedAlertingSettings struct {
@@ -174,6 +175,9 @@ type UnifiedAlertingStateHistorySettings struct {
 	MultiPrimary          string
 	MultiSecondaries      []string
 	ExternalLabels        map[string]string
+	// SQLMaxAge is how long state history recorded by the "sql" backend is kept for.
+	// Zero or less keeps it forever.
+	SQLMaxAge time.Duration
 }
 
 // IsEnabled returns true if UnifiedAlertingSettings.Enabled is either nil or true.
@@ -422,6 +426,7 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 		MultiPrimary:          stateHistory.Key("primary").MustString(""),
 		MultiSecondaries:      splitTrim(stateHistory.Key("secondaries").MustString(""), ","),
 		ExternalLabels:        stateHistoryLabels.KeysHash(),
+		SQLMaxAge:             stateHistory.Key("sql_max_age").MustDuration(stateHistoryDefaultSQLMaxAge),
 	}
 	uaCfg.StateHistory = uaCfgStateHistory
 
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// postgresGuardrailsDialect enforces the query guardrails with the session statement_timeout
+// and the planner estimates of EXPLAIN.
+type postgresGuardrailsDialect struct{}
+
+func (postgresGuardrailsDialect) SetStatementTimeout(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("SET statement_timeout = %d", timeout.Milliseconds()))
+	return err
+}
+
+func (postgresGuardrailsDialect) EstimateCost(ctx context.Context, conn *sql.Conn, query string) (float64, error) {
+	var plan string
+	if err := conn.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query).Scan(&plan); err != nil {
+		return 0, err
+	}
+	return parsePostgresPlanCost(plan)
+}
+
+func parsePostgresPlanCost(plan string) (float64, error) {
+	var explained []struct {
+		Plan struct {
+			TotalCost float64 `json:"Total Cost"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &explained); err != nil {
+		return 0, err
+	}
+	if len(explained) == 0 {
+		return 0, errors.New("empty query plan")
+	}
+	return explained[0].Plan.TotalCost, nil
+}
@@ -0,0 +1,16 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePostgresPlanCost(t *testing.T) {
+	cost, err := parsePostgresPlanCost(`[{"Plan": {"Node Type": "Seq Scan", "Startup Cost": 0.00, "Total Cost": 1834.50}}]`)
+	require.NoError(t, err)
+	require.Equal(t, 1834.5, cost)
+
+	_, err = parsePostgresPlanCost(`[]`)
+	require.Error(t, err)
+}
@@ -38,15 +38,31 @@ type Service struct {
 	logger     log.Logger
 }
 
-func (s *Service) getDSInfo(ctx context.Context, pluginCtx backend.PluginContext) (*sqleng.DataSourceHandler, error) {
+// postgresInstance is the datasource instance. Besides the query handler it keeps what is
+// needed to open dedicated LISTEN connections for streaming.
+type postgresInstance struct {
+	*sqleng.DataSourceHandler
+	cnnstr string
+	dialer pq.Dialer
+}
+
+func (s *Service) getInstance(ctx context.Context, pluginCtx backend.PluginContext) (*postgresInstance, error) {
 	i, err := s.im.Get(ctx, pluginCtx)
 	if err != nil {
 		return nil, err
 	}
-	instance := i.(*sqleng.DataSourceHandler)
+	instance := i.(*postgresInstance)
 	return instance, nil
 }
 
+func (s *Service) getDSInfo(ctx context.Context, pluginCtx backend.PluginContext) (*sqleng.DataSourceHandler, error) {
+	instance, err := s.getInstance(ctx, pluginCtx)
+	if err != nil {
+		return nil, err
+	}
+	return instance.DataSourceHandler, nil
+}
+
 func (s *Service) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
 	dsInfo, err := s.getDSInfo(ctx, req.PluginContext)
 	if err != nil {
@@ -83,6 +99,7 @@ func newPostgres(ctx context.Context, userFacingDefaultError string, rowLimit in
 		DSInfo:            dsInfo,
 		MetricColumnTypes: []string{"UNKNOWN", "TEXT", "VARCHAR", "CHAR"},
 		RowLimit:          rowLimit,
+		GuardrailsDialect: postgresGuardrailsDialect{},
 	}
 
 	queryResultTransformer := postgresQueryResultTransformer{}
@@ -160,8 +177,19 @@ func (s *Service) newInstanceSettings() datasource.InstanceFactoryFunc {
 			return nil, err
 		}
 
+		dialer, err := newListenerDialer(ctx, settings)
+		if err != nil {
+			logger.Error("postgres proxy creation failed", "error", err)
+			handler.Dispose()
+			return nil, fmt.Errorf("postgres proxy creation failed")
+		}
+
 		logger.Debug("Successfully connected to Postgres")
-		return handler, nil
+		return &postgresInstance{
+			DataSourceHandler: handler,
+			cnnstr:            cnnstr,
+			dialer:            dialer,
+		}, nil
 	}
 }
 
@@ -5,10 +5,28 @@ import (
 	"net"
 	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/lib/pq"
 	"golang.org/x/net/proxy"
 )
 
+// newListenerDialer returns the dialer used for LISTEN connections, which are opened
+// outside of the database/sql pool and so need the secure socks proxy applied separately.
+func newListenerDialer(ctx context.Context, settings backend.DataSourceInstanceSettings) (pq.Dialer, error) {
+	proxyClient, err := settings.ProxyClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !proxyClient.SecureSocksProxyEnabled() {
+		return netDialer{}, nil
+	}
+	dialer, err := proxyClient.NewSecureSocksProxyContextDialer()
+	if err != nil {
+		return nil, err
+	}
+	return newPostgresProxyDialer(dialer), nil
+}
+
 // we wrap the proxy.Dialer to become dialer that the postgres module accepts
 func newPostgresProxyDialer(dialer proxy.Dialer) pq.Dialer {
 	return &postgresProxyDialer{d: dialer}
@@ -33,3 +51,14 @@ func (p *postgresProxyDialer) DialTimeout(network, address string, timeout time.
 
 	return p.d.(proxy.ContextDialer).DialContext(ctx, network, address)
 }
+
+// netDialer dials directly, for LISTEN connections when no proxy is configured
+type netDialer struct{}
+
+func (netDialer) Dial(network, address string) (net.Conn, error) {
+	return net.Dial(network, address)
+}
+
+func (netDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout(network, address, timeout)
+}
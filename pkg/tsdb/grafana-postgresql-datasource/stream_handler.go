@@ -0,0 +1,258 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/lib/pq"
+)
+
+const (
+	listenPathPrefix = "listen/"
+
+	listenerMinReconnectInterval = 10 * time.Second
+	listenerMaxReconnectInterval = time.Minute
+	listenerPingInterval         = 90 * time.Second
+)
+
+// channelNameRegex matches channel names that are valid unquoted Postgres identifiers.
+var channelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,62}$`)
+
+// channelFromPath extracts the LISTEN channel from a stream path like listen/orders.
+func channelFromPath(path string) (string, bool) {
+	if !strings.HasPrefix(path, listenPathPrefix) {
+		return "", false
+	}
+	channel := strings.TrimPrefix(path, listenPathPrefix)
+	if !channelNameRegex.MatchString(channel) {
+		return "", false
+	}
+	return channel, true
+}
+
+func (s *Service) SubscribeStream(ctx context.Context, req *backend.SubscribeStreamRequest) (*backend.SubscribeStreamResponse, error) {
+	s.logger.FromContext(ctx).Debug("Allowing access to stream", "path", req.Path, "user", req.PluginContext.User)
+
+	status := backend.SubscribeStreamStatusNotFound
+	if _, ok := channelFromPath(req.Path); ok {
+		status = backend.SubscribeStreamStatusOK
+	}
+
+	return &backend.SubscribeStreamResponse{
+		Status: status,
+	}, nil
+}
+
+func (s *Service) PublishStream(ctx context.Context, req *backend.PublishStreamRequest) (*backend.PublishStreamResponse, error) {
+	// Do not allow publishing at all, NOTIFY has to come from the database.
+	return &backend.PublishStreamResponse{
+		Status: backend.PublishStreamStatusPermissionDenied,
+	}, nil
+}
+
+// RunStream listens on a Postgres channel and sends every NOTIFY payload as a frame.
+// It uses a dedicated connection outside of the query pool, since LISTEN is bound to the
+// session it was issued on.
+func (s *Service) RunStream(ctx context.Context, req *backend.RunStreamRequest, sender *backend.StreamSender) error {
+	logger := s.logger.FromContext(ctx)
+	logger.Debug("New stream call", "path", req.Path)
+
+	channel, ok := channelFromPath(req.Path)
+	if !ok {
+		return fmt.Errorf("unknown path %s", req.Path)
+	}
+
+	instance, err := s.getInstance(ctx, req.PluginContext)
+	if err != nil {
+		return err
+	}
+
+	listener := pq.NewDialListener(instance.dialer, instance.cnnstr, listenerMinReconnectInterval, listenerMaxReconnectInterval,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				logger.Warn("Postgres listener connection problem", "channel", channel, "event", event, "error", err)
+			}
+		})
+	defer func() {
+		if err := listener.Close(); err != nil {
+			logger.Warn("Failed to close postgres listener", "channel", channel, "error", err)
+		}
+	}()
+
+	if err := listener.Listen(channel); err != nil {
+		return fmt.Errorf("failed to listen on channel %s: %w", channel, err)
+	}
+
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Debug("Stop streaming", "channel", channel)
+			return nil
+		case notification := <-listener.Notify:
+			// A nil notification is sent after the connection was re-established,
+			// notifications sent in the meantime are lost.
+			if notification == nil {
+				logger.Debug("Postgres listener reconnected", "channel", channel)
+				continue
+			}
+			frame := notificationToFrame(channel, notification.Extra, time.Now())
+			if err := sender.SendFrame(frame, data.IncludeAll); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			// Ping to detect broken connections when the channel is quiet.
+			go func() {
+				if err := listener.Ping(); err != nil {
+					logger.Debug("Postgres listener ping failed", "channel", channel, "error", err)
+				}
+			}()
+		}
+	}
+}
+
+// notificationToFrame converts a NOTIFY payload to a frame. The payload can be a JSON object
+// or an array of objects, each object becoming a row. A "time" key is used as the row time,
+// otherwise the time the notification was received is used. Other payloads are sent as text.
+func notificationToFrame(channel string, payload string, received time.Time) *data.Frame {
+	rows, ok := parseNotificationRows(payload)
+	if !ok {
+		return data.NewFrame(channel,
+			data.NewField("time", nil, []time.Time{received}),
+			data.NewField("payload", nil, []string{payload}),
+		)
+	}
+
+	times := make([]time.Time, len(rows))
+	for i, row := range rows {
+		times[i] = received
+		if t, ok := parseRowTime(row["time"]); ok {
+			times[i] = t
+		}
+		delete(row, "time")
+	}
+
+	keys := map[string]struct{}{}
+	for _, row := range rows {
+		for key := range row {
+			keys[key] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	fields := []*data.Field{data.NewField("time", nil, times)}
+	for _, name := range names {
+		fields = append(fields, newNotificationField(name, rows))
+	}
+	return data.NewFrame(channel, fields...)
+}
+
+func parseNotificationRows(payload string) ([]map[string]any, bool) {
+	trimmed := strings.TrimSpace(payload)
+	if strings.HasPrefix(trimmed, "{") {
+		row := map[string]any{}
+		if err := json.Unmarshal([]byte(trimmed), &row); err != nil {
+			return nil, false
+		}
+		return []map[string]any{row}, true
+	}
+	if strings.HasPrefix(trimmed, "[") {
+		rows := []map[string]any{}
+		if err := json.Unmarshal([]byte(trimmed), &rows); err != nil || len(rows) == 0 {
+			return nil, false
+		}
+		for i := range rows {
+			if rows[i] == nil {
+				rows[i] = map[string]any{}
+			}
+		}
+		return rows, true
+	}
+	return nil, false
+}
+
+// parseRowTime accepts RFC3339 strings and epoch milliseconds.
+func parseRowTime(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t.UTC(), true
+	case float64:
+		return time.UnixMilli(int64(v)).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// newNotificationField builds a nullable field for a key. Numbers and booleans keep their
+// type when all rows agree, anything else is converted to text.
+func newNotificationField(name string, rows []map[string]any) *data.Field {
+	allNumbers, allBools := true, true
+	for _, row := range rows {
+		switch row[name].(type) {
+		case nil:
+		case float64:
+			allBools = false
+		case bool:
+			allNumbers = false
+		default:
+			allNumbers, allBools = false, false
+		}
+	}
+
+	switch {
+	case allNumbers:
+		values := make([]*float64, len(rows))
+		for i, row := range rows {
+			if v, ok := row[name].(float64); ok {
+				values[i] = &v
+			}
+		}
+		return data.NewField(name, nil, values)
+	case allBools:
+		values := make([]*bool, len(rows))
+		for i, row := range rows {
+			if v, ok := row[name].(bool); ok {
+				values[i] = &v
+			}
+		}
+		return data.NewField(name, nil, values)
+	default:
+		values := make([]*string, len(rows))
+		for i, row := range rows {
+			values[i] = stringValue(row[name])
+		}
+		return data.NewField(name, nil, values)
+	}
+}
+
+func stringValue(value any) *string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return &v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		s := string(b)
+		return &s
+	}
+}
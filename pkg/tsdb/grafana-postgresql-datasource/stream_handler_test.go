@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelFromPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		channel string
+		ok      bool
+	}{
+		{path: "listen/orders", channel: "orders", ok: true},
+		{path: "listen/order_events_2", channel: "order_events_2", ok: true},
+		{path: "listen/", ok: false},
+		{path: "listen/orders;drop", ok: false},
+		{path: "listen/1orders", ok: false},
+		{path: "orders", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			channel, ok := channelFromPath(tt.path)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.channel, channel)
+		})
+	}
+}
+
+func TestSubscribeStream(t *testing.T) {
+	s := &Service{logger: backend.NewLoggerWith("logger", "tsdb.postgres.test")}
+
+	resp, err := s.SubscribeStream(context.Background(), &backend.SubscribeStreamRequest{Path: "listen/orders"})
+	require.NoError(t, err)
+	assert.Equal(t, backend.SubscribeStreamStatusOK, resp.Status)
+
+	resp, err = s.SubscribeStream(context.Background(), &backend.SubscribeStreamRequest{Path: "query/orders"})
+	require.NoError(t, err)
+	assert.Equal(t, backend.SubscribeStreamStatusNotFound, resp.Status)
+}
+
+func TestNotificationToFrame(t *testing.T) {
+	received := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("converts a JSON object to a single row", func(t *testing.T) {
+		frame := notificationToFrame("orders", `{"id": 42, "status": "shipped", "paid": true}`, received)
+
+		expected := data.NewFrame("orders",
+			data.NewField("time", nil, []time.Time{received}),
+			data.NewField("id", nil, []*float64{ptr(42.0)}),
+			data.NewField("paid", nil, []*bool{ptr(true)}),
+			data.NewField("status", nil, []*string{ptr("shipped")}),
+		)
+		assert.Equal(t, expected, frame)
+	})
+
+	t.Run("converts a JSON array to rows and uses the time key", func(t *testing.T) {
+		frame := notificationToFrame("orders", `[
+			{"time": "2024-05-01T09:00:00Z", "id": 1, "meta": {"region": "eu"}},
+			{"time": 1714557600000, "id": 2, "meta": "none"}
+		]`, received)
+
+		expected := data.NewFrame("orders",
+			data.NewField("time", nil, []time.Time{
+				time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+				time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			}),
+			data.NewField("id", nil, []*float64{ptr(1.0), ptr(2.0)}),
+			data.NewField("meta", nil, []*string{ptr(`{"region":"eu"}`), ptr("none")}),
+		)
+		assert.Equal(t, expected, frame)
+	})
+
+	t.Run("keeps missing keys as nulls", func(t *testing.T) {
+		frame := notificationToFrame("orders", `[{"id": 1}, {"status": "new"}]`, received)
+
+		require.Len(t, frame.Fields, 3)
+		assert.Equal(t, ptr(1.0), frame.Fields[1].At(0))
+		assert.Nil(t, frame.Fields[1].At(1))
+		assert.Nil(t, frame.Fields[2].At(0))
+		assert.Equal(t, ptr("new"), frame.Fields[2].At(1))
+	})
+
+	t.Run("sends other payloads as text", func(t *testing.T) {
+		frame := notificationToFrame("orders", `order 42 shipped`, received)
+
+		expected := data.NewFrame("orders",
+			data.NewField("time", nil, []time.Time{received}),
+			data.NewField("payload", nil, []string{"order 42 shipped"}),
+		)
+		assert.Equal(t, expected, frame)
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,197 @@
+package graphite
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+const (
+	metricsFindResourcePath  = "metrics/find"
+	tagsResourcePath         = "tags/autoComplete/tags"
+	tagValuesResourcePath    = "tags/autoComplete/values"
+	functionsResourcePath    = "functions"
+	resourceCacheExpiration  = time.Minute
+	functionsCacheExpiration = time.Hour
+)
+
+// Graphite 1.1.7 serializes some function parameter defaults as a bare Infinity,
+// which is not valid JSON. See https://github.com/graphite-project/graphite-web/issues/2609
+var infinityDefaultRegex = regexp.MustCompile(`"default": ?Infinity`)
+
+type normalizeResponse func(body []byte) ([]byte, error)
+
+func (s *Service) newResourceMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+metricsFindResourcePath, s.handleResourceReq(metricsFindResourcePath, http.MethodPost, resourceCacheExpiration, normalizeMetricsFind))
+	mux.HandleFunc("/"+tagsResourcePath, s.handleResourceReq(tagsResourcePath, http.MethodGet, resourceCacheExpiration, normalizeTags))
+	mux.HandleFunc("/"+tagValuesResourcePath, s.handleResourceReq(tagValuesResourcePath, http.MethodGet, resourceCacheExpiration, normalizeTags))
+	mux.HandleFunc("/"+functionsResourcePath, s.handleResourceReq(functionsResourcePath, http.MethodGet, functionsCacheExpiration, normalizeFunctions))
+	return mux
+}
+
+// handleResourceReq proxies a request to the given Graphite endpoint using the datasource
+// HTTP client, normalizes the response and caches it per datasource instance.
+func (s *Service) handleResourceReq(graphitePath string, method string, expiration time.Duration, normalize normalizeResponse) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		logger := logger.FromContext(ctx)
+
+		if req.Method != http.MethodGet && req.Method != http.MethodPost {
+			writeResponse(rw, http.StatusMethodNotAllowed, fmt.Sprintf("method %s is not allowed", req.Method))
+			return
+		}
+		// ParseForm merges the query string with a form encoded body, so the
+		// endpoints accept parameters either way like Graphite itself does.
+		if err := req.ParseForm(); err != nil {
+			writeResponse(rw, http.StatusBadRequest, fmt.Sprintf("invalid request parameters: %v", err))
+			return
+		}
+
+		dsInfo, err := s.getDSInfo(ctx, backend.PluginConfigFromContext(ctx))
+		if err != nil {
+			writeResponse(rw, http.StatusInternalServerError, fmt.Sprintf("failed to get datasource info: %v", err))
+			return
+		}
+
+		cacheKey := graphitePath + "?" + req.Form.Encode()
+		if dsInfo.ResourceCache != nil {
+			if cached, found := dsInfo.ResourceCache.Get(cacheKey); found {
+				rw.Header().Set("Content-Type", "application/json")
+				writeResponseBytes(rw, http.StatusOK, cached.([]byte))
+				return
+			}
+		}
+
+		graphiteReq, err := createResourceRequest(req, dsInfo, graphitePath, method)
+		if err != nil {
+			writeResponse(rw, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ctx, span := s.tracer.Start(ctx, "graphite resource")
+		defer span.End()
+		s.tracer.Inject(ctx, graphiteReq.Header, span)
+
+		res, err := dsInfo.HTTPClient.Do(graphiteReq.WithContext(ctx))
+		if err != nil {
+			writeResponse(rw, http.StatusBadGateway, fmt.Sprintf("graphite request failed: %v", err))
+			return
+		}
+		defer func() {
+			if err := res.Body.Close(); err != nil {
+				logger.Warn("Failed to close response body", "error", err)
+			}
+		}()
+
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			writeResponse(rw, http.StatusBadGateway, fmt.Sprintf("failed to read graphite response: %v", err))
+			return
+		}
+		if res.StatusCode/100 != 2 {
+			logger.Info("Resource request failed", "path", graphitePath, "status", res.Status, "body", string(body))
+			writeResponseBytes(rw, res.StatusCode, body)
+			return
+		}
+
+		normalized, err := normalize(body)
+		if err != nil {
+			logger.Info("Failed to normalize graphite response", "path", graphitePath, "error", err)
+			writeResponse(rw, http.StatusInternalServerError, fmt.Sprintf("unexpected graphite response: %v", err))
+			return
+		}
+
+		if dsInfo.ResourceCache != nil {
+			dsInfo.ResourceCache.Set(cacheKey, normalized, expiration)
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		writeResponseBytes(rw, http.StatusOK, normalized)
+	}
+}
+
+func createResourceRequest(req *http.Request, dsInfo *datasourceInfo, graphitePath string, method string) (*http.Request, error) {
+	u, err := url.Parse(dsInfo.URL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, graphitePath)
+
+	var body io.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader(req.Form.Encode())
+	} else {
+		u.RawQuery = req.Form.Encode()
+	}
+
+	graphiteReq, err := http.NewRequestWithContext(req.Context(), method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if method == http.MethodPost {
+		graphiteReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return graphiteReq, nil
+}
+
+func normalizeMetricsFind(body []byte) ([]byte, error) {
+	results := []MetricFindResult{}
+	if err := json.Unmarshal(body, &results); err != nil {
+		return nil, err
+	}
+	if results == nil {
+		results = []MetricFindResult{}
+	}
+	return json.Marshal(results)
+}
+
+// normalizeTags converts the tag autocomplete response to a sorted list of strings,
+// since some Graphite versions return numeric tag values as numbers.
+func normalizeTags(body []byte) ([]byte, error) {
+	var values []any
+	if err := json.Unmarshal(body, &values); err != nil {
+		return nil, err
+	}
+
+	tags := make([]string, 0, len(values))
+	for _, value := range values {
+		switch value := value.(type) {
+		case string:
+			tags = append(tags, value)
+		case float64:
+			tags = append(tags, strconv.FormatFloat(value, 'f', -1, 64))
+		}
+	}
+	sort.Strings(tags)
+	return json.Marshal(tags)
+}
+
+func normalizeFunctions(body []byte) ([]byte, error) {
+	fixed := infinityDefaultRegex.ReplaceAll(body, []byte(`"default": 1e9999`))
+	var functions map[string]json.RawMessage
+	if err := json.Unmarshal(fixed, &functions); err != nil {
+		return nil, err
+	}
+	return fixed, nil
+}
+
+func writeResponseBytes(rw http.ResponseWriter, code int, msg []byte) {
+	rw.WriteHeader(code)
+	if _, err := rw.Write(msg); err != nil {
+		logger.Error("Unable to write HTTP response", "error", err)
+	}
+}
+
+func writeResponse(rw http.ResponseWriter, code int, msg string) {
+	writeResponseBytes(rw, code, []byte(msg))
+}
@@ -0,0 +1,167 @@
+package graphite
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+)
+
+func TestNormalizeMetricsFind(t *testing.T) {
+	t.Run("converts numeric flags to booleans", func(t *testing.T) {
+		body := `[{"text":"cpu","id":"servers.cpu","expandable":1,"leaf":0,"allowChildren":1}]`
+		normalized, err := normalizeMetricsFind([]byte(body))
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"text":"cpu","id":"servers.cpu","expandable":true,"leaf":false}]`, string(normalized))
+	})
+
+	t.Run("returns an empty list for null responses", func(t *testing.T) {
+		normalized, err := normalizeMetricsFind([]byte(`null`))
+		require.NoError(t, err)
+		assert.Equal(t, `[]`, string(normalized))
+	})
+
+	t.Run("fails on invalid flags", func(t *testing.T) {
+		_, err := normalizeMetricsFind([]byte(`[{"text":"cpu","expandable":"yes"}]`))
+		require.Error(t, err)
+	})
+}
+
+func TestNormalizeTags(t *testing.T) {
+	normalized, err := normalizeTags([]byte(`["region", 200, "app"]`))
+	require.NoError(t, err)
+	assert.Equal(t, `["200","app","region"]`, string(normalized))
+}
+
+func TestNormalizeFunctions(t *testing.T) {
+	body := `{"sumSeries":{"name":"sumSeries","params":[{"name":"limit","default": Infinity}]}}`
+	normalized, err := normalizeFunctions([]byte(body))
+	require.NoError(t, err)
+	assert.Equal(t, `{"sumSeries":{"name":"sumSeries","params":[{"name":"limit","default": 1e9999}]}}`, string(normalized))
+}
+
+func TestResourceHandler(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	var lastMethod, lastQuery, lastBody string
+	graphite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests++
+		lastMethod = r.Method
+		lastQuery = r.URL.RawQuery
+		lastBody = string(body)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/metrics/find":
+			_, _ = w.Write([]byte(`[{"text":"cpu","id":"servers.cpu","expandable":1,"leaf":0}]`))
+		case "/tags/autoComplete/tags":
+			_, _ = w.Write([]byte(`["name","region"]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`not found`))
+		}
+	}))
+	t.Cleanup(graphite.Close)
+
+	service := &Service{
+		im: resourceInstanceManager{dsInfo: datasourceInfo{
+			HTTPClient:    graphite.Client(),
+			URL:           graphite.URL,
+			ResourceCache: cache.New(time.Minute, time.Minute),
+		}},
+		tracer: tracing.InitializeTracerForTest(),
+	}
+	service.resourceHandler = httpadapter.New(service.newResourceMux())
+
+	t.Run("forwards metrics/find as a form post and normalizes the response", func(t *testing.T) {
+		resp := callResource(t, service, http.MethodPost, "metrics/find?from=1&until=2", "query=servers.*")
+		require.Equal(t, http.StatusOK, resp.Status)
+		assert.JSONEq(t, `[{"text":"cpu","id":"servers.cpu","expandable":true,"leaf":false}]`, string(resp.Body))
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, http.MethodPost, lastMethod)
+		assert.Equal(t, "from=1&query=servers.%2A&until=2", lastBody)
+	})
+
+	t.Run("serves repeated requests from the cache", func(t *testing.T) {
+		mu.Lock()
+		before := requests
+		mu.Unlock()
+
+		resp := callResource(t, service, http.MethodGet, "tags/autoComplete/tags?expr=name%3Dcpu", "")
+		require.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, `["name","region"]`, string(resp.Body))
+
+		resp = callResource(t, service, http.MethodGet, "tags/autoComplete/tags?expr=name%3Dcpu", "")
+		require.Equal(t, http.StatusOK, resp.Status)
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, "expr=name%3Dcpu", lastQuery)
+		assert.Equal(t, before+1, requests)
+	})
+
+	t.Run("passes through downstream errors without caching them", func(t *testing.T) {
+		mu.Lock()
+		before := requests
+		mu.Unlock()
+
+		for i := 0; i < 2; i++ {
+			resp := callResource(t, service, http.MethodGet, "functions", "")
+			require.Equal(t, http.StatusNotFound, resp.Status)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, before+2, requests)
+	})
+}
+
+func callResource(t *testing.T, s *Service, method string, path string, body string) *backend.CallResourceResponse {
+	t.Helper()
+	req := &backend.CallResourceRequest{
+		PluginContext: backend.PluginContext{},
+		Method:        method,
+		Path:          path,
+		URL:           "/" + path,
+		Body:          []byte(body),
+		Headers:       map[string][]string{},
+	}
+	if method == http.MethodPost {
+		req.Headers["Content-Type"] = []string{"application/x-www-form-urlencoded"}
+	}
+
+	var resp *backend.CallResourceResponse
+	err := s.CallResource(context.Background(), req, backend.CallResourceResponseSenderFunc(func(r *backend.CallResourceResponse) error {
+		resp = r
+		return nil
+	}))
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	return resp
+}
+
+type resourceInstanceManager struct {
+	dsInfo datasourceInfo
+}
+
+func (f resourceInstanceManager) Get(_ context.Context, _ backend.PluginContext) (instancemgmt.Instance, error) {
+	return f.dsInfo, nil
+}
+
+func (f resourceInstanceManager) Do(_ context.Context, _ backend.PluginContext, _ instancemgmt.InstanceCallbackFunc) error {
+	return nil
+}
@@ -1,6 +1,9 @@
 package graphite
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/grafana/grafana/pkg/components/null"
 )
 
@@ -13,3 +16,25 @@ type TargetResponseDTO struct {
 
 type DataTimePoint [2]null.Float
 type DataTimeSeriesPoints []DataTimePoint
+
+// graphiteBool accepts both booleans and the 0/1 integers older Graphite versions return.
+type graphiteBool bool
+
+func (b *graphiteBool) UnmarshalJSON(data []byte) error {
+	switch strings.TrimSpace(string(data)) {
+	case "true", "1":
+		*b = true
+	case "false", "0", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean value: %s", data)
+	}
+	return nil
+}
+
+type MetricFindResult struct {
+	Text       string       `json:"text"`
+	ID         string       `json:"id"`
+	Expandable graphiteBool `json:"expandable"`
+	Leaf       graphiteBool `json:"leaf"`
+}
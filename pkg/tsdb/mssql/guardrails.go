@@ -0,0 +1,75 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var statementSubTreeCostRegex = regexp.MustCompile(`StatementSubTreeCost="([^"]+)"`)
+
+// mssqlGuardrailsDialect enforces the query guardrails with the estimated execution plans of SHOWPLAN_XML.
+type mssqlGuardrailsDialect struct{}
+
+// SetStatementTimeout does nothing, SQL Server has no session setting limiting the execution time of statements.
+// LOCK_TIMEOUT only limits the time spent waiting for locks. The statement timeout is enforced on the client side
+// with the query context, the driver asks the server to cancel the query when it expires.
+func (mssqlGuardrailsDialect) SetStatementTimeout(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+	return nil
+}
+
+func (mssqlGuardrailsDialect) EstimateCost(ctx context.Context, conn *sql.Conn, query string) (cost float64, err error) {
+	if _, err := conn.ExecContext(ctx, "SET SHOWPLAN_XML ON"); err != nil {
+		return 0, err
+	}
+	defer func() {
+		// The connection goes back to the pool, so the plan mode has to be switched off again
+		if _, offErr := conn.ExecContext(context.WithoutCancel(ctx), "SET SHOWPLAN_XML OFF"); offErr != nil && err == nil {
+			err = offErr
+		}
+	}()
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	plans := []string{}
+	for rows.Next() {
+		var plan string
+		if err := rows.Scan(&plan); err != nil {
+			return 0, err
+		}
+		plans = append(plans, plan)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	return parseMssqlPlanCost(plans)
+}
+
+// parseMssqlPlanCost sums the estimated cost of all statements of the batch.
+func parseMssqlPlanCost(plans []string) (float64, error) {
+	found := false
+	total := 0.0
+	for _, plan := range plans {
+		for _, match := range statementSubTreeCostRegex.FindAllStringSubmatch(plan, -1) {
+			cost, err := strconv.ParseFloat(match[1], 64)
+			if err != nil {
+				return 0, err
+			}
+			total += cost
+			found = true
+		}
+	}
+	if !found {
+		return 0, errors.New("query plan has no cost")
+	}
+	return total, nil
+}
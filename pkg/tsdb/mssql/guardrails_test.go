@@ -0,0 +1,21 @@
+package mssql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMssqlPlanCost(t *testing.T) {
+	plan := `<ShowPlanXML><BatchSequence><Batch><Statements>` +
+		`<StmtSimple StatementText="SELECT 1" StatementSubTreeCost="0.25"/>` +
+		`<StmtSimple StatementText="SELECT 2" StatementSubTreeCost="1.5E+01"/>` +
+		`</Statements></Batch></BatchSequence></ShowPlanXML>`
+
+	cost, err := parseMssqlPlanCost([]string{plan})
+	require.NoError(t, err)
+	require.Equal(t, 15.25, cost)
+
+	_, err = parseMssqlPlanCost([]string{`<ShowPlanXML/>`})
+	require.Error(t, err)
+}
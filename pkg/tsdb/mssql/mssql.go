@@ -113,6 +113,7 @@ func newMSSQL(ctx context.Context, driverName string, userFacingDefaultError str
 		DSInfo:            dsInfo,
 		MetricColumnTypes: []string{"VARCHAR", "CHAR", "NVARCHAR", "NCHAR"},
 		RowLimit:          rowLimit,
+		GuardrailsDialect: mssqlGuardrailsDialect{},
 	}
 
 	queryResultTransformer := mssqlQueryResultTransformer{
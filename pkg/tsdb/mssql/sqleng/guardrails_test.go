@@ -0,0 +1,157 @@
+package sqleng
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGuardrails(t *testing.T) {
+	t.Run("parses the maximum time range", func(t *testing.T) {
+		g, err := newGuardrails(JsonData{MaxTimeRange: "7d", StatementTimeout: 30}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 7*24*time.Hour, g.maxTimeRange)
+		assert.Equal(t, 30*time.Second, g.statementTimeout)
+	})
+
+	t.Run("rejects an invalid maximum time range", func(t *testing.T) {
+		_, err := newGuardrails(JsonData{MaxTimeRange: "a week"}, nil)
+		require.Error(t, err)
+	})
+
+	t.Run("rejects negative limits", func(t *testing.T) {
+		_, err := newGuardrails(JsonData{MaxQueryCost: -1}, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestGuardrailsCheckQuery(t *testing.T) {
+	to := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	query := backend.DataQuery{TimeRange: backend.TimeRange{From: to.Add(-48 * time.Hour), To: to}}
+
+	t.Run("no guardrails allow anything", func(t *testing.T) {
+		require.NoError(t, guardrails{}.checkQuery(query, "SELECT * FROM orders"))
+	})
+
+	t.Run("requires a time filter macro", func(t *testing.T) {
+		g := guardrails{requireTimeFilter: true}
+
+		err := g.checkQuery(query, "SELECT * FROM orders")
+		var rejectedErr *QueryRejectedError
+		require.ErrorAs(t, err, &rejectedErr)
+
+		require.NoError(t, g.checkQuery(query, "SELECT * FROM orders WHERE $__timeFilter(created)"))
+		require.NoError(t, g.checkQuery(query, "SELECT * FROM orders WHERE $__unixEpochFilter(created)"))
+	})
+
+	t.Run("ignores time filter macros in comments and strings", func(t *testing.T) {
+		g := guardrails{requireTimeFilter: true}
+
+		for _, rawSQL := range []string{
+			"SELECT * FROM orders -- $__timeFilter(created)",
+			"SELECT * FROM orders /* WHERE $__timeFilter(created) */",
+			"SELECT * FROM orders WHERE note = '$__timeFilter(created)'",
+			"SELECT * FROM orders WHERE note = 'it''s $__timeFilter(created)'",
+		} {
+			err := g.checkQuery(query, rawSQL)
+			var rejectedErr *QueryRejectedError
+			require.ErrorAs(t, err, &rejectedErr, rawSQL)
+		}
+
+		require.NoError(t, g.checkQuery(query, "SELECT * FROM orders -- recent orders\nWHERE $__timeFilter(created)"))
+		require.NoError(t, g.checkQuery(query, "SELECT 'a' AS kind /* all */ FROM orders WHERE $__timeFilter(created)"))
+	})
+
+	t.Run("limits the time range", func(t *testing.T) {
+		g := guardrails{maxTimeRange: 24 * time.Hour}
+
+		err := g.checkQuery(query, "SELECT 1")
+		require.EqualError(t, err, "time range of 2d exceeds the maximum of 1d")
+
+		g.maxTimeRange = 72 * time.Hour
+		require.NoError(t, g.checkQuery(query, "SELECT 1"))
+	})
+}
+
+type fakeGuardrailsDialect struct {
+	timeout time.Duration
+	cost    float64
+	costErr error
+}
+
+func (d *fakeGuardrailsDialect) SetStatementTimeout(_ context.Context, _ *sql.Conn, timeout time.Duration) error {
+	d.timeout = timeout
+	return nil
+}
+
+func (d *fakeGuardrailsDialect) EstimateCost(_ context.Context, _ *sql.Conn, _ string) (float64, error) {
+	return d.cost, d.costErr
+}
+
+func TestGuardrailsQuery(t *testing.T) {
+	logger := backend.NewLoggerWith("logger", "test")
+
+	t.Run("sets the statement timeout on the session", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(1))
+
+		dialect := &fakeGuardrailsDialect{}
+		g := guardrails{statementTimeout: 5 * time.Second, dialect: dialect}
+
+		rows, release, err := g.query(context.Background(), logger, db, "SELECT 1")
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+		release()
+
+		assert.Equal(t, 5*time.Second, dialect.timeout)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects queries above the cost ceiling", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+
+		g := guardrails{maxQueryCost: 1000, dialect: &fakeGuardrailsDialect{cost: 5000}}
+
+		_, _, err = g.query(context.Background(), logger, db, "SELECT * FROM orders")
+		require.EqualError(t, err, "estimated query cost of 5000 exceeds the maximum of 1000")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects queries whose cost can't be estimated", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+
+		g := guardrails{maxQueryCost: 1000, dialect: &fakeGuardrailsDialect{costErr: errors.New("syntax error")}}
+
+		_, _, err = g.query(context.Background(), logger, db, "SELECT 1; SELECT 2")
+		var rejectedErr *QueryRejectedError
+		require.ErrorAs(t, err, &rejectedErr)
+	})
+
+	t.Run("runs queries below the cost ceiling", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(1))
+
+		g := guardrails{maxQueryCost: 1000, dialect: &fakeGuardrailsDialect{cost: 10}}
+
+		rows, release, err := g.query(context.Background(), logger, db, "SELECT 1")
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+		release()
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
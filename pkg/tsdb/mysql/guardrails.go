@@ -0,0 +1,52 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// mysqlGuardrailsDialect enforces the query guardrails with the session execution time limit
+// and the optimizer estimates of EXPLAIN.
+type mysqlGuardrailsDialect struct{}
+
+func (mysqlGuardrailsDialect) SetStatementTimeout(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("SET SESSION max_execution_time = %d", timeout.Milliseconds()))
+	if err == nil {
+		return nil
+	}
+	// MariaDB doesn't know max_execution_time and uses max_statement_time in seconds instead
+	if _, mariadbErr := conn.ExecContext(ctx, fmt.Sprintf("SET SESSION max_statement_time = %g", timeout.Seconds())); mariadbErr != nil {
+		return err
+	}
+	return nil
+}
+
+func (mysqlGuardrailsDialect) EstimateCost(ctx context.Context, conn *sql.Conn, query string) (float64, error) {
+	var plan string
+	if err := conn.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+query).Scan(&plan); err != nil {
+		return 0, err
+	}
+	return parseMysqlPlanCost(plan)
+}
+
+func parseMysqlPlanCost(plan string) (float64, error) {
+	var explained struct {
+		QueryBlock struct {
+			CostInfo struct {
+				QueryCost string `json:"query_cost"`
+			} `json:"cost_info"`
+		} `json:"query_block"`
+	}
+	if err := json.Unmarshal([]byte(plan), &explained); err != nil {
+		return 0, err
+	}
+	if explained.QueryBlock.CostInfo.QueryCost == "" {
+		return 0, errors.New("query plan has no cost")
+	}
+	return strconv.ParseFloat(explained.QueryBlock.CostInfo.QueryCost, 64)
+}
@@ -0,0 +1,16 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMysqlPlanCost(t *testing.T) {
+	cost, err := parseMysqlPlanCost(`{"query_block": {"select_id": 1, "cost_info": {"query_cost": "1021.75"}}}`)
+	require.NoError(t, err)
+	require.Equal(t, 1021.75, cost)
+
+	_, err = parseMysqlPlanCost(`{"query_block": {"union_result": {}}}`)
+	require.Error(t, err)
+}
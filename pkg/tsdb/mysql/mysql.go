@@ -134,6 +134,7 @@ func NewInstanceSettings(logger log.Logger) datasource.InstanceFactoryFunc {
 			TimeColumnNames:   []string{"time", "time_sec"},
 			MetricColumnTypes: []string{"CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT"},
 			RowLimit:          sqlCfg.RowLimit,
+			GuardrailsDialect: mysqlGuardrailsDialect{},
 		}
 
 		userFacingDefaultError, err := cfg.UserFacingDefaultError()
@@ -0,0 +1,187 @@
+package sqleng
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// QueryRejectedError is returned when a query violates one of the datasource guardrails.
+type QueryRejectedError struct {
+	Reason string
+}
+
+func (e *QueryRejectedError) Error() string {
+	return e.Reason
+}
+
+func queryRejected(format string, args ...any) error {
+	return &QueryRejectedError{Reason: fmt.Sprintf(format, args...)}
+}
+
+var timeFilterMacroRegex = regexp.MustCompile(`\$__(timeFilter|unixEpochFilter|unixEpochNanoFilter)\(`)
+
+// stripCommentsAndStrings removes the comments and quoted strings of a query, so macros
+// that only appear in them aren't taken into account.
+func stripCommentsAndStrings(rawSQL string) string {
+	var b strings.Builder
+	for i := 0; i < len(rawSQL); i++ {
+		switch {
+		case strings.HasPrefix(rawSQL[i:], "--"):
+			end := strings.IndexByte(rawSQL[i:], '\n')
+			if end < 0 {
+				return b.String()
+			}
+			i += end
+			b.WriteByte('\n')
+		case strings.HasPrefix(rawSQL[i:], "/*"):
+			end := strings.Index(rawSQL[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 3
+			b.WriteByte(' ')
+		case rawSQL[i] == '\'' || rawSQL[i] == '"':
+			// doubled quotes are part of the string
+			quote := rawSQL[i]
+			for i++; i < len(rawSQL); i++ {
+				if rawSQL[i] != quote {
+					continue
+				}
+				if i+1 < len(rawSQL) && rawSQL[i+1] == quote {
+					i++
+					continue
+				}
+				break
+			}
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(rawSQL[i])
+		}
+	}
+	return b.String()
+}
+
+// GuardrailsDialect provides the database specific parts of the query guardrails.
+type GuardrailsDialect interface {
+	// SetStatementTimeout limits the execution time of the statements run on conn.
+	SetStatementTimeout(ctx context.Context, conn *sql.Conn, timeout time.Duration) error
+	// EstimateCost returns the cost the query planner estimates for running query on conn.
+	EstimateCost(ctx context.Context, conn *sql.Conn, query string) (float64, error)
+}
+
+// guardrails are the per datasource limits enforced before and during query execution.
+type guardrails struct {
+	maxTimeRange      time.Duration
+	requireTimeFilter bool
+	statementTimeout  time.Duration
+	maxQueryCost      float64
+	dialect           GuardrailsDialect
+}
+
+func newGuardrails(jsonData JsonData, dialect GuardrailsDialect) (guardrails, error) {
+	g := guardrails{
+		requireTimeFilter: jsonData.RequireTimeFilter,
+		statementTimeout:  time.Duration(jsonData.StatementTimeout) * time.Second,
+		maxQueryCost:      jsonData.MaxQueryCost,
+		dialect:           dialect,
+	}
+	if jsonData.MaxTimeRange != "" {
+		maxTimeRange, err := gtime.ParseDuration(jsonData.MaxTimeRange)
+		if err != nil {
+			return guardrails{}, fmt.Errorf("invalid maximum time range %q: %w", jsonData.MaxTimeRange, err)
+		}
+		g.maxTimeRange = maxTimeRange
+	}
+	if g.statementTimeout < 0 || g.maxQueryCost < 0 || g.maxTimeRange < 0 {
+		return guardrails{}, errors.New("query guardrails must not be negative")
+	}
+	return g, nil
+}
+
+// checkQuery validates the raw query and its time range before it is sent to the database.
+func (g guardrails) checkQuery(query backend.DataQuery, rawSQL string) error {
+	if g.requireTimeFilter && !timeFilterMacroRegex.MatchString(stripCommentsAndStrings(rawSQL)) {
+		return queryRejected("the query must filter on time with the $__timeFilter macro")
+	}
+	if g.maxTimeRange > 0 {
+		if duration := query.TimeRange.To.Sub(query.TimeRange.From); duration > g.maxTimeRange {
+			return queryRejected("time range of %s exceeds the maximum of %s",
+				gtime.FormatInterval(duration), gtime.FormatInterval(g.maxTimeRange))
+		}
+	}
+	return nil
+}
+
+// needsSession reports whether queries have to run on a dedicated connection so the
+// statement timeout or the cost estimate apply to the same session as the query.
+func (g guardrails) needsSession() bool {
+	return g.dialect != nil && (g.statementTimeout > 0 || g.maxQueryCost > 0)
+}
+
+// query runs the query, enforcing the statement timeout and cost ceiling. The returned
+// function releases the resources and must be called once the rows are closed.
+func (g guardrails) query(ctx context.Context, logger log.Logger, db *sql.DB, query string) (*sql.Rows, func(), error) {
+	cancel := func() {}
+	if g.statementTimeout > 0 {
+		// Also bound the query on the client side in case the server setting is not honored.
+		ctx, cancel = context.WithTimeout(ctx, g.statementTimeout)
+	}
+
+	if !g.needsSession() {
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		return rows, cancel, nil
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	release := func() {
+		if err := conn.Close(); err != nil {
+			logger.Warn("Failed to release connection", "err", err)
+		}
+		cancel()
+	}
+
+	if g.statementTimeout > 0 {
+		if err := g.dialect.SetStatementTimeout(ctx, conn, g.statementTimeout); err != nil {
+			release()
+			return nil, nil, fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+	}
+
+	if g.maxQueryCost > 0 {
+		cost, err := g.dialect.EstimateCost(ctx, conn, query)
+		if err != nil {
+			// Queries the planner can't explain are rejected, otherwise they would bypass the ceiling
+			logger.Debug("Failed to estimate query cost", "err", err)
+			release()
+			return nil, nil, queryRejected("the query cost could not be estimated: %v", err)
+		}
+		if cost > g.maxQueryCost {
+			release()
+			return nil, nil, queryRejected("estimated query cost of %.0f exceeds the maximum of %.0f", cost, g.maxQueryCost)
+		}
+	}
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return rows, release, nil
+}
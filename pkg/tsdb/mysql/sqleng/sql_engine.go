@@ -59,6 +59,12 @@ type JsonData struct {
 	SecureDSProxyUsername   string `json:"secureSocksProxyUsername"`
 	AllowCleartextPasswords bool   `json:"allowCleartextPasswords"`
 	AuthenticationType      string `json:"authenticationType"`
+
+	// Query guardrails, see guardrails.go
+	MaxTimeRange      string  `json:"maxTimeRange"`
+	RequireTimeFilter bool    `json:"requireTimeFilter"`
+	StatementTimeout  int     `json:"statementTimeout"`
+	MaxQueryCost      float64 `json:"maxQueryCost"`
 }
 
 type DataSourceInfo struct {
@@ -77,6 +83,7 @@ type DataPluginConfiguration struct {
 	TimeColumnNames   []string
 	MetricColumnTypes []string
 	RowLimit          int64
+	GuardrailsDialect GuardrailsDialect
 }
 
 type DataSourceHandler struct {
@@ -89,6 +96,7 @@ type DataSourceHandler struct {
 	dsInfo                 DataSourceInfo
 	rowLimit               int64
 	userError              string
+	guardrails             guardrails
 }
 
 type QueryJson struct {
@@ -116,6 +124,11 @@ func (e *DataSourceHandler) TransformQueryError(logger log.Logger, err error) er
 
 func NewQueryDataHandler(userFacingDefaultError string, db *sql.DB, config DataPluginConfiguration, queryResultTransformer SqlQueryResultTransformer,
 	macroEngine SQLMacroEngine, log log.Logger) (*DataSourceHandler, error) {
+	guardrails, err := newGuardrails(config.DSInfo.JsonData, config.GuardrailsDialect)
+	if err != nil {
+		return nil, err
+	}
+
 	queryDataHandler := DataSourceHandler{
 		queryResultTransformer: queryResultTransformer,
 		macroEngine:            macroEngine,
@@ -124,6 +137,7 @@ func NewQueryDataHandler(userFacingDefaultError string, db *sql.DB, config DataP
 		dsInfo:                 config.DSInfo,
 		rowLimit:               config.RowLimit,
 		userError:              userFacingDefaultError,
+		guardrails:             guardrails,
 	}
 
 	if len(config.TimeColumnNames) > 0 {
@@ -257,11 +271,22 @@ func (e *DataSourceHandler) executeQuery(query backend.DataQuery, wg *sync.WaitG
 		return
 	}
 
-	rows, err := e.db.QueryContext(queryContext, interpolatedQuery)
+	if err := e.guardrails.checkQuery(query, queryJson.RawSql); err != nil {
+		errAppendDebug("query rejected", err, interpolatedQuery)
+		return
+	}
+
+	rows, release, err := e.guardrails.query(queryContext, logger, e.db, interpolatedQuery)
 	if err != nil {
+		var rejectedErr *QueryRejectedError
+		if errors.As(err, &rejectedErr) {
+			errAppendDebug("query rejected", err, interpolatedQuery)
+			return
+		}
 		errAppendDebug("db query error", e.TransformQueryError(logger, err), interpolatedQuery)
 		return
 	}
+	defer release()
 	defer func() {
 		if err := rows.Close(); err != nil {
 			logger.Warn("Failed to close rows", "err", err)
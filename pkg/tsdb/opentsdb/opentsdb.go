@@ -8,12 +8,15 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
@@ -25,22 +28,37 @@ import (
 var logger = log.New("tsdb.opentsdb")
 
 type Service struct {
-	im instancemgmt.InstanceManager
+	im              instancemgmt.InstanceManager
+	resourceHandler backend.CallResourceHandler
 }
 
 func ProvideService(httpClientProvider httpclient.Provider) *Service {
-	return &Service{
+	s := &Service{
 		im: datasource.NewInstanceManager(newInstanceSettings(httpClientProvider)),
 	}
+	s.resourceHandler = httpadapter.New(s.newResourceMux())
+	return s
 }
 
 type datasourceInfo struct {
-	HTTPClient *http.Client
-	URL        string
+	HTTPClient  *http.Client
+	URL         string
+	TSDBVersion int
+	LookupLimit int
+}
+
+type jsonData struct {
+	TSDBVersion int `json:"tsdbVersion"`
+	LookupLimit int `json:"lookupLimit"`
 }
 
 type DsAccess string
 
+const (
+	defaultTSDBVersion = 1
+	defaultLookupLimit = 1000
+)
+
 func newInstanceSettings(httpClientProvider httpclient.Provider) datasource.InstanceFactoryFunc {
 	return func(ctx context.Context, settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
 		opts, err := settings.HTTPClientOptions(ctx)
@@ -53,50 +71,113 @@ func newInstanceSettings(httpClientProvider httpclient.Provider) datasource.Inst
 			return nil, err
 		}
 
+		settingsData := jsonData{}
+		if len(settings.JSONData) > 0 {
+			if err := json.Unmarshal(settings.JSONData, &settingsData); err != nil {
+				return nil, fmt.Errorf("error reading settings: %w", err)
+			}
+		}
+		if settingsData.TSDBVersion == 0 {
+			settingsData.TSDBVersion = defaultTSDBVersion
+		}
+		if settingsData.LookupLimit == 0 {
+			settingsData.LookupLimit = defaultLookupLimit
+		}
+
 		model := &datasourceInfo{
-			HTTPClient: client,
-			URL:        settings.URL,
+			HTTPClient:  client,
+			URL:         settings.URL,
+			TSDBVersion: settingsData.TSDBVersion,
+			LookupLimit: settingsData.LookupLimit,
 		}
 
 		return model, nil
 	}
 }
 
-func (s *Service) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
-	var tsdbQuery OpenTsdbQuery
+func (s *Service) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	return s.resourceHandler.CallResource(ctx, req, sender)
+}
 
+// QueryData sends all queries sharing a time range to OpenTSDB in a single /api/query call
+// and maps the returned series back to the query they belong to.
+func (s *Service) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
 	logger := logger.FromContext(ctx)
 
-	q := req.Queries[0]
+	dsInfo, err := s.getDSInfo(ctx, req.PluginContext)
+	if err != nil {
+		return nil, err
+	}
 
-	myRefID := q.RefID
+	result := backend.NewQueryDataResponse()
+	for _, batch := range s.batchQueries(req.Queries, dsInfo.TSDBVersion) {
+		// TODO: Don't use global variable
+		if setting.Env == setting.Dev {
+			logger.Debug("OpenTsdb request", "params", batch.tsdbQuery)
+		}
 
-	tsdbQuery.Start = q.TimeRange.From.UnixNano() / int64(time.Millisecond)
-	tsdbQuery.End = q.TimeRange.To.UnixNano() / int64(time.Millisecond)
+		batchResult, err := s.executeBatch(ctx, logger, dsInfo, batch)
+		if err != nil {
+			for _, refID := range batch.refIDs {
+				result.Responses[refID] = backend.DataResponse{Error: err}
+			}
+			continue
+		}
+		for refID, res := range batchResult.Responses {
+			result.Responses[refID] = res
+		}
+	}
 
-	for _, query := range req.Queries {
+	return result, nil
+}
+
+// queryBatch is a set of queries that can be sent to OpenTSDB in one request. The refIDs
+// are in the same order as tsdbQuery.Queries, which is the order of the query indexes
+// OpenTSDB reports back.
+type queryBatch struct {
+	tsdbQuery OpenTsdbQuery
+	refIDs    []string
+}
+
+func (s *Service) batchQueries(queries []backend.DataQuery, tsdbVersion int) []*queryBatch {
+	batches := []*queryBatch{}
+	byTimeRange := map[backend.TimeRange]*queryBatch{}
+
+	for _, query := range queries {
 		metric := s.buildMetric(query)
-		tsdbQuery.Queries = append(tsdbQuery.Queries, metric)
-	}
+		if metric == nil {
+			continue
+		}
 
-	// TODO: Don't use global variable
-	if setting.Env == setting.Dev {
-		logger.Debug("OpenTsdb request", "params", tsdbQuery)
+		batch, ok := byTimeRange[query.TimeRange]
+		if !ok {
+			batch = &queryBatch{
+				tsdbQuery: OpenTsdbQuery{
+					Start: query.TimeRange.From.UnixNano() / int64(time.Millisecond),
+					End:   query.TimeRange.To.UnixNano() / int64(time.Millisecond),
+					// The query index in the response is only available from OpenTSDB 2.3
+					ShowQuery: tsdbVersion >= 3,
+				},
+			}
+			byTimeRange[query.TimeRange] = batch
+			batches = append(batches, batch)
+		}
+		batch.tsdbQuery.Queries = append(batch.tsdbQuery.Queries, metric)
+		batch.refIDs = append(batch.refIDs, query.RefID)
 	}
 
-	dsInfo, err := s.getDSInfo(ctx, req.PluginContext)
-	if err != nil {
-		return nil, err
-	}
+	return batches
+}
 
-	request, err := s.createRequest(ctx, logger, dsInfo, tsdbQuery)
+func (s *Service) executeBatch(ctx context.Context, logger log.Logger, dsInfo *datasourceInfo, batch *queryBatch) (*backend.QueryDataResponse, error) {
+	request, err := s.createRequest(ctx, logger, dsInfo, batch.tsdbQuery)
 	if err != nil {
-		return &backend.QueryDataResponse{}, err
+		return nil, err
 	}
 
 	res, err := dsInfo.HTTPClient.Do(request)
 	if err != nil {
-		return &backend.QueryDataResponse{}, err
+		return nil, err
 	}
 
 	defer func() {
@@ -106,12 +187,7 @@ func (s *Service) QueryData(ctx context.Context, req *backend.QueryDataRequest)
 		}
 	}()
 
-	result, err := s.parseResponse(logger, res, myRefID)
-	if err != nil {
-		return &backend.QueryDataResponse{}, err
-	}
-
-	return result, nil
+	return s.parseResponse(logger, res, batch)
 }
 
 func (s *Service) createRequest(ctx context.Context, logger log.Logger, dsInfo *datasourceInfo, data OpenTsdbQuery) (*http.Request, error) {
@@ -140,7 +216,7 @@ func (s *Service) createRequest(ctx context.Context, logger log.Logger, dsInfo *
 	return req, nil
 }
 
-func (s *Service) parseResponse(logger log.Logger, res *http.Response, myRefID string) (*backend.QueryDataResponse, error) {
+func (s *Service) parseResponse(logger log.Logger, res *http.Response, batch *queryBatch) (*backend.QueryDataResponse, error) {
 	resp := backend.NewQueryDataResponse()
 
 	body, err := io.ReadAll(res.Body)
@@ -165,8 +241,17 @@ func (s *Service) parseResponse(logger log.Logger, res *http.Response, myRefID s
 		return nil, err
 	}
 
-	frames := data.Frames{}
+	for _, refID := range batch.refIDs {
+		resp.Responses[refID] = backend.DataResponse{Frames: data.Frames{}}
+	}
+
 	for _, val := range responseData {
+		refID, ok := batch.refIDFor(val)
+		if !ok {
+			// the series would be shown as the result of another query of the batch
+			logger.Warn("Ignoring series matching none of the queries", "metric", val.Metric, "tags", val.Tags, "refIds", batch.refIDs)
+			continue
+		}
 		labels := data.Labels{}
 		for label, value := range val.Tags {
 			labels[label] = value
@@ -174,7 +259,7 @@ func (s *Service) parseResponse(logger log.Logger, res *http.Response, myRefID s
 
 		frame := data.NewFrameOfFieldTypes(val.Metric, len(val.DataPoints), data.FieldTypeTime, data.FieldTypeFloat64)
 		frame.Meta = &data.FrameMeta{Type: data.FrameTypeTimeSeriesMulti, TypeVersion: data.FrameTypeVersion{0, 1}}
-		frame.RefID = myRefID
+		frame.RefID = refID
 		timeField := frame.Fields[0]
 		timeField.Name = data.TimeSeriesTimeFieldName
 		dataField := frame.Fields[1]
@@ -185,14 +270,59 @@ func (s *Service) parseResponse(logger log.Logger, res *http.Response, myRefID s
 		for i, point := range points {
 			frame.SetRow(i, time.Unix(int64(point[0]), 0).UTC(), point[1])
 		}
-		frames = append(frames, frame)
+		result := resp.Responses[refID]
+		result.Frames = append(result.Frames, frame)
+		resp.Responses[refID] = result
 	}
-	result := resp.Responses[myRefID]
-	result.Frames = frames
-	resp.Responses[myRefID] = result
 	return resp, nil
 }
 
+// refIDFor returns the refID of the query a series belongs to. OpenTSDB 2.3+ reports the
+// query index; for older versions the series is matched on metric name and tags the same
+// way the frontend does it. It returns false when the series matches none of the queries.
+func (b *queryBatch) refIDFor(series OpenTsdbResponse) (string, bool) {
+	if len(b.refIDs) == 0 {
+		return "", false
+	}
+	if series.Query != nil && series.Query.Index >= 0 && series.Query.Index < len(b.refIDs) {
+		return b.refIDs[series.Query.Index], true
+	}
+	// a series can only belong to the query of a batch of one
+	if len(b.refIDs) == 1 {
+		return b.refIDs[0], true
+	}
+
+	for i, query := range b.tsdbQuery.Queries {
+		if i >= len(b.refIDs) || query["metric"] != series.Metric {
+			continue
+		}
+		if _, hasFilters := query["filters"]; hasFilters {
+			return b.refIDs[i], true
+		}
+		if tagsMatch(query["tags"], series.Tags) {
+			return b.refIDs[i], true
+		}
+	}
+	return "", false
+}
+
+func tagsMatch(queryTags any, seriesTags map[string]string) bool {
+	tags, ok := queryTags.(map[string]any)
+	if !ok {
+		return true
+	}
+	for tagk, tagv := range tags {
+		value := fmt.Sprintf("%v", tagv)
+		if value == "*" {
+			continue
+		}
+		if !slices.Contains(strings.Split(value, "|"), seriesTags[tagk]) {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *Service) buildMetric(query backend.DataQuery) map[string]any {
 	metric := make(map[string]any)
 
@@ -201,8 +331,14 @@ func (s *Service) buildMetric(query backend.DataQuery) map[string]any {
 		return nil
 	}
 
+	// Queries without a metric are skipped, like the frontend does
+	metricName := model.Get("metric").MustString()
+	if metricName == "" {
+		return nil
+	}
+
 	// Setting metric and aggregator
-	metric["metric"] = model.Get("metric").MustString()
+	metric["metric"] = metricName
 	metric["aggregator"] = model.Get("aggregator").MustString()
 
 	// Setting downsampling options
@@ -210,11 +346,17 @@ func (s *Service) buildMetric(query backend.DataQuery) map[string]any {
 	if !disableDownsampling {
 		downsampleInterval := model.Get("downsampleInterval").MustString()
 		if downsampleInterval == "" {
-			downsampleInterval = "1m" // default value for blank
+			downsampleInterval = formatDownsampleInterval(query.Interval)
+		}
+		downsampleInterval = fixDownsampleInterval(downsampleInterval)
+		downsampleAggregator := model.Get("downsampleAggregator").MustString()
+		if downsampleAggregator == "" {
+			downsampleAggregator = "avg"
 		}
-		downsample := downsampleInterval + "-" + model.Get("downsampleAggregator").MustString()
-		if model.Get("downsampleFillPolicy").MustString() != "none" {
-			metric["downsample"] = downsample + "-" + model.Get("downsampleFillPolicy").MustString()
+		downsample := downsampleInterval + "-" + downsampleAggregator
+		fillPolicy := model.Get("downsampleFillPolicy").MustString()
+		if fillPolicy != "" && fillPolicy != "none" {
+			metric["downsample"] = downsample + "-" + fillPolicy
 		} else {
 			metric["downsample"] = downsample
 		}
@@ -226,17 +368,17 @@ func (s *Service) buildMetric(query backend.DataQuery) map[string]any {
 		rateOptions := make(map[string]any)
 		rateOptions["counter"] = model.Get("isCounter").MustBool()
 
-		counterMax, counterMaxCheck := model.CheckGet("counterMax")
+		counterMax, counterMaxCheck := optionalFloat(model, "counterMax")
 		if counterMaxCheck {
-			rateOptions["counterMax"] = counterMax.MustFloat64()
+			rateOptions["counterMax"] = counterMax
 		}
 
-		resetValue, resetValueCheck := model.CheckGet("counterResetValue")
+		resetValue, resetValueCheck := optionalFloat(model, "counterResetValue")
 		if resetValueCheck {
-			rateOptions["resetValue"] = resetValue.MustFloat64()
+			rateOptions["resetValue"] = resetValue
 		}
 
-		if !counterMaxCheck && (!resetValueCheck || resetValue.MustFloat64() == 0) {
+		if !counterMaxCheck && (!resetValueCheck || resetValue == 0) {
 			rateOptions["dropResets"] = true
 		}
 
@@ -255,9 +397,64 @@ func (s *Service) buildMetric(query backend.DataQuery) map[string]any {
 		metric["filters"] = filters.MustArray()
 	}
 
+	if model.Get("explicitTags").MustBool() {
+		metric["explicitTags"] = true
+	}
+
 	return metric
 }
 
+// formatDownsampleInterval converts the query interval to an OpenTSDB duration, falling
+// back to one minute when the request carries no interval.
+func formatDownsampleInterval(interval time.Duration) string {
+	switch {
+	case interval <= 0:
+		return "1m" // default value for blank
+	case interval%time.Hour == 0:
+		return fmt.Sprintf("%dh", interval/time.Hour)
+	case interval%time.Minute == 0:
+		return fmt.Sprintf("%dm", interval/time.Minute)
+	case interval%time.Second == 0:
+		return fmt.Sprintf("%ds", interval/time.Second)
+	default:
+		return fmt.Sprintf("%dms", interval/time.Millisecond)
+	}
+}
+
+// fixDownsampleInterval rewrites fractional seconds like 0.5s to milliseconds, since
+// OpenTSDB only accepts integer durations.
+func fixDownsampleInterval(interval string) string {
+	if !strings.HasSuffix(interval, "s") || strings.HasSuffix(interval, "ms") || !strings.Contains(interval, ".") {
+		return interval
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSuffix(interval, "s"), 64)
+	if err != nil {
+		return interval
+	}
+	return strconv.FormatFloat(seconds*1000, 'f', -1, 64) + "ms"
+}
+
+// optionalFloat reads a numeric rate option that the query editor may store as a
+// number or as a (possibly empty) string.
+func optionalFloat(model *simplejson.Json, key string) (float64, bool) {
+	value, ok := model.CheckGet(key)
+	if !ok {
+		return 0, false
+	}
+	if f, err := value.Float64(); err == nil {
+		return f, true
+	}
+	str, err := value.String()
+	if err != nil || strings.TrimSpace(str) == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
 func (s *Service) getDSInfo(ctx context.Context, pluginCtx backend.PluginContext) (*datasourceInfo, error) {
 	i, err := s.im.Get(ctx, pluginCtx)
 	if err != nil {
@@ -33,7 +33,7 @@ func TestOpenTsdbExecutor(t *testing.T) {
 	t.Run("Parse response should handle invalid JSON", func(t *testing.T) {
 		response := `{ invalid }`
 
-		result, err := service.parseResponse(logger, &http.Response{Body: io.NopCloser(strings.NewReader(response))}, "A")
+		result, err := service.parseResponse(logger, &http.Response{Body: io.NopCloser(strings.NewReader(response))}, &queryBatch{refIDs: []string{"A"}})
 		require.Nil(t, result)
 		require.Error(t, err)
 	})
@@ -68,7 +68,7 @@ func TestOpenTsdbExecutor(t *testing.T) {
 
 		resp := http.Response{Body: io.NopCloser(strings.NewReader(response))}
 		resp.StatusCode = 200
-		result, err := service.parseResponse(logger, &resp, "A")
+		result, err := service.parseResponse(logger, &resp, &queryBatch{refIDs: []string{"A"}})
 		require.NoError(t, err)
 
 		frame := result.Responses["A"]
@@ -110,7 +110,7 @@ func TestOpenTsdbExecutor(t *testing.T) {
 
 		resp := http.Response{Body: io.NopCloser(strings.NewReader(response))}
 		resp.StatusCode = 200
-		result, err := service.parseResponse(logger, &resp, myRefid)
+		result, err := service.parseResponse(logger, &resp, &queryBatch{refIDs: []string{myRefid}})
 		require.NoError(t, err)
 
 		if diff := cmp.Diff(testFrame, result.Responses[myRefid].Frames[0], data.FrameTestCompareOptions()...); diff != "" {
@@ -286,4 +286,168 @@ func TestOpenTsdbExecutor(t *testing.T) {
 		require.Equal(t, float64(45), metricRateOptions["counterMax"])
 		require.Equal(t, float64(60), metricRateOptions["resetValue"])
 	})
+
+	t.Run("Build metric with rate options stored as strings", func(t *testing.T) {
+		query := backend.DataQuery{
+			JSON: []byte(`
+					{
+						"metric": "cpu.average.percent",
+						"aggregator": "avg",
+						"disableDownsampling": true,
+						"shouldComputeRate": true,
+						"isCounter": true,
+						"counterMax": "45",
+						"counterResetValue": ""
+					}`,
+			),
+		}
+
+		metric := service.buildMetric(query)
+
+		metricRateOptions := metric["rateOptions"].(map[string]any)
+		require.Len(t, metricRateOptions, 2)
+		require.Equal(t, float64(45), metricRateOptions["counterMax"])
+	})
+
+	t.Run("Build metric uses the query interval when no downsample interval is set", func(t *testing.T) {
+		query := backend.DataQuery{
+			Interval: 30 * time.Second,
+			JSON: []byte(`
+					{
+						"metric": "cpu.average.percent",
+						"aggregator": "avg",
+						"downsampleAggregator": "max",
+						"explicitTags": true
+					}`,
+			),
+		}
+
+		metric := service.buildMetric(query)
+
+		require.Equal(t, "30s-max", metric["downsample"])
+		require.Equal(t, true, metric["explicitTags"])
+	})
+
+	t.Run("Build metric converts fractional second intervals to milliseconds", func(t *testing.T) {
+		query := backend.DataQuery{
+			JSON: []byte(`
+					{
+						"metric": "cpu.average.percent",
+						"aggregator": "avg",
+						"downsampleInterval": "0.5s",
+						"downsampleAggregator": "avg",
+						"downsampleFillPolicy": "zero"
+					}`,
+			),
+		}
+
+		metric := service.buildMetric(query)
+
+		require.Equal(t, "500ms-avg-zero", metric["downsample"])
+	})
+
+	t.Run("Build metric skips queries without a metric", func(t *testing.T) {
+		query := backend.DataQuery{JSON: []byte(`{"aggregator": "avg"}`)}
+		require.Nil(t, service.buildMetric(query))
+	})
+}
+
+func TestOpenTsdbBatching(t *testing.T) {
+	service := &Service{}
+	timeRange := backend.TimeRange{From: time.Unix(0, 0), To: time.Unix(3600, 0)}
+
+	t.Run("queries sharing a time range are sent in one request", func(t *testing.T) {
+		queries := []backend.DataQuery{
+			{RefID: "A", TimeRange: timeRange, JSON: []byte(`{"metric": "cpu", "aggregator": "sum"}`)},
+			{RefID: "B", TimeRange: timeRange, JSON: []byte(`{"metric": "mem", "aggregator": "sum"}`)},
+			{RefID: "C", TimeRange: timeRange, JSON: []byte(`{"aggregator": "sum"}`)},
+			{RefID: "D", TimeRange: backend.TimeRange{From: time.Unix(0, 0), To: time.Unix(60, 0)}, JSON: []byte(`{"metric": "disk", "aggregator": "sum"}`)},
+		}
+
+		batches := service.batchQueries(queries, 3)
+
+		require.Len(t, batches, 2)
+		require.Equal(t, []string{"A", "B"}, batches[0].refIDs)
+		require.Len(t, batches[0].tsdbQuery.Queries, 2)
+		require.True(t, batches[0].tsdbQuery.ShowQuery)
+		require.Equal(t, int64(3600000), batches[0].tsdbQuery.End)
+		require.Equal(t, []string{"D"}, batches[1].refIDs)
+	})
+
+	t.Run("show query is only requested from OpenTSDB 2.3", func(t *testing.T) {
+		queries := []backend.DataQuery{
+			{RefID: "A", TimeRange: timeRange, JSON: []byte(`{"metric": "cpu", "aggregator": "sum"}`)},
+		}
+
+		batches := service.batchQueries(queries, 2)
+
+		require.Len(t, batches, 1)
+		require.False(t, batches[0].tsdbQuery.ShowQuery)
+	})
+
+	t.Run("series are mapped to queries using the query index", func(t *testing.T) {
+		batch := &queryBatch{refIDs: []string{"A", "B"}}
+		response := `
+		[
+			{"metric": "cpu", "dps": [[1405544146, 1.0]], "tags": {}, "query": {"index": 1}},
+			{"metric": "cpu", "dps": [[1405544146, 2.0]], "tags": {}, "query": {"index": 0}}
+		]`
+
+		resp := http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(response))}
+		result, err := service.parseResponse(logger, &resp, batch)
+		require.NoError(t, err)
+
+		require.Len(t, result.Responses["A"].Frames, 1)
+		require.Len(t, result.Responses["B"].Frames, 1)
+		require.Equal(t, 2.0, result.Responses["A"].Frames[0].Fields[1].At(0))
+		require.Equal(t, 1.0, result.Responses["B"].Frames[0].Fields[1].At(0))
+	})
+
+	t.Run("series are mapped to queries by metric and tags without a query index", func(t *testing.T) {
+		batch := &queryBatch{
+			refIDs: []string{"A", "B"},
+			tsdbQuery: OpenTsdbQuery{Queries: []map[string]any{
+				{"metric": "cpu", "tags": map[string]any{"host": "a|b"}},
+				{"metric": "cpu", "tags": map[string]any{"host": "c"}},
+			}},
+		}
+		response := `
+		[
+			{"metric": "cpu", "dps": [[1405544146, 1.0]], "tags": {"host": "c"}},
+			{"metric": "cpu", "dps": [[1405544146, 2.0]], "tags": {"host": "b"}}
+		]`
+
+		resp := http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(response))}
+		result, err := service.parseResponse(logger, &resp, batch)
+		require.NoError(t, err)
+
+		require.Len(t, result.Responses["A"].Frames, 1)
+		require.Len(t, result.Responses["B"].Frames, 1)
+		require.Equal(t, "b", result.Responses["A"].Frames[0].Fields[1].Labels["host"])
+		require.Equal(t, "c", result.Responses["B"].Frames[0].Fields[1].Labels["host"])
+	})
+
+	t.Run("series matching none of the queries are ignored without a query index", func(t *testing.T) {
+		batch := &queryBatch{
+			refIDs: []string{"A", "B"},
+			tsdbQuery: OpenTsdbQuery{Queries: []map[string]any{
+				{"metric": "cpu", "tags": map[string]any{"host": "a"}},
+				{"metric": "mem", "tags": map[string]any{"host": "a"}},
+			}},
+		}
+		response := `
+		[
+			{"metric": "cpu", "dps": [[1405544146, 1.0]], "tags": {"host": "a"}},
+			{"metric": "disk", "dps": [[1405544146, 2.0]], "tags": {"host": "a"}}
+		]`
+
+		resp := http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(response))}
+		result, err := service.parseResponse(logger, &resp, batch)
+		require.NoError(t, err)
+
+		require.Len(t, result.Responses["A"].Frames, 1)
+		require.Equal(t, "cpu", result.Responses["A"].Frames[0].Name)
+		require.Empty(t, result.Responses["B"].Frames)
+		require.Len(t, result.Responses, 2)
+	})
 }
@@ -0,0 +1,146 @@
+package opentsdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+var suggestTypes = map[string]bool{
+	"metrics": true,
+	"tagk":    true,
+	"tagv":    true,
+}
+
+type normalizeResponse func(body []byte) ([]byte, error)
+
+func (s *Service) newResourceMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/suggest", s.handleSuggest)
+	mux.HandleFunc("/aggregators", s.handleResourceReq("api/aggregators", normalizeStringList))
+	mux.HandleFunc("/filters", s.handleResourceReq("api/config/filters", normalizeFilters))
+	return mux
+}
+
+// handleSuggest looks up metric names, tag keys or tag values starting with the q parameter.
+func (s *Service) handleSuggest(rw http.ResponseWriter, req *http.Request) {
+	suggestType := req.URL.Query().Get("type")
+	if !suggestTypes[suggestType] {
+		writeResponse(rw, http.StatusBadRequest, fmt.Sprintf("invalid suggest type %q, expected one of metrics, tagk or tagv", suggestType))
+		return
+	}
+	s.handleResourceReq("api/suggest", normalizeStringList)(rw, req)
+}
+
+func (s *Service) handleResourceReq(opentsdbPath string, normalize normalizeResponse) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		logger := logger.FromContext(ctx)
+
+		if req.Method != http.MethodGet {
+			writeResponse(rw, http.StatusMethodNotAllowed, fmt.Sprintf("method %s is not allowed", req.Method))
+			return
+		}
+
+		dsInfo, err := s.getDSInfo(ctx, backend.PluginConfigFromContext(ctx))
+		if err != nil {
+			writeResponse(rw, http.StatusInternalServerError, fmt.Sprintf("failed to get datasource info: %v", err))
+			return
+		}
+
+		u, err := url.Parse(dsInfo.URL)
+		if err != nil {
+			writeResponse(rw, http.StatusInternalServerError, fmt.Sprintf("invalid datasource url: %v", err))
+			return
+		}
+		u.Path = path.Join(u.Path, opentsdbPath)
+		params := req.URL.Query()
+		if opentsdbPath == "api/suggest" && params.Get("max") == "" {
+			params.Set("max", strconv.Itoa(dsInfo.LookupLimit))
+		}
+		u.RawQuery = params.Encode()
+
+		opentsdbReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			writeResponse(rw, http.StatusInternalServerError, fmt.Sprintf("failed to create request: %v", err))
+			return
+		}
+
+		res, err := dsInfo.HTTPClient.Do(opentsdbReq)
+		if err != nil {
+			writeResponse(rw, http.StatusBadGateway, fmt.Sprintf("opentsdb request failed: %v", err))
+			return
+		}
+		defer func() {
+			if err := res.Body.Close(); err != nil {
+				logger.Warn("Failed to close response body", "error", err)
+			}
+		}()
+
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			writeResponse(rw, http.StatusBadGateway, fmt.Sprintf("failed to read opentsdb response: %v", err))
+			return
+		}
+		if res.StatusCode/100 != 2 {
+			logger.Info("Resource request failed", "path", opentsdbPath, "status", res.Status, "body", string(body))
+			writeResponseBytes(rw, res.StatusCode, body)
+			return
+		}
+
+		normalized, err := normalize(body)
+		if err != nil {
+			logger.Info("Failed to normalize opentsdb response", "path", opentsdbPath, "error", err)
+			writeResponse(rw, http.StatusInternalServerError, fmt.Sprintf("unexpected opentsdb response: %v", err))
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		writeResponseBytes(rw, http.StatusOK, normalized)
+	}
+}
+
+// normalizeStringList sorts suggest and aggregator responses and never returns null.
+func normalizeStringList(body []byte) ([]byte, error) {
+	values := []string{}
+	if err := json.Unmarshal(body, &values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = []string{}
+	}
+	sort.Strings(values)
+	return json.Marshal(values)
+}
+
+// normalizeFilters returns the sorted names of the filter types OpenTSDB supports.
+func normalizeFilters(body []byte) ([]byte, error) {
+	filters := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &filters); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return json.Marshal(names)
+}
+
+func writeResponseBytes(rw http.ResponseWriter, code int, msg []byte) {
+	rw.WriteHeader(code)
+	if _, err := rw.Write(msg); err != nil {
+		logger.Error("Unable to write HTTP response", "error", err)
+	}
+}
+
+func writeResponse(rw http.ResponseWriter, code int, msg string) {
+	writeResponseBytes(rw, code, []byte(msg))
+}
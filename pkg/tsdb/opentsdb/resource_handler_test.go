@@ -0,0 +1,96 @@
+package opentsdb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceHandler(t *testing.T) {
+	opentsdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/suggest":
+			if r.URL.Query().Get("max") != "25" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`["sys.mem", "sys.cpu"]`))
+		case "/api/aggregators":
+			_, _ = w.Write([]byte(`["sum", "avg", "count"]`))
+		case "/api/config/filters":
+			_, _ = w.Write([]byte(`{"wildcard": {"description": "..."}, "literal_or": {"description": "..."}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(opentsdb.Close)
+
+	service := &Service{
+		im: resourceInstanceManager{dsInfo: &datasourceInfo{
+			HTTPClient:  opentsdb.Client(),
+			URL:         opentsdb.URL,
+			LookupLimit: 25,
+		}},
+	}
+	service.resourceHandler = httpadapter.New(service.newResourceMux())
+
+	t.Run("suggest returns sorted values using the lookup limit", func(t *testing.T) {
+		resp := callResource(t, service, "suggest?type=metrics&q=sys")
+		require.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, `["sys.cpu","sys.mem"]`, string(resp.Body))
+	})
+
+	t.Run("suggest rejects unknown types", func(t *testing.T) {
+		resp := callResource(t, service, "suggest?type=annotations&q=sys")
+		require.Equal(t, http.StatusBadRequest, resp.Status)
+	})
+
+	t.Run("aggregators are sorted", func(t *testing.T) {
+		resp := callResource(t, service, "aggregators")
+		require.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, `["avg","count","sum"]`, string(resp.Body))
+	})
+
+	t.Run("filters return the filter type names", func(t *testing.T) {
+		resp := callResource(t, service, "filters")
+		require.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, `["literal_or","wildcard"]`, string(resp.Body))
+	})
+}
+
+func callResource(t *testing.T, s *Service, path string) *backend.CallResourceResponse {
+	t.Helper()
+	req := &backend.CallResourceRequest{
+		Method: http.MethodGet,
+		Path:   path,
+		URL:    "/" + path,
+	}
+
+	var resp *backend.CallResourceResponse
+	err := s.CallResource(context.Background(), req, backend.CallResourceResponseSenderFunc(func(r *backend.CallResourceResponse) error {
+		resp = r
+		return nil
+	}))
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	return resp
+}
+
+type resourceInstanceManager struct {
+	dsInfo *datasourceInfo
+}
+
+func (f resourceInstanceManager) Get(_ context.Context, _ backend.PluginContext) (instancemgmt.Instance, error) {
+	return f.dsInfo, nil
+}
+
+func (f resourceInstanceManager) Do(_ context.Context, _ backend.PluginContext, _ instancemgmt.InstanceCallbackFunc) error {
+	return nil
+}
@@ -1,13 +1,20 @@
 package opentsdb
 
 type OpenTsdbQuery struct {
-	Start   int64            `json:"start"`
-	End     int64            `json:"end"`
-	Queries []map[string]any `json:"queries"`
+	Start     int64            `json:"start"`
+	End       int64            `json:"end"`
+	Queries   []map[string]any `json:"queries"`
+	ShowQuery bool             `json:"showQuery,omitempty"`
 }
 
 type OpenTsdbResponse struct {
 	Metric     string            `json:"metric"`
 	Tags       map[string]string `json:"tags"`
 	DataPoints [][]float64       `json:"dps"`
+	// Query is only returned when the request sets showQuery
+	Query *OpenTsdbResponseQuery `json:"query,omitempty"`
+}
+
+type OpenTsdbResponseQuery struct {
+	Index int `json:"index"`
 }
@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var errPathNotAllowed = errors.New("database file is not allowed")
+
+// resolveDatabasePath returns the absolute path of the database file after resolving symlinks,
+// and fails unless it lies within one of the allowed directories from [sql_datasources] sqlite_allowed_paths.
+func resolveDatabasePath(allowedPaths []string, database string) (string, error) {
+	if len(allowedPaths) == 0 {
+		return "", fmt.Errorf("%w: no directories are allowed in the sqlite_allowed_paths setting", errPathNotAllowed)
+	}
+	if database == "" {
+		return "", errors.New("database file path is required")
+	}
+	if !filepath.IsAbs(database) {
+		return "", fmt.Errorf("%w: %q is not an absolute path", errPathNotAllowed, database)
+	}
+
+	// Resolve symlinks so a link inside an allowed directory can't point outside of it.
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(database))
+	if err != nil {
+		return "", fmt.Errorf("database file %q can't be opened: %w", database, err)
+	}
+
+	for _, allowed := range allowedPaths {
+		dir, err := filepath.Abs(allowed)
+		if err != nil {
+			continue
+		}
+		dir, err = filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		if isWithin(dir, resolved) {
+			return resolved, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %q is not within the directories allowed in the sqlite_allowed_paths setting", errPathNotAllowed, database)
+}
+
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveDatabasePath(t *testing.T) {
+	allowed := t.TempDir()
+	other := t.TempDir()
+
+	dbFile := filepath.Join(allowed, "metrics.db")
+	require.NoError(t, os.WriteFile(dbFile, nil, 0600))
+	outsideFile := filepath.Join(other, "secret.db")
+	require.NoError(t, os.WriteFile(outsideFile, nil, 0600))
+
+	resolvedDBFile, err := filepath.EvalSymlinks(dbFile)
+	require.NoError(t, err)
+
+	t.Run("allows files within an allowed directory", func(t *testing.T) {
+		path, err := resolveDatabasePath([]string{other, allowed}, dbFile)
+		require.NoError(t, err)
+		assert.Equal(t, resolvedDBFile, path)
+	})
+
+	t.Run("rejects files outside of the allowed directories", func(t *testing.T) {
+		_, err := resolveDatabasePath([]string{allowed}, outsideFile)
+		assert.ErrorIs(t, err, errPathNotAllowed)
+	})
+
+	t.Run("rejects paths escaping the allowed directory", func(t *testing.T) {
+		_, err := resolveDatabasePath([]string{allowed}, filepath.Join(allowed, "..", filepath.Base(other), "secret.db"))
+		assert.ErrorIs(t, err, errPathNotAllowed)
+	})
+
+	t.Run("rejects symlinks pointing outside of the allowed directories", func(t *testing.T) {
+		link := filepath.Join(allowed, "link.db")
+		require.NoError(t, os.Symlink(outsideFile, link))
+
+		_, err := resolveDatabasePath([]string{allowed}, link)
+		assert.ErrorIs(t, err, errPathNotAllowed)
+	})
+
+	t.Run("rejects relative paths", func(t *testing.T) {
+		_, err := resolveDatabasePath([]string{allowed}, "metrics.db")
+		assert.ErrorIs(t, err, errPathNotAllowed)
+	})
+
+	t.Run("rejects all files when no directory is allowed", func(t *testing.T) {
+		_, err := resolveDatabasePath(nil, dbFile)
+		assert.ErrorIs(t, err, errPathNotAllowed)
+	})
+
+	t.Run("fails for missing files", func(t *testing.T) {
+		_, err := resolveDatabasePath([]string{allowed}, filepath.Join(allowed, "missing.db"))
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, errPathNotAllowed)
+	})
+}
@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/tsdb/grafana-postgresql-datasource/sqleng"
+)
+
+func TestSQLite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "metrics.db")
+
+	writer, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = writer.Exec(`CREATE TABLE metrics (ts DATETIME, epoch INTEGER, host TEXT, value REAL);
+		INSERT INTO metrics VALUES
+			('2024-05-01 10:00:00', 1714557600, 'edge-1', 1.5),
+			('2024-05-01 10:01:00', 1714557660, 'edge-1', 2.5),
+			('2024-05-01 10:00:00', 1714557600, 'edge-2', 3.5),
+			('2024-05-01 11:00:00', 1714561200, 'edge-2', 4.5);`)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	db, err := sql.Open(driverName, connectionString(path))
+	require.NoError(t, err)
+
+	logger := backend.NewLoggerWith("logger", "tsdb.sqlite.test")
+	config := sqleng.DataPluginConfiguration{
+		DSInfo:            sqleng.DataSourceInfo{Database: path},
+		MetricColumnTypes: []string{"TEXT"},
+		RowLimit:          1000000,
+	}
+	exe, err := sqleng.NewQueryDataHandler("", db, config, &sqliteQueryResultTransformer{}, newSqliteMacroEngine(), logger)
+	require.NoError(t, err)
+	t.Cleanup(exe.Dispose)
+
+	timeRange := backend.TimeRange{
+		From: time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC),
+		To:   time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+	}
+
+	query := func(t *testing.T, rawSQL string, format string) backend.DataResponse {
+		t.Helper()
+		resp, err := exe.QueryData(context.Background(), &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{
+				{
+					RefID:     "A",
+					JSON:      []byte(`{"rawSql": "` + rawSQL + `", "format": "` + format + `"}`),
+					TimeRange: timeRange,
+				},
+			},
+		})
+		require.NoError(t, err)
+		return resp.Responses["A"]
+	}
+
+	t.Run("time series from a datetime column", func(t *testing.T) {
+		result := query(t, "SELECT $__time(ts), host, value FROM metrics WHERE $__timeFilter(ts) ORDER BY ts", "time_series")
+		require.NoError(t, result.Error)
+
+		require.Len(t, result.Frames, 1)
+		frame := result.Frames[0]
+		require.Equal(t, 2, frame.Rows())
+		require.Len(t, frame.Fields, 3)
+		assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), timeAt(t, frame.Fields[0], 0))
+		assert.Equal(t, time.Date(2024, 5, 1, 10, 1, 0, 0, time.UTC), timeAt(t, frame.Fields[0], 1))
+	})
+
+	t.Run("grouped time series from an epoch column", func(t *testing.T) {
+		result := query(t, "SELECT $__unixEpochGroupAlias(epoch, '5m'), avg(value) AS value FROM metrics WHERE $__unixEpochFilter(epoch) GROUP BY 1 ORDER BY 1", "time_series")
+		require.NoError(t, result.Error)
+
+		require.Len(t, result.Frames, 1)
+		frame := result.Frames[0]
+		require.Equal(t, 1, frame.Rows())
+		assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), timeAt(t, frame.Fields[0], 0))
+		value, err := frame.Fields[1].NullableFloatAt(0)
+		require.NoError(t, err)
+		assert.Equal(t, 2.5, *value)
+	})
+
+	t.Run("the database file is read-only", func(t *testing.T) {
+		result := query(t, "DELETE FROM metrics", "table")
+		require.Error(t, result.Error)
+	})
+
+	t.Run("other database files can't be attached", func(t *testing.T) {
+		result := query(t, "ATTACH DATABASE '"+filepath.Join(dir, "other.db")+"' AS other", "table")
+		require.Error(t, result.Error)
+	})
+
+	t.Run("the database can't be copied with VACUUM INTO", func(t *testing.T) {
+		target := filepath.Join(dir, "copy.db")
+		result := query(t, "VACUUM INTO '"+target+"'", "table")
+		require.Error(t, result.Error)
+		assert.NoFileExists(t, target)
+	})
+
+	t.Run("pragmas are not allowed", func(t *testing.T) {
+		result := query(t, "PRAGMA table_info(metrics)", "table")
+		require.Error(t, result.Error)
+	})
+}
+
+func timeAt(t *testing.T, field *data.Field, i int) time.Time {
+	t.Helper()
+	switch v := field.At(i).(type) {
+	case time.Time:
+		return v.UTC()
+	case *time.Time:
+		require.NotNil(t, v)
+		return v.UTC()
+	}
+	require.Failf(t, "not a time field", "field %s has type %s", field.Name, field.Type())
+	return time.Time{}
+}
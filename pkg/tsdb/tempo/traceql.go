@@ -0,0 +1,441 @@
+package tempo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana/pkg/tsdb/tempo/kinds/dataquery"
+	"github.com/grafana/tempo/pkg/tempopb"
+	commonv11 "github.com/grafana/tempo/pkg/tempopb/common/v1"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// metricsQueryRegex matches the metrics functions of TraceQL, for example { status = error } | rate()
+var metricsQueryRegex = regexp.MustCompile(`\|\s*(rate|count_over_time|quantile_over_time|histogram_over_time|min_over_time|max_over_time|avg_over_time|sum_over_time|compare)\s*\(`)
+
+var tempoUnmarshaler = &jsonpb.Unmarshaler{AllowUnknownFields: true}
+
+func isMetricsQuery(query string) bool {
+	return metricsQueryRegex.MatchString(query)
+}
+
+func (s *Service) runTraceQLQuery(ctx context.Context, pCtx backend.PluginContext, query backend.DataQuery) (*backend.DataResponse, error) {
+	dsInfo, err := s.getDSInfo(ctx, pCtx)
+	if err != nil {
+		s.logger.FromContext(ctx).Error("Failed to get datasource information", "error", err, "function", logEntrypoint())
+		return nil, err
+	}
+
+	return s.executeTraceQLQuery(ctx, dsInfo, query)
+}
+
+// executeTraceQLQuery runs a TraceQL search, or a TraceQL metrics query when the query has a metrics function
+func (s *Service) executeTraceQLQuery(ctx context.Context, dsInfo *Datasource, query backend.DataQuery) (*backend.DataResponse, error) {
+	ctxLogger := s.logger.FromContext(ctx)
+	result := &backend.DataResponse{}
+
+	ctx, span := tracing.DefaultTracer().Start(ctx, "datasource.tempo.executeTraceQLQuery", trace.WithAttributes(
+		attribute.String("queryType", query.QueryType),
+	))
+	defer span.End()
+
+	model := &dataquery.TempoQuery{}
+	if err := json.Unmarshal(query.JSON, model); err != nil {
+		ctxLogger.Error("Failed to unmarshall Tempo query model", "error", err, "function", logEntrypoint())
+		return result, err
+	}
+
+	traceQL := traceQLFromModel(query.QueryType, model)
+	if traceQL == "" {
+		err := fmt.Errorf("TraceQL query is required")
+		ctxLogger.Error("Failed to validate model query", "error", err, "function", logEntrypoint())
+		return result, err
+	}
+	span.SetAttributes(attribute.String("query", traceQL))
+
+	if isMetricsQuery(traceQL) {
+		return s.runTraceQLMetrics(ctx, dsInfo, model, traceQL, query, span)
+	}
+	return s.runTraceQLSearch(ctx, dsInfo, model, traceQL, query, span)
+}
+
+func (s *Service) runTraceQLSearch(ctx context.Context, dsInfo *Datasource, model *dataquery.TempoQuery, traceQL string, query backend.DataQuery, span trace.Span) (*backend.DataResponse, error) {
+	ctxLogger := s.logger.FromContext(ctx)
+	result := &backend.DataResponse{}
+
+	params := url.Values{}
+	params.Set("q", traceQL)
+	params.Set("start", strconv.FormatInt(query.TimeRange.From.Unix(), 10))
+	params.Set("end", strconv.FormatInt(query.TimeRange.To.Unix(), 10))
+	if model.Limit != nil && *model.Limit > 0 {
+		params.Set("limit", strconv.FormatInt(*model.Limit, 10))
+	}
+	if model.Spss != nil && *model.Spss > 0 {
+		params.Set("spss", strconv.FormatInt(*model.Spss, 10))
+	}
+
+	//nolint:bodyclose
+	resp, body, err := s.performTraceQLRequest(ctx, dsInfo, "/api/search", params, span)
+	if err != nil {
+		return result, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		result.Error = fmt.Errorf("failed to run TraceQL search: %s Status: %s Body: %s", traceQL, resp.Status, string(body))
+		ctxLogger.Error("Failed to run TraceQL search", "error", result.Error, "function", logEntrypoint())
+		span.RecordError(result.Error)
+		span.SetStatus(codes.Error, result.Error.Error())
+		return result, nil
+	}
+
+	var searchResponse tempopb.SearchResponse
+	if err := tempoUnmarshaler.Unmarshal(bytes.NewReader(body), &searchResponse); err != nil {
+		ctxLogger.Error("Failed to unmarshal TraceQL search response", "error", err, "function", logEntrypoint())
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return result, fmt.Errorf("failed to unmarshal TraceQL search response: %w", err)
+	}
+
+	var frame *data.Frame
+	if model.TableType != nil && *model.TableType == dataquery.SearchTableTypeSpans {
+		frame = spansToFrame(searchResponse.Traces)
+	} else {
+		frame = tracesToFrame(searchResponse.Traces)
+	}
+	frame.RefID = query.RefID
+
+	result.Frames = data.Frames{frame}
+	return result, nil
+}
+
+func (s *Service) runTraceQLMetrics(ctx context.Context, dsInfo *Datasource, model *dataquery.TempoQuery, traceQL string, query backend.DataQuery, span trace.Span) (*backend.DataResponse, error) {
+	ctxLogger := s.logger.FromContext(ctx)
+	result := &backend.DataResponse{}
+
+	params := url.Values{}
+	params.Set("q", traceQL)
+	params.Set("start", strconv.FormatInt(query.TimeRange.From.Unix(), 10))
+	params.Set("end", strconv.FormatInt(query.TimeRange.To.Unix(), 10))
+	if model.Step != nil && *model.Step != "" {
+		params.Set("step", *model.Step)
+	}
+
+	//nolint:bodyclose
+	resp, body, err := s.performTraceQLRequest(ctx, dsInfo, "/api/metrics/query_range", params, span)
+	if err != nil {
+		return result, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		result.Error = fmt.Errorf("failed to run TraceQL metrics query: %s Status: %s Body: %s", traceQL, resp.Status, string(body))
+		ctxLogger.Error("Failed to run TraceQL metrics query", "error", result.Error, "function", logEntrypoint())
+		span.RecordError(result.Error)
+		span.SetStatus(codes.Error, result.Error.Error())
+		return result, nil
+	}
+
+	var metricsResponse tempopb.QueryRangeResponse
+	if err := tempoUnmarshaler.Unmarshal(bytes.NewReader(body), &metricsResponse); err != nil {
+		ctxLogger.Error("Failed to unmarshal TraceQL metrics response", "error", err, "function", logEntrypoint())
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return result, fmt.Errorf("failed to unmarshal TraceQL metrics response: %w", err)
+	}
+
+	result.Frames = seriesToFrames(metricsResponse.Series, query.RefID)
+	return result, nil
+}
+
+func (s *Service) performTraceQLRequest(ctx context.Context, dsInfo *Datasource, path string, params url.Values, span trace.Span) (*http.Response, []byte, error) {
+	ctxLogger := s.logger.FromContext(ctx)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, dsInfo.URL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		ctxLogger.Error("Failed to create request", "error", err, "function", logEntrypoint())
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	resp, err := dsInfo.HTTPClient.Do(request)
+	if err != nil {
+		ctxLogger.Error("Failed to send request to Tempo", "error", err, "function", logEntrypoint())
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, nil, fmt.Errorf("failed get to tempo: %w", err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			ctxLogger.Error("Failed to close response body", "error", err, "function", logEntrypoint())
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		ctxLogger.Error("Failed to read response body", "error", err, "function", logEntrypoint())
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+// traceQLFromModel returns the TraceQL query of a query model.
+// The filters of the search query builder are turned into a TraceQL query like the query editor does.
+func traceQLFromModel(queryType string, model *dataquery.TempoQuery) string {
+	if queryType != string(dataquery.TempoQueryTypeTraceqlSearch) {
+		if model.Query == nil {
+			return ""
+		}
+		return strings.TrimSpace(*model.Query)
+	}
+
+	conditions := make([]string, 0, len(model.Filters))
+	for _, filter := range model.Filters {
+		if condition := filterToTraceQL(filter); condition != "" {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	query := "{}"
+	if len(conditions) > 0 {
+		query = "{ " + strings.Join(conditions, " && ") + " }"
+	}
+
+	if len(model.GroupBy) > 0 {
+		groupBy := make([]string, 0, len(model.GroupBy))
+		for _, filter := range model.GroupBy {
+			if filter.Tag != nil && *filter.Tag != "" {
+				groupBy = append(groupBy, scopedTag(filter))
+			}
+		}
+		if len(groupBy) > 0 {
+			query += " | select(" + strings.Join(groupBy, ", ") + ")"
+		}
+	}
+	return query
+}
+
+func filterToTraceQL(filter dataquery.TraceqlFilter) string {
+	if filter.Tag == nil || *filter.Tag == "" || filter.Operator == nil || filter.Value == nil {
+		return ""
+	}
+
+	var value string
+	switch v := (*filter.Value).(type) {
+	case []any:
+		if len(v) == 0 {
+			return ""
+		}
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+		value = strings.Join(values, "|")
+		if len(v) > 1 || (filter.ValueType != nil && *filter.ValueType == "string") {
+			value = strconv.Quote(value)
+		}
+	default:
+		value = fmt.Sprint(v)
+		if value == "" {
+			return ""
+		}
+		if filter.ValueType != nil && *filter.ValueType == "string" {
+			value = strconv.Quote(value)
+		}
+	}
+
+	return fmt.Sprintf("%s%s%s", scopedTag(filter), *filter.Operator, value)
+}
+
+func scopedTag(filter dataquery.TraceqlFilter) string {
+	tag := *filter.Tag
+	if filter.Scope == nil || *filter.Scope == dataquery.TraceqlSearchScopeUnscoped {
+		return "." + tag
+	}
+	if *filter.Scope == dataquery.TraceqlSearchScopeIntrinsic {
+		return tag
+	}
+	return string(*filter.Scope) + "." + tag
+}
+
+// tracesToFrame returns a table with a row per trace
+func tracesToFrame(traces []*tempopb.TraceSearchMetadata) *data.Frame {
+	traceIDs := make([]string, 0, len(traces))
+	startTimes := make([]time.Time, 0, len(traces))
+	services := make([]string, 0, len(traces))
+	names := make([]string, 0, len(traces))
+	durations := make([]int64, 0, len(traces))
+
+	for _, t := range traces {
+		traceIDs = append(traceIDs, t.TraceID)
+		startTimes = append(startTimes, time.Unix(0, int64(t.StartTimeUnixNano)))
+		services = append(services, t.RootServiceName)
+		names = append(names, t.RootTraceName)
+		durations = append(durations, int64(t.DurationMs))
+	}
+
+	frame := data.NewFrame("Traces",
+		data.NewField("traceID", nil, traceIDs).SetConfig(&data.FieldConfig{DisplayName: "Trace ID"}),
+		data.NewField("startTime", nil, startTimes).SetConfig(&data.FieldConfig{DisplayName: "Start time"}),
+		data.NewField("traceService", nil, services).SetConfig(&data.FieldConfig{DisplayName: "Service"}),
+		data.NewField("traceName", nil, names).SetConfig(&data.FieldConfig{DisplayName: "Name"}),
+		data.NewField("traceDuration", nil, durations).SetConfig(&data.FieldConfig{DisplayName: "Duration", Unit: "ms"}),
+	)
+	frame.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeTable}
+	return frame
+}
+
+// spansToFrame returns a table with a row per span matching the query, with a column per span attribute
+func spansToFrame(traces []*tempopb.TraceSearchMetadata) *data.Frame {
+	type spanRow struct {
+		trace      *tempopb.TraceSearchMetadata
+		span       *tempopb.Span
+		attributes map[string]string
+	}
+
+	var rows []spanRow
+	attributeKeys := map[string]bool{}
+	for _, t := range traces {
+		spanSets := t.SpanSets
+		if len(spanSets) == 0 && t.SpanSet != nil {
+			spanSets = []*tempopb.SpanSet{t.SpanSet}
+		}
+
+		for _, spanSet := range spanSets {
+			for _, span := range spanSet.Spans {
+				attributes := map[string]string{}
+				for _, attrs := range [][]*commonv11.KeyValue{spanSet.Attributes, span.Attributes} {
+					for _, kv := range attrs {
+						if kv == nil || kv.Key == "" {
+							continue
+						}
+						attributes[kv.Key] = attributeString(kv.Value)
+						attributeKeys[kv.Key] = true
+					}
+				}
+				rows = append(rows, spanRow{trace: t, span: span, attributes: attributes})
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(attributeKeys))
+	for key := range attributeKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	traceIDs := make([]string, 0, len(rows))
+	spanIDs := make([]string, 0, len(rows))
+	startTimes := make([]time.Time, 0, len(rows))
+	services := make([]string, 0, len(rows))
+	traceNames := make([]string, 0, len(rows))
+	spanNames := make([]string, 0, len(rows))
+	durations := make([]float64, 0, len(rows))
+	attributeValues := make([][]*string, len(keys))
+
+	for _, row := range rows {
+		traceIDs = append(traceIDs, row.trace.TraceID)
+		spanIDs = append(spanIDs, row.span.SpanID)
+		startTimes = append(startTimes, time.Unix(0, int64(row.span.StartTimeUnixNano)))
+		services = append(services, row.trace.RootServiceName)
+		traceNames = append(traceNames, row.trace.RootTraceName)
+		spanNames = append(spanNames, row.span.Name)
+		durations = append(durations, float64(row.span.DurationNanos)/float64(time.Millisecond))
+
+		for i, key := range keys {
+			if value, ok := row.attributes[key]; ok {
+				attributeValues[i] = append(attributeValues[i], &value)
+			} else {
+				attributeValues[i] = append(attributeValues[i], nil)
+			}
+		}
+	}
+
+	frame := data.NewFrame("Spans",
+		data.NewField("traceID", nil, traceIDs).SetConfig(&data.FieldConfig{DisplayName: "Trace ID"}),
+		data.NewField("spanID", nil, spanIDs).SetConfig(&data.FieldConfig{DisplayName: "Span ID"}),
+		data.NewField("startTime", nil, startTimes).SetConfig(&data.FieldConfig{DisplayName: "Start time"}),
+		data.NewField("traceService", nil, services).SetConfig(&data.FieldConfig{DisplayName: "Service"}),
+		data.NewField("traceName", nil, traceNames).SetConfig(&data.FieldConfig{DisplayName: "Trace name"}),
+		data.NewField("name", nil, spanNames).SetConfig(&data.FieldConfig{DisplayName: "Name"}),
+		data.NewField("duration", nil, durations).SetConfig(&data.FieldConfig{DisplayName: "Duration", Unit: "ms"}),
+	)
+	for i, key := range keys {
+		values := attributeValues[i]
+		if values == nil {
+			values = []*string{}
+		}
+		frame.Fields = append(frame.Fields, data.NewField(key, nil, values))
+	}
+	frame.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeTable}
+	return frame
+}
+
+// seriesToFrames returns a time series frame per series of a TraceQL metrics query
+func seriesToFrames(series []*tempopb.TimeSeries, refID string) data.Frames {
+	frames := make(data.Frames, 0, len(series))
+	for _, s := range series {
+		labels := data.Labels{}
+		for _, kv := range s.Labels {
+			if kv.Key == "" {
+				continue
+			}
+			labels[kv.Key] = attributeString(kv.Value)
+		}
+
+		samples := s.Samples
+		sort.SliceStable(samples, func(i, j int) bool {
+			return samples[i].TimestampMs < samples[j].TimestampMs
+		})
+
+		times := make([]time.Time, 0, len(samples))
+		values := make([]float64, 0, len(samples))
+		for _, sample := range samples {
+			times = append(times, time.UnixMilli(sample.TimestampMs))
+			values = append(values, sample.Value)
+		}
+
+		valueField := data.NewField(data.TimeSeriesValueFieldName, labels, values)
+		if s.PromLabels != "" {
+			valueField.Config = &data.FieldConfig{DisplayNameFromDS: s.PromLabels}
+		}
+
+		frame := data.NewFrame("", data.NewField(data.TimeSeriesTimeFieldName, nil, times), valueField)
+		frame.RefID = refID
+		frame.Meta = &data.FrameMeta{
+			Type:        data.FrameTypeTimeSeriesMulti,
+			TypeVersion: data.FrameTypeVersion{0, 1},
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func attributeString(value *commonv11.AnyValue) string {
+	if value == nil {
+		return ""
+	}
+	v := getAttributeVal(value)
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
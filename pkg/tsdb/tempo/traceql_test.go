@@ -0,0 +1,246 @@
+package tempo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/tsdb/tempo/kinds/dataquery"
+)
+
+const searchResponse = `{
+  "traces": [
+    {
+      "traceID": "2f3e0cee77ae5dc9c17ade3689eb2e54",
+      "rootServiceName": "shop-backend",
+      "rootTraceName": "update-billing",
+      "startTimeUnixNano": "1684778327699392724",
+      "durationMs": 557,
+      "spanSets": [
+        {
+          "spans": [
+            {
+              "spanID": "563d623c76514f8e",
+              "name": "authenticate",
+              "startTimeUnixNano": "1684778327735077898",
+              "durationNanos": "446979497",
+              "attributes": [{"key": "status", "value": {"stringValue": "error"}}]
+            }
+          ],
+          "matched": 1,
+          "attributes": [{"key": "service.name", "value": {"stringValue": "auth"}}]
+        }
+      ]
+    }
+  ],
+  "metrics": {"inspectedBytes": "1000", "completedJobs": 1, "totalJobs": 1}
+}`
+
+const metricsResponse = `{
+  "series": [
+    {
+      "labels": [{"key": "resource.service.name", "value": {"stringValue": "shop-backend"}}],
+      "samples": [
+        {"timestampMs": "1700000060000", "value": 2},
+        {"timestampMs": "1700000000000", "value": 1.5}
+      ],
+      "promLabels": "{resource.service.name=\"shop-backend\"}"
+    }
+  ],
+  "metrics": {"inspectedBytes": "1000"}
+}`
+
+func TestTraceQLQuery(t *testing.T) {
+	timeRange := backend.TimeRange{From: time.Unix(1700000000, 0), To: time.Unix(1700003600, 0)}
+
+	t.Run("should run TraceQL search", func(t *testing.T) {
+		var request *http.Request
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request = r
+			_, _ = w.Write([]byte(searchResponse))
+		}))
+		defer server.Close()
+
+		service := &Service{logger: backend.NewLoggerWith("logger", "tempo-test")}
+		res, err := service.executeTraceQLQuery(context.Background(), &Datasource{HTTPClient: server.Client(), URL: server.URL}, backend.DataQuery{
+			RefID:     "A",
+			QueryType: string(dataquery.TempoQueryTypeTraceql),
+			TimeRange: timeRange,
+			JSON:      []byte(`{"query": "{ status = error }", "limit": 20}`),
+		})
+		require.NoError(t, err)
+		require.NoError(t, res.Error)
+
+		assert.Equal(t, "/api/search", request.URL.Path)
+		assert.Equal(t, "{ status = error }", request.URL.Query().Get("q"))
+		assert.Equal(t, "1700000000", request.URL.Query().Get("start"))
+		assert.Equal(t, "1700003600", request.URL.Query().Get("end"))
+		assert.Equal(t, "20", request.URL.Query().Get("limit"))
+
+		require.Len(t, res.Frames, 1)
+		frame := res.Frames[0]
+		assert.Equal(t, "A", frame.RefID)
+		require.Equal(t, 1, frame.Rows())
+		assert.Equal(t, "2f3e0cee77ae5dc9c17ade3689eb2e54", frame.Fields[0].At(0))
+		assert.Equal(t, time.Unix(0, 1684778327699392724), frame.Fields[1].At(0))
+		assert.Equal(t, "shop-backend", frame.Fields[2].At(0))
+		assert.Equal(t, "update-billing", frame.Fields[3].At(0))
+		assert.Equal(t, int64(557), frame.Fields[4].At(0))
+	})
+
+	t.Run("should return a row per span for the spans table", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(searchResponse))
+		}))
+		defer server.Close()
+
+		service := &Service{logger: backend.NewLoggerWith("logger", "tempo-test")}
+		res, err := service.executeTraceQLQuery(context.Background(), &Datasource{HTTPClient: server.Client(), URL: server.URL}, backend.DataQuery{
+			RefID:     "A",
+			QueryType: string(dataquery.TempoQueryTypeTraceql),
+			TimeRange: timeRange,
+			JSON:      []byte(`{"query": "{ status = error }", "tableType": "spans"}`),
+		})
+		require.NoError(t, err)
+		require.Len(t, res.Frames, 1)
+
+		frame := res.Frames[0]
+		require.Equal(t, 1, frame.Rows())
+		spanID, _ := frame.FieldByName("spanID")
+		assert.Equal(t, "563d623c76514f8e", spanID.At(0))
+		name, _ := frame.FieldByName("name")
+		assert.Equal(t, "authenticate", name.At(0))
+		duration, _ := frame.FieldByName("duration")
+		assert.InDelta(t, 446.979497, duration.At(0), 0.000001)
+
+		status, _ := frame.FieldByName("status")
+		require.NotNil(t, status)
+		assert.Equal(t, "error", *status.At(0).(*string))
+		serviceName, _ := frame.FieldByName("service.name")
+		require.NotNil(t, serviceName)
+		assert.Equal(t, "auth", *serviceName.At(0).(*string))
+	})
+
+	t.Run("should run TraceQL metrics query", func(t *testing.T) {
+		var request *http.Request
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request = r
+			_, _ = w.Write([]byte(metricsResponse))
+		}))
+		defer server.Close()
+
+		service := &Service{logger: backend.NewLoggerWith("logger", "tempo-test")}
+		res, err := service.executeTraceQLQuery(context.Background(), &Datasource{HTTPClient: server.Client(), URL: server.URL}, backend.DataQuery{
+			RefID:     "B",
+			QueryType: string(dataquery.TempoQueryTypeTraceql),
+			TimeRange: timeRange,
+			JSON:      []byte(`{"query": "{ } | rate() by (resource.service.name)", "step": "60s"}`),
+		})
+		require.NoError(t, err)
+		require.NoError(t, res.Error)
+
+		assert.Equal(t, "/api/metrics/query_range", request.URL.Path)
+		assert.Equal(t, "60s", request.URL.Query().Get("step"))
+
+		require.Len(t, res.Frames, 1)
+		frame := res.Frames[0]
+		assert.Equal(t, "B", frame.RefID)
+		assert.Equal(t, data.FrameTypeTimeSeriesMulti, frame.Meta.Type)
+		require.Equal(t, 2, frame.Rows())
+		assert.Equal(t, time.UnixMilli(1700000000000), frame.Fields[0].At(0))
+		assert.Equal(t, 1.5, frame.Fields[1].At(0))
+		assert.Equal(t, 2.0, frame.Fields[1].At(1))
+		assert.Equal(t, data.Labels{"resource.service.name": "shop-backend"}, frame.Fields[1].Labels)
+	})
+
+	t.Run("should return error of failed queries", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("invalid TraceQL query"))
+		}))
+		defer server.Close()
+
+		service := &Service{logger: backend.NewLoggerWith("logger", "tempo-test")}
+		res, err := service.executeTraceQLQuery(context.Background(), &Datasource{HTTPClient: server.Client(), URL: server.URL}, backend.DataQuery{
+			RefID:     "A",
+			QueryType: string(dataquery.TempoQueryTypeTraceql),
+			TimeRange: timeRange,
+			JSON:      []byte(`{"query": "{ status = }"}`),
+		})
+		require.NoError(t, err)
+		require.Error(t, res.Error)
+		assert.Contains(t, res.Error.Error(), "invalid TraceQL query")
+	})
+
+	t.Run("should require a query", func(t *testing.T) {
+		service := &Service{logger: backend.NewLoggerWith("logger", "tempo-test")}
+		_, err := service.executeTraceQLQuery(context.Background(), &Datasource{}, backend.DataQuery{
+			RefID:     "A",
+			QueryType: string(dataquery.TempoQueryTypeTraceql),
+			TimeRange: timeRange,
+			JSON:      []byte(`{"query": ""}`),
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestIsMetricsQuery(t *testing.T) {
+	assert.True(t, isMetricsQuery("{ } | rate()"))
+	assert.True(t, isMetricsQuery("{ status = error } | count_over_time() by (resource.service.name)"))
+	assert.True(t, isMetricsQuery("{ } | quantile_over_time(duration, .9)"))
+	assert.False(t, isMetricsQuery("{ status = error }"))
+	assert.False(t, isMetricsQuery(`{ name = "rate()" } | select(span.foo)`))
+}
+
+func TestTraceQLFromModel(t *testing.T) {
+	filters := func(jsonFilters string) *dataquery.TempoQuery {
+		model := &dataquery.TempoQuery{}
+		require.NoError(t, json.Unmarshal([]byte(`{"filters": `+jsonFilters+`}`), model))
+		return model
+	}
+
+	tests := []struct {
+		name     string
+		model    *dataquery.TempoQuery
+		expected string
+	}{
+		{
+			name:     "should return empty query without filters",
+			model:    filters(`[]`),
+			expected: "{}",
+		},
+		{
+			name:     "should quote string values",
+			model:    filters(`[{"id": "service-name", "scope": "resource", "tag": "service.name", "operator": "=", "value": ["shop-backend"], "valueType": "string"}]`),
+			expected: `{ resource.service.name="shop-backend" }`,
+		},
+		{
+			name: "should combine filters",
+			model: filters(`[
+				{"id": "status", "scope": "intrinsic", "tag": "status", "operator": "=", "value": "error", "valueType": "keyword"},
+				{"id": "duration", "tag": "http.status_code", "operator": ">", "value": "499", "valueType": "int"},
+				{"id": "incomplete", "scope": "span", "tag": "http.method", "operator": "="}
+			]`),
+			expected: `{ status=error && .http.status_code>499 }`,
+		},
+		{
+			name:     "should join multiple values as a regex",
+			model:    filters(`[{"id": "name", "scope": "span", "tag": "name", "operator": "=~", "value": ["a", "b"], "valueType": "string"}]`),
+			expected: `{ span.name=~"a|b" }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, traceQLFromModel(string(dataquery.TempoQueryTypeTraceqlSearch), tt.model))
+		})
+	}
+}
@@ -10,7 +10,7 @@ import {
 } from '@grafana/data';
 import { ConfigSection, ConfigSubSection, DataSourceDescription, Stack } from '@grafana/experimental';
 import { config } from '@grafana/runtime';
-import { ConnectionLimits, Divider, TLSSecretsConfig, useMigrateDatabaseFields } from '@grafana/sql';
+import { ConnectionLimits, Divider, QueryGuardrails, TLSSecretsConfig, useMigrateDatabaseFields } from '@grafana/sql';
 import {
   Input,
   Select,
@@ -399,6 +399,8 @@ export const PostgresConfigEditor = (props: DataSourcePluginOptionsEditorProps<P
 
         <ConnectionLimits options={options} onOptionsChange={onOptionsChange} />
 
+        <QueryGuardrails options={options} onOptionsChange={onOptionsChange} />
+
         {config.secureSocksDSProxyEnabled && (
           <SecureSocksProxySettings options={options} onOptionsChange={onOptionsChange} />
         )}
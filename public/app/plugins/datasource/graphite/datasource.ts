@@ -636,7 +636,7 @@ export class GraphiteDatasource
 
     const httpOptions: BackendSrvRequest = {
       method: 'POST',
-      url: '/metrics/find',
+      url: 'metrics/find',
       params,
       data: `query=${query}`,
       headers: {
@@ -647,7 +647,7 @@ export class GraphiteDatasource
     };
 
     return lastValueFrom(
-      this.doGraphiteRequest(httpOptions).pipe(
+      this.doResourceRequest(httpOptions).pipe(
         map((results: any) => {
           return _map(results.data, (metric) => {
             return {
@@ -785,13 +785,13 @@ export class GraphiteDatasource
 
     const httpOptions: BackendSrvRequest = {
       method: 'GET',
-      url: '/tags/autoComplete/tags',
+      url: 'tags/autoComplete/tags',
       params,
       // for cancellations
       requestId: options.requestId,
     };
 
-    return lastValueFrom(this.doGraphiteRequest(httpOptions).pipe(mapToTags()));
+    return lastValueFrom(this.doResourceRequest(httpOptions).pipe(mapToTags()));
   }
 
   getTagValuesAutoComplete(expressions: string[], tag: string, valuePrefix?: string, optionalOptions?: any) {
@@ -813,13 +813,13 @@ export class GraphiteDatasource
 
     const httpOptions: BackendSrvRequest = {
       method: 'GET',
-      url: '/tags/autoComplete/values',
+      url: 'tags/autoComplete/values',
       params,
       // for cancellations
       requestId: options.requestId,
     };
 
-    return lastValueFrom(this.doGraphiteRequest(httpOptions).pipe(mapToTags()));
+    return lastValueFrom(this.doResourceRequest(httpOptions).pipe(mapToTags()));
   }
 
   getVersion(optionalOptions: any) {
@@ -872,22 +872,15 @@ export class GraphiteDatasource
 
     const httpOptions = {
       method: 'GET',
-      url: '/functions',
-      // add responseType because if this is not defined,
-      // backend_srv defaults to json
-      responseType: 'text' as const,
+      url: 'functions',
     };
 
     return lastValueFrom(
-      this.doGraphiteRequest(httpOptions).pipe(
+      this.doResourceRequest(httpOptions).pipe(
         map((results: any) => {
-          // Fix for a Graphite bug: https://github.com/graphite-project/graphite-web/issues/2609
-          // There is a fix for it https://github.com/graphite-project/graphite-web/pull/2612 but
-          // it was merged to master in July 2020 but it has never been released (the last Graphite
-          // release was 1.1.7 - March 2020). The bug was introduced in Graphite 1.1.7, in versions
-          // 1.1.0 - 1.1.6 /functions endpoint returns a valid JSON
-          const fixedData = JSON.parse(results.data.replace(/"default": ?Infinity/g, '"default": 1e9999'));
-          this.funcDefs = gfunc.parseFuncDefs(fixedData);
+          // the backend fixes the invalid JSON returned by Graphite 1.1.7,
+          // see https://github.com/graphite-project/graphite-web/issues/2609
+          this.funcDefs = gfunc.parseFuncDefs(results.data);
           return this.funcDefs;
         }),
         catchError((error) => {
@@ -947,6 +940,21 @@ export class GraphiteDatasource
       );
   }
 
+  /**
+   * Sends a request to a resource endpoint of the Graphite backend, like getResource and postResource of
+   * DataSourceWithBackend. The backend queries Graphite with the HTTP client of the data source, so that the
+   * request goes through private data source connect, and normalizes the response.
+   */
+  doResourceRequest(options: BackendSrvRequest) {
+    return getBackendSrv()
+      .fetch({ ...options, url: `/api/datasources/uid/${this.uid}/resources/${options.url}` })
+      .pipe(
+        catchError((err) => {
+          return throwError(reduceError(err));
+        })
+      );
+  }
+
   buildGraphiteParams(options: any, scopedVars?: ScopedVars): string[] {
     const graphiteOptions = ['from', 'until', 'rawData', 'format', 'maxDataPoints', 'cacheTimeout'];
     const cleanOptions = [],
@@ -19,6 +19,7 @@ describe('graphiteDatasource integration with backendSrv and fetch', () => {
     jest.clearAllMocks();
 
     const instanceSettings = {
+      uid: 'graphite-uid',
       url: '/api/datasources/proxy/1',
       name: 'graphiteProd',
       jsonData: {
@@ -30,42 +31,16 @@ describe('graphiteDatasource integration with backendSrv and fetch', () => {
   });
 
   describe('returns a list of functions', () => {
-    it('should return a list of functions with invalid JSON', async () => {
-      const INVALID_JSON =
-        '{"testFunction":{"name":"function","description":"description","module":"graphite.render.functions","group":"Transform","params":[{"name":"param","type":"intOrInf","required":true,"default":Infinity}]}}';
-
-      mockBackendSrv(INVALID_JSON);
-
-      const funcDefs = await ctx.ds.getFuncDefs();
-
-      expect(funcDefs).toEqual({
-        testFunction: {
-          category: 'Transform',
-          defaultParams: ['inf'],
-          description: 'description',
-          fake: true,
-          name: 'function',
-          params: [
-            {
-              multiple: false,
-              name: 'param',
-              optional: false,
-              options: undefined,
-              type: 'int_or_infinity',
-            },
-          ],
-        },
-      });
-    });
-
-    it('should return a list of functions with valid JSON', async () => {
+    // the backend replaces the Infinity defaults returned by Graphite 1.1.7 that aren't valid JSON
+    it('should return a list of functions from the backend', async () => {
       const VALID_JSON =
         '{"testFunction":{"name":"function","description":"description","module":"graphite.render.functions","group":"Transform","params":[{"name":"param","type":"intOrInf","required":true,"default":1e9999}]}}';
 
-      mockBackendSrv(VALID_JSON);
+      const fromFetchMock = mockBackendSrv(VALID_JSON);
 
       const funcDefs = await ctx.ds.getFuncDefs();
 
+      expect(fromFetchMock.mock.calls[0][0]).toBe('api/datasources/uid/graphite-uid/resources/functions');
       expect(funcDefs).toEqual({
         testFunction: {
           category: 'Transform',
@@ -118,9 +93,7 @@ function mockBackendSrv(data: string) {
       url: 'http://localhost:3000/api/some-mock',
       headers: new Headers({
         method: 'GET',
-        url: '/functions',
-        // to work around Graphite returning invalid JSON
-        responseType: 'text',
+        url: '/api/datasources/uid/graphite-uid/resources/functions',
       }),
     };
     return of(mockedResponse);
@@ -145,4 +118,6 @@ function mockBackendSrv(data: string) {
   });
 
   setBackendSrv(mockedBackendSrv);
+
+  return fromFetchMock;
 }
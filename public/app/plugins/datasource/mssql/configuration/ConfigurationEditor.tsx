@@ -12,7 +12,7 @@ import {
 } from '@grafana/data';
 import { ConfigSection, ConfigSubSection, DataSourceDescription } from '@grafana/experimental';
 import { config } from '@grafana/runtime';
-import { ConnectionLimits, useMigrateDatabaseFields } from '@grafana/sql';
+import { ConnectionLimits, QueryGuardrails, useMigrateDatabaseFields } from '@grafana/sql';
 import { NumberInput } from '@grafana/sql/src/components/configuration/NumberInput';
 import {
   Alert,
@@ -341,6 +341,12 @@ export const ConfigurationEditor = (props: DataSourcePluginOptionsEditorProps<Ms
       >
         <ConnectionLimits options={dsSettings} onOptionsChange={onOptionsChange} />
 
+        <QueryGuardrails
+          options={dsSettings}
+          onOptionsChange={onOptionsChange}
+          statementTimeoutDescription="SQL Server has no statement timeout setting, Grafana cancels the query when the timeout is reached."
+        />
+
         <ConfigSubSection title="Connection details">
           <Field
             description={
@@ -0,0 +1,10 @@
+import { DataSourcePlugin } from '@grafana/data';
+import { SQLQuery, SqlQueryEditor } from '@grafana/sql';
+
+import { SQLiteDatasource } from './SQLiteDatasource';
+import { ConfigurationEditor } from './configuration/ConfigurationEditor';
+import { SQLiteOptions } from './types';
+
+export const plugin = new DataSourcePlugin<SQLiteDatasource, SQLQuery, SQLiteOptions>(SQLiteDatasource)
+  .setQueryEditor(SqlQueryEditor)
+  .setConfigEditor(ConfigurationEditor);
@@ -0,0 +1,89 @@
+import { isEmpty } from 'lodash';
+
+import { createSelectClause, haveColumns, RAQBFieldTypes, SQLQuery } from '@grafana/sql';
+
+/**
+ * Maps the declared column type to the editor field type, following the SQLite type affinity rules.
+ */
+export function getFieldConfig(type: string): { raqbFieldType: RAQBFieldTypes; icon: string } {
+  const declared = type.toUpperCase();
+  if (declared.startsWith('BOOL')) {
+    return { raqbFieldType: 'boolean', icon: 'toggle-off' };
+  }
+  if (declared === 'DATE') {
+    return { raqbFieldType: 'date', icon: 'clock-nine' };
+  }
+  if (declared.includes('DATETIME') || declared.includes('TIMESTAMP')) {
+    return { raqbFieldType: 'datetime', icon: 'clock-nine' };
+  }
+  if (declared.includes('CHAR') || declared.includes('CLOB') || declared.includes('TEXT')) {
+    return { raqbFieldType: 'text', icon: 'text' };
+  }
+  if (
+    declared.includes('INT') ||
+    declared.includes('REAL') ||
+    declared.includes('FLOA') ||
+    declared.includes('DOUB') ||
+    declared.includes('NUMERIC') ||
+    declared.includes('DECIMAL')
+  ) {
+    return { raqbFieldType: 'number', icon: 'calculator-alt' };
+  }
+  return { raqbFieldType: 'text', icon: 'text' };
+}
+
+export function toRawSql({ sql, table }: SQLQuery): string {
+  let rawQuery = '';
+
+  // Return early with empty string if there is no sql column
+  if (!sql || !haveColumns(sql.columns)) {
+    return rawQuery;
+  }
+
+  rawQuery += createSelectClause(sql.columns);
+
+  if (table) {
+    rawQuery += `FROM ${table} `;
+  }
+
+  if (sql.whereString) {
+    rawQuery += `WHERE ${sql.whereString} `;
+  }
+
+  if (sql.groupBy?.[0]?.property.name) {
+    const groupBy = sql.groupBy.map((g) => g.property.name).filter((g) => !isEmpty(g));
+    rawQuery += `GROUP BY ${groupBy.join(', ')} `;
+  }
+
+  if (sql.orderBy?.property.name) {
+    rawQuery += `ORDER BY ${sql.orderBy.property.name} `;
+  }
+
+  if (sql.orderBy?.property.name && sql.orderByDirection) {
+    rawQuery += `${sql.orderByDirection} `;
+  }
+
+  // Altough LIMIT 0 doesn't make sense, it is still possible to have LIMIT 0
+  if (sql.limit !== undefined && sql.limit >= 0) {
+    rawQuery += `LIMIT ${sql.limit} `;
+  }
+  return rawQuery;
+}
+
+// Puts double quotes around the identifier if it is necessary.
+export function quoteIdentifierIfNecessary(value: string) {
+  return /^[a-zA-Z_][a-zA-Z0-9_]*$/.test(value) ? value : `"${value.replace(/"/g, '""')}"`;
+}
+
+// remove identifier quoting from identifier to use in metadata queries
+export function unquoteIdentifier(value: string) {
+  if (value[0] === '"' && value[value.length - 1] === '"') {
+    return value.substring(1, value.length - 1).replace(/""/g, '"');
+  } else {
+    return value;
+  }
+}
+
+export function quoteLiteral(value: string) {
+  return "'" + value.replace(/'/g, "''") + "'";
+}
@@ -0,0 +1,9 @@
+import { quoteLiteral, unquoteIdentifier } from './sqlUtil';
+
+export function showTables() {
+  return `SELECT name FROM sqlite_schema WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY name`;
+}
+
+export function buildColumnQuery(table: string) {
+  return `SELECT name, type FROM pragma_table_info(${quoteLiteral(unquoteIdentifier(table))}) ORDER BY cid`;
+}